@@ -0,0 +1,82 @@
+// Package buildinfo - сведения о сборке сервисов gw-project: версия, коммит и время
+// сборки. Значения задаются при сборке через -ldflags, например:
+//
+//	go build -ldflags "-X gw-common/buildinfo.Version=1.4.0 \
+//	  -X gw-common/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X gw-common/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// Без -ldflags коммит берется из сведений VCS, которые go build встраивает при сборке
+// из репозитория git.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Значения по умолчанию для сборки без -ldflags и сведений VCS
+const (
+	DefaultVersion = "dev"
+	Unknown        = "unknown"
+)
+
+// Задаются через -ldflags "-X"
+var (
+	Version   = DefaultVersion
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info сведения о сборке
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	// Modified сборка из рабочей копии с незафиксированными изменениями (по сведениям VCS)
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get возвращает сведения о сборке
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = Unknown
+	}
+	return info
+}
+
+// String возвращает сведения о сборке одной строкой для логов и флага -version
+func (i Info) String() string {
+	commit := i.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, commit, i.BuildTime, i.GoVersion)
+}
@@ -0,0 +1,201 @@
+// Package configfile - загрузка файла конфигурации сервисов gw-project в переменные
+// окружения. Поддерживаются .env, YAML (.yaml, .yml) и JSON (.json) с единой схемой:
+// путь ключа в файле, соединенный через "_" в верхнем регистре, - имя переменной
+// окружения (db.host -> DB_HOST). Переменные, уже заданные в окружении процесса,
+// имеют приоритет над файлом. Повторная загрузка (перечитывание конфигурации без
+// перезапуска) заменяет значения, заданные прежним файлом, и удаляет убранные из него.
+//
+// Пакет входит в общий модуль gw-common и используется всеми сервисами
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Форматы файла конфигурации
+const (
+	FormatEnv  = "env"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// FormatFromPath определяет формат файла по расширению; файлы без расширения
+// YAML или JSON (.env, config.env, local.conf) читаются как .env
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatEnv
+	}
+}
+
+// loaded переменные окружения, заданные из файла предыдущей загрузкой
+var (
+	loadedMu sync.Mutex
+	loaded   = make(map[string]bool)
+)
+
+// Load читает файл конфигурации и задает его переменные в окружении процесса.
+// Переменные окружения, заданные не из файла, не перезаписываются
+func Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	vars, err := Parse(file, FormatFromPath(path))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	// Настройки, убранные из файла, возвращаются к значениям по умолчанию
+	for key := range loaded {
+		if _, ok := vars[key]; !ok {
+			os.Unsetenv(key)
+			delete(loaded, key)
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, exists := os.LookupEnv(key); exists && !loaded[key] {
+			continue
+		}
+		if err := os.Setenv(key, vars[key]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		loaded[key] = true
+	}
+	return nil
+}
+
+// Parse читает переменные окружения из файла конфигурации в формате format
+func Parse(r io.Reader, format string) (map[string]string, error) {
+	switch format {
+	case FormatEnv:
+		return godotenv.Parse(r)
+	case FormatYAML, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return vars, nil
+	}
+	// YAML разбирает и JSON, но пропускает его синтаксические ошибки вроде лишних запятых
+	if format == FormatJSON && !json.Valid(data) {
+		var value interface{}
+		return nil, fmt.Errorf("invalid JSON: %w", json.Unmarshal(data, &value))
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", strings.ToUpper(format), err)
+	}
+	root := resolve(&doc)
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = resolve(root.Content[0])
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("config must be an object of settings")
+	}
+	if err := flatten(vars, "", root); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// flatten записывает в vars значения вложенного объекта node с префиксом имени prefix
+func flatten(vars map[string]string, prefix string, node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := envName(node.Content[i].Value)
+		if key == "" {
+			return fmt.Errorf("line %d: empty key", node.Content[i].Line)
+		}
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		value := resolve(node.Content[i+1])
+		switch value.Kind {
+		case yaml.MappingNode:
+			if err := flatten(vars, key, value); err != nil {
+				return err
+			}
+			continue
+		case yaml.SequenceNode:
+			// Списки задаются в переменных окружения через запятую
+			items := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				item = resolve(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("line %d: %s: list items must be scalar values", item.Line, key)
+				}
+				items = append(items, item.Value)
+			}
+			if err := set(vars, key, strings.Join(items, ","), value.Line); err != nil {
+				return err
+			}
+		case yaml.ScalarNode:
+			// null означает значение по умолчанию
+			if value.Tag == "!!null" {
+				continue
+			}
+			if err := set(vars, key, value.Value, value.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func set(vars map[string]string, key, value string, line int) error {
+	if _, exists := vars[key]; exists {
+		return fmt.Errorf("line %d: duplicate setting %s", line, key)
+	}
+	vars[key] = value
+	return nil
+}
+
+// envName приводит ключ файла к имени переменной окружения: max-recv.size -> MAX_RECV_SIZE
+func envName(key string) string {
+	key = strings.TrimSpace(key)
+	key = strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(key)
+	return strings.ToUpper(key)
+}
+
+// resolve раскрывает ссылку YAML (*anchor) на узел
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
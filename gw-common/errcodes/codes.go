@@ -0,0 +1,182 @@
+// Package errcodes - общий реестр кодов ошибок сервисов gw-project: строковый
+// код для клиентов, числовой код для дашбордов и алертов, HTTP статус и gRPC код.
+package errcodes
+
+import (
+	"net/http"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Code строковый код ошибки, возвращаемый клиентам
+type Code string
+
+// Коды ошибок. Диапазоны числовых кодов: 1xxx - некорректный запрос,
+// 2xxx - аутентификация и права, 3xxx - ресурсы, 4xxx - бизнес-правила,
+// 5xxx - внутренние ошибки и зависимости
+const (
+	InvalidRequest      Code = "invalid_request"
+	InvalidAmount       Code = "invalid_amount"
+	UnsupportedCurrency Code = "unsupported_currency"
+	SameCurrency        Code = "same_currency"
+	MethodNotAllowed    Code = "method_not_allowed"
+	PayloadTooLarge     Code = "payload_too_large"
+	WeakPassword        Code = "weak_password"
+	Unauthorized        Code = "unauthorized"
+	InvalidCredentials  Code = "invalid_credentials"
+	Forbidden           Code = "forbidden"
+	PairNotAllowed      Code = "pair_not_allowed"
+	NotFound            Code = "not_found"
+	AlreadyExists       Code = "already_exists"
+	UserExists          Code = "user_exists"
+	InsufficientFunds   Code = "insufficient_funds"
+	LimitExceeded       Code = "limit_exceeded"
+	RateLimited         Code = "rate_limited"
+	AccountFrozen       Code = "account_frozen"
+	AccountClosed       Code = "account_closed"
+	BalanceNotEmpty     Code = "balance_not_empty"
+	Internal            Code = "internal_error"
+	ServiceUnavailable  Code = "service_unavailable"
+	StaleRate           Code = "stale_rate"
+	Timeout             Code = "timeout"
+)
+
+// Definition запись реестра: числовой код, HTTP статус и gRPC код для строкового кода
+type Definition struct {
+	Code        Code       `json:"code"`
+	Number      int        `json:"number"`
+	HTTPStatus  int        `json:"http_status"`
+	GRPCCode    codes.Code `json:"grpc_code"`
+	Description string     `json:"description"`
+}
+
+// registry реестр кодов ошибок
+var registry = map[Code]Definition{
+	InvalidRequest:      {InvalidRequest, 1001, http.StatusBadRequest, codes.InvalidArgument, "Invalid request body or parameters"},
+	InvalidAmount:       {InvalidAmount, 1002, http.StatusBadRequest, codes.InvalidArgument, "Amount is not positive"},
+	UnsupportedCurrency: {UnsupportedCurrency, 1003, http.StatusBadRequest, codes.InvalidArgument, "Currency is not supported"},
+	SameCurrency:        {SameCurrency, 1004, http.StatusBadRequest, codes.InvalidArgument, "Exchange currencies are the same"},
+	MethodNotAllowed:    {MethodNotAllowed, 1005, http.StatusMethodNotAllowed, codes.Unimplemented, "HTTP method is not allowed"},
+	PayloadTooLarge:     {PayloadTooLarge, 1006, http.StatusRequestEntityTooLarge, codes.InvalidArgument, "Request body is too large"},
+	WeakPassword:        {WeakPassword, 1007, http.StatusBadRequest, codes.InvalidArgument, "Password does not meet the password policy"},
+	Unauthorized:        {Unauthorized, 2001, http.StatusUnauthorized, codes.Unauthenticated, "Missing or invalid token"},
+	InvalidCredentials:  {InvalidCredentials, 2002, http.StatusUnauthorized, codes.Unauthenticated, "Invalid username or password"},
+	Forbidden:           {Forbidden, 2003, http.StatusForbidden, codes.PermissionDenied, "Insufficient permissions"},
+	PairNotAllowed:      {PairNotAllowed, 2004, http.StatusForbidden, codes.PermissionDenied, "Currency pair is not allowed for the caller"},
+	NotFound:            {NotFound, 3001, http.StatusNotFound, codes.NotFound, "Resource not found"},
+	AlreadyExists:       {AlreadyExists, 3002, http.StatusConflict, codes.AlreadyExists, "Resource already exists"},
+	UserExists:          {UserExists, 3003, http.StatusConflict, codes.AlreadyExists, "Username or email is taken"},
+	InsufficientFunds:   {InsufficientFunds, 4001, http.StatusBadRequest, codes.FailedPrecondition, "Insufficient funds"},
+	LimitExceeded:       {LimitExceeded, 4002, http.StatusUnprocessableEntity, codes.FailedPrecondition, "Operation limit exceeded"},
+	RateLimited:         {RateLimited, 4003, http.StatusTooManyRequests, codes.ResourceExhausted, "Too many requests, retry after Retry-After"},
+	AccountFrozen:       {AccountFrozen, 4004, http.StatusForbidden, codes.FailedPrecondition, "Withdrawals and exchanges are frozen for the account"},
+	AccountClosed:       {AccountClosed, 4005, http.StatusForbidden, codes.FailedPrecondition, "Account is closed, money operations are not allowed"},
+	BalanceNotEmpty:     {BalanceNotEmpty, 4006, http.StatusConflict, codes.FailedPrecondition, "Account has non-zero balances"},
+	Internal:            {Internal, 5001, http.StatusInternalServerError, codes.Internal, "Internal error, details are only logged"},
+	ServiceUnavailable:  {ServiceUnavailable, 5002, http.StatusServiceUnavailable, codes.Unavailable, "Dependency is unavailable"},
+	StaleRate:           {StaleRate, 5003, http.StatusServiceUnavailable, codes.FailedPrecondition, "Exchange rate is outdated, the rate feed is not updating"},
+	Timeout:             {Timeout, 5004, http.StatusGatewayTimeout, codes.DeadlineExceeded, "Request did not complete within the deadline"},
+}
+
+// Lookup возвращает запись реестра для кода
+func Lookup(code Code) (Definition, bool) {
+	def, ok := registry[code]
+	return def, ok
+}
+
+// All возвращает все записи реестра, отсортированные по числовому коду
+func All() []Definition {
+	defs := make([]Definition, 0, len(registry))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Number < defs[j].Number })
+	return defs
+}
+
+// definition возвращает запись реестра; неизвестный код считается internal_error
+func (c Code) definition() Definition {
+	if def, ok := registry[c]; ok {
+		return def
+	}
+	return registry[Internal]
+}
+
+// Number числовой код ошибки
+func (c Code) Number() int {
+	return c.definition().Number
+}
+
+// HTTPStatus HTTP статус ответа с ошибкой
+func (c Code) HTTPStatus() int {
+	return c.definition().HTTPStatus
+}
+
+// GRPCCode gRPC код статуса с ошибкой
+func (c Code) GRPCCode() codes.Code {
+	return c.definition().GRPCCode
+}
+
+// String реализует fmt.Stringer
+func (c Code) String() string {
+	return string(c)
+}
+
+// FromHTTPStatus возвращает общий код для HTTP статуса ответа без кода ошибки,
+// например от прокси. Для успешных статусов возвращает пустой код
+func FromHTTPStatus(status int) Code {
+	switch {
+	case status < http.StatusBadRequest:
+		return ""
+	case status == http.StatusUnauthorized:
+		return Unauthorized
+	case status == http.StatusForbidden:
+		return Forbidden
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusMethodNotAllowed:
+		return MethodNotAllowed
+	case status == http.StatusConflict:
+		return AlreadyExists
+	case status == http.StatusRequestEntityTooLarge:
+		return PayloadTooLarge
+	case status == http.StatusTooManyRequests:
+		return RateLimited
+	case status == http.StatusBadGateway,
+		status == http.StatusServiceUnavailable,
+		status == http.StatusGatewayTimeout:
+		return ServiceUnavailable
+	case status < http.StatusInternalServerError:
+		return InvalidRequest
+	default:
+		return Internal
+	}
+}
+
+// FromGRPCCode возвращает общий код для gRPC кода статуса без ErrorInfo.
+// Для codes.OK возвращает пустой код
+func FromGRPCCode(code codes.Code) Code {
+	switch code {
+	case codes.OK:
+		return ""
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return InvalidRequest
+	case codes.Unauthenticated:
+		return Unauthorized
+	case codes.PermissionDenied:
+		return Forbidden
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists:
+		return AlreadyExists
+	case codes.Unimplemented:
+		return MethodNotAllowed
+	case codes.ResourceExhausted:
+		return RateLimited
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return ServiceUnavailable
+	default:
+		return Internal
+	}
+}
@@ -0,0 +1,49 @@
+package errcodes
+
+import (
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// Domain домен ErrorInfo в деталях gRPC статуса
+const Domain = "gw-project"
+
+// GRPCError создает ошибку gRPC с кодом статуса из реестра. Строковый код
+// передается в деталях статуса (ErrorInfo.Reason), чтобы клиент получил
+// тот же код, что и в HTTP ответах
+func GRPCError(code Code, message string) error {
+	st := status.New(code.GRPCCode(), message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: Domain})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// GRPCErrorf создает ошибку gRPC с форматированным сообщением
+func GRPCErrorf(code Code, format string, args ...interface{}) error {
+	return GRPCError(code, fmt.Sprintf(format, args...))
+}
+
+// FromError возвращает код ошибки, полученной от gRPC сервиса: из ErrorInfo,
+// если сервис его передал, иначе по коду статуса. Для nil возвращает пустой код
+func FromError(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return Internal
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			if _, known := registry[Code(info.Reason)]; known {
+				return Code(info.Reason)
+			}
+		}
+	}
+	return FromGRPCCode(st.Code())
+}
@@ -0,0 +1,65 @@
+package kafkasecurity
+
+import (
+	"fmt"
+	"strings"
+
+	"gw-common/env"
+)
+
+// Config параметры аутентификации и шифрования соединений с Kafka.
+// Нулевое значение - соединение без TLS и аутентификации
+type Config struct {
+	// SASLMechanism PLAIN, SCRAM-SHA-256 или SCRAM-SHA-512; пусто - без SASL
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+	// TLS включает TLS. TLSCAFile - CA для проверки брокеров (по умолчанию
+	// системные), TLSCertFile и TLSKeyFile - клиентский сертификат для mTLS
+	TLS                   bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+}
+
+// FromEnv читает настройки из переменных KAFKA_SASL_* и KAFKA_TLS_*;
+// без них соединение остается без TLS и аутентификации
+func FromEnv() Config {
+	return Config{
+		SASLMechanism:         strings.ToUpper(env.String("KAFKA_SASL_MECHANISM", "")),
+		SASLUsername:          env.String("KAFKA_SASL_USERNAME", ""),
+		SASLPassword:          env.String("KAFKA_SASL_PASSWORD", ""),
+		TLS:                   env.Bool("KAFKA_TLS_ENABLED", false),
+		TLSCAFile:             env.String("KAFKA_TLS_CA_FILE", ""),
+		TLSCertFile:           env.String("KAFKA_TLS_CERT_FILE", ""),
+		TLSKeyFile:            env.String("KAFKA_TLS_KEY_FILE", ""),
+		TLSInsecureSkipVerify: env.Bool("KAFKA_TLS_INSECURE_SKIP_VERIFY", false),
+	}
+}
+
+// Validate проверяет механизм SASL, учетные данные и согласованность файлов TLS
+func (c Config) Validate() error {
+	switch strings.ToUpper(c.SASLMechanism) {
+	case "":
+	case SASLMechanismPlain, SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512:
+		if c.SASLUsername == "" || c.SASLPassword == "" {
+			return fmt.Errorf("KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD are required for KAFKA_SASL_MECHANISM=%s", c.SASLMechanism)
+		}
+	default:
+		return fmt.Errorf("invalid KAFKA_SASL_MECHANISM: %s (expected PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512)", c.SASLMechanism)
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together")
+	}
+	if !c.TLS && (c.TLSCAFile != "" || c.TLSCertFile != "") {
+		return fmt.Errorf("KAFKA_TLS_CA_FILE and KAFKA_TLS_CERT_FILE require KAFKA_TLS_ENABLED=true")
+	}
+	return nil
+}
+
+// PlaintextCredentials сообщает, что учетные данные SASL PLAIN передаются без TLS
+func (c Config) PlaintextCredentials() bool {
+	return strings.EqualFold(c.SASLMechanism, SASLMechanismPlain) && !c.TLS
+}
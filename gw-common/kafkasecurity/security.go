@@ -0,0 +1,140 @@
+// Package kafkasecurity - SASL аутентификация и TLS соединений сервисов gw-project
+// с Kafka и чтение их настроек из переменных KAFKA_SASL_* и KAFKA_TLS_*
+package kafkasecurity
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// Механизмы SASL аутентификации
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// Security механизм SASL и настройки TLS для клиентов Kafka.
+// nil означает соединение без TLS и аутентификации
+type Security struct {
+	SASL sasl.Mechanism
+	TLS  *tls.Config
+}
+
+// New создает механизм SASL и загружает сертификаты TLS.
+// Возвращает nil, если ни SASL, ни TLS не включены
+func New(cfg Config) (*Security, error) {
+	if cfg.SASLMechanism == "" && !cfg.TLS {
+		return nil, nil
+	}
+
+	security := &Security{}
+
+	switch strings.ToUpper(cfg.SASLMechanism) {
+	case "":
+	case SASLMechanismPlain:
+		security.SASL = plain.Mechanism{Username: cfg.SASLUsername, Password: cfg.SASLPassword}
+	case SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512:
+		algorithm := scram.SHA256
+		if strings.EqualFold(cfg.SASLMechanism, SASLMechanismSCRAMSHA512) {
+			algorithm = scram.SHA512
+		}
+		mechanism, err := scram.Mechanism(algorithm, cfg.SASLUsername, cfg.SASLPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SCRAM mechanism: %w", err)
+		}
+		security.SASL = mechanism
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism: %q", cfg.SASLMechanism)
+	}
+
+	if cfg.TLS {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		security.TLS = tlsConfig
+	}
+
+	return security, nil
+}
+
+// newTLSConfig собирает настройки TLS из файлов сертификатов
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+	}
+
+	if cfg.TLSCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in Kafka CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Dialer возвращает dialer для reader и проверки брокеров. Для nil возвращается
+// nil, и kafka-go использует dialer по умолчанию
+func (s *Security) Dialer() *kafka.Dialer {
+	if s == nil {
+		return nil
+	}
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		SASLMechanism: s.SASL,
+		TLS:           s.TLS,
+	}
+}
+
+// Transport возвращает транспорт writer и client. Для nil возвращается nil
+// интерфейс, чтобы kafka-go использовал транспорт по умолчанию
+func (s *Security) Transport() kafka.RoundTripper {
+	if s == nil {
+		return nil
+	}
+	return &kafka.Transport{
+		SASL:        s.SASL,
+		TLS:         s.TLS,
+		DialTimeout: 10 * time.Second,
+	}
+}
+
+// String описывает режим соединения для логов
+func (s *Security) String() string {
+	switch {
+	case s == nil:
+		return "plaintext"
+	case s.SASL != nil && s.TLS != nil:
+		return "sasl_ssl/" + s.SASL.Name()
+	case s.SASL != nil:
+		return "sasl_plaintext/" + s.SASL.Name()
+	default:
+		return "ssl"
+	}
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Поля записи, одинаковые во всех бэкендах
+const (
+	FieldTimestamp = "timestamp"
+	FieldLevel     = "level"
+	FieldMessage   = "message"
+)
+
+// TimestampFormat формат поля timestamp
+const TimestampFormat = "2006-01-02 15:04:05"
+
+// Encoder кодирует запись лога в одну строку JSON. Все реализации выводят поля
+// timestamp, level (имя уровня logrus: debug, info, warning, error...) и message,
+// а поля записи - в одном представлении: ошибки строкой, длительности в наносекундах,
+// время в RFC 3339
+type Encoder interface {
+	Encode(buf *bytes.Buffer, entry *logrus.Entry) error
+}
+
+// NewEncoder создает кодировщик бэкенда; пустое имя - logrus
+func NewEncoder(backend string) (Encoder, error) {
+	switch backend {
+	case "", BackendLogrus:
+		return newLogrusEncoder(), nil
+	case BackendZap:
+		return newZapEncoder(), nil
+	case BackendZerolog:
+		return zerologEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log backend %q (expected %s, %s or %s)", backend, BackendLogrus, BackendZap, BackendZerolog)
+	}
+}
+
+// Formatter форматирует записи logrus кодировщиком бэкенда. Хуки logrus выполняются
+// до форматирования, поэтому их изменения полей видны всем бэкендам
+type Formatter struct {
+	Encoder Encoder
+	// Sampler ограничивает отладочные записи, nil - без ограничения
+	Sampler *Sampler
+}
+
+// Format реализует logrus.Formatter. Отброшенная сэмплированием запись не выводится
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.Sampler != nil && entry.Level >= logrus.DebugLevel && !f.Sampler.Allow(entry.Message, entry.Time) {
+		return nil, nil
+	}
+
+	buf := entry.Buffer
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+	if err := f.Encoder.Encode(buf, entry); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// logrusEncoder кодирует запись стандартным JSONFormatter logrus
+type logrusEncoder struct {
+	formatter *logrus.JSONFormatter
+}
+
+func newLogrusEncoder() logrusEncoder {
+	return logrusEncoder{formatter: &logrus.JSONFormatter{
+		TimestampFormat: TimestampFormat,
+		FieldMap: logrus.FieldMap{
+			logrus.FieldKeyTime:  FieldTimestamp,
+			logrus.FieldKeyLevel: FieldLevel,
+			logrus.FieldKeyMsg:   FieldMessage,
+		},
+	}}
+}
+
+func (e logrusEncoder) Encode(buf *bytes.Buffer, entry *logrus.Entry) error {
+	// JSONFormatter пишет в entry.Buffer, если он задан
+	if entry.Buffer != buf {
+		copied := *entry
+		copied.Buffer = buf
+		entry = &copied
+	}
+	_, err := e.formatter.Format(entry)
+	return err
+}
+
+// zapEncoder кодирует запись JSON кодировщиком zap без его ядра и уровней:
+// уровень проверяет logrus, имя уровня записывается полем
+type zapEncoder struct {
+	encoder zapcore.Encoder
+}
+
+func newZapEncoder() zapEncoder {
+	return zapEncoder{encoder: zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        FieldTimestamp,
+		MessageKey:     FieldMessage,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.TimeEncoderOfLayout(TimestampFormat),
+		EncodeDuration: zapcore.NanosDurationEncoder,
+	})}
+}
+
+func (e zapEncoder) Encode(buf *bytes.Buffer, entry *logrus.Entry) error {
+	fields := make([]zapcore.Field, 0, len(entry.Data)+1)
+	fields = append(fields, zap.String(FieldLevel, entry.Level.String()))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			fields = append(fields, zap.String(key, v))
+		case int:
+			fields = append(fields, zap.Int(key, v))
+		case int64:
+			fields = append(fields, zap.Int64(key, v))
+		case float64:
+			fields = append(fields, zap.Float64(key, v))
+		case bool:
+			fields = append(fields, zap.Bool(key, v))
+		case error:
+			fields = append(fields, zap.String(key, v.Error()))
+		case time.Duration:
+			fields = append(fields, zap.Int64(key, int64(v)))
+		case time.Time:
+			fields = append(fields, zap.String(key, v.Format(time.RFC3339Nano)))
+		default:
+			fields = append(fields, zap.Reflect(key, v))
+		}
+	}
+
+	encoded, err := e.encoder.EncodeEntry(zapcore.Entry{Time: entry.Time, Message: entry.Message}, fields)
+	if err != nil {
+		return err
+	}
+	buf.Write(encoded.Bytes())
+	encoded.Free()
+	return nil
+}
+
+// zerologEncoder кодирует запись событием zerolog без уровня zerolog:
+// уровень проверяет logrus, имя уровня записывается полем
+type zerologEncoder struct{}
+
+func (zerologEncoder) Encode(buf *bytes.Buffer, entry *logrus.Entry) error {
+	logger := zerolog.New(buf)
+	event := logger.Log().
+		Str(FieldTimestamp, entry.Time.Format(TimestampFormat)).
+		Str(FieldLevel, entry.Level.String())
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			event.Str(key, v)
+		case int:
+			event.Int(key, v)
+		case int64:
+			event.Int64(key, v)
+		case float64:
+			event.Float64(key, v)
+		case bool:
+			event.Bool(key, v)
+		case error:
+			event.Str(key, v.Error())
+		case time.Duration:
+			event.Int64(key, int64(v))
+		case time.Time:
+			event.Str(key, v.Format(time.RFC3339Nano))
+		default:
+			event.Interface(key, v)
+		}
+	}
+	event.Msg(entry.Message)
+	return nil
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+
+	"gw-common/env"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Значения по умолчанию
+const (
+	DefaultLevel   = "info"
+	DefaultBackend = BackendLogrus
+	// Отладочные записи с одним сообщением: первые 100 за секунду, затем каждая сотая
+	DefaultSampleInitial    = 100
+	DefaultSampleThereafter = 100
+
+	// Файл логов ротируется после 100 МБ, ротированные файлы хранятся неделю, не больше 5
+	DefaultFileMaxSizeMB  = 100
+	DefaultFileMaxAgeDays = 7
+	DefaultFileMaxBackups = 5
+
+	// Маскирование персональных данных и секретов в логах и порог маскируемых сумм
+	DefaultRedact                = true
+	DefaultRedactAmountThreshold = 10000.0
+)
+
+// Config настройки логгера
+type Config struct {
+	Level string
+	// Backend кодировщик записей: logrus (по умолчанию), zap или zerolog
+	Backend string
+	// SampleInitial и SampleThereafter ограничивают отладочные записи: за секунду
+	// выводятся первые SampleInitial записей с одним сообщением, затем каждая
+	// SampleThereafter-я. SampleInitial 0 - без ограничения
+	SampleInitial    int
+	SampleThereafter int
+	// File запись в файл с ротацией, пустой File.Path - без файла
+	File FileConfig
+	// DisableStdout отключает вывод в stdout; без файла вывод в stdout остается
+	DisableStdout bool
+	// Redact маскирует персональные данные и секреты (см. RedactHook)
+	Redact bool
+	// RedactAmountThreshold порог маскируемых сумм, 0 - суммы не маскируются
+	RedactAmountThreshold float64
+}
+
+// Defaults возвращает настройки логгера по умолчанию
+func Defaults() Config {
+	return Config{
+		Level:            DefaultLevel,
+		Backend:          DefaultBackend,
+		SampleInitial:    DefaultSampleInitial,
+		SampleThereafter: DefaultSampleThereafter,
+		File: FileConfig{
+			MaxSizeMB:  DefaultFileMaxSizeMB,
+			MaxAgeDays: DefaultFileMaxAgeDays,
+			MaxBackups: DefaultFileMaxBackups,
+		},
+		Redact:                DefaultRedact,
+		RedactAmountThreshold: DefaultRedactAmountThreshold,
+	}
+}
+
+// FromEnv читает настройки логгера из переменных LOG_*; незаданные переменные
+// получают значения Defaults
+func FromEnv() Config {
+	defaults := Defaults()
+	return Config{
+		Level:            env.String("LOG_LEVEL", defaults.Level),
+		Backend:          env.String("LOG_BACKEND", defaults.Backend),
+		SampleInitial:    env.Int("LOG_DEBUG_SAMPLE_INITIAL", defaults.SampleInitial),
+		SampleThereafter: env.Int("LOG_DEBUG_SAMPLE_THEREAFTER", defaults.SampleThereafter),
+		File: FileConfig{
+			Path:       env.String("LOG_FILE", defaults.File.Path),
+			MaxSizeMB:  env.Int("LOG_FILE_MAX_SIZE_MB", defaults.File.MaxSizeMB),
+			MaxAgeDays: env.Int("LOG_FILE_MAX_AGE_DAYS", defaults.File.MaxAgeDays),
+			MaxBackups: env.Int("LOG_FILE_MAX_BACKUPS", defaults.File.MaxBackups),
+			Compress:   env.Bool("LOG_FILE_COMPRESS", defaults.File.Compress),
+		},
+		DisableStdout:         !env.Bool("LOG_STDOUT", !defaults.DisableStdout),
+		Redact:                env.Bool("LOG_REDACT", defaults.Redact),
+		RedactAmountThreshold: env.Float("LOG_REDACT_AMOUNT_THRESHOLD", defaults.RedactAmountThreshold),
+	}
+}
+
+// Validate проверяет настройки логгера
+func (c Config) Validate() error {
+	if _, err := logrus.ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("invalid log level: %s", c.Level)
+	}
+	if err := ValidateBackend(c.Backend); err != nil {
+		return err
+	}
+	if c.SampleInitial < 0 || c.SampleThereafter < 0 {
+		return fmt.Errorf("LOG_DEBUG_SAMPLE_INITIAL and LOG_DEBUG_SAMPLE_THEREAFTER must not be negative")
+	}
+	if err := ValidateFile(c.File); err != nil {
+		return err
+	}
+	if c.DisableStdout && c.File.Path == "" {
+		return fmt.Errorf("LOG_STDOUT=false requires LOG_FILE")
+	}
+	if c.RedactAmountThreshold < 0 {
+		return fmt.Errorf("LOG_REDACT_AMOUNT_THRESHOLD must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig настройки файла логов. Файл ротируется по размеру: текущий файл
+// переименовывается с отметкой времени, а старые копии удаляются по возрасту и числу
+type FileConfig struct {
+	Path string
+	// MaxSizeMB размер файла в мегабайтах, после которого он ротируется
+	MaxSizeMB int
+	// MaxAgeDays срок хранения ротированных файлов в днях, 0 - без ограничения
+	MaxAgeDays int
+	// MaxBackups число хранимых ротированных файлов, 0 - без ограничения
+	MaxBackups int
+	// Compress сжимает ротированные файлы gzip
+	Compress bool
+}
+
+// ValidateFile проверяет настройки файла логов
+func ValidateFile(cfg FileConfig) error {
+	if cfg.Path == "" {
+		return nil
+	}
+	if cfg.MaxSizeMB <= 0 {
+		return fmt.Errorf("log file max size must be positive, got %d", cfg.MaxSizeMB)
+	}
+	if cfg.MaxAgeDays < 0 || cfg.MaxBackups < 0 {
+		return fmt.Errorf("log file max age and max backups must not be negative")
+	}
+	return nil
+}
+
+// newOutput возвращает вывод логгера: stdout, файл с ротацией или оба сразу
+func newOutput(cfg Config) io.Writer {
+	if cfg.File.Path == "" {
+		return os.Stdout
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   cfg.File.Path,
+		MaxSize:    cfg.File.MaxSizeMB,
+		MaxAge:     cfg.File.MaxAgeDays,
+		MaxBackups: cfg.File.MaxBackups,
+		Compress:   cfg.File.Compress,
+		LocalTime:  true,
+	}
+	if cfg.DisableStdout {
+		return file
+	}
+	return io.MultiWriter(os.Stdout, file)
+}
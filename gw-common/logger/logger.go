@@ -0,0 +1,81 @@
+// Package logger - логгер сервисов gw-project: logrus с подключаемыми бэкендами
+// вывода (logrus, zap, zerolog), семплированием debug записей, ротацией файла и
+// маскированием персональных данных
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Бэкенды вывода логов
+const (
+	BackendLogrus  = "logrus"
+	BackendZap     = "zap"
+	BackendZerolog = "zerolog"
+)
+
+// New создает новый настроенный логгер
+func New(level string) *logrus.Logger {
+	logger, _ := NewWithConfig(Config{Level: level})
+	return logger
+}
+
+// NewWithConfig создает логгер с выбранным бэкендом. Сервисы пишут логи через
+// *logrus.Logger, а записи после хуков и проверки уровня кодирует бэкенд, поэтому
+// смена бэкенда не требует изменений в коде сервисов
+func NewWithConfig(cfg Config) (*logrus.Logger, error) {
+	encoder, err := NewEncoder(cfg.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logrus.New()
+
+	// Установка формата вывода
+	formatter := &Formatter{Encoder: encoder}
+	if cfg.SampleInitial > 0 {
+		formatter.Sampler = NewSampler(time.Second, cfg.SampleInitial, cfg.SampleThereafter)
+	}
+	logger.SetFormatter(formatter)
+
+	// Хуки выполняются до форматирования, маскирование видно всем бэкендам и выводам
+	if cfg.Redact {
+		logger.AddHook(NewRedactHook(cfg.RedactAmountThreshold))
+	}
+
+	// Установка уровня логирования
+	logLevel, err := logrus.ParseLevel(cfg.Level)
+	if err != nil {
+		logLevel = logrus.InfoLevel
+	}
+	logger.SetLevel(logLevel)
+
+	// Вывод в stdout и файл
+	logger.SetOutput(newOutput(cfg))
+
+	return logger, nil
+}
+
+// ValidateBackend проверяет имя бэкенда
+func ValidateBackend(backend string) error {
+	_, err := NewEncoder(backend)
+	return err
+}
+
+// SetLevel меняет уровень логирования без перезапуска
+func SetLevel(logger *logrus.Logger, level string) error {
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+	logger.SetLevel(logLevel)
+	return nil
+}
+
+// WithFields добавляет дополнительные поля к логгеру
+func WithFields(logger *logrus.Logger, fields map[string]interface{}) *logrus.Entry {
+	return logger.WithFields(fields)
+}
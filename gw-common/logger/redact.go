@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted значение, которым заменяются секреты
+const Redacted = "[REDACTED]"
+
+// secretFields поля, значения которых не выводятся никогда
+var secretFields = map[string]bool{
+	"token":            true,
+	"access_token":     true,
+	"refresh_token":    true,
+	"authorization":    true,
+	"password":         true,
+	"old_password":     true,
+	"current_password": true,
+	"new_password":     true,
+	"secret":           true,
+	"api_key":          true,
+	"api_token":        true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	// JWT, ключи API кошелька и значения заголовка Authorization
+	tokenPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*|gwk_[A-Za-z0-9]+|(?i:bearer)\s+\S+`)
+	// Суммы в тексте сообщений сервисов: "Amount=1500.00", "Fee=2.50" и "1500.00 USD"
+	amountPattern = regexp.MustCompile(`(?i:\b(\w*amount|fee)=)(-?\d+(?:\.\d+)?)|\b(\d+(?:\.\d+)?)( [A-Z]{3}\b)`)
+)
+
+// RedactHook маскирует персональные данные и секреты в записях до форматирования:
+// адреса email (остаются первый символ и домен), JWT, ключи API и Bearer токены в
+// сообщении и строковых полях, значения полей с секретами, а также суммы больше
+// AmountThreshold в полях amount и *_amount и в тексте сообщения. В тексте суммой
+// считается значение после Amount=, TotalAmount=, Fee= и число перед кодом валюты
+type RedactHook struct {
+	// AmountThreshold порог сумм, 0 - суммы не маскируются
+	AmountThreshold float64
+}
+
+// NewRedactHook создает хук маскирования
+func NewRedactHook(amountThreshold float64) *RedactHook {
+	return &RedactHook{AmountThreshold: amountThreshold}
+}
+
+// Levels реализует logrus.Hook
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire реализует logrus.Hook. Поля записи разделяются с logrus.Entry вызывающего
+// кода, поэтому при замене значений поля копируются
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactAmounts(RedactString(entry.Message))
+
+	var data logrus.Fields
+	for key, value := range entry.Data {
+		redacted, changed := h.redactField(key, value)
+		if !changed {
+			continue
+		}
+		if data == nil {
+			data = make(logrus.Fields, len(entry.Data))
+			for k, v := range entry.Data {
+				data[k] = v
+			}
+		}
+		data[key] = redacted
+	}
+	if data != nil {
+		entry.Data = data
+	}
+	return nil
+}
+
+// redactField возвращает значение поля после маскирования и признак изменения
+func (h *RedactHook) redactField(key string, value interface{}) (interface{}, bool) {
+	name := strings.ToLower(key)
+	if secretFields[name] {
+		return Redacted, true
+	}
+
+	switch v := value.(type) {
+	case string:
+		redacted := RedactString(v)
+		return redacted, redacted != v
+	case error:
+		message := v.Error()
+		redacted := RedactString(message)
+		return redacted, redacted != message
+	}
+
+	if h.AmountThreshold > 0 && (name == "amount" || strings.HasSuffix(name, "_amount")) {
+		if amount, ok := toFloat(value); ok && (amount > h.AmountThreshold || amount < -h.AmountThreshold) {
+			return fmt.Sprintf(">%g", h.AmountThreshold), true
+		}
+	}
+	return value, false
+}
+
+// redactAmounts маскирует суммы больше AmountThreshold в тексте сообщения
+func (h *RedactHook) redactAmounts(message string) string {
+	if h.AmountThreshold <= 0 {
+		return message
+	}
+	return amountPattern.ReplaceAllStringFunc(message, func(match string) string {
+		parts := amountPattern.FindStringSubmatch(match)
+		prefix, value, suffix := match[:len(match)-len(parts[2])], parts[2], ""
+		if value == "" {
+			prefix, value, suffix = "", parts[3], parts[4]
+		}
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || (amount <= h.AmountThreshold && amount >= -h.AmountThreshold) {
+			return match
+		}
+		return prefix + fmt.Sprintf(">%g", h.AmountThreshold) + suffix
+	})
+}
+
+// RedactString маскирует адреса email и токены в строке
+func RedactString(s string) string {
+	if strings.Contains(s, "@") {
+		s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	}
+	if strings.Contains(s, "eyJ") || strings.Contains(s, "gwk_") || strings.Contains(strings.ToLower(s), "bearer") {
+		s = tokenPattern.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// samplerCounters число счетчиков сэмплера; сообщения распределяются по ним хешем
+const samplerCounters = 4096
+
+// Sampler ограничивает поток записей с одинаковым сообщением: за интервал tick
+// пропускает первые initial записей, затем каждую thereafter-ю. Как и в zap,
+// сообщения с совпавшим хешем делят один счетчик
+type Sampler struct {
+	tick       time.Duration
+	initial    uint64
+	thereafter uint64
+
+	mu       sync.Mutex
+	counters [samplerCounters]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt time.Time
+	count   uint64
+}
+
+// NewSampler создает сэмплер; thereafter 0 - после первых initial записи отбрасываются
+func NewSampler(tick time.Duration, initial, thereafter int) *Sampler {
+	return &Sampler{
+		tick:       tick,
+		initial:    uint64(initial),
+		thereafter: uint64(thereafter),
+	}
+}
+
+// Allow сообщает, выводить ли запись с сообщением message в момент now
+func (s *Sampler) Allow(message string, now time.Time) bool {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(message))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := &s.counters[hash.Sum32()%samplerCounters]
+	if !now.Before(counter.resetAt) {
+		counter.resetAt = now.Add(s.tick)
+		counter.count = 0
+	}
+	counter.count++
+
+	if counter.count <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (counter.count-s.initial)%s.thereafter == 0
+}
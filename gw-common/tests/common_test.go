@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gw-common/env"
+	"gw-common/kafkasecurity"
+	"gw-common/logger"
+	"gw-common/utils"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("GW_TEST_STRING", "value")
+	t.Setenv("GW_TEST_INT", "42")
+	t.Setenv("GW_TEST_BAD_INT", "forty-two")
+	t.Setenv("GW_TEST_BOOL", "true")
+	t.Setenv("GW_TEST_FLOAT", "0.25")
+	t.Setenv("GW_TEST_DURATION", "1m30s")
+	t.Setenv("GW_TEST_LIST", " a, ,b ,c")
+
+	if got := env.String("GW_TEST_STRING", "default"); got != "value" {
+		t.Errorf("Expected value, got %q", got)
+	}
+	if got := env.String("GW_TEST_MISSING", "default"); got != "default" {
+		t.Errorf("Expected default for missing variable, got %q", got)
+	}
+	if got := env.Int("GW_TEST_INT", 1); got != 42 {
+		t.Errorf("Expected 42, got %d", got)
+	}
+	// Некорректное значение заменяется значением по умолчанию
+	if got := env.Int("GW_TEST_BAD_INT", 1); got != 1 {
+		t.Errorf("Expected default for invalid int, got %d", got)
+	}
+	if !env.Bool("GW_TEST_BOOL", false) {
+		t.Error("Expected true")
+	}
+	if got := env.Float("GW_TEST_FLOAT", 1); got != 0.25 {
+		t.Errorf("Expected 0.25, got %v", got)
+	}
+	if got := env.Duration("GW_TEST_DURATION", time.Second); got != 90*time.Second {
+		t.Errorf("Expected 1m30s, got %v", got)
+	}
+
+	list := env.List("GW_TEST_LIST", nil)
+	if len(list) != 3 || list[0] != "a" || list[1] != "b" || list[2] != "c" {
+		t.Errorf("Expected [a b c], got %v", list)
+	}
+	if got := env.List("GW_TEST_MISSING", []string{"x"}); len(got) != 1 || got[0] != "x" {
+		t.Errorf("Expected default list, got %v", got)
+	}
+}
+
+func TestLoggerConfig(t *testing.T) {
+	// Без переменных LOG_* действуют значения по умолчанию
+	for _, key := range []string{"LOG_LEVEL", "LOG_BACKEND", "LOG_FILE", "LOG_STDOUT", "LOG_REDACT_AMOUNT_THRESHOLD"} {
+		t.Setenv(key, "")
+	}
+	if got := logger.FromEnv(); got != logger.Defaults() {
+		t.Errorf("Expected defaults %+v, got %+v", logger.Defaults(), got)
+	}
+	if err := logger.Defaults().Validate(); err != nil {
+		t.Errorf("Expected valid defaults, got %v", err)
+	}
+
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_BACKEND", logger.BackendZap)
+	t.Setenv("LOG_FILE", "/var/log/gw.log")
+	t.Setenv("LOG_STDOUT", "false")
+	t.Setenv("LOG_REDACT_AMOUNT_THRESHOLD", "500")
+	cfg := logger.FromEnv()
+	if cfg.Level != "debug" || cfg.Backend != logger.BackendZap || cfg.File.Path != "/var/log/gw.log" || !cfg.DisableStdout || cfg.RedactAmountThreshold != 500 {
+		t.Errorf("Expected LOG_* overrides, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	invalid := map[string]func(cfg *logger.Config){
+		"level":     func(cfg *logger.Config) { cfg.Level = "verbose" },
+		"backend":   func(cfg *logger.Config) { cfg.Backend = "slog" },
+		"sample":    func(cfg *logger.Config) { cfg.SampleThereafter = -1 },
+		"file size": func(cfg *logger.Config) { cfg.File.MaxSizeMB = 0 },
+		"stdout":    func(cfg *logger.Config) { cfg.File.Path = "" },
+		"threshold": func(cfg *logger.Config) { cfg.RedactAmountThreshold = -1 },
+	}
+	for name, mutate := range invalid {
+		broken := cfg
+		mutate(&broken)
+		if err := broken.Validate(); err == nil {
+			t.Errorf("Expected %s validation error", name)
+		}
+	}
+}
+
+func TestKafkaSecurity(t *testing.T) {
+	security, err := kafkasecurity.New(kafkasecurity.Config{})
+	if err != nil || security != nil {
+		t.Fatalf("Expected no security for empty config, got %v, %v", security, err)
+	}
+	if security.Dialer() != nil || security.Transport() != nil {
+		t.Error("Expected default kafka-go dialer and transport without security")
+	}
+
+	for mechanism, name := range map[string]string{
+		"plain":         "PLAIN",
+		"SCRAM-SHA-256": "SCRAM-SHA-256",
+		"scram-sha-512": "SCRAM-SHA-512",
+	} {
+		security, err := kafkasecurity.New(kafkasecurity.Config{
+			SASLMechanism: mechanism,
+			SASLUsername:  "wallet",
+			SASLPassword:  "secret",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create %s security: %v", mechanism, err)
+		}
+		if security.SASL == nil || security.SASL.Name() != name {
+			t.Errorf("Expected SASL mechanism %s, got %v", name, security.SASL)
+		}
+		if security.TLS != nil {
+			t.Errorf("Expected no TLS for %s", mechanism)
+		}
+	}
+
+	if _, err := kafkasecurity.New(kafkasecurity.Config{SASLMechanism: "GSSAPI"}); err == nil {
+		t.Error("Expected error for unsupported SASL mechanism")
+	}
+
+	security, err = kafkasecurity.New(kafkasecurity.Config{TLS: true, TLSInsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Failed to create TLS security: %v", err)
+	}
+	if security.TLS == nil || !security.TLS.InsecureSkipVerify || security.SASL != nil {
+		t.Errorf("Expected TLS without SASL, got %+v", security)
+	}
+	if dialer := security.Dialer(); dialer == nil || dialer.TLS != security.TLS {
+		t.Errorf("Expected dialer with TLS, got %+v", dialer)
+	}
+
+	if _, err := kafkasecurity.New(kafkasecurity.Config{TLS: true, TLSCAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
+
+func TestKafkaSecurityConfig(t *testing.T) {
+	t.Setenv("KAFKA_SASL_MECHANISM", "scram-sha-256")
+	t.Setenv("KAFKA_SASL_USERNAME", "wallet")
+	t.Setenv("KAFKA_SASL_PASSWORD", "secret")
+	t.Setenv("KAFKA_TLS_ENABLED", "true")
+	t.Setenv("KAFKA_TLS_CA_FILE", "/etc/kafka/ca.pem")
+
+	cfg := kafkasecurity.FromEnv()
+	if cfg.SASLMechanism != kafkasecurity.SASLMechanismSCRAMSHA256 || cfg.SASLUsername != "wallet" || !cfg.TLS || cfg.TLSCAFile != "/etc/kafka/ca.pem" {
+		t.Errorf("Expected KAFKA_SASL_* and KAFKA_TLS_* settings, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+	if err := (kafkasecurity.Config{}).Validate(); err != nil {
+		t.Errorf("Expected empty config to be valid, got %v", err)
+	}
+
+	invalid := map[string]kafkasecurity.Config{
+		"mechanism":   {SASLMechanism: "GSSAPI"},
+		"credentials": {SASLMechanism: kafkasecurity.SASLMechanismPlain, SASLUsername: "wallet"},
+		"key pair":    {TLS: true, TLSCertFile: "/etc/kafka/client.pem"},
+		"tls files":   {TLSCAFile: "/etc/kafka/ca.pem"},
+	}
+	for name, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %s validation error", name)
+		}
+	}
+
+	if !(kafkasecurity.Config{SASLMechanism: kafkasecurity.SASLMechanismPlain}).PlaintextCredentials() {
+		t.Error("Expected SASL PLAIN without TLS to send plaintext credentials")
+	}
+	if (kafkasecurity.Config{SASLMechanism: kafkasecurity.SASLMechanismPlain, TLS: true}).PlaintextCredentials() {
+		t.Error("Expected SASL PLAIN over TLS to be protected")
+	}
+}
+
+func TestCurrencyUtils(t *testing.T) {
+	if got := utils.NormalizeCurrency(" usd "); got != "USD" {
+		t.Errorf("Expected USD, got %q", got)
+	}
+
+	for _, code := range []string{"usd", "EUR"} {
+		if err := utils.ValidateCurrencyCode(code); err != nil {
+			t.Errorf("Expected %s to be valid: %v", code, err)
+		}
+	}
+	for _, code := range []string{"US", "USDT", "U5D", ""} {
+		if err := utils.ValidateCurrencyCode(code); err == nil {
+			t.Errorf("Expected %q to be invalid", code)
+		}
+	}
+
+	supported := []string{"USD", "EUR", "RUB"}
+	if err := utils.ValidateCurrency("rub", supported); err != nil {
+		t.Errorf("Expected rub to be supported: %v", err)
+	}
+	if err := utils.ValidateCurrency("GBP", supported); !errors.Is(err, utils.ErrUnsupportedCurrency) {
+		t.Errorf("Expected ErrUnsupportedCurrency, got %v", err)
+	}
+
+	if err := utils.ValidateAmount(0); err == nil {
+		t.Error("Expected zero amount to be invalid")
+	}
+}
+
+func TestFormatUtils(t *testing.T) {
+	if got := utils.FormatExchangeRate(0.5); got != "0.50000000" {
+		t.Errorf("Unexpected rate format: %s", got)
+	}
+	if got := utils.FormatDuration(90 * time.Second); got != "1.50m" {
+		t.Errorf("Unexpected duration format: %s", got)
+	}
+	if got := utils.FormatProcessingRate(30, 10*time.Second); got != "3.00 msg/s" {
+		t.Errorf("Unexpected processing rate format: %s", got)
+	}
+	if got := utils.FormatBytes(1536); got != "1.5 KB" {
+		t.Errorf("Unexpected bytes format: %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Package utils - общие проверки кодов валют и форматирование значений для логов
+// и вывода сервисов gw-project
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedCurrency валюта не входит в список поддерживаемых
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// NormalizeCurrency приводит код валюты к верхнему регистру
+func NormalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// ValidateCurrencyCode проверяет формат кода валюты (три латинские буквы, ISO 4217)
+func ValidateCurrencyCode(currency string) error {
+	currency = NormalizeCurrency(currency)
+	if len(currency) != 3 {
+		return fmt.Errorf("invalid currency code: %q. Expected 3 letters", currency)
+	}
+
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return fmt.Errorf("invalid currency code: %q. Expected 3 letters", currency)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCurrency проверяет, что валюта входит в список поддерживаемых
+func ValidateCurrency(currency string, supported []string) error {
+	currency = NormalizeCurrency(currency)
+	for _, code := range supported {
+		if strings.EqualFold(code, currency) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s. Supported currencies: %s", ErrUnsupportedCurrency, currency, strings.Join(supported, ", "))
+}
+
+// ValidateAmount проверяет, что сумма положительная
+func ValidateAmount(amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	return nil
+}
@@ -1,10 +1,15 @@
-package pkg
+package utils
 
 import (
 	"fmt"
 	"time"
 )
 
+// FormatExchangeRate форматирует курс обмена для вывода
+func FormatExchangeRate(rate float64) string {
+	return fmt.Sprintf("%.8f", rate)
+}
+
 // FormatDuration форматирует duration в удобочитаемый формат
 func FormatDuration(d time.Duration) string {
 	if d < time.Second {
@@ -19,8 +24,8 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.2fh", d.Hours())
 }
 
-// FormatRate форматирует скорость обработки
-func FormatRate(messagesProcessed int64, duration time.Duration) string {
+// FormatProcessingRate форматирует скорость обработки
+func FormatProcessingRate(messagesProcessed int64, duration time.Duration) string {
 	if duration.Seconds() == 0 {
 		return "0 msg/s"
 	}
@@ -4,21 +4,21 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
-	"gw-currency-wallet/internal/api"
-	"gw-currency-wallet/internal/api/middleware"
-	"gw-currency-wallet/internal/cache"
+	"gw-common/buildinfo"
+	"gw-common/logger"
+	"gw-currency-wallet/internal/app"
+	"gw-currency-wallet/internal/archive"
 	"gw-currency-wallet/internal/config"
-	"gw-currency-wallet/internal/grpc"
-	"gw-currency-wallet/internal/kafka"
-	"gw-currency-wallet/internal/logger"
-	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
 	"gw-currency-wallet/internal/storages/postgres"
+
+	"github.com/sirupsen/logrus"
 )
 
 // @title Currency Wallet API
@@ -39,12 +39,24 @@ import (
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description Partner API key (see /api/v1/api-keys). Grants read-only or trade access.
 
 func main() {
 	// Парсинг флагов командной строки
 	configPath := flag.String("c", "", "Path to config file")
+	checkLedger := flag.Bool("check-ledger", false, "Check ledger invariants and exit (non-zero exit code on violations)")
+	repairSQL := flag.String("repair-sql", "", "With -check-ledger: write SQL repairing balance discrepancies to this file")
+	showVersion := flag.Bool("version", false, "Print version and build info and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Printf("gw-currency-wallet %s\n", buildinfo.Get())
+		return
+	}
+
 	// Загрузка конфигурации
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -59,122 +71,252 @@ func main() {
 	}
 
 	// Инициализация логгера
-	log := logger.New(cfg.Logger.Level)
-	log.Info("Starting gw-currency-wallet service...")
+	log, err := logger.NewWithConfig(cfg.Logger)
+	if err != nil {
+		fmt.Printf("Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	log.Infof("Starting gw-currency-wallet service %s...", buildinfo.Get())
 	log.Infof("Configuration loaded from: %s", *configPath)
 
-	// Подключение к базе данных
-	dbConfig := &postgres.Config{
-		Host:            cfg.Database.Host,
-		Port:            cfg.Database.Port,
-		User:            cfg.Database.User,
-		Password:        cfg.Database.Password,
-		DBName:          cfg.Database.DBName,
-		SSLMode:         cfg.Database.SSLMode,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		MaxIdleConns:    cfg.Database.MaxIdleConns,
-		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	// Команды обслуживания без запуска сервиса: migrate - схема БД,
+	// archive - выгрузка старых транзакций в S3, reconcile - сверка балансов с журналом
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		os.Exit(runMigrate(&cfg.Database, flag.Args()[1:], log))
+	case "archive":
+		os.Exit(runArchive(cfg, log))
+	case "reconcile":
+		os.Exit(runReconcile(&cfg.Database, flag.Args()[1:], log))
+	default:
+		log.Fatalf("Unknown command %q (expected migrate, archive or reconcile)", flag.Arg(0))
+	}
+
+	// Режим проверки инвариантов учета (для CI и аудита)
+	if *checkLedger {
+		storage, err := app.ConnectStorage(cfg, log)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(runLedgerCheck(storage, *repairSQL, log))
 	}
 
-	storage, err := postgres.New(dbConfig, log)
+	// Сборка компонентов сервиса
+	application, err := app.New(cfg, app.WithLogger(log), app.WithConfigPath(*configPath))
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to start service: %v", err)
+	}
+
+	// Graceful shutdown по SIGINT и SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := application.Run(ctx); err != nil {
+		log.Errorf("Server stopped with errors: %v", err)
+		os.Exit(1)
+	}
+	log.Info("Server stopped gracefully")
+}
+
+// runMigrate выполняет команду migrate up|down [N]|status и возвращает код выхода процесса
+func runMigrate(cfg *config.DatabaseConfig, args []string, log *logrus.Logger) int {
+	if cfg.Driver != config.DBDriverPostgres {
+		log.Errorf("Migrations are supported only for STORAGE_DRIVER=%s, %s creates its schema on start", config.DBDriverPostgres, cfg.Driver)
+		return 2
+	}
+	if len(args) == 0 || len(args) > 2 {
+		log.Error("Usage: migrate up|down [N]|status")
+		return 2
 	}
-	defer storage.Close()
 
-	// Проверка подключения к БД
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	if err := storage.Ping(ctx); err != nil {
-		cancel()
-		log.Fatalf("Database ping failed: %v", err)
-	}
-	cancel()
-	log.Info("Database connection established")
-
-	// Подключение к gRPC exchanger service
-	exchangerClient, err := grpc.NewExchangerClient(
-		cfg.Exchanger.Host,
-		cfg.Exchanger.Port,
-		cfg.Exchanger.Timeout,
-		log,
-	)
+	storage, err := postgres.Open(&postgres.Config{
+		Host:            cfg.Host,
+		Port:            cfg.Port,
+		User:            cfg.User,
+		Password:        cfg.Password,
+		DBName:          cfg.DBName,
+		SSLMode:         cfg.SSLMode,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+	}, log)
 	if err != nil {
-		log.Fatalf("Failed to connect to exchanger service: %v", err)
-	}
-	defer exchangerClient.Close()
-
-	// Проверка подключения к exchanger service
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
-	if err := exchangerClient.Ping(ctx); err != nil {
-		cancel()
-		log.Warnf("Exchanger service ping failed: %v (service may be unavailable)", err)
-	} else {
-		cancel()
-		log.Info("Connected to exchanger service")
-	}
-
-	// Инициализация кеша курсов валют
-	ratesCache := cache.NewRatesCache(cfg.Cache.RatesTTL)
-	log.Info("Rates cache initialized")
-
-	// Инициализация Kafka producer
-	kafkaProducer := kafka.NewProducer(
-		cfg.Kafka.Brokers,
-		cfg.Kafka.Topic,
-		cfg.Kafka.TransferThreshold,
-		log,
-	)
-	defer kafkaProducer.Close()
-
-	// Создание сервисного слоя
-	walletService := service.NewWalletService(
-		storage,
-		exchangerClient,
-		ratesCache,
-		kafkaProducer,
-		log,
-	)
-	log.Info("Wallet service initialized")
-
-	// Создание JWT middleware
-	jwtMiddleware := middleware.NewJWTMiddleware(cfg.JWT.Secret, log)
-
-	// Настройка роутера
-	router := api.SetupRouter(walletService, jwtMiddleware, log, cfg.Server.GinMode)
-
-	// Создание HTTP сервера
-	srv := &http.Server{
-		Addr:         ":" + cfg.Server.HTTPPort,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-
-	// Graceful shutdown
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// Запуск HTTP сервера в горутине
-	go func() {
-		log.Infof("HTTP server is listening on port %s", cfg.Server.HTTPPort)
-		log.Infof("Swagger documentation available at: http://localhost:%s/swagger/index.html", cfg.Server.HTTPPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start HTTP server: %v", err)
+		log.Errorf("Failed to connect to database: %v", err)
+		return 1
+	}
+	defer storage.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		err = storage.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				log.Errorf("Invalid number of migrations to roll back: %s", args[1])
+				return 2
+			}
 		}
-	}()
+		err = storage.MigrateDown(ctx, steps)
+	case "status":
+		var status postgres.MigrationStatus
+		if status, err = storage.MigrationStatus(ctx); err == nil {
+			log.Infof("Schema version: %d, latest: %d, pending: %t, dirty: %t",
+				status.Version, status.Latest, status.Pending(), status.Dirty)
+		}
+	default:
+		log.Errorf("Unknown migrate command %q (expected up, down or status)", args[0])
+		return 2
+	}
+
+	if err != nil {
+		log.Errorf("Migration failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runArchive выгружает транзакции старше ARCHIVE_AFTER в S3 и удаляет их из БД.
+// Возвращает код выхода процесса
+func runArchive(cfg *config.Config, log *logrus.Logger) int {
+	if cfg.Archive.S3Endpoint == "" || cfg.Archive.S3Bucket == "" {
+		log.Error("ARCHIVE_S3_ENDPOINT and ARCHIVE_S3_BUCKET are required for archive")
+		return 2
+	}
+
+	store, err := archive.NewS3Store(archive.S3Config{
+		Endpoint:  cfg.Archive.S3Endpoint,
+		Region:    cfg.Archive.S3Region,
+		Bucket:    cfg.Archive.S3Bucket,
+		AccessKey: cfg.Archive.S3AccessKey,
+		SecretKey: cfg.Archive.S3SecretKey,
+		UseSSL:    cfg.Archive.S3UseSSL,
+	})
+	if err != nil {
+		log.Errorf("Invalid S3 settings: %v", err)
+		return 2
+	}
+
+	storage, err := app.NewStorage(&cfg.Database, log)
+	if err != nil {
+		log.Errorf("Failed to connect to database: %v", err)
+		return 1
+	}
+	defer storage.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := store.CheckBucket(ctx); err != nil {
+		log.Errorf("S3 storage is not available: %v", err)
+		return 1
+	}
+
+	before := time.Now().Add(-cfg.Archive.After)
+	log.Infof("Archiving transactions created before %s to s3://%s/%s", before.Format(time.RFC3339), cfg.Archive.S3Bucket, cfg.Archive.Prefix)
+
+	archiver := archive.New(storage, store, archive.Config{
+		Prefix:    cfg.Archive.Prefix,
+		BatchSize: cfg.Archive.BatchSize,
+		FileRows:  cfg.Archive.FileRows,
+	}, log)
+	result, err := archiver.Archive(ctx, before)
+	log.Infof("Archived %d transactions to %d files, deleted %d", result.Archived, result.Files, result.Deleted)
+	if err != nil {
+		log.Errorf("Archive failed: %v", err)
+		return 1
+	}
+	return 0
+}
 
-	// Ожидание сигнала завершения
-	<-done
-	log.Info("Shutting down server...")
+// runReconcile выполняет команду reconcile [rebuild]: сверяет балансы с суммой
+// записей журнала и с rebuild пересчитывает расходящиеся балансы из журнала.
+// Возвращает код выхода процесса: 1, если расхождения остались
+func runReconcile(cfg *config.DatabaseConfig, args []string, log *logrus.Logger) int {
+	rebuild := len(args) == 1 && args[0] == "rebuild"
+	if len(args) > 1 || (len(args) == 1 && !rebuild) {
+		log.Error("Usage: reconcile [rebuild]")
+		return 2
+	}
+
+	storage, err := app.NewStorage(cfg, log)
+	if err != nil {
+		log.Errorf("Failed to connect to database: %v", err)
+		return 1
+	}
+	defer storage.Close()
 
-	// Graceful shutdown с таймаутом
-	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Errorf("Server forced to shutdown: %v", err)
+	drift, err := storage.FindBalanceDrift(ctx)
+	if err != nil {
+		log.Errorf("Reconciliation failed: %v", err)
+		return 1
+	}
+	for _, v := range drift {
+		log.Warnf("Balance drift: user=%d currency=%s: %s", v.UserID, v.Currency, v.Details)
 	}
 
-	log.Info("Server stopped gracefully")
+	if len(drift) == 0 {
+		log.Info("Balances match ledger entries")
+		return 0
+	}
+	if !rebuild {
+		log.Errorf("Found %d balances that differ from ledger entries, run reconcile rebuild to fix them", len(drift))
+		return 1
+	}
+
+	rebuilt, err := storage.RebuildBalances(ctx)
+	if err != nil {
+		log.Errorf("Failed to rebuild balances: %v", err)
+		return 1
+	}
+	log.Infof("Rebuilt %d balances from ledger entries", rebuilt)
+	return 0
+}
+
+// runLedgerCheck проверяет инварианты учета и возвращает код выхода процесса.
+// Если задан repairPath и найдены нарушения, в файл записывается SQL для их
+// исправления; скрипт не применяется автоматически
+func runLedgerCheck(storage storages.Storage, repairPath string, log *logrus.Logger) int {
+	defer storage.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	violations, err := storage.FindLedgerViolations(ctx)
+	if err != nil {
+		log.Errorf("Ledger check failed: %v", err)
+		return 2
+	}
+
+	for _, v := range violations {
+		log.Warnf("Ledger violation: type=%s user=%d currency=%s tx=%d: %s",
+			v.Type, v.UserID, v.Currency, v.TransactionID, v.Details)
+	}
+
+	if len(violations) > 0 {
+		counts := make(map[string]int)
+		for _, v := range violations {
+			counts[v.Type]++
+		}
+		log.Errorf("Ledger check found %d violations: %v", len(violations), counts)
+
+		if repairPath != "" {
+			if err := os.WriteFile(repairPath, []byte(storages.RepairSQL(violations)), 0o600); err != nil {
+				log.Errorf("Failed to write repair SQL: %v", err)
+				return 2
+			}
+			log.Infof("Repair SQL written to %s, review it before applying", repairPath)
+		}
+		return 1
+	}
+
+	log.Info("Ledger check passed")
+	return 0
 }
@@ -0,0 +1,28 @@
+// walletctl - консольный клиент кошелька для проверок после выката и демонстраций.
+//
+//	walletctl -url http://localhost:8080 login -u alice -password-stdin
+//	walletctl deposit 100 USD
+//	walletctl exchange 50 USD EUR
+//	walletctl transactions -limit 10
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gw-currency-wallet/internal/walletctl"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := walletctl.Run(ctx, walletctl.Env{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
+	}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,964 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/grpc"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Параметры пагинации списков
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// AdminHandler обработчик административных операций
+type AdminHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewAdminHandler создает новый обработчик административных операций
+func NewAdminHandler(service *service.WalletService, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// UserResponse информация о пользователе для администраторов
+type UserResponse struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	// Status действующий статус учетной записи: active, frozen, closed
+	Status       string     `json:"status"`
+	FrozenUntil  *time.Time `json:"frozen_until,omitempty"`
+	StatusReason string     `json:"status_reason,omitempty"`
+	// VerificationLevel уровень верификации: unverified, basic, full
+	VerificationLevel string `json:"verification_level"`
+}
+
+// UsersListResponse страница списка пользователей
+type UsersListResponse struct {
+	Users []UserResponse `json:"users"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+}
+
+// LedgerCheckResponse результат проверки инвариантов учета
+type LedgerCheckResponse struct {
+	CheckedAt  time.Time                  `json:"checked_at"`
+	OK         bool                       `json:"ok"`
+	Violations []storages.LedgerViolation `json:"violations"`
+	// RepairSQL SQL для исправления расхождений балансов (при repair_sql=true)
+	RepairSQL string `json:"repair_sql,omitempty"`
+}
+
+// LedgerEntriesResponse записи журнала балансов пользователя
+type LedgerEntriesResponse struct {
+	Entries []storages.LedgerEntry `json:"entries"`
+}
+
+// UserBalancesResponse пользователь и его балансы
+type UserBalancesResponse struct {
+	User    UserResponse          `json:"user"`
+	Balance storages.UserBalances `json:"balance"`
+}
+
+// LimitsResponse лимиты пользователя
+type LimitsResponse struct {
+	Limits []storages.Limit `json:"limits"`
+}
+
+// CallerPairsRequest список пар валют, разрешенных вызывающей стороне exchanger
+type CallerPairsRequest struct {
+	Pairs []grpc.CurrencyPair `json:"pairs" binding:"dive"`
+}
+
+// CallerPairsResponse пары валют вызывающей стороны; пустой список - без ограничений
+type CallerPairsResponse struct {
+	Caller string              `json:"caller"`
+	Pairs  []grpc.CurrencyPair `json:"pairs"`
+}
+
+// SetLimitRequest запрос на установку лимита пользователя
+type SetLimitRequest struct {
+	Operation string  `json:"operation" binding:"required,oneof=withdraw exchange"`
+	Currency  string  `json:"currency" binding:"required,len=3"`
+	Period    string  `json:"period" binding:"required,oneof=daily monthly"`
+	Amount    float64 `json:"amount" binding:"gte=0"`
+}
+
+// UnfreezeUserRequest запрос на снятие заморозки пользователя
+type UnfreezeUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ChangeUserStatusRequest запрос на изменение статуса учетной записи. Until -
+// окончание заморозки, без него заморозка действует до снятия
+type ChangeUserStatusRequest struct {
+	Status string     `json:"status" binding:"required,oneof=active frozen closed"`
+	Reason string     `json:"reason" binding:"required,max=500"`
+	Until  *time.Time `json:"until"`
+}
+
+// StatusHistoryResponse история изменений статуса учетной записи
+type StatusHistoryResponse struct {
+	Events []storages.AccountStatusEvent `json:"events"`
+}
+
+// ReviewVerificationRequest решение администратора по заявке на верификацию
+type ReviewVerificationRequest struct {
+	// Comment комментарий, обязателен при отклонении и виден пользователю
+	Comment string `json:"comment" binding:"max=500"`
+}
+
+// VerificationRequestsResponse список заявок на верификацию
+type VerificationRequestsResponse struct {
+	Requests []storages.VerificationRequest `json:"requests"`
+}
+
+// SetAPIKeyRateLimitRequest лимит запросов ключа API: rate запросов в секунду,
+// не больше burst подряд; rate 0 - ключ расходует лимит пользователя
+type SetAPIKeyRateLimitRequest struct {
+	Rate  float64 `json:"rate" binding:"gte=0"`
+	Burst int     `json:"burst" binding:"gte=0"`
+}
+
+// ListUsers возвращает список пользователей
+// @Summary List users
+// @Description Paginated list of users, searchable by username or email (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param search query string false "Substring of username or email"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} UsersListResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.Error(middleware.InvalidRequest("Invalid page"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	users, total, err := h.service.ListUsers(c.Request.Context(), c.Query("search"), page, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to list users: %v", err)
+		c.Error(err)
+		return
+	}
+
+	response := UsersListResponse{
+		Users: make([]UserResponse, 0, len(users)),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	for _, user := range users {
+		response.Users = append(response.Users, newUserResponse(&user))
+	}
+
+	respondList(c, response, response.Users, Meta{Page: page, Limit: limit, Total: total})
+}
+
+// GetUserBalances возвращает балансы пользователя
+// @Summary Get user balances
+// @Description Get balances of any user by ID (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} UserBalancesResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/balances [get]
+func (h *AdminHandler) GetUserBalances(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid user id"))
+		return
+	}
+
+	user, err := h.service.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	balances, err := h.service.GetUserBalances(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to get balances for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, UserBalancesResponse{
+		User:    newUserResponse(user),
+		Balance: balances,
+	})
+}
+
+// ExchangeForUser обменивает валюту на счете пользователя от имени администратора
+// @Summary Exchange currency for user
+// @Description Exchange currency on behalf of a user; the admin exchange margin is applied (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body ExchangeRequest true "Exchange data"
+// @Success 200 {object} ExchangeResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 422 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/exchange [post]
+func (h *AdminHandler) ExchangeForUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid user id"))
+		return
+	}
+
+	var req ExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	if _, err := h.service.GetUser(c.Request.Context(), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	exchangedAmount, fee, newBalances, err := h.service.ExchangeCurrency(
+		c.Request.Context(),
+		userID,
+		req.FromCurrency,
+		req.ToCurrency,
+		req.Amount,
+		storages.ExchangeSourceAdmin,
+	)
+	if err != nil {
+		h.logger.Errorf("Failed to exchange currency for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, ExchangeResponse{
+		Message:         "Exchange successful",
+		ExchangedAmount: exchangedAmount,
+		Fee:             fee,
+		FeeCurrency:     req.FromCurrency,
+		NewBalance:      newBalances,
+	})
+}
+
+// CheckLedger проверяет инварианты учета по всем пользователям
+// @Summary Check ledger invariants
+// @Description Verify that balances match completed transactions and ledger entries, no balance is negative and no transaction is orphaned (admin only).
+// @Description With repair_sql=true the response contains SQL repairing balance discrepancies; it is not applied automatically
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param repair_sql query bool false "Include repair SQL"
+// @Success 200 {object} LedgerCheckResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/ledger/check [get]
+func (h *AdminHandler) CheckLedger(c *gin.Context) {
+	withRepair, err := strconv.ParseBool(c.DefaultQuery("repair_sql", "false"))
+	if err != nil {
+		c.Error(middleware.InvalidRequest("Invalid repair_sql"))
+		return
+	}
+
+	violations, err := h.service.CheckLedger(c.Request.Context())
+	if err != nil {
+		h.logger.Errorf("Failed to check ledger: %v", err)
+		c.Error(err)
+		return
+	}
+
+	if violations == nil {
+		violations = []storages.LedgerViolation{}
+	}
+
+	response := LedgerCheckResponse{
+		CheckedAt:  time.Now().UTC(),
+		OK:         len(violations) == 0,
+		Violations: violations,
+	}
+	if withRepair && len(violations) > 0 {
+		response.RepairSQL = storages.RepairSQL(violations)
+	}
+
+	respond(c, http.StatusOK, response)
+}
+
+// GetUserLedger возвращает журнал изменений балансов пользователя
+// @Summary Get user ledger entries
+// @Description Latest balance ledger entries of a user, newest first; a balance equals the sum of its entries (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param currency query string false "Currency code"
+// @Param limit query int false "Number of entries (default 20, max 100)"
+// @Success 200 {object} LedgerEntriesResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/ledger [get]
+func (h *AdminHandler) GetUserLedger(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	entries, err := h.service.GetLedgerEntries(c.Request.Context(), userID, c.Query("currency"), limit)
+	if err != nil {
+		h.logger.Errorf("Failed to get ledger entries for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, LedgerEntriesResponse{Entries: entries}, entries, Meta{Limit: limit, Total: int64(len(entries))})
+}
+
+// GetUserLimits возвращает лимиты пользователя
+// @Summary Get user limits
+// @Description Daily and monthly withdrawal and exchange limits of a user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} LimitsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/limits [get]
+func (h *AdminHandler) GetUserLimits(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	limits, err := h.service.GetUserLimits(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to get limits for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	if limits == nil {
+		limits = []storages.Limit{}
+	}
+
+	respondList(c, LimitsResponse{Limits: limits}, limits, Meta{Total: int64(len(limits))})
+}
+
+// SetUserLimit создает или обновляет лимит пользователя
+// @Summary Set user limit
+// @Description Create or update a daily or monthly withdrawal or exchange limit in the debited currency (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body SetLimitRequest true "Limit"
+// @Success 200 {object} storages.Limit
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/limits [put]
+func (h *AdminHandler) SetUserLimit(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	limit := &storages.Limit{
+		UserID:    userID,
+		Operation: req.Operation,
+		Currency:  req.Currency,
+		Period:    req.Period,
+		Amount:    req.Amount,
+	}
+	if err := h.service.SetUserLimit(c.Request.Context(), limit); err != nil {
+		h.logger.Errorf("Failed to set limit for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, limit)
+}
+
+// DeleteUserLimit удаляет лимит пользователя
+// @Summary Delete user limit
+// @Description Remove a withdrawal or exchange limit of a user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param operation path string true "withdraw or exchange"
+// @Param currency path string true "Currency code"
+// @Param period path string true "daily or monthly"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/limits/{operation}/{currency}/{period} [delete]
+func (h *AdminHandler) DeleteUserLimit(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteUserLimit(c.Request.Context(), userID, c.Param("operation"), c.Param("currency"), c.Param("period"))
+	if err != nil {
+		h.logger.Warnf("Failed to delete limit for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "Limit deleted")
+}
+
+// UnfreezeUser снимает заморозку выводов и обменов пользователя
+// @Summary Unfreeze user
+// @Description Lift a freeze of withdrawals and exchanges, e.g. set after a suspicious activity alert of gw-notification; older freeze requests are ignored afterwards (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body UnfreezeUserRequest true "Unfreeze reason"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/unfreeze [post]
+func (h *AdminHandler) UnfreezeUser(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UnfreezeUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	user, err := h.service.UnfreezeUser(c.Request.Context(), userID, req.Reason, adminID)
+	if err != nil {
+		h.logger.Errorf("Failed to unfreeze user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, newUserResponse(user))
+}
+
+// ChangeUserStatus изменяет статус учетной записи пользователя
+// @Summary Change user status
+// @Description Set account status with an audit reason (admin only). frozen blocks withdrawals and exchanges until the optional until time, closed blocks all money operations and login, cancels pending withdrawals and deactivates schedules, active lifts both
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body ChangeUserStatusRequest true "New status and reason"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/status [put]
+func (h *AdminHandler) ChangeUserStatus(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ChangeUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	user, err := h.service.ChangeUserStatus(c.Request.Context(), userID, req.Status, req.Reason, req.Until, adminID)
+	if err != nil {
+		h.logger.Errorf("Failed to change status of user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	h.logger.Infof("Admin %d changed status of user %d to %s", adminID, userID, req.Status)
+	respond(c, http.StatusOK, newUserResponse(user))
+}
+
+// GetUserStatusHistory возвращает историю изменений статуса пользователя
+// @Summary Get user status history
+// @Description Latest account status changes with reasons and admin IDs, newest first; changed_by 0 means an automatic change (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Number of events (default 20, max 100)"
+// @Success 200 {object} StatusHistoryResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/status-history [get]
+func (h *AdminHandler) GetUserStatusHistory(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultStatusHistoryLimit)))
+	if err != nil || limit < 1 || limit > service.MaxStatusHistoryLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	events, err := h.service.GetAccountStatusHistory(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to get status history of user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, StatusHistoryResponse{Events: events}, events, Meta{Limit: limit, Total: int64(len(events))})
+}
+
+// ListVerificationRequests возвращает заявки на верификацию
+// @Summary List verification requests
+// @Description Verification requests of all users, newest first (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, approved or rejected (default pending)"
+// @Param limit query int false "Number of requests (default 20, max 100)"
+// @Success 200 {object} VerificationRequestsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/verifications [get]
+func (h *AdminHandler) ListVerificationRequests(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultVerificationRequestsLimit)))
+	if err != nil || limit < 1 || limit > service.MaxVerificationRequestsLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	status := c.DefaultQuery("status", storages.VerificationStatusPending)
+	requests, err := h.service.ListVerificationRequests(c.Request.Context(), status, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to list verification requests: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, VerificationRequestsResponse{Requests: requests}, requests, Meta{Limit: limit, Total: int64(len(requests))})
+}
+
+// ApproveVerification одобряет заявку на верификацию
+// @Summary Approve verification
+// @Description Approve a pending verification request and raise the verification level of the user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Verification request ID"
+// @Param request body ReviewVerificationRequest false "Review comment"
+// @Success 200 {object} storages.VerificationRequest
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/verifications/{id}/approve [post]
+func (h *AdminHandler) ApproveVerification(c *gin.Context) {
+	h.reviewVerification(c, h.service.ApproveVerification)
+}
+
+// RejectVerification отклоняет заявку на верификацию
+// @Summary Reject verification
+// @Description Reject a pending verification request; the comment is required and shown to the user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Verification request ID"
+// @Param request body ReviewVerificationRequest true "Rejection reason"
+// @Success 200 {object} storages.VerificationRequest
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/verifications/{id}/reject [post]
+func (h *AdminHandler) RejectVerification(c *gin.Context) {
+	h.reviewVerification(c, h.service.RejectVerification)
+}
+
+// reviewVerification записывает решение администратора по заявке
+func (h *AdminHandler) reviewVerification(c *gin.Context, review func(ctx context.Context, requestID, adminID int64, comment string) (*storages.VerificationRequest, error)) {
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || requestID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid verification request id"))
+		return
+	}
+
+	// Тело необязательно при одобрении
+	var req ReviewVerificationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(middleware.BindingError(err))
+			return
+		}
+	}
+
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	request, err := review(c.Request.Context(), requestID, adminID, req.Comment)
+	if err != nil {
+		h.logger.Errorf("Failed to review verification request %d: %v", requestID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, request)
+}
+
+// GetUserAPIKeys возвращает действующие ключи API пользователя
+// @Summary Get user API keys
+// @Description Active API keys of a user with their rate limits (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} APIKeysResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/api-keys [get]
+func (h *AdminHandler) GetUserAPIKeys(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list API keys of user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, APIKeysResponse{APIKeys: keys}, keys, Meta{Total: int64(len(keys))})
+}
+
+// SetAPIKeyRateLimit задает лимит запросов ключа API пользователя
+// @Summary Set API key rate limit
+// @Description Give a partner API key its own rate limit instead of the user's one (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param key_id path int true "API key ID"
+// @Param request body SetAPIKeyRateLimitRequest true "Rate limit"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/api-keys/{key_id}/rate-limit [put]
+func (h *AdminHandler) SetAPIKeyRateLimit(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	keyID, ok := parseAPIKeyID(c, "key_id")
+	if !ok {
+		return
+	}
+
+	var req SetAPIKeyRateLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	if err := h.service.SetAPIKeyRateLimit(c.Request.Context(), userID, keyID, req.Rate, req.Burst); err != nil {
+		h.logger.Warnf("Failed to set rate limit of API key %d: %v", keyID, err)
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "API key rate limit updated")
+}
+
+// ListPendingWithdrawals возвращает ожидающие подтверждения выводы
+// @Summary List pending withdrawals
+// @Description Withdrawals waiting for approval, oldest first, optionally of one user (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "User ID"
+// @Param limit query int false "Number of withdrawals (default 20, max 100)"
+// @Success 200 {object} WithdrawalsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/withdrawals/pending [get]
+func (h *AdminHandler) ListPendingWithdrawals(c *gin.Context) {
+	var userID int64
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			c.Error(middleware.InvalidRequest("Invalid user_id"))
+			return
+		}
+		userID = id
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	withdrawals, err := h.service.ListPendingWithdrawals(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to list pending withdrawals: %v", err)
+		c.Error(err)
+		return
+	}
+
+	response := newWithdrawalsResponse(withdrawals)
+	respondList(c, response, response.Withdrawals, Meta{Limit: limit, Total: int64(len(response.Withdrawals))})
+}
+
+// ApproveWithdrawal подтверждает ожидающий вывод
+// @Summary Approve pending withdrawal
+// @Description Complete a withdrawal waiting for approval: the hold is released and the amount and fee are debited (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Withdrawal transaction ID"
+// @Success 200 {object} WithdrawalResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/withdrawals/{id}/approve [post]
+func (h *AdminHandler) ApproveWithdrawal(c *gin.Context) {
+	txID, ok := parseWithdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.ApproveWithdrawal(c.Request.Context(), txID)
+	if err != nil {
+		h.logger.Errorf("Failed to approve withdrawal %d: %v", txID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, newWithdrawalResponse(withdrawal))
+}
+
+// RejectWithdrawal отклоняет ожидающий вывод
+// @Summary Reject pending withdrawal
+// @Description Mark a withdrawal waiting for approval as failed and release the held amount and fee (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Withdrawal transaction ID"
+// @Success 200 {object} WithdrawalResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/withdrawals/{id}/reject [post]
+func (h *AdminHandler) RejectWithdrawal(c *gin.Context) {
+	txID, ok := parseWithdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.RejectWithdrawal(c.Request.Context(), txID)
+	if err != nil {
+		h.logger.Errorf("Failed to reject withdrawal %d: %v", txID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, newWithdrawalResponse(withdrawal))
+}
+
+// parseUserID разбирает ID пользователя из пути и проверяет, что пользователь существует.
+// При ошибке отвечает клиенту и возвращает false
+func (h *AdminHandler) parseUserID(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid user id"))
+		return 0, false
+	}
+
+	if _, err := h.service.GetUser(c.Request.Context(), userID); err != nil {
+		c.Error(err)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// GetExchangerCallerPairs возвращает пары валют, разрешенные вызывающей стороне exchanger
+// @Summary Get exchanger caller pairs
+// @Description Currency pairs an exchanger caller (API token) may query or convert; empty list means no restriction (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param caller path string true "Caller name from exchanger API_TOKENS"
+// @Success 200 {object} CallerPairsResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 502 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/exchanger/callers/{caller}/pairs [get]
+func (h *AdminHandler) GetExchangerCallerPairs(c *gin.Context) {
+	caller := c.Param("caller")
+
+	pairs, err := h.service.GetExchangerCallerPairs(c.Request.Context(), caller)
+	if err != nil {
+		h.logger.Errorf("Failed to get exchanger pairs for caller %s: %v", caller, err)
+		c.Error(middleware.ExchangerError(err, "Failed to get caller pairs"))
+		return
+	}
+
+	respond(c, http.StatusOK, CallerPairsResponse{Caller: caller, Pairs: pairs})
+}
+
+// SetExchangerCallerPairs заменяет список пар валют, разрешенных вызывающей стороне exchanger
+// @Summary Set exchanger caller pairs
+// @Description Replace currency pairs an exchanger caller may query or convert; an empty list removes the restriction (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param caller path string true "Caller name from exchanger API_TOKENS"
+// @Param request body CallerPairsRequest true "Allowed pairs"
+// @Success 200 {object} CallerPairsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 502 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/exchanger/callers/{caller}/pairs [put]
+func (h *AdminHandler) SetExchangerCallerPairs(c *gin.Context) {
+	caller := c.Param("caller")
+
+	var req CallerPairsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	pairs, err := h.service.SetExchangerCallerPairs(c.Request.Context(), caller, req.Pairs)
+	if err != nil {
+		h.logger.Errorf("Failed to set exchanger pairs for caller %s: %v", caller, err)
+		c.Error(middleware.ExchangerError(err, "Failed to set caller pairs"))
+		return
+	}
+
+	respond(c, http.StatusOK, CallerPairsResponse{Caller: caller, Pairs: pairs})
+}
+
+// newUserResponse преобразует пользователя в ответ без чувствительных данных.
+// Истекшая временная заморозка показывается как active
+func newUserResponse(user *storages.User) UserResponse {
+	response := UserResponse{
+		ID:                user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		Role:              user.Role,
+		CreatedAt:         user.CreatedAt,
+		Status:            storages.AccountStatusActive,
+		VerificationLevel: user.VerificationLevel,
+	}
+	switch {
+	case user.Status == storages.AccountStatusClosed:
+		response.Status = storages.AccountStatusClosed
+		response.StatusReason = user.StatusReason
+	case user.Frozen(time.Now().UTC()):
+		response.Status = storages.AccountStatusFrozen
+		response.FrozenUntil = user.FrozenUntil
+		response.StatusReason = user.StatusReason
+	}
+	return response
+}
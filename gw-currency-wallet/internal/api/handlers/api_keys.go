@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// APIKeyHandler обработчик ключей API пользователя
+type APIKeyHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewAPIKeyHandler создает обработчик ключей API
+func NewAPIKeyHandler(service *service.WalletService, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateAPIKeyRequest запрос на создание ключа API
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// Access read_only - балансы, курсы и история; trade - также обмен валют
+	Access string `json:"access" binding:"required,oneof=read_only trade"`
+}
+
+// CreateAPIKeyResponse созданный ключ API. Key возвращается только в этом ответе
+type CreateAPIKeyResponse struct {
+	Key    string          `json:"key"`
+	APIKey storages.APIKey `json:"api_key"`
+}
+
+// APIKeysResponse список действующих ключей API
+type APIKeysResponse struct {
+	APIKeys []storages.APIKey `json:"api_keys"`
+}
+
+// ListAPIKeys возвращает действующие ключи API пользователя
+// @Summary List API keys
+// @Description Active API keys of the user. Key values are not returned
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} APIKeysResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list API keys: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, APIKeysResponse{APIKeys: keys}, keys, Meta{Total: int64(len(keys))})
+}
+
+// CreateAPIKey создает ключ API для внешней системы
+// @Summary Create API key
+// @Description Create a key for partner systems, sent in the X-API-Key header instead of a JWT.
+// @Description The key is returned only once. Keys cannot withdraw funds or manage keys
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "Key name and access level"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	scopes, ok := middleware.ScopesForAPIKeyAccess(req.Access)
+	if !ok {
+		c.Error(middleware.InvalidRequest("Invalid access: " + req.Access))
+		return
+	}
+
+	key, secret, err := h.service.CreateAPIKey(c.Request.Context(), userID, req.Name, scopes)
+	if err != nil {
+		h.logger.Errorf("Failed to create API key: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, CreateAPIKeyResponse{Key: secret, APIKey: *key})
+}
+
+// RevokeAPIKey отзывает ключ API пользователя
+// @Summary Revoke API key
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	keyID, ok := parseAPIKeyID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(c.Request.Context(), userID, keyID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "API key revoked")
+}
+
+// parseAPIKeyID разбирает ID ключа API из параметра пути param. При ошибке ответ уже сформирован
+func parseAPIKeyID(c *gin.Context, param string) (int64, bool) {
+	keyID, err := strconv.ParseInt(c.Param(param), 10, 64)
+	if err != nil || keyID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid API key id"))
+		return 0, false
+	}
+	return keyID, true
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"gw-currency-wallet/internal/api/middleware"
 	"gw-currency-wallet/internal/service"
-	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/storages"
 )
 
 // AuthHandler обработчик для аутентификации
@@ -29,7 +32,7 @@ func NewAuthHandler(service *service.WalletService, jwtMiddleware *middleware.JW
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // проверяется политикой паролей
 }
 
 // LoginRequest запрос на авторизацию
@@ -38,6 +41,28 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// RefreshRequest запрос на обновление access токена
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse access токен и, при входе, refresh токен
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// ChangePasswordRequest запрос на смену пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// DeleteAccountRequest подтверждение удаления учетной записи паролем
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 // Register регистрирует нового пользователя
 // @Summary Register a new user
 // @Description Register a new user with username, email and password
@@ -46,27 +71,27 @@ type LoginRequest struct {
 // @Produce json
 // @Param request body RegisterRequest true "Registration data"
 // @Success 201 {object} map[string]string
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
 // @Router /api/v1/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		c.Error(middleware.BindingError(err))
 		return
 	}
 
 	// Регистрируем пользователя
 	if err := h.service.RegisterUser(c.Request.Context(), req.Username, req.Email, req.Password); err != nil {
-		if err.Error() == "username already exists" || err.Error() == "email already exists" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+		if !errors.Is(err, service.ErrUserExists) && !errors.Is(err, service.ErrInvalidArgument) && !errors.Is(err, service.ErrWeakPassword) {
+			h.logger.Errorf("Failed to register user: %v", err)
 		}
-		h.logger.Errorf("Failed to register user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	respondMessage(c, http.StatusCreated, "User registered successfully")
 }
 
 // Login авторизует пользователя
@@ -76,30 +101,191 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login credentials"
-// @Success 200 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
 // @Router /api/v1/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		c.Error(middleware.BindingError(err))
 		return
 	}
 
 	// Аутентифицируем пользователя
 	user, err := h.service.AuthenticateUser(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		c.Error(err)
+		return
+	}
+
+	// Каждый вход создает сессию, токены сессии действуют до ее отзыва
+	session, err := h.service.StartSession(c.Request.Context(), user.ID, c.Request.UserAgent(), c.ClientIP(), time.Now().Add(h.jwtMiddleware.RefreshTTL()))
+	if err != nil {
+		h.logger.Errorf("Failed to start session: %v", err)
+		c.Error(err)
+		return
+	}
+
+	// Генерируем access и refresh токены
+	token, err := h.jwtMiddleware.GenerateSessionToken(user.ID, user.Username, user.Role, middleware.TokenTypeAccess, session.ID)
+	if err != nil {
+		h.logger.Errorf("Failed to generate token: %v", err)
+		c.Error(err)
+		return
+	}
+
+	refreshToken, err := h.jwtMiddleware.GenerateSessionToken(user.ID, user.Username, user.Role, middleware.TokenTypeRefresh, session.ID)
+	if err != nil {
+		h.logger.Errorf("Failed to generate refresh token: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, TokenResponse{Token: token, RefreshToken: refreshToken})
+}
+
+// Refresh выдает новый access токен по refresh токену
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token with the user's current role
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
+// @Router /api/v1/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	claims, err := h.jwtMiddleware.ParseToken(req.RefreshToken)
+	if err != nil || claims.TokenType != middleware.TokenTypeRefresh {
+		c.Error(middleware.Unauthorized("Invalid refresh token"))
+		return
+	}
+
+	// Роль берется из БД, чтобы изменения ролей применялись при обновлении токена
+	user, err := h.service.GetUser(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Invalid refresh token"))
+		return
+	}
+	if user.Status == storages.AccountStatusClosed {
+		c.Error(service.ErrAccountClosed)
 		return
 	}
 
-	// Генерируем JWT токен
-	token, err := h.jwtMiddleware.GenerateToken(user.ID, user.Username, 24*3600*1000000000) // 24 hours
+	// Refresh токены, выданные до появления сессий, действуют до истечения срока
+	if claims.SessionID != 0 {
+		if err := h.service.RefreshSession(c.Request.Context(), user.ID, claims.SessionID); err != nil {
+			if !errors.Is(err, storages.ErrNotFound) {
+				h.logger.Errorf("Failed to refresh session: %v", err)
+			}
+			c.Error(middleware.Unauthorized("Invalid refresh token"))
+			return
+		}
+	}
+
+	token, err := h.jwtMiddleware.GenerateSessionToken(user.ID, user.Username, user.Role, middleware.TokenTypeAccess, claims.SessionID)
 	if err != nil {
 		h.logger.Errorf("Failed to generate token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, TokenResponse{Token: token})
+}
+
+// JWKS возвращает открытые ключи проверки токенов для других сервисов
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens signed with RS256 or EdDSA: the current key and previous keys accepted during key rotation, matched by the kid token header. Empty for HS256
+// @Tags auth
+// @Produce json
+// @Success 200 {object} middleware.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Ключи меняются только при перезапуске, сервисы могут кешировать набор
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtMiddleware.JWKS())
+}
+
+// ChangePassword меняет пароль пользователя
+// @Summary Change password
+// @Description Change the password of the user. The current password is required, the new one must meet the password policy. Other sessions of the user are revoked
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/user/password [put]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	// Остальные сессии пользователя отзываются, текущая продолжает действовать
+	sessionID, _ := middleware.GetSessionID(c)
+	if err := h.service.ChangePassword(c.Request.Context(), userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		h.logger.Warnf("Failed to change password of user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "Password changed")
+}
+
+// DeleteAccount удаляет учетную запись пользователя
+// @Summary Delete account
+// @Description Close the account of the user and anonymize username and email. Transactions and ledger entries are kept for audit. All balances, including funds held by pending withdrawals, must be zero
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body DeleteAccountRequest true "Password confirmation"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/user [delete]
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	if err := h.service.DeleteAccount(c.Request.Context(), userID, req.Password); err != nil {
+		h.logger.Warnf("Failed to delete account of user %d: %v", userID, err)
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	respondMessage(c, http.StatusOK, "Account deleted")
 }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/config"
+)
+
+// ConfigReloader перечитывает конфигурацию сервиса без перезапуска (config.Reloader)
+type ConfigReloader interface {
+	Reload() (config.ReloadResult, error)
+}
+
+// ConfigHandler обработчик перечитывания конфигурации
+type ConfigHandler struct {
+	reloader ConfigReloader
+	logger   *logrus.Logger
+}
+
+// NewConfigHandler создает новый обработчик перечитывания конфигурации
+func NewConfigHandler(reloader ConfigReloader, logger *logrus.Logger) *ConfigHandler {
+	return &ConfigHandler{
+		reloader: reloader,
+		logger:   logger,
+	}
+}
+
+// Reload перечитывает конфигурацию, как SIGHUP
+// @Summary Reload config
+// @Description Re-reads the config file and environment and applies LOG_LEVEL, CACHE_RATES_TTL, KAFKA_TRANSFER_THRESHOLD, KAFKA_THRESHOLD_OVERRIDES and RATE_LIMIT_*_RPS/BURST without a restart. Other changed sections are listed in restart_required. An invalid config leaves the current settings in place (admin only)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} config.ReloadResult
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/config/reload [post]
+func (h *ConfigHandler) Reload(c *gin.Context) {
+	result, err := h.reloader.Reload()
+	if err != nil {
+		h.logger.Errorf("Failed to reload config: %v", err)
+		// Ответ только администраторам: причина нужна для исправления конфигурации
+		c.Error(middleware.NewAPIError(http.StatusInternalServerError, middleware.CodeInternal, "Failed to reload config: "+err.Error()))
+		return
+	}
+
+	respond(c, http.StatusOK, result)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"gw-currency-wallet/internal/api/middleware"
 	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,32 +26,62 @@ func NewExchangeHandler(service *service.WalletService, logger *logrus.Logger) *
 
 // ExchangeRequest запрос на обмен валюты
 type ExchangeRequest struct {
-	FromCurrency string  `json:"from_currency" binding:"required,oneof=USD EUR RUB"`
-	ToCurrency   string  `json:"to_currency" binding:"required,oneof=USD EUR RUB"`
+	FromCurrency string  `json:"from_currency" binding:"required"`
+	ToCurrency   string  `json:"to_currency" binding:"required"`
 	Amount       float64 `json:"amount" binding:"required,gt=0"`
 }
 
+// RatesResponse курсы валют по парам FROM_TO
+type RatesResponse struct {
+	Rates map[string]float32 `json:"rates"`
+}
+
+// CurrenciesResponse коды поддерживаемых валют
+type CurrenciesResponse struct {
+	Currencies []string `json:"currencies"`
+}
+
+// ExchangeResponse результат обмена
+type ExchangeResponse struct {
+	Message         string                `json:"message"`
+	ExchangedAmount float64               `json:"exchanged_amount"`
+	Fee             float64               `json:"fee"`
+	FeeCurrency     string                `json:"fee_currency"`
+	NewBalance      storages.UserBalances `json:"new_balance"`
+}
+
+// RebalanceRequest запрос на ребалансировку портфеля
+type RebalanceRequest struct {
+	// Targets целевые доли валют в процентах, сумма должна быть 100
+	Targets map[string]float64 `json:"targets" binding:"required"`
+	// BaseCurrency валюта, в которой оценивается портфель; по умолчанию первая по алфавиту из targets
+	BaseCurrency string `json:"base_currency"`
+	// DryRun только рассчитать план, не выполняя обмены
+	DryRun bool `json:"dry_run"`
+}
+
 // GetRates возвращает курсы валют
 // @Summary Get exchange rates
 // @Description Get current exchange rates for all currency pairs
 // @Tags exchange
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} RatesResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
 // @Router /api/v1/exchange/rates [get]
 func (h *ExchangeHandler) GetRates(c *gin.Context) {
 	_, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(middleware.Unauthorized("Unauthorized"))
 		return
 	}
 
 	rates, err := h.service.GetExchangeRates(c.Request.Context())
 	if err != nil {
 		h.logger.Errorf("Failed to get exchange rates: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		c.Error(err)
 		return
 	}
 
@@ -60,7 +91,35 @@ func (h *ExchangeHandler) GetRates(c *gin.Context) {
 		formattedRates[key] = value
 	}
 
-	c.JSON(http.StatusOK, gin.H{"rates": formattedRates})
+	respond(c, http.StatusOK, RatesResponse{Rates: formattedRates})
+}
+
+// GetCurrencies возвращает список поддерживаемых валют
+// @Summary Get supported currencies
+// @Description Get currency codes supported by the exchanger service
+// @Tags exchange
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} CurrenciesResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Router /api/v1/exchange/currencies [get]
+func (h *ExchangeHandler) GetCurrencies(c *gin.Context) {
+	_, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	currencies, err := h.service.GetSupportedCurrencies(c.Request.Context())
+	if err != nil {
+		h.logger.Errorf("Failed to get supported currencies: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, CurrenciesResponse{Currencies: currencies}, currencies, Meta{Total: int64(len(currencies))})
 }
 
 // Exchange обменивает валюту
@@ -68,49 +127,87 @@ func (h *ExchangeHandler) GetRates(c *gin.Context) {
 // @Description Exchange one currency for another
 // @Tags exchange
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Accept json
 // @Produce json
 // @Param request body ExchangeRequest true "Exchange data"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Success 200 {object} ExchangeResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 422 {object} middleware.ErrorResponse
+// @Failure 503 {object} middleware.ErrorResponse
 // @Router /api/v1/exchange [post]
 func (h *ExchangeHandler) Exchange(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(middleware.Unauthorized("Unauthorized"))
 		return
 	}
 
 	var req ExchangeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		c.Error(middleware.BindingError(err))
 		return
 	}
 
-	// Проверка, что валюты разные
-	if req.FromCurrency == req.ToCurrency {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "from_currency and to_currency must be different"})
-		return
-	}
-
-	exchangedAmount, newBalances, err := h.service.ExchangeCurrency(
+	exchangedAmount, fee, newBalances, err := h.service.ExchangeCurrency(
 		c.Request.Context(),
 		userID,
 		req.FromCurrency,
 		req.ToCurrency,
 		req.Amount,
+		storages.ExchangeSourceAPI,
 	)
 
 	if err != nil {
 		h.logger.Errorf("Failed to exchange currency: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":          "Exchange successful",
-		"exchanged_amount": exchangedAmount,
-		"new_balance":      newBalances,
+	respond(c, http.StatusOK, ExchangeResponse{
+		Message:         "Exchange successful",
+		ExchangedAmount: exchangedAmount,
+		Fee:             fee,
+		FeeCurrency:     req.FromCurrency,
+		NewBalance:      newBalances,
 	})
 }
+
+// Rebalance приводит балансы пользователя к целевому распределению
+// @Summary Rebalance portfolio
+// @Description Compute and atomically execute the minimal set of exchanges that brings balances to the target percentage allocation. Currencies with a balance that are not listed in targets are sold. With dry_run only the plan is returned
+// @Tags exchange
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body RebalanceRequest true "Target allocation"
+// @Success 200 {object} service.RebalanceResult
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 422 {object} middleware.ErrorResponse
+// @Failure 503 {object} middleware.ErrorResponse
+// @Router /api/v1/exchange/rebalance [post]
+func (h *ExchangeHandler) Rebalance(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req RebalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	result, err := h.service.RebalancePortfolio(c.Request.Context(), userID, req.Targets, req.BaseCurrency, req.DryRun)
+	if err != nil {
+		h.logger.Errorf("Failed to rebalance portfolio: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, result)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/graphql"
+	"gw-currency-wallet/internal/service"
+)
+
+// GraphQLConfig параметры эндпоинта /api/v1/graphql
+type GraphQLConfig struct {
+	Enabled bool
+	// MaxDepth максимальная вложенность полей запроса
+	MaxDepth int
+}
+
+// GraphQLHandler выполняет запросы GraphQL к балансам, транзакциям и курсам
+type GraphQLHandler struct {
+	schema *graphqlgo.Schema
+	logger *logrus.Logger
+}
+
+// NewGraphQLHandler создает обработчик GraphQL
+func NewGraphQLHandler(service *service.WalletService, cfg GraphQLConfig, logger *logrus.Logger) *GraphQLHandler {
+	schema, err := graphql.NewSchema(service, cfg.MaxDepth)
+	if err != nil {
+		// Схема и резолверы входят в сборку, расхождение между ними - ошибка программы
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+
+	return &GraphQLHandler{
+		schema: schema,
+		logger: logger,
+	}
+}
+
+// Query выполняет запрос GraphQL
+// @Summary GraphQL query
+// @Description Execute a GraphQL query over balances, transactions and rates of the user. Only query
+// @Description operations are supported. Errors of fields are returned in "errors" with the field path and
+// @Description the API error code in extensions.code; requests that fail parsing or validation get 400.
+// @Description GET accepts query, operationName and variables (JSON) as query parameters
+// @Tags graphql
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body graphql.Request true "GraphQL request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/graphql [post]
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req graphql.Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				c.Error(middleware.InvalidRequest("Invalid variables: " + err.Error()))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		c.Error(middleware.InvalidRequest("Query is required"))
+		return
+	}
+
+	ctx := graphql.WithUser(c.Request.Context(), userID)
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	// Ошибки резолверов переводятся в ошибки API, как в REST ответах
+	for _, gqlErr := range resp.Errors {
+		if gqlErr.ResolverError == nil {
+			continue
+		}
+		apiErr := middleware.ToAPIError(gqlErr.ResolverError)
+		if apiErr.Code == middleware.CodeInternal {
+			h.logger.Errorf("Failed to resolve GraphQL field %v: %v", gqlErr.Path, gqlErr.ResolverError)
+		}
+		gqlErr.Message = apiErr.Message
+		gqlErr.Extensions = map[string]interface{}{"code": apiErr.Code, "number": apiErr.Number}
+	}
+
+	// Без data запрос отклонен до выполнения: ошибка разбора или проверки по схеме
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
+
+// Schema возвращает схему GraphQL
+// @Summary GraphQL schema
+// @Description Schema of the GraphQL endpoint in SDL, for client code generation. Introspection queries are supported as well
+// @Tags graphql
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/graphql/schema [get]
+func (h *GraphQLHandler) Schema(c *gin.Context) {
+	c.String(http.StatusOK, graphql.SDL)
+}
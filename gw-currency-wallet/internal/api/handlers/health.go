@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gw-currency-wallet/internal/health"
+)
+
+// HealthHandler обработчик проверок liveness и readiness
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler создает новый обработчик проверок
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Live сообщает, что процесс работает. Зависимости не проверяются:
+// их недоступность не должна приводить к перезапуску сервиса
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready возвращает состояние зависимостей по результатам последних проверок.
+// Ответ 503, если недоступна любая зависимость, в том числе необязательная:
+// балансировщик не направляет трафик на экземпляр в режиме degraded
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Report()
+	status := http.StatusOK
+	if report.Status != health.StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// ReadyLegacy readiness для /ready: 503 только при недоступности обязательной
+// зависимости (БД), в режиме degraded ответ 200
+func (h *HealthHandler) ReadyLegacy(c *gin.Context) {
+	report := h.checker.Report()
+	status := http.StatusOK
+	if report.Status == health.StatusNotReady {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
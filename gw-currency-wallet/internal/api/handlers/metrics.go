@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/grpc"
+	"gw-currency-wallet/internal/service"
+)
+
+// MetricsHandler обработчик метрик в текстовом формате Prometheus
+type MetricsHandler struct {
+	service *service.WalletService
+	timeout *middleware.RequestTimeout
+}
+
+// NewMetricsHandler создает новый обработчик метрик
+func NewMetricsHandler(service *service.WalletService, timeout *middleware.RequestTimeout) *MetricsHandler {
+	return &MetricsHandler{service: service, timeout: timeout}
+}
+
+// breakerStates числовые значения состояния circuit breaker для метрики
+var breakerStates = map[string]int{
+	grpc.BreakerClosed:   0,
+	grpc.BreakerHalfOpen: 1,
+	grpc.BreakerOpen:     2,
+}
+
+// Metrics возвращает метрики клиента exchanger: состояние circuit breaker,
+// число отказов подряд, открытий breaker, отклоненных вызовов и повторов,
+// число WebSocket соединений и запросов с истекшим сроком обработки
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	var b strings.Builder
+
+	writeMetric(&b, "http_requests_deadline_exceeded_total", "counter",
+		"HTTP requests cancelled by HTTP_REQUEST_TIMEOUT", h.timeout.DeadlineExceeded())
+
+	if stats, ok := h.service.ExchangerStats(); ok {
+		writeMetric(&b, "exchanger_circuit_breaker_state", "gauge",
+			"Circuit breaker state: 0 - closed, 1 - half-open, 2 - open", breakerStates[stats.State])
+		writeMetric(&b, "exchanger_consecutive_failures", "gauge",
+			"Consecutive failed exchanger calls", stats.ConsecutiveFailures)
+		writeMetric(&b, "exchanger_circuit_breaker_opens_total", "counter",
+			"Number of times the circuit breaker opened", stats.Opens)
+		writeMetric(&b, "exchanger_circuit_breaker_rejected_total", "counter",
+			"Calls rejected by the open circuit breaker", stats.Rejected)
+		writeMetric(&b, "exchanger_retries_total", "counter",
+			"Retried exchanger calls", stats.Retries)
+	}
+
+	if bus := h.service.Events(); bus != nil {
+		stats := bus.Stats()
+		writeMetric(&b, "websocket_connections", "gauge",
+			"Open WebSocket connections", stats.Subscribers)
+		writeMetric(&b, "websocket_slow_consumers_total", "counter",
+			"WebSocket connections closed because the client did not keep up with events", stats.SlowConsumers)
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeMetric записывает метрику с описанием и типом
+func writeMetric(b *strings.Builder, name, metricType, help string, value interface{}) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, metricType, name, value)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gw-currency-wallet/internal/api/middleware"
+)
+
+// Envelope тело ответа /api/v2: ресурс или список в data, сведения о странице
+// списка в meta, ошибка в error. Ошибки во всех версиях возвращаются как
+// {"error": {...}} без data и meta
+type Envelope struct {
+	Data  interface{}          `json:"data,omitempty"`
+	Meta  *Meta                `json:"meta,omitempty"`
+	Error *middleware.APIError `json:"error,omitempty"`
+}
+
+// Meta сведения о странице списка. Для списков, ограниченных только limit,
+// total - число возвращенных записей; для списков без ограничения limit отсутствует
+type Meta struct {
+	// Page номер страницы, только для списков с постраничным выводом по page или offset
+	Page  int   `json:"page,omitempty"`
+	Limit int   `json:"limit,omitempty"`
+	Total int64 `json:"total"`
+	// NextCursor курсор следующей страницы, только для списков с курсором; на последней странице отсутствует
+	NextCursor int64 `json:"next_cursor,omitempty"`
+}
+
+// v2Body ответ v1, представление которого в /api/v2 отличается от {"data": ответ v1}
+type v2Body interface {
+	v2() Envelope
+}
+
+// respond отвечает телом body в формате версии API запроса: в v1 тело
+// возвращается как есть, в v2 - в конверте Envelope
+func respond(c *gin.Context, status int, body interface{}) {
+	if middleware.GetAPIVersion(c) < middleware.APIVersion2 {
+		c.JSON(status, body)
+		return
+	}
+
+	if mapped, ok := body.(v2Body); ok {
+		c.JSON(status, mapped.v2())
+		return
+	}
+	c.JSON(status, Envelope{Data: body})
+}
+
+// respondList отвечает списком: в v1 телом body как есть, в v2 - элементами
+// items в data и сведениями о странице в meta
+func respondList(c *gin.Context, body, items interface{}, meta Meta) {
+	if middleware.GetAPIVersion(c) < middleware.APIVersion2 {
+		c.JSON(http.StatusOK, body)
+		return
+	}
+
+	// Пустой список в v2 всегда [], а не null
+	if value := reflect.ValueOf(items); value.Kind() == reflect.Slice && value.IsNil() {
+		items = []interface{}{}
+	}
+	c.JSON(http.StatusOK, Envelope{Data: items, Meta: &meta})
+}
+
+// respondMessage отвечает на операцию без данных ответа: в v1 сообщением
+// {"message": ...}, в v2 без тела (200 заменяется на 204 No Content)
+func respondMessage(c *gin.Context, status int, message string) {
+	if middleware.GetAPIVersion(c) < middleware.APIVersion2 {
+		c.JSON(status, gin.H{"message": message})
+		return
+	}
+
+	if status == http.StatusOK {
+		status = http.StatusNoContent
+	}
+	// Без тела заголовки не отправляются сами, поэтому статус записывается сразу
+	c.Status(status)
+	c.Writer.WriteHeaderNow()
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// ScheduleHandler обработчик регулярных операций пользователя
+type ScheduleHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewScheduleHandler создает обработчик регулярных операций
+func NewScheduleHandler(service *service.WalletService, logger *logrus.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateScheduleRequest запрос на создание регулярной операции
+type CreateScheduleRequest struct {
+	// Operation deposit - пополнение to_currency, exchange - обмен from_currency на to_currency
+	Operation    string  `json:"operation" binding:"required,oneof=deposit exchange"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency" binding:"required"`
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	Period       string  `json:"period" binding:"required,oneof=daily weekly monthly"`
+	// StartAt первое выполнение (RFC 3339), по умолчанию сразу
+	StartAt *time.Time `json:"start_at"`
+}
+
+// UpdateScheduleRequest изменения регулярной операции; отсутствующие поля не меняются
+type UpdateScheduleRequest struct {
+	Amount  *float64   `json:"amount" binding:"omitempty,gt=0"`
+	Period  *string    `json:"period" binding:"omitempty,oneof=daily weekly monthly"`
+	StartAt *time.Time `json:"start_at"`
+	// Active false приостанавливает операцию, true возобновляет
+	Active *bool `json:"active"`
+}
+
+// SchedulesResponse список регулярных операций
+type SchedulesResponse struct {
+	Schedules []storages.Schedule `json:"schedules"`
+}
+
+// ListSchedules возвращает регулярные операции пользователя
+// @Summary List recurring operations
+// @Description Get recurring deposits and exchanges of the user
+// @Tags schedules
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} SchedulesResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/schedules [get]
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list schedules: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, SchedulesResponse{Schedules: schedules}, schedules, Meta{Total: int64(len(schedules))})
+}
+
+// CreateSchedule создает регулярную операцию
+// @Summary Create recurring operation
+// @Description Create a daily, weekly or monthly deposit or exchange between the user's currencies.
+// @Description Exchanges use the scheduled margin and require the exchange scope
+// @Tags schedules
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateScheduleRequest true "Schedule data"
+// @Success 201 {object} storages.Schedule
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/schedules [post]
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	if req.Operation == storages.ScheduleOperationExchange {
+		scopes, _ := middleware.GetScopes(c)
+		if !slices.Contains(scopes, middleware.ScopeExchange) {
+			c.Error(middleware.Forbidden("Insufficient scope: " + middleware.ScopeExchange))
+			return
+		}
+		if req.FromCurrency == "" {
+			c.Error(middleware.InvalidRequest("from_currency is required for exchange"))
+			return
+		}
+	}
+
+	schedule := &storages.Schedule{
+		UserID:       userID,
+		Operation:    req.Operation,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Amount:       req.Amount,
+		Period:       req.Period,
+	}
+	if req.StartAt != nil {
+		schedule.StartAt = *req.StartAt
+	}
+
+	if err := h.service.CreateSchedule(c.Request.Context(), schedule); err != nil {
+		h.logger.Errorf("Failed to create schedule: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, schedule)
+}
+
+// GetSchedule возвращает регулярную операцию
+// @Summary Get recurring operation
+// @Description Get a recurring operation with its next run and last error
+// @Tags schedules
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} storages.Schedule
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/schedules/{id} [get]
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	userID, scheduleID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.service.GetSchedule(c.Request.Context(), userID, scheduleID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, schedule)
+}
+
+// UpdateSchedule изменяет регулярную операцию
+// @Summary Update recurring operation
+// @Description Change amount, period or start, pause (active=false) or resume (active=true) a recurring operation.
+// @Description Runs missed while paused are not executed
+// @Tags schedules
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param request body UpdateScheduleRequest true "Changes"
+// @Success 200 {object} storages.Schedule
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/schedules/{id} [patch]
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	userID, scheduleID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	schedule, err := h.service.UpdateSchedule(c.Request.Context(), userID, scheduleID, service.ScheduleUpdate{
+		Amount:  req.Amount,
+		Period:  req.Period,
+		StartAt: req.StartAt,
+		Active:  req.Active,
+	})
+	if err != nil {
+		h.logger.Errorf("Failed to update schedule %d: %v", scheduleID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, schedule)
+}
+
+// DeleteSchedule удаляет регулярную операцию
+// @Summary Delete recurring operation
+// @Tags schedules
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/schedules/{id} [delete]
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	userID, scheduleID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSchedule(c.Request.Context(), userID, scheduleID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "Schedule deleted")
+}
+
+// parseIDs извлекает пользователя из токена и ID операции из пути.
+// При ошибке ответ уже сформирован
+func (h *ScheduleHandler) parseIDs(c *gin.Context) (int64, int64, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return 0, 0, false
+	}
+
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || scheduleID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid schedule id"))
+		return 0, 0, false
+	}
+
+	return userID, scheduleID, true
+}
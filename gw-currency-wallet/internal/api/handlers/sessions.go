@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// SessionHandler обработчик сессий пользователя
+type SessionHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewSessionHandler создает обработчик сессий
+func NewSessionHandler(service *service.WalletService, logger *logrus.Logger) *SessionHandler {
+	return &SessionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SessionResponse сессия пользователя; Current - сессия токена запроса
+type SessionResponse struct {
+	storages.Session
+	Current bool `json:"current"`
+}
+
+// SessionsResponse список действующих сессий
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// ListSessions возвращает действующие сессии пользователя
+// @Summary List sessions
+// @Description Active sessions of the user (one per login): device, IP and issue time. The session of the request token is marked as current
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} SessionsResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/user/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list sessions: %v", err)
+		c.Error(err)
+		return
+	}
+
+	currentID, _ := middleware.GetSessionID(c)
+	response := SessionsResponse{Sessions: make([]SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, SessionResponse{Session: session, Current: session.ID == currentID})
+	}
+
+	respondList(c, response, response.Sessions, Meta{Total: int64(len(response.Sessions))})
+}
+
+// RevokeSession отзывает сессию пользователя
+// @Summary Revoke session
+// @Description Revoke an active session of the user: its access and refresh tokens are rejected immediately. Revoking the current session logs out
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/user/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || sessionID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid session id"))
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "Session revoked")
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/statement"
+)
+
+// statementWriteTimeout время на отправку одной части выписки. Таймаут записи
+// сервера продлевается после каждой части, поэтому длинная выписка не обрывается
+const statementWriteTimeout = 15 * time.Second
+
+// StatementHandler обработчик выписок по счету
+type StatementHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewStatementHandler создает обработчик выписок
+func NewStatementHandler(service *service.WalletService, logger *logrus.Logger) *StatementHandler {
+	return &StatementHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Export выгружает выписку по счету в CSV или PDF
+// @Summary Export account statement
+// @Description Streams a statement of the user's balance movements for the period with the running balance
+// @Description of the currency after each movement, opening and closing balances. Dates are UTC days,
+// @Description "to" is inclusive; the period defaults to the last 30 days and must not exceed 366 days
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce text/csv
+// @Produce application/pdf
+// @Param format query string false "Statement format: csv (default) or pdf"
+// @Param from query string false "First day of the period, YYYY-MM-DD"
+// @Param to query string false "Last day of the period, YYYY-MM-DD (default today)"
+// @Success 200 {file} file
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/transactions/export [get]
+func (h *StatementHandler) Export(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	format := c.DefaultQuery("format", statement.FormatCSV)
+	if format != statement.FormatCSV && format != statement.FormatPDF {
+		c.Error(middleware.InvalidRequest("format must be csv or pdf"))
+		return
+	}
+
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(statement.DateLayout, value); err != nil {
+			c.Error(middleware.InvalidRequest("Invalid to"))
+			return
+		}
+	}
+	from := to.AddDate(0, 0, 1-service.DefaultStatementDays)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(statement.DateLayout, value); err != nil {
+			c.Error(middleware.InvalidRequest("Invalid from"))
+			return
+		}
+	}
+
+	header, err := h.service.PrepareStatement(c.Request.Context(), userID, from, to)
+	if err != nil {
+		h.logger.Errorf("Failed to prepare statement: %v", err)
+		c.Error(err)
+		return
+	}
+
+	out := &chunkWriter{Writer: c.Writer, rc: http.NewResponseController(c.Writer)}
+	w, err := statement.New(format, out)
+	if err != nil {
+		c.Error(middleware.InvalidRequest(err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s.%s", header.From.Format(statement.DateLayout), header.To.Format(statement.DateLayout), format)
+	c.Header("Content-Type", statement.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	out.extendDeadline()
+
+	// Ответ уже начат: ошибку можно только записать в лог, клиент получит неполную выписку
+	if err := h.service.WriteStatement(c.Request.Context(), header, w); err != nil {
+		h.logger.Errorf("Failed to write statement for user %d: %v", userID, err)
+		c.Abort()
+	}
+}
+
+// chunkWriter отправляет выписку клиенту по частям: Flush отправляет записанное
+// и продлевает таймаут записи сервера
+type chunkWriter struct {
+	io.Writer
+	rc *http.ResponseController
+}
+
+func (w *chunkWriter) Flush() error {
+	if err := w.rc.Flush(); err != nil {
+		return err
+	}
+	w.extendDeadline()
+	return nil
+}
+
+// extendDeadline продлевает таймаут записи. Если соединение не поддерживает
+// таймауты (http.ErrNotSupported), действует таймаут сервера
+func (w *chunkWriter) extendDeadline() {
+	w.rc.SetWriteDeadline(time.Now().Add(statementWriteTimeout))
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// TransactionHandler обработчик истории транзакций пользователя
+type TransactionHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewTransactionHandler создает обработчик истории транзакций
+func NewTransactionHandler(service *service.WalletService, logger *logrus.Logger) *TransactionHandler {
+	return &TransactionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// TransactionResponse транзакция пользователя
+type TransactionResponse struct {
+	ID int64 `json:"id"`
+	// Type deposit, withdraw, exchange или fee
+	Type string `json:"type"`
+	// Status pending, completed, failed или cancelled
+	Status       string     `json:"status"`
+	Source       string     `json:"source,omitempty"`
+	FromCurrency string     `json:"from_currency,omitempty"`
+	ToCurrency   string     `json:"to_currency,omitempty"`
+	FromAmount   float64    `json:"from_amount"`
+	ToAmount     float64    `json:"to_amount"`
+	ExchangeRate float64    `json:"exchange_rate,omitempty"`
+	Fee          float64    `json:"fee"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// TransactionsResponse страница истории транзакций
+type TransactionsResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	// Total число транзакций, подходящих под фильтр
+	Total int64 `json:"total"`
+	// NextCursor курсор следующей страницы, на последней странице отсутствует
+	NextCursor int64 `json:"next_cursor,omitempty"`
+}
+
+// ListTransactions возвращает страницу истории транзакций пользователя
+// @Summary List transactions
+// @Description Transaction history of the user, newest first. Pages are fetched by next_cursor of the previous
+// @Description page (or by offset); from and to accept RFC 3339 time or a YYYY-MM-DD date in UTC
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param limit query int false "Number of transactions (default 50, max 500)"
+// @Param offset query int false "Number of transactions to skip"
+// @Param cursor query int false "next_cursor of the previous page"
+// @Param type query string false "Comma-separated types: deposit, withdraw, exchange, fee"
+// @Param status query string false "Comma-separated statuses: pending, completed, failed, cancelled"
+// @Param from query string false "Inclusive lower bound of created_at"
+// @Param to query string false "Exclusive upper bound of created_at"
+// @Success 200 {object} TransactionsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/transactions [get]
+func (h *TransactionHandler) ListTransactions(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var filter storages.TransactionFilter
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 || filter.Limit > storages.MaxTransactionPageSize {
+			c.Error(middleware.InvalidRequest("Invalid limit"))
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			c.Error(middleware.InvalidRequest("Invalid offset"))
+			return
+		}
+	}
+	if value := c.Query("cursor"); value != "" {
+		if filter.Cursor, err = strconv.ParseInt(value, 10, 64); err != nil || filter.Cursor < 1 {
+			c.Error(middleware.InvalidRequest("Invalid cursor"))
+			return
+		}
+	}
+	filter.Types = queryList(c.Query("type"))
+	filter.Statuses = queryList(c.Query("status"))
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseQueryTime(value); err != nil {
+			c.Error(middleware.InvalidRequest("Invalid from"))
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseQueryTime(value); err != nil {
+			c.Error(middleware.InvalidRequest("Invalid to"))
+			return
+		}
+	}
+
+	page, err := h.service.GetTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		h.logger.Errorf("Failed to get transactions: %v", err)
+		c.Error(err)
+		return
+	}
+
+	response := TransactionsResponse{
+		Transactions: make([]TransactionResponse, 0, len(page.Transactions)),
+		Total:        page.Total,
+		NextCursor:   page.NextCursor,
+	}
+	for _, tx := range page.Transactions {
+		response.Transactions = append(response.Transactions, TransactionResponse{
+			ID:           tx.ID,
+			Type:         tx.Type,
+			Status:       tx.Status,
+			Source:       tx.Source,
+			FromCurrency: tx.FromCurrency,
+			ToCurrency:   tx.ToCurrency,
+			FromAmount:   tx.FromAmount,
+			ToAmount:     tx.ToAmount,
+			ExchangeRate: tx.ExchangeRate,
+			Fee:          tx.Fee,
+			CreatedAt:    tx.CreatedAt,
+			CompletedAt:  tx.CompletedAt,
+		})
+	}
+	meta := Meta{Limit: filter.PageSize(), Total: page.Total, NextCursor: page.NextCursor}
+	// Номер страницы известен только при выборке по offset
+	if filter.Cursor == 0 {
+		meta.Page = filter.Offset/meta.Limit + 1
+	}
+	respondList(c, response, response.Transactions, meta)
+}
+
+// queryList разбирает список значений через запятую
+func queryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseQueryTime разбирает время в RFC 3339 или дату YYYY-MM-DD (начало дня UTC)
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
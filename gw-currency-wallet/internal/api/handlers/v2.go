@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"gw-currency-wallet/internal/storages"
+)
+
+// Представление ответов в /api/v2. Ответы v1 без метода v2 возвращаются в data
+// без изменений. Отличия v2:
+//   - списки возвращаются массивом в data, сведения о странице - в meta (respondList);
+//   - балансы после операции возвращаются в balances, без сообщения об успехе;
+//   - отложенный вывод возвращается как ресурс вывода;
+//   - операции без данных ответа возвращают 204 No Content.
+
+// TokenResponseV2 токены пользователя в /api/v2
+type TokenResponseV2 struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+}
+
+// BalanceResponseV2 балансы пользователя в /api/v2
+type BalanceResponseV2 struct {
+	Balances storages.UserBalances `json:"balances"`
+	// Held суммы, удержанные ожидающими выводами; пусто без подтверждения выводов
+	Held storages.UserBalances `json:"held"`
+}
+
+// DepositResponseV2 результат пополнения в /api/v2
+type DepositResponseV2 struct {
+	Balances storages.UserBalances `json:"balances"`
+}
+
+// WithdrawResponseV2 результат вывода в /api/v2
+type WithdrawResponseV2 struct {
+	Fee      float64               `json:"fee"`
+	Balances storages.UserBalances `json:"balances"`
+}
+
+// ExchangeResponseV2 результат обмена в /api/v2
+type ExchangeResponseV2 struct {
+	ExchangedAmount float64               `json:"exchanged_amount"`
+	Fee             float64               `json:"fee"`
+	FeeCurrency     string                `json:"fee_currency"`
+	Balances        storages.UserBalances `json:"balances"`
+}
+
+// UserBalancesResponseV2 пользователь и его балансы в /api/v2
+type UserBalancesResponseV2 struct {
+	User     UserResponse          `json:"user"`
+	Balances storages.UserBalances `json:"balances"`
+}
+
+func (r TokenResponse) v2() Envelope {
+	return Envelope{Data: TokenResponseV2{AccessToken: r.Token, RefreshToken: r.RefreshToken, TokenType: "Bearer"}}
+}
+
+func (r BalanceResponse) v2() Envelope {
+	held := storages.UserBalances{}
+	if r.Held != nil {
+		held = *r.Held
+	}
+	return Envelope{Data: BalanceResponseV2{Balances: r.Balance, Held: held}}
+}
+
+func (r DepositResponse) v2() Envelope {
+	return Envelope{Data: DepositResponseV2{Balances: r.NewBalance}}
+}
+
+func (r WithdrawResponse) v2() Envelope {
+	return Envelope{Data: WithdrawResponseV2{Fee: r.Fee, Balances: r.NewBalance}}
+}
+
+func (r PendingWithdrawResponse) v2() Envelope {
+	return Envelope{Data: r.withdrawal}
+}
+
+func (r ExchangeResponse) v2() Envelope {
+	return Envelope{Data: ExchangeResponseV2{
+		ExchangedAmount: r.ExchangedAmount,
+		Fee:             r.Fee,
+		FeeCurrency:     r.FeeCurrency,
+		Balances:        r.NewBalance,
+	}}
+}
+
+func (r UserBalancesResponse) v2() Envelope {
+	return Envelope{Data: UserBalancesResponseV2{User: r.User, Balances: r.Balance}}
+}
+
+func (r RatesResponse) v2() Envelope {
+	return Envelope{Data: r.Rates}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// VerificationHandler обработчик верификации пользователя
+type VerificationHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewVerificationHandler создает обработчик верификации
+func NewVerificationHandler(service *service.WalletService, logger *logrus.Logger) *VerificationHandler {
+	return &VerificationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SubmitVerificationRequest данные для повышения уровня верификации. Для уровня
+// full обязателен документ
+type SubmitVerificationRequest struct {
+	Level          string `json:"level" binding:"required,oneof=basic full"`
+	FullName       string `json:"full_name" binding:"required,max=200"`
+	DateOfBirth    string `json:"date_of_birth" binding:"required"` // YYYY-MM-DD
+	Country        string `json:"country" binding:"required,len=2"` // ISO 3166-1 alpha-2
+	DocumentType   string `json:"document_type" binding:"omitempty,oneof=passport id_card driver_license residence_permit"`
+	DocumentNumber string `json:"document_number" binding:"max=50"`
+}
+
+// GetVerification возвращает уровень верификации пользователя
+// @Summary Get verification status
+// @Description Verification level of the user, withdrawal and exchange limits of the level and latest verification requests
+// @Tags verification
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} service.VerificationStatus
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/verification [get]
+func (h *VerificationHandler) GetVerification(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	status, err := h.service.GetVerificationStatus(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to get verification status: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, status)
+}
+
+// SubmitVerification создает заявку на повышение уровня верификации
+// @Summary Submit verification
+// @Description Request a higher verification level (basic: name, date of birth and country; full: also an identity document). Only one request can be pending
+// @Tags verification
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SubmitVerificationRequest true "Verification data"
+// @Success 201 {object} storages.VerificationRequest
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/verification [post]
+func (h *VerificationHandler) SubmitVerification(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req SubmitVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	request := &storages.VerificationRequest{
+		UserID:         userID,
+		Level:          req.Level,
+		FullName:       req.FullName,
+		DateOfBirth:    req.DateOfBirth,
+		Country:        req.Country,
+		DocumentType:   req.DocumentType,
+		DocumentNumber: req.DocumentNumber,
+	}
+	if err := h.service.SubmitVerification(c.Request.Context(), request); err != nil {
+		h.logger.Warnf("Failed to submit verification for user %d: %v", userID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, request)
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gw-common/buildinfo"
+)
+
+// Version возвращает версию, коммит и время сборки сервиса
+func Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
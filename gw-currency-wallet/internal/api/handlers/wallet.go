@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gw-currency-wallet/internal/api/middleware"
 	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,40 +28,165 @@ func NewWalletHandler(service *service.WalletService, logger *logrus.Logger) *Wa
 // DepositRequest запрос на пополнение
 type DepositRequest struct {
 	Amount   float64 `json:"amount" binding:"required,gt=0"`
-	Currency string  `json:"currency" binding:"required,oneof=USD EUR RUB"`
+	Currency string  `json:"currency" binding:"required"`
 }
 
 // WithdrawRequest запрос на вывод
 type WithdrawRequest struct {
 	Amount   float64 `json:"amount" binding:"required,gt=0"`
-	Currency string  `json:"currency" binding:"required,oneof=USD EUR RUB"`
+	Currency string  `json:"currency" binding:"required"`
+}
+
+// BalanceResponse балансы пользователя
+type BalanceResponse struct {
+	Balance storages.UserBalances `json:"balance"`
+	// Held суммы, удержанные ожидающими выводами; только при подтверждении выводов
+	Held *storages.UserBalances `json:"held,omitempty"`
+}
+
+// DepositResponse результат пополнения
+type DepositResponse struct {
+	Message    string                `json:"message"`
+	NewBalance storages.UserBalances `json:"new_balance"`
+}
+
+// WithdrawResponse результат вывода
+type WithdrawResponse struct {
+	Message    string                `json:"message"`
+	Fee        float64               `json:"fee"`
+	NewBalance storages.UserBalances `json:"new_balance"`
+}
+
+// PendingWithdrawResponse вывод, ожидающий подтверждения
+type PendingWithdrawResponse struct {
+	Message       string  `json:"message"`
+	TransactionID int64   `json:"transaction_id"`
+	Status        string  `json:"status"`
+	Fee           float64 `json:"fee"`
+
+	withdrawal WithdrawalResponse
 }
 
 // GetBalance возвращает баланс пользователя
 // @Summary Get user balance
-// @Description Get balance for all currencies
+// @Description Get balance for all currencies. Amounts held by pending withdrawals are returned in "held"
+// @Description and are not available for withdrawals and exchanges
 // @Tags wallet
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} BalanceResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
 // @Router /api/v1/balance [get]
 func (h *WalletHandler) GetBalance(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(middleware.Unauthorized("Unauthorized"))
 		return
 	}
 
 	balances, err := h.service.GetUserBalances(c.Request.Context(), userID)
 	if err != nil {
 		h.logger.Errorf("Failed to get balances: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get balances"})
+		c.Error(err)
+		return
+	}
+
+	response := BalanceResponse{Balance: balances}
+	if h.service.WithdrawalApprovalRequired() {
+		held, err := h.service.GetHeldBalances(c.Request.Context(), userID)
+		if err != nil {
+			h.logger.Errorf("Failed to get held balances: %v", err)
+			c.Error(err)
+			return
+		}
+		response.Held = &held
+	}
+
+	respond(c, http.StatusOK, response)
+}
+
+// GetBalanceTotal возвращает стоимость всех балансов пользователя в одной валюте
+// @Summary Get total balance
+// @Description Converts all non-zero balances to the given currency by current exchanger rates without margin
+// @Description and returns the total with the per-currency breakdown and the rates used
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param currency query string true "Currency of the total"
+// @Success 200 {object} service.BalanceTotal
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 503 {object} middleware.ErrorResponse
+// @Router /api/v1/balance/total [get]
+func (h *WalletHandler) GetBalanceTotal(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	currency := c.Query("currency")
+	if currency == "" {
+		c.Error(middleware.InvalidRequest("currency is required"))
+		return
+	}
+
+	total, err := h.service.GetBalanceTotal(c.Request.Context(), userID, currency)
+	if err != nil {
+		h.logger.Errorf("Failed to get balance total: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, total)
+}
+
+// GetBalanceHistory возвращает стоимость балансов пользователя по дням
+// @Summary Get balance history
+// @Description Daily time series of the user's balances and their value in the base currency for the last days (UTC),
+// @Description including today. Past balances are rebuilt from the ledger and valued by the exchanger's historical
+// @Description rates without margin. Currencies without a rate for a day are listed in "unpriced" and not included in "value"
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param base query string true "Base currency"
+// @Param days query int false "Number of days (default 30, max 365)"
+// @Success 200 {object} service.BalanceHistory
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 503 {object} middleware.ErrorResponse
+// @Router /api/v1/balance/history [get]
+func (h *WalletHandler) GetBalanceHistory(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	base := c.Query("base")
+	if base == "" {
+		c.Error(middleware.InvalidRequest("base is required"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"balance": balances})
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(service.DefaultBalanceHistoryDays)))
+	if err != nil || days < 1 || days > service.MaxBalanceHistoryDays {
+		c.Error(middleware.InvalidRequest("Invalid days"))
+		return
+	}
+
+	history, err := h.service.GetBalanceHistory(c.Request.Context(), userID, base, days)
+	if err != nil {
+		h.logger.Errorf("Failed to get balance history: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, history)
 }
 
 // Deposit пополняет счет пользователя
@@ -70,70 +197,93 @@ func (h *WalletHandler) GetBalance(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body DepositRequest true "Deposit data"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Success 200 {object} DepositResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
 // @Router /api/v1/wallet/deposit [post]
 func (h *WalletHandler) Deposit(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(middleware.Unauthorized("Unauthorized"))
 		return
 	}
 
 	var req DepositRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		c.Error(middleware.BindingError(err))
 		return
 	}
 
 	newBalances, err := h.service.Deposit(c.Request.Context(), userID, req.Currency, req.Amount)
 	if err != nil {
 		h.logger.Errorf("Failed to deposit: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Account topped up successfully",
-		"new_balance": newBalances,
+	respond(c, http.StatusOK, DepositResponse{
+		Message:    "Account topped up successfully",
+		NewBalance: newBalances,
 	})
 }
 
 // Withdraw выводит средства со счета
 // @Summary Withdraw funds
-// @Description Withdraw funds from user account
+// @Description Withdraw funds from user account. When withdrawal approval is required the withdrawal
+// @Description is created pending (202): the amount and fee are held until an administrator or
+// @Description automatic approval completes it, or it is cancelled
 // @Tags wallet
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param request body WithdrawRequest true "Withdrawal data"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Success 200 {object} WithdrawResponse
+// @Success 202 {object} PendingWithdrawResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 422 {object} middleware.ErrorResponse
 // @Router /api/v1/wallet/withdraw [post]
 func (h *WalletHandler) Withdraw(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Error(middleware.Unauthorized("Unauthorized"))
 		return
 	}
 
 	var req WithdrawRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	if h.service.WithdrawalApprovalRequired() {
+		withdrawal, err := h.service.RequestWithdraw(c.Request.Context(), userID, req.Currency, req.Amount)
+		if err != nil {
+			h.logger.Errorf("Failed to request withdrawal: %v", err)
+			c.Error(err)
+			return
+		}
+
+		respond(c, http.StatusAccepted, PendingWithdrawResponse{
+			Message:       "Withdrawal is pending approval",
+			TransactionID: withdrawal.ID,
+			Status:        withdrawal.Status,
+			Fee:           withdrawal.Fee,
+			withdrawal:    newWithdrawalResponse(withdrawal),
+		})
 		return
 	}
 
-	newBalances, err := h.service.Withdraw(c.Request.Context(), userID, req.Currency, req.Amount)
+	newBalances, fee, err := h.service.Withdraw(c.Request.Context(), userID, req.Currency, req.Amount)
 	if err != nil {
 		h.logger.Errorf("Failed to withdraw: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Withdrawal successful",
-		"new_balance": newBalances,
+	respond(c, http.StatusOK, WithdrawResponse{
+		Message:    "Withdrawal successful",
+		Fee:        fee,
+		NewBalance: newBalances,
 	})
 }
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// WebhookHandler обработчик вебхуков пользователя
+type WebhookHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewWebhookHandler создает обработчик вебхуков
+func NewWebhookHandler(service *service.WalletService, logger *logrus.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateWebhookRequest запрос на регистрацию вебхука
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Events типы событий (deposit.completed, exchange.executed); пустой список - все события
+	Events []string `json:"events"`
+}
+
+// CreateWebhookResponse созданный вебхук. Secret возвращается только в этом ответе
+type CreateWebhookResponse struct {
+	Secret  string           `json:"secret"`
+	Webhook storages.Webhook `json:"webhook"`
+}
+
+// WebhooksResponse список вебхуков
+type WebhooksResponse struct {
+	Webhooks []storages.Webhook `json:"webhooks"`
+}
+
+// WebhookDeliveriesResponse последние доставки вебхука
+type WebhookDeliveriesResponse struct {
+	Deliveries []storages.WebhookDelivery `json:"deliveries"`
+}
+
+// ListWebhooks возвращает вебхуки пользователя
+// @Summary List webhooks
+// @Description Registered webhooks of the user. Signing secrets are not returned
+// @Tags webhooks
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Success 200 {object} WebhooksResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	webhooks, err := h.service.ListWebhooks(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("Failed to list webhooks: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, WebhooksResponse{Webhooks: webhooks}, webhooks, Meta{Total: int64(len(webhooks))})
+}
+
+// CreateWebhook регистрирует вебхук
+// @Summary Create webhook
+// @Description Register a URL that receives signed JSON events of the account via POST.
+// @Description The X-Webhook-Signature header is "t=<unix>,v1=<hex HMAC-SHA256 of "<unix>.<body>">"
+// @Description keyed with the secret, which is returned only once. Failed deliveries are retried
+// @Description with exponential backoff; events may arrive more than once, deduplicate by X-Webhook-Event-Id
+// @Tags webhooks
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Webhook URL and events"
+// @Success 201 {object} CreateWebhookResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BindingError(err))
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(c.Request.Context(), userID, req.URL, req.Events)
+	if err != nil {
+		h.logger.Errorf("Failed to create webhook: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, CreateWebhookResponse{Secret: webhook.Secret, Webhook: *webhook})
+}
+
+// DeleteWebhook удаляет вебхук
+// @Summary Delete webhook
+// @Description Delete the webhook; its pending deliveries are dropped
+// @Tags webhooks
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(c.Request.Context(), userID, webhookID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondMessage(c, http.StatusOK, "Webhook deleted")
+}
+
+// ListDeliveries возвращает последние доставки вебхука
+// @Summary List webhook deliveries
+// @Description Latest deliveries of the webhook, newest first, with status, attempts and the last error
+// @Tags webhooks
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Number of deliveries (default 50, max 200)"
+// @Success 200 {object} WebhookDeliveriesResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultWebhookDeliveriesLimit)))
+	if err != nil || limit < 1 || limit > service.MaxWebhookDeliveriesLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveries(c.Request.Context(), userID, webhookID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to list webhook deliveries: %v", err)
+		c.Error(err)
+		return
+	}
+
+	respondList(c, WebhookDeliveriesResponse{Deliveries: deliveries}, deliveries, Meta{Limit: limit, Total: int64(len(deliveries))})
+}
+
+// parseWebhookID разбирает ID вебхука из пути. При ошибке ответ уже сформирован
+func parseWebhookID(c *gin.Context) (int64, bool) {
+	webhookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || webhookID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid webhook id"))
+		return 0, false
+	}
+	return webhookID, true
+}
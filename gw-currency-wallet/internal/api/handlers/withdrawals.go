@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// WithdrawalHandler обработчик ожидающих подтверждения выводов пользователя
+type WithdrawalHandler struct {
+	service *service.WalletService
+	logger  *logrus.Logger
+}
+
+// NewWithdrawalHandler создает обработчик ожидающих выводов
+func NewWithdrawalHandler(service *service.WalletService, logger *logrus.Logger) *WithdrawalHandler {
+	return &WithdrawalHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// WithdrawalResponse вывод средств; сумма с комиссией ожидающего вывода удержана на балансе
+type WithdrawalResponse struct {
+	ID       int64   `json:"id"`
+	UserID   int64   `json:"user_id"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Fee      float64 `json:"fee"`
+	// Status pending, completed, failed (отклонен) или cancelled (отменен пользователем)
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// WithdrawalsResponse список ожидающих выводов
+type WithdrawalsResponse struct {
+	Withdrawals []WithdrawalResponse `json:"withdrawals"`
+}
+
+// ListPending возвращает ожидающие подтверждения выводы пользователя
+// @Summary List pending withdrawals
+// @Description Withdrawals waiting for approval, oldest first. Their amount and fee are held on the balance
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Produce json
+// @Param limit query int false "Number of withdrawals (default 20, max 100)"
+// @Success 200 {object} WithdrawalsResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/wallet/withdrawals/pending [get]
+func (h *WithdrawalHandler) ListPending(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.Error(middleware.InvalidRequest("Invalid limit"))
+		return
+	}
+
+	withdrawals, err := h.service.ListPendingWithdrawals(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to list pending withdrawals: %v", err)
+		c.Error(err)
+		return
+	}
+
+	response := newWithdrawalsResponse(withdrawals)
+	respondList(c, response, response.Withdrawals, Meta{Limit: limit, Total: int64(len(response.Withdrawals))})
+}
+
+// Cancel отменяет ожидающий вывод пользователя
+// @Summary Cancel pending withdrawal
+// @Description Cancel a withdrawal waiting for approval and release the held amount and fee
+// @Tags wallet
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Withdrawal transaction ID"
+// @Success 200 {object} WithdrawalResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/wallet/withdrawals/{id}/cancel [post]
+func (h *WithdrawalHandler) Cancel(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	txID, ok := parseWithdrawalID(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := h.service.CancelWithdrawal(c.Request.Context(), userID, txID)
+	if err != nil {
+		h.logger.Errorf("Failed to cancel withdrawal %d: %v", txID, err)
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, newWithdrawalResponse(withdrawal))
+}
+
+// parseWithdrawalID разбирает ID вывода из пути. При ошибке ответ уже сформирован
+func parseWithdrawalID(c *gin.Context) (int64, bool) {
+	txID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || txID < 1 {
+		c.Error(middleware.InvalidRequest("Invalid withdrawal id"))
+		return 0, false
+	}
+	return txID, true
+}
+
+func newWithdrawalResponse(tx *storages.Transaction) WithdrawalResponse {
+	return WithdrawalResponse{
+		ID:          tx.ID,
+		UserID:      tx.UserID,
+		Currency:    tx.FromCurrency,
+		Amount:      tx.FromAmount,
+		Fee:         tx.Fee,
+		Status:      tx.Status,
+		CreatedAt:   tx.CreatedAt,
+		CompletedAt: tx.CompletedAt,
+	}
+}
+
+func newWithdrawalsResponse(withdrawals []storages.Transaction) WithdrawalsResponse {
+	response := WithdrawalsResponse{Withdrawals: make([]WithdrawalResponse, 0, len(withdrawals))}
+	for i := range withdrawals {
+		response.Withdrawals = append(response.Withdrawals, newWithdrawalResponse(&withdrawals[i]))
+	}
+	return response
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"gw-common/errcodes"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/events"
+	"gw-currency-wallet/internal/service"
+)
+
+const (
+	// wsWriteTimeout ограничение записи одного сообщения
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxMessageSize ограничение входящих сообщений: клиент только отвечает на ping
+	wsMaxMessageSize = 512
+)
+
+// WebSocketConfig параметры соединений /api/v1/ws
+type WebSocketConfig struct {
+	// PingInterval период ping; соединение закрывается, если pong не пришел за два периода
+	PingInterval time.Duration
+	// AllowedOrigins разрешенные Origin ("*" - любые), пусто - только тот же хост
+	AllowedOrigins []string
+}
+
+// WebSocketHandler отправляет клиенту изменения его балансов и новые курсы
+type WebSocketHandler struct {
+	service      *service.WalletService
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	logger       *logrus.Logger
+}
+
+// NewWebSocketHandler создает обработчик WebSocket соединений
+func NewWebSocketHandler(service *service.WalletService, cfg WebSocketConfig, logger *logrus.Logger) *WebSocketHandler {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	if len(cfg.AllowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(cfg.AllowedOrigins, "*") || slices.Contains(cfg.AllowedOrigins, origin)
+		}
+	}
+
+	return &WebSocketHandler{
+		service:      service,
+		upgrader:     upgrader,
+		pingInterval: cfg.PingInterval,
+		logger:       logger,
+	}
+}
+
+// Live устанавливает WebSocket соединение для обновлений в реальном времени
+// @Summary Live balance and rate updates
+// @Description WebSocket connection. After connecting the server sends the current balances and rates,
+// @Description then a message on every balance change of the user and on every rates refresh:
+// @Description {"type": "balance", "balances": {...}, "timestamp": "..."} or {"type": "rates", "rates": {...}, "timestamp": "..."}.
+// @Description Browsers may pass the access token in the access_token query parameter.
+// @Tags wallet
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param access_token query string false "Access token (if the Authorization header cannot be set)"
+// @Success 101 "Switching Protocols"
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 503 {object} middleware.ErrorResponse
+// @Router /api/v1/ws [get]
+func (h *WebSocketHandler) Live(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Error(middleware.Unauthorized("Unauthorized"))
+		return
+	}
+
+	bus := h.service.Events()
+	if bus == nil {
+		c.Error(middleware.CodeError(errcodes.ServiceUnavailable, "Live updates are disabled"))
+		return
+	}
+
+	// Подписка оформляется до чтения текущего состояния, чтобы не пропустить
+	// изменения между ними
+	sub := bus.Subscribe(userID)
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrader уже ответил клиенту ошибкой
+		h.logger.Debugf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	h.logger.Debugf("WebSocket connected: UserID=%d", userID)
+
+	ctx := c.Request.Context()
+	if balances, err := h.service.GetUserBalances(ctx, userID); err == nil {
+		if err := h.write(conn, events.Event{Type: events.TypeBalance, Balances: balances, Timestamp: time.Now().UTC()}); err != nil {
+			return
+		}
+	} else {
+		h.logger.Warnf("Failed to get balances for WebSocket: UserID=%d: %v", userID, err)
+	}
+	if rates, err := h.service.GetExchangeRates(ctx); err == nil {
+		if err := h.write(conn, events.Event{Type: events.TypeRates, Rates: rates, Timestamp: time.Now().UTC()}); err != nil {
+			return
+		}
+	}
+
+	closed := make(chan struct{})
+	go h.readLoop(conn, closed)
+
+	ticker := time.NewTicker(h.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				h.closeConn(conn, sub.Err())
+				return
+			}
+			if err := h.write(conn, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			h.logger.Debugf("WebSocket disconnected: UserID=%d", userID)
+			return
+		}
+	}
+}
+
+// readLoop читает входящие сообщения, чтобы обрабатывать pong и закрытие
+// соединения клиентом, и закрывает closed при ошибке чтения
+func (h *WebSocketHandler) readLoop(conn *websocket.Conn, closed chan<- struct{}) {
+	defer close(closed)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	})
+
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// write отправляет событие в формате JSON
+func (h *WebSocketHandler) write(conn *websocket.Conn, event events.Event) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(event)
+}
+
+// closeConn отправляет клиенту причину закрытия подписки: медленный клиент
+// получает код "try again later", при остановке сервиса - "going away"
+func (h *WebSocketHandler) closeConn(conn *websocket.Conn, reason error) {
+	code, text := websocket.CloseGoingAway, "server is shutting down"
+	if errors.Is(reason, events.ErrSlowConsumer) {
+		code, text = websocket.CloseTryAgainLater, "too many pending events"
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteTimeout))
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gw-currency-wallet/internal/ratelimit"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// APIKeyHeader заголовок с ключом API, альтернатива JWT для внешних систем
+const APIKeyHeader = "X-API-Key"
+
+// Уровни доступа ключей API. Ключ не дает прав на вывод средств, управление
+// ключами и административные операции
+const (
+	// APIKeyAccessReadOnly балансы, курсы и чтение операций
+	APIKeyAccessReadOnly = "read_only"
+	// APIKeyAccessTrade чтение и обмен валют
+	APIKeyAccessTrade = "trade"
+)
+
+// ScopesForAPIKeyAccess возвращает scopes уровня доступа ключа API
+func ScopesForAPIKeyAccess(access string) ([]string, bool) {
+	switch access {
+	case APIKeyAccessReadOnly:
+		return []string{ScopeWalletRead}, true
+	case APIKeyAccessTrade:
+		return []string{ScopeWalletRead, ScopeExchange}, true
+	default:
+		return nil, false
+	}
+}
+
+// APIKeyAuthenticator проверяет ключи API (см. WalletService.AuthenticateAPIKey)
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (*storages.APIKey, *storages.User, error)
+}
+
+// AuthWithAPIKey middleware аутентификации по заголовку X-API-Key, без него - по JWT (см. Auth).
+// Запрос с ключом получает scopes ключа, лимит запросов ключа подключается в RateLimiter.PerUser
+func (m *JWTMiddleware) AuthWithAPIKey(keys APIKeyAuthenticator) gin.HandlerFunc {
+	jwtAuth := m.Auth()
+
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if secret == "" {
+			jwtAuth(c)
+			return
+		}
+
+		key, user, err := keys.AuthenticateAPIKey(c.Request.Context(), secret)
+		if err != nil {
+			if errors.Is(err, storages.ErrNotFound) {
+				AbortWithError(c, Unauthorized("Invalid API key"))
+				return
+			}
+			if errors.Is(err, service.ErrAccountClosed) {
+				AbortWithError(c, ToAPIError(err))
+				return
+			}
+			m.logger.Errorf("Failed to authenticate API key: %v", err)
+			AbortWithError(c, ToAPIError(err))
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Set("scopes", key.Scopes)
+		c.Set("api_key_id", key.ID)
+		if rule := (ratelimit.Rule{Rate: key.RateLimit, Burst: key.RateBurst}); rule.Enabled() {
+			c.Set("api_key_rate_limit", rule)
+		}
+		c.Next()
+	}
+}
+
+// GetAPIKeyID извлекает ID ключа API из контекста; false - запрос с JWT
+func GetAPIKeyID(c *gin.Context) (int64, bool) {
+	keyID, exists := c.Get("api_key_id")
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := keyID.(int64)
+	return id, ok
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
+	"gw-common/errcodes"
+	"gw-currency-wallet/internal/password"
+	"gw-currency-wallet/internal/service"
+)
+
+// Коды ошибок API из общего реестра gw-project (gw-common/errcodes)
+const (
+	CodeInvalidRequest      = string(errcodes.InvalidRequest)
+	CodeUnauthorized        = string(errcodes.Unauthorized)
+	CodeForbidden           = string(errcodes.Forbidden)
+	CodeNotFound            = string(errcodes.NotFound)
+	CodeUserExists          = string(errcodes.UserExists)
+	CodeInvalidCredentials  = string(errcodes.InvalidCredentials)
+	CodeInvalidAmount       = string(errcodes.InvalidAmount)
+	CodeUnsupportedCurrency = string(errcodes.UnsupportedCurrency)
+	CodeSameCurrency        = string(errcodes.SameCurrency)
+	CodePayloadTooLarge     = string(errcodes.PayloadTooLarge)
+	CodeWeakPassword        = string(errcodes.WeakPassword)
+	CodeInsufficientFunds   = string(errcodes.InsufficientFunds)
+	CodeLimitExceeded       = string(errcodes.LimitExceeded)
+	CodeRateLimited         = string(errcodes.RateLimited)
+	CodeAccountFrozen       = string(errcodes.AccountFrozen)
+	CodeAccountClosed       = string(errcodes.AccountClosed)
+	CodeBalanceNotEmpty     = string(errcodes.BalanceNotEmpty)
+	CodeServiceUnavailable  = string(errcodes.ServiceUnavailable)
+	CodeStaleRate           = string(errcodes.StaleRate)
+	CodeInternal            = string(errcodes.Internal)
+)
+
+// APIError ошибка API: код для обработки клиентом, числовой код из реестра,
+// сообщение и детали
+type APIError struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Number  int         `json:"number"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Error реализует интерфейс error
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ErrorResponse тело ответа с ошибкой
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// NewAPIError создает ошибку API с явным статусом
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Number: errcodes.Code(code).Number(), Message: message}
+}
+
+// CodeError создает ошибку API со статусом из реестра кодов
+func CodeError(code errcodes.Code, message string) *APIError {
+	return NewAPIError(code.HTTPStatus(), string(code), message)
+}
+
+// InvalidRequest ошибка разбора или валидации запроса
+func InvalidRequest(message string) *APIError {
+	return CodeError(errcodes.InvalidRequest, message)
+}
+
+// Unauthorized ошибка авторизации
+func Unauthorized(message string) *APIError {
+	return CodeError(errcodes.Unauthorized, message)
+}
+
+// Forbidden ошибка недостаточных прав
+func Forbidden(message string) *APIError {
+	return CodeError(errcodes.Forbidden, message)
+}
+
+// NotFound ошибка отсутствующего ресурса
+func NotFound(message string) *APIError {
+	return CodeError(errcodes.NotFound, message)
+}
+
+// ExchangerError переводит ошибку вызова exchanger в ошибку API. Ошибки запроса
+// (неверные аргументы, права, отсутствующие данные) возвращаются с кодом,
+// полученным от exchanger; сбои и недоступность exchanger - как 502 service_unavailable
+func ExchangerError(err error, message string) *APIError {
+	switch code := errcodes.FromError(err); code {
+	case errcodes.Internal, errcodes.ServiceUnavailable:
+		return NewAPIError(http.StatusBadGateway, CodeServiceUnavailable, message)
+	default:
+		var grpcErr interface{ GRPCStatus() *status.Status }
+		if errors.As(err, &grpcErr) {
+			message += ": " + grpcErr.GRPCStatus().Message()
+		}
+		return CodeError(code, message)
+	}
+}
+
+// AbortWithError прерывает обработку запроса и отвечает ошибкой API
+func AbortWithError(c *gin.Context, err *APIError) {
+	c.AbortWithStatusJSON(err.Status, ErrorResponse{Error: err})
+}
+
+// errorMapping соответствие ошибки сервисного слоя коду API; статус ответа
+// берется из реестра кодов
+type errorMapping struct {
+	err  error
+	code errcodes.Code
+}
+
+// serviceErrors ошибки сервисного слоя, возвращаемые клиенту с текстом ошибки
+var serviceErrors = []errorMapping{
+	{service.ErrUserExists, errcodes.UserExists},
+	{service.ErrInvalidCredentials, errcodes.InvalidCredentials},
+	{service.ErrWeakPassword, errcodes.WeakPassword},
+	{service.ErrUserNotFound, errcodes.NotFound},
+	{service.ErrNotFound, errcodes.NotFound},
+	{service.ErrInvalidAmount, errcodes.InvalidAmount},
+	{service.ErrUnsupportedCurrency, errcodes.UnsupportedCurrency},
+	{service.ErrSameCurrency, errcodes.SameCurrency},
+	{service.ErrInsufficientFunds, errcodes.InsufficientFunds},
+	{service.ErrLimitExceeded, errcodes.LimitExceeded},
+	{service.ErrAccountFrozen, errcodes.AccountFrozen},
+	{service.ErrAccountClosed, errcodes.AccountClosed},
+	{service.ErrBalanceNotEmpty, errcodes.BalanceNotEmpty},
+	{service.ErrVerificationPending, errcodes.AlreadyExists},
+	{service.ErrInvalidArgument, errcodes.InvalidRequest},
+	{service.ErrStaleRate, errcodes.StaleRate},
+	{service.ErrExchangerUnavailable, errcodes.ServiceUnavailable},
+}
+
+// ErrorHandler отвечает на ошибку, добавленную обработчиком через c.Error.
+// Ошибки сервисного слоя переводятся в статус и код API; неизвестные ошибки
+// возвращаются клиенту как internal_error без подробностей (исходная ошибка
+// остается в c.Errors и попадает в лог через Logger)
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		apiErr := ToAPIError(c.Errors.Last().Err)
+		c.JSON(apiErr.Status, ErrorResponse{Error: apiErr})
+	}
+}
+
+// ToAPIError переводит ошибку в ошибку API
+func ToAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, m := range serviceErrors {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+
+		result := CodeError(m.code, err.Error())
+
+		var limitErr *service.LimitExceededError
+		if errors.As(err, &limitErr) {
+			result.Details = limitErr
+		}
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			result.Details = policyErr
+		}
+		return result
+	}
+
+	return CodeError(errcodes.Internal, "Internal server error")
+}
@@ -1,93 +1,211 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Типы токенов
+const (
+	// TokenTypeAccess токен для доступа к API
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh токен только для получения нового access токена
+	TokenTypeRefresh = "refresh"
+)
+
+// Scopes определяют разрешенные операции access токена
+const (
+	ScopeWalletRead  = "wallet:read"
+	ScopeWalletWrite = "wallet:write"
+	ScopeExchange    = "exchange"
+	ScopeAdmin       = "admin"
 )
 
 // Claims структура JWT claims
 type Claims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Role      string   `json:"role"`
+	Scopes    []string `json:"scopes,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	// SessionID сессия, выданная при входе; 0 - токен выдан до появления сессий
+	SessionID int64 `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// SessionValidator проверяет сессии токенов (см. WalletService.ValidateSession)
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, userID, sessionID int64) error
+}
+
 // JWTMiddleware middleware для проверки JWT токенов
 type JWTMiddleware struct {
-	secret []byte
-	logger *logrus.Logger
+	keys       *JWTKeys
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	logger     *logrus.Logger
+	sessions   SessionValidator // проверка сессий токенов, см. SetSessionValidator
 }
 
-// NewJWTMiddleware создает новый JWT middleware
-func NewJWTMiddleware(secret string, logger *logrus.Logger) *JWTMiddleware {
+// NewJWTMiddleware создает новый JWT middleware с подписью HS256
+func NewJWTMiddleware(secret string, accessTTL, refreshTTL time.Duration, logger *logrus.Logger) *JWTMiddleware {
+	return NewJWTMiddlewareWithKeys(NewHMACKeys(secret), accessTTL, refreshTTL, logger)
+}
+
+// NewJWTMiddlewareWithKeys создает JWT middleware с ключами keys (см. LoadJWTKeys)
+func NewJWTMiddlewareWithKeys(keys *JWTKeys, accessTTL, refreshTTL time.Duration, logger *logrus.Logger) *JWTMiddleware {
 	return &JWTMiddleware{
-		secret: []byte(secret),
-		logger: logger,
+		keys:       keys,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		logger:     logger,
 	}
 }
 
+// SetSessionValidator включает проверку сессии каждого токена: токены отозванной
+// или истекшей сессии отклоняются до истечения их срока действия
+func (m *JWTMiddleware) SetSessionValidator(sessions SessionValidator) {
+	m.sessions = sessions
+}
+
+// RefreshTTL время жизни refresh токена и сессии
+func (m *JWTMiddleware) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// JWKS возвращает открытые ключи проверки токенов
+func (m *JWTMiddleware) JWKS() JWKSet {
+	return m.keys.JWKS()
+}
+
+// ScopesForRole возвращает scopes, выдаваемые пользователю с ролью role
+func ScopesForRole(role string) []string {
+	scopes := []string{ScopeWalletRead, ScopeWalletWrite, ScopeExchange}
+	if role == storages.RoleAdmin {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return scopes
+}
+
 // Auth middleware для аутентификации
 func (m *JWTMiddleware) Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Получаем токен из заголовка Authorization
 		authHeader := c.GetHeader("Authorization")
+		// Браузерный WebSocket API не передает заголовки, поэтому при установке
+		// WebSocket соединения токен принимается в параметре access_token
+		if authHeader == "" && websocket.IsWebSocketUpgrade(c.Request) {
+			if token := c.Query("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			AbortWithError(c, Unauthorized("Authorization header is required"))
 			return
 		}
 
 		// Проверяем формат "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			AbortWithError(c, Unauthorized("Invalid authorization header format"))
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Парсим и валидируем токен
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Проверяем алгоритм подписи
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return m.secret, nil
-		})
-
+		claims, err := m.ParseToken(parts[1])
 		if err != nil {
 			m.logger.Warnf("Invalid token: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			AbortWithError(c, Unauthorized("Invalid token"))
 			return
 		}
 
-		// Извлекаем claims
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-			// Сохраняем данные пользователя в контекст
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		// Refresh токен нельзя использовать для доступа к API
+		if claims.TokenType == TokenTypeRefresh {
+			AbortWithError(c, Unauthorized("Refresh token cannot be used for API access"))
 			return
 		}
+
+		// Токены, выданные до появления сессий, действуют до истечения срока
+		if claims.SessionID != 0 && m.sessions != nil {
+			if err := m.sessions.ValidateSession(c.Request.Context(), claims.UserID, claims.SessionID); err != nil {
+				if errors.Is(err, storages.ErrNotFound) {
+					AbortWithError(c, Unauthorized("Session is revoked or expired"))
+					return
+				}
+				m.logger.Errorf("Failed to validate session: %v", err)
+				AbortWithError(c, ToAPIError(err))
+				return
+			}
+		}
+
+		// Токены, выданные до появления scopes, получают scopes по роли
+		scopes := claims.Scopes
+		if claims.TokenType == "" {
+			scopes = ScopesForRole(claims.Role)
+		}
+
+		// Сохраняем данные пользователя в контекст
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("scopes", scopes)
+		if claims.SessionID != 0 {
+			c.Set("session_id", claims.SessionID)
+		}
+		c.Next()
+	}
+}
+
+// ParseToken проверяет подпись и срок действия токена и возвращает его claims.
+// Ключ выбирается по kid и алгоритму токена (см. JWTKeys)
+func (m *JWTMiddleware) ParseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keys.verificationKey)
+	if err != nil {
+		return nil, err
 	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	return claims, nil
 }
 
-// GenerateToken генерирует JWT токен для пользователя
-func (m *JWTMiddleware) GenerateToken(userID int64, username string, expiration time.Duration) (string, error) {
+// GenerateToken генерирует JWT токен типа tokenType для пользователя без сессии.
+// Access токен содержит scopes роли, refresh токен scopes не содержит
+func (m *JWTMiddleware) GenerateToken(userID int64, username, role, tokenType string) (string, error) {
+	return m.GenerateSessionToken(userID, username, role, tokenType, 0)
+}
+
+// GenerateSessionToken генерирует JWT токен типа tokenType сессии sessionID
+func (m *JWTMiddleware) GenerateSessionToken(userID int64, username, role, tokenType string, sessionID int64) (string, error) {
+	expiration := m.accessTTL
+	var scopes []string
+	switch tokenType {
+	case TokenTypeAccess:
+		scopes = ScopesForRole(role)
+	case TokenTypeRefresh:
+		expiration = m.refreshTTL
+	default:
+		return "", fmt.Errorf("unknown token type: %s", tokenType)
+	}
+
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		Scopes:    scopes,
+		TokenType: tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -95,8 +213,7 @@ func (m *JWTMiddleware) GenerateToken(userID int64, username string, expiration
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(m.secret)
+	tokenString, err := m.keys.signedString(claims)
 	if err != nil {
 		m.logger.Errorf("Failed to sign token: %v", err)
 		return "", fmt.Errorf("failed to generate token: %w", err)
@@ -120,6 +237,18 @@ func GetUserID(c *gin.Context) (int64, error) {
 	return id, nil
 }
 
+// GetSessionID извлекает ID сессии токена из контекста; false - запрос с ключом API
+// или токеном, выданным до появления сессий
+func GetSessionID(c *gin.Context) (int64, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := sessionID.(int64)
+	return id, ok
+}
+
 // GetUsername извлекает username из контекста
 func GetUsername(c *gin.Context) (string, error) {
 	username, exists := c.Get("username")
@@ -134,3 +263,76 @@ func GetUsername(c *gin.Context) (string, error) {
 
 	return name, nil
 }
+
+// GetRole извлекает роль пользователя из контекста
+func GetRole(c *gin.Context) (string, error) {
+	role, exists := c.Get("role")
+	if !exists {
+		return "", fmt.Errorf("role not found in context")
+	}
+
+	name, ok := role.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid role type")
+	}
+
+	return name, nil
+}
+
+// GetScopes извлекает scopes токена из контекста
+func GetScopes(c *gin.Context) ([]string, error) {
+	scopes, exists := c.Get("scopes")
+	if !exists {
+		return nil, fmt.Errorf("scopes not found in context")
+	}
+
+	list, ok := scopes.([]string)
+	if !ok {
+		return nil, fmt.Errorf("invalid scopes type")
+	}
+
+	return list, nil
+}
+
+// RequireScope middleware ограничивает доступ токенами, содержащими все указанные scopes.
+// Должен подключаться после Auth.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, err := GetScopes(c)
+		if err != nil {
+			AbortWithError(c, Forbidden("Forbidden"))
+			return
+		}
+
+		has := make(map[string]bool, len(granted))
+		for _, scope := range granted {
+			has[scope] = true
+		}
+
+		for _, scope := range scopes {
+			if !has[scope] {
+				AbortWithError(c, Forbidden("Insufficient scope: "+scope))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole middleware ограничивает доступ пользователями с одной из указанных ролей.
+// Должен подключаться после Auth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(c *gin.Context) {
+		role, err := GetRole(c)
+		if err != nil || !allowed[role] {
+			AbortWithError(c, Forbidden("Forbidden"))
+			return
+		}
+		c.Next()
+	}
+}
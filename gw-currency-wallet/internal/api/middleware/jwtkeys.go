@@ -0,0 +1,278 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Алгоритмы подписи JWT
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// JWTKeysConfig настройки ключей JWT
+type JWTKeysConfig struct {
+	// Algorithm алгоритм подписи новых токенов
+	Algorithm string
+	// Secret секрет HMAC для HS256
+	Secret string
+	// PrivateKeyFile PEM файл закрытого ключа RS256 или EdDSA
+	PrivateKeyFile string
+	// KeyID kid новых токенов; пусто - отпечаток ключа (RFC 7638), для HS256 без kid
+	KeyID string
+	// PreviousKeys прежние ключи kid:path (PEM файл открытого или закрытого ключа)
+	PreviousKeys []string
+	// PreviousSecret прежний секрет HMAC, токены без kid
+	PreviousSecret string
+	// PreviousUntil время, до которого принимаются токены прежних ключей; нулевое - без ограничения
+	PreviousUntil time.Time
+}
+
+// jwtKey ключ подписи или проверки JWT
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{} // секрет HMAC или закрытый ключ; nil - ключ только для проверки
+	verify interface{} // секрет HMAC или открытый ключ
+}
+
+// JWTKeys текущий ключ подписи токенов и прежние ключи, токены которых принимаются
+// до окончания ротации. Открытые ключи публикуются в JWKS для других сервисов
+type JWTKeys struct {
+	current       *jwtKey
+	previous      []*jwtKey
+	previousUntil time.Time
+	now           func() time.Time
+}
+
+// NewHMACKeys создает ключи HS256 с секретом secret, токены без kid
+func NewHMACKeys(secret string) *JWTKeys {
+	return &JWTKeys{
+		current: &jwtKey{method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)},
+		now:     time.Now,
+	}
+}
+
+// LoadJWTKeys загружает ключ подписи и прежние ключи по настройкам cfg
+func LoadJWTKeys(cfg JWTKeysConfig) (*JWTKeys, error) {
+	keys := &JWTKeys{previousUntil: cfg.PreviousUntil, now: time.Now}
+
+	switch cfg.Algorithm {
+	case JWTAlgorithmHS256, "":
+		keys.current = &jwtKey{id: cfg.KeyID, method: jwt.SigningMethodHS256, sign: []byte(cfg.Secret), verify: []byte(cfg.Secret)}
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		key, err := loadKeyFile(cfg.PrivateKeyFile, cfg.KeyID, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT private key: %w", err)
+		}
+		if key.method.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("JWT private key is for %s, not %s", key.method.Alg(), cfg.Algorithm)
+		}
+		keys.current = key
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", cfg.Algorithm)
+	}
+
+	if cfg.PreviousSecret != "" {
+		secret := []byte(cfg.PreviousSecret)
+		keys.previous = append(keys.previous, &jwtKey{method: jwt.SigningMethodHS256, verify: secret})
+	}
+	for _, item := range cfg.PreviousKeys {
+		kid, path, ok := strings.Cut(item, ":")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid previous JWT key %q (expected kid:path)", item)
+		}
+		if kid == keys.current.id {
+			return nil, fmt.Errorf("previous JWT key %s has the kid of the current key", kid)
+		}
+		key, err := loadKeyFile(path, kid, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load previous JWT key %s: %w", kid, err)
+		}
+		keys.previous = append(keys.previous, key)
+	}
+	return keys, nil
+}
+
+// loadKeyFile читает PEM файл ключа RSA или Ed25519. Для ключа подписи нужен
+// закрытый ключ, для проверки подходит и открытый
+func loadKeyFile(path, kid string, private bool) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key := &jwtKey{id: kid}
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.method, key.sign, key.verify = jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey
+		} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			signer := edKey.(ed25519.PrivateKey)
+			key.method, key.sign, key.verify = jwt.SigningMethodEdDSA, signer, signer.Public()
+		} else {
+			return nil, errors.New("unsupported private key (expected RSA or Ed25519)")
+		}
+	} else {
+		if private {
+			return nil, fmt.Errorf("expected a private key, got %s", block.Type)
+		}
+		if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			key.method, key.verify = jwt.SigningMethodRS256, rsaKey
+		} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			key.method, key.verify = jwt.SigningMethodEdDSA, edKey
+		} else {
+			return nil, errors.New("unsupported public key (expected RSA or Ed25519)")
+		}
+	}
+	// Закрытый ключ проверки прежнего ключа для подписи не используется
+	if !private {
+		key.sign = nil
+	}
+
+	if key.id == "" {
+		thumbprint, err := key.jwk().thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.id = thumbprint
+	}
+	return key, nil
+}
+
+// active возвращает текущий ключ и прежние ключи, если ротация не завершена
+func (k *JWTKeys) active() []*jwtKey {
+	keys := []*jwtKey{k.current}
+	if k.previousUntil.IsZero() || k.now().Before(k.previousUntil) {
+		keys = append(keys, k.previous...)
+	}
+	return keys
+}
+
+// verificationKey возвращает ключи проверки токена token: ключ с kid токена
+// и тем же алгоритмом. Токены без kid проверяются секретами HMAC
+func (k *JWTKeys) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg := token.Method.Alg()
+
+	var keys []jwt.VerificationKey
+	for _, key := range k.active() {
+		if key.method.Alg() != alg {
+			continue
+		}
+		if key.id == kid || (kid == "" && alg == JWTAlgorithmHS256) {
+			keys = append(keys, key.verify)
+		}
+	}
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("unknown signing key (kid %q, alg %s)", kid, alg)
+	case 1:
+		return keys[0], nil
+	default:
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}
+}
+
+// signedString подписывает токен с claims текущим ключом
+func (k *JWTKeys) signedString(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.current.method, claims)
+	if k.current.id != "" {
+		token.Header["kid"] = k.current.id
+	}
+	return token.SignedString(k.current.sign)
+}
+
+// JWK открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// N и E модуль и экспонента ключа RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve и X кривая и открытый ключ OKP (Ed25519)
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet набор открытых ключей для проверки токенов (JWKS)
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи текущего и действующих прежних ключей.
+// Секреты HMAC не публикуются
+func (k *JWTKeys) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.active() {
+		if jwk := key.jwk(); jwk.KeyType != "" {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// jwk возвращает открытый ключ key в формате JWK; для HMAC - пустой JWK
+func (key *jwtKey) jwk() JWK {
+	jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+	switch public := key.verify.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}
+	}
+	return jwk
+}
+
+// thumbprint возвращает отпечаток ключа по RFC 7638: SHA-256 обязательных полей
+// JWK в лексикографическом порядке
+func (jwk JWK) thumbprint() (string, error) {
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -1,12 +1,27 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// sensitiveRequestKey ключ контекста отметки SensitiveRequest
+const sensitiveRequestKey = "sensitive_request"
+
+// SensitiveRequest отмечает запросы, тело которых содержит учетные данные или
+// персональные данные (регистрация, вход, смена пароля, верификация). Для них Logger
+// пишет вместо текста ошибок только коды API: текст ошибок привязки и сервисного
+// слоя может содержать части тела запроса
+func SensitiveRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(sensitiveRequestKey, true)
+		c.Next()
+	}
+}
+
 // Logger middleware для логирования HTTP запросов
 func Logger(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -32,7 +47,15 @@ func Logger(logger *logrus.Logger) gin.HandlerFunc {
 		})
 
 		if len(c.Errors) > 0 {
-			entry.Error(c.Errors.String())
+			message := c.Errors.String()
+			if c.GetBool(sensitiveRequestKey) {
+				message = "Request failed: " + errorCodes(c)
+			}
+			if statusCode >= 500 {
+				entry.Error(message)
+			} else {
+				entry.Warn(message)
+			}
 		} else {
 			if statusCode >= 500 {
 				entry.Error("Internal server error")
@@ -44,3 +67,12 @@ func Logger(logger *logrus.Logger) gin.HandlerFunc {
 		}
 	}
 }
+
+// errorCodes перечисляет коды API ошибок запроса без их текста
+func errorCodes(c *gin.Context) string {
+	codes := make([]string, 0, len(c.Errors))
+	for _, err := range c.Errors {
+		codes = append(codes, ToAPIError(err.Err).Code)
+	}
+	return strings.Join(codes, ", ")
+}
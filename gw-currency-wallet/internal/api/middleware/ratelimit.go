@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gw-common/errcodes"
+	"gw-currency-wallet/internal/ratelimit"
+)
+
+// Заголовки ответа с состоянием лимита
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// RateLimiter ограничивает частоту запросов: по IP для публичных маршрутов
+// и по пользователю для маршрутов с авторизацией. nil RateLimiter ничего не ограничивает
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	logger  *logrus.Logger
+
+	mu     sync.RWMutex
+	public ratelimit.Rule
+	user   ratelimit.Rule
+}
+
+// NewRateLimiter создает middleware ограничения частоты запросов
+func NewRateLimiter(limiter ratelimit.Limiter, public, user ratelimit.Rule, logger *logrus.Logger) *RateLimiter {
+	return &RateLimiter{
+		limiter: limiter,
+		public:  public,
+		user:    user,
+		logger:  logger,
+	}
+}
+
+// SetRules меняет правила публичных маршрутов и пользователей без перезапуска.
+// Накопленные bucket сохраняются и пересчитываются по новому правилу
+func (r *RateLimiter) SetRules(public, user ratelimit.Rule) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.public = public
+	r.user = user
+}
+
+// rules возвращает действующие правила публичных маршрутов и пользователей
+func (r *RateLimiter) rules() (public, user ratelimit.Rule) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.public, r.user
+}
+
+// PerIP ограничивает запросы с одного IP адреса
+func (r *RateLimiter) PerIP() gin.HandlerFunc {
+	if r == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return r.limit(func(c *gin.Context) (string, ratelimit.Rule) {
+		public, _ := r.rules()
+		return "ip:" + c.ClientIP(), public
+	})
+}
+
+// PerUser ограничивает запросы одного пользователя. Подключается после
+// JWTMiddleware.Auth; без user_id в контексте запрос ограничивается по IP.
+// Ключ API с собственным лимитом ограничивается отдельно от пользователя
+func (r *RateLimiter) PerUser() gin.HandlerFunc {
+	if r == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return r.limit(func(c *gin.Context) (string, ratelimit.Rule) {
+		if keyID, ok := GetAPIKeyID(c); ok {
+			if rule, ok := c.Get("api_key_rate_limit"); ok {
+				return "apikey:" + strconv.FormatInt(keyID, 10), rule.(ratelimit.Rule)
+			}
+		}
+		_, user := r.rules()
+		if userID, err := GetUserID(c); err == nil {
+			return "user:" + strconv.FormatInt(userID, 10), user
+		}
+		return "ip:" + c.ClientIP(), user
+	})
+}
+
+// limit проверяет bucket ключа запроса по его правилу. Ошибка хранилища лимитов (например,
+// недоступен Redis) не блокирует API: запрос пропускается с предупреждением в логе
+func (r *RateLimiter) limit(key func(c *gin.Context) (string, ratelimit.Rule)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bucket, rule := key(c)
+		if !rule.Enabled() {
+			c.Next()
+			return
+		}
+
+		result, err := r.limiter.Allow(c.Request.Context(), bucket, rule)
+		if err != nil {
+			r.logger.Warnf("Rate limit check failed, allowing request: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(rule.Burst))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header(RetryAfterHeader, strconv.Itoa(retryAfter))
+			AbortWithError(c, CodeError(errcodes.RateLimited, "Too many requests, retry in "+strconv.Itoa(retryAfter)+"s"))
+			return
+		}
+
+		c.Next()
+	}
+}
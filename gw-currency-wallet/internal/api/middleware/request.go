@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gw-common/errcodes"
+)
+
+// RequestConfig ограничения входящих запросов
+type RequestConfig struct {
+	// MaxBodyBytes максимальный размер тела запроса, 0 - без ограничения
+	MaxBodyBytes int64
+	// StrictJSON отклонять JSON с полями, которых нет в запросе обработчика
+	StrictJSON bool
+	// Timeout срок обработки запроса (504 по истечении), 0 - без ограничения
+	Timeout time.Duration
+}
+
+// currencyFields поля JSON и параметры запроса с кодом валюты
+var currencyFields = map[string]bool{
+	"currency":      true,
+	"from_currency": true,
+	"to_currency":   true,
+	"base_currency": true,
+}
+
+// currencyKeyFields поля JSON - объекты, ключи которых коды валют
+var currencyKeyFields = map[string]bool{
+	"targets": true,
+}
+
+// SanitizeRequest ограничивает размер тела запроса (413 payload_too_large) и
+// приводит коды валют в теле JSON, параметрах запроса и пути к верхнему регистру
+// без пробелов до привязки к запросу обработчика. Некорректный JSON передается
+// обработчику без изменений, ошибку возвращает привязка
+func SanitizeRequest(cfg RequestConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		normalizeCurrencyParams(c)
+
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if cfg.MaxBodyBytes > 0 {
+			if c.Request.ContentLength > cfg.MaxBodyBytes {
+				AbortWithError(c, CodeError(errcodes.PayloadTooLarge, "Request body is too large"))
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes)
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				AbortWithError(c, CodeError(errcodes.PayloadTooLarge, "Request body is too large"))
+				return
+			}
+			AbortWithError(c, InvalidRequest("Failed to read request body"))
+			return
+		}
+
+		if c.ContentType() == gin.MIMEJSON {
+			body = normalizeCurrencyJSON(body)
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Next()
+	}
+}
+
+// normalizeCurrency приводит код валюты к виду, в котором он хранится
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// normalizeCurrencyParams нормализует коды валют в параметрах запроса и пути
+func normalizeCurrencyParams(c *gin.Context) {
+	query := c.Request.URL.Query()
+	changed := false
+	for name, values := range query {
+		if !currencyFields[name] {
+			continue
+		}
+		for i, value := range values {
+			if normalized := normalizeCurrency(value); normalized != value {
+				values[i] = normalized
+				changed = true
+			}
+		}
+	}
+	if changed {
+		c.Request.URL.RawQuery = query.Encode()
+	}
+
+	for i, param := range c.Params {
+		if currencyFields[param.Key] {
+			c.Params[i].Value = normalizeCurrency(param.Value)
+		}
+	}
+}
+
+// normalizeCurrencyJSON возвращает тело с нормализованными кодами валют
+// или исходное тело, если менять нечего или это не JSON
+func normalizeCurrencyJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return body
+	}
+	if !normalizeCurrencyValue(value) {
+		return body
+	}
+
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// normalizeCurrencyValue нормализует коды валют во вложенных объектах и массивах
+// и сообщает, изменилось ли значение
+func normalizeCurrencyValue(value any) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if code, ok := field.(string); ok && currencyFields[key] {
+				if normalized := normalizeCurrency(code); normalized != code {
+					v[key] = normalized
+					changed = true
+				}
+				continue
+			}
+			if object, ok := field.(map[string]any); ok && currencyKeyFields[key] && normalizeCurrencyKeys(object) {
+				changed = true
+			}
+			if normalizeCurrencyValue(field) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if normalizeCurrencyValue(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// normalizeCurrencyKeys нормализует ключи объекта. Ключ, который после
+// нормализации совпал бы с другим, не меняется: повтор валюты отклонит обработчик
+func normalizeCurrencyKeys(object map[string]any) bool {
+	changed := false
+	for key, field := range object {
+		normalized := normalizeCurrency(key)
+		if normalized == key {
+			continue
+		}
+		if _, exists := object[normalized]; exists {
+			continue
+		}
+		delete(object, key)
+		object[normalized] = field
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gw-common/errcodes"
+)
+
+// RequestTimeout ограничивает время обработки запроса: контекст запроса получает
+// срок, по истечении которого вызовы БД и exchanger с этим контекстом отменяются.
+// nil RequestTimeout или нулевой срок ничего не ограничивает
+type RequestTimeout struct {
+	timeout  time.Duration
+	exceeded atomic.Int64
+}
+
+// NewRequestTimeout создает middleware срока обработки запроса
+func NewRequestTimeout(timeout time.Duration) *RequestTimeout {
+	return &RequestTimeout{timeout: timeout}
+}
+
+// Handler задает срок контексту запроса. Если срок истек, а ответ еще не отправлен,
+// запрос завершается 504 timeout; WebSocket соединения не ограничиваются
+func (t *RequestTimeout) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t == nil || t.timeout <= 0 || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), t.timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		t.exceeded.Add(1)
+		// Ошибку отвечает ErrorHandler по последней ошибке запроса; исходная ошибка
+		// обработчика остается в c.Errors и попадает в лог
+		if !c.Writer.Written() {
+			c.Error(CodeError(errcodes.Timeout, "Request timed out"))
+		}
+	}
+}
+
+// DeadlineExceeded возвращает число запросов, срок обработки которых истек
+func (t *RequestTimeout) DeadlineExceeded() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.exceeded.Load()
+}
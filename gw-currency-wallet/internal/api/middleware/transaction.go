@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TxRunner выполняет функцию в транзакции БД
+type TxRunner interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// errRollback обработчик завершился ошибкой, транзакция откатывается
+var errRollback = errors.New("request failed, transaction rolled back")
+
+// Transaction выполняет запрос в одной транзакции БД. Подключается к маршрутам,
+// обработчики которых выполняют несколько записей. Транзакция фиксируется,
+// если обработчик не добавил ошибок и ответил статусом ниже 400, иначе откатывается.
+// Ответ буферизуется и отправляется после фиксации, чтобы клиент не получил
+// успешный ответ на изменения, которые не удалось зафиксировать
+func Transaction(runner TxRunner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		request := c.Request
+		c.Writer = writer
+
+		err := runner.WithTransaction(request.Context(), func(ctx context.Context) error {
+			c.Request = request.WithContext(ctx)
+			c.Next()
+
+			if len(c.Errors) > 0 || writer.status >= http.StatusBadRequest {
+				return errRollback
+			}
+			return nil
+		})
+
+		c.Writer = writer.ResponseWriter
+		c.Request = request
+
+		if err != nil && !errors.Is(err, errRollback) {
+			// Изменения не зафиксированы, ответ обработчика отбрасывается
+			c.Error(err)
+			return
+		}
+
+		writer.flush()
+	}
+}
+
+// bufferedWriter накапливает ответ обработчика до фиксации транзакции
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+// WriteHeader запоминает статус ответа
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow отмечает ответ как начатый
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+// Write добавляет данные в буфер
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+// WriteString добавляет строку в буфер
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+// Status возвращает статус ответа
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+// Size возвращает размер буферизованного тела ответа; -1, если ответ не начат
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+// Written проверяет, начат ли ответ
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// flush отправляет буферизованный ответ клиенту
+func (w *bufferedWriter) flush() {
+	if !w.written {
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
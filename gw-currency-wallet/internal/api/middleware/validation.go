@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Правила ошибок разбора JSON; остальные правила - теги binding валидатора
+const (
+	RuleType         = "type"
+	RuleUnknownField = "unknown_field"
+)
+
+// FieldError ошибка поля запроса для показа клиенту рядом с полем формы
+type FieldError struct {
+	// Field путь поля в JSON запроса: amount, targets[USD]
+	Field string `json:"field"`
+	// Rule нарушенное правило: тег binding (required, oneof, ...), type или unknown_field
+	Rule string `json:"rule"`
+	// Param параметр правила: 3 для len=3, список значений для oneof, тип JSON для type
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationDetails детали ошибки invalid_request с ошибками полей
+type ValidationDetails struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Валидатор gin называет поля по тегу json, чтобы в ошибках были имена полей
+// запроса, а не структуры. Имена кешируются при первой проверке структуры,
+// поэтому настройка выполняется при загрузке пакета
+func init() {
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName возвращает имя поля в JSON; поля без тега json называются как в структуре
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// BindingError переводит ошибку привязки запроса (ShouldBindJSON) в ошибку
+// invalid_request. Ошибки валидации и типов полей возвращаются по полям в details
+func BindingError(err error) *APIError {
+	// Синтаксические ошибки JSON к полям не относятся
+	fields := fieldErrors(err)
+	if len(fields) == 0 {
+		return InvalidRequest("Invalid request: " + err.Error())
+	}
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	apiErr := InvalidRequest("Invalid request: " + strings.Join(messages, "; "))
+	apiErr.Details = ValidationDetails{Fields: fields}
+	return apiErr
+}
+
+// fieldErrors возвращает ошибки полей из ошибки привязки или nil
+func fieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fieldErr.Namespace()),
+				Rule:    fieldErr.Tag(),
+				Param:   fieldErr.Param(),
+				Message: ruleMessage(fieldErr),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    RuleType,
+			Param:   jsonType(typeErr.Type),
+			Message: "must be of type " + jsonType(typeErr.Type),
+		}}
+	}
+
+	// Неизвестное поле при HTTP_STRICT_JSON: json: unknown field "role"
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return []FieldError{{
+			Field:   strings.Trim(name, `"`),
+			Rule:    RuleUnknownField,
+			Message: "is an unknown field",
+		}}
+	}
+	return nil
+}
+
+// fieldPath убирает из пути валидатора имя структуры запроса: RebalanceRequest.targets[USD] -> targets[USD]
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// ruleMessage возвращает сообщение о нарушенном правиле без имени поля
+func ruleMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	// Для строк и списков ограничения длины, для чисел - значения
+	var unit string
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "len":
+		return "must be exactly " + param + unit
+	case "min":
+		return "must be at least " + param + unit
+	case "max":
+		return "must be at most " + param + unit
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be less than or equal to " + param
+	}
+	if param != "" {
+		return fmt.Sprintf("does not satisfy the %s=%s rule", fieldErr.Tag(), param)
+	}
+	return fmt.Sprintf("does not satisfy the %s rule", fieldErr.Tag())
+}
+
+// jsonType возвращает тип JSON значения, ожидаемый для поля типа t
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return t.String()
+}
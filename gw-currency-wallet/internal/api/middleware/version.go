@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Версии REST API. Версии обслуживаются одновременно общими обработчиками,
+// формат ответа выбирается по версии запроса
+const (
+	APIVersion1 = 1
+	APIVersion2 = 2
+)
+
+// APIVersions версии API, для которых регистрируются маршруты /api/v{N}
+var APIVersions = []int{APIVersion1, APIVersion2}
+
+// APIVersion middleware сохраняет в контексте версию API группы маршрутов
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("api_version", version)
+		c.Next()
+	}
+}
+
+// GetAPIVersion возвращает версию API запроса; маршруты вне /api/v{N} отвечают как v1
+func GetAPIVersion(c *gin.Context) int {
+	if version, ok := c.Get("api_version"); ok {
+		if v, ok := version.(int); ok {
+			return v
+		}
+	}
+	return APIVersion1
+}
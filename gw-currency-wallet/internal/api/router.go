@@ -1,19 +1,30 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
-	"gw-currency-wallet/internal/api/handlers"
-	"gw-currency-wallet/internal/api/middleware"
-	"gw-currency-wallet/internal/service"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gw-currency-wallet/internal/api/handlers"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/health"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
 )
 
 // SetupRouter настраивает и возвращает роутер с всеми эндпоинтами
 func SetupRouter(
 	walletService *service.WalletService,
 	jwtMiddleware *middleware.JWTMiddleware,
+	rateLimiter *middleware.RateLimiter,
+	checker *health.Checker,
+	requestConfig middleware.RequestConfig,
+	wsConfig handlers.WebSocketConfig,
+	graphqlConfig handlers.GraphQLConfig,
+	reloader handlers.ConfigReloader,
 	logger *logrus.Logger,
 	ginMode string,
 ) *gin.Engine {
@@ -25,41 +36,211 @@ func SetupRouter(
 	// Middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger(logger))
+	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.SanitizeRequest(requestConfig))
+	// Срок обработки задается после ErrorHandler, чтобы истечение срока стало ответом 504
+	requestTimeout := middleware.NewRequestTimeout(requestConfig.Timeout)
+	router.Use(requestTimeout.Handler())
+
+	// Привязка JSON в gin настраивается глобально для процесса
+	binding.EnableDecoderDisallowUnknownFields = requestConfig.StrictJSON
+
+	// Liveness и readiness
+	healthHandler := handlers.NewHealthHandler(checker)
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+	// Прежние адреса проверок сохранены для совместимости
+	router.GET("/health", healthHandler.Live)
+	router.GET("/ready", healthHandler.ReadyLegacy)
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Сведения о сборке
+	router.GET("/version", handlers.Version)
+
+	// Метрики клиента exchanger и HTTP запросов в формате Prometheus
+	metricsHandler := handlers.NewMetricsHandler(walletService, requestTimeout)
+	router.GET("/metrics", metricsHandler.Metrics)
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Инициализация handlers
-	authHandler := handlers.NewAuthHandler(walletService, jwtMiddleware, logger)
-	walletHandler := handlers.NewWalletHandler(walletService, logger)
-	exchangeHandler := handlers.NewExchangeHandler(walletService, logger)
+	// Обработчики общие для всех версий API, формат ответа выбирается по версии запроса
+	h := &apiHandlers{
+		auth:         handlers.NewAuthHandler(walletService, jwtMiddleware, logger),
+		wallet:       handlers.NewWalletHandler(walletService, logger),
+		exchange:     handlers.NewExchangeHandler(walletService, logger),
+		admin:        handlers.NewAdminHandler(walletService, logger),
+		ws:           handlers.NewWebSocketHandler(walletService, wsConfig, logger),
+		schedule:     handlers.NewScheduleHandler(walletService, logger),
+		withdrawal:   handlers.NewWithdrawalHandler(walletService, logger),
+		apiKey:       handlers.NewAPIKeyHandler(walletService, logger),
+		verification: handlers.NewVerificationHandler(walletService, logger),
+		session:      handlers.NewSessionHandler(walletService, logger),
+		statement:    handlers.NewStatementHandler(walletService, logger),
+		webhook:      handlers.NewWebhookHandler(walletService, logger),
+		transaction:  handlers.NewTransactionHandler(walletService, logger),
+	}
+	if graphqlConfig.Enabled {
+		h.graphql = handlers.NewGraphQLHandler(walletService, graphqlConfig, logger)
+	}
+	// Перечитывание конфигурации доступно, если сервис передал reloader
+	if reloader != nil {
+		h.config = handlers.NewConfigHandler(reloader, logger)
+	}
+
+	// Открытые ключи проверки токенов
+	router.GET("/.well-known/jwks.json", h.auth.JWKS)
+
+	// Версии API /api/v1, /api/v2, ... с одинаковыми маршрутами
+	for _, version := range middleware.APIVersions {
+		group := router.Group(fmt.Sprintf("/api/v%d", version), middleware.APIVersion(version))
+		registerAPIRoutes(group, h, walletService, jwtMiddleware, rateLimiter)
+	}
+
+	return router
+}
+
+// apiHandlers обработчики маршрутов API
+type apiHandlers struct {
+	auth         *handlers.AuthHandler
+	wallet       *handlers.WalletHandler
+	exchange     *handlers.ExchangeHandler
+	admin        *handlers.AdminHandler
+	ws           *handlers.WebSocketHandler
+	schedule     *handlers.ScheduleHandler
+	withdrawal   *handlers.WithdrawalHandler
+	apiKey       *handlers.APIKeyHandler
+	verification *handlers.VerificationHandler
+	session      *handlers.SessionHandler
+	statement    *handlers.StatementHandler
+	webhook      *handlers.WebhookHandler
+	transaction  *handlers.TransactionHandler
+	// graphql nil, если GraphQL отключен
+	graphql *handlers.GraphQLHandler
+	// config nil без перечитывания конфигурации
+	config *handlers.ConfigHandler
+}
+
+// registerAPIRoutes регистрирует маршруты одной версии API в группе api
+func registerAPIRoutes(
+	api *gin.RouterGroup,
+	h *apiHandlers,
+	walletService *service.WalletService,
+	jwtMiddleware *middleware.JWTMiddleware,
+	rateLimiter *middleware.RateLimiter,
+) {
+	// Public routes (без авторизации), лимит запросов по IP
+	public := api.Group("")
+	public.Use(rateLimiter.PerIP())
+	{
+		// Пользователь и начальные балансы создаются в одной транзакции
+		public.POST("/register", middleware.SensitiveRequest(), middleware.Transaction(walletService), h.auth.Register)
+		public.POST("/login", middleware.SensitiveRequest(), h.auth.Login)
+		public.POST("/refresh", middleware.SensitiveRequest(), h.auth.Refresh)
+	}
 
-	// API v1 routes
-	v1 := router.Group("/api/v1")
+	// Protected routes (требуют JWT или ключ API), лимит запросов по пользователю
+	// или по ключу API с собственным лимитом
+	authorized := api.Group("")
+	authorized.Use(jwtMiddleware.AuthWithAPIKey(walletService), rateLimiter.PerUser())
 	{
-		// Public routes (без авторизации)
-		v1.POST("/register", authHandler.Register)
-		v1.POST("/login", authHandler.Login)
-
-		// Protected routes (требуют авторизации)
-		authorized := v1.Group("")
-		authorized.Use(jwtMiddleware.Auth())
-		{
-			// Wallet operations
-			authorized.GET("/balance", walletHandler.GetBalance)
-			authorized.POST("/wallet/deposit", walletHandler.Deposit)
-			authorized.POST("/wallet/withdraw", walletHandler.Withdraw)
-
-			// Exchange operations
-			authorized.GET("/exchange/rates", exchangeHandler.GetRates)
-			authorized.POST("/exchange", exchangeHandler.Exchange)
+		// Wallet operations
+		authorized.GET("/balance", middleware.RequireScope(middleware.ScopeWalletRead), h.wallet.GetBalance)
+		authorized.GET("/balance/total", middleware.RequireScope(middleware.ScopeWalletRead), h.wallet.GetBalanceTotal)
+		authorized.GET("/balance/history", middleware.RequireScope(middleware.ScopeWalletRead), h.wallet.GetBalanceHistory)
+		authorized.GET("/transactions", middleware.RequireScope(middleware.ScopeWalletRead), h.transaction.ListTransactions)
+		authorized.GET("/transactions/export", middleware.RequireScope(middleware.ScopeWalletRead), h.statement.Export)
+		authorized.POST("/wallet/deposit", middleware.RequireScope(middleware.ScopeWalletWrite), h.wallet.Deposit)
+		authorized.POST("/wallet/withdraw", middleware.RequireScope(middleware.ScopeWalletWrite), h.wallet.Withdraw)
+		authorized.GET("/wallet/withdrawals/pending", middleware.RequireScope(middleware.ScopeWalletRead), h.withdrawal.ListPending)
+		authorized.POST("/wallet/withdrawals/:id/cancel", middleware.RequireScope(middleware.ScopeWalletWrite), h.withdrawal.Cancel)
+
+		// Exchange operations
+		authorized.GET("/exchange/rates", middleware.RequireScope(middleware.ScopeWalletRead), h.exchange.GetRates)
+		authorized.GET("/exchange/currencies", middleware.RequireScope(middleware.ScopeWalletRead), h.exchange.GetCurrencies)
+		authorized.POST("/exchange", middleware.RequireScope(middleware.ScopeExchange), h.exchange.Exchange)
+		// Обмены ребалансировки выполняются сервисом в одной транзакции
+		authorized.POST("/exchange/rebalance", middleware.RequireScope(middleware.ScopeExchange), h.exchange.Rebalance)
+
+		// Регулярные операции; для обмена по расписанию нужен также scope exchange
+		authorized.GET("/schedules", middleware.RequireScope(middleware.ScopeWalletRead), h.schedule.ListSchedules)
+		authorized.POST("/schedules", middleware.RequireScope(middleware.ScopeWalletWrite), h.schedule.CreateSchedule)
+		authorized.GET("/schedules/:id", middleware.RequireScope(middleware.ScopeWalletRead), h.schedule.GetSchedule)
+		authorized.PATCH("/schedules/:id", middleware.RequireScope(middleware.ScopeWalletWrite), h.schedule.UpdateSchedule)
+		authorized.DELETE("/schedules/:id", middleware.RequireScope(middleware.ScopeWalletWrite), h.schedule.DeleteSchedule)
+
+		// Обновления балансов и курсов в реальном времени
+		authorized.GET("/ws", middleware.RequireScope(middleware.ScopeWalletRead), h.ws.Live)
+
+		// Вебхуки отправляют данные счета на внешний адрес, поэтому регистрируются
+		// и удаляются только со scope wallet:write
+		authorized.GET("/webhooks", middleware.RequireScope(middleware.ScopeWalletRead), h.webhook.ListWebhooks)
+		authorized.POST("/webhooks", middleware.RequireScope(middleware.ScopeWalletWrite), h.webhook.CreateWebhook)
+		authorized.DELETE("/webhooks/:id", middleware.RequireScope(middleware.ScopeWalletWrite), h.webhook.DeleteWebhook)
+		authorized.GET("/webhooks/:id/deliveries", middleware.RequireScope(middleware.ScopeWalletRead), h.webhook.ListDeliveries)
+
+		// GraphQL только читает данные, поэтому достаточно scope wallet:read
+		if h.graphql != nil {
+			authorized.GET("/graphql", middleware.RequireScope(middleware.ScopeWalletRead), h.graphql.Query)
+			authorized.POST("/graphql", middleware.RequireScope(middleware.ScopeWalletRead), h.graphql.Query)
+			authorized.GET("/graphql/schema", middleware.RequireScope(middleware.ScopeWalletRead), h.graphql.Schema)
 		}
 	}
 
-	return router
+	// Ключи API создаются и отзываются только с JWT, не другим ключом
+	apiKeys := api.Group("/api-keys")
+	apiKeys.Use(jwtMiddleware.Auth(), rateLimiter.PerUser())
+	{
+		apiKeys.GET("", middleware.RequireScope(middleware.ScopeWalletRead), h.apiKey.ListAPIKeys)
+		apiKeys.POST("", middleware.RequireScope(middleware.ScopeWalletWrite), h.apiKey.CreateAPIKey)
+		apiKeys.DELETE("/:id", middleware.RequireScope(middleware.ScopeWalletWrite), h.apiKey.RevokeAPIKey)
+	}
+
+	// Пароль меняется и учетная запись удаляется только с JWT и подтверждением паролем,
+	// сессиями также управляют только с JWT
+	user := api.Group("/user")
+	user.Use(jwtMiddleware.Auth(), rateLimiter.PerUser())
+	{
+		user.GET("/sessions", middleware.RequireScope(middleware.ScopeWalletRead), h.session.ListSessions)
+		user.DELETE("/sessions/:id", middleware.RequireScope(middleware.ScopeWalletWrite), h.session.RevokeSession)
+		user.PUT("/password", middleware.SensitiveRequest(), middleware.RequireScope(middleware.ScopeWalletWrite), h.auth.ChangePassword)
+		user.DELETE("", middleware.SensitiveRequest(), middleware.RequireScope(middleware.ScopeWalletWrite), h.auth.DeleteAccount)
+	}
+
+	// Верификация проходит только с JWT: ключи API не передают персональные данные
+	verification := api.Group("/verification")
+	verification.Use(jwtMiddleware.Auth(), rateLimiter.PerUser())
+	{
+		verification.GET("", middleware.RequireScope(middleware.ScopeWalletRead), h.verification.GetVerification)
+		verification.POST("", middleware.SensitiveRequest(), middleware.RequireScope(middleware.ScopeWalletWrite), h.verification.SubmitVerification)
+	}
+
+	// Admin routes (требуют роль admin и scope admin)
+	admin := api.Group("/admin")
+	admin.Use(jwtMiddleware.Auth(), rateLimiter.PerUser(), middleware.RequireRole(storages.RoleAdmin), middleware.RequireScope(middleware.ScopeAdmin))
+	{
+		admin.GET("/users", h.admin.ListUsers)
+		admin.GET("/users/:id/balances", h.admin.GetUserBalances)
+		admin.GET("/users/:id/ledger", h.admin.GetUserLedger)
+		admin.POST("/users/:id/exchange", h.admin.ExchangeForUser)
+		admin.POST("/users/:id/unfreeze", h.admin.UnfreezeUser)
+		admin.PUT("/users/:id/status", h.admin.ChangeUserStatus)
+		admin.GET("/users/:id/status-history", h.admin.GetUserStatusHistory)
+		admin.GET("/verifications", h.admin.ListVerificationRequests)
+		admin.POST("/verifications/:id/approve", h.admin.ApproveVerification)
+		admin.POST("/verifications/:id/reject", h.admin.RejectVerification)
+		admin.GET("/users/:id/limits", h.admin.GetUserLimits)
+		admin.PUT("/users/:id/limits", h.admin.SetUserLimit)
+		admin.DELETE("/users/:id/limits/:operation/:currency/:period", h.admin.DeleteUserLimit)
+		admin.GET("/users/:id/api-keys", h.admin.GetUserAPIKeys)
+		admin.PUT("/users/:id/api-keys/:key_id/rate-limit", h.admin.SetAPIKeyRateLimit)
+		admin.GET("/ledger/check", h.admin.CheckLedger)
+		admin.GET("/withdrawals/pending", h.admin.ListPendingWithdrawals)
+		admin.POST("/withdrawals/:id/approve", h.admin.ApproveWithdrawal)
+		admin.POST("/withdrawals/:id/reject", h.admin.RejectWithdrawal)
+		admin.GET("/exchanger/callers/:caller/pairs", h.admin.GetExchangerCallerPairs)
+		admin.PUT("/exchanger/callers/:caller/pairs", h.admin.SetExchangerCallerPairs)
+		if h.config != nil {
+			admin.POST("/config/reload", h.config.Reload)
+		}
+	}
 }
@@ -0,0 +1,200 @@
+// Package app собирает компоненты gw-currency-wallet по конфигурации и управляет
+// их жизненным циклом: New создает и связывает компоненты, Run запускает HTTP сервер
+// и фоновые задачи и останавливает их по этапам. cmd/main.go и интеграционные тесты
+// запускают сервис одинаково
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"gw-common/logger"
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/events"
+	"gw-currency-wallet/internal/grpc"
+	"gw-currency-wallet/internal/health"
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/shutdown"
+	"gw-currency-wallet/internal/storages"
+
+	"github.com/sirupsen/logrus"
+)
+
+// App собранный сервис кошелька
+type App struct {
+	cfg        *config.Config
+	configPath string
+	logger     *logrus.Logger
+
+	storage         storages.Storage
+	exchangerClient *grpc.ExchangerClient
+	kafkaProducer   *kafka.Producer
+	eventBus        *events.Bus
+	checker         *health.Checker
+	reloader        *config.Reloader
+	service         *service.WalletService
+	handler         http.Handler
+	server          *http.Server
+
+	// workers фоновые задачи, запускаемые Run
+	workers []func(ctx context.Context)
+}
+
+// Option настраивает App
+type Option func(*App)
+
+// WithLogger задает логгер; по умолчанию логгер создается по cfg.Logger
+func WithLogger(logger *logrus.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+// WithConfigPath задает файл конфигурации, который перечитывается по SIGHUP и
+// POST /api/v1/admin/config/reload; без него перечитывается только окружение
+func WithConfigPath(path string) Option {
+	return func(a *App) {
+		a.configPath = path
+	}
+}
+
+// New создает и связывает компоненты сервиса. БД обязательна: если она недоступна
+// дольше STARTUP_TIMEOUT, возвращается ошибка; без exchanger сервис создается в
+// режиме degraded. При ошибке уже созданные соединения закрываются
+func New(cfg *config.Config, opts ...Option) (*App, error) {
+	a := &App{cfg: cfg}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.logger == nil {
+		log, err := logger.NewWithConfig(cfg.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+		a.logger = log
+	}
+
+	if err := a.build(); err != nil {
+		a.close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Logger возвращает логгер сервиса
+func (a *App) Logger() *logrus.Logger {
+	return a.logger
+}
+
+// Service возвращает сервисный слой
+func (a *App) Service() *service.WalletService {
+	return a.service
+}
+
+// Storage возвращает хранилище
+func (a *App) Storage() storages.Storage {
+	return a.storage
+}
+
+// Handler возвращает HTTP обработчик API для вызова без сетевого сервера
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Run запускает фоновые задачи и HTTP сервер и работает до отмены ctx или ошибки
+// сервера, после чего останавливает компоненты по этапам (см. shutdown)
+func (a *App) Run(ctx context.Context) error {
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, run := range a.workers {
+		workers.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer workers.Done()
+			run(workersCtx)
+		}(run)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		a.logger.Infof("HTTP server is listening on port %s", a.cfg.Server.HTTPPort)
+		a.logger.Infof("Swagger documentation available at: http://localhost:%s/swagger/index.html", a.cfg.Server.HTTPPort)
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("HTTP server failed: %w", err)
+		}
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-serverErr:
+		a.logger.Error(runErr)
+	}
+	a.logger.Info("Shutting down server...")
+
+	if err := a.shutdownCoordinator(stopWorkers, &workers).Shutdown(); err != nil {
+		return errors.Join(runErr, err)
+	}
+	return runErr
+}
+
+// shutdownCoordinator описывает этапы остановки: компонент закрывается только после
+// остановки всех, кто его использует. Обработчики HTTP и фоновые задачи пишут в Kafka
+// и БД, поэтому producer и хранилище закрываются последними
+func (a *App) shutdownCoordinator(stopWorkers context.CancelFunc, workers *sync.WaitGroup) *shutdown.Coordinator {
+	coordinator := shutdown.New(a.logger)
+	coordinator.Add("HTTP server", a.cfg.Shutdown.HTTPTimeout, func(ctx context.Context) error {
+		// WebSocket соединения не отслеживаются srv.Shutdown: закрытие шины
+		// завершает их обработчики
+		a.eventBus.Close()
+		return a.server.Shutdown(ctx)
+	})
+	coordinator.Add("background workers", a.cfg.Shutdown.WorkersTimeout, func(ctx context.Context) error {
+		// Регулярные операции, доставка вебхуков, consumers, обновление курсов, outbox relay,
+		// проверки готовности и перечитывание конфигурации используют БД, exchanger и Kafka
+		stopWorkers()
+		finished := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	coordinator.Add("Kafka producer", a.cfg.Shutdown.KafkaTimeout, func(ctx context.Context) error {
+		// Close отправляет накопленные асинхронным producer сообщения
+		return a.kafkaProducer.Close()
+	})
+	coordinator.Add("exchanger client", a.cfg.Shutdown.StorageTimeout, func(ctx context.Context) error {
+		return a.exchangerClient.Close()
+	})
+	coordinator.Add("storage", a.cfg.Shutdown.StorageTimeout, func(ctx context.Context) error {
+		return a.storage.Close()
+	})
+	return coordinator
+}
+
+// close закрывает созданные соединения, если New не завершился
+func (a *App) close() {
+	if a.kafkaProducer != nil {
+		a.kafkaProducer.Close()
+	}
+	if a.exchangerClient != nil {
+		a.exchangerClient.Close()
+	}
+	if a.storage != nil {
+		a.storage.Close()
+	}
+}
+
+// addWorker добавляет фоновую задачу, которая работает до отмены ctx
+func (a *App) addWorker(run func(ctx context.Context)) {
+	a.workers = append(a.workers, run)
+}
@@ -0,0 +1,491 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gw-common/kafkasecurity"
+	"gw-common/logger"
+	"gw-currency-wallet/internal/api"
+	"gw-currency-wallet/internal/api/handlers"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/cache"
+	"gw-currency-wallet/internal/config"
+	"gw-currency-wallet/internal/events"
+	"gw-currency-wallet/internal/grpc"
+	"gw-currency-wallet/internal/health"
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/outbox"
+	"gw-currency-wallet/internal/password"
+	"gw-currency-wallet/internal/pricing"
+	"gw-currency-wallet/internal/ratelimit"
+	"gw-currency-wallet/internal/scheduler"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/storages/memory"
+	"gw-currency-wallet/internal/storages/postgres"
+	"gw-currency-wallet/internal/storages/sqlite"
+	"gw-currency-wallet/internal/webhook"
+
+	"github.com/sirupsen/logrus"
+)
+
+// build создает компоненты сервиса в порядке зависимостей
+func (a *App) build() error {
+	cfg, log := a.cfg, a.logger
+
+	// Подключение к базе данных. Без БД сервис работать не может,
+	// поэтому после STARTUP_TIMEOUT запуск прерывается
+	storage, err := ConnectStorage(cfg, log)
+	if err != nil {
+		return err
+	}
+	a.storage = storage
+
+	// Подключение к gRPC exchanger service
+	a.exchangerClient, err = newExchangerClient(&cfg.Exchanger, log)
+	if err != nil {
+		return fmt.Errorf("failed to connect to exchanger service: %w", err)
+	}
+
+	// Ожидание exchanger service. Без него сервис запускается в режиме degraded:
+	// курсы и обмен недоступны, пока exchanger не появится
+	if err := health.Retry(context.Background(), "Exchanger service", retryConfig(cfg), log, a.exchangerClient.Ping); err != nil {
+		log.Warnf("Starting in degraded mode: %v", err)
+	} else {
+		log.Info("Connected to exchanger service")
+	}
+
+	// Инициализация кеша курсов валют
+	ratesCache := cache.NewRatesCache(cfg.Cache.RatesTTL)
+	log.Info("Rates cache initialized")
+
+	// Инициализация кеша поддерживаемых валют
+	currenciesCache := cache.NewCurrenciesCache(cfg.Cache.CurrenciesTTL)
+
+	// Наценки на курс обмена по источникам операций
+	pricer := pricing.NewPricer(map[string]float64{
+		storages.ExchangeSourceAPI:       cfg.Pricing.APIMargin,
+		storages.ExchangeSourceScheduled: cfg.Pricing.ScheduledMargin,
+		storages.ExchangeSourceAdmin:     cfg.Pricing.AdminMargin,
+	})
+
+	// Комиссии за вывод и обмен
+	feeRules, err := pricing.ParseFeeRules(cfg.Pricing.Fees)
+	if err != nil {
+		return fmt.Errorf("invalid FEES: %w", err)
+	}
+	fees := pricing.NewFeeSchedule(feeRules)
+	log.Infof("Loaded %d fee rules", len(feeRules))
+
+	// SASL и TLS соединений с Kafka
+	kafkaSecurity, err := kafkasecurity.New(cfg.Kafka.Security)
+	if err != nil {
+		return fmt.Errorf("invalid Kafka security settings: %w", err)
+	}
+	if cfg.Kafka.Security.PlaintextCredentials() {
+		log.Warn("Kafka SASL PLAIN without TLS sends credentials in clear text")
+	}
+
+	// Инициализация Kafka producer
+	a.kafkaProducer, err = newProducer(&cfg.Kafka, kafkaSecurity, log)
+	if err != nil {
+		return err
+	}
+
+	// Проверка готовности зависимостей для /health/ready. Exchanger и Kafka
+	// необязательны: без exchanger недоступны курсы и обмен, а уведомления
+	// накапливаются в outbox до восстановления Kafka
+	a.checker = health.NewChecker(5*time.Second, log)
+	a.checker.Register("database", true, storage.Ping)
+	a.checker.Register("exchanger", false, a.exchangerClient.Ping)
+	a.checker.Register("kafka", false, a.kafkaProducer.Ping)
+	rateLimiter := newRateLimiter(cfg.RateLimit, a.checker, log)
+	a.checker.CheckAll(context.Background())
+	a.addWorker(func(ctx context.Context) {
+		a.checker.Run(ctx, cfg.Startup.ReadinessInterval)
+	})
+
+	// Создание сервисного слоя
+	walletService := service.NewWalletService(
+		storage,
+		a.exchangerClient,
+		ratesCache,
+		currenciesCache,
+		pricer,
+		fees,
+		a.kafkaProducer,
+		log,
+	)
+	a.service = walletService
+	log.Info("Wallet service initialized")
+
+	// Лимиты уровней верификации
+	tierLimits, err := service.ParseTierLimits(cfg.Verification.TierLimits)
+	if err != nil {
+		return fmt.Errorf("invalid VERIFICATION_TIER_LIMITS: %w", err)
+	}
+	walletService.SetTierLimits(tierLimits)
+
+	// Политика паролей для регистрации и смены пароля
+	passwordPolicy, err := newPasswordPolicy(&cfg.Password)
+	if err != nil {
+		return err
+	}
+	walletService.SetPasswordPolicy(passwordPolicy)
+
+	// Outbox relay: уведомления пишутся в outbox в транзакции БД
+	// и публикуются в Kafka отдельно, поэтому не теряются при сбоях Kafka
+	relay := outbox.NewRelay(storage, a.kafkaProducer, outbox.Config{
+		PollInterval:  cfg.Outbox.PollInterval,
+		BatchSize:     cfg.Outbox.BatchSize,
+		MaxAttempts:   cfg.Outbox.MaxAttempts,
+		RetryInterval: cfg.Outbox.RetryInterval,
+	}, log)
+	relay.SetAmountConverter(walletService.ReferenceAmount)
+	a.addWorker(relay.Run)
+
+	// Шина событий для обновлений в реальном времени (/api/v1/ws)
+	a.eventBus = events.NewBus(cfg.WebSocket.SendBuffer)
+	walletService.SetEventBus(a.eventBus)
+
+	// Фоновое обновление курсов до истечения TTL кеша
+	if cfg.Cache.RatesRefresh {
+		refresher := cache.NewRatesRefresher(ratesCache, walletService.RefreshExchangeRates,
+			cfg.Cache.RatesRefreshLead, cfg.Cache.RatesRefreshJitter, log)
+		a.addWorker(refresher.Run)
+	}
+
+	// Обновление курсов пар в кеше по событиям изменения курсов gw-exchanger
+	if cfg.Kafka.RatesTopic != "" {
+		ratesConsumer := kafka.NewRateUpdateConsumer(kafka.RateUpdateConsumerConfig{
+			Brokers:  cfg.Kafka.Brokers,
+			Topic:    cfg.Kafka.RatesTopic,
+			GroupID:  kafka.InstanceGroupID(cfg.Kafka.RatesGroupPrefix),
+			Security: kafkaSecurity,
+		}, walletService.ApplyRateUpdate, log)
+		a.addWorker(func(ctx context.Context) {
+			defer ratesConsumer.Close()
+			if err := ratesConsumer.Start(ctx); err != nil {
+				log.Errorf("Rate update consumer error: %v", err)
+			}
+		})
+	}
+
+	// Выполнение регулярных операций пользователей
+	if cfg.Scheduler.Enabled {
+		worker := scheduler.NewWorker(storage, walletService.ExecuteSchedule, scheduler.Config{
+			PollInterval:  cfg.Scheduler.PollInterval,
+			BatchSize:     cfg.Scheduler.BatchSize,
+			MaxAttempts:   cfg.Scheduler.MaxAttempts,
+			RetryInterval: cfg.Scheduler.RetryInterval,
+		}, log)
+		a.addWorker(worker.Run)
+	} else {
+		log.Info("Scheduler is disabled")
+	}
+
+	// Доставка вебхуков пользователей
+	if cfg.Webhook.Enabled {
+		worker := webhook.NewWorker(storage, webhook.Config{
+			PollInterval:  cfg.Webhook.PollInterval,
+			BatchSize:     cfg.Webhook.BatchSize,
+			MaxAttempts:   cfg.Webhook.MaxAttempts,
+			RetryInterval: cfg.Webhook.RetryInterval,
+			Timeout:       cfg.Webhook.Timeout,
+
+			AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+		}, log)
+		a.addWorker(worker.Run)
+	} else {
+		log.Info("Webhook delivery is disabled")
+	}
+
+	// Вывод с подтверждением и автоматическое подтверждение ожидающих выводов
+	if cfg.Withdraw.ApprovalRequired {
+		walletService.SetWithdrawalApproval(service.WithdrawalApproval{
+			Required:             true,
+			AutoApproveAfter:     cfg.Withdraw.AutoApproveAfter,
+			AutoApproveMaxAmount: cfg.Withdraw.AutoApproveMaxAmount,
+		})
+	}
+	if cfg.Withdraw.ApprovalRequired && cfg.Withdraw.AutoApproveAfter > 0 {
+		approver := scheduler.NewApprovalWorker(walletService.AutoApproveWithdrawals, cfg.Withdraw.PollInterval, log)
+		a.addWorker(approver.Run)
+	}
+
+	// Заморозка пользователей по управляющим сообщениям сервиса уведомлений
+	if cfg.Kafka.ControlTopic != "" {
+		controlConsumer := kafka.NewControlConsumer(kafka.ControlConsumerConfig{
+			Brokers:  cfg.Kafka.Brokers,
+			Topic:    cfg.Kafka.ControlTopic,
+			GroupID:  cfg.Kafka.ControlGroupID,
+			Security: kafkaSecurity,
+		}, walletService.ApplyControlMessage, log)
+		a.addWorker(func(ctx context.Context) {
+			defer controlConsumer.Close()
+			if err := controlConsumer.Start(ctx); err != nil {
+				log.Errorf("Control consumer error: %v", err)
+			}
+		})
+	}
+
+	// Демо-режим: пользователи с опубликованным паролем и статические курсы
+	if cfg.Demo.Enabled {
+		log.Warn("DEMO MODE is enabled: demo users with a published password are created, never use it in production")
+		walletService.EnableDemoRates()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_, err := walletService.SeedDemoData(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to seed demo data: %w", err)
+		}
+	}
+
+	// Назначение ролей администраторов из конфигурации
+	if len(cfg.JWT.AdminUsernames) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := walletService.EnsureAdmins(ctx, cfg.JWT.AdminUsernames); err != nil {
+			log.Warnf("Failed to assign admin roles: %v", err)
+		}
+		cancel()
+	}
+
+	// Создание JWT middleware
+	jwtKeys, err := middleware.LoadJWTKeys(middleware.JWTKeysConfig{
+		Algorithm:      cfg.JWT.Algorithm,
+		Secret:         cfg.JWT.Secret,
+		PrivateKeyFile: cfg.JWT.PrivateKeyFile,
+		KeyID:          cfg.JWT.KeyID,
+		PreviousKeys:   cfg.JWT.PreviousKeys,
+		PreviousSecret: cfg.JWT.PreviousSecret,
+		PreviousUntil:  cfg.JWT.PreviousKeysUntil,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	jwtMiddleware := middleware.NewJWTMiddlewareWithKeys(jwtKeys, cfg.JWT.Expiration, cfg.JWT.RefreshExpiration, log)
+	// Токены отозванных сессий отклоняются сразу, а не по истечении срока
+	jwtMiddleware.SetSessionValidator(walletService)
+
+	// Перечитывание конфигурации без перезапуска: по SIGHUP и POST /api/v1/admin/config/reload
+	a.reloader = config.NewReloader(a.configPath, cfg, func(next *config.Config) error {
+		overrides, err := kafka.ParseThresholdOverrides(next.Kafka.ThresholdOverrides)
+		if err != nil {
+			return fmt.Errorf("invalid KAFKA_THRESHOLD_OVERRIDES: %w", err)
+		}
+		if err := logger.SetLevel(log, next.Logger.Level); err != nil {
+			return err
+		}
+		ratesCache.SetTTL(next.Cache.RatesTTL)
+		a.kafkaProducer.SetThresholds(next.Kafka.TransferThreshold, overrides)
+		rateLimiter.SetRules(
+			ratelimit.Rule{Rate: next.RateLimit.PublicRate, Burst: next.RateLimit.PublicBurst},
+			ratelimit.Rule{Rate: next.RateLimit.UserRate, Burst: next.RateLimit.UserBurst},
+		)
+		return nil
+	}, log)
+	a.addWorker(a.reloader.Run)
+
+	// Настройка роутера и HTTP сервера
+	return a.newServer(rateLimiter, jwtMiddleware)
+}
+
+// newServer создает роутер и HTTP сервер
+func (a *App) newServer(rateLimiter *middleware.RateLimiter, jwtMiddleware *middleware.JWTMiddleware) error {
+	cfg := a.cfg
+	wsConfig := handlers.WebSocketConfig{
+		PingInterval:   cfg.WebSocket.PingInterval,
+		AllowedOrigins: cfg.WebSocket.AllowedOrigins,
+	}
+	requestConfig := middleware.RequestConfig{
+		MaxBodyBytes: cfg.Server.MaxBodyBytes,
+		StrictJSON:   cfg.Server.StrictJSON,
+		Timeout:      cfg.Server.RequestTimeout,
+	}
+	graphqlConfig := handlers.GraphQLConfig{
+		Enabled:  cfg.GraphQL.Enabled,
+		MaxDepth: cfg.GraphQL.MaxDepth,
+	}
+	router := api.SetupRouter(a.service, jwtMiddleware, rateLimiter, a.checker, requestConfig, wsConfig, graphqlConfig, a.reloader, a.logger, cfg.Server.GinMode)
+	// IP клиента для лимитов и логов берется из X-Forwarded-For только от доверенных прокси
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	a.handler = router
+
+	a.server = &http.Server{
+		Addr:         ":" + cfg.Server.HTTPPort,
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	return nil
+}
+
+// retryConfig параметры ожидания зависимостей при запуске
+func retryConfig(cfg *config.Config) health.RetryConfig {
+	return health.RetryConfig{
+		Timeout:     cfg.Startup.Timeout,
+		Interval:    cfg.Startup.RetryInterval,
+		MaxInterval: cfg.Startup.MaxRetryInterval,
+	}
+}
+
+// ConnectStorage подключается к БД, повторяя попытки в течение STARTUP_TIMEOUT
+func ConnectStorage(cfg *config.Config, log *logrus.Logger) (storages.Storage, error) {
+	var storage storages.Storage
+	err := health.Retry(context.Background(), "Database", retryConfig(cfg), log, func(ctx context.Context) error {
+		var err error
+		storage, err = NewStorage(&cfg.Database, log)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	log.Info("Database connection established")
+	return storage, nil
+}
+
+// NewStorage создает хранилище для драйвера из конфигурации
+func NewStorage(cfg *config.DatabaseConfig, log *logrus.Logger) (storages.Storage, error) {
+	switch cfg.Driver {
+	case config.DBDriverSQLite:
+		return sqlite.New(&sqlite.Config{Path: cfg.SQLitePath}, log)
+	case config.DBDriverMemory:
+		return memory.New(log), nil
+	default:
+		return postgres.New(&postgres.Config{
+			Host:            cfg.Host,
+			Port:            cfg.Port,
+			User:            cfg.User,
+			Password:        cfg.Password,
+			DBName:          cfg.DBName,
+			SSLMode:         cfg.SSLMode,
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			MigrateOnStart:  cfg.MigrateOnStart,
+		}, log)
+	}
+}
+
+// newExchangerClient создает gRPC клиент exchanger с повторами и circuit breaker
+func newExchangerClient(cfg *config.ExchangerConfig, log *logrus.Logger) (*grpc.ExchangerClient, error) {
+	return grpc.NewExchangerClient(
+		cfg.Host,
+		cfg.Port,
+		cfg.APIToken,
+		cfg.Timeout,
+		grpc.TransportOptions{
+			Compression:       cfg.Compression,
+			MaxRecvMsgSize:    cfg.MaxRecvMsgSize,
+			MaxSendMsgSize:    cfg.MaxSendMsgSize,
+			KeepaliveTime:     cfg.KeepaliveTime,
+			KeepaliveTimeout:  cfg.KeepaliveTimeout,
+			ReconnectMaxDelay: cfg.ReconnectMaxDelay,
+		},
+		grpc.CallPolicy{
+			Retry: grpc.RetryPolicy{
+				MaxAttempts:    cfg.RetryMaxAttempts,
+				InitialBackoff: cfg.RetryInitialBackoff,
+				MaxBackoff:     cfg.RetryMaxBackoff,
+			},
+			Breaker: grpc.BreakerPolicy{
+				FailureThreshold: cfg.BreakerFailureThreshold,
+				OpenTimeout:      cfg.BreakerOpenTimeout,
+			},
+		},
+		log,
+	)
+}
+
+// newProducer создает Kafka producer с порогами крупных переводов и маршрутами событий
+func newProducer(cfg *config.KafkaConfig, security *kafkasecurity.Security, log *logrus.Logger) (*kafka.Producer, error) {
+	// Пороги крупных переводов по валютам
+	thresholdOverrides, err := kafka.ParseThresholdOverrides(cfg.ThresholdOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KAFKA_THRESHOLD_OVERRIDES: %w", err)
+	}
+
+	// Маршрутизация событий по топикам Kafka
+	routes, err := kafka.ParseRoutes(cfg.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KAFKA_ROUTES: %w", err)
+	}
+
+	return kafka.NewProducer(&kafka.Config{
+		Brokers:            cfg.Brokers,
+		Topic:              cfg.Topic,
+		TransferThreshold:  cfg.TransferThreshold,
+		ThresholdCurrency:  cfg.ThresholdCurrency,
+		ThresholdOverrides: thresholdOverrides,
+		Sync:               cfg.Sync,
+		RequiredAcks:       cfg.RequiredAcks,
+		MessageFormat:      cfg.MessageFormat,
+		UserEventsTopic:    cfg.UserEventsTopic,
+		Routes:             routes,
+		Security:           security,
+	}, log), nil
+}
+
+// newPasswordPolicy создает политику паролей для регистрации и смены пароля
+func newPasswordPolicy(cfg *config.PasswordConfig) (*password.Policy, error) {
+	banned := cfg.Banned
+	if cfg.BannedFile != "" {
+		fromFile, err := password.LoadBannedList(cfg.BannedFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PASSWORD_BANNED_FILE: %w", err)
+		}
+		banned = append(banned, fromFile...)
+	}
+	policy, err := password.NewPolicy(password.Config{
+		MinLength:     cfg.MinLength,
+		RequireLower:  cfg.RequireLower,
+		RequireUpper:  cfg.RequireUpper,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		Banned:        banned,
+		MinScore:      cfg.MinScore,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid password policy: %w", err)
+	}
+	return policy, nil
+}
+
+// newRateLimiter создает ограничение частоты запросов. Redis регистрируется как
+// необязательная зависимость: при его недоступности запросы не ограничиваются
+func newRateLimiter(cfg config.RateLimitConfig, checker *health.Checker, log *logrus.Logger) *middleware.RateLimiter {
+	if !cfg.Enabled {
+		log.Info("Rate limiting is disabled")
+		return nil
+	}
+
+	var limiter ratelimit.Limiter
+	switch cfg.Backend {
+	case "redis":
+		redisLimiter := ratelimit.NewRedisLimiter(ratelimit.RedisConfig{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+			Prefix:   cfg.RedisPrefix,
+		})
+		checker.Register("redis", false, redisLimiter.Ping)
+		limiter = redisLimiter
+	default:
+		limiter = ratelimit.NewMemoryLimiter()
+	}
+
+	log.Infof("Rate limiting enabled (backend: %s, public: %.2f rps burst %d, user: %.2f rps burst %d)",
+		cfg.Backend, cfg.PublicRate, cfg.PublicBurst, cfg.UserRate, cfg.UserBurst)
+
+	return middleware.NewRateLimiter(limiter,
+		ratelimit.Rule{Rate: cfg.PublicRate, Burst: cfg.PublicBurst},
+		ratelimit.Rule{Rate: cfg.UserRate, Burst: cfg.UserBurst},
+		log)
+}
@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/storages"
+)
+
+// csvHeader столбцы выгрузки. Названия совпадают с форматом импорта истории
+// gw-notification (-backfill), поэтому распакованный архив можно импортировать
+var csvHeader = []string{
+	"id", "user_id", "type", "from_currency", "to_currency", "from_amount", "to_amount",
+	"exchange_rate", "market_rate", "margin", "source", "status", "created_at", "completed_at",
+}
+
+// Config параметры выгрузки
+type Config struct {
+	// Prefix префикс ключей объектов
+	Prefix string
+	// BatchSize число транзакций в одном запросе к БД и одном удалении
+	BatchSize int
+	// FileRows максимальное число транзакций в одном объекте
+	FileRows int
+}
+
+// Result итог выгрузки
+type Result struct {
+	Files    int   // загружено объектов
+	Archived int64 // выгружено транзакций
+	Deleted  int64 // удалено из БД
+}
+
+// Archiver выгружает старые транзакции в хранилище объектов в виде CSV,
+// сжатого gzip, и удаляет выгруженные из БД
+type Archiver struct {
+	storage storages.Storage
+	store   ObjectStore
+	cfg     Config
+	logger  *logrus.Logger
+}
+
+// New создает выгрузку транзакций
+func New(storage storages.Storage, store ObjectStore, cfg Config, logger *logrus.Logger) *Archiver {
+	return &Archiver{
+		storage: storage,
+		store:   store,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Archive выгружает транзакции, созданные раньше before. Транзакции удаляются
+// только после загрузки объекта, поэтому прерванную выгрузку можно повторить:
+// ключ объекта определяется диапазоном ID, и повтор перезаписывает тот же объект
+func (a *Archiver) Archive(ctx context.Context, before time.Time) (Result, error) {
+	var result Result
+	var afterID int64
+
+	for {
+		ids, data, err := a.buildFile(ctx, before, afterID)
+		if err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		key := fmt.Sprintf("%stransactions-%d-%d.csv.gz", a.cfg.Prefix, ids[0], ids[len(ids)-1])
+		if err := a.store.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), "application/gzip"); err != nil {
+			return result, fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		result.Files++
+		result.Archived += int64(len(ids))
+		a.logger.Infof("Uploaded %s: %d transactions, %d bytes", key, len(ids), len(data))
+
+		for start := 0; start < len(ids); start += a.cfg.BatchSize {
+			end := min(start+a.cfg.BatchSize, len(ids))
+			deleted, err := a.storage.DeleteArchivedTransactions(ctx, ids[start:end])
+			if err != nil {
+				return result, err
+			}
+			result.Deleted += deleted
+		}
+
+		afterID = ids[len(ids)-1]
+		if len(ids) < a.cfg.FileRows {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// buildFile читает до FileRows транзакций после afterID и возвращает их ID
+// и сжатый CSV
+func (a *Archiver) buildFile(ctx context.Context, before time.Time, afterID int64) ([]int64, []byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	var ids []int64
+	for len(ids) < a.cfg.FileRows {
+		limit := min(a.cfg.BatchSize, a.cfg.FileRows-len(ids))
+		transactions, err := a.storage.ListArchivableTransactions(ctx, before, afterID, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, tx := range transactions {
+			if err := w.Write(csvRecord(tx)); err != nil {
+				return nil, nil, fmt.Errorf("failed to write CSV record: %w", err)
+			}
+			ids = append(ids, tx.ID)
+			afterID = tx.ID
+		}
+
+		if len(transactions) < limit {
+			break
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to compress CSV: %w", err)
+	}
+
+	return ids, buf.Bytes(), nil
+}
+
+// csvRecord преобразует транзакцию в строку CSV. Время записывается в UTC (RFC 3339)
+func csvRecord(tx storages.Transaction) []string {
+	completedAt := ""
+	if tx.CompletedAt != nil {
+		completedAt = tx.CompletedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return []string{
+		strconv.FormatInt(tx.ID, 10),
+		strconv.FormatInt(tx.UserID, 10),
+		tx.Type,
+		tx.FromCurrency,
+		tx.ToCurrency,
+		formatAmount(tx.FromAmount),
+		formatAmount(tx.ToAmount),
+		formatAmount(tx.ExchangeRate),
+		formatAmount(tx.MarketRate),
+		formatAmount(tx.Margin),
+		tx.Source,
+		tx.Status,
+		tx.CreatedAt.UTC().Format(time.RFC3339Nano),
+		completedAt,
+	}
+}
+
+// formatAmount форматирует число без потери точности и экспоненты
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
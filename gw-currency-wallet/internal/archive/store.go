@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ObjectStore хранилище объектов для выгрузок
+type ObjectStore interface {
+	// PutObject загружает объект размером size, существующий объект с тем же ключом перезаписывается
+	PutObject(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+}
+
+// S3Config параметры S3-совместимого хранилища (AWS S3, MinIO, Ceph и т.п.)
+type S3Config struct {
+	Endpoint  string // host[:port] без схемы
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3Store загружает выгрузки в бакет S3-совместимого хранилища
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store создает клиент хранилища. Подключение не проверяется, см. CheckBucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	return &S3Store{client: client, bucket: cfg.Bucket}, nil
+}
+
+// CheckBucket проверяет доступность хранилища и наличие бакета
+func (s *S3Store) CheckBucket(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket %s: %w", s.bucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucket)
+	}
+	return nil
+}
+
+// PutObject загружает объект в бакет
+func (s *S3Store) PutObject(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
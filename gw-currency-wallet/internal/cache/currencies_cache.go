@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// CurrenciesCache кеш для списка поддерживаемых валют
+type CurrenciesCache struct {
+	currencies []string
+	mu         sync.RWMutex
+	ttl        time.Duration
+	lastUp     time.Time
+}
+
+// NewCurrenciesCache создает новый кеш валют
+func NewCurrenciesCache(ttl time.Duration) *CurrenciesCache {
+	return &CurrenciesCache{
+		ttl: ttl,
+	}
+}
+
+// Set сохраняет список валют в кеш
+func (c *CurrenciesCache) Set(currencies []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.currencies = append([]string(nil), currencies...)
+	c.lastUp = time.Now()
+}
+
+// Get возвращает список валют из кеша, если он актуален
+func (c *CurrenciesCache) Get() ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Проверяем, не истек ли TTL
+	if time.Since(c.lastUp) > c.ttl || len(c.currencies) == 0 {
+		return nil, false
+	}
+
+	// Возвращаем копию, чтобы избежать race condition
+	return append([]string(nil), c.currencies...), true
+}
+
+// Clear очищает кеш
+func (c *CurrenciesCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.currencies = nil
+	c.lastUp = time.Time{}
+}
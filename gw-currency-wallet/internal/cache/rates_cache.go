@@ -1,13 +1,17 @@
 package cache
 
 import (
+	"maps"
 	"sync"
 	"time"
 )
 
-// RatesCache кеш для курсов валют
+// RatesCache кеш для курсов валют: средних и, если exchanger их возвращает,
+// курсов покупки (bid) и продажи (ask)
 type RatesCache struct {
 	rates  map[string]float32
+	bids   map[string]float32
+	asks   map[string]float32
 	mu     sync.RWMutex
 	ttl    time.Duration
 	lastUp time.Time
@@ -21,12 +25,19 @@ func NewRatesCache(ttl time.Duration) *RatesCache {
 	}
 }
 
-// Set сохраняет курсы в кеш
+// Set сохраняет средние курсы в кеш
 func (c *RatesCache) Set(rates map[string]float32) {
+	c.SetWithSides(rates, nil, nil)
+}
+
+// SetWithSides сохраняет в кеш средние курсы вместе с курсами покупки и продажи
+func (c *RatesCache) SetWithSides(rates, bids, asks map[string]float32) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.rates = rates
+	c.bids = bids
+	c.asks = asks
 	c.lastUp = time.Now()
 }
 
@@ -64,15 +75,111 @@ func (c *RatesCache) GetRate(fromCurrency, toCurrency string) (float32, bool) {
 	return rate, exists
 }
 
+// GetBidRate возвращает курс обмена from -> to для продающего fromCurrency:
+// bid прямой пары, иначе 1/ask обратной пары. Без курсов покупки и продажи
+// возвращается средний курс прямой пары
+func (c *RatesCache) GetBidRate(fromCurrency, toCurrency string) (float32, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if time.Since(c.lastUp) > c.ttl {
+		return 0, false
+	}
+
+	if bid, ok := c.bids[fromCurrency+"_"+toCurrency]; ok && bid > 0 {
+		return bid, true
+	}
+	if ask, ok := c.asks[toCurrency+"_"+fromCurrency]; ok && ask > 0 {
+		return 1 / ask, true
+	}
+	if len(c.bids) > 0 {
+		return 0, false
+	}
+
+	rate, exists := c.rates[fromCurrency+"_"+toCurrency]
+	return rate, exists
+}
+
+// InvalidatePair удаляет из актуального кеша курсы пары: средний, покупки и продажи.
+// Остальные курсы и TTL не меняются. Возвращает false, если кеш пуст или устарел
+func (c *RatesCache) InvalidatePair(fromCurrency, toCurrency string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastUp) > c.ttl {
+		return false
+	}
+
+	// Карты могли быть переданы подписчикам, поэтому меняются копии
+	key := fromCurrency + "_" + toCurrency
+	c.rates = maps.Clone(c.rates)
+	delete(c.rates, key)
+	if c.bids != nil {
+		c.bids = maps.Clone(c.bids)
+		delete(c.bids, key)
+	}
+	if c.asks != nil {
+		c.asks = maps.Clone(c.asks)
+		delete(c.asks, key)
+	}
+	return true
+}
+
+// SetPair сохраняет курсы пары в актуальный кеш, не продлевая TTL. Курсы покупки
+// и продажи сохраняются, только если кеш их содержит. Возвращает false, если кеш
+// пуст или устарел: курсы пары придут со следующим обновлением всех курсов
+func (c *RatesCache) SetPair(fromCurrency, toCurrency string, rate, bid, ask float32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastUp) > c.ttl {
+		return false
+	}
+
+	key := fromCurrency + "_" + toCurrency
+	c.rates = maps.Clone(c.rates)
+	if c.rates == nil {
+		c.rates = make(map[string]float32)
+	}
+	c.rates[key] = rate
+	if c.bids != nil && bid > 0 {
+		c.bids = maps.Clone(c.bids)
+		c.bids[key] = bid
+	}
+	if c.asks != nil && ask > 0 {
+		c.asks = maps.Clone(c.asks)
+		c.asks[key] = ask
+	}
+	return true
+}
+
 // Clear очищает кеш
 func (c *RatesCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.rates = make(map[string]float32)
+	c.bids = nil
+	c.asks = nil
 	c.lastUp = time.Time{}
 }
 
+// SetTTL меняет TTL курсов без перезапуска; применяется и к уже сохраненным курсам
+func (c *RatesCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+}
+
+// ExpiresAt возвращает время, когда истечет TTL курсов. Для пустого кеша время уже прошло
+func (c *RatesCache) ExpiresAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lastUp.Add(c.ttl)
+}
+
 // IsValid проверяет, актуален ли кеш
 func (c *RatesCache) IsValid() bool {
 	c.mu.RLock()
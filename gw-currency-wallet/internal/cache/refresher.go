@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RatesRefresher заранее обновляет RatesCache незадолго до истечения TTL, чтобы
+// запросы пользователей не ждали ответа exchanger
+type RatesRefresher struct {
+	cache   *RatesCache
+	refresh func(ctx context.Context) error
+	// lead насколько раньше истечения TTL обновлять курсы
+	lead time.Duration
+	// jitter случайная добавка к lead, чтобы экземпляры не обращались к exchanger одновременно
+	jitter time.Duration
+	logger *logrus.Logger
+}
+
+// NewRatesRefresher создает фоновое обновление кеша курсов. refresh получает курсы
+// и сохраняет их в cache (см. WalletService.RefreshExchangeRates)
+func NewRatesRefresher(cache *RatesCache, refresh func(ctx context.Context) error, lead, jitter time.Duration, logger *logrus.Logger) *RatesRefresher {
+	return &RatesRefresher{
+		cache:   cache,
+		refresh: refresh,
+		lead:    lead,
+		jitter:  jitter,
+		logger:  logger,
+	}
+}
+
+// Run обновляет курсы до отмены контекста. Пустой кеш обновляется сразу,
+// после ошибки попытка повторяется через lead
+func (r *RatesRefresher) Run(ctx context.Context) {
+	r.logger.Infof("Rates cache refresher started (lead: %v, jitter: %v)", r.lead, r.jitter)
+
+	timer := time.NewTimer(r.nextRefresh())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Rates cache refresher stopped")
+			return
+		case <-timer.C:
+			wait := r.nextRefresh()
+			if wait == 0 {
+				if err := r.refresh(ctx); err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					r.logger.Warnf("Failed to refresh exchange rates, retrying in %v: %v", r.lead, err)
+					timer.Reset(r.lead)
+					continue
+				}
+				r.logger.Debug("Exchange rates cache refreshed")
+				wait = r.nextRefresh()
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// nextRefresh возвращает паузу до следующего обновления. Курсы, сохраненные
+// запросом пользователя, сдвигают обновление
+func (r *RatesRefresher) nextRefresh() time.Duration {
+	lead := r.lead
+	if r.jitter > 0 {
+		lead += rand.N(r.jitter)
+	}
+
+	// Для пустого кеша time.Until насыщается до минимальной длительности,
+	// поэтому сравниваем до вычитания
+	wait := time.Until(r.cache.ExpiresAt())
+	if wait <= lead {
+		return 0
+	}
+	return wait - lead
+}
@@ -3,32 +3,59 @@ package config
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
+	"gw-common/configfile"
+	"gw-common/env"
+	"gw-common/kafkasecurity"
+	"gw-common/logger"
 )
 
 // Config содержит всю конфигурацию приложения
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	JWT       JWTConfig
-	Exchanger ExchangerConfig
-	Cache     CacheConfig
-	Kafka     KafkaConfig
-	Logger    LoggerConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Exchanger    ExchangerConfig
+	Cache        CacheConfig
+	Pricing      PricingConfig
+	Kafka        KafkaConfig
+	Outbox       OutboxConfig
+	Scheduler    SchedulerConfig
+	Webhook      WebhookConfig
+	Withdraw     WithdrawConfig
+	Verification VerificationConfig
+	Password     PasswordConfig
+	Startup      StartupConfig
+	Shutdown     ShutdownConfig
+	Demo         DemoConfig
+	RateLimit    RateLimitConfig
+	Archive      ArchiveConfig
+	WebSocket    WebSocketConfig
+	GraphQL      GraphQLConfig
+	Logger       logger.Config
 }
 
 // ServerConfig содержит конфигурацию сервера
 type ServerConfig struct {
 	HTTPPort string
 	GinMode  string
+	// TrustedProxies прокси, которым доверяется X-Forwarded-For при определении
+	// IP клиента; пусто - IP берется из соединения
+	TrustedProxies []string
+	// MaxBodyBytes максимальный размер тела запроса, больше - 413
+	MaxBodyBytes int64
+	// StrictJSON отклонять тела запросов с неизвестными полями
+	StrictJSON bool
+	// RequestTimeout срок обработки запроса, 0 - без ограничения
+	RequestTimeout time.Duration
 }
 
 // DatabaseConfig содержит конфигурацию базы данных
 type DatabaseConfig struct {
+	Driver          string // postgres, sqlite, memory
+	SQLitePath      string // файл базы данных для драйвера sqlite
 	Host            string
 	Port            int
 	User            string
@@ -38,24 +65,71 @@ type DatabaseConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// MigrateOnStart применяет миграции PostgreSQL при запуске (см. команду migrate)
+	MigrateOnStart bool
 }
 
 // JWTConfig содержит конфигурацию JWT
 type JWTConfig struct {
-	Secret     string
-	Expiration time.Duration
+	// Algorithm алгоритм подписи: HS256 (секрет Secret), RS256 или EdDSA (ключ PrivateKeyFile)
+	Algorithm         string
+	Secret            string
+	PrivateKeyFile    string
+	KeyID             string
+	Expiration        time.Duration
+	RefreshExpiration time.Duration
+	// PreviousKeys прежние ключи kid:path и PreviousSecret прежний секрет HMAC: их токены
+	// принимаются до PreviousKeysUntil (нулевое - пока ключи заданы)
+	PreviousKeys      []string
+	PreviousSecret    string
+	PreviousKeysUntil time.Time
+	// AdminUsernames пользователи, которым при старте назначается роль admin
+	AdminUsernames []string
 }
 
 // ExchangerConfig содержит конфигурацию gRPC клиента для exchanger
 type ExchangerConfig struct {
-	Host    string
-	Port    string
-	Timeout time.Duration
+	Host        string
+	Port        string
+	APIToken    string // токен кошелька в API_TOKENS exchanger
+	Timeout     time.Duration
+	Compression string // сжатие запросов: gzip, none
+	// MaxRecvMsgSize и MaxSendMsgSize ограничения размера сообщений в байтах
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// Keepalive соединения и максимальная пауза между попытками переподключения
+	KeepaliveTime     time.Duration
+	KeepaliveTimeout  time.Duration
+	ReconnectMaxDelay time.Duration
+
+	// Повторы вызовов с экспоненциальной паузой
+	RetryMaxAttempts    int
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+	// Circuit breaker: открывается после BreakerFailureThreshold отказов подряд
+	// (0 - отключен), пробный вызов через BreakerOpenTimeout
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
 }
 
 // CacheConfig содержит конфигурацию кеша
 type CacheConfig struct {
-	RatesTTL time.Duration
+	RatesTTL      time.Duration
+	CurrenciesTTL time.Duration
+	// RatesRefresh включает фоновое обновление курсов за RatesRefreshLead
+	// (плюс случайная добавка до RatesRefreshJitter) до истечения RatesTTL
+	RatesRefresh       bool
+	RatesRefreshLead   time.Duration
+	RatesRefreshJitter time.Duration
+}
+
+// PricingConfig содержит наценки на курс обмена по источникам операции (доля от курса)
+type PricingConfig struct {
+	APIMargin       float64
+	ScheduledMargin float64
+	AdminMargin     float64
+	// Fees правила комиссий "operation:currency:value" через запятую (см. pricing.ParseFeeRules)
+	Fees string
 }
 
 // KafkaConfig содержит конфигурацию Kafka
@@ -63,18 +137,179 @@ type KafkaConfig struct {
 	Brokers           []string
 	Topic             string
 	TransferThreshold float64
+	// ThresholdCurrency валюта, в которой задан TransferThreshold
+	ThresholdCurrency string
+	// ThresholdOverrides пороги по валютам "currency:amount" через запятую (см. kafka.ParseThresholdOverrides)
+	ThresholdOverrides string
+	Sync               bool
+	RequiredAcks       string // all, one, none
+	MessageFormat      string // json, protobuf
+	UserEventsTopic    string // топик событий жизненного цикла пользователей, пусто - не отправлять
+	// ControlTopic топик управляющих сообщений сервиса уведомлений (заморозка
+	// пользователей), пусто - не читать. ControlGroupID - группа consumer
+	ControlTopic   string
+	ControlGroupID string
+	// RatesTopic топик событий изменения курсов gw-exchanger, пусто - не читать.
+	// RatesGroupPrefix - префикс группы consumer, уникальной для каждого экземпляра
+	RatesTopic       string
+	RatesGroupPrefix string
+	// Routes маршруты событий "event:topic[:threshold]" через запятую (см. kafka.ParseRoutes)
+	Routes string
+	// Security SASL и TLS соединений с брокерами (KAFKA_SASL_*, KAFKA_TLS_*)
+	Security kafkasecurity.Config
+}
+
+// OutboxConfig содержит конфигурацию outbox relay
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts число попыток отправки записи, после которого она откладывается насовсем
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором записи, затем растет вдвое
+	RetryInterval time.Duration
+}
+
+// SchedulerConfig содержит конфигурацию выполнения регулярных операций
+type SchedulerConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts число попыток запуска, после которого он пропускается до следующего по расписанию
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором запуска, затем растет вдвое
+	RetryInterval time.Duration
+}
+
+// WebhookConfig содержит конфигурацию доставки вебхуков
+type WebhookConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	// BatchSize число доставок, отправляемых одновременно
+	BatchSize int
+	// MaxAttempts число попыток, после которого доставка считается неудачной
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором доставки, затем растет вдвое
+	RetryInterval time.Duration
+	// Timeout время ожидания ответа получателя
+	Timeout time.Duration
+	// AllowPrivateNetworks разрешает доставку на loopback и адреса внутренних сетей
+	AllowPrivateNetworks bool
+}
+
+// WithdrawConfig содержит конфигурацию вывода с подтверждением
+type WithdrawConfig struct {
+	// ApprovalRequired выводы ожидают подтверждения, сумма с комиссией удерживается на балансе
+	ApprovalRequired bool
+	// AutoApproveAfter ожидающие дольше выводы подтверждаются автоматически, 0 - только администратором
+	AutoApproveAfter time.Duration
+	// AutoApproveMaxAmount автоматически подтверждаются выводы на сумму не больше (в валюте вывода), 0 - любые
+	AutoApproveMaxAmount float64
+	// PollInterval период проверки выводов для автоматического подтверждения
+	PollInterval time.Duration
+}
+
+// VerificationConfig содержит конфигурацию уровней верификации
+type VerificationConfig struct {
+	// TierLimits лимиты уровней "level:operation:currency:period:amount" через запятую
+	// (см. service.ParseTierLimits), пусто - уровень не влияет на лимиты
+	TierLimits string
+}
+
+// PasswordConfig содержит политику паролей для регистрации и смены пароля
+type PasswordConfig struct {
+	// MinLength минимальная длина пароля в символах
+	MinLength int
+	// RequireLower, RequireUpper, RequireDigit, RequireSymbol требуют символ класса
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Banned запрещенные пароли через запятую, BannedFile - файл с паролем в каждой строке
+	Banned     []string
+	BannedFile string
+	// MinScore минимальная оценка стойкости zxcvbn от 0 до 4, 0 - без оценки
+	MinScore int
+}
+
+// StartupConfig содержит параметры ожидания зависимостей при запуске и проверки готовности
+type StartupConfig struct {
+	Timeout           time.Duration
+	RetryInterval     time.Duration
+	MaxRetryInterval  time.Duration
+	ReadinessInterval time.Duration
+}
+
+// ShutdownConfig содержит таймауты этапов остановки сервиса: HTTP сервер дожидается
+// текущих запросов, затем останавливаются фоновые задачи, Kafka producer отправляет
+// накопленные сообщения и закрывается хранилище
+type ShutdownConfig struct {
+	HTTPTimeout    time.Duration
+	WorkersTimeout time.Duration
+	KafkaTimeout   time.Duration
+	StorageTimeout time.Duration
+}
+
+// DemoConfig содержит конфигурацию демо-режима
+type DemoConfig struct {
+	// Enabled создает демо-пользователей при запуске и включает демо-курсы,
+	// пока exchanger недоступен. Запрещен при GIN_MODE=release
+	Enabled bool
+}
+
+// RateLimitConfig содержит конфигурацию ограничения частоты запросов (token bucket)
+type RateLimitConfig struct {
+	Enabled bool
+	Backend string // memory, redis
+	// PublicRate и PublicBurst лимит публичных маршрутов по IP (запросов в секунду и запас)
+	PublicRate  float64
+	PublicBurst int
+	// UserRate и UserBurst лимит маршрутов с авторизацией по пользователю
+	UserRate      float64
+	UserBurst     int
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	RedisPrefix   string
+}
+
+// ArchiveConfig содержит конфигурацию выгрузки старых транзакций в S3 (команда archive)
+type ArchiveConfig struct {
+	// After возраст транзакций, после которого они выгружаются
+	After     time.Duration
+	BatchSize int // транзакций в одном запросе к БД
+	FileRows  int // транзакций в одном объекте
+	Prefix    string
+	// Параметры S3-совместимого хранилища
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// WebSocketConfig содержит конфигурацию обновлений в реальном времени (/api/v1/ws)
+type WebSocketConfig struct {
+	PingInterval time.Duration
+	// SendBuffer число неотправленных событий, после которого медленный клиент отключается
+	SendBuffer int
+	// AllowedOrigins разрешенные Origin браузерных клиентов, пусто - только тот же хост
+	AllowedOrigins []string
 }
 
-// LoggerConfig содержит конфигурацию логгера
-type LoggerConfig struct {
-	Level string
+// GraphQLConfig содержит конфигурацию эндпоинта /api/v1/graphql
+type GraphQLConfig struct {
+	Enabled bool
+	// MaxDepth максимальная вложенность полей запроса
+	MaxDepth int
 }
 
-// Load загружает конфигурацию из файла окружения
+// Load загружает конфигурацию из переменных окружения и файла конфигурации
+// (.env, YAML или JSON, см. gw-common/configfile). Переменные окружения важнее файла
 func Load(configPath string) (*Config, error) {
 	// Загрузка переменных окружения из файла
 	if configPath != "" {
-		if err := godotenv.Load(configPath); err != nil {
+		if err := configfile.Load(configPath); err != nil {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 	}
@@ -82,98 +317,381 @@ func Load(configPath string) (*Config, error) {
 	cfg := &Config{}
 
 	// Server
-	cfg.Server.HTTPPort = getEnv("HTTP_PORT", DefaultHTTPPort)
-	cfg.Server.GinMode = getEnv("GIN_MODE", DefaultGinMode)
-
-	// Database
-	cfg.Database.Host = getEnv("DB_HOST", DefaultDBHost)
-	cfg.Database.Port = getEnvInt("DB_PORT", DefaultDBPort)
-	cfg.Database.User = getEnv("DB_USER", DefaultDBUser)
-	cfg.Database.Password = getEnv("DB_PASSWORD", DefaultDBPassword)
-	cfg.Database.DBName = getEnv("DB_NAME", DefaultDBName)
-	cfg.Database.SSLMode = getEnv("DB_SSLMODE", DefaultDBSSLMode)
-	cfg.Database.MaxOpenConns = getEnvInt("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns)
-	cfg.Database.MaxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns)
-	cfg.Database.ConnMaxLifetime = getEnvDuration("DB_CONN_MAX_LIFETIME", DefaultDBConnMaxLifetime)
+	cfg.Server.HTTPPort = env.String("HTTP_PORT", DefaultHTTPPort)
+	cfg.Server.GinMode = env.String("GIN_MODE", DefaultGinMode)
+	cfg.Server.TrustedProxies = env.List("TRUSTED_PROXIES", nil)
+	cfg.Server.MaxBodyBytes = int64(env.Int("HTTP_MAX_BODY_BYTES", DefaultMaxBodyBytes))
+	cfg.Server.StrictJSON = env.Bool("HTTP_STRICT_JSON", DefaultStrictJSON)
+	cfg.Server.RequestTimeout = env.Duration("HTTP_REQUEST_TIMEOUT", DefaultRequestTimeout)
+
+	// Database: STORAGE_DRIVER - общее для сервисов имя, DB_DRIVER сохранен для совместимости
+	cfg.Database.Driver = env.String("STORAGE_DRIVER", env.String("DB_DRIVER", DefaultDBDriver))
+	cfg.Database.SQLitePath = env.String("DB_SQLITE_PATH", DefaultDBSQLitePath)
+	cfg.Database.Host = env.String("DB_HOST", DefaultDBHost)
+	cfg.Database.Port = env.Int("DB_PORT", DefaultDBPort)
+	cfg.Database.User = env.String("DB_USER", DefaultDBUser)
+	cfg.Database.Password = env.String("DB_PASSWORD", DefaultDBPassword)
+	cfg.Database.DBName = env.String("DB_NAME", DefaultDBName)
+	cfg.Database.SSLMode = env.String("DB_SSLMODE", DefaultDBSSLMode)
+	cfg.Database.MaxOpenConns = env.Int("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns)
+	cfg.Database.MaxIdleConns = env.Int("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns)
+	cfg.Database.ConnMaxLifetime = env.Duration("DB_CONN_MAX_LIFETIME", DefaultDBConnMaxLifetime)
+	cfg.Database.MigrateOnStart = env.Bool("DB_MIGRATE_ON_START", DefaultDBMigrateOnStart)
 
 	// JWT
-	cfg.JWT.Secret = getEnv("JWT_SECRET", DefaultJWTSecret)
-	cfg.JWT.Expiration = getEnvDuration("JWT_EXPIRATION", DefaultJWTExpiration)
+	cfg.JWT.Algorithm = env.String("JWT_ALGORITHM", DefaultJWTAlgorithm)
+	cfg.JWT.Secret = env.String("JWT_SECRET", DefaultJWTSecret)
+	cfg.JWT.PrivateKeyFile = env.String("JWT_PRIVATE_KEY_FILE", "")
+	cfg.JWT.KeyID = env.String("JWT_KEY_ID", "")
+	cfg.JWT.PreviousKeys = env.List("JWT_PREVIOUS_KEYS", nil)
+	cfg.JWT.PreviousSecret = env.String("JWT_PREVIOUS_SECRET", "")
+	if until := os.Getenv("JWT_PREVIOUS_KEYS_UNTIL"); until != "" {
+		parsed, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_PREVIOUS_KEYS_UNTIL (expected RFC 3339 time): %w", err)
+		}
+		cfg.JWT.PreviousKeysUntil = parsed
+	}
+	cfg.JWT.Expiration = env.Duration("JWT_EXPIRATION", DefaultJWTExpiration)
+	cfg.JWT.RefreshExpiration = env.Duration("JWT_REFRESH_EXPIRATION", DefaultJWTRefreshExpiration)
+	cfg.JWT.AdminUsernames = env.List("ADMIN_USERNAMES", nil)
 
 	// Exchanger gRPC
-	cfg.Exchanger.Host = getEnv("EXCHANGER_GRPC_HOST", DefaultExchangerHost)
-	cfg.Exchanger.Port = getEnv("EXCHANGER_GRPC_PORT", DefaultExchangerPort)
-	cfg.Exchanger.Timeout = getEnvDuration("EXCHANGER_GRPC_TIMEOUT", DefaultExchangerTimeout)
+	cfg.Exchanger.Host = env.String("EXCHANGER_GRPC_HOST", DefaultExchangerHost)
+	cfg.Exchanger.Port = env.String("EXCHANGER_GRPC_PORT", DefaultExchangerPort)
+	cfg.Exchanger.Timeout = env.Duration("EXCHANGER_GRPC_TIMEOUT", DefaultExchangerTimeout)
+	cfg.Exchanger.APIToken = env.String("EXCHANGER_API_TOKEN", "")
+	cfg.Exchanger.Compression = env.String("EXCHANGER_GRPC_COMPRESSION", DefaultExchangerCompression)
+	cfg.Exchanger.MaxRecvMsgSize = env.Int("EXCHANGER_GRPC_MAX_RECV_MSG_SIZE", DefaultExchangerMaxRecvMsgSize)
+	cfg.Exchanger.MaxSendMsgSize = env.Int("EXCHANGER_GRPC_MAX_SEND_MSG_SIZE", DefaultExchangerMaxSendMsgSize)
+	cfg.Exchanger.KeepaliveTime = env.Duration("EXCHANGER_GRPC_KEEPALIVE_TIME", DefaultExchangerKeepaliveTime)
+	cfg.Exchanger.KeepaliveTimeout = env.Duration("EXCHANGER_GRPC_KEEPALIVE_TIMEOUT", DefaultExchangerKeepaliveTimeout)
+	cfg.Exchanger.ReconnectMaxDelay = env.Duration("EXCHANGER_GRPC_RECONNECT_MAX_DELAY", DefaultExchangerReconnectMaxDelay)
+	cfg.Exchanger.RetryMaxAttempts = env.Int("EXCHANGER_RETRY_MAX_ATTEMPTS", DefaultExchangerRetryMaxAttempts)
+	cfg.Exchanger.RetryInitialBackoff = env.Duration("EXCHANGER_RETRY_INITIAL_BACKOFF", DefaultExchangerRetryInitialBackoff)
+	cfg.Exchanger.RetryMaxBackoff = env.Duration("EXCHANGER_RETRY_MAX_BACKOFF", DefaultExchangerRetryMaxBackoff)
+	cfg.Exchanger.BreakerFailureThreshold = env.Int("EXCHANGER_BREAKER_FAILURE_THRESHOLD", DefaultExchangerBreakerFailureThreshold)
+	cfg.Exchanger.BreakerOpenTimeout = env.Duration("EXCHANGER_BREAKER_OPEN_TIMEOUT", DefaultExchangerBreakerOpenTimeout)
 
 	// Cache
-	cfg.Cache.RatesTTL = getEnvDuration("CACHE_RATES_TTL", DefaultCacheRatesTTL)
+	cfg.Cache.RatesTTL = env.Duration("CACHE_RATES_TTL", DefaultCacheRatesTTL)
+	cfg.Cache.CurrenciesTTL = env.Duration("CACHE_CURRENCIES_TTL", DefaultCacheCurrenciesTTL)
+	cfg.Cache.RatesRefresh = env.Bool("CACHE_RATES_REFRESH_ENABLED", DefaultCacheRatesRefreshEnabled)
+	cfg.Cache.RatesRefreshLead = env.Duration("CACHE_RATES_REFRESH_LEAD", DefaultCacheRatesRefreshLead)
+	cfg.Cache.RatesRefreshJitter = env.Duration("CACHE_RATES_REFRESH_JITTER", DefaultCacheRatesRefreshJitter)
+
+	// Pricing
+	cfg.Pricing.APIMargin = env.Float("EXCHANGE_MARGIN_API", DefaultExchangeMarginAPI)
+	cfg.Pricing.ScheduledMargin = env.Float("EXCHANGE_MARGIN_SCHEDULED", DefaultExchangeMarginScheduled)
+	cfg.Pricing.AdminMargin = env.Float("EXCHANGE_MARGIN_ADMIN", DefaultExchangeMarginAdmin)
+	cfg.Pricing.Fees = env.String("FEES", "")
 
 	// Kafka
-	brokers := getEnv("KAFKA_BROKERS", DefaultKafkaBrokers)
+	brokers := env.String("KAFKA_BROKERS", DefaultKafkaBrokers)
 	cfg.Kafka.Brokers = []string{brokers} // В продакшене можно разбить по запятой
-	cfg.Kafka.Topic = getEnv("KAFKA_TOPIC", DefaultKafkaTopic)
-	cfg.Kafka.TransferThreshold = getEnvFloat("KAFKA_TRANSFER_THRESHOLD", DefaultKafkaTransferThreshold)
+	cfg.Kafka.Topic = env.String("KAFKA_TOPIC", DefaultKafkaTopic)
+	cfg.Kafka.TransferThreshold = env.Float("KAFKA_TRANSFER_THRESHOLD", DefaultKafkaTransferThreshold)
+	cfg.Kafka.ThresholdCurrency = strings.ToUpper(env.String("KAFKA_THRESHOLD_CURRENCY", DefaultKafkaThresholdCurrency))
+	cfg.Kafka.ThresholdOverrides = env.String("KAFKA_THRESHOLD_OVERRIDES", "")
+	cfg.Kafka.Sync = env.Bool("KAFKA_SYNC", DefaultKafkaSync)
+	cfg.Kafka.RequiredAcks = env.String("KAFKA_REQUIRED_ACKS", DefaultKafkaRequiredAcks)
+	cfg.Kafka.MessageFormat = strings.ToLower(env.String("KAFKA_MESSAGE_FORMAT", DefaultKafkaMessageFormat))
+	cfg.Kafka.UserEventsTopic = env.String("KAFKA_USER_EVENTS_TOPIC", DefaultKafkaUserEventsTopic)
+	cfg.Kafka.Routes = env.String("KAFKA_ROUTES", "")
+	cfg.Kafka.ControlTopic = env.String("KAFKA_CONTROL_TOPIC", "")
+	cfg.Kafka.ControlGroupID = env.String("KAFKA_CONTROL_GROUP_ID", DefaultKafkaControlGroupID)
+	cfg.Kafka.RatesTopic = env.String("KAFKA_RATES_TOPIC", "")
+	cfg.Kafka.RatesGroupPrefix = env.String("KAFKA_RATES_GROUP_PREFIX", DefaultKafkaRatesGroupPrefix)
+	cfg.Kafka.Security = kafkasecurity.FromEnv()
+
+	// Outbox
+	cfg.Outbox.PollInterval = env.Duration("OUTBOX_POLL_INTERVAL", DefaultOutboxPollInterval)
+	cfg.Outbox.BatchSize = env.Int("OUTBOX_BATCH_SIZE", DefaultOutboxBatchSize)
+	cfg.Outbox.MaxAttempts = env.Int("OUTBOX_MAX_ATTEMPTS", DefaultOutboxMaxAttempts)
+	cfg.Outbox.RetryInterval = env.Duration("OUTBOX_RETRY_INTERVAL", DefaultOutboxRetryInterval)
+
+	// Scheduler
+	cfg.Scheduler.Enabled = env.Bool("SCHEDULER_ENABLED", DefaultSchedulerEnabled)
+	cfg.Scheduler.PollInterval = env.Duration("SCHEDULER_POLL_INTERVAL", DefaultSchedulerPollInterval)
+	cfg.Scheduler.BatchSize = env.Int("SCHEDULER_BATCH_SIZE", DefaultSchedulerBatchSize)
+	cfg.Scheduler.MaxAttempts = env.Int("SCHEDULER_MAX_ATTEMPTS", DefaultSchedulerMaxAttempts)
+	cfg.Scheduler.RetryInterval = env.Duration("SCHEDULER_RETRY_INTERVAL", DefaultSchedulerRetryInterval)
+
+	// Webhook
+	cfg.Webhook.Enabled = env.Bool("WEBHOOK_ENABLED", DefaultWebhookEnabled)
+	cfg.Webhook.PollInterval = env.Duration("WEBHOOK_POLL_INTERVAL", DefaultWebhookPollInterval)
+	cfg.Webhook.BatchSize = env.Int("WEBHOOK_BATCH_SIZE", DefaultWebhookBatchSize)
+	cfg.Webhook.MaxAttempts = env.Int("WEBHOOK_MAX_ATTEMPTS", DefaultWebhookMaxAttempts)
+	cfg.Webhook.RetryInterval = env.Duration("WEBHOOK_RETRY_INTERVAL", DefaultWebhookRetryInterval)
+	cfg.Webhook.Timeout = env.Duration("WEBHOOK_TIMEOUT", DefaultWebhookTimeout)
+	cfg.Webhook.AllowPrivateNetworks = env.Bool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+
+	// Withdraw
+	cfg.Withdraw.ApprovalRequired = env.Bool("WITHDRAW_APPROVAL_REQUIRED", DefaultWithdrawApprovalRequired)
+	cfg.Withdraw.AutoApproveAfter = env.Duration("WITHDRAW_AUTO_APPROVE_AFTER", 0)
+	cfg.Withdraw.AutoApproveMaxAmount = env.Float("WITHDRAW_AUTO_APPROVE_MAX_AMOUNT", 0)
+	cfg.Withdraw.PollInterval = env.Duration("WITHDRAW_APPROVAL_POLL_INTERVAL", DefaultWithdrawApprovalPollInterval)
+
+	// Verification
+	cfg.Verification.TierLimits = env.String("VERIFICATION_TIER_LIMITS", "")
+
+	// Password policy
+	cfg.Password.MinLength = env.Int("PASSWORD_MIN_LENGTH", DefaultPasswordMinLength)
+	cfg.Password.RequireLower = env.Bool("PASSWORD_REQUIRE_LOWER", false)
+	cfg.Password.RequireUpper = env.Bool("PASSWORD_REQUIRE_UPPER", false)
+	cfg.Password.RequireDigit = env.Bool("PASSWORD_REQUIRE_DIGIT", false)
+	cfg.Password.RequireSymbol = env.Bool("PASSWORD_REQUIRE_SYMBOL", false)
+	cfg.Password.Banned = env.List("PASSWORD_BANNED", nil)
+	cfg.Password.BannedFile = env.String("PASSWORD_BANNED_FILE", "")
+	cfg.Password.MinScore = env.Int("PASSWORD_MIN_SCORE", 0)
+
+	// Startup
+	cfg.Startup.Timeout = env.Duration("STARTUP_TIMEOUT", DefaultStartupTimeout)
+	cfg.Startup.RetryInterval = env.Duration("STARTUP_RETRY_INTERVAL", DefaultStartupRetryInterval)
+	cfg.Startup.MaxRetryInterval = env.Duration("STARTUP_MAX_RETRY_INTERVAL", DefaultStartupMaxRetryInterval)
+	cfg.Startup.ReadinessInterval = env.Duration("READINESS_CHECK_INTERVAL", DefaultReadinessCheckInterval)
+
+	// Shutdown
+	cfg.Shutdown.HTTPTimeout = env.Duration("SHUTDOWN_HTTP_TIMEOUT", DefaultShutdownHTTPTimeout)
+	cfg.Shutdown.WorkersTimeout = env.Duration("SHUTDOWN_WORKERS_TIMEOUT", DefaultShutdownWorkersTimeout)
+	cfg.Shutdown.KafkaTimeout = env.Duration("SHUTDOWN_KAFKA_TIMEOUT", DefaultShutdownKafkaTimeout)
+	cfg.Shutdown.StorageTimeout = env.Duration("SHUTDOWN_STORAGE_TIMEOUT", DefaultShutdownStorageTimeout)
 
 	// Logger
-	cfg.Logger.Level = getEnv("LOG_LEVEL", DefaultLogLevel)
+	// Demo
+	cfg.Demo.Enabled = env.Bool("DEMO_MODE", false)
+
+	// Rate limit
+	cfg.RateLimit.Enabled = env.Bool("RATE_LIMIT_ENABLED", DefaultRateLimitEnabled)
+	cfg.RateLimit.Backend = env.String("RATE_LIMIT_BACKEND", DefaultRateLimitBackend)
+	cfg.RateLimit.PublicRate = env.Float("RATE_LIMIT_PUBLIC_RPS", DefaultRateLimitPublicRate)
+	cfg.RateLimit.PublicBurst = env.Int("RATE_LIMIT_PUBLIC_BURST", DefaultRateLimitPublicBurst)
+	cfg.RateLimit.UserRate = env.Float("RATE_LIMIT_USER_RPS", DefaultRateLimitUserRate)
+	cfg.RateLimit.UserBurst = env.Int("RATE_LIMIT_USER_BURST", DefaultRateLimitUserBurst)
+	cfg.RateLimit.RedisAddr = env.String("REDIS_ADDR", DefaultRedisAddr)
+	cfg.RateLimit.RedisPassword = env.String("REDIS_PASSWORD", "")
+	cfg.RateLimit.RedisDB = env.Int("REDIS_DB", DefaultRedisDB)
+	cfg.RateLimit.RedisPrefix = env.String("RATE_LIMIT_REDIS_PREFIX", DefaultRateLimitRedisPrefix)
+
+	// Archive
+	cfg.Archive.After = env.Duration("ARCHIVE_AFTER", DefaultArchiveAfter)
+	cfg.Archive.BatchSize = env.Int("ARCHIVE_BATCH_SIZE", DefaultArchiveBatchSize)
+	cfg.Archive.FileRows = env.Int("ARCHIVE_FILE_ROWS", DefaultArchiveFileRows)
+	cfg.Archive.Prefix = env.String("ARCHIVE_PREFIX", DefaultArchivePrefix)
+	cfg.Archive.S3Endpoint = env.String("ARCHIVE_S3_ENDPOINT", "")
+	cfg.Archive.S3Region = env.String("ARCHIVE_S3_REGION", "")
+	cfg.Archive.S3Bucket = env.String("ARCHIVE_S3_BUCKET", "")
+	cfg.Archive.S3AccessKey = env.String("ARCHIVE_S3_ACCESS_KEY", "")
+	cfg.Archive.S3SecretKey = env.String("ARCHIVE_S3_SECRET_KEY", "")
+	cfg.Archive.S3UseSSL = env.Bool("ARCHIVE_S3_USE_SSL", DefaultArchiveS3UseSSL)
+
+	// WebSocket
+	cfg.WebSocket.PingInterval = env.Duration("WS_PING_INTERVAL", DefaultWSPingInterval)
+	cfg.WebSocket.SendBuffer = env.Int("WS_SEND_BUFFER", DefaultWSSendBuffer)
+	cfg.WebSocket.AllowedOrigins = env.List("WS_ALLOWED_ORIGINS", nil)
+
+	// GraphQL
+	cfg.GraphQL.Enabled = env.Bool("GRAPHQL_ENABLED", DefaultGraphQLEnabled)
+	cfg.GraphQL.MaxDepth = env.Int("GRAPHQL_MAX_DEPTH", DefaultGraphQLMaxDepth)
+
+	cfg.Logger = logger.FromEnv()
 
 	return cfg, nil
 }
 
-// getEnv получает переменную окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Validate проверяет корректность конфигурации
+func (c *Config) Validate() error {
+	if c.Server.HTTPPort == "" {
+		return fmt.Errorf("HTTP_PORT is required")
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("HTTP_MAX_BODY_BYTES must not be negative")
+	}
+	if c.Server.RequestTimeout < 0 {
+		return fmt.Errorf("HTTP_REQUEST_TIMEOUT must not be negative")
 	}
-	return defaultValue
-}
 
-// getEnvInt получает целочисленную переменную окружения
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+	switch c.Database.Driver {
+	case DBDriverPostgres:
+		if c.Database.Host == "" {
+			return fmt.Errorf("DB_HOST is required")
+		}
+	case DBDriverSQLite:
+		if c.Database.SQLitePath == "" {
+			return fmt.Errorf("DB_SQLITE_PATH is required")
 		}
+	case DBDriverMemory:
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER: %s (expected %s, %s or %s)",
+			c.Database.Driver, DBDriverPostgres, DBDriverSQLite, DBDriverMemory)
 	}
-	return defaultValue
-}
 
-// getEnvFloat получает переменную окружения типа float64
-func getEnvFloat(key string, defaultValue float64) float64 {
-	if value := os.Getenv(key); value != "" {
-		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
-			return floatValue
+	switch c.JWT.Algorithm {
+	case JWTAlgorithmHS256:
+		if c.JWT.Secret == "" || c.JWT.Secret == "your-super-secret-jwt-key-change-this-in-production" {
+			return fmt.Errorf("JWT_SECRET must be set to a secure value")
+		}
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		if c.JWT.PrivateKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for JWT_ALGORITHM=%s", c.JWT.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported JWT_ALGORITHM: %s (expected %s, %s or %s)",
+			c.JWT.Algorithm, JWTAlgorithmHS256, JWTAlgorithmRS256, JWTAlgorithmEdDSA)
+	}
+	for _, key := range c.JWT.PreviousKeys {
+		if kid, path, ok := strings.Cut(key, ":"); !ok || kid == "" || path == "" {
+			return fmt.Errorf("invalid JWT_PREVIOUS_KEYS entry %q (expected kid:path)", key)
 		}
 	}
-	return defaultValue
-}
 
-// getEnvDuration получает переменную окружения типа duration
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+	if c.Cache.RatesRefresh {
+		if c.Cache.RatesRefreshLead <= 0 || c.Cache.RatesRefreshJitter < 0 {
+			return fmt.Errorf("CACHE_RATES_REFRESH_LEAD must be positive and CACHE_RATES_REFRESH_JITTER not negative")
+		}
+		if c.Cache.RatesRefreshLead+c.Cache.RatesRefreshJitter >= c.Cache.RatesTTL {
+			return fmt.Errorf("CACHE_RATES_REFRESH_LEAD + CACHE_RATES_REFRESH_JITTER must be less than CACHE_RATES_TTL (%v)", c.Cache.RatesTTL)
 		}
 	}
-	return defaultValue
-}
 
-// Validate проверяет корректность конфигурации
-func (c *Config) Validate() error {
-	if c.Server.HTTPPort == "" {
-		return fmt.Errorf("HTTP_PORT is required")
+	for name, margin := range map[string]float64{
+		"EXCHANGE_MARGIN_API":       c.Pricing.APIMargin,
+		"EXCHANGE_MARGIN_SCHEDULED": c.Pricing.ScheduledMargin,
+		"EXCHANGE_MARGIN_ADMIN":     c.Pricing.AdminMargin,
+	} {
+		if margin < 0 || margin >= 1 {
+			return fmt.Errorf("%s must be in range [0, 1), got %v", name, margin)
+		}
+	}
+
+	switch c.Kafka.RequiredAcks {
+	case "all", "one", "none":
+	default:
+		return fmt.Errorf("invalid KAFKA_REQUIRED_ACKS: %s (expected all, one or none)", c.Kafka.RequiredAcks)
+	}
+
+	switch c.Kafka.MessageFormat {
+	case "json", "protobuf":
+	default:
+		return fmt.Errorf("invalid KAFKA_MESSAGE_FORMAT: %s (expected json or protobuf)", c.Kafka.MessageFormat)
+	}
+
+	if err := c.Kafka.Security.Validate(); err != nil {
+		return err
+	}
+
+	switch c.Exchanger.Compression {
+	case "gzip", "none":
+	default:
+		return fmt.Errorf("invalid EXCHANGER_GRPC_COMPRESSION: %s (expected gzip or none)", c.Exchanger.Compression)
+	}
+
+	if c.Exchanger.MaxRecvMsgSize <= 0 || c.Exchanger.MaxSendMsgSize <= 0 {
+		return fmt.Errorf("EXCHANGER_GRPC_MAX_RECV_MSG_SIZE and EXCHANGER_GRPC_MAX_SEND_MSG_SIZE must be positive")
+	}
+
+	// gRPC не отправляет keepalive ping чаще раза в 10s
+	if c.Exchanger.KeepaliveTime < 10*time.Second || c.Exchanger.KeepaliveTimeout <= 0 {
+		return fmt.Errorf("EXCHANGER_GRPC_KEEPALIVE_TIME must be at least 10s and EXCHANGER_GRPC_KEEPALIVE_TIMEOUT must be positive")
+	}
+
+	if c.Exchanger.ReconnectMaxDelay < time.Second {
+		return fmt.Errorf("EXCHANGER_GRPC_RECONNECT_MAX_DELAY must be at least 1s, got %v", c.Exchanger.ReconnectMaxDelay)
+	}
+
+	if c.Exchanger.RetryMaxAttempts < 1 {
+		return fmt.Errorf("EXCHANGER_RETRY_MAX_ATTEMPTS must be at least 1, got %d", c.Exchanger.RetryMaxAttempts)
+	}
+
+	if c.Exchanger.RetryInitialBackoff <= 0 || c.Exchanger.RetryMaxBackoff < c.Exchanger.RetryInitialBackoff {
+		return fmt.Errorf("EXCHANGER_RETRY_INITIAL_BACKOFF must be positive and not exceed EXCHANGER_RETRY_MAX_BACKOFF")
+	}
+
+	if c.Exchanger.BreakerFailureThreshold < 0 {
+		return fmt.Errorf("EXCHANGER_BREAKER_FAILURE_THRESHOLD must not be negative, got %d", c.Exchanger.BreakerFailureThreshold)
+	}
+
+	if c.Exchanger.BreakerFailureThreshold > 0 && c.Exchanger.BreakerOpenTimeout <= 0 {
+		return fmt.Errorf("EXCHANGER_BREAKER_OPEN_TIMEOUT must be positive")
+	}
+
+	if len(c.Kafka.ThresholdCurrency) != 3 {
+		return fmt.Errorf("invalid KAFKA_THRESHOLD_CURRENCY: %q (expected 3-letter currency code)", c.Kafka.ThresholdCurrency)
+	}
+
+	if c.Outbox.PollInterval <= 0 || c.Outbox.BatchSize <= 0 || c.Outbox.MaxAttempts <= 0 || c.Outbox.RetryInterval <= 0 {
+		return fmt.Errorf("OUTBOX_POLL_INTERVAL, OUTBOX_BATCH_SIZE, OUTBOX_MAX_ATTEMPTS and OUTBOX_RETRY_INTERVAL must be positive")
+	}
+
+	if c.Scheduler.Enabled && (c.Scheduler.PollInterval <= 0 || c.Scheduler.BatchSize <= 0 ||
+		c.Scheduler.MaxAttempts <= 0 || c.Scheduler.RetryInterval <= 0) {
+		return fmt.Errorf("SCHEDULER_POLL_INTERVAL, SCHEDULER_BATCH_SIZE, SCHEDULER_MAX_ATTEMPTS and SCHEDULER_RETRY_INTERVAL must be positive")
+	}
+
+	if c.Webhook.Enabled && (c.Webhook.PollInterval <= 0 || c.Webhook.BatchSize <= 0 ||
+		c.Webhook.MaxAttempts <= 0 || c.Webhook.RetryInterval <= 0 || c.Webhook.Timeout <= 0) {
+		return fmt.Errorf("WEBHOOK_POLL_INTERVAL, WEBHOOK_BATCH_SIZE, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_RETRY_INTERVAL and WEBHOOK_TIMEOUT must be positive")
+	}
+
+	if c.Withdraw.AutoApproveAfter < 0 || c.Withdraw.AutoApproveMaxAmount < 0 {
+		return fmt.Errorf("WITHDRAW_AUTO_APPROVE_AFTER and WITHDRAW_AUTO_APPROVE_MAX_AMOUNT must not be negative")
+	}
+
+	if c.Withdraw.AutoApproveAfter > 0 && c.Withdraw.PollInterval <= 0 {
+		return fmt.Errorf("WITHDRAW_APPROVAL_POLL_INTERVAL must be positive when WITHDRAW_AUTO_APPROVE_AFTER is set")
+	}
+
+	if c.Startup.Timeout <= 0 || c.Startup.RetryInterval <= 0 || c.Startup.MaxRetryInterval <= 0 || c.Startup.ReadinessInterval <= 0 {
+		return fmt.Errorf("STARTUP_TIMEOUT, STARTUP_RETRY_INTERVAL, STARTUP_MAX_RETRY_INTERVAL and READINESS_CHECK_INTERVAL must be positive")
+	}
+
+	if c.Shutdown.HTTPTimeout <= 0 || c.Shutdown.WorkersTimeout <= 0 || c.Shutdown.KafkaTimeout <= 0 || c.Shutdown.StorageTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_HTTP_TIMEOUT, SHUTDOWN_WORKERS_TIMEOUT, SHUTDOWN_KAFKA_TIMEOUT and SHUTDOWN_STORAGE_TIMEOUT must be positive")
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Backend {
+		case "memory":
+		case "redis":
+			if c.RateLimit.RedisAddr == "" {
+				return fmt.Errorf("REDIS_ADDR is required for RATE_LIMIT_BACKEND=redis")
+			}
+		default:
+			return fmt.Errorf("invalid RATE_LIMIT_BACKEND: %s (expected memory or redis)", c.RateLimit.Backend)
+		}
+		if c.RateLimit.PublicRate < 0 || c.RateLimit.UserRate < 0 {
+			return fmt.Errorf("RATE_LIMIT_PUBLIC_RPS and RATE_LIMIT_USER_RPS must not be negative")
+		}
+		if (c.RateLimit.PublicRate > 0 && c.RateLimit.PublicBurst < 1) || (c.RateLimit.UserRate > 0 && c.RateLimit.UserBurst < 1) {
+			return fmt.Errorf("RATE_LIMIT_PUBLIC_BURST and RATE_LIMIT_USER_BURST must be at least 1")
+		}
+	}
+
+	// Лимиты считаются по транзакциям с начала дня и месяца, поэтому
+	// выгружать можно только транзакции старше самого длинного месяца
+	if c.Archive.After < MinArchiveAfter {
+		return fmt.Errorf("ARCHIVE_AFTER must be at least %v", MinArchiveAfter)
+	}
+	if c.Archive.BatchSize <= 0 || c.Archive.FileRows <= 0 {
+		return fmt.Errorf("ARCHIVE_BATCH_SIZE and ARCHIVE_FILE_ROWS must be positive")
+	}
+
+	if c.WebSocket.PingInterval <= 0 || c.WebSocket.SendBuffer <= 0 {
+		return fmt.Errorf("WS_PING_INTERVAL and WS_SEND_BUFFER must be positive")
 	}
 
-	if c.Database.Host == "" {
-		return fmt.Errorf("DB_HOST is required")
+	if c.GraphQL.Enabled && c.GraphQL.MaxDepth <= 0 {
+		return fmt.Errorf("GRAPHQL_MAX_DEPTH must be positive")
 	}
 
-	if c.JWT.Secret == "" || c.JWT.Secret == "your-super-secret-jwt-key-change-this-in-production" {
-		return fmt.Errorf("JWT_SECRET must be set to a secure value")
+	// Демо-пользователи создаются с опубликованным паролем: режим release
+	// считается production, и демо-режим в нем не запускается
+	if c.Demo.Enabled && c.Server.GinMode == "release" {
+		return fmt.Errorf("DEMO_MODE must not be enabled with GIN_MODE=release")
 	}
 
-	if _, err := logrus.ParseLevel(c.Logger.Level); err != nil {
-		return fmt.Errorf("invalid log level: %s", c.Logger.Level)
+	if err := c.Logger.Validate(); err != nil {
+		return err
 	}
 
 	return nil
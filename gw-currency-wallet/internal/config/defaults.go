@@ -6,11 +6,27 @@ import "time"
 const (
 	DefaultHTTPPort = "8080"
 	DefaultGinMode  = "release"
-	DefaultLogLevel = "info"
+
+	// DefaultMaxBodyBytes максимальный размер тела запроса (1 МБ)
+	DefaultMaxBodyBytes = 1 << 20
+	DefaultStrictJSON   = true
+	// DefaultRequestTimeout срок обработки запроса; меньше WriteTimeout сервера (15s),
+	// чтобы клиент успел получить 504
+	DefaultRequestTimeout = 10 * time.Second
+)
+
+// Поддерживаемые драйверы базы данных
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+	// DBDriverMemory хранилище в памяти процесса: данные теряются при перезапуске
+	DBDriverMemory = "memory"
 )
 
 // Database defaults
 const (
+	DefaultDBDriver          = DBDriverPostgres
+	DefaultDBSQLitePath      = "wallet.db"
 	DefaultDBHost            = "localhost"
 	DefaultDBPort            = 5432
 	DefaultDBUser            = "wallet_user"
@@ -20,24 +36,60 @@ const (
 	DefaultDBMaxOpenConns    = 25
 	DefaultDBMaxIdleConns    = 5
 	DefaultDBConnMaxLifetime = 5 * time.Minute
+	DefaultDBMigrateOnStart  = true
+)
+
+// Алгоритмы подписи JWT
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
 )
 
 // JWT defaults
 const (
-	DefaultJWTSecret     = "change-me-in-production"
-	DefaultJWTExpiration = 24 * time.Hour
+	DefaultJWTAlgorithm         = JWTAlgorithmHS256
+	DefaultJWTSecret            = "change-me-in-production"
+	DefaultJWTExpiration        = 24 * time.Hour
+	DefaultJWTRefreshExpiration = 7 * 24 * time.Hour
 )
 
 // Exchanger gRPC defaults
 const (
-	DefaultExchangerHost    = "localhost"
-	DefaultExchangerPort    = "50051"
-	DefaultExchangerTimeout = 5 * time.Second
+	DefaultExchangerHost        = "localhost"
+	DefaultExchangerPort        = "50051"
+	DefaultExchangerTimeout     = 5 * time.Second
+	DefaultExchangerCompression = "none"
+	// Ответы со справочником курсов и историей могут быть крупнее запросов
+	DefaultExchangerMaxRecvMsgSize = 16 << 20
+	DefaultExchangerMaxSendMsgSize = 4 << 20
+
+	DefaultExchangerKeepaliveTime     = 30 * time.Second
+	DefaultExchangerKeepaliveTimeout  = 10 * time.Second
+	DefaultExchangerReconnectMaxDelay = 30 * time.Second
+
+	DefaultExchangerRetryMaxAttempts        = 3
+	DefaultExchangerRetryInitialBackoff     = 100 * time.Millisecond
+	DefaultExchangerRetryMaxBackoff         = time.Second
+	DefaultExchangerBreakerFailureThreshold = 5
+	DefaultExchangerBreakerOpenTimeout      = 30 * time.Second
 )
 
 // Cache defaults
 const (
-	DefaultCacheRatesTTL = 5 * time.Minute
+	DefaultCacheRatesTTL      = 5 * time.Minute
+	DefaultCacheCurrenciesTTL = time.Hour
+
+	DefaultCacheRatesRefreshEnabled = false
+	DefaultCacheRatesRefreshLead    = 30 * time.Second
+	DefaultCacheRatesRefreshJitter  = 10 * time.Second
+)
+
+// Pricing defaults (наценка на курс, доля от курса)
+const (
+	DefaultExchangeMarginAPI       = 0.0
+	DefaultExchangeMarginScheduled = 0.0
+	DefaultExchangeMarginAdmin     = 0.0
 )
 
 // Kafka defaults
@@ -45,4 +97,100 @@ const (
 	DefaultKafkaBrokers           = "localhost:9092"
 	DefaultKafkaTopic             = "large-transfers"
 	DefaultKafkaTransferThreshold = 30000.0
+	DefaultKafkaThresholdCurrency = "USD"
+	DefaultKafkaSync              = true
+	DefaultKafkaRequiredAcks      = "all"
+	DefaultKafkaMessageFormat     = "json"
+	DefaultKafkaUserEventsTopic   = "user-lifecycle"
+	DefaultKafkaControlGroupID    = "gw-currency-wallet-control"
+	DefaultKafkaRatesGroupPrefix  = "gw-currency-wallet-rates"
+)
+
+// Outbox defaults
+const (
+	DefaultOutboxPollInterval  = time.Second
+	DefaultOutboxBatchSize     = 100
+	DefaultOutboxMaxAttempts   = 12
+	DefaultOutboxRetryInterval = 30 * time.Second
+)
+
+// Scheduler defaults
+const (
+	DefaultSchedulerEnabled       = true
+	DefaultSchedulerPollInterval  = 30 * time.Second
+	DefaultSchedulerBatchSize     = 100
+	DefaultSchedulerMaxAttempts   = 5
+	DefaultSchedulerRetryInterval = 5 * time.Minute
+)
+
+// Webhook defaults
+const (
+	DefaultWebhookEnabled       = true
+	DefaultWebhookPollInterval  = 5 * time.Second
+	DefaultWebhookBatchSize     = 20
+	DefaultWebhookMaxAttempts   = 8
+	DefaultWebhookRetryInterval = time.Minute
+	DefaultWebhookTimeout       = 10 * time.Second
+)
+
+// Withdrawal defaults
+const (
+	DefaultWithdrawApprovalRequired     = false
+	DefaultWithdrawApprovalPollInterval = time.Minute
+)
+
+// Password policy defaults
+const (
+	DefaultPasswordMinLength = 8
+)
+
+// Rate limit defaults (запросов в секунду и запас token bucket)
+const (
+	DefaultRateLimitEnabled     = true
+	DefaultRateLimitBackend     = "memory"
+	DefaultRateLimitPublicRate  = 1.0
+	DefaultRateLimitPublicBurst = 10
+	DefaultRateLimitUserRate    = 10.0
+	DefaultRateLimitUserBurst   = 40
+	DefaultRedisAddr            = "localhost:6379"
+	DefaultRedisDB              = 0
+	DefaultRateLimitRedisPrefix = "wallet:ratelimit:"
+)
+
+// Archive defaults
+const (
+	DefaultArchiveAfter     = 365 * 24 * time.Hour
+	MinArchiveAfter         = 31 * 24 * time.Hour
+	DefaultArchiveBatchSize = 1000
+	DefaultArchiveFileRows  = 100000
+	DefaultArchivePrefix    = "wallet/transactions/"
+	DefaultArchiveS3UseSSL  = true
+)
+
+// WebSocket defaults
+const (
+	DefaultWSPingInterval = 30 * time.Second
+	DefaultWSSendBuffer   = 64
+)
+
+// GraphQL defaults
+const (
+	DefaultGraphQLEnabled  = false
+	DefaultGraphQLMaxDepth = 6
+)
+
+// Startup defaults
+const (
+	DefaultStartupTimeout          = time.Minute
+	DefaultStartupRetryInterval    = time.Second
+	DefaultStartupMaxRetryInterval = 15 * time.Second
+	DefaultReadinessCheckInterval  = 10 * time.Second
+)
+
+// Shutdown defaults (таймауты этапов остановки)
+const (
+	DefaultShutdownHTTPTimeout    = 10 * time.Second
+	DefaultShutdownWorkersTimeout = 10 * time.Second
+	DefaultShutdownKafkaTimeout   = 10 * time.Second
+	DefaultShutdownStorageTimeout = 5 * time.Second
 )
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// Типы событий
+const (
+	TypeBalance = "balance" // изменились балансы пользователя
+	TypeRates   = "rates"   // получены новые курсы
+)
+
+var (
+	// ErrSlowConsumer подписка закрыта: подписчик не успевал читать события
+	ErrSlowConsumer = errors.New("subscriber is too slow")
+	// ErrBusClosed подписка закрыта при остановке сервиса
+	ErrBusClosed = errors.New("event bus is closed")
+)
+
+// Event событие для подписчиков
+type Event struct {
+	Type string `json:"type"`
+	// UserID получатель события, 0 - все подписчики
+	UserID    int64                 `json:"-"`
+	Balances  storages.UserBalances `json:"balances,omitempty"`
+	Rates     map[string]float32    `json:"rates,omitempty"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// Subscription подписка на события одного пользователя
+type Subscription struct {
+	bus    *Bus
+	userID int64
+	ch     chan Event
+	err    error
+}
+
+// Events возвращает канал событий. Канал закрывается при закрытии подписки,
+// причину возвращает Err
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err возвращает причину закрытия подписки шиной (ErrSlowConsumer, ErrBusClosed)
+// или nil. Читать после закрытия канала Events
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s, nil)
+}
+
+// Stats состояние шины для метрик
+type Stats struct {
+	Subscribers int
+	// SlowConsumers подписки, закрытые из-за переполнения буфера
+	SlowConsumers int64
+}
+
+// Bus шина событий внутри процесса: сервисный слой публикует изменения,
+// обработчики WebSocket доставляют их клиентам. Публикация не блокируется:
+// подписка, буфер которой переполнен, закрывается, и клиент переподключается,
+// получая актуальное состояние заново
+type Bus struct {
+	mu            sync.Mutex
+	subs          map[*Subscription]struct{}
+	buffer        int
+	closed        bool
+	slowConsumers atomic.Int64
+}
+
+// NewBus создает шину событий. buffer - число событий, которые подписчик может
+// не прочитать, прежде чем подписка будет закрыта
+func NewBus(buffer int) *Bus {
+	return &Bus{
+		subs:   make(map[*Subscription]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe подписывает на события пользователя userID и события для всех.
+// После Close шины возвращается уже закрытая подписка
+func (b *Bus) Subscribe(userID int64) *Subscription {
+	sub := &Subscription{bus: b, userID: userID, ch: make(chan Event, b.buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.err = ErrBusClosed
+		close(sub.ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish доставляет событие подписчикам. Время события проставляется, если не задано
+func (b *Bus) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if event.UserID != 0 && sub.userID != event.UserID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.slowConsumers.Add(1)
+			b.remove(sub, ErrSlowConsumer)
+		}
+	}
+}
+
+// Close закрывает все подписки. Вызывается при остановке сервиса, чтобы
+// обработчики закрыли соединения
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub, ErrBusClosed)
+	}
+}
+
+// Stats возвращает число подписчиков и закрытых медленных подписок
+func (b *Bus) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{Subscribers: len(b.subs), SlowConsumers: b.slowConsumers.Load()}
+}
+
+// remove удаляет подписку и закрывает ее канал. Вызывается под b.mu
+func (b *Bus) remove(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
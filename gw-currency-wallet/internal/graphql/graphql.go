@@ -0,0 +1,81 @@
+// Package graphql описывает схему GraphQL кошелька и ее резолверы. Схема задается
+// в SDL (schema.graphql), разбор, проверку и выполнение запросов выполняет
+// github.com/graph-gophers/graphql-go: методы резолверов сопоставляются полям
+// схемы при запуске, расхождение схемы и резолверов - ошибка запуска
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"gw-currency-wallet/internal/service"
+)
+
+// SDL схема GraphQL, публикуется для генерации клиентов
+//
+//go:embed schema.graphql
+var SDL string
+
+// Request запрос GraphQL по HTTP. Extensions клиентов принимаются и не используются
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// NewSchema создает исполняемую схему поверх WalletService. maxDepth ограничивает
+// вложенность полей запроса, 0 - без ограничения
+func NewSchema(svc *service.WalletService, maxDepth int) (*graphqlgo.Schema, error) {
+	return graphqlgo.ParseSchema(SDL, &Resolver{svc: svc},
+		graphqlgo.UseStringDescriptions(),
+		graphqlgo.UseFieldResolvers(),
+		graphqlgo.MaxDepth(maxDepth),
+	)
+}
+
+// userKey ключ пользователя запроса в контексте резолверов
+type userKey struct{}
+
+// WithUser возвращает контекст запроса пользователя userID
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// currentUser возвращает пользователя запроса из контекста резолвера
+func currentUser(ctx context.Context) int64 {
+	return ctx.Value(userKey{}).(int64)
+}
+
+// DateTime скаляр даты и времени в RFC 3339, в ответах - в UTC
+type DateTime struct {
+	time.Time
+}
+
+// ImplementsGraphQLType сопоставляет тип скаляру DateTime схемы
+func (DateTime) ImplementsGraphQLType(name string) bool {
+	return name == "DateTime"
+}
+
+// UnmarshalGraphQL разбирает значение аргумента
+func (t *DateTime) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("DateTime must be an RFC 3339 string")
+	}
+	value, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("DateTime must be an RFC 3339 string, got %q", s)
+	}
+	t.Time = value
+	return nil
+}
+
+// MarshalJSON записывает значение в ответ
+func (t DateTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,274 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"gw-currency-wallet/internal/api/middleware"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Resolver корневой резолвер Query. Поля вычисляются только тогда, когда запрошены;
+// библиотека может вычислять поля параллельно
+type Resolver struct {
+	svc *service.WalletService
+}
+
+// user объект User
+type user struct {
+	ID                graphqlgo.ID
+	Username          string
+	Email             string
+	Role              string
+	Status            string
+	VerificationLevel string
+	CreatedAt         DateTime
+}
+
+// Me возвращает пользователя запроса
+func (r *Resolver) Me(ctx context.Context) (*user, error) {
+	u, err := r.svc.GetUser(ctx, currentUser(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &user{
+		ID:                graphqlgo.ID(strconv.FormatInt(u.ID, 10)),
+		Username:          u.Username,
+		Email:             u.Email,
+		Role:              u.Role,
+		Status:            u.Status,
+		VerificationLevel: u.VerificationLevel,
+		CreatedAt:         DateTime{u.CreatedAt},
+	}, nil
+}
+
+// Balances возвращает балансы пользователя, упорядоченные по валюте
+func (r *Resolver) Balances(ctx context.Context) ([]*balance, error) {
+	userID := currentUser(ctx)
+	balances, err := r.svc.GetUserBalances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	currencies := make([]string, 0, len(balances))
+	for currency := range balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	// Удержания загружаются один раз на запрос и только если запрошены held или available
+	held := &heldLoader{svc: r.svc, userID: userID}
+	result := make([]*balance, len(currencies))
+	for i, currency := range currencies {
+		result[i] = &balance{Currency: currency, Amount: balances[currency], held: held}
+	}
+	return result, nil
+}
+
+// BalanceTotal возвращает стоимость всех балансов в currency
+func (r *Resolver) BalanceTotal(ctx context.Context, args struct{ Currency string }) (*service.BalanceTotal, error) {
+	return r.svc.GetBalanceTotal(ctx, currentUser(ctx), args.Currency)
+}
+
+// transactionsArgs аргументы поля transactions
+type transactionsArgs struct {
+	Limit    *int32
+	Offset   *int32
+	Cursor   *graphqlgo.ID
+	Types    *[]string
+	Statuses *[]string
+	From     *DateTime
+	To       *DateTime
+}
+
+// transactionPage объект TransactionPage
+type transactionPage struct {
+	Items      []*transaction
+	Total      int32
+	NextCursor *graphqlgo.ID
+}
+
+// Transactions возвращает страницу истории транзакций от новых к старым
+func (r *Resolver) Transactions(ctx context.Context, args transactionsArgs) (*transactionPage, error) {
+	filter, err := transactionFilter(args)
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.svc.GetTransactions(ctx, currentUser(ctx), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &transactionPage{Items: make([]*transaction, len(page.Transactions)), Total: int32(page.Total)}
+	for i := range page.Transactions {
+		result.Items[i] = &transaction{tx: page.Transactions[i]}
+	}
+	if page.NextCursor > 0 {
+		cursor := graphqlgo.ID(strconv.FormatInt(page.NextCursor, 10))
+		result.NextCursor = &cursor
+	}
+	return result, nil
+}
+
+// rate объект Rate
+type rate struct {
+	Pair string
+	From string
+	To   string
+	Rate float64
+}
+
+// Rates возвращает курсы exchanger, упорядоченные по паре
+func (r *Resolver) Rates(ctx context.Context) ([]*rate, error) {
+	rates, err := r.svc.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]string, 0, len(rates))
+	for pair := range rates {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	result := make([]*rate, 0, len(pairs))
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "_")
+		if !ok {
+			continue
+		}
+		// Курс float32 переводится по его десятичной записи, как в JSON ответе /exchange/rates
+		value, _ := strconv.ParseFloat(strconv.FormatFloat(float64(rates[pair]), 'g', -1, 32), 64)
+		result = append(result, &rate{Pair: pair, From: from, To: to, Rate: value})
+	}
+	return result, nil
+}
+
+// Currencies возвращает коды валют exchanger
+func (r *Resolver) Currencies(ctx context.Context) ([]string, error) {
+	return r.svc.GetSupportedCurrencies(ctx)
+}
+
+// balance объект Balance
+type balance struct {
+	Currency string
+	Amount   float64
+	held     *heldLoader
+}
+
+// Held возвращает сумму, удержанную ожидающими выводами
+func (b *balance) Held(ctx context.Context) (float64, error) {
+	held, err := b.held.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return held[b.Currency], nil
+}
+
+// Available возвращает сумму, доступную для выводов и обменов
+func (b *balance) Available(ctx context.Context) (float64, error) {
+	held, err := b.held.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return b.Amount - held[b.Currency], nil
+}
+
+// heldLoader загружает удержания пользователя один раз для всех балансов запроса
+type heldLoader struct {
+	svc    *service.WalletService
+	userID int64
+
+	once sync.Once
+	held storages.UserBalances
+	err  error
+}
+
+// load возвращает удержания, загружая их при первом вызове
+func (l *heldLoader) load(ctx context.Context) (storages.UserBalances, error) {
+	l.once.Do(func() {
+		l.held, l.err = l.svc.GetHeldBalances(ctx, l.userID)
+	})
+	return l.held, l.err
+}
+
+// transaction объект Transaction; пустые необязательные поля - null
+type transaction struct {
+	tx storages.Transaction
+}
+
+// Поля объекта Transaction
+
+func (t *transaction) ID() graphqlgo.ID {
+	return graphqlgo.ID(strconv.FormatInt(t.tx.ID, 10))
+}
+
+func (t *transaction) Type() string          { return t.tx.Type }
+func (t *transaction) Status() string        { return t.tx.Status }
+func (t *transaction) Source() *string       { return optional(t.tx.Source) }
+func (t *transaction) FromCurrency() *string { return optional(t.tx.FromCurrency) }
+func (t *transaction) ToCurrency() *string   { return optional(t.tx.ToCurrency) }
+func (t *transaction) FromAmount() float64   { return t.tx.FromAmount }
+func (t *transaction) ToAmount() float64     { return t.tx.ToAmount }
+func (t *transaction) Fee() float64          { return t.tx.Fee }
+func (t *transaction) CreatedAt() DateTime   { return DateTime{t.tx.CreatedAt} }
+
+func (t *transaction) ExchangeRate() *float64 {
+	if t.tx.ExchangeRate == 0 {
+		return nil
+	}
+	return &t.tx.ExchangeRate
+}
+
+func (t *transaction) CompletedAt() *DateTime {
+	if t.tx.CompletedAt == nil {
+		return nil
+	}
+	return &DateTime{*t.tx.CompletedAt}
+}
+
+// optional возвращает nil для пустой строки
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// transactionFilter переводит аргументы поля transactions в фильтр истории
+func transactionFilter(args transactionsArgs) (storages.TransactionFilter, error) {
+	var filter storages.TransactionFilter
+	if args.Limit != nil {
+		if *args.Limit < 1 {
+			return filter, middleware.InvalidRequest(fmt.Sprintf("limit must be between 1 and %d", storages.MaxTransactionPageSize))
+		}
+		filter.Limit = int(*args.Limit)
+	}
+	if args.Offset != nil {
+		filter.Offset = int(*args.Offset)
+	}
+	if args.Cursor != nil {
+		value, err := strconv.ParseInt(string(*args.Cursor), 10, 64)
+		if err != nil || value < 1 {
+			return filter, middleware.InvalidRequest("Invalid cursor")
+		}
+		filter.Cursor = value
+	}
+	if args.Types != nil {
+		filter.Types = *args.Types
+	}
+	if args.Statuses != nil {
+		filter.Statuses = *args.Statuses
+	}
+	if args.From != nil {
+		filter.From = args.From.Time
+	}
+	if args.To != nil {
+		filter.To = args.To.Time
+	}
+	return filter, nil
+}
@@ -3,51 +3,163 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	pb "gw-currency-wallet/proto"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
+// APITokenHeader ключ metadata с API токеном кошелька для exchanger
+const APITokenHeader = "x-api-token"
+
+// Режимы сжатия сообщений
+const (
+	CompressionGzip = gzip.Name
+	CompressionNone = "none"
+)
+
+// TransportOptions параметры соединения и передачи сообщений gRPC
+type TransportOptions struct {
+	// Compression сжатие запросов: CompressionGzip или CompressionNone.
+	// Ответы exchanger сжимает тем же алгоритмом
+	Compression string
+	// MaxRecvMsgSize максимальный размер ответа в байтах
+	MaxRecvMsgSize int
+	// MaxSendMsgSize максимальный размер запроса в байтах
+	MaxSendMsgSize int
+	// KeepaliveTime интервал keepalive ping; не должен быть меньше
+	// GRPC_KEEPALIVE_MIN_TIME exchanger, иначе сервер разорвет соединение
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout время ожидания ответа на ping, после которого
+	// соединение считается оборванным и переустанавливается
+	KeepaliveTimeout time.Duration
+	// ReconnectMaxDelay максимальная пауза между попытками переподключения
+	ReconnectMaxDelay time.Duration
+}
+
+// dialOptions возвращает параметры соединения: keepalive и паузы переподключения
+func (o TransportOptions) dialOptions() []grpc.DialOption {
+	var options []grpc.DialOption
+	if o.KeepaliveTime > 0 {
+		options = append(options, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                o.KeepaliveTime,
+			Timeout:             o.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	if o.ReconnectMaxDelay > 0 {
+		backoffConfig := backoff.DefaultConfig
+		backoffConfig.BaseDelay = time.Second
+		backoffConfig.MaxDelay = o.ReconnectMaxDelay
+		options = append(options, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoffConfig,
+			MinConnectTimeout: 5 * time.Second,
+		}))
+	}
+	return options
+}
+
+// callOptions возвращает параметры вызовов по умолчанию
+func (o TransportOptions) callOptions() []grpc.CallOption {
+	var options []grpc.CallOption
+	if o.MaxRecvMsgSize > 0 {
+		options = append(options, grpc.MaxCallRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		options = append(options, grpc.MaxCallSendMsgSize(o.MaxSendMsgSize))
+	}
+	if o.Compression == CompressionGzip {
+		options = append(options, grpc.UseCompressor(gzip.Name))
+	}
+	return options
+}
+
+// CurrencyPair пара валют, разрешенная вызывающей стороне exchanger
+type CurrencyPair struct {
+	FromCurrency string `json:"from_currency" binding:"required,len=3"`
+	ToCurrency   string `json:"to_currency" binding:"required,len=3"`
+}
+
+// ExchangeRates курсы exchanger по ключу FROM_TO: средние, покупки (bid) и продажи (ask).
+// Bids и Asks пусты, если exchanger не возвращает курсы покупки и продажи
+type ExchangeRates struct {
+	Rates map[string]float32
+	Bids  map[string]float32
+	Asks  map[string]float32
+}
+
+// PairRate курс пары from -> to: средний, покупки и продажи from
+type PairRate struct {
+	Rate float32
+	Bid  float32
+	Ask  float32
+}
+
+// RateHistoryDateLayout формат дат истории курсов exchanger
+const RateHistoryDateLayout = "2006-01-02"
+
+// RatePoint курс пары на конец дня Date (RateHistoryDateLayout, UTC)
+type RatePoint struct {
+	Date string
+	Rate float64
+}
+
 // ExchangerClient обертка над gRPC клиентом для exchanger сервиса
 type ExchangerClient struct {
-	client  pb.ExchangeServiceClient
-	conn    *grpc.ClientConn
-	timeout time.Duration
-	logger  *logrus.Logger
+	client     pb.ExchangeServiceClient
+	health     healthpb.HealthClient
+	conn       *grpc.ClientConn
+	timeout    time.Duration
+	resilience *resilience
+	logger     *logrus.Logger
 }
 
-// NewExchangerClient создает новый gRPC клиент
-func NewExchangerClient(host, port string, timeout time.Duration, logger *logrus.Logger) (*ExchangerClient, error) {
+// NewExchangerClient создает новый gRPC клиент. apiToken передается exchanger
+// в каждом запросе для идентификации кошелька; пустой токен не передается.
+// Вызовы выполняются с повторами и через circuit breaker согласно policy
+func NewExchangerClient(host, port, apiToken string, timeout time.Duration, transport TransportOptions, policy CallPolicy, logger *logrus.Logger) (*ExchangerClient, error) {
 	address := fmt.Sprintf("%s:%s", host, port)
+	resilience := newResilience(policy, logger)
 
-	// Создаем соединение с gRPC сервером
-	conn, err := grpc.Dial(
-		address,
+	// Создаем соединение с gRPC сервером. Подключение не блокирует запуск:
+	// gRPC устанавливает его в фоне и переподключается при обрывах с
+	// экспоненциальной паузой, keepalive обнаруживает зависшие соединения.
+	// Доступность сервиса проверяется через Ping
+	dialOptions := append([]grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-		grpc.WithTimeout(10*time.Second),
-	)
+		grpc.WithChainUnaryInterceptor(resilience.interceptor(), apiTokenInterceptor(apiToken)),
+		grpc.WithDefaultCallOptions(transport.callOptions()...),
+	}, transport.dialOptions()...)
+
+	conn, err := grpc.Dial(address, dialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to exchanger service: %w", err)
 	}
 
 	client := pb.NewExchangeServiceClient(conn)
 
-	logger.Infof("Connected to exchanger service at %s", address)
+	logger.Infof("Exchanger client created for %s (compression: %s)", address, transport.Compression)
 
 	return &ExchangerClient{
-		client:  client,
-		conn:    conn,
-		timeout: timeout,
-		logger:  logger,
+		client:     client,
+		health:     healthpb.NewHealthClient(conn),
+		conn:       conn,
+		timeout:    timeout,
+		resilience: resilience,
+		logger:     logger,
 	}, nil
 }
 
 // GetExchangeRates получает все курсы валют
-func (c *ExchangerClient) GetExchangeRates(ctx context.Context) (map[string]float32, error) {
+func (c *ExchangerClient) GetExchangeRates(ctx context.Context) (*ExchangeRates, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -59,12 +171,22 @@ func (c *ExchangerClient) GetExchangeRates(ctx context.Context) (map[string]floa
 		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
 	}
 
+	// Устаревшие курсы не используются: обмен по ним exchanger отклоняет с stale_rate
+	for _, key := range resp.Stale {
+		delete(resp.Rates, key)
+		delete(resp.Bids, key)
+		delete(resp.Asks, key)
+	}
+	if len(resp.Stale) > 0 {
+		c.logger.Warnf("Exchanger reported %d stale exchange rates: %s", len(resp.Stale), strings.Join(resp.Stale, ", "))
+	}
+
 	c.logger.Debugf("Received %d exchange rates", len(resp.Rates))
-	return resp.Rates, nil
+	return &ExchangeRates{Rates: resp.Rates, Bids: resp.Bids, Asks: resp.Asks}, nil
 }
 
 // GetExchangeRateForCurrency получает курс для конкретной пары валют
-func (c *ExchangerClient) GetExchangeRateForCurrency(ctx context.Context, fromCurrency, toCurrency string) (float32, error) {
+func (c *ExchangerClient) GetExchangeRateForCurrency(ctx context.Context, fromCurrency, toCurrency string) (PairRate, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
@@ -78,11 +200,105 @@ func (c *ExchangerClient) GetExchangeRateForCurrency(ctx context.Context, fromCu
 	resp, err := c.client.GetExchangeRateForCurrency(ctx, req)
 	if err != nil {
 		c.logger.Errorf("Failed to get exchange rate for %s->%s: %v", fromCurrency, toCurrency, err)
-		return 0, fmt.Errorf("failed to get exchange rate: %w", err)
+		return PairRate{}, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	c.logger.Debugf("Received exchange rate: %s -> %s = %.8f (bid: %.8f, ask: %.8f)", fromCurrency, toCurrency, resp.Rate, resp.Bid, resp.Ask)
+	return PairRate{Rate: resp.Rate, Bid: resp.Bid, Ask: resp.Ask}, nil
+}
+
+// GetCurrencies получает коды активных валют
+func (c *ExchangerClient) GetCurrencies(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.Debug("Requesting supported currencies from exchanger service")
+
+	resp, err := c.client.GetCurrencies(ctx, &pb.CurrenciesRequest{})
+	if err != nil {
+		c.logger.Errorf("Failed to get currencies: %v", err)
+		return nil, fmt.Errorf("failed to get currencies: %w", err)
+	}
+
+	codes := make([]string, 0, len(resp.Currencies))
+	for _, currency := range resp.Currencies {
+		codes = append(codes, currency.Code)
+	}
+
+	c.logger.Debugf("Received %d currencies", len(codes))
+	return codes, nil
+}
+
+// GetCallerPairs получает пары валют, разрешенные вызывающей стороне exchanger
+func (c *ExchangerClient) GetCallerPairs(ctx context.Context, caller string) ([]CurrencyPair, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.Debugf("Requesting allowed pairs for caller %s", caller)
+
+	resp, err := c.client.GetCallerPairs(ctx, &pb.CallerPairsRequest{Caller: caller})
+	if err != nil {
+		c.logger.Errorf("Failed to get pairs for caller %s: %v", caller, err)
+		return nil, fmt.Errorf("failed to get caller pairs: %w", err)
+	}
+
+	return fromProtoPairs(resp.Pairs), nil
+}
+
+// SetCallerPairs заменяет список пар валют, разрешенных вызывающей стороне exchanger
+func (c *ExchangerClient) SetCallerPairs(ctx context.Context, caller string, pairs []CurrencyPair) ([]CurrencyPair, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.Debugf("Setting %d allowed pairs for caller %s", len(pairs), caller)
+
+	req := &pb.SetCallerPairsRequest{
+		Caller: caller,
+		Pairs:  make([]*pb.CurrencyPair, 0, len(pairs)),
+	}
+	for _, pair := range pairs {
+		req.Pairs = append(req.Pairs, &pb.CurrencyPair{
+			FromCurrency: pair.FromCurrency,
+			ToCurrency:   pair.ToCurrency,
+		})
+	}
+
+	resp, err := c.client.SetCallerPairs(ctx, req)
+	if err != nil {
+		c.logger.Errorf("Failed to set pairs for caller %s: %v", caller, err)
+		return nil, fmt.Errorf("failed to set caller pairs: %w", err)
+	}
+
+	return fromProtoPairs(resp.Pairs), nil
+}
+
+// GetRateHistory получает курс пары на конец каждого дня с start по end включительно.
+// Дни до первой записи истории exchanger не возвращает
+func (c *ExchangerClient) GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, start, end time.Time) ([]RatePoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.logger.Debugf("Requesting rate history: %s -> %s, %s..%s", fromCurrency, toCurrency,
+		start.Format(RateHistoryDateLayout), end.Format(RateHistoryDateLayout))
+
+	resp, err := c.client.GetRateHistory(ctx, &pb.RateHistoryRequest{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		StartDate:    start.Format(RateHistoryDateLayout),
+		EndDate:      end.Format(RateHistoryDateLayout),
+	})
+	if err != nil {
+		c.logger.Errorf("Failed to get rate history for %s->%s: %v", fromCurrency, toCurrency, err)
+		return nil, fmt.Errorf("failed to get rate history: %w", err)
+	}
+
+	points := make([]RatePoint, 0, len(resp.Points))
+	for _, point := range resp.Points {
+		points = append(points, RatePoint{Date: point.Date, Rate: point.Rate})
 	}
 
-	c.logger.Debugf("Received exchange rate: %s -> %s = %.8f", fromCurrency, toCurrency, resp.Rate)
-	return resp.Rate, nil
+	c.logger.Debugf("Received %d daily rates: %s -> %s", len(points), fromCurrency, toCurrency)
+	return points, nil
 }
 
 // Close закрывает соединение с gRPC сервером
@@ -94,8 +310,46 @@ func (c *ExchangerClient) Close() error {
 	return nil
 }
 
-// Ping проверяет доступность сервиса
+// Ping проверяет готовность exchanger через стандартный health check gRPC:
+// сервис готов, только если отвечает SERVING (exchanger подключен к БД)
 func (c *ExchangerClient) Ping(ctx context.Context) error {
-	_, err := c.GetExchangeRates(ctx)
-	return err
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: pb.ExchangeService_ServiceDesc.ServiceName})
+	if err != nil {
+		return fmt.Errorf("failed to check exchanger health: %w", err)
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("exchanger is not serving: %s", resp.Status)
+	}
+	return nil
+}
+
+// Stats возвращает метрики повторов и состояние circuit breaker
+func (c *ExchangerClient) Stats() ResilienceStats {
+	return c.resilience.stats()
+}
+
+// apiTokenInterceptor добавляет API токен в metadata исходящих запросов
+func apiTokenInterceptor(apiToken string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if apiToken != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, APITokenHeader, apiToken)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// fromProtoPairs преобразует пары валют из формата protobuf
+func fromProtoPairs(pairs []*pb.CurrencyPair) []CurrencyPair {
+	result := make([]CurrencyPair, 0, len(pairs))
+	for _, pair := range pairs {
+		result = append(result, CurrencyPair{
+			FromCurrency: pair.FromCurrency,
+			ToCurrency:   pair.ToCurrency,
+		})
+	}
+	return result
 }
@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen возвращается без обращения к exchanger, пока circuit breaker открыт
+var ErrCircuitOpen = errors.New("exchanger circuit breaker is open")
+
+// Состояния circuit breaker
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// RetryPolicy параметры повторных попыток вызова exchanger
+type RetryPolicy struct {
+	// MaxAttempts общее число попыток, включая первую; 1 - без повторов
+	MaxAttempts int
+	// InitialBackoff пауза перед первым повтором, удваивается после каждой неудачи
+	InitialBackoff time.Duration
+	// MaxBackoff максимальная пауза между попытками
+	MaxBackoff time.Duration
+}
+
+// BreakerPolicy параметры circuit breaker
+type BreakerPolicy struct {
+	// FailureThreshold число неудачных вызовов подряд, после которого breaker
+	// открывается; 0 - breaker отключен
+	FailureThreshold int
+	// OpenTimeout время, через которое открытый breaker пропускает пробный вызов
+	OpenTimeout time.Duration
+}
+
+// CallPolicy политика вызовов exchanger: повторы и circuit breaker
+type CallPolicy struct {
+	Retry   RetryPolicy
+	Breaker BreakerPolicy
+}
+
+// ResilienceStats метрики повторов и circuit breaker
+type ResilienceStats struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Opens               int64  `json:"opens_total"`    // сколько раз breaker открывался
+	Rejected            int64  `json:"rejected_total"` // вызовы, отклоненные открытым breaker
+	Retries             int64  `json:"retries_total"`  // повторные попытки вызовов
+}
+
+// retryableCodes коды ошибок, при которых вызов повторяется
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+}
+
+// failureCodes коды ошибок, которые считаются отказом exchanger для breaker.
+// Ошибки запроса (NotFound, InvalidArgument и т.п.) breaker не учитывает
+var failureCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.Internal:          true,
+	codes.Unknown:           true,
+}
+
+// circuitBreaker размыкает вызовы после серии отказов exchanger
+type circuitBreaker struct {
+	mu       sync.Mutex
+	policy   BreakerPolicy
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // пробный вызов в состоянии half-open уже выполняется
+	opens    int64
+	rejected int64
+	logger   *logrus.Logger
+}
+
+// newCircuitBreaker создает circuit breaker в закрытом состоянии
+func newCircuitBreaker(policy BreakerPolicy, logger *logrus.Logger) *circuitBreaker {
+	return &circuitBreaker{
+		policy: policy,
+		state:  BreakerClosed,
+		logger: logger,
+	}
+}
+
+// allow проверяет, можно ли выполнить вызов
+func (b *circuitBreaker) allow() bool {
+	if b.policy.FailureThreshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.policy.OpenTimeout {
+			b.rejected++
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// Пока пробный вызов не завершился, остальные отклоняются
+		if b.probing {
+			b.rejected++
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record учитывает результат вызова
+func (b *circuitBreaker) record(err error) {
+	if b.policy.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	// Отмена вызова клиентом ничего не говорит о состоянии exchanger
+	if status.Code(err) == codes.Canceled {
+		return
+	}
+
+	if err == nil || !failureCodes[status.Code(err)] {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.policy.FailureThreshold {
+		if b.state != BreakerOpen {
+			b.opens++
+			b.setState(BreakerOpen)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+// setState меняет состояние и логирует переход. Вызывается под b.mu
+func (b *circuitBreaker) setState(state string) {
+	switch state {
+	case BreakerOpen:
+		b.logger.Warnf("Exchanger circuit breaker opened after %d consecutive failures", b.failures)
+	case BreakerClosed:
+		b.logger.Info("Exchanger circuit breaker closed")
+	default:
+		b.logger.Infof("Exchanger circuit breaker is %s, sending a trial call", state)
+	}
+	b.state = state
+}
+
+// stats возвращает состояние breaker
+func (b *circuitBreaker) stats() ResilienceStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return ResilienceStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Opens:               b.opens,
+		Rejected:            b.rejected,
+	}
+}
+
+// resilience выполняет вызовы exchanger с повторами через circuit breaker
+type resilience struct {
+	retry   RetryPolicy
+	breaker *circuitBreaker
+	retries atomic.Int64
+	logger  *logrus.Logger
+}
+
+// newResilience создает обработчик политики вызовов
+func newResilience(policy CallPolicy, logger *logrus.Logger) *resilience {
+	return &resilience{
+		retry:   policy.Retry,
+		breaker: newCircuitBreaker(policy.Breaker, logger),
+		logger:  logger,
+	}
+}
+
+// interceptor возвращает unary interceptor, применяющий политику к каждому вызову.
+// Breaker учитывает вызов целиком: серия повторов считается одним отказом
+func (r *resilience) interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !r.breaker.allow() {
+			return ErrCircuitOpen
+		}
+
+		err := r.invokeWithRetry(ctx, method, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+		r.breaker.record(err)
+		return err
+	}
+}
+
+// invokeWithRetry повторяет вызов с экспоненциальной паузой, пока код ошибки
+// допускает повтор, не исчерпаны попытки и не истек контекст вызова
+func (r *resilience) invokeWithRetry(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	backoff := r.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil || attempt >= r.retry.MaxAttempts || !retryableCodes[status.Code(err)] {
+			return err
+		}
+
+		r.logger.Debugf("Retrying %s in %v (attempt %d): %v", method, backoff, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		r.retries.Add(1)
+
+		backoff *= 2
+		if backoff > r.retry.MaxBackoff {
+			backoff = r.retry.MaxBackoff
+		}
+	}
+}
+
+// stats возвращает метрики повторов и circuit breaker
+func (r *resilience) stats() ResilienceStats {
+	stats := r.breaker.stats()
+	stats.Retries = r.retries.Load()
+	return stats
+}
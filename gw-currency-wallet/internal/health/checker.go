@@ -0,0 +1,169 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Состояния сервиса для /ready
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not_ready"
+)
+
+// Состояния зависимости
+const (
+	DependencyUp   = "up"
+	DependencyDown = "down"
+)
+
+// CheckFunc проверяет доступность зависимости
+type CheckFunc func(ctx context.Context) error
+
+// DependencyState состояние зависимости на момент последней проверки
+type DependencyState struct {
+	Status    string    `json:"status"`
+	Required  bool      `json:"required"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report состояние сервиса и его зависимостей
+type Report struct {
+	Status       string                     `json:"status"`
+	Dependencies map[string]DependencyState `json:"dependencies"`
+}
+
+// dependency зарегистрированная зависимость
+type dependency struct {
+	check    CheckFunc
+	required bool
+	state    DependencyState
+}
+
+// Checker периодически проверяет зависимости сервиса.
+// Сервис готов, если доступны все обязательные зависимости; недоступность
+// необязательных переводит его в режим degraded
+type Checker struct {
+	mu           sync.RWMutex
+	dependencies map[string]*dependency
+	timeout      time.Duration
+	logger       *logrus.Logger
+}
+
+// NewChecker создает новый Checker. timeout ограничивает одну проверку
+func NewChecker(timeout time.Duration, logger *logrus.Logger) *Checker {
+	return &Checker{
+		dependencies: make(map[string]*dependency),
+		timeout:      timeout,
+		logger:       logger,
+	}
+}
+
+// Register добавляет зависимость. До первой проверки она считается недоступной
+func (c *Checker) Register(name string, required bool, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dependencies[name] = &dependency{
+		check:    check,
+		required: required,
+		state:    DependencyState{Status: DependencyDown, Required: required},
+	}
+}
+
+// CheckAll проверяет все зависимости и сохраняет результат
+func (c *Checker) CheckAll(ctx context.Context) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.dependencies))
+	checks := make([]CheckFunc, 0, len(c.dependencies))
+	for name, dep := range c.dependencies {
+		names = append(names, name)
+		checks = append(checks, dep.check)
+	}
+	c.mu.RUnlock()
+
+	for i, name := range names {
+		checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := checks[i](checkCtx)
+		cancel()
+
+		c.setState(name, err)
+	}
+}
+
+// setState сохраняет результат проверки и логирует смену состояния
+func (c *Checker) setState(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dep, ok := c.dependencies[name]
+	if !ok {
+		return
+	}
+
+	state := DependencyState{
+		Status:    DependencyUp,
+		Required:  dep.required,
+		CheckedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		state.Status = DependencyDown
+		state.Error = err.Error()
+	}
+
+	if state.Status != dep.state.Status {
+		if err != nil {
+			c.logger.Warnf("Dependency %s is down: %v", name, err)
+		} else {
+			c.logger.Infof("Dependency %s is up", name)
+		}
+	}
+
+	dep.state = state
+}
+
+// Run проверяет зависимости с интервалом interval до отмены контекста
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.CheckAll(ctx)
+		}
+	}
+}
+
+// Report возвращает состояние сервиса по результатам последних проверок
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := Report{
+		Status:       StatusReady,
+		Dependencies: make(map[string]DependencyState, len(c.dependencies)),
+	}
+
+	for name, dep := range c.dependencies {
+		report.Dependencies[name] = dep.state
+		if dep.state.Status == DependencyUp {
+			continue
+		}
+
+		if dep.required {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
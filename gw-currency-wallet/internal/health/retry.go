@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryConfig содержит параметры повторных попыток подключения к зависимости
+type RetryConfig struct {
+	// Timeout общее время ожидания зависимости
+	Timeout time.Duration
+	// Interval начальная пауза между попытками, удваивается после каждой неудачи
+	Interval time.Duration
+	// MaxInterval максимальная пауза между попытками
+	MaxInterval time.Duration
+}
+
+// Retry вызывает fn, пока она не завершится успешно или не истечет cfg.Timeout.
+// Возвращает последнюю ошибку fn, если зависимость так и не стала доступна
+func Retry(ctx context.Context, name string, cfg RetryConfig, logger *logrus.Logger, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	interval := cfg.Interval
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Infof("%s is available after %d attempts", name, attempt)
+			}
+			return nil
+		}
+
+		logger.Warnf("%s is unavailable (attempt %d): %v, retrying in %v", name, attempt, err, interval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s is unavailable after %d attempts: %w", name, attempt, err)
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > cfg.MaxInterval {
+			interval = cfg.MaxInterval
+		}
+	}
+}
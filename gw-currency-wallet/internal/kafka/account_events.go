@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// AccountEventMessage событие учетной записи: регистрация или неудачный вход
+type AccountEventMessage struct {
+	EventID string `json:"event_id"`
+	Event   string `json:"event"`
+	// UserID 0, если неудачный вход выполнен под несуществующим именем
+	UserID    int64     `json:"user_id,omitempty"`
+	Username  string    `json:"username"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HasRoute сообщает, что для события настроен хотя бы один топик
+func (p *Producer) HasRoute(event string) bool {
+	return len(p.eventWriters[event]) > 0
+}
+
+// SendAccountEvent отправляет событие учетной записи во все топики его маршрутов.
+// Без маршрута для события ничего не делает
+func (p *Producer) SendAccountEvent(ctx context.Context, message AccountEventMessage) error {
+	writers := p.eventWriters[message.Event]
+	if len(writers) == 0 {
+		p.logger.Debugf("No route for %s events, skipping", message.Event)
+		return nil
+	}
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account event: %w", err)
+	}
+
+	// Ключ по имени сохраняет порядок событий учетной записи, в том числе
+	// неудачных входов под несуществующим именем
+	kafkaMessage := kafka.Message{
+		Key:   []byte("username_" + message.Username),
+		Value: messageBytes,
+		Headers: []kafka.Header{
+			{Key: EventIDHeader, Value: []byte(message.EventID)},
+			{Key: SourceHeader, Value: []byte(SourceService)},
+		},
+		Time: message.Timestamp,
+	}
+
+	for _, writer := range writers {
+		if err := writer.WriteMessages(ctx, kafkaMessage); err != nil {
+			p.logger.Errorf("Failed to send %s event to Kafka topic %s: %v", message.Event, writer.Topic, err)
+			return fmt.Errorf("failed to send account event: %w", err)
+		}
+	}
+
+	p.logger.Debugf("Sent %s event for %s", message.Event, message.Username)
+	return nil
+}
@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	pb "gw-currency-wallet/proto"
+)
+
+// Форматы сериализации уведомлений о крупных переводах
+const (
+	MessageFormatJSON     = "json"
+	MessageFormatProtobuf = "protobuf"
+)
+
+// ContentTypeHeader заголовок с форматом тела сообщения
+const ContentTypeHeader = "content-type"
+
+// Значения ContentTypeHeader
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// contentTypes тип содержимого для каждого формата
+var contentTypes = map[string]string{
+	MessageFormatJSON:     ContentTypeJSON,
+	MessageFormatProtobuf: ContentTypeProtobuf,
+}
+
+// ValidMessageFormat проверяет, что формат сериализации поддерживается
+func ValidMessageFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// marshalLargeTransfer сериализует уведомление в формате format и возвращает
+// тело сообщения и значение ContentTypeHeader
+func marshalLargeTransfer(message LargeTransferMessage, format string) ([]byte, string, error) {
+	switch format {
+	case MessageFormatJSON, "":
+		value, err := json.Marshal(message)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal message: %w", err)
+		}
+		return value, ContentTypeJSON, nil
+
+	case MessageFormatProtobuf:
+		value, err := proto.Marshal(&pb.LargeTransfer{
+			EventId:           message.EventID,
+			TransactionId:     message.TransactionID,
+			UserId:            message.UserID,
+			Type:              message.Type,
+			FromCurrency:      message.FromCurrency,
+			ToCurrency:        message.ToCurrency,
+			Amount:            message.Amount,
+			Timestamp:         timestamppb.New(message.Timestamp),
+			MessageId:         message.MessageID,
+			SchemaVersion:     int32(message.SchemaVersion),
+			ConvertedAmount:   message.ConvertedAmount,
+			ConvertedCurrency: message.ConvertedCurrency,
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal protobuf message: %w", err)
+		}
+		return value, ContentTypeProtobuf, nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported message format: %q", format)
+	}
+}
+
+// DecodeLargeTransfer разбирает уведомление по заголовку ContentTypeHeader.
+// Сообщения без заголовка считаются JSON
+func DecodeLargeTransfer(msg kafka.Message) (LargeTransferMessage, error) {
+	var contentType string
+	for _, h := range msg.Headers {
+		if h.Key == ContentTypeHeader {
+			contentType = string(h.Value)
+		}
+	}
+
+	switch contentType {
+	case ContentTypeJSON, "":
+		var message LargeTransferMessage
+		if err := json.Unmarshal(msg.Value, &message); err != nil {
+			return LargeTransferMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+		return message, nil
+
+	case ContentTypeProtobuf:
+		var transfer pb.LargeTransfer
+		if err := proto.Unmarshal(msg.Value, &transfer); err != nil {
+			return LargeTransferMessage{}, fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+		}
+		return LargeTransferMessage{
+			EventID:           transfer.GetEventId(),
+			TransactionID:     transfer.GetTransactionId(),
+			UserID:            transfer.GetUserId(),
+			Type:              transfer.GetType(),
+			FromCurrency:      transfer.GetFromCurrency(),
+			ToCurrency:        transfer.GetToCurrency(),
+			Amount:            transfer.GetAmount(),
+			Timestamp:         transfer.GetTimestamp().AsTime(),
+			MessageID:         transfer.GetMessageId(),
+			SchemaVersion:     int(transfer.GetSchemaVersion()),
+			ConvertedAmount:   transfer.GetConvertedAmount(),
+			ConvertedCurrency: transfer.GetConvertedCurrency(),
+		}, nil
+
+	default:
+		return LargeTransferMessage{}, fmt.Errorf("unsupported content type: %q", contentType)
+	}
+}
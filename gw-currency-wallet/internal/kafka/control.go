@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gw-common/kafkasecurity"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Действия управляющих сообщений
+const (
+	// ControlActionFreeze временно замораживает выводы и обмены пользователя
+	ControlActionFreeze = "freeze"
+)
+
+// controlRetryDelay пауза перед повтором после ошибки чтения или применения сообщения
+const controlRetryDelay = 5 * time.Second
+
+// ControlMessage управляющее сообщение сервиса уведомлений, например о заморозке
+// пользователя после срабатывания правила подозрительной активности
+type ControlMessage struct {
+	EventID string `json:"event_id"`
+	Action  string `json:"action"`
+	UserID  int64  `json:"user_id"`
+	// Rule и Reason правило и описание срабатывания
+	Rule   string `json:"rule,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Until окончание заморозки
+	Until     time.Time `json:"until"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ControlHandler применяет управляющее сообщение. Ошибка означает сбой
+// хранилища: сообщение будет применено повторно
+type ControlHandler func(ctx context.Context, message ControlMessage) error
+
+// ControlConsumerConfig конфигурация consumer управляющих сообщений
+type ControlConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// Security SASL и TLS соединений с брокерами, nil - без аутентификации
+	Security *kafkasecurity.Security
+}
+
+// ControlConsumer читает управляющие сообщения сервиса уведомлений.
+// Сообщения редкие, поэтому обрабатываются по одному
+type ControlConsumer struct {
+	reader  *kafka.Reader
+	handler ControlHandler
+	logger  *logrus.Logger
+}
+
+// NewControlConsumer создает consumer управляющих сообщений
+func NewControlConsumer(cfg ControlConsumerConfig, handler ControlHandler, logger *logrus.Logger) *ControlConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
+		Dialer:      cfg.Security.Dialer(),
+		Logger:      kafka.LoggerFunc(logger.Debugf),
+		ErrorLogger: kafka.LoggerFunc(logger.Errorf),
+	})
+
+	logger.Infof("Control consumer initialized: Topic=%s, GroupID=%s", cfg.Topic, cfg.GroupID)
+
+	return &ControlConsumer{
+		reader:  reader,
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Start читает сообщения до отмены контекста (блокирующий вызов). Сообщение
+// коммитится только после применения, чтобы заморозка не потерялась при сбое
+func (c *ControlConsumer) Start(ctx context.Context) error {
+	c.logger.Info("Starting control consumer...")
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Control consumer stopped")
+				return nil
+			}
+			c.logger.Errorf("Failed to fetch control message: %v", err)
+			if !sleepContext(ctx, controlRetryDelay) {
+				return nil
+			}
+			continue
+		}
+
+		for {
+			err := c.HandleMessage(ctx, msg)
+			if err == nil {
+				break
+			}
+			c.logger.Errorf("Failed to apply control message, retrying in %v: %v", controlRetryDelay, err)
+			if !sleepContext(ctx, controlRetryDelay) {
+				return nil
+			}
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			c.logger.Errorf("Failed to commit control message: %v", err)
+		}
+	}
+}
+
+// HandleMessage применяет одно сообщение. Некорректные сообщения пропускаются
+// с ошибкой в логе, ошибка возвращается только при сбое обработчика
+func (c *ControlConsumer) HandleMessage(ctx context.Context, msg kafka.Message) error {
+	message, err := ParseControlMessage(msg)
+	if err != nil {
+		c.logger.Errorf("Skipping invalid control message at offset %d: %v", msg.Offset, err)
+		return nil
+	}
+
+	if err := c.handler(ctx, message); err != nil {
+		return fmt.Errorf("failed to apply control message %s: %w", message.EventID, err)
+	}
+	return nil
+}
+
+// ParseControlMessage разбирает и проверяет управляющее сообщение
+func ParseControlMessage(msg kafka.Message) (ControlMessage, error) {
+	var message ControlMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return ControlMessage{}, fmt.Errorf("failed to unmarshal control message: %w", err)
+	}
+
+	if message.EventID == "" {
+		for _, h := range msg.Headers {
+			if h.Key == EventIDHeader {
+				message.EventID = string(h.Value)
+			}
+		}
+	}
+
+	if message.UserID <= 0 {
+		return ControlMessage{}, fmt.Errorf("invalid user ID: %d", message.UserID)
+	}
+
+	if message.Timestamp.IsZero() {
+		return ControlMessage{}, fmt.Errorf("control message without timestamp")
+	}
+
+	if message.Action == ControlActionFreeze && !message.Until.After(message.Timestamp) {
+		return ControlMessage{}, fmt.Errorf("freeze must end after %s", message.Timestamp.Format(time.RFC3339))
+	}
+
+	return message, nil
+}
+
+// sleepContext ждет d или отмены контекста. Возвращает false, если контекст отменен
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Close закрывает consumer
+func (c *ControlConsumer) Close() error {
+	c.logger.Info("Closing control consumer")
+	return c.reader.Close()
+}
@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Ping проверяет готовность producer к записи: брокеры отвечают, у всех топиков
+// маршрутов и событий пользователей есть партиции с лидерами, и последняя запись
+// уведомлений не завершилась ошибкой
+func (p *Producer) Ping(ctx context.Context) error {
+	var topics []string
+	for _, route := range p.currentRoutes() {
+		topics = append(topics, route.Topic)
+	}
+	for _, writers := range p.eventWriters {
+		for _, writer := range writers {
+			topics = append(topics, writer.Topic)
+		}
+	}
+	if p.userWriter != nil {
+		topics = append(topics, p.userWriter.Topic)
+	}
+
+	client := &kafka.Client{Addr: p.writer.Addr, Transport: p.transport}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("failed to get Kafka metadata: %w", err)
+	}
+
+	for _, topic := range metadata.Topics {
+		if topic.Error != nil {
+			return fmt.Errorf("topic %s is unavailable: %w", topic.Name, topic.Error)
+		}
+		if len(topic.Partitions) == 0 {
+			return fmt.Errorf("topic %s has no partitions", topic.Name)
+		}
+		for _, partition := range topic.Partitions {
+			if partition.Error != nil || partition.Leader.Host == "" {
+				return fmt.Errorf("topic %s partition %d has no leader", topic.Name, partition.ID)
+			}
+		}
+	}
+
+	if err := p.lastWriteError(); err != nil {
+		return fmt.Errorf("last write to Kafka failed: %w", err)
+	}
+	return nil
+}
+
+// setWriteResult запоминает результат последней записи в топик уведомлений
+func (p *Producer) setWriteResult(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.writeErr = err
+}
+
+// lastWriteError возвращает ошибку последней записи; nil, если запись прошла
+// или еще не выполнялась
+func (p *Producer) lastWriteError() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.writeErr
+}
@@ -2,100 +2,439 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"gw-common/kafkasecurity"
+
+	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
+// LargeTransferSchemaVersion текущая версия схемы LargeTransferMessage.
+// Версия 1 - сообщения без schema_version; версия 2 добавила message_id
+// и сумму в валюте зачисления
+const LargeTransferSchemaVersion = 2
+
+// SourceService имя сервиса в заголовке SourceHeader
+const SourceService = "gw-currency-wallet"
+
 // LargeTransferMessage сообщение о крупном переводе
 type LargeTransferMessage struct {
-	UserID       int64     `json:"user_id"`
-	Type         string    `json:"type"`
-	FromCurrency string    `json:"from_currency"`
-	ToCurrency   string    `json:"to_currency"`
-	Amount       float64   `json:"amount"`
-	Timestamp    time.Time `json:"timestamp"`
+	EventID       string    `json:"event_id,omitempty"`
+	TransactionID int64     `json:"transaction_id,omitempty"`
+	UserID        int64     `json:"user_id"`
+	Type          string    `json:"type"`
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	Amount        float64   `json:"amount"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	// MessageID уникален для каждой отправки, в отличие от EventID,
+	// который повторяется при повторной отправке того же события
+	MessageID     string `json:"message_id,omitempty"`
+	SchemaVersion int    `json:"schema_version"`
+	// ConvertedAmount сумма в валюте ConvertedCurrency: для обмена - зачисленная
+	// сумма в валюте назначения, для пополнения и вывода совпадает с Amount
+	ConvertedAmount   float64 `json:"converted_amount,omitempty"`
+	ConvertedCurrency string  `json:"converted_currency,omitempty"`
+
+	// ReferenceAmount сумма в опорной валюте порога для выбора топиков,
+	// в сообщение не попадает; 0 - сравнивается Amount
+	ReferenceAmount float64 `json:"-"`
+}
+
+// Заголовки сообщений Kafka
+const (
+	// EventIDHeader идентификатор события для дедупликации
+	EventIDHeader = "event_id"
+	// MessageIDHeader уникальный идентификатор отправленного сообщения
+	MessageIDHeader = "message_id"
+	// SchemaVersionHeader версия схемы тела сообщения
+	SchemaVersionHeader = "schema_version"
+	// TraceIDHeader идентификатор трассировки
+	TraceIDHeader = "trace_id"
+	// SourceHeader сервис, отправивший сообщение
+	SourceHeader = "source"
+)
+
+// traceIDKey ключ идентификатора трассировки в контексте
+type traceIDKey struct{}
+
+// ContextWithTraceID возвращает контекст с идентификатором трассировки,
+// который SendLargeTransfers передаст в заголовке TraceIDHeader
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext возвращает идентификатор трассировки из контекста
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// EventIDFromTransaction возвращает детерминированный идентификатор события для транзакции.
+// Один и тот же ID при повторной отправке позволяет consumer отбрасывать дубликаты.
+func EventIDFromTransaction(transactionID int64) string {
+	if transactionID <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("wallet-tx-%d", transactionID)
 }
 
+// Режимы подтверждения записи брокерами
+const (
+	RequiredAcksAll  = "all"
+	RequiredAcksOne  = "one"
+	RequiredAcksNone = "none"
+)
+
+// Config содержит конфигурацию Kafka producer
+type Config struct {
+	Brokers []string
+	Topic   string
+	// TransferThreshold порог крупного перевода в валюте ThresholdCurrency
+	TransferThreshold float64
+	// ThresholdCurrency опорная валюта порога; суммы в других валютах
+	// пересчитываются в нее по курсу
+	ThresholdCurrency string
+	// ThresholdOverrides пороги для отдельных валют в сумме самой валюты,
+	// применяются без пересчета
+	ThresholdOverrides map[string]float64
+	// Sync синхронная отправка: WriteMessages возвращает ошибку доставки.
+	// В асинхронном режиме ошибки передаются DeliveryErrorHandler
+	Sync bool
+	// RequiredAcks подтверждения брокеров: all, one, none
+	RequiredAcks string
+	// MessageFormat формат уведомлений о крупных переводах: json или protobuf
+	MessageFormat string
+	// UserEventsTopic топик событий жизненного цикла пользователей,
+	// пустое значение отключает их отправку
+	UserEventsTopic string
+	// Routes таблица маршрутизации событий по топикам. Без маршрутов
+	// large_transfer уведомления идут в Topic
+	Routes []Route
+	// Security SASL и TLS соединений с брокерами, nil - без аутентификации
+	Security *kafkasecurity.Security
+}
+
+// DeliveryErrorHandler вызывается для сообщений, которые не удалось доставить
+// в асинхронном режиме
+type DeliveryErrorHandler func(messages []LargeTransferMessage, err error)
+
 // Producer Kafka producer для отправки сообщений
 type Producer struct {
-	writer    *kafka.Writer
-	threshold float64
-	logger    *logrus.Logger
+	// writer топика крупных переводов с наименьшим порогом
+	writer  *kafka.Writer
+	writers map[string]*kafka.Writer
+	// routes маршруты из конфигурации и топик крупных переводов без маршрутов,
+	// из них пересчитываются transferRoutes при смене порога
+	routes       []Route
+	defaultTopic string
+	// eventWriters топики событий учетных записей по типу события
+	eventWriters map[string][]*kafka.Writer
+	userWriter   *kafka.Writer
+
+	transport         kafka.RoundTripper
+	thresholdCurrency string
+	format            string
+	logger            *logrus.Logger
+
+	mu             sync.RWMutex
+	transferRoutes []Route // меняются SetThresholds
+	threshold      float64
+	overrides      map[string]float64
+	onDeliveryFail DeliveryErrorHandler
+	writeErr       error // ошибка последней записи уведомлений, для Ping
 }
 
 // NewProducer создает новый Kafka producer
-func NewProducer(brokers []string, topic string, threshold float64, logger *logrus.Logger) *Producer {
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokers...),
-		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireOne,
-		Async:        true, // Асинхронная отправка для производительности
-		Compression:  kafka.Snappy,
-		BatchTimeout: 10 * time.Millisecond,
+func NewProducer(cfg *Config, logger *logrus.Logger) *Producer {
+	var acks kafka.RequiredAcks
+	if err := acks.UnmarshalText([]byte(cfg.RequiredAcks)); err != nil {
+		logger.Warnf("Invalid Kafka required acks %q, using %q", cfg.RequiredAcks, RequiredAcksAll)
+		acks = kafka.RequireAll
 	}
 
-	logger.Infof("Kafka producer initialized for topic: %s", topic)
+	format := cfg.MessageFormat
+	if !ValidMessageFormat(format) {
+		if format != "" {
+			logger.Warnf("Invalid Kafka message format %q, using %q", format, MessageFormatJSON)
+		}
+		format = MessageFormatJSON
+	}
+
+	p := &Producer{
+		thresholdCurrency: cfg.ThresholdCurrency,
+		overrides:         cfg.ThresholdOverrides,
+		format:            format,
+		transport:         transport(cfg.Security),
+		logger:            logger,
+		routes:            cfg.Routes,
+		defaultTopic:      cfg.Topic,
+		transferRoutes:    transferRoutes(cfg.Routes, cfg.Topic, cfg.TransferThreshold),
+		writers:           make(map[string]*kafka.Writer),
+		eventWriters:      make(map[string][]*kafka.Writer),
+	}
+	// Уведомление считается крупным по наименьшему порогу маршрутов
+	p.threshold = p.transferRoutes[0].Threshold
 
-	return &Producer{
-		writer:    writer,
-		threshold: threshold,
-		logger:    logger,
+	for _, route := range p.transferRoutes {
+		writer := &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        route.Topic,
+			Balancer:     &kafka.LeastBytes{},
+			RequiredAcks: acks,
+			// В синхронном режиме outbox relay помечает записи отправленными
+			// только после подтверждения от брокера
+			Async:        !cfg.Sync,
+			Compression:  kafka.Snappy,
+			BatchTimeout: 10 * time.Millisecond,
+			Transport:    p.transport,
+		}
+		if !cfg.Sync {
+			writer.Completion = p.onCompletion
+		}
+		p.writers[route.Topic] = writer
+	}
+	p.writer = p.writers[p.transferRoutes[0].Topic]
+
+	for _, route := range cfg.Routes {
+		if route.Event == EventLargeTransfer {
+			continue
+		}
+		p.eventWriters[route.Event] = append(p.eventWriters[route.Event],
+			newUserEventsWriter(cfg.Brokers, route.Topic, p.transport))
+	}
+	if cfg.UserEventsTopic != "" {
+		p.userWriter = newUserEventsWriter(cfg.Brokers, cfg.UserEventsTopic, p.transport)
 	}
+
+	for _, route := range p.transferRoutes {
+		logger.Infof("Routing large transfers from %.2f %s to topic: %s", route.Threshold, cfg.ThresholdCurrency, route.Topic)
+	}
+	for _, route := range cfg.Routes {
+		if route.Event != EventLargeTransfer {
+			logger.Infof("Routing %s events to topic: %s", route.Event, route.Topic)
+		}
+	}
+	logger.Infof("Kafka producer initialized (sync: %t, required acks: %s, format: %s, security: %s)",
+		cfg.Sync, acks, format, cfg.Security)
+
+	return p
 }
 
-// SendLargeTransferNotification отправляет уведомление о крупном переводе, если сумма превышает порог
-func (p *Producer) SendLargeTransferNotification(ctx context.Context, userID int64, transferType, fromCurrency, toCurrency string, amount float64) error {
-	// Проверяем, превышает ли сумма порог
-	if amount < p.threshold {
-		p.logger.Debugf("Transfer amount %.2f is below threshold %.2f, skipping Kafka notification", amount, p.threshold)
+// transport возвращает транспорт, общий для writer-ов продюсера и client проверки
+// брокеров; nil security - транспорт kafka-go по умолчанию
+func transport(security *kafkasecurity.Security) kafka.RoundTripper {
+	return security.Transport()
+}
+
+// IsAsync сообщает, что ошибки доставки приходят в DeliveryErrorHandler, а не из SendLargeTransfers
+func (p *Producer) IsAsync() bool {
+	return p.writer.Async
+}
+
+// OnDeliveryError задает обработчик ошибок доставки асинхронного режима
+func (p *Producer) OnDeliveryError(handler DeliveryErrorHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onDeliveryFail = handler
+}
+
+// onCompletion получает результат асинхронной записи пачки сообщений
+func (p *Producer) onCompletion(messages []kafka.Message, err error) {
+	p.setWriteResult(err)
+	if err == nil {
+		p.logger.Debugf("Delivered %d messages to Kafka", len(messages))
+		return
+	}
+
+	p.logger.Errorf("Failed to deliver %d messages to Kafka: %v", len(messages), err)
+
+	p.mu.RLock()
+	handler := p.onDeliveryFail
+	p.mu.RUnlock()
+
+	if handler == nil {
+		return
+	}
+
+	failed := make([]LargeTransferMessage, 0, len(messages))
+	for _, message := range messages {
+		transfer, unmarshalErr := DecodeLargeTransfer(message)
+		if unmarshalErr != nil {
+			p.logger.Errorf("Failed to decode undelivered message: %v", unmarshalErr)
+			continue
+		}
+		failed = append(failed, transfer)
+	}
+
+	handler(failed, err)
+}
+
+// SetThresholds меняет общий порог крупного перевода и пороги валют без перезапуска.
+// Топики не меняются; маршруты со своим порогом сохраняют его
+func (p *Producer) SetThresholds(threshold float64, overrides map[string]float64) {
+	routes := transferRoutes(p.routes, p.defaultTopic, threshold)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transferRoutes = routes
+	p.threshold = routes[0].Threshold
+	p.overrides = overrides
+}
+
+// currentRoutes возвращает действующие маршруты крупных переводов. Срез не изменяется:
+// SetThresholds заменяет его целиком
+func (p *Producer) currentRoutes() []Route {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.transferRoutes
+}
+
+// IsLargeTransfer проверяет, превышает ли сумма в опорной валюте наименьший порог маршрутов
+func (p *Producer) IsLargeTransfer(amount float64) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return amount >= p.threshold
+}
+
+// ThresholdCurrency возвращает опорную валюту порога
+func (p *Producer) ThresholdCurrency() string {
+	return p.thresholdCurrency
+}
+
+// CurrencyThreshold возвращает порог, заданный для валюты, в сумме самой валюты
+func (p *Producer) CurrencyThreshold(currency string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	threshold, ok := p.overrides[currency]
+	return threshold, ok
+}
+
+// SendLargeTransfers отправляет уведомления о крупных переводах.
+// Топики выбираются по ReferenceAmount (см. TransferTopics).
+// В синхронном режиме ошибка означает, что сообщения могли быть не доставлены и их
+// нужно отправить повторно: в топики, куда запись уже прошла, придут дубликаты с тем же
+// event_id. В асинхронном режиме сообщения только ставятся в очередь writer
+func (p *Producer) SendLargeTransfers(ctx context.Context, messages []LargeTransferMessage) error {
+	routes := p.currentRoutes()
+	batches := make(map[string][]kafka.Message, len(p.writers))
+	for _, message := range messages {
+		kafkaMessage, err := EncodeLargeTransfer(ctx, message, p.format)
+		if err != nil {
+			p.logger.Errorf("Failed to marshal Kafka message: %v", err)
+			return err
+		}
+
+		referenceAmount := message.ReferenceAmount
+		if referenceAmount == 0 {
+			referenceAmount = message.Amount
+		}
+		for _, topic := range transferTopics(routes, referenceAmount) {
+			batches[topic] = append(batches[topic], kafkaMessage)
+		}
+	}
+
+	for _, route := range routes {
+		batch := batches[route.Topic]
+		if len(batch) == 0 {
+			continue
+		}
+
+		err := p.writers[route.Topic].WriteMessages(ctx, batch...)
+		if !p.IsAsync() {
+			p.setWriteResult(err)
+		}
+		if err != nil {
+			p.logger.Errorf("Failed to send messages to Kafka topic %s: %v", route.Topic, err)
+			return fmt.Errorf("failed to send messages: %w", err)
+		}
+	}
+
+	if p.IsAsync() {
+		p.logger.Debugf("Queued %d large transfer notifications for Kafka", len(messages))
 		return nil
 	}
 
-	message := LargeTransferMessage{
-		UserID:       userID,
-		Type:         transferType,
-		FromCurrency: fromCurrency,
-		ToCurrency:   toCurrency,
-		Amount:       amount,
-		Timestamp:    time.Now(),
+	p.logger.Infof("Sent %d large transfer notifications to Kafka", len(messages))
+	return nil
+}
+
+// EncodeLargeTransfer сериализует уведомление текущей версии схемы в сообщение Kafka
+// в формате format (json или protobuf). Недостающие идентификаторы заполняются:
+// event_id - из транзакции, message_id - новым UUID, trace_id - из контекста или,
+// если его нет, значением message_id
+func EncodeLargeTransfer(ctx context.Context, message LargeTransferMessage, format string) (kafka.Message, error) {
+	if message.EventID == "" {
+		message.EventID = EventIDFromTransaction(message.TransactionID)
 	}
+	if message.MessageID == "" {
+		message.MessageID = uuid.NewString()
+	}
+	message.SchemaVersion = LargeTransferSchemaVersion
 
-	// Сериализуем сообщение в JSON
-	messageBytes, err := json.Marshal(message)
+	messageBytes, contentType, err := marshalLargeTransfer(message, format)
 	if err != nil {
-		p.logger.Errorf("Failed to marshal Kafka message: %v", err)
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return kafka.Message{}, err
 	}
 
-	// Отправляем сообщение в Kafka
+	traceID := TraceIDFromContext(ctx)
+	if traceID == "" {
+		traceID = message.MessageID
+	}
+
+	// Ключ по пользователю сохраняет порядок событий одного пользователя в партиции,
+	// а event_id в заголовке и теле сообщения делает повторную доставку идемпотентной
 	kafkaMessage := kafka.Message{
-		Key:   []byte(fmt.Sprintf("user_%d", userID)),
+		Key:   []byte(fmt.Sprintf("user_%d", message.UserID)),
 		Value: messageBytes,
 		Time:  time.Now(),
+		Headers: []kafka.Header{
+			{Key: MessageIDHeader, Value: []byte(message.MessageID)},
+			{Key: SchemaVersionHeader, Value: []byte(strconv.Itoa(message.SchemaVersion))},
+			{Key: TraceIDHeader, Value: []byte(traceID)},
+			{Key: SourceHeader, Value: []byte(SourceService)},
+			{Key: ContentTypeHeader, Value: []byte(contentType)},
+		},
 	}
-
-	err = p.writer.WriteMessages(ctx, kafkaMessage)
-	if err != nil {
-		p.logger.Errorf("Failed to send message to Kafka: %v", err)
-		return fmt.Errorf("failed to send message: %w", err)
+	if message.EventID != "" {
+		kafkaMessage.Headers = append(kafkaMessage.Headers, kafka.Header{Key: EventIDHeader, Value: []byte(message.EventID)})
 	}
-
-	p.logger.Infof("Sent large transfer notification to Kafka: UserID=%d, Amount=%.2f %s",
-		userID, amount, fromCurrency)
-
-	return nil
+	return kafkaMessage, nil
 }
 
 // Close закрывает Kafka producer
 func (p *Producer) Close() error {
-	if p.writer != nil {
-		p.logger.Info("Closing Kafka producer")
-		return p.writer.Close()
+	if p.userWriter != nil {
+		if err := p.userWriter.Close(); err != nil {
+			p.logger.Errorf("Failed to close user events writer: %v", err)
+		}
 	}
-	return nil
+	for event, writers := range p.eventWriters {
+		for _, writer := range writers {
+			if err := writer.Close(); err != nil {
+				p.logger.Errorf("Failed to close %s events writer: %v", event, err)
+			}
+		}
+	}
+
+	p.logger.Info("Closing Kafka producer")
+	var closeErr error
+	for topic, writer := range p.writers {
+		if err := writer.Close(); err != nil {
+			p.logger.Errorf("Failed to close writer for topic %s: %v", topic, err)
+			closeErr = err
+		}
+	}
+	return closeErr
 }
@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gw-common/kafkasecurity"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// RateUpdateMessage событие изменения курса пары, которое публикует gw-exchanger
+type RateUpdateMessage struct {
+	EventID      string `json:"event_id"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	// OldRate курс до изменения, 0 - пара создана
+	OldRate float64 `json:"old_rate"`
+	NewRate float64 `json:"new_rate"`
+	// Source источник изменения: plugin:<name>, api:<caller>, import
+	Source        string    `json:"source"`
+	Timestamp     time.Time `json:"timestamp"`
+	SchemaVersion int       `json:"schema_version"`
+}
+
+// RateUpdateHandler применяет событие изменения курса. Ошибка пишется в лог,
+// событие не повторяется: курс обновится вместе со всеми курсами по TTL кеша
+type RateUpdateHandler func(ctx context.Context, message RateUpdateMessage) error
+
+// RateUpdateConsumerConfig конфигурация consumer событий изменения курсов
+type RateUpdateConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	// GroupID группа consumer. Кеш курсов у каждого экземпляра свой, поэтому
+	// группа должна быть уникальной для экземпляра (см. InstanceGroupID)
+	GroupID string
+	// Security SASL и TLS соединений с брокерами, nil - без аутентификации
+	Security *kafkasecurity.Security
+}
+
+// RateUpdateConsumer читает события изменения курсов gw-exchanger. При запуске
+// читаются только новые события: кеш курсов заполняется из exchanger
+type RateUpdateConsumer struct {
+	reader  *kafka.Reader
+	handler RateUpdateHandler
+	logger  *logrus.Logger
+}
+
+// InstanceGroupID возвращает группу consumer, уникальную для экземпляра сервиса
+func InstanceGroupID(prefix string) string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "wallet"
+	}
+	return fmt.Sprintf("%s-%s-%s", prefix, hostname, uuid.NewString()[:8])
+}
+
+// NewRateUpdateConsumer создает consumer событий изменения курсов
+func NewRateUpdateConsumer(cfg RateUpdateConsumerConfig, handler RateUpdateHandler, logger *logrus.Logger) *RateUpdateConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
+		StartOffset: kafka.LastOffset,
+		Dialer:      cfg.Security.Dialer(),
+		Logger:      kafka.LoggerFunc(logger.Debugf),
+		ErrorLogger: kafka.LoggerFunc(logger.Errorf),
+	})
+
+	logger.Infof("Rate update consumer initialized: Topic=%s, GroupID=%s", cfg.Topic, cfg.GroupID)
+
+	return &RateUpdateConsumer{
+		reader:  reader,
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Start читает события до отмены контекста (блокирующий вызов)
+func (c *RateUpdateConsumer) Start(ctx context.Context) error {
+	c.logger.Info("Starting rate update consumer...")
+
+	for {
+		msg, err := c.reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Rate update consumer stopped")
+				return nil
+			}
+			c.logger.Errorf("Failed to fetch rate update: %v", err)
+			if !sleepContext(ctx, controlRetryDelay) {
+				return nil
+			}
+			continue
+		}
+
+		c.HandleMessage(ctx, msg)
+	}
+}
+
+// HandleMessage применяет одно событие. Некорректные события и ошибки
+// обработчика пишутся в лог
+func (c *RateUpdateConsumer) HandleMessage(ctx context.Context, msg kafka.Message) {
+	message, err := ParseRateUpdate(msg)
+	if err != nil {
+		c.logger.Errorf("Skipping invalid rate update at offset %d: %v", msg.Offset, err)
+		return
+	}
+
+	if err := c.handler(ctx, message); err != nil {
+		c.logger.Warnf("Failed to apply rate update %s: %v", message.EventID, err)
+	}
+}
+
+// ParseRateUpdate разбирает и проверяет событие изменения курса
+func ParseRateUpdate(msg kafka.Message) (RateUpdateMessage, error) {
+	var message RateUpdateMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return RateUpdateMessage{}, fmt.Errorf("failed to unmarshal rate update: %w", err)
+	}
+
+	if message.EventID == "" {
+		for _, h := range msg.Headers {
+			if h.Key == EventIDHeader {
+				message.EventID = string(h.Value)
+			}
+		}
+	}
+
+	if message.FromCurrency == "" || message.ToCurrency == "" {
+		return RateUpdateMessage{}, fmt.Errorf("rate update without currency pair")
+	}
+
+	if message.NewRate <= 0 {
+		return RateUpdateMessage{}, fmt.Errorf("invalid rate %s -> %s: %v", message.FromCurrency, message.ToCurrency, message.NewRate)
+	}
+
+	return message, nil
+}
+
+// Close закрывает consumer
+func (c *RateUpdateConsumer) Close() error {
+	c.logger.Info("Closing rate update consumer")
+	return c.reader.Close()
+}
@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Типы событий таблицы маршрутизации
+const (
+	EventLargeTransfer  = "large_transfer"
+	EventFailedLogin    = "failed_login"
+	EventAccountCreated = "account_created"
+)
+
+// routedEvents события, которые можно направить в топики
+var routedEvents = map[string]bool{
+	EventLargeTransfer:  true,
+	EventFailedLogin:    true,
+	EventAccountCreated: true,
+}
+
+// Route направляет события одного типа в топик
+type Route struct {
+	Event string
+	Topic string
+	// Threshold порог крупного перевода в опорной валюте для маршрута
+	// large_transfer; 0 - общий порог TransferThreshold
+	Threshold float64
+}
+
+// ParseRoutes разбирает таблицу маршрутизации в формате
+// "large_transfer:compliance:1000000,failed_login:security-events".
+// Порог указывается только для large_transfer. Одно событие можно направить
+// в несколько топиков
+func ParseRoutes(value string) ([]Route, error) {
+	var routes []Route
+	seen := make(map[Route]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid route %q: expected event:topic[:threshold]", item)
+		}
+
+		route := Route{
+			Event: strings.ToLower(strings.TrimSpace(parts[0])),
+			Topic: strings.TrimSpace(parts[1]),
+		}
+		if !routedEvents[route.Event] {
+			return nil, fmt.Errorf("invalid route %q: unknown event %q", item, route.Event)
+		}
+		if route.Topic == "" {
+			return nil, fmt.Errorf("invalid route %q: empty topic", item)
+		}
+
+		if len(parts) == 3 {
+			if route.Event != EventLargeTransfer {
+				return nil, fmt.Errorf("invalid route %q: threshold is supported only for %s", item, EventLargeTransfer)
+			}
+			threshold, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if err != nil || threshold <= 0 {
+				return nil, fmt.Errorf("invalid route %q: threshold must be a positive number", item)
+			}
+			route.Threshold = threshold
+		}
+
+		key := Route{Event: route.Event, Topic: route.Topic}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate route %s:%s", route.Event, route.Topic)
+		}
+		seen[key] = true
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// transferRoutes возвращает маршруты крупных переводов по возрастанию порога.
+// Без маршрутов large_transfer уведомления идут в defaultTopic с общим порогом
+func transferRoutes(routes []Route, defaultTopic string, defaultThreshold float64) []Route {
+	var result []Route
+	for _, route := range routes {
+		if route.Event != EventLargeTransfer {
+			continue
+		}
+		if route.Threshold == 0 {
+			route.Threshold = defaultThreshold
+		}
+		result = append(result, route)
+	}
+
+	if len(result) == 0 {
+		return []Route{{Event: EventLargeTransfer, Topic: defaultTopic, Threshold: defaultThreshold}}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Threshold < result[j].Threshold
+	})
+	return result
+}
+
+// TransferTopics возвращает топики, в которые попадет уведомление о переводе
+// с суммой referenceAmount в опорной валюте. Топик с наименьшим порогом получает
+// все уведомления: решение о крупном переводе уже принято сервисом, в том числе
+// по порогам отдельных валют
+func (p *Producer) TransferTopics(referenceAmount float64) []string {
+	return transferTopics(p.currentRoutes(), referenceAmount)
+}
+
+// transferTopics выбирает топики уведомления по маршрутам routes (см. TransferTopics)
+func transferTopics(routes []Route, referenceAmount float64) []string {
+	topics := []string{routes[0].Topic}
+	for _, route := range routes[1:] {
+		if referenceAmount >= route.Threshold {
+			topics = append(topics, route.Topic)
+		}
+	}
+	return topics
+}
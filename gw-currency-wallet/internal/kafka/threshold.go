@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseThresholdOverrides разбирает пороги крупных переводов по валютам
+// в формате "RUB:3000000,EUR:28000". Порог задается в сумме самой валюты
+func ParseThresholdOverrides(value string) (map[string]float64, error) {
+	overrides := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid threshold %q: expected currency:amount", item)
+		}
+
+		currency := strings.ToUpper(strings.TrimSpace(parts[0]))
+		if currency == "" {
+			return nil, fmt.Errorf("invalid threshold %q: empty currency", item)
+		}
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("invalid threshold %q: amount must be a positive number", item)
+		}
+
+		overrides[currency] = amount
+	}
+
+	return overrides, nil
+}
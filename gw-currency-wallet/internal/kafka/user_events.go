@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// События жизненного цикла пользователя
+const (
+	UserEventFrozen    = "user_frozen"
+	UserEventDeleted   = "user_deleted"
+	UserEventActivated = "user_activated"
+)
+
+// UserLifecycleMessage событие жизненного цикла пользователя. gw-notification
+// отключает по нему доставку уведомлений и помечает сохраненные переводы
+type UserLifecycleMessage struct {
+	EventID   string    `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	Event     string    `json:"event"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// newUserEventsWriter создает writer событий пользователей. Запись синхронная
+// с подтверждением всех реплик: событие редкое, а его потеря оставит
+// сервис уведомлений с устаревшим статусом пользователя
+func newUserEventsWriter(brokers []string, topic string, transport kafka.RoundTripper) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    transport,
+	}
+}
+
+// SendUserLifecycle отправляет событие жизненного цикла пользователя.
+// Без настроенного топика событий ничего не делает
+func (p *Producer) SendUserLifecycle(ctx context.Context, message UserLifecycleMessage) error {
+	if p.userWriter == nil {
+		p.logger.Debugf("User events topic is not configured, skipping %s for user %d", message.Event, message.UserID)
+		return nil
+	}
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user event: %w", err)
+	}
+
+	// Ключ по пользователю сохраняет порядок событий одного пользователя
+	err = p.userWriter.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(fmt.Sprintf("user_%d", message.UserID)),
+		Value:   messageBytes,
+		Headers: []kafka.Header{{Key: EventIDHeader, Value: []byte(message.EventID)}},
+		Time:    message.Timestamp,
+	})
+	if err != nil {
+		p.logger.Errorf("Failed to send user event to Kafka: %v", err)
+		return fmt.Errorf("failed to send user event: %w", err)
+	}
+
+	p.logger.Infof("Sent user event %s for user %d", message.Event, message.UserID)
+	return nil
+}
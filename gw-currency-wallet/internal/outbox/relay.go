@@ -0,0 +1,242 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/polling"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Publisher отправляет уведомления о крупных переводах, реализуется kafka.Producer
+type Publisher interface {
+	SendLargeTransfers(ctx context.Context, messages []kafka.LargeTransferMessage) error
+	IsAsync() bool
+	OnDeliveryError(handler kafka.DeliveryErrorHandler)
+}
+
+// Config параметры outbox relay
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts число попыток, после которого запись откладывается насовсем
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором записи (см. polling.Backoff)
+	RetryInterval time.Duration
+}
+
+// Relay публикует записи outbox в Kafka и отмечает их отправленными.
+// В синхронном режиме producer доставка "хотя бы один раз": при сбое между отправкой
+// и отметкой сообщение уйдет повторно, дубликаты отбрасываются consumer по event_id.
+// Если порция не отправилась, записи отправляются по одной: неудачная запись
+// повторяется с растущей паузой, не задерживая следующие, а после MaxAttempts
+// попыток откладывается насовсем (parked_at) и остается в outbox для разбора.
+// В асинхронном режиме записи отмечаются до отправки, а недоставленные сообщения
+// возвращаются в очередь обработчиком ошибок доставки с паузой RetryInterval; при
+// падении процесса сообщения, еще не записанные в Kafka, теряются
+type Relay struct {
+	storage  storages.Storage
+	producer Publisher
+	cfg      Config
+	logger   *logrus.Logger
+
+	// referenceAmount пересчитывает сумму в опорную валюту порога для
+	// выбора топиков producer, nil - без пересчета
+	referenceAmount AmountConverter
+}
+
+// AmountConverter пересчитывает сумму в валюте currency в опорную валюту порога
+type AmountConverter func(ctx context.Context, currency string, amount float64) float64
+
+// NewRelay создает новый outbox relay
+func NewRelay(storage storages.Storage, producer Publisher, cfg Config, logger *logrus.Logger) *Relay {
+	r := &Relay{
+		storage:  storage,
+		producer: producer,
+		cfg:      cfg,
+		logger:   logger,
+	}
+
+	if producer.IsAsync() {
+		producer.OnDeliveryError(r.requeue)
+	}
+
+	return r
+}
+
+// SetAmountConverter задает пересчет сумм для маршрутов крупных переводов с
+// собственными порогами
+func (r *Relay) SetAmountConverter(converter AmountConverter) {
+	r.referenceAmount = converter
+}
+
+// Run опрашивает outbox до отмены контекста
+func (r *Relay) Run(ctx context.Context) {
+	r.logger.Infof("Outbox relay started (interval: %v, batch size: %d, max attempts: %d)",
+		r.cfg.PollInterval, r.cfg.BatchSize, r.cfg.MaxAttempts)
+	polling.Run(ctx, r.cfg.PollInterval, r.cfg.BatchSize, r.RunDue)
+	r.logger.Info("Outbox relay stopped")
+}
+
+// RunDue отправляет одну порцию записей outbox, время попытки которых наступило
+// к now, и возвращает их количество
+func (r *Relay) RunDue(ctx context.Context, now time.Time) (int, error) {
+	entries, err := r.storage.FetchPendingOutbox(ctx, now, r.cfg.BatchSize)
+	if err != nil {
+		r.logger.Errorf("Failed to fetch outbox entries: %v", err)
+		return 0, err
+	}
+
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	// Записи, исчерпавшие попытки (в асинхронном режиме попытки считает requeue),
+	// откладываются насовсем
+	due := entries[:0]
+	for _, entry := range entries {
+		if entry.Attempts < r.cfg.MaxAttempts {
+			due = append(due, entry)
+			continue
+		}
+		if err := r.storage.ParkOutbox(ctx, entry.ID); err != nil {
+			r.logger.Errorf("Failed to park outbox entry %d: %v", entry.ID, err)
+			return 0, err
+		}
+		r.logger.Errorf("Outbox entry %d for transaction %d parked after %d attempts: %s",
+			entry.ID, entry.Transaction.ID, entry.Attempts, entry.LastError)
+	}
+	if len(due) == 0 {
+		return len(entries), nil
+	}
+
+	ids := make([]int64, 0, len(due))
+	messages := make([]kafka.LargeTransferMessage, 0, len(due))
+	for _, entry := range due {
+		ids = append(ids, entry.ID)
+		message := newLargeTransferMessage(&entry.Transaction)
+		if r.referenceAmount != nil {
+			message.ReferenceAmount = r.referenceAmount(ctx, message.FromCurrency, message.Amount)
+		}
+		messages = append(messages, message)
+	}
+
+	if r.producer.IsAsync() {
+		if err := r.relayAsync(ctx, ids, messages); err != nil {
+			return 0, err
+		}
+		return len(entries), nil
+	}
+
+	err = r.producer.SendLargeTransfers(ctx, messages)
+	if err == nil {
+		if err := r.storage.MarkOutboxSent(ctx, ids); err != nil {
+			r.logger.Errorf("Failed to mark outbox entries as sent: %v", err)
+			return 0, err
+		}
+		r.logger.Debugf("Relayed %d outbox entries", len(due))
+		return len(entries), nil
+	}
+
+	// Порция не отправилась: записи отправляются по одной, чтобы одна
+	// неотправляемая запись не задерживала остальные
+	r.logger.Warnf("Failed to relay %d outbox entries, sending one by one: %v", len(due), err)
+	for i, entry := range due {
+		if err := r.relayEntry(ctx, now, entry, messages[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// relayEntry отправляет одну запись и сохраняет результат попытки. Возвращает
+// только ошибки хранилища
+func (r *Relay) relayEntry(ctx context.Context, now time.Time, entry storages.OutboxEntry, message kafka.LargeTransferMessage) error {
+	sendErr := r.producer.SendLargeTransfers(ctx, []kafka.LargeTransferMessage{message})
+	if sendErr == nil {
+		if err := r.storage.MarkOutboxSent(ctx, []int64{entry.ID}); err != nil {
+			r.logger.Errorf("Failed to mark outbox entry %d as sent: %v", entry.ID, err)
+			return err
+		}
+		return nil
+	}
+
+	// После последней попытки запись откладывается насовсем при следующем опросе
+	attempt := entry.Attempts + 1
+	retryAt := now
+	if attempt < r.cfg.MaxAttempts {
+		retryAt = now.Add(polling.Backoff(r.cfg.RetryInterval, attempt))
+	}
+	r.logger.Warnf("Outbox entry %d attempt %d failed, retrying at %s: %v",
+		entry.ID, attempt, retryAt.Format(time.RFC3339), sendErr)
+
+	if err := r.storage.MarkOutboxFailed(ctx, entry.ID, sendErr.Error(), retryAt); err != nil {
+		r.logger.Errorf("Failed to record outbox entry %d failure: %v", entry.ID, err)
+		return err
+	}
+	return nil
+}
+
+// relayAsync отмечает записи отправленными и ставит сообщения в очередь producer.
+// Ошибки доставки обрабатывает requeue
+func (r *Relay) relayAsync(ctx context.Context, ids []int64, messages []kafka.LargeTransferMessage) error {
+	if err := r.storage.MarkOutboxSent(ctx, ids); err != nil {
+		r.logger.Errorf("Failed to mark outbox entries as sent: %v", err)
+		return err
+	}
+
+	if err := r.producer.SendLargeTransfers(ctx, messages); err != nil {
+		r.logger.Warnf("Failed to queue %d outbox entries: %v", len(messages), err)
+		r.requeue(messages, err)
+		return err
+	}
+
+	r.logger.Debugf("Queued %d outbox entries", len(messages))
+	return nil
+}
+
+// requeue возвращает недоставленные сообщения в outbox для повторной отправки
+// через RetryInterval
+func (r *Relay) requeue(messages []kafka.LargeTransferMessage, deliveryErr error) {
+	if len(messages) == 0 {
+		return
+	}
+
+	transactionIDs := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		transactionIDs = append(transactionIDs, message.TransactionID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	retryAt := time.Now().Add(r.cfg.RetryInterval)
+	if err := r.storage.RequeueOutbox(ctx, transactionIDs, deliveryErr.Error(), retryAt); err != nil {
+		r.logger.Errorf("Failed to requeue %d undelivered messages: %v", len(messages), err)
+	}
+}
+
+// newLargeTransferMessage формирует сообщение Kafka из транзакции.
+// Время события - момент проведения транзакции, а не отправки
+func newLargeTransferMessage(tx *storages.Transaction) kafka.LargeTransferMessage {
+	timestamp := tx.CreatedAt
+	if tx.CompletedAt != nil {
+		timestamp = *tx.CompletedAt
+	}
+
+	return kafka.LargeTransferMessage{
+		EventID:       kafka.EventIDFromTransaction(tx.ID),
+		TransactionID: tx.ID,
+		UserID:        tx.UserID,
+		Type:          tx.Type,
+		FromCurrency:  tx.FromCurrency,
+		ToCurrency:    tx.ToCurrency,
+		Amount:        tx.FromAmount,
+		Timestamp:     timestamp,
+
+		ConvertedAmount:   tx.ToAmount,
+		ConvertedCurrency: tx.ToCurrency,
+	}
+}
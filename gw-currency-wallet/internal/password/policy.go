@@ -0,0 +1,169 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// MaxLength максимальная длина пароля в байтах: bcrypt не учитывает байты сверх 72
+const MaxLength = 72
+
+// DefaultMinLength минимальная длина пароля по умолчанию
+const DefaultMinLength = 8
+
+// MaxScore максимальная оценка стойкости zxcvbn
+const MaxScore = 4
+
+// ErrWeak пароль не соответствует политике
+var ErrWeak = errors.New("password does not meet the policy")
+
+// Config требования к паролям
+type Config struct {
+	// MinLength минимальная длина в символах
+	MinLength int
+	// RequireLower, RequireUpper, RequireDigit, RequireSymbol требуют хотя бы один
+	// символ класса: строчную и заглавную букву, цифру, прочий символ
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Banned запрещенные пароли, сравниваются без учета регистра
+	Banned []string
+	// MinScore минимальная оценка zxcvbn от 0 до 4, 0 - без оценки
+	MinScore int
+}
+
+// Policy проверяет пароли при регистрации и смене пароля
+type Policy struct {
+	cfg    Config
+	banned map[string]struct{}
+}
+
+// PolicyError пароль нарушает требования политики; Violations - все нарушения
+type PolicyError struct {
+	Violations []string `json:"violations"`
+}
+
+// Error реализует интерфейс error
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrWeak, strings.Join(e.Violations, "; "))
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrWeak)
+func (e *PolicyError) Unwrap() error {
+	return ErrWeak
+}
+
+// DefaultPolicy политика по умолчанию: только минимальная длина
+func DefaultPolicy() *Policy {
+	policy, _ := NewPolicy(Config{MinLength: DefaultMinLength})
+	return policy
+}
+
+// NewPolicy создает политику паролей
+func NewPolicy(cfg Config) (*Policy, error) {
+	if cfg.MinLength < 1 || cfg.MinLength > MaxLength {
+		return nil, fmt.Errorf("min length must be between 1 and %d", MaxLength)
+	}
+	if cfg.MinScore < 0 || cfg.MinScore > MaxScore {
+		return nil, fmt.Errorf("min score must be between 0 and %d", MaxScore)
+	}
+
+	policy := &Policy{cfg: cfg, banned: make(map[string]struct{}, len(cfg.Banned))}
+	for _, banned := range cfg.Banned {
+		if banned = strings.TrimSpace(banned); banned != "" {
+			policy.banned[strings.ToLower(banned)] = struct{}{}
+		}
+	}
+	return policy, nil
+}
+
+// LoadBannedList читает запрещенные пароли из файла: по одному в строке,
+// пустые строки и строки с # пропускаются
+func LoadBannedList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open banned password list: %w", err)
+	}
+	defer file.Close()
+
+	var passwords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read banned password list: %w", err)
+	}
+	return passwords, nil
+}
+
+// BannedCount число запрещенных паролей
+func (p *Policy) BannedCount() int {
+	return len(p.banned)
+}
+
+// Validate проверяет пароль и возвращает *PolicyError со всеми нарушениями.
+// userInputs (имя пользователя, email) понижают оценку zxcvbn паролей, которые их содержат
+func (p *Policy) Validate(password string, userInputs ...string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.cfg.MinLength))
+	}
+	if len(password) > MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.cfg.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		violations = append(violations, "is too common")
+	}
+
+	if p.cfg.MinScore > 0 && len(password) <= MaxLength {
+		if score := zxcvbn.PasswordStrength(password, userInputs).Score; score < p.cfg.MinScore {
+			violations = append(violations, fmt.Sprintf("is too easy to guess: strength %d of required %d", score, p.cfg.MinScore))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Package polling содержит общий цикл фоновых обработчиков кошелька, которые
+// периодически выбирают из БД записи с наступившим временем (регулярные операции,
+// доставки вебхуков, outbox), и расчет паузы перед повтором неудачной записи
+package polling
+
+import (
+	"context"
+	"time"
+)
+
+// MaxRetryInterval ограничение паузы между повторами
+const MaxRetryInterval = 24 * time.Hour
+
+// RunDueFunc обрабатывает одну порцию записей, время которых наступило к now,
+// и возвращает их количество
+type RunDueFunc func(ctx context.Context, now time.Time) (int, error)
+
+// Run вызывает runDue каждые interval до отмены ctx. Если порция заполнена
+// (batchSize записей), следующая берется сразу, не дожидаясь тика
+func Run(ctx context.Context, interval time.Duration, batchSize int, runDue RunDueFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				processed, err := runDue(ctx, time.Now())
+				if err != nil || processed < batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Backoff возвращает паузу перед повтором после attempt неудачных попыток:
+// base удваивается с каждой попыткой, но не превышает MaxRetryInterval
+func Backoff(base time.Duration, attempt int) time.Duration {
+	interval := base
+	for i := 1; i < attempt && interval < MaxRetryInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, MaxRetryInterval)
+}
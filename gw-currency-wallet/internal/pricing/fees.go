@@ -0,0 +1,102 @@
+package pricing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// AnyCurrency правило комиссии для всех валют операции
+const AnyCurrency = "*"
+
+// FeeRule комиссия за операцию в валюте списания
+type FeeRule struct {
+	Operation string  // withdraw, exchange
+	Currency  string  // код валюты или AnyCurrency
+	Percent   float64 // доля от суммы операции
+	Fixed     float64 // фиксированная сумма в валюте операции
+}
+
+// FeeSchedule набор правил комиссий. Правило для конкретной валюты
+// имеет приоритет над правилом для всех валют
+type FeeSchedule struct {
+	rules map[string]FeeRule
+}
+
+// NewFeeSchedule создает набор правил комиссий
+func NewFeeSchedule(rules []FeeRule) *FeeSchedule {
+	schedule := &FeeSchedule{rules: make(map[string]FeeRule, len(rules))}
+	for _, rule := range rules {
+		schedule.rules[feeKey(rule.Operation, rule.Currency)] = rule
+	}
+	return schedule
+}
+
+// ParseFeeRules разбирает правила вида "operation:currency:value" через запятую.
+// value - процент ("1%") или фиксированная сумма ("0.5"), например
+// "withdraw:*:1%,withdraw:USD:2,exchange:*:0.5%"
+func ParseFeeRules(value string) ([]FeeRule, error) {
+	var rules []FeeRule
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid fee rule %q: expected operation:currency:value", item)
+		}
+
+		rule := FeeRule{
+			Operation: strings.TrimSpace(parts[0]),
+			Currency:  strings.ToUpper(strings.TrimSpace(parts[1])),
+		}
+
+		switch rule.Operation {
+		case storages.TransactionTypeWithdraw, storages.TransactionTypeExchange:
+		default:
+			return nil, fmt.Errorf("invalid fee rule %q: unsupported operation %s", item, rule.Operation)
+		}
+
+		amount := strings.TrimSpace(parts[2])
+		isPercent := strings.HasSuffix(amount, "%")
+		number, err := strconv.ParseFloat(strings.TrimSuffix(amount, "%"), 64)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid fee rule %q: invalid value %s", item, amount)
+		}
+
+		if isPercent {
+			if number >= 100 {
+				return nil, fmt.Errorf("invalid fee rule %q: percent must be less than 100", item)
+			}
+			rule.Percent = number / 100
+		} else {
+			rule.Fixed = number
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Calculate возвращает комиссию за операцию на сумму amount в валюте currency
+func (s *FeeSchedule) Calculate(operation, currency string, amount float64) float64 {
+	rule, ok := s.rules[feeKey(operation, currency)]
+	if !ok {
+		rule, ok = s.rules[feeKey(operation, AnyCurrency)]
+	}
+	if !ok {
+		return 0
+	}
+
+	return amount*rule.Percent + rule.Fixed
+}
+
+// feeKey ключ правила комиссии
+func feeKey(operation, currency string) string {
+	return operation + ":" + currency
+}
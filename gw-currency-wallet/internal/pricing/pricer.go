@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"fmt"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// Pricer применяет наценку к курсу exchanger в зависимости от источника операции
+type Pricer struct {
+	margins map[string]float64
+}
+
+// NewPricer создает Pricer с наценками по источникам операций (доля от курса).
+// Для источников без наценки курс exchanger используется без изменений
+func NewPricer(margins map[string]float64) *Pricer {
+	p := &Pricer{margins: make(map[string]float64, len(margins))}
+	for source, margin := range margins {
+		p.margins[source] = margin
+	}
+	return p
+}
+
+// Quote рассчитывает курс для клиента по курсу exchanger
+func (p *Pricer) Quote(source string, marketRate float64) (storages.ExchangeQuote, error) {
+	switch source {
+	case storages.ExchangeSourceAPI, storages.ExchangeSourceScheduled, storages.ExchangeSourceAdmin:
+	default:
+		return storages.ExchangeQuote{}, fmt.Errorf("unknown exchange source: %s", source)
+	}
+
+	margin := p.margins[source]
+	return storages.ExchangeQuote{
+		Source:     source,
+		MarketRate: marketRate,
+		Margin:     margin,
+		Rate:       marketRate * (1 - margin),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule параметры token bucket: Rate токенов в секунду, не больше Burst в запасе.
+// Rate <= 0 отключает ограничение
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled сообщает, что правило ограничивает запросы
+func (r Rule) Enabled() bool {
+	return r.Rate > 0 && r.Burst > 0
+}
+
+// refillTime время, за которое пустой bucket заполняется полностью
+func (r Rule) refillTime() time.Duration {
+	return time.Duration(float64(r.Burst) / r.Rate * float64(time.Second))
+}
+
+// Result результат проверки запроса
+type Result struct {
+	Allowed bool
+	// Remaining токенов осталось после запроса
+	Remaining int
+	// RetryAfter через сколько появится токен для отклоненного запроса
+	RetryAfter time.Duration
+}
+
+// Limiter хранилище token bucket по ключам (IP, пользователь)
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// bucket состояние token bucket одного ключа
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rule    Rule
+}
+
+// sweepInterval как часто из памяти удаляются заполненные bucket
+const sweepInterval = time.Minute
+
+// MemoryLimiter token bucket в памяти процесса. Подходит для одного экземпляра
+// кошелька: при нескольких экземплярах каждый считает запросы отдельно
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter создает limiter в памяти
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow списывает токен ключа key, если он есть
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if !rule.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), updated: now, rule: rule}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+		b.updated = now
+	}
+	b.rule = rule
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+	}
+
+	retryAfter := time.Duration((1 - b.tokens) / rule.Rate * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: retryAfter}, nil
+}
+
+// sweep удаляет bucket, которые успели заполниться: новый bucket для
+// ключа создается полным, поэтому результат проверок не меняется
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.rule.refillTime() {
+			delete(l.buckets, key)
+		}
+	}
+}
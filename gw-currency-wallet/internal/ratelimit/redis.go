@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript атомарно пополняет bucket по прошедшему времени и списывает
+// токен. Время берется из Redis, чтобы экземпляры с разными часами считали одинаково.
+// Возвращает {allowed, remaining, retry_after_ms}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// RedisConfig параметры подключения к Redis
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix префикс ключей bucket, чтобы сервисы не пересекались в одной базе
+	Prefix string
+}
+
+// RedisLimiter token bucket в Redis: экземпляры кошелька делят общий лимит
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter подключается к Redis
+func NewRedisLimiter(cfg RedisConfig) *RedisLimiter {
+	return &RedisLimiter{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		prefix: cfg.Prefix,
+	}
+}
+
+// Allow списывает токен ключа key, если он есть
+func (l *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if !rule.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key}, rule.Rate, rule.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// Ping проверяет доступность Redis
+func (l *RedisLimiter) Ping(ctx context.Context) error {
+	return l.client.Ping(ctx).Err()
+}
+
+// Close закрывает соединения с Redis
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// approvalBatchSize число выводов, подтверждаемых за один проход
+const approvalBatchSize = 100
+
+// ApproveFunc подтверждает до limit выводов, срок автоматического подтверждения
+// которых наступил к now, и возвращает их число (см. WalletService.AutoApproveWithdrawals)
+type ApproveFunc func(ctx context.Context, now time.Time, limit int) (int, error)
+
+// ApprovalWorker периодически подтверждает ожидающие выводы, которые никто
+// не отменил и не отклонил за отведенное время
+type ApprovalWorker struct {
+	approve  ApproveFunc
+	interval time.Duration
+	logger   *logrus.Logger
+}
+
+// NewApprovalWorker создает обработчик автоматического подтверждения выводов
+func NewApprovalWorker(approve ApproveFunc, interval time.Duration, logger *logrus.Logger) *ApprovalWorker {
+	return &ApprovalWorker{
+		approve:  approve,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run подтверждает выводы до отмены контекста
+func (w *ApprovalWorker) Run(ctx context.Context) {
+	w.logger.Infof("Withdrawal auto approval started (interval: %v)", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Withdrawal auto approval stopped")
+			return
+		case <-ticker.C:
+			for {
+				approved, err := w.approve(ctx, time.Now(), approvalBatchSize)
+				if err != nil {
+					w.logger.Errorf("Withdrawal auto approval failed: %v", err)
+				}
+				if approved > 0 {
+					w.logger.Infof("Auto approved %d withdrawals", approved)
+				}
+				if err != nil || approved < approvalBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
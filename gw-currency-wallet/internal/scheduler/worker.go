@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/polling"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Config параметры выполнения регулярных операций
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts число попыток запуска, после которого он пропускается
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором запуска (см. polling.Backoff)
+	RetryInterval time.Duration
+}
+
+// ExecuteFunc выполняет наступивший запуск регулярной операции (см. WalletService.ExecuteSchedule)
+type ExecuteFunc func(ctx context.Context, schedule storages.Schedule) error
+
+// Worker периодически выбирает регулярные операции, время которых наступило,
+// и выполняет их. Неудачный запуск повторяется с растущей паузой; после
+// MaxAttempts попыток он пропускается, и операция ждет следующего запуска
+// по расписанию. Ошибка сохраняется в last_error
+type Worker struct {
+	storage storages.Storage
+	execute ExecuteFunc
+	cfg     Config
+	logger  *logrus.Logger
+}
+
+// NewWorker создает обработчик регулярных операций
+func NewWorker(storage storages.Storage, execute ExecuteFunc, cfg Config, logger *logrus.Logger) *Worker {
+	return &Worker{
+		storage: storage,
+		execute: execute,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Run выполняет наступившие операции до отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Infof("Scheduler started (interval: %v, batch size: %d, max attempts: %d)",
+		w.cfg.PollInterval, w.cfg.BatchSize, w.cfg.MaxAttempts)
+	polling.Run(ctx, w.cfg.PollInterval, w.cfg.BatchSize, w.RunDue)
+	w.logger.Info("Scheduler stopped")
+}
+
+// RunDue выполняет одну порцию операций, время которых наступило к now,
+// и возвращает их количество
+func (w *Worker) RunDue(ctx context.Context, now time.Time) (int, error) {
+	schedules, err := w.storage.ListDueSchedules(ctx, now, w.cfg.BatchSize)
+	if err != nil {
+		w.logger.Errorf("Failed to fetch due schedules: %v", err)
+		return 0, err
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if err := w.execute(ctx, schedule); err != nil {
+			w.fail(ctx, schedule, now, err)
+		}
+	}
+
+	return len(schedules), nil
+}
+
+// fail планирует повтор запуска или пропускает его после MaxAttempts попыток
+func (w *Worker) fail(ctx context.Context, schedule storages.Schedule, now time.Time, runErr error) {
+	attempt := schedule.Attempts + 1
+	if attempt >= w.cfg.MaxAttempts {
+		next := schedule.NextOccurrence(now)
+		w.logger.Warnf("Schedule %d run skipped after %d attempts, next run at %s: %v",
+			schedule.ID, attempt, next.Format(time.RFC3339), runErr)
+		if _, err := w.storage.CompleteScheduleRun(ctx, schedule.ID, schedule.RunCount, next, runErr.Error()); err != nil {
+			w.logger.Errorf("Failed to skip schedule %d run: %v", schedule.ID, err)
+		}
+		return
+	}
+
+	retryAt := now.Add(polling.Backoff(w.cfg.RetryInterval, attempt))
+	w.logger.Warnf("Schedule %d attempt %d failed, retrying at %s: %v",
+		schedule.ID, attempt, retryAt.Format(time.RFC3339), runErr)
+	if err := w.storage.RetryScheduleRun(ctx, schedule.ID, schedule.RunCount, retryAt, runErr.Error()); err != nil {
+		w.logger.Errorf("Failed to record schedule %d failure: %v", schedule.ID, err)
+	}
+}
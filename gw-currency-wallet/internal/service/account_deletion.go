@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Обезличенные имя и email удаленных пользователей. Префикс и домен
+// зарезервированы и не принимаются при регистрации
+const (
+	DeletedUsernamePrefix = "deleted-"
+	DeletedEmailDomain    = "deleted.invalid"
+)
+
+// accountDeletionReason причина закрытия учетной записи, удаленной пользователем
+const accountDeletionReason = "account deleted by user"
+
+// DeleteAccount удаляет учетную запись по запросу пользователя после проверки
+// пароля: закрывает ее, заменяет имя и email хешами, отзывает ключи API
+// и отключает регулярные операции. Транзакции и журнал сохраняются для аудита.
+// Пока на балансах или в удержании есть средства, возвращает ErrBalanceNotEmpty
+func (s *WalletService) DeleteAccount(ctx context.Context, userID int64, password string) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		s.logger.Warnf("Rejected account deletion of user %d: invalid password", userID)
+		return ErrInvalidCredentials
+	}
+
+	err = s.storage.DeleteUser(ctx, userID, storages.UserDeletion{
+		Username:  DeletedUsernamePrefix + anonymize(user.Username)[:32],
+		Email:     anonymize(strings.ToLower(user.Email)) + "@" + DeletedEmailDomain,
+		Reason:    accountDeletionReason,
+		DeletedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, storages.ErrBalanceNotEmpty) {
+		return fmt.Errorf("%w, withdraw or exchange the remaining funds first", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	s.logger.Warnf("User %d deleted the account", userID)
+
+	// Новые операции уже запрещены статусом closed
+	if err := s.deactivateSchedules(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.PublishUserLifecycle(ctx, userID, kafka.UserEventDeleted, accountDeletionReason); err != nil {
+		s.logger.Errorf("Failed to publish deletion of user %d: %v", userID, err)
+	}
+	return nil
+}
+
+// reservedIdentity сообщает, что имя или email совпадают с обезличенными
+// значениями удаленных пользователей
+func reservedIdentity(username, email string) bool {
+	return strings.HasPrefix(strings.ToLower(username), DeletedUsernamePrefix) ||
+		strings.HasSuffix(strings.ToLower(email), "@"+DeletedEmailDomain)
+}
+
+// anonymize возвращает SHA-256 значения в hex
+func anonymize(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Ограничения истории статусов учетной записи
+const (
+	DefaultStatusHistoryLimit = 20
+	MaxStatusHistoryLimit     = 100
+)
+
+// closeBatchSize число ожидающих выводов, отменяемых за один запрос при закрытии
+const closeBatchSize = 100
+
+// ChangeUserStatus изменяет статус учетной записи по решению администратора
+// adminID. until допускается только для заморозки (nil - до снятия). Закрытие
+// отменяет ожидающие выводы и отключает регулярные операции пользователя
+func (s *WalletService) ChangeUserStatus(ctx context.Context, userID int64, status, reason string, until *time.Time, adminID int64) (*storages.User, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidArgument)
+	}
+	if until != nil && status != storages.AccountStatusFrozen {
+		return nil, fmt.Errorf("%w: until is supported only for status %s", ErrInvalidArgument, storages.AccountStatusFrozen)
+	}
+	if until != nil && !until.After(time.Now()) {
+		return nil, fmt.Errorf("%w: until must be in the future", ErrInvalidArgument)
+	}
+
+	// Удаленная пользователем учетная запись обезличена и не открывается снова
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: account is deleted by the user", ErrAccountClosed)
+	}
+
+	switch status {
+	case storages.AccountStatusFrozen:
+		if until != nil {
+			utc := until.UTC()
+			until = &utc
+		}
+		if _, err := s.FreezeUser(ctx, userID, until, reason, time.Now().UTC(), adminID); err != nil {
+			return nil, err
+		}
+		return s.GetUser(ctx, userID)
+	case storages.AccountStatusActive:
+		return s.activateUser(ctx, userID, reason, adminID)
+	case storages.AccountStatusClosed:
+		return s.closeUser(ctx, userID, reason, adminID)
+	default:
+		return nil, fmt.Errorf("%w: unsupported account status: %s", ErrInvalidArgument, status)
+	}
+}
+
+// FreezeUser замораживает выводы и обмены пользователя до until (nil - до снятия
+// заморозки администратором). Действующая заморозка не сокращается, закрытая
+// учетная запись не замораживается (ErrAccountClosed). changedAt - время решения
+// о заморозке: если статус уже менялся позже, например заморозку сняли, заморозка
+// не применяется и возвращается false. changedBy - ID администратора, 0 - автоматически
+func (s *WalletService) FreezeUser(ctx context.Context, userID int64, until *time.Time, reason string, changedAt time.Time, changedBy int64) (bool, error) {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status == storages.AccountStatusClosed {
+		return false, ErrAccountClosed
+	}
+
+	if user.Frozen(time.Now().UTC()) && until != nil {
+		if user.FrozenUntil == nil {
+			until = nil
+		} else if user.FrozenUntil.After(*until) {
+			until = user.FrozenUntil
+		}
+	}
+
+	applied, err := s.storage.SetUserStatus(ctx, userID, storages.UserStatusChange{
+		Status:      storages.AccountStatusFrozen,
+		FrozenUntil: until,
+		Reason:      reason,
+		ChangedAt:   changedAt.UTC(),
+		ChangedBy:   changedBy,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to freeze user: %w", err)
+	}
+	if !applied {
+		s.logger.Infof("Skipping stale freeze of user %d: status changed after %s", userID, changedAt.Format(time.RFC3339))
+		return false, nil
+	}
+
+	if until != nil {
+		// Временную заморозку запрашивает сервис уведомлений, он уже знает о ней
+		s.logger.Warnf("User %d is frozen until %s: %s", userID, until.Format(time.RFC3339), reason)
+		return true, nil
+	}
+
+	s.logger.Warnf("User %d is frozen: %s", userID, reason)
+	if err := s.PublishUserLifecycle(ctx, userID, kafka.UserEventFrozen, reason); err != nil {
+		s.logger.Errorf("Failed to publish freeze of user %d: %v", userID, err)
+	}
+	return true, nil
+}
+
+// UnfreezeUser снимает заморозку пользователя. Управляющие сообщения о заморозке,
+// созданные раньше снятия, после него не применяются. Закрытая учетная запись
+// открывается только через ChangeUserStatus
+func (s *WalletService) UnfreezeUser(ctx context.Context, userID int64, reason string, changedBy int64) (*storages.User, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == storages.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+
+	return s.activateUser(ctx, userID, reason, changedBy)
+}
+
+// activateUser переводит учетную запись в статус active
+func (s *WalletService) activateUser(ctx context.Context, userID int64, reason string, changedBy int64) (*storages.User, error) {
+	_, err := s.storage.SetUserStatus(ctx, userID, storages.UserStatusChange{
+		Status:    storages.AccountStatusActive,
+		Reason:    reason,
+		ChangedAt: time.Now().UTC(),
+		ChangedBy: changedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate user: %w", err)
+	}
+
+	s.logger.Infof("User %d is active: %s", userID, reason)
+	if err := s.PublishUserLifecycle(ctx, userID, kafka.UserEventActivated, reason); err != nil {
+		s.logger.Errorf("Failed to publish activation of user %d: %v", userID, err)
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// closeUser закрывает учетную запись: статус closed запрещает все операции
+// с деньгами, ожидающие выводы отменяются, регулярные операции отключаются
+func (s *WalletService) closeUser(ctx context.Context, userID int64, reason string, changedBy int64) (*storages.User, error) {
+	_, err := s.storage.SetUserStatus(ctx, userID, storages.UserStatusChange{
+		Status:    storages.AccountStatusClosed,
+		Reason:    reason,
+		ChangedAt: time.Now().UTC(),
+		ChangedBy: changedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to close user: %w", err)
+	}
+	s.logger.Warnf("User %d is closed: %s", userID, reason)
+
+	// Новые операции уже запрещены статусом, поэтому отмена не гоняется с ними
+	if err := s.cancelPendingWithdrawals(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.deactivateSchedules(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.PublishUserLifecycle(ctx, userID, kafka.UserEventDeleted, reason); err != nil {
+		s.logger.Errorf("Failed to publish closing of user %d: %v", userID, err)
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// cancelPendingWithdrawals отменяет все ожидающие выводы пользователя. Выводы,
+// параллельно обработанные администратором, пропускаются
+func (s *WalletService) cancelPendingWithdrawals(ctx context.Context, userID int64) error {
+	for {
+		withdrawals, err := s.storage.ListPendingWithdrawals(ctx, storages.PendingWithdrawalFilter{
+			UserID: userID,
+			Limit:  closeBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list pending withdrawals: %w", err)
+		}
+
+		cancelled := 0
+		for _, withdrawal := range withdrawals {
+			_, err := s.storage.CancelWithdraw(ctx, withdrawal.ID, storages.TransactionStatusCancelled)
+			if errors.Is(err, storages.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to cancel withdrawal %d: %w", withdrawal.ID, err)
+			}
+			cancelled++
+			s.logger.Infof("Cancelled pending withdrawal %d of closed user %d", withdrawal.ID, userID)
+		}
+
+		if cancelled == 0 {
+			return nil
+		}
+	}
+}
+
+// deactivateSchedules отключает регулярные операции пользователя
+func (s *WalletService) deactivateSchedules(ctx context.Context, userID int64) error {
+	schedules, err := s.storage.ListSchedules(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	for i := range schedules {
+		if !schedules[i].Active {
+			continue
+		}
+		schedules[i].Active = false
+		if err := s.storage.UpdateSchedule(ctx, &schedules[i]); err != nil {
+			return fmt.Errorf("failed to deactivate schedule %d: %w", schedules[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// GetAccountStatusHistory возвращает до limit последних изменений статуса
+// пользователя, от новых к старым
+func (s *WalletService) GetAccountStatusHistory(ctx context.Context, userID int64, limit int) ([]storages.AccountStatusEvent, error) {
+	if limit < 1 || limit > MaxStatusHistoryLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, MaxStatusHistoryLimit)
+	}
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.storage.GetAccountStatusHistory(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account status history: %w", err)
+	}
+	return events, nil
+}
+
+// ApplyControlMessage применяет управляющее сообщение сервиса уведомлений.
+// Сообщения для неизвестных и закрытых пользователей пропускаются
+func (s *WalletService) ApplyControlMessage(ctx context.Context, message kafka.ControlMessage) error {
+	switch message.Action {
+	case kafka.ControlActionFreeze:
+		until := message.Until.UTC()
+		reason := fmt.Sprintf("%s: %s", message.Rule, message.Reason)
+		_, err := s.FreezeUser(ctx, message.UserID, &until, reason, message.Timestamp, 0)
+		if errors.Is(err, storages.ErrNotFound) || errors.Is(err, ErrAccountClosed) {
+			s.logger.Warnf("Skipping control message %s for unknown or closed user %d", message.EventID, message.UserID)
+			return nil
+		}
+		return err
+	default:
+		s.logger.Warnf("Skipping control message %s with unknown action %q", message.EventID, message.Action)
+		return nil
+	}
+}
+
+// checkNotFrozen возвращает ErrAccountClosed для закрытой учетной записи и
+// ErrAccountFrozen, если выводы и обмены пользователя заморожены
+func (s *WalletService) checkNotFrozen(ctx context.Context, userID int64) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.Status == storages.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	if !user.Frozen(time.Now().UTC()) {
+		return nil
+	}
+	if user.FrozenUntil != nil {
+		return fmt.Errorf("%w until %s", ErrAccountFrozen, user.FrozenUntil.Format(time.RFC3339))
+	}
+	return ErrAccountFrozen
+}
+
+// checkNotClosed возвращает ErrAccountClosed для закрытой учетной записи.
+// Пополнения замороженного пользователя разрешены: заморозка удерживает
+// средства на счете, а не запрещает их зачисление
+func (s *WalletService) checkNotClosed(ctx context.Context, userID int64) error {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.Status == storages.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// MaxAPIKeysPerUser максимальное число действующих ключей API пользователя
+const MaxAPIKeysPerUser = 10
+
+// apiKeyPrefix начало всех ключей API, по нему ключ легко найти в конфигурации и логах
+const apiKeyPrefix = "gwk_"
+
+// apiKeyVisibleChars число символов ключа, сохраняемых открыто для отличия ключей
+const apiKeyVisibleChars = 12
+
+// CreateAPIKey создает ключ API пользователя со scopes. Ключ возвращается только
+// здесь: хранится его SHA-256. Лимит запросов ключа задает администратор
+func (s *WalletService) CreateAPIKey(ctx context.Context, userID int64, name string, scopes []string) (*storages.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: API key name is required", ErrInvalidArgument)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: API key scopes are required", ErrInvalidArgument)
+	}
+
+	existing, err := s.storage.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list API keys: %w", err)
+	}
+	if len(existing) >= MaxAPIKeysPerUser {
+		return nil, "", fmt.Errorf("%w: at most %d API keys per user", ErrInvalidArgument, MaxAPIKeysPerUser)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(buf)
+
+	key := &storages.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  secret[:apiKeyVisibleChars],
+		KeyHash: hashAPIKey(secret),
+		Scopes:  scopes,
+	}
+	if err := s.storage.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, secret, nil
+}
+
+// ListAPIKeys возвращает действующие ключи API пользователя
+func (s *WalletService) ListAPIKeys(ctx context.Context, userID int64) ([]storages.APIKey, error) {
+	keys, err := s.storage.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ API пользователя; запросы с ним сразу отклоняются
+func (s *WalletService) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	if err := s.storage.RevokeAPIKey(ctx, userID, keyID); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// SetAPIKeyRateLimit задает ключу API собственный лимит: rate запросов в секунду,
+// не больше burst подряд. rate 0 - ключ расходует лимит запросов пользователя
+func (s *WalletService) SetAPIKeyRateLimit(ctx context.Context, userID, keyID int64, rate float64, burst int) error {
+	if rate < 0 || burst < 0 {
+		return fmt.Errorf("%w: rate limit must not be negative", ErrInvalidArgument)
+	}
+	if rate > 0 && burst < 1 {
+		return fmt.Errorf("%w: burst must be at least 1", ErrInvalidArgument)
+	}
+	if rate == 0 {
+		burst = 0
+	}
+
+	if err := s.storage.SetAPIKeyRateLimit(ctx, userID, keyID, rate, burst); err != nil {
+		return fmt.Errorf("failed to set API key rate limit: %w", err)
+	}
+
+	s.logger.Infof("API key %d of user %d rate limit set to %.2f rps, burst %d", keyID, userID, rate, burst)
+	return nil
+}
+
+// AuthenticateAPIKey возвращает действующий ключ API и его владельца.
+// Неизвестный или отозванный ключ - ErrNotFound, ключ закрытого пользователя - ErrAccountClosed
+func (s *WalletService) AuthenticateAPIKey(ctx context.Context, secret string) (*storages.APIKey, *storages.User, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, nil, fmt.Errorf("API key %w", ErrNotFound)
+	}
+
+	key, err := s.storage.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	user, err := s.storage.GetUserByID(ctx, key.UserID)
+	if errors.Is(err, storages.ErrNotFound) || (err == nil && user == nil) {
+		return nil, nil, fmt.Errorf("API key owner %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status == storages.AccountStatusClosed {
+		return nil, nil, ErrAccountClosed
+	}
+
+	return key, user, nil
+}
+
+// hashAPIKey возвращает SHA-256 ключа. Ключ случайный и длинный, поэтому
+// медленный хеш паролей не нужен и поиск ключа не замедляет каждый запрос
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
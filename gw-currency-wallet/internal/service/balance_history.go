@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gw-common/errcodes"
+	"gw-currency-wallet/internal/grpc"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Ограничения периода истории стоимости балансов, в днях
+const (
+	DefaultBalanceHistoryDays = 30
+	MaxBalanceHistoryDays     = 365
+)
+
+// amountPrecision точность сумм в БД (NUMERIC(20, 8)): восстановленные балансы
+// и стоимость округляются до нее, чтобы не показывать погрешность вычитаний
+const amountPrecision = 1e8
+
+// BalanceHistoryPoint балансы пользователя на конец дня и их стоимость в базовой валюте
+type BalanceHistoryPoint struct {
+	Date     string                `json:"date"`
+	Value    float64               `json:"value"`
+	Balances storages.UserBalances `json:"balances"`
+	// Unpriced валюты с ненулевым балансом без курса к базовой валюте на этот день,
+	// их балансы не входят в Value
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// BalanceHistory дневной ряд стоимости балансов пользователя
+type BalanceHistory struct {
+	BaseCurrency string                `json:"base_currency"`
+	Points       []BalanceHistoryPoint `json:"points"`
+}
+
+// GetBalanceHistory возвращает стоимость балансов пользователя в baseCurrency на конец
+// каждого из последних days дней (UTC), включая текущий. Балансы прошлых дней
+// восстанавливаются из журнала ledger_entries, стоимость считается по истории курсов
+// exchanger без наценки: курс валюты к базовой или обратный к курсу базовой к валюте
+func (s *WalletService) GetBalanceHistory(ctx context.Context, userID int64, baseCurrency string, days int) (*BalanceHistory, error) {
+	if days < 1 || days > MaxBalanceHistoryDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidArgument, MaxBalanceHistoryDays)
+	}
+
+	baseCurrency, err := s.validateCurrency(ctx, baseCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.exchangerClient == nil {
+		return nil, ErrExchangerUnavailable
+	}
+
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, 1-days)
+
+	balances, err := s.storage.GetAllBalances(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	entries, err := s.storage.GetLedgerEntriesSince(ctx, userID, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+
+	daily := dailyBalances(balances, entries, start, days)
+
+	// Курсы к базовой валюте запрашиваются для валют, баланс которых был ненулевым
+	held := make(map[string]bool)
+	for _, dayBalances := range daily {
+		for currency, amount := range dayBalances {
+			if amount != 0 && currency != baseCurrency {
+				held[currency] = true
+			}
+		}
+	}
+
+	rates := make(map[string]map[string]float64, len(held))
+	for _, currency := range sortedCurrencies(held) {
+		rates[currency], err = s.dailyBaseRates(ctx, currency, baseCurrency, start, end)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	history := &BalanceHistory{
+		BaseCurrency: baseCurrency,
+		Points:       make([]BalanceHistoryPoint, 0, days),
+	}
+	for day, dayBalances := range daily {
+		point := BalanceHistoryPoint{
+			Date:     start.AddDate(0, 0, day).Format(grpc.RateHistoryDateLayout),
+			Balances: dayBalances,
+		}
+		for _, currency := range sortedCurrencies(dayBalances) {
+			amount := dayBalances[currency]
+			switch {
+			case currency == baseCurrency:
+				point.Value += amount
+			case amount == 0:
+			default:
+				rate, ok := rates[currency][point.Date]
+				if !ok {
+					point.Unpriced = append(point.Unpriced, currency)
+					continue
+				}
+				point.Value += amount * rate
+			}
+		}
+		point.Value = roundAmount(point.Value)
+		history.Points = append(history.Points, point)
+	}
+
+	return history, nil
+}
+
+// dailyBaseRates возвращает курс currency к base по датам. Без истории прямой пары
+// используется обратный курс base -> currency; без обеих история пуста
+func (s *WalletService) dailyBaseRates(ctx context.Context, currency, base string, start, end time.Time) (map[string]float64, error) {
+	rates := make(map[string]float64)
+
+	points, err := s.exchangerClient.GetRateHistory(ctx, currency, base, start, end)
+	if errcodes.FromError(err) == errcodes.NotFound {
+		points, err = s.exchangerClient.GetRateHistory(ctx, base, currency, start, end)
+		for i := range points {
+			if points[i].Rate > 0 {
+				points[i].Rate = 1 / points[i].Rate
+			}
+		}
+	}
+	if errcodes.FromError(err) == errcodes.NotFound {
+		s.logger.Warnf("No rate history for %s -> %s", currency, base)
+		return rates, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to get rate history %s -> %s: %w", ErrExchangerUnavailable, currency, base, err)
+	}
+
+	for _, point := range points {
+		if point.Rate > 0 {
+			rates[point.Date] = point.Rate
+		}
+	}
+	return rates, nil
+}
+
+// dailyBalances восстанавливает балансы на конец каждого из days дней начиная с start:
+// из текущих балансов вычитаются записи журнала, созданные после конца дня
+func dailyBalances(balances []storages.Balance, entries []storages.LedgerEntry, start time.Time, days int) []storages.UserBalances {
+	current := make(storages.UserBalances, len(balances))
+	for _, balance := range balances {
+		current[balance.Currency] = balance.Amount
+	}
+
+	// Записи с равным временем сохраняют порядок ID
+	entries = append([]storages.LedgerEntry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	daily := make([]storages.UserBalances, days)
+	next := len(entries) - 1
+	for day := days - 1; day >= 0; day-- {
+		dayEnd := start.AddDate(0, 0, day+1)
+		for ; next >= 0 && !entries[next].CreatedAt.Before(dayEnd); next-- {
+			current[entries[next].Currency] -= entries[next].Amount
+		}
+
+		dayBalances := make(storages.UserBalances, len(current))
+		for currency, amount := range current {
+			dayBalances[currency] = roundAmount(amount)
+		}
+		daily[day] = dayBalances
+	}
+	return daily
+}
+
+// roundAmount округляет сумму до точности хранения в БД
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*amountPrecision) / amountPrecision
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// BalanceTotalItem баланс в валюте и его стоимость в валюте итога
+type BalanceTotalItem struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Rate     float64 `json:"rate"` // курс Currency к валюте итога
+	Value    float64 `json:"value"`
+}
+
+// BalanceTotal стоимость всех балансов пользователя в одной валюте
+type BalanceTotal struct {
+	Currency  string             `json:"currency"`
+	Total     float64            `json:"total"`
+	Breakdown []BalanceTotalItem `json:"breakdown"`
+}
+
+// GetBalanceTotal пересчитывает ненулевые балансы пользователя в currency по текущим
+// курсам exchanger без наценки и возвращает итог с разбивкой по валютам. Курс валюты
+// берется из списка курсов (прямой или обратный), для пар без курса в списке, например
+// кросс-курсов, запрашивается у exchanger
+func (s *WalletService) GetBalanceTotal(ctx context.Context, userID int64, currency string) (*BalanceTotal, error) {
+	currency, err := s.validateCurrency(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.GetUserBalances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := s.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &BalanceTotal{
+		Currency:  currency,
+		Breakdown: make([]BalanceTotalItem, 0, len(balances)),
+	}
+	for _, code := range sortedCurrencies(balances) {
+		amount := balances[code]
+		if amount == 0 {
+			continue
+		}
+
+		rate, ok := baseRate(rates, code, currency)
+		if !ok {
+			if rate, err = s.fetchMidRate(ctx, code, currency); err != nil {
+				return nil, err
+			}
+		}
+
+		item := BalanceTotalItem{
+			Currency: code,
+			Amount:   amount,
+			Rate:     rate,
+			Value:    roundAmount(amount * rate),
+		}
+		total.Total += item.Value
+		total.Breakdown = append(total.Breakdown, item)
+	}
+	total.Total = roundAmount(total.Total)
+
+	return total, nil
+}
+
+// fetchMidRate запрашивает у exchanger средний курс пары, которой нет в списке курсов
+func (s *WalletService) fetchMidRate(ctx context.Context, fromCurrency, toCurrency string) (float64, error) {
+	if s.exchangerClient == nil {
+		return 0, fmt.Errorf("%w: no exchange rate for %s -> %s", ErrExchangerUnavailable, fromCurrency, toCurrency)
+	}
+
+	pairRate, err := s.fetchPairRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("%w: no exchange rate for %s -> %s: %w", ErrExchangerUnavailable, fromCurrency, toCurrency, err)
+	}
+
+	if pairRate.Rate <= 0 {
+		return 0, fmt.Errorf("%w: no exchange rate for %s -> %s", ErrExchangerUnavailable, fromCurrency, toCurrency)
+	}
+	return float64(pairRate.Rate), nil
+}
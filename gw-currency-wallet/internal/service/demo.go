@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// DemoEmailDomain домен email демо-пользователей. По нему демо-данные
+// отличаются от настоящих
+const DemoEmailDomain = "demo.invalid"
+
+// DemoPassword пароль всех демо-пользователей. Он опубликован в README,
+// поэтому демо-режим нельзя включать в production
+const DemoPassword = "demo-password"
+
+// ErrDemoNotAllowed демо-данные нельзя создать в этой БД
+var ErrDemoNotAllowed = errors.New("demo data is not allowed")
+
+// DemoOperation операция, которую демо-пользователь выполняет после регистрации
+type DemoOperation struct {
+	Type         string // deposit, withdraw, exchange
+	FromCurrency string
+	ToCurrency   string // только для exchange
+	Amount       float64
+}
+
+// DemoUser демо-пользователь и его история операций
+type DemoUser struct {
+	Username   string
+	Admin      bool
+	Operations []DemoOperation
+}
+
+// DemoUsers демо-пользователи. Имена начинаются с demo_, email - в домене DemoEmailDomain
+var DemoUsers = []DemoUser{
+	{
+		Username: "demo_alice",
+		Operations: []DemoOperation{
+			{Type: storages.TransactionTypeDeposit, FromCurrency: "USD", Amount: 5000},
+			{Type: storages.TransactionTypeDeposit, FromCurrency: "EUR", Amount: 1200},
+			{Type: storages.TransactionTypeExchange, FromCurrency: "USD", ToCurrency: "EUR", Amount: 1000},
+			{Type: storages.TransactionTypeWithdraw, FromCurrency: "EUR", Amount: 300},
+		},
+	},
+	{
+		Username: "demo_bob",
+		Operations: []DemoOperation{
+			{Type: storages.TransactionTypeDeposit, FromCurrency: "RUB", Amount: 250000},
+			{Type: storages.TransactionTypeExchange, FromCurrency: "RUB", ToCurrency: "USD", Amount: 90000},
+			{Type: storages.TransactionTypeDeposit, FromCurrency: "USD", Amount: 150},
+		},
+	},
+	{
+		Username: "demo_admin",
+		Admin:    true,
+		Operations: []DemoOperation{
+			{Type: storages.TransactionTypeDeposit, FromCurrency: "USD", Amount: 100},
+		},
+	},
+}
+
+// DemoCurrencies валюты демо-режима, используются, пока exchanger недоступен
+var DemoCurrencies = []string{"USD", "EUR", "RUB"}
+
+// DemoRates курсы демо-режима, используются, пока exchanger недоступен
+var DemoRates = map[string]float32{
+	"USD_EUR": 0.92,
+	"EUR_USD": 1.087,
+	"USD_RUB": 90,
+	"RUB_USD": 0.0111,
+	"EUR_RUB": 97.8,
+	"RUB_EUR": 0.0102,
+}
+
+// DemoEmail возвращает email демо-пользователя
+func DemoEmail(username string) string {
+	return username + "@" + DemoEmailDomain
+}
+
+// EnableDemoRates включает демо-курсы: если exchanger недоступен, курсы и список
+// валют берутся из DemoRates и DemoCurrencies. Курсы exchanger, когда он доступен,
+// важнее демо-курсов
+func (s *WalletService) EnableDemoRates() {
+	s.demoRates = true
+	s.logger.Warn("Demo rates are enabled: static rates are used while exchanger is unavailable")
+}
+
+// demoRatesFallback возвращает демо-курсы, если они включены
+func (s *WalletService) demoRatesFallback() (map[string]float32, bool) {
+	if !s.demoRates {
+		return nil, false
+	}
+
+	rates := make(map[string]float32, len(DemoRates))
+	for pair, rate := range DemoRates {
+		rates[pair] = rate
+	}
+	return rates, true
+}
+
+// SeedDemoData создает демо-пользователей с балансами и историей операций.
+// Уже созданные демо-пользователи пропускаются, поэтому повторный запуск безопасен.
+// Если в БД есть пользователи не из демо-набора, данные не создаются: демо-режим
+// не должен смешивать опубликованные пароли с настоящими аккаунтами.
+// Возвращает число созданных пользователей
+func (s *WalletService) SeedDemoData(ctx context.Context) (int, error) {
+	_, total, err := s.storage.ListUsers(ctx, "", 1, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	_, demo, err := s.storage.ListUsers(ctx, "@"+DemoEmailDomain, 1, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count demo users: %w", err)
+	}
+	if total > demo {
+		return 0, fmt.Errorf("%w: database contains %d non-demo users", ErrDemoNotAllowed, total-demo)
+	}
+
+	created := 0
+	for _, demoUser := range DemoUsers {
+		existing, err := s.storage.GetUserByUsername(ctx, demoUser.Username)
+		if err != nil && !errors.Is(err, storages.ErrNotFound) {
+			return created, fmt.Errorf("failed to check demo user %s: %w", demoUser.Username, err)
+		}
+		if existing != nil {
+			continue
+		}
+
+		// Пользователь создается вместе с историей операций или не создается совсем
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			return s.seedDemoUser(ctx, demoUser)
+		})
+		if err != nil {
+			return created, fmt.Errorf("failed to seed demo user %s: %w", demoUser.Username, err)
+		}
+		created++
+	}
+
+	s.logger.Warnf("Demo data seeded: %d users created (password %q, email domain %s)", created, DemoPassword, DemoEmailDomain)
+	return created, nil
+}
+
+// seedDemoUser регистрирует демо-пользователя и выполняет его операции
+func (s *WalletService) seedDemoUser(ctx context.Context, demoUser DemoUser) error {
+	// Опубликованный демо-пароль не проверяется политикой паролей
+	if err := s.createUser(ctx, demoUser.Username, DemoEmail(demoUser.Username), DemoPassword); err != nil {
+		return err
+	}
+
+	user, err := s.storage.GetUserByUsername(ctx, demoUser.Username)
+	if err != nil || user == nil {
+		return fmt.Errorf("failed to get created user: %w", err)
+	}
+
+	if demoUser.Admin {
+		if err := s.storage.UpdateUserRole(ctx, user.ID, storages.RoleAdmin); err != nil {
+			return fmt.Errorf("failed to grant admin role: %w", err)
+		}
+	}
+
+	for _, op := range demoUser.Operations {
+		switch op.Type {
+		case storages.TransactionTypeDeposit:
+			_, err = s.Deposit(ctx, user.ID, op.FromCurrency, op.Amount)
+		case storages.TransactionTypeWithdraw:
+			_, _, err = s.Withdraw(ctx, user.ID, op.FromCurrency, op.Amount)
+		case storages.TransactionTypeExchange:
+			_, _, _, err = s.ExchangeCurrency(ctx, user.ID, op.FromCurrency, op.ToCurrency, op.Amount, storages.ExchangeSourceAPI)
+		default:
+			err = fmt.Errorf("unknown demo operation: %s", op.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to run demo %s %.2f %s: %w", op.Type, op.Amount, op.FromCurrency, err)
+		}
+	}
+
+	return nil
+}
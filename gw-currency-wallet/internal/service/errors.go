@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+
+	"gw-common/utils"
+	"gw-currency-wallet/internal/password"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Ошибки сервисного слоя. Возвращаются обернутыми через %w,
+// проверяются через errors.Is
+var (
+	ErrNotFound             = storages.ErrNotFound
+	ErrUserExists           = errors.New("user already exists")
+	ErrInvalidCredentials   = errors.New("invalid username or password")
+	ErrWeakPassword         = password.ErrWeak
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidAmount        = errors.New("amount must be positive")
+	ErrUnsupportedCurrency  = utils.ErrUnsupportedCurrency
+	ErrSameCurrency         = errors.New("from_currency and to_currency must be different")
+	ErrInsufficientFunds    = storages.ErrInsufficientFunds
+	ErrBalanceNotEmpty      = storages.ErrBalanceNotEmpty
+	ErrLimitExceeded        = errors.New("limit exceeded")
+	ErrAccountFrozen        = errors.New("account is frozen")
+	ErrAccountClosed        = errors.New("account is closed")
+	ErrVerificationPending  = errors.New("verification request is already pending")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrExchangerUnavailable = errors.New("exchanger service is not available")
+	ErrStaleRate            = errors.New("exchange rate is stale")
+)
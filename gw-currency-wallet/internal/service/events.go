@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"gw-currency-wallet/internal/events"
+	"gw-currency-wallet/internal/storages"
+)
+
+// pendingEventsKey ключ контекста с пользователями, балансы которых изменились
+// в транзакции, открытой WithTransaction
+type pendingEventsKey struct{}
+
+type pendingEvents struct {
+	mu    sync.Mutex
+	users []int64
+}
+
+// SetEventBus включает публикацию изменений балансов и курсов в шину событий
+func (s *WalletService) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// Events возвращает шину событий или nil, если публикация не включена
+func (s *WalletService) Events() *events.Bus {
+	return s.events
+}
+
+// publishBalances сообщает подписчикам новые балансы пользователя. Внутри
+// WithTransaction событие откладывается до фиксации транзакции, а балансы
+// перечитываются после нее: откаченные изменения клиентам не отправляются
+func (s *WalletService) publishBalances(ctx context.Context, userID int64, balances storages.UserBalances) {
+	if s.events == nil {
+		return
+	}
+
+	if pending, ok := ctx.Value(pendingEventsKey{}).(*pendingEvents); ok {
+		pending.mu.Lock()
+		pending.users = append(pending.users, userID)
+		pending.mu.Unlock()
+		return
+	}
+
+	s.events.Publish(events.Event{Type: events.TypeBalance, UserID: userID, Balances: balances})
+}
+
+// publishRates сообщает всем подписчикам курсы, полученные из exchanger
+func (s *WalletService) publishRates(rates map[string]float32) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{Type: events.TypeRates, Rates: rates})
+}
+
+// flushBalances публикует отложенные события после фиксации транзакции
+func (s *WalletService) flushBalances(ctx context.Context, pending *pendingEvents) {
+	published := make(map[int64]bool, len(pending.users))
+	for _, userID := range pending.users {
+		if published[userID] {
+			continue
+		}
+		published[userID] = true
+
+		balances, err := s.GetUserBalances(ctx, userID)
+		if err != nil {
+			s.logger.Warnf("Failed to read balances for event: UserID=%d: %v", userID, err)
+			continue
+		}
+		s.events.Publish(events.Event{Type: events.TypeBalance, UserID: userID, Balances: balances})
+	}
+}
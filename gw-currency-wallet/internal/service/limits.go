@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-common/utils"
+	"gw-currency-wallet/internal/storages"
+)
+
+// LimitExceededError операция превышает лимит пользователя. Remaining - сумма,
+// которую еще можно списать за текущий период
+type LimitExceededError struct {
+	Operation string    `json:"operation"`
+	Currency  string    `json:"currency"`
+	Period    string    `json:"period"`
+	Limit     float64   `json:"limit"`
+	Used      float64   `json:"used"`
+	Remaining float64   `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+	// VerificationLevel уровень верификации, лимит которого превышен; пусто -
+	// превышен лимит, установленный пользователю администратором
+	VerificationLevel string `json:"verification_level,omitempty"`
+}
+
+// Error реализует интерфейс error
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s %s limit exceeded: %.2f of %.2f %s used, %.2f remaining",
+		e.Period, e.Operation, e.Used, e.Limit, e.Currency, e.Remaining)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrLimitExceeded)
+func (e *LimitExceededError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// GetUserLimits возвращает лимиты пользователя
+func (s *WalletService) GetUserLimits(ctx context.Context, userID int64) ([]storages.Limit, error) {
+	limits, err := s.storage.GetUserLimits(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetUserLimit создает или обновляет лимит пользователя на операцию в валюте списания
+func (s *WalletService) SetUserLimit(ctx context.Context, limit *storages.Limit) error {
+	if err := validateLimitKey(limit.Operation, limit.Period); err != nil {
+		return err
+	}
+
+	if limit.Amount < 0 {
+		return fmt.Errorf("%w: limit amount must not be negative", ErrInvalidArgument)
+	}
+
+	currency, err := s.validateCurrency(ctx, limit.Currency)
+	if err != nil {
+		return err
+	}
+	limit.Currency = currency
+
+	if err := s.storage.SetUserLimit(ctx, limit); err != nil {
+		return fmt.Errorf("failed to set limit: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserLimit удаляет лимит пользователя
+func (s *WalletService) DeleteUserLimit(ctx context.Context, userID int64, operation, currency, period string) error {
+	if err := validateLimitKey(operation, period); err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteUserLimit(ctx, userID, operation, utils.NormalizeCurrency(currency), period); err != nil {
+		return fmt.Errorf("failed to delete limit: %w", err)
+	}
+
+	return nil
+}
+
+// withinLimits выполняет списание fn в одной транзакции с проверкой лимитов.
+// Строка пользователя блокируется до фиксации, поэтому параллельные списания
+// одного пользователя проверяются по очереди и вместе не превышают лимит
+func (s *WalletService) withinLimits(ctx context.Context, userID int64, operation, currency string, amount float64, fn func(ctx context.Context) error) error {
+	return s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.storage.LockUserLimits(ctx, userID); err != nil {
+			return fmt.Errorf("failed to lock limits: %w", err)
+		}
+		if err := s.checkLimits(ctx, userID, operation, currency, amount); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+// checkLimits проверяет, что операция на сумму amount не превышает лимиты
+// пользователя и лимиты его уровня верификации за текущий день и месяц (UTC).
+// Вызывается внутри withinLimits
+func (s *WalletService) checkLimits(ctx context.Context, userID int64, operation, currency string, amount float64) error {
+	limits, err := s.storage.GetUserLimits(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get limits: %w", err)
+	}
+
+	var level string
+	if len(s.tierLimits) > 0 {
+		user, err := s.storage.GetUserByID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		level = user.VerificationLevel
+	}
+	tierLimits := s.tierLimitsFor(level, operation, currency)
+
+	now := time.Now().UTC()
+	for i, limit := range append(limits, tierLimits...) {
+		if limit.Operation != operation || limit.Currency != currency {
+			continue
+		}
+
+		start, end := limitPeriod(limit.Period, now)
+		used, err := s.storage.SumUserOperations(ctx, userID, operation, currency, start)
+		if err != nil {
+			return fmt.Errorf("failed to check limits: %w", err)
+		}
+
+		if used+amount > limit.Amount {
+			remaining := limit.Amount - used
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			s.logger.Warnf("Limit exceeded: UserID=%d, %s %s %.2f, used %.2f of %.2f %s",
+				userID, limit.Period, operation, amount, used, limit.Amount, currency)
+
+			limitErr := &LimitExceededError{
+				Operation: operation,
+				Currency:  currency,
+				Period:    limit.Period,
+				Limit:     limit.Amount,
+				Used:      used,
+				Remaining: remaining,
+				ResetsAt:  end,
+			}
+			if i >= len(limits) {
+				limitErr.VerificationLevel = level
+			}
+			return limitErr
+		}
+	}
+
+	return nil
+}
+
+// limitPeriod возвращает начало и конец периода лимита, содержащего now
+func limitPeriod(period string, now time.Time) (time.Time, time.Time) {
+	if period == storages.LimitPeriodMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// validateLimitKey проверяет операцию и период лимита
+func validateLimitKey(operation, period string) error {
+	switch operation {
+	case storages.TransactionTypeWithdraw, storages.TransactionTypeExchange:
+	default:
+		return fmt.Errorf("%w: unsupported limit operation: %s", ErrInvalidArgument, operation)
+	}
+
+	switch period {
+	case storages.LimitPeriodDaily, storages.LimitPeriodMonthly:
+	default:
+		return fmt.Errorf("%w: unsupported limit period: %s", ErrInvalidArgument, period)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gw-currency-wallet/internal/password"
+	"gw-currency-wallet/internal/storages"
+)
+
+// SetPasswordPolicy задает политику паролей для регистрации и смены пароля.
+// Пароли зарегистрированных пользователей не перепроверяются
+func (s *WalletService) SetPasswordPolicy(policy *password.Policy) {
+	s.passwordPolicy = policy
+	if policy.BannedCount() > 0 {
+		s.logger.Infof("Password policy configured: %d banned passwords", policy.BannedCount())
+	}
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего.
+// Новый пароль проверяется политикой паролей и должен отличаться от текущего.
+// Сессии пользователя, кроме currentSessionID (0 - все), отзываются
+func (s *WalletService) ChangePassword(ctx context.Context, userID, currentSessionID int64, currentPassword, newPassword string) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status == storages.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		s.logger.Warnf("Rejected password change of user %d: invalid current password", userID)
+		return ErrInvalidCredentials
+	}
+	if newPassword == currentPassword {
+		return fmt.Errorf("%w: new password must differ from the current one", ErrInvalidArgument)
+	}
+	if err := s.passwordPolicy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Errorf("Failed to hash password: %v", err)
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.storage.UpdateUserPassword(ctx, userID, string(hashedPassword)); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	revoked, err := s.storage.RevokeUserSessions(ctx, userID, currentSessionID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.logger.Infof("User %d changed the password, %d other sessions revoked", userID, revoked)
+	return nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// rebalanceMinValue минимальная стоимость обмена в базовой валюте: меньшие
+// отклонения от целевого распределения не исправляются
+const rebalanceMinValue = 0.01
+
+// rebalancePercentTolerance допустимое отклонение суммы долей от 100%
+const rebalancePercentTolerance = 0.01
+
+// RebalanceExchange обмен в плане ребалансировки
+type RebalanceExchange struct {
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Amount       float64 `json:"amount"` // сумма списания в FromCurrency
+	Value        float64 `json:"value"`  // стоимость обмена в базовой валюте
+	// Результат заполняется после выполнения обмена
+	ExchangedAmount float64 `json:"exchanged_amount"`
+	Fee             float64 `json:"fee"`
+}
+
+// RebalanceResult план ребалансировки и результат его выполнения
+type RebalanceResult struct {
+	BaseCurrency string                `json:"base_currency"`
+	TotalValue   float64               `json:"total_value"` // стоимость портфеля в базовой валюте
+	Executed     bool                  `json:"executed"`
+	Exchanges    []RebalanceExchange   `json:"exchanges"`
+	Balances     storages.UserBalances `json:"new_balance,omitempty"`
+}
+
+// RebalancePortfolio приводит балансы пользователя к целевому распределению targets
+// (валюта -> доля в процентах, сумма 100). Валюты с балансом, не указанные в targets,
+// продаются полностью. Стоимость балансов считается в baseCurrency (по умолчанию -
+// первая по алфавиту валюта из targets) по курсам exchanger без наценки.
+//
+// План состоит из минимального набора обменов: валюты с избытком продаются за валюты
+// с недостатком, крупнейший избыток покрывает крупнейший недостаток, поэтому обменов
+// не больше, чем валют минус один. Все обмены выполняются в одной транзакции БД:
+// при ошибке любого из них балансы не меняются. Наценка и комиссия уменьшают
+// полученные суммы, поэтому итоговое распределение близко к целевому, но не точно.
+// При dryRun возвращается только план
+func (s *WalletService) RebalancePortfolio(ctx context.Context, userID int64, targets map[string]float64, baseCurrency string, dryRun bool) (*RebalanceResult, error) {
+	targets, err := s.normalizeTargets(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseCurrency == "" {
+		baseCurrency = sortedCurrencies(targets)[0]
+	}
+	baseCurrency, err = s.validateCurrency(ctx, baseCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RebalanceResult{BaseCurrency: baseCurrency}
+
+	err = s.WithTransaction(ctx, func(ctx context.Context) error {
+		// Балансы читаются в транзакции обменов, чтобы план соответствовал
+		// состоянию, от которого они выполняются
+		balances, err := s.GetUserBalances(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		rates, err := s.GetExchangeRates(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.TotalValue, result.Exchanges, err = planRebalance(balances, targets, baseCurrency, rates)
+		if err != nil {
+			return err
+		}
+
+		if dryRun || len(result.Exchanges) == 0 {
+			return nil
+		}
+
+		for i := range result.Exchanges {
+			if err := s.executeRebalanceExchange(ctx, userID, balances, &result.Exchanges[i]); err != nil {
+				return err
+			}
+		}
+
+		result.Executed = true
+		result.Balances, err = s.GetUserBalances(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebalance portfolio: %w", err)
+	}
+
+	s.logger.Infof("Rebalance completed: UserID=%d, Exchanges=%d, TotalValue=%.2f %s, DryRun=%t",
+		userID, len(result.Exchanges), result.TotalValue, baseCurrency, dryRun)
+
+	return result, nil
+}
+
+// executeRebalanceExchange выполняет обмен плана. Если после комиссии баланса
+// не хватает (валюта продается полностью), сумма обмена уменьшается на комиссию
+func (s *WalletService) executeRebalanceExchange(ctx context.Context, userID int64, balances storages.UserBalances, exchange *RebalanceExchange) error {
+	available := balances[exchange.FromCurrency]
+	if fee := s.calculateFee(storages.TransactionTypeExchange, exchange.FromCurrency, exchange.Amount); exchange.Amount+fee > available {
+		exchange.Amount = available - fee
+	}
+	if exchange.Amount <= 0 {
+		return fmt.Errorf("%w: %s balance does not cover the exchange fee", ErrInsufficientFunds, exchange.FromCurrency)
+	}
+
+	exchanged, fee, _, err := s.ExchangeCurrency(ctx, userID, exchange.FromCurrency, exchange.ToCurrency, exchange.Amount, storages.ExchangeSourceAPI)
+	if err != nil {
+		return fmt.Errorf("failed to exchange %s -> %s: %w", exchange.FromCurrency, exchange.ToCurrency, err)
+	}
+
+	balances[exchange.FromCurrency] -= exchange.Amount + fee
+	exchange.ExchangedAmount = exchanged
+	exchange.Fee = fee
+	return nil
+}
+
+// normalizeTargets проверяет целевое распределение и нормализует коды валют
+func (s *WalletService) normalizeTargets(ctx context.Context, targets map[string]float64) (map[string]float64, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: targets must not be empty", ErrInvalidArgument)
+	}
+
+	normalized := make(map[string]float64, len(targets))
+	total := 0.0
+	for currency, percent := range targets {
+		code, err := s.validateCurrency(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("%w: target for %s must be between 0 and 100, got %v", ErrInvalidArgument, code, percent)
+		}
+		if _, ok := normalized[code]; ok {
+			return nil, fmt.Errorf("%w: duplicate target for %s", ErrInvalidArgument, code)
+		}
+		normalized[code] = percent
+		total += percent
+	}
+
+	if math.Abs(total-100) > rebalancePercentTolerance {
+		return nil, fmt.Errorf("%w: targets must sum to 100, got %v", ErrInvalidArgument, total)
+	}
+
+	return normalized, nil
+}
+
+// planRebalance рассчитывает стоимость портфеля и обмены, приводящие его к целевому распределению
+func planRebalance(balances storages.UserBalances, targets map[string]float64, base string, rates map[string]float32) (float64, []RebalanceExchange, error) {
+	currencies := make(map[string]bool, len(balances)+len(targets))
+	for currency, amount := range balances {
+		if amount > 0 {
+			currencies[currency] = true
+		}
+	}
+	for currency := range targets {
+		currencies[currency] = true
+	}
+
+	// Курс каждой валюты к базовой и текущая стоимость
+	toBase := make(map[string]float64, len(currencies))
+	values := make(map[string]float64, len(currencies))
+	total := 0.0
+	for currency := range currencies {
+		rate, ok := baseRate(rates, currency, base)
+		if !ok {
+			return 0, nil, fmt.Errorf("%w: no exchange rate for %s -> %s", ErrExchangerUnavailable, currency, base)
+		}
+		toBase[currency] = rate
+		values[currency] = balances[currency] * rate
+		total += values[currency]
+	}
+
+	if total <= 0 {
+		return 0, nil, fmt.Errorf("%w: nothing to rebalance", ErrInsufficientFunds)
+	}
+
+	type position struct {
+		currency string
+		value    float64
+	}
+	var surpluses, deficits []position
+	for _, currency := range sortedCurrencies(currencies) {
+		diff := values[currency] - total*targets[currency]/100
+		switch {
+		case diff >= rebalanceMinValue:
+			surpluses = append(surpluses, position{currency, diff})
+		case diff <= -rebalanceMinValue:
+			deficits = append(deficits, position{currency, -diff})
+		}
+	}
+
+	byValue := func(p []position) func(i, j int) bool {
+		return func(i, j int) bool { return p[i].value > p[j].value }
+	}
+	sort.SliceStable(surpluses, byValue(surpluses))
+	sort.SliceStable(deficits, byValue(deficits))
+
+	exchanges := []RebalanceExchange{}
+	for i, j := 0, 0; i < len(surpluses) && j < len(deficits); {
+		value := math.Min(surpluses[i].value, deficits[j].value)
+		if value >= rebalanceMinValue {
+			from := surpluses[i].currency
+			exchanges = append(exchanges, RebalanceExchange{
+				FromCurrency: from,
+				ToCurrency:   deficits[j].currency,
+				// Избыток валюты, продаваемой полностью, равен ее балансу: сумма берется
+				// из баланса, чтобы не оставлять остаток из-за округления
+				Amount: math.Min(value/toBase[from], balances[from]),
+				Value:  value,
+			})
+		}
+
+		surpluses[i].value -= value
+		deficits[j].value -= value
+		if surpluses[i].value < rebalanceMinValue {
+			i++
+		}
+		if deficits[j].value < rebalanceMinValue {
+			j++
+		}
+	}
+
+	return total, exchanges, nil
+}
+
+// baseRate возвращает курс currency к base: прямой или обратный к курсу base -> currency
+func baseRate(rates map[string]float32, currency, base string) (float64, bool) {
+	if currency == base {
+		return 1, true
+	}
+	if rate, ok := rates[currency+"_"+base]; ok && rate > 0 {
+		return float64(rate), true
+	}
+	if rate, ok := rates[base+"_"+currency]; ok && rate > 0 {
+		return 1 / float64(rate), true
+	}
+	return 0, false
+}
+
+// sortedCurrencies возвращает ключи карты валют по алфавиту
+func sortedCurrencies[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
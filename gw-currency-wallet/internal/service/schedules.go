@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// MaxSchedulesPerUser максимальное число регулярных операций пользователя
+const MaxSchedulesPerUser = 20
+
+// ScheduleUpdate изменения регулярной операции; nil - поле не меняется
+type ScheduleUpdate struct {
+	Amount  *float64
+	Period  *string
+	StartAt *time.Time
+	Active  *bool
+}
+
+// CreateSchedule создает регулярную операцию пользователя. Без StartAt первое
+// выполнение происходит при ближайшей проверке расписания
+func (s *WalletService) CreateSchedule(ctx context.Context, schedule *storages.Schedule) error {
+	if schedule.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if err := validateSchedulePeriod(schedule.Period); err != nil {
+		return err
+	}
+
+	toCurrency, err := s.validateCurrency(ctx, schedule.ToCurrency)
+	if err != nil {
+		return err
+	}
+	schedule.ToCurrency = toCurrency
+
+	switch schedule.Operation {
+	case storages.ScheduleOperationDeposit:
+		schedule.FromCurrency = ""
+	case storages.ScheduleOperationExchange:
+		fromCurrency, err := s.validateCurrency(ctx, schedule.FromCurrency)
+		if err != nil {
+			return err
+		}
+		if fromCurrency == toCurrency {
+			return ErrSameCurrency
+		}
+		schedule.FromCurrency = fromCurrency
+	default:
+		return fmt.Errorf("%w: unsupported schedule operation: %s", ErrInvalidArgument, schedule.Operation)
+	}
+
+	existing, err := s.storage.ListSchedules(ctx, schedule.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+	if len(existing) >= MaxSchedulesPerUser {
+		return fmt.Errorf("%w: at most %d schedules per user", ErrInvalidArgument, MaxSchedulesPerUser)
+	}
+
+	if schedule.StartAt.IsZero() {
+		schedule.StartAt = time.Now()
+	}
+	schedule.NextRunAt = schedule.StartAt
+	schedule.Active = true
+
+	if err := s.storage.CreateSchedule(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	return nil
+}
+
+// ListSchedules возвращает регулярные операции пользователя
+func (s *WalletService) ListSchedules(ctx context.Context, userID int64) ([]storages.Schedule, error) {
+	schedules, err := s.storage.ListSchedules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule возвращает регулярную операцию пользователя
+func (s *WalletService) GetSchedule(ctx context.Context, userID, scheduleID int64) (*storages.Schedule, error) {
+	schedule, err := s.storage.GetSchedule(ctx, userID, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule изменяет регулярную операцию пользователя. При изменении периода
+// или начала следующее выполнение пересчитывается; возобновленная операция
+// не выполняет запуски, пропущенные на паузе
+func (s *WalletService) UpdateSchedule(ctx context.Context, userID, scheduleID int64, update ScheduleUpdate) (*storages.Schedule, error) {
+	schedule, err := s.storage.GetSchedule(ctx, userID, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	if update.Amount != nil {
+		if *update.Amount <= 0 {
+			return nil, ErrInvalidAmount
+		}
+		schedule.Amount = *update.Amount
+	}
+
+	reschedule := false
+	if update.Period != nil {
+		if err := validateSchedulePeriod(*update.Period); err != nil {
+			return nil, err
+		}
+		reschedule = reschedule || schedule.Period != *update.Period
+		schedule.Period = *update.Period
+	}
+	if update.StartAt != nil {
+		schedule.StartAt = *update.StartAt
+		reschedule = true
+	}
+	if update.Active != nil {
+		reschedule = reschedule || (*update.Active && !schedule.Active)
+		schedule.Active = *update.Active
+	}
+
+	if reschedule {
+		now := time.Now()
+		switch {
+		case schedule.StartAt.After(now):
+			schedule.NextRunAt = schedule.StartAt
+		case schedule.NextRunAt.Before(now) || update.Period != nil || update.StartAt != nil:
+			schedule.NextRunAt = schedule.NextOccurrence(now)
+		}
+	}
+
+	if err := s.storage.UpdateSchedule(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// DeleteSchedule удаляет регулярную операцию пользователя
+func (s *WalletService) DeleteSchedule(ctx context.Context, userID, scheduleID int64) error {
+	if err := s.storage.DeleteSchedule(ctx, userID, scheduleID); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+// ExecuteSchedule выполняет наступивший запуск регулярной операции. Запуск
+// завершается в той же транзакции БД, что и операция, поэтому выполняется
+// один раз, даже если его одновременно обрабатывают несколько экземпляров
+// кошелька. При ошибке операция откатывается, повтор планирует вызывающий
+func (s *WalletService) ExecuteSchedule(ctx context.Context, schedule storages.Schedule) error {
+	next := schedule.NextOccurrence(time.Now())
+
+	return s.WithTransaction(ctx, func(ctx context.Context) error {
+		claimed, err := s.storage.CompleteScheduleRun(ctx, schedule.ID, schedule.RunCount, next, "")
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			s.logger.Debugf("Schedule %d run %d is already completed", schedule.ID, schedule.RunCount)
+			return nil
+		}
+
+		switch schedule.Operation {
+		case storages.ScheduleOperationDeposit:
+			_, err = s.Deposit(ctx, schedule.UserID, schedule.ToCurrency, schedule.Amount)
+		case storages.ScheduleOperationExchange:
+			_, _, _, err = s.ExchangeCurrency(ctx, schedule.UserID, schedule.FromCurrency, schedule.ToCurrency,
+				schedule.Amount, storages.ExchangeSourceScheduled)
+		default:
+			err = fmt.Errorf("%w: unsupported schedule operation: %s", ErrInvalidArgument, schedule.Operation)
+		}
+		if err != nil {
+			return err
+		}
+
+		s.logger.Infof("Schedule %d executed: UserID=%d, %s %.2f, next run at %s",
+			schedule.ID, schedule.UserID, schedule.Operation, schedule.Amount, next.Format(time.RFC3339))
+		return nil
+	})
+}
+
+// validateSchedulePeriod проверяет периодичность регулярной операции
+func validateSchedulePeriod(period string) error {
+	switch period {
+	case storages.SchedulePeriodDaily, storages.SchedulePeriodWeekly, storages.SchedulePeriodMonthly:
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported schedule period: %s", ErrInvalidArgument, period)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// sessionDeviceMaxLength максимальная длина описания устройства сессии
+const sessionDeviceMaxLength = 255
+
+// StartSession создает сессию пользователя при входе по паролю. device - User-Agent
+// клиента, expiresAt - окончание срока действия refresh токена сессии
+func (s *WalletService) StartSession(ctx context.Context, userID int64, device, ip string, expiresAt time.Time) (*storages.Session, error) {
+	if runes := []rune(device); len(runes) > sessionDeviceMaxLength {
+		device = string(runes[:sessionDeviceMaxLength])
+	}
+
+	session := &storages.Session{
+		UserID:    userID,
+		Device:    device,
+		IP:        ip,
+		ExpiresAt: expiresAt.UTC(),
+	}
+	if err := s.storage.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, nil
+}
+
+// ValidateSession проверяет, что сессия пользователя не отозвана и не истекла.
+// Для недействующей сессии возвращает ErrNotFound
+func (s *WalletService) ValidateSession(ctx context.Context, userID, sessionID int64) error {
+	session, err := s.storage.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if !session.Active(time.Now().UTC()) {
+		return fmt.Errorf("session is revoked or expired: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// RefreshSession проверяет сессию при обновлении access токена и обновляет время
+// ее последнего использования. Для недействующей сессии возвращает ErrNotFound
+func (s *WalletService) RefreshSession(ctx context.Context, userID, sessionID int64) error {
+	if err := s.ValidateSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	if err := s.storage.TouchSession(ctx, sessionID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions возвращает действующие сессии пользователя, новые первыми
+func (s *WalletService) ListSessions(ctx context.Context, userID int64) ([]storages.Session, error) {
+	sessions, err := s.storage.ListActiveSessions(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession отзывает действующую сессию пользователя: токены сессии перестают
+// приниматься. ErrNotFound, если сессии нет или она уже не действует
+func (s *WalletService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	if err := s.storage.RevokeSession(ctx, userID, sessionID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/statement"
+)
+
+// Ограничения периода выписки, в днях
+const (
+	DefaultStatementDays = 30
+	MaxStatementDays     = 366
+)
+
+// statementBatchSize число записей журнала в одном запросе к БД и одной отправляемой части выписки
+const statementBatchSize = 500
+
+// PrepareStatement проверяет период выписки с from по to (дни UTC включительно)
+// и возвращает ее заголовок с балансами на начало периода. Ошибки до начала
+// выгрузки возвращаются здесь, чтобы обработчик мог ответить ошибкой, а не выпиской
+func (s *WalletService) PrepareStatement(ctx context.Context, userID int64, from, to time.Time) (*statement.Header, error) {
+	from = truncateDay(from)
+	to = truncateDay(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidArgument)
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > MaxStatementDays {
+		return nil, fmt.Errorf("%w: statement period must not exceed %d days", ErrInvalidArgument, MaxStatementDays)
+	}
+
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	opening, err := s.storage.GetLedgerTotalsBefore(ctx, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening balances: %w", err)
+	}
+	for currency, amount := range opening {
+		opening[currency] = roundAmount(amount)
+	}
+
+	return &statement.Header{
+		UserID:      userID,
+		Username:    user.Username,
+		From:        from,
+		To:          to,
+		Opening:     opening,
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+// WriteStatement выгружает движения по балансам за период заголовка с остатком
+// после каждого движения. Журнал читается частями по statementBatchSize записей,
+// каждая часть сразу передается w
+func (s *WalletService) WriteStatement(ctx context.Context, header *statement.Header, w statement.Writer) error {
+	if err := w.WriteHeader(*header); err != nil {
+		return fmt.Errorf("failed to write statement header: %w", err)
+	}
+
+	balances := make(map[string]float64, len(header.Opening))
+	for currency, amount := range header.Opening {
+		balances[currency] = amount
+	}
+
+	end := header.To.AddDate(0, 0, 1)
+	var afterID int64
+	for {
+		entries, err := s.storage.ListLedgerEntries(ctx, header.UserID, header.From, end, afterID, statementBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get ledger entries: %w", err)
+		}
+
+		lines := make([]statement.Line, 0, len(entries))
+		for _, entry := range entries {
+			balances[entry.Currency] = roundAmount(balances[entry.Currency] + entry.Amount)
+			line := statement.Line{
+				Time:     entry.CreatedAt,
+				Type:     entry.Kind,
+				Currency: entry.Currency,
+				Amount:   entry.Amount,
+				Balance:  balances[entry.Currency],
+			}
+			if entry.TransactionID != nil {
+				line.TransactionID = *entry.TransactionID
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) > 0 {
+			if err := w.WriteLines(lines); err != nil {
+				return fmt.Errorf("failed to write statement lines: %w", err)
+			}
+		}
+
+		if len(entries) < statementBatchSize {
+			break
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+
+	if err := w.Close(balances); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+	return nil
+}
+
+// truncateDay возвращает начало дня t в UTC
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
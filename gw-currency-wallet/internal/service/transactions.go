@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// transactionTypes и transactionStatuses допустимые значения фильтра истории транзакций
+var (
+	transactionTypes = []string{
+		storages.TransactionTypeDeposit,
+		storages.TransactionTypeWithdraw,
+		storages.TransactionTypeExchange,
+		storages.TransactionTypeFee,
+	}
+	transactionStatuses = []string{
+		storages.TransactionStatusPending,
+		storages.TransactionStatusCompleted,
+		storages.TransactionStatusFailed,
+		storages.TransactionStatusCancelled,
+	}
+)
+
+// GetTransactions возвращает страницу истории транзакций пользователя от новых
+// к старым. Limit 0 - размер страницы по умолчанию
+func (s *WalletService) GetTransactions(ctx context.Context, userID int64, filter storages.TransactionFilter) (*storages.TransactionPage, error) {
+	if filter.Limit < 0 || filter.Limit > storages.MaxTransactionPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, storages.MaxTransactionPageSize)
+	}
+	if filter.Offset < 0 || filter.Cursor < 0 {
+		return nil, fmt.Errorf("%w: offset and cursor must not be negative", ErrInvalidArgument)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidArgument)
+	}
+	for _, t := range filter.Types {
+		if !slices.Contains(transactionTypes, t) {
+			return nil, fmt.Errorf("%w: unknown transaction type %q", ErrInvalidArgument, t)
+		}
+	}
+	for _, status := range filter.Statuses {
+		if !slices.Contains(transactionStatuses, status) {
+			return nil, fmt.Errorf("%w: unknown transaction status %q", ErrInvalidArgument, status)
+		}
+	}
+
+	page, err := s.storage.GetUserTransactions(ctx, userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	return page, nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gw-common/utils"
+	"gw-currency-wallet/internal/pricing"
+	"gw-currency-wallet/internal/storages"
+)
+
+// Параметры заявок на верификацию
+const (
+	// MinVerificationAge минимальный возраст пользователя для верификации
+	MinVerificationAge = 18
+	// DefaultVerificationRequestsLimit число заявок в ответе по умолчанию
+	DefaultVerificationRequestsLimit = 20
+	// MaxVerificationRequestsLimit максимальное число заявок в ответе
+	MaxVerificationRequestsLimit = 100
+	// userVerificationRequests число последних заявок в статусе верификации пользователя
+	userVerificationRequests = 10
+)
+
+// verificationLevels уровни верификации по возрастанию
+var verificationLevels = map[string]int{
+	storages.VerificationLevelUnverified: 0,
+	storages.VerificationLevelBasic:      1,
+	storages.VerificationLevelFull:       2,
+}
+
+// verificationDocuments типы документов для уровня full
+var verificationDocuments = map[string]bool{
+	"passport":         true,
+	"id_card":          true,
+	"driver_license":   true,
+	"residence_permit": true,
+}
+
+// TierLimit лимит операции для уровня верификации в валюте списания. Лимит
+// для конкретной валюты важнее лимита для всех валют
+type TierLimit struct {
+	Level     string  `json:"level"`
+	Operation string  `json:"operation"`
+	Currency  string  `json:"currency"` // код валюты или pricing.AnyCurrency - каждая валюта отдельно
+	Period    string  `json:"period"`
+	Amount    float64 `json:"amount"`
+}
+
+// VerificationStatus уровень верификации пользователя, лимиты уровня и последние заявки
+type VerificationStatus struct {
+	Level    string                         `json:"level"`
+	Limits   []TierLimit                    `json:"limits"`
+	Requests []storages.VerificationRequest `json:"requests"`
+}
+
+// ParseTierLimits разбирает лимиты уровней вида "level:operation:currency:period:amount"
+// через запятую, например "unverified:withdraw:*:daily:0,basic:withdraw:*:daily:1000".
+// Лимит 0 запрещает операцию. Операции без лимита для уровня не ограничены
+func ParseTierLimits(value string) ([]TierLimit, error) {
+	var limits []TierLimit
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalid tier limit %q: expected level:operation:currency:period:amount", item)
+		}
+
+		limit := TierLimit{
+			Level:     strings.ToLower(strings.TrimSpace(parts[0])),
+			Operation: strings.ToLower(strings.TrimSpace(parts[1])),
+			Currency:  utils.NormalizeCurrency(parts[2]),
+			Period:    strings.ToLower(strings.TrimSpace(parts[3])),
+		}
+
+		if _, ok := verificationLevels[limit.Level]; !ok {
+			return nil, fmt.Errorf("invalid tier limit %q: unknown verification level %s", item, limit.Level)
+		}
+		if err := validateLimitKey(limit.Operation, limit.Period); err != nil {
+			return nil, fmt.Errorf("invalid tier limit %q: %w", item, err)
+		}
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(parts[4]), 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("invalid tier limit %q: amount must be a non-negative number", item)
+		}
+		limit.Amount = amount
+
+		key := strings.Join([]string{limit.Level, limit.Operation, limit.Currency, limit.Period}, ":")
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate tier limit %s", key)
+		}
+		seen[key] = true
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// SetTierLimits задает лимиты уровней верификации. Без лимитов уровень
+// верификации не влияет на операции
+func (s *WalletService) SetTierLimits(limits []TierLimit) {
+	s.tierLimits = limits
+	if len(limits) > 0 {
+		s.logger.Infof("Verification tier limits configured: %d rules", len(limits))
+	}
+}
+
+// TierLimits возвращает лимиты уровня верификации
+func (s *WalletService) TierLimits(level string) []TierLimit {
+	limits := make([]TierLimit, 0)
+	for _, limit := range s.tierLimits {
+		if limit.Level == level {
+			limits = append(limits, limit)
+		}
+	}
+	return limits
+}
+
+// tierLimitsFor возвращает лимиты уровня level на операцию в валюте currency
+// в виде лимитов пользователя. Лимит для валюты заменяет лимит для всех валют
+// за тот же период
+func (s *WalletService) tierLimitsFor(level, operation, currency string) []storages.Limit {
+	byPeriod := make(map[string]TierLimit)
+	for _, limit := range s.tierLimits {
+		if limit.Level != level || limit.Operation != operation {
+			continue
+		}
+		if limit.Currency != currency && limit.Currency != pricing.AnyCurrency {
+			continue
+		}
+		if existing, ok := byPeriod[limit.Period]; ok && existing.Currency != pricing.AnyCurrency {
+			continue
+		}
+		byPeriod[limit.Period] = limit
+	}
+
+	limits := make([]storages.Limit, 0, len(byPeriod))
+	for _, period := range []string{storages.LimitPeriodDaily, storages.LimitPeriodMonthly} {
+		if limit, ok := byPeriod[period]; ok {
+			limits = append(limits, storages.Limit{
+				Operation: operation,
+				Currency:  currency,
+				Period:    period,
+				Amount:    limit.Amount,
+			})
+		}
+	}
+	return limits
+}
+
+// SubmitVerification создает заявку пользователя на повышение уровня верификации.
+// Для basic нужны имя, дата рождения и страна, для full - еще и документ
+func (s *WalletService) SubmitVerification(ctx context.Context, request *storages.VerificationRequest) error {
+	if err := s.checkNotClosed(ctx, request.UserID); err != nil {
+		return err
+	}
+
+	user, err := s.GetUser(ctx, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := validateVerificationRequest(request, time.Now().UTC()); err != nil {
+		return err
+	}
+	if verificationLevels[request.Level] <= verificationLevels[user.VerificationLevel] {
+		return fmt.Errorf("%w: user is already verified at level %s", ErrInvalidArgument, user.VerificationLevel)
+	}
+
+	if err := s.storage.CreateVerificationRequest(ctx, request); err != nil {
+		if errors.Is(err, storages.ErrDuplicate) {
+			return ErrVerificationPending
+		}
+		return fmt.Errorf("failed to submit verification: %w", err)
+	}
+
+	s.logger.Infof("Verification submitted: UserID=%d, Level=%s, RequestID=%d", request.UserID, request.Level, request.ID)
+	return nil
+}
+
+// GetVerificationStatus возвращает уровень верификации пользователя, лимиты
+// уровня и последние заявки
+func (s *WalletService) GetVerificationStatus(ctx context.Context, userID int64) (*VerificationStatus, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.storage.ListVerificationRequests(ctx, storages.VerificationRequestFilter{
+		UserID: userID,
+		Limit:  userVerificationRequests,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verification requests: %w", err)
+	}
+
+	return &VerificationStatus{
+		Level:    user.VerificationLevel,
+		Limits:   s.TierLimits(user.VerificationLevel),
+		Requests: requests,
+	}, nil
+}
+
+// ListVerificationRequests возвращает до limit заявок на верификацию для
+// администраторов, новые первыми; пустой status - в любом статусе
+func (s *WalletService) ListVerificationRequests(ctx context.Context, status string, limit int) ([]storages.VerificationRequest, error) {
+	switch status {
+	case "", storages.VerificationStatusPending, storages.VerificationStatusApproved, storages.VerificationStatusRejected:
+	default:
+		return nil, fmt.Errorf("%w: unsupported verification status: %s", ErrInvalidArgument, status)
+	}
+	if limit < 1 || limit > MaxVerificationRequestsLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, MaxVerificationRequestsLimit)
+	}
+
+	requests, err := s.storage.ListVerificationRequests(ctx, storages.VerificationRequestFilter{Status: status, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verification requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ApproveVerification одобряет заявку и повышает уровень верификации пользователя
+func (s *WalletService) ApproveVerification(ctx context.Context, requestID, adminID int64, comment string) (*storages.VerificationRequest, error) {
+	return s.reviewVerification(ctx, requestID, storages.VerificationReview{
+		Status:     storages.VerificationStatusApproved,
+		ReviewerID: adminID,
+		Comment:    strings.TrimSpace(comment),
+	})
+}
+
+// RejectVerification отклоняет заявку; причина обязательна и видна пользователю
+func (s *WalletService) RejectVerification(ctx context.Context, requestID, adminID int64, reason string) (*storages.VerificationRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidArgument)
+	}
+
+	return s.reviewVerification(ctx, requestID, storages.VerificationReview{
+		Status:     storages.VerificationStatusRejected,
+		ReviewerID: adminID,
+		Comment:    reason,
+	})
+}
+
+// reviewVerification записывает решение по заявке в статусе pending
+func (s *WalletService) reviewVerification(ctx context.Context, requestID int64, review storages.VerificationReview) (*storages.VerificationRequest, error) {
+	review.ReviewedAt = time.Now().UTC()
+
+	request, err := s.storage.ReviewVerificationRequest(ctx, requestID, review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review verification request: %w", err)
+	}
+
+	s.logger.Infof("Verification request %d %s: UserID=%d, Level=%s, AdminID=%d",
+		requestID, review.Status, request.UserID, request.Level, review.ReviewerID)
+	return request, nil
+}
+
+// validateVerificationRequest проверяет и нормализует данные заявки
+func validateVerificationRequest(request *storages.VerificationRequest, now time.Time) error {
+	request.Level = strings.ToLower(strings.TrimSpace(request.Level))
+	request.FullName = strings.TrimSpace(request.FullName)
+	request.Country = strings.ToUpper(strings.TrimSpace(request.Country))
+	request.DocumentType = strings.ToLower(strings.TrimSpace(request.DocumentType))
+	request.DocumentNumber = strings.TrimSpace(request.DocumentNumber)
+
+	if request.Level != storages.VerificationLevelBasic && request.Level != storages.VerificationLevelFull {
+		return fmt.Errorf("%w: unsupported verification level: %s", ErrInvalidArgument, request.Level)
+	}
+	if request.FullName == "" {
+		return fmt.Errorf("%w: full_name is required", ErrInvalidArgument)
+	}
+	if len(request.Country) != 2 {
+		return fmt.Errorf("%w: country must be an ISO 3166-1 alpha-2 code", ErrInvalidArgument)
+	}
+
+	birth, err := time.Parse(time.DateOnly, strings.TrimSpace(request.DateOfBirth))
+	if err != nil {
+		return fmt.Errorf("%w: date_of_birth must be in YYYY-MM-DD format", ErrInvalidArgument)
+	}
+	if now.Before(birth.AddDate(MinVerificationAge, 0, 0)) {
+		return fmt.Errorf("%w: user must be at least %d years old", ErrInvalidArgument, MinVerificationAge)
+	}
+	request.DateOfBirth = birth.Format(time.DateOnly)
+
+	if request.Level == storages.VerificationLevelFull {
+		if !verificationDocuments[request.DocumentType] {
+			return fmt.Errorf("%w: unsupported document_type: %q", ErrInvalidArgument, request.DocumentType)
+		}
+		if request.DocumentNumber == "" {
+			return fmt.Errorf("%w: document_number is required for level %s", ErrInvalidArgument, storages.VerificationLevelFull)
+		}
+	}
+
+	return nil
+}
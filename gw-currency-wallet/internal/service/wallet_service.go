@@ -2,14 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/singleflight"
+	"gw-common/errcodes"
+	"gw-common/utils"
 	"gw-currency-wallet/internal/cache"
+	"gw-currency-wallet/internal/events"
 	"gw-currency-wallet/internal/grpc"
 	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/password"
+	"gw-currency-wallet/internal/pricing"
 	"gw-currency-wallet/internal/storages"
-	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
+	"gw-currency-wallet/internal/webhook"
 )
 
 // WalletService сервисный слой для бизнес-логики
@@ -17,8 +27,22 @@ type WalletService struct {
 	storage         storages.Storage
 	exchangerClient *grpc.ExchangerClient
 	ratesCache      *cache.RatesCache
+	currenciesCache *cache.CurrenciesCache
+	pricer          *pricing.Pricer
+	fees            *pricing.FeeSchedule
 	kafkaProducer   *kafka.Producer
 	logger          *logrus.Logger
+	demoRates       bool        // курсы демо-режима при недоступном exchanger, см. EnableDemoRates
+	events          *events.Bus // шина событий для WebSocket, см. SetEventBus
+	// withdrawalApproval параметры вывода с подтверждением, см. SetWithdrawalApproval
+	withdrawalApproval WithdrawalApproval
+	// tierLimits лимиты уровней верификации, см. SetTierLimits
+	tierLimits []TierLimit
+	// passwordPolicy требования к паролям, см. SetPasswordPolicy
+	passwordPolicy *password.Policy
+	// ratesFlight объединяет одновременные запросы курсов к exchanger, например
+	// когда истекает кеш под нагрузкой
+	ratesFlight singleflight.Group
 }
 
 // NewWalletService создает новый экземпляр сервиса
@@ -26,6 +50,9 @@ func NewWalletService(
 	storage storages.Storage,
 	exchangerClient *grpc.ExchangerClient,
 	ratesCache *cache.RatesCache,
+	currenciesCache *cache.CurrenciesCache,
+	pricer *pricing.Pricer,
+	fees *pricing.FeeSchedule,
 	kafkaProducer *kafka.Producer,
 	logger *logrus.Logger,
 ) *WalletService {
@@ -33,22 +60,44 @@ func NewWalletService(
 		storage:         storage,
 		exchangerClient: exchangerClient,
 		ratesCache:      ratesCache,
+		currenciesCache: currenciesCache,
+		pricer:          pricer,
+		fees:            fees,
 		kafkaProducer:   kafkaProducer,
 		logger:          logger,
+		passwordPolicy:  password.DefaultPolicy(),
 	}
 }
 
-// RegisterUser регистрирует нового пользователя
+// RegisterUser регистрирует нового пользователя. Пароль проверяется политикой паролей
 func (s *WalletService) RegisterUser(ctx context.Context, username, email, password string) error {
+	if reservedIdentity(username, email) {
+		return fmt.Errorf("%w: username prefix %s and email domain %s are reserved", ErrInvalidArgument, DeletedUsernamePrefix, DeletedEmailDomain)
+	}
+	if err := s.passwordPolicy.Validate(password, username, email); err != nil {
+		return err
+	}
+
+	return s.createUser(ctx, username, email, password)
+}
+
+// createUser создает пользователя без проверки политики паролей
+func (s *WalletService) createUser(ctx context.Context, username, email, password string) error {
 	// Проверяем, не существует ли уже пользователь
-	existingUser, _ := s.storage.GetUserByUsername(ctx, username)
+	existingUser, err := s.storage.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, storages.ErrNotFound) {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
 	if existingUser != nil {
-		return fmt.Errorf("username already exists")
+		return fmt.Errorf("%w: username is taken", ErrUserExists)
 	}
 
-	existingUser, _ = s.storage.GetUserByEmail(ctx, email)
+	existingUser, err = s.storage.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, storages.ErrNotFound) {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
 	if existingUser != nil {
-		return fmt.Errorf("email already exists")
+		return fmt.Errorf("%w: email is taken", ErrUserExists)
 	}
 
 	// Хешируем пароль
@@ -63,144 +112,399 @@ func (s *WalletService) RegisterUser(ctx context.Context, username, email, passw
 		Username:     username,
 		Email:        email,
 		PasswordHash: string(hashedPassword),
+		Role:         storages.RoleUser,
+	}
+
+	// Балансы в валютах, добавленных позже, создаются при первой операции
+	currencies, err := s.GetSupportedCurrencies(ctx)
+	if err != nil {
+		s.logger.Warnf("Failed to get supported currencies, skipping initial balances: %v", err)
 	}
 
-	if err := s.storage.CreateUser(ctx, user); err != nil {
+	if err := s.storage.CreateUser(ctx, user, currencies); err != nil {
+		// Пользователь с тем же именем или email мог быть создан параллельным запросом
+		if errors.Is(err, storages.ErrDuplicate) {
+			return fmt.Errorf("%w: username or email is taken", ErrUserExists)
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	s.logger.Infof("User registered successfully: %s", username)
+	s.publishAccountEvent(ctx, kafka.EventAccountCreated, user.ID, username)
+	return nil
+}
+
+// WithTransaction выполняет fn в транзакции БД. Операции сервиса, вызванные
+// с контекстом fn, фиксируются вместе или откатываются при ошибке fn.
+// События изменения балансов публикуются после фиксации
+func (s *WalletService) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pendingEventsKey{}).(*pendingEvents); ok || s.events == nil {
+		return s.storage.WithTransaction(ctx, fn)
+	}
+
+	pending := &pendingEvents{}
+	if err := s.storage.WithTransaction(context.WithValue(ctx, pendingEventsKey{}, pending), fn); err != nil {
+		return err
+	}
+	s.flushBalances(ctx, pending)
 	return nil
 }
 
 // AuthenticateUser аутентифицирует пользователя
 func (s *WalletService) AuthenticateUser(ctx context.Context, username, password string) (*storages.User, error) {
 	user, err := s.storage.GetUserByUsername(ctx, username)
+	if errors.Is(err, storages.ErrNotFound) || (err == nil && user == nil) {
+		s.publishAccountEvent(ctx, kafka.EventFailedLogin, 0, username)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid username or password")
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// Проверяем пароль
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		s.logger.Warnf("Failed authentication attempt for user: %s", username)
-		return nil, fmt.Errorf("invalid username or password")
+		s.publishAccountEvent(ctx, kafka.EventFailedLogin, user.ID, username)
+		return nil, ErrInvalidCredentials
+	}
+
+	// Закрытие сообщается только после проверки пароля, чтобы не раскрывать статус
+	if user.Status == storages.AccountStatusClosed {
+		s.logger.Warnf("Rejected login of closed user: %s", username)
+		return nil, ErrAccountClosed
 	}
 
 	s.logger.Infof("User authenticated successfully: %s", username)
 	return user, nil
 }
 
+// EnsureAdmins назначает роль администратора перечисленным пользователям.
+// Несуществующие пользователи пропускаются.
+func (s *WalletService) EnsureAdmins(ctx context.Context, usernames []string) error {
+	for _, username := range usernames {
+		user, err := s.storage.GetUserByUsername(ctx, username)
+		if errors.Is(err, storages.ErrNotFound) || (err == nil && user == nil) {
+			s.logger.Warnf("Admin user %s not found, skipping", username)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get admin user %s: %w", username, err)
+		}
+
+		if user.Role == storages.RoleAdmin {
+			continue
+		}
+
+		if err := s.storage.UpdateUserRole(ctx, user.ID, storages.RoleAdmin); err != nil {
+			return fmt.Errorf("failed to grant admin role to %s: %w", username, err)
+		}
+		s.logger.Infof("Granted admin role to user: %s", username)
+	}
+
+	return nil
+}
+
+// ListUsers возвращает страницу пользователей для администраторов
+func (s *WalletService) ListUsers(ctx context.Context, search string, page, limit int) ([]storages.User, int64, error) {
+	if page < 1 || limit < 1 {
+		return nil, 0, fmt.Errorf("%w: page and limit must be positive", ErrInvalidArgument)
+	}
+
+	users, total, err := s.storage.ListUsers(ctx, search, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// GetUser возвращает пользователя по ID
+func (s *WalletService) GetUser(ctx context.Context, userID int64) (*storages.User, error) {
+	user, err := s.storage.GetUserByID(ctx, userID)
+	if errors.Is(err, storages.ErrNotFound) || (err == nil && user == nil) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// CheckLedger проверяет инварианты учета и возвращает найденные нарушения
+func (s *WalletService) CheckLedger(ctx context.Context) ([]storages.LedgerViolation, error) {
+	violations, err := s.storage.FindLedgerViolations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger: %w", err)
+	}
+
+	for _, v := range violations {
+		s.logger.Warnf("Ledger violation: type=%s user=%d currency=%s tx=%d: %s",
+			v.Type, v.UserID, v.Currency, v.TransactionID, v.Details)
+	}
+
+	return violations, nil
+}
+
+// GetLedgerEntries возвращает последние записи журнала балансов пользователя,
+// пустая currency - по всем валютам
+func (s *WalletService) GetLedgerEntries(ctx context.Context, userID int64, currency string, limit int) ([]storages.LedgerEntry, error) {
+	entries, err := s.storage.GetLedgerEntries(ctx, userID, strings.ToUpper(currency), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	return entries, nil
+}
+
 // GetUserBalances возвращает балансы пользователя
-func (s *WalletService) GetUserBalances(ctx context.Context, userID int64) (*storages.UserBalances, error) {
+func (s *WalletService) GetUserBalances(ctx context.Context, userID int64) (storages.UserBalances, error) {
 	balances, err := s.storage.GetAllBalances(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balances: %w", err)
 	}
 
-	userBalances := &storages.UserBalances{}
+	userBalances := make(storages.UserBalances, len(balances))
 	for _, balance := range balances {
-		switch balance.Currency {
-		case "USD":
-			userBalances.USD = balance.Amount
-		case "EUR":
-			userBalances.EUR = balance.Amount
-		case "RUB":
-			userBalances.RUB = balance.Amount
-		}
+		userBalances[balance.Currency] = balance.Amount
 	}
 
 	return userBalances, nil
 }
 
-// Deposit пополняет баланс пользователя
-func (s *WalletService) Deposit(ctx context.Context, userID int64, currency string, amount float64) (*storages.UserBalances, error) {
-	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be positive")
+// GetSupportedCurrencies возвращает коды поддерживаемых валют (из кеша или gRPC)
+func (s *WalletService) GetSupportedCurrencies(ctx context.Context) ([]string, error) {
+	if currencies, ok := s.currenciesCache.Get(); ok {
+		return currencies, nil
 	}
 
-	// Получаем текущий баланс
-	balance, err := s.storage.GetBalance(ctx, userID, currency)
+	if s.exchangerClient == nil {
+		if s.demoRates {
+			return append([]string(nil), DemoCurrencies...), nil
+		}
+		return nil, ErrExchangerUnavailable
+	}
+
+	s.logger.Debug("Fetching supported currencies from exchanger service")
+	currencies, err := s.exchangerClient.GetCurrencies(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get balance: %w", err)
+		if s.demoRates {
+			s.logger.Warnf("Exchanger is unavailable, using demo currencies: %v", err)
+			return append([]string(nil), DemoCurrencies...), nil
+		}
+		return nil, fmt.Errorf("%w: failed to get supported currencies: %w", ErrExchangerUnavailable, err)
 	}
 
-	// Обновляем баланс
-	balance.Amount += amount
-	if err := s.storage.UpdateBalance(ctx, balance); err != nil {
-		return nil, fmt.Errorf("failed to update balance: %w", err)
+	s.currenciesCache.Set(currencies)
+
+	return currencies, nil
+}
+
+// GetExchangerCallerPairs возвращает пары валют, разрешенные вызывающей стороне exchanger.
+// Пустой список означает отсутствие ограничений
+func (s *WalletService) GetExchangerCallerPairs(ctx context.Context, caller string) ([]grpc.CurrencyPair, error) {
+	if s.exchangerClient == nil {
+		return nil, ErrExchangerUnavailable
 	}
 
-	// Создаем запись о транзакции
-	tx := &storages.Transaction{
-		UserID:       userID,
-		Type:         storages.TransactionTypeDeposit,
-		FromCurrency: currency,
-		ToCurrency:   currency,
-		FromAmount:   amount,
-		ToAmount:     amount,
-		ExchangeRate: 1.0,
-		Status:       storages.TransactionStatusCompleted,
+	return s.exchangerClient.GetCallerPairs(ctx, caller)
+}
+
+// SetExchangerCallerPairs заменяет список пар валют, разрешенных вызывающей стороне exchanger.
+// Пустой список снимает ограничения
+func (s *WalletService) SetExchangerCallerPairs(ctx context.Context, caller string, pairs []grpc.CurrencyPair) ([]grpc.CurrencyPair, error) {
+	if s.exchangerClient == nil {
+		return nil, ErrExchangerUnavailable
 	}
-	if err := s.storage.CreateTransaction(ctx, tx); err != nil {
-		s.logger.Warnf("Failed to create transaction record: %v", err)
+
+	for i := range pairs {
+		pairs[i].FromCurrency = utils.NormalizeCurrency(pairs[i].FromCurrency)
+		pairs[i].ToCurrency = utils.NormalizeCurrency(pairs[i].ToCurrency)
 	}
 
-	// Отправляем уведомление в Kafka, если сумма большая
-	if err := s.kafkaProducer.SendLargeTransferNotification(ctx, userID, "deposit", currency, currency, amount); err != nil {
-		s.logger.Warnf("Failed to send Kafka notification: %v", err)
+	s.logger.Infof("Setting %d allowed exchanger pairs for caller %s", len(pairs), caller)
+	return s.exchangerClient.SetCallerPairs(ctx, caller, pairs)
+}
+
+// PublishUserLifecycle сообщает сервису уведомлений о заморозке, удалении или
+// повторной активации пользователя. Вызывается после изменения статуса в БД;
+// без Kafka producer ничего не делает
+func (s *WalletService) PublishUserLifecycle(ctx context.Context, userID int64, event, reason string) error {
+	if s.kafkaProducer == nil {
+		return nil
 	}
 
-	s.logger.Infof("Deposit completed: UserID=%d, Amount=%.2f %s", userID, amount, currency)
+	now := time.Now().UTC()
+	message := kafka.UserLifecycleMessage{
+		EventID:   fmt.Sprintf("wallet-user-%d-%s-%d", userID, event, now.UnixNano()),
+		UserID:    userID,
+		Event:     event,
+		Reason:    reason,
+		Timestamp: now,
+	}
 
-	return s.GetUserBalances(ctx, userID)
+	if err := s.kafkaProducer.SendUserLifecycle(ctx, message); err != nil {
+		return fmt.Errorf("failed to publish user event: %w", err)
+	}
+	return nil
+}
+
+// publishAccountEvent отправляет событие учетной записи в фоне, не задерживая
+// регистрацию и вход. Ошибки отправки только логируются; без маршрута для события
+// ничего не делает
+func (s *WalletService) publishAccountEvent(ctx context.Context, event string, userID int64, username string) {
+	if s.kafkaProducer == nil || !s.kafkaProducer.HasRoute(event) {
+		return
+	}
+
+	now := time.Now().UTC()
+	message := kafka.AccountEventMessage{
+		EventID:   fmt.Sprintf("wallet-account-%s-%s-%d", event, username, now.UnixNano()),
+		Event:     event,
+		UserID:    userID,
+		Username:  username,
+		Timestamp: now,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		if err := s.kafkaProducer.SendAccountEvent(ctx, message); err != nil {
+			s.logger.Warnf("Failed to publish %s event for %s: %v", event, username, err)
+		}
+	}()
+}
+
+// ExchangerStats возвращает метрики повторов и состояние circuit breaker клиента
+// exchanger; ok == false, если клиент не настроен
+func (s *WalletService) ExchangerStats() (stats grpc.ResilienceStats, ok bool) {
+	if s.exchangerClient == nil {
+		return grpc.ResilienceStats{}, false
+	}
+	return s.exchangerClient.Stats(), true
 }
 
-// Withdraw выводит средства со счета пользователя
-func (s *WalletService) Withdraw(ctx context.Context, userID int64, currency string, amount float64) (*storages.UserBalances, error) {
+// validateCurrency нормализует код валюты и проверяет, что она поддерживается.
+// Если exchanger недоступен, валюта проверяется по валютам существующих балансов:
+// пополнения и выводы в уже используемых валютах не зависят от exchanger
+func (s *WalletService) validateCurrency(ctx context.Context, currency string) (string, error) {
+	supported, err := s.GetSupportedCurrencies(ctx)
+	if errors.Is(err, ErrExchangerUnavailable) {
+		supported, err = s.balanceCurrencies(ctx, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	currency = utils.NormalizeCurrency(currency)
+	if err := utils.ValidateCurrency(currency, supported); err != nil {
+		return "", err
+	}
+
+	return currency, nil
+}
+
+// balanceCurrencies возвращает валюты существующих балансов вместо списка exchanger.
+// Если балансов нет или их не удалось прочитать, возвращается исходная ошибка exchanger
+func (s *WalletService) balanceCurrencies(ctx context.Context, exchangerErr error) ([]string, error) {
+	currencies, err := s.storage.GetBalanceCurrencies(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to get balance currencies: %v", err)
+		return nil, exchangerErr
+	}
+	if len(currencies) == 0 {
+		return nil, exchangerErr
+	}
+	s.logger.Warnf("Validating currency against existing balances: %v", exchangerErr)
+	return currencies, nil
+}
+
+// Deposit пополняет баланс пользователя
+func (s *WalletService) Deposit(ctx context.Context, userID int64, currency string, amount float64) (storages.UserBalances, error) {
 	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be positive")
+		return nil, ErrInvalidAmount
 	}
 
-	// Получаем текущий баланс
-	balance, err := s.storage.GetBalance(ctx, userID, currency)
+	currency, err := s.validateCurrency(ctx, currency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get balance: %w", err)
+		return nil, err
 	}
 
-	// Проверяем достаточность средств
-	if balance.Amount < amount {
-		return nil, fmt.Errorf("insufficient funds: have %.2f, need %.2f", balance.Amount, amount)
+	if err := s.checkNotClosed(ctx, userID); err != nil {
+		return nil, err
 	}
 
-	// Обновляем баланс
-	balance.Amount -= amount
-	if err := s.storage.UpdateBalance(ctx, balance); err != nil {
-		return nil, fmt.Errorf("failed to update balance: %w", err)
+	// Пополняем баланс атомарно вместе с записью о транзакции, outbox и доставками вебхуков
+	large := s.isLargeTransfer(ctx, currency, amount)
+	var txID int64
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if txID, err = s.storage.ExecuteDeposit(ctx, userID, currency, amount, large); err != nil {
+			return err
+		}
+		return s.enqueueWebhookEvent(ctx, userID, storages.WebhookEventDepositCompleted, webhook.DepositData{
+			TransactionID: txID,
+			Currency:      currency,
+			Amount:        amount,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to deposit: %w", err)
+	}
+
+	s.logger.Infof("Deposit completed: UserID=%d, Amount=%.2f %s, TxID=%d", userID, amount, currency, txID)
+
+	balances, err := s.GetUserBalances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishBalances(ctx, userID, balances)
+
+	return balances, nil
+}
+
+// Withdraw выводит средства со счета пользователя. Возвращает новые балансы
+// и комиссию, списанную в той же валюте сверх суммы вывода
+func (s *WalletService) Withdraw(ctx context.Context, userID int64, currency string, amount float64) (storages.UserBalances, float64, error) {
+	if amount <= 0 {
+		return nil, 0, ErrInvalidAmount
 	}
 
-	// Создаем запись о транзакции
-	tx := &storages.Transaction{
-		UserID:       userID,
-		Type:         storages.TransactionTypeWithdraw,
-		FromCurrency: currency,
-		ToCurrency:   currency,
-		FromAmount:   amount,
-		ToAmount:     amount,
-		ExchangeRate: 1.0,
-		Status:       storages.TransactionStatusCompleted,
+	currency, err := s.validateCurrency(ctx, currency)
+	if err != nil {
+		return nil, 0, err
 	}
-	if err := s.storage.CreateTransaction(ctx, tx); err != nil {
-		s.logger.Warnf("Failed to create transaction record: %v", err)
+
+	if err := s.checkNotFrozen(ctx, userID); err != nil {
+		return nil, 0, err
 	}
 
-	// Отправляем уведомление в Kafka, если сумма большая
-	if err := s.kafkaProducer.SendLargeTransferNotification(ctx, userID, "withdraw", currency, currency, amount); err != nil {
-		s.logger.Warnf("Failed to send Kafka notification: %v", err)
+	fee := s.calculateFee(storages.TransactionTypeWithdraw, currency, amount)
+
+	// Списываем средства и комиссию атомарно вместе с проверкой лимитов,
+	// записью о транзакции и outbox
+	large := s.isLargeTransfer(ctx, currency, amount)
+	var txID int64
+	err = s.withinLimits(ctx, userID, storages.TransactionTypeWithdraw, currency, amount, func(ctx context.Context) error {
+		var err error
+		if txID, err = s.storage.ExecuteWithdraw(ctx, userID, currency, amount, fee, large); err != nil {
+			return fmt.Errorf("failed to withdraw: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	s.logger.Infof("Withdrawal completed: UserID=%d, Amount=%.2f %s", userID, amount, currency)
+	s.logger.Infof("Withdrawal completed: UserID=%d, Amount=%.2f %s, Fee=%.2f, TxID=%d", userID, amount, currency, fee, txID)
 
-	return s.GetUserBalances(ctx, userID)
+	balances, err := s.GetUserBalances(ctx, userID)
+	if err != nil {
+		return nil, fee, err
+	}
+	s.publishBalances(ctx, userID, balances)
+
+	return balances, fee, nil
 }
 
 // GetExchangeRates получает курсы валют (из кеша или gRPC)
@@ -212,66 +516,297 @@ func (s *WalletService) GetExchangeRates(ctx context.Context) (map[string]float3
 	}
 
 	// Получаем из gRPC сервиса
-	s.logger.Debug("Fetching exchange rates from exchanger service")
-	rates, err := s.exchangerClient.GetExchangeRates(ctx)
+	rates, err := s.fetchExchangeRates(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+		if demoRates, ok := s.demoRatesFallback(); ok {
+			s.logger.Warnf("Exchanger is unavailable, using demo rates: %v", err)
+			return demoRates, nil
+		}
+		return nil, fmt.Errorf("%w: failed to get exchange rates: %w", ErrExchangerUnavailable, err)
 	}
 
 	// Сохраняем в кеш
-	s.ratesCache.Set(rates)
+	s.ratesCache.SetWithSides(rates.Rates, rates.Bids, rates.Asks)
+	s.publishRates(rates.Rates)
 
-	return rates, nil
+	return rates.Rates, nil
 }
 
-// ExchangeCurrency обменивает валюту
-func (s *WalletService) ExchangeCurrency(ctx context.Context, userID int64, fromCurrency, toCurrency string, amount float64) (float64, *storages.UserBalances, error) {
+// RefreshExchangeRates получает курсы из exchanger и сохраняет их в кеш.
+// Используется фоновым обновлением кеша (cache.RatesRefresher)
+func (s *WalletService) RefreshExchangeRates(ctx context.Context) error {
+	rates, err := s.fetchExchangeRates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh exchange rates: %w", err)
+	}
+
+	s.ratesCache.SetWithSides(rates.Rates, rates.Bids, rates.Asks)
+	s.publishRates(rates.Rates)
+	return nil
+}
+
+// ApplyRateUpdate применяет событие изменения курса exchanger: курсы пары сразу
+// удаляются из кеша и запрашиваются у exchanger вместе с курсами покупки и продажи.
+// Если запрос не удался, пара запрашивается при следующем обмене, а в списке
+// курсов появится со следующим обновлением кеша
+func (s *WalletService) ApplyRateUpdate(ctx context.Context, message kafka.RateUpdateMessage) error {
+	if !s.ratesCache.InvalidatePair(message.FromCurrency, message.ToCurrency) {
+		s.logger.Debugf("Rates cache is empty, skipping rate update %s -> %s", message.FromCurrency, message.ToCurrency)
+		return nil
+	}
+	if s.exchangerClient == nil {
+		return nil
+	}
+
+	pairRate, err := s.fetchPairRate(ctx, message.FromCurrency, message.ToCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to refresh rate %s -> %s: %w", message.FromCurrency, message.ToCurrency, err)
+	}
+
+	s.ratesCache.SetPair(message.FromCurrency, message.ToCurrency, pairRate.Rate, pairRate.Bid, pairRate.Ask)
+	s.logger.Debugf("Updated cached rate %s -> %s = %.8f (source: %s)", message.FromCurrency, message.ToCurrency, pairRate.Rate, message.Source)
+	return nil
+}
+
+// ExchangeCurrency обменивает валюту. source определяет наценку к курсу
+// (storages.ExchangeSourceAPI, ExchangeSourceScheduled, ExchangeSourceAdmin).
+// Возвращает полученную сумму, комиссию в исходной валюте и новые балансы
+func (s *WalletService) ExchangeCurrency(ctx context.Context, userID int64, fromCurrency, toCurrency string, amount float64, source string) (float64, float64, storages.UserBalances, error) {
 	if amount <= 0 {
-		return 0, nil, fmt.Errorf("amount must be positive")
+		return 0, 0, nil, ErrInvalidAmount
+	}
+
+	fromCurrency, err := s.validateCurrency(ctx, fromCurrency)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	toCurrency, err = s.validateCurrency(ctx, toCurrency)
+	if err != nil {
+		return 0, 0, nil, err
 	}
 
 	if fromCurrency == toCurrency {
-		return 0, nil, fmt.Errorf("from_currency and to_currency must be different")
+		return 0, 0, nil, ErrSameCurrency
 	}
 
-	// Получаем курс обмена (из кеша или gRPC)
+	if err := s.checkNotFrozen(ctx, userID); err != nil {
+		return 0, 0, nil, err
+	}
+
+	// Получаем курс обмена (из кеша или gRPC). Пользователь продает fromCurrency,
+	// поэтому используется курс покупки exchanger (bid) или, по обратной паре,
+	// обратный курс продажи (1/ask)
 	var rate float32
-	var err error
 
 	// Пытаемся получить из кеша
-	rate, ok := s.ratesCache.GetRate(fromCurrency, toCurrency)
+	rate, ok := s.ratesCache.GetBidRate(fromCurrency, toCurrency)
 	if !ok {
 		// Получаем из gRPC сервиса
 		s.logger.Debugf("Fetching exchange rate from exchanger service: %s -> %s", fromCurrency, toCurrency)
-		rate, err = s.exchangerClient.GetExchangeRateForCurrency(ctx, fromCurrency, toCurrency)
+		rate, err = s.fetchExchangeRate(ctx, fromCurrency, toCurrency)
 		if err != nil {
-			return 0, nil, fmt.Errorf("failed to get exchange rate: %w", err)
+			// Устаревший курс - не сбой exchanger: обмен по нему запрещен
+			if errcodes.FromError(err) == errcodes.StaleRate {
+				return 0, 0, nil, fmt.Errorf("%w: %s -> %s: %w", ErrStaleRate, fromCurrency, toCurrency, err)
+			}
+			return 0, 0, nil, fmt.Errorf("%w: failed to get exchange rate: %w", ErrExchangerUnavailable, err)
 		}
 	} else {
 		s.logger.Debugf("Using cached exchange rate: %s -> %s = %.8f", fromCurrency, toCurrency, rate)
 	}
 
-	// Вычисляем сумму после обмена
-	exchangedAmount := float64(rate) * amount
-
-	// Выполняем обмен атомарно
-	if err := s.storage.ExecuteExchange(ctx, userID, fromCurrency, toCurrency, amount, exchangedAmount, float64(rate)); err != nil {
-		return 0, nil, fmt.Errorf("failed to execute exchange: %w", err)
+	// Применяем наценку для источника операции
+	quote, err := s.quote(source, float64(rate))
+	if err != nil {
+		return 0, 0, nil, err
 	}
 
-	// Отправляем уведомление в Kafka, если сумма большая
-	if err := s.kafkaProducer.SendLargeTransferNotification(ctx, userID, "exchange", fromCurrency, toCurrency, amount); err != nil {
-		s.logger.Warnf("Failed to send Kafka notification: %v", err)
+	// Вычисляем сумму после обмена
+	exchangedAmount := quote.Rate * amount
+
+	fee := s.calculateFee(storages.TransactionTypeExchange, fromCurrency, amount)
+
+	// Выполняем обмен атомарно вместе с проверкой лимитов, записью outbox,
+	// комиссией и доставками вебхуков
+	large := s.isLargeTransfer(ctx, fromCurrency, amount)
+	var txID int64
+	err = s.withinLimits(ctx, userID, storages.TransactionTypeExchange, fromCurrency, amount, func(ctx context.Context) error {
+		var err error
+		if txID, err = s.storage.ExecuteExchange(ctx, userID, fromCurrency, toCurrency, amount, exchangedAmount, quote, fee, large); err != nil {
+			return fmt.Errorf("failed to execute exchange: %w", err)
+		}
+		err = s.enqueueWebhookEvent(ctx, userID, storages.WebhookEventExchangeExecuted, webhook.ExchangeData{
+			TransactionID: txID,
+			FromCurrency:  fromCurrency,
+			ToCurrency:    toCurrency,
+			FromAmount:    amount,
+			ToAmount:      exchangedAmount,
+			Rate:          quote.Rate,
+			Fee:           fee,
+			Source:        source,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute exchange: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, nil, err
 	}
 
-	s.logger.Infof("Exchange completed: UserID=%d, %.2f %s -> %.2f %s (rate: %.8f)",
-		userID, amount, fromCurrency, exchangedAmount, toCurrency, rate)
+	s.logger.Infof("Exchange completed: UserID=%d, %.2f %s -> %.2f %s (rate: %.8f, market rate: %.8f, source: %s), Fee=%.2f %s, TxID=%d",
+		userID, amount, fromCurrency, exchangedAmount, toCurrency, quote.Rate, quote.MarketRate, source, fee, fromCurrency, txID)
 
 	// Получаем обновленные балансы
 	balances, err := s.GetUserBalances(ctx, userID)
 	if err != nil {
-		return exchangedAmount, nil, nil
+		return exchangedAmount, fee, nil, nil
+	}
+	s.publishBalances(ctx, userID, balances)
+
+	return exchangedAmount, fee, balances, nil
+}
+
+// fetchExchangeRates получает все курсы из exchanger
+func (s *WalletService) fetchExchangeRates(ctx context.Context) (*grpc.ExchangeRates, error) {
+	if s.exchangerClient == nil {
+		return nil, ErrExchangerUnavailable
+	}
+
+	rates, err := s.sharedFetch(ctx, "rates", func(ctx context.Context) (interface{}, error) {
+		s.logger.Debug("Fetching exchange rates from exchanger service")
+		return s.exchangerClient.GetExchangeRates(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates.(*grpc.ExchangeRates), nil
+}
+
+// fetchExchangeRate получает курс обмена from -> to для продающего fromCurrency из
+// exchanger, как GetBidRate кеша: bid прямой пары, а если exchanger не знает прямую
+// пару - 1/ask обратной. Без bid и ask используется средний курс. В демо-режиме
+// при недоступном exchanger возвращается демо-курс
+func (s *WalletService) fetchExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (float32, error) {
+	err := ErrExchangerUnavailable
+	if s.exchangerClient != nil {
+		var pairRate grpc.PairRate
+		pairRate, err = s.fetchPairRate(ctx, fromCurrency, toCurrency)
+		if err == nil {
+			if pairRate.Bid > 0 {
+				return pairRate.Bid, nil
+			}
+			return pairRate.Rate, nil
+		}
+
+		if errcodes.FromError(err) == errcodes.NotFound {
+			if inverse, inverseErr := s.fetchPairRate(ctx, toCurrency, fromCurrency); inverseErr == nil {
+				// Покупка toCurrency по обратной паре идет по курсу продажи exchanger
+				if inverse.Ask > 0 {
+					return 1 / inverse.Ask, nil
+				}
+				if inverse.Rate > 0 {
+					return 1 / inverse.Rate, nil
+				}
+			}
+		}
+	}
+
+	if demoRates, ok := s.demoRatesFallback(); ok {
+		if rate, ok := demoRates[fromCurrency+"_"+toCurrency]; ok {
+			s.logger.Warnf("Exchanger is unavailable, using demo rate %s -> %s: %v", fromCurrency, toCurrency, err)
+			return rate, nil
+		}
+	}
+	return 0, err
+}
+
+// fetchPairRate запрашивает курсы пары у exchanger одним вызовом для одновременных обменов
+func (s *WalletService) fetchPairRate(ctx context.Context, fromCurrency, toCurrency string) (grpc.PairRate, error) {
+	rate, err := s.sharedFetch(ctx, "rate:"+fromCurrency+"_"+toCurrency, func(ctx context.Context) (interface{}, error) {
+		return s.exchangerClient.GetExchangeRateForCurrency(ctx, fromCurrency, toCurrency)
+	})
+	if err != nil {
+		return grpc.PairRate{}, err
+	}
+	return rate.(grpc.PairRate), nil
+}
+
+// sharedFetch выполняет fetch один раз для всех одновременных вызовов с ключом key,
+// остальные получают тот же результат. Общий вызов не отменяется, если отменен
+// запрос первого вызывающего (таймаут задает клиент exchanger), а каждый
+// вызывающий перестает ждать по своему контексту
+func (s *WalletService) sharedFetch(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	result := s.ratesFlight.DoChan(key, func() (interface{}, error) {
+		return fetch(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case res := <-result:
+		if res.Shared {
+			s.logger.Debugf("Shared exchanger call result for %s", key)
+		}
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// quote рассчитывает курс обмена с наценкой. Без pricer наценка не применяется
+func (s *WalletService) quote(source string, marketRate float64) (storages.ExchangeQuote, error) {
+	if s.pricer == nil {
+		return pricing.NewPricer(nil).Quote(source, marketRate)
+	}
+	return s.pricer.Quote(source, marketRate)
+}
+
+// calculateFee рассчитывает комиссию за операцию. Без набора правил комиссия не взимается
+func (s *WalletService) calculateFee(operation, currency string, amount float64) float64 {
+	if s.fees == nil {
+		return 0
+	}
+	return s.fees.Calculate(operation, currency, amount)
+}
+
+// isLargeTransfer проверяет, нужно ли уведомление о переводе суммы amount в валюте currency.
+// Если для валюты задан свой порог, сумма сравнивается с ним, иначе пересчитывается
+// в опорную валюту порога по кешированному курсу.
+// Само уведомление пишется в outbox и отправляется outbox.Relay
+func (s *WalletService) isLargeTransfer(ctx context.Context, currency string, amount float64) bool {
+	if s.kafkaProducer == nil {
+		return false
+	}
+
+	if threshold, ok := s.kafkaProducer.CurrencyThreshold(currency); ok {
+		return amount >= threshold
+	}
+
+	return s.kafkaProducer.IsLargeTransfer(s.ReferenceAmount(ctx, currency, amount))
+}
+
+// ReferenceAmount пересчитывает сумму в опорную валюту порога крупных переводов.
+// Без курса возвращает сумму без пересчета, чтобы не пропустить уведомление
+func (s *WalletService) ReferenceAmount(ctx context.Context, currency string, amount float64) float64 {
+	if s.kafkaProducer == nil {
+		return amount
+	}
+
+	reference := s.kafkaProducer.ThresholdCurrency()
+	if reference == "" || currency == reference {
+		return amount
+	}
+
+	rate, ok := s.ratesCache.GetRate(currency, reference)
+	if !ok && s.exchangerClient != nil {
+		if _, err := s.GetExchangeRates(ctx); err == nil {
+			rate, ok = s.ratesCache.GetRate(currency, reference)
+		}
+	}
+	if !ok {
+		s.logger.Warnf("No %s -> %s rate for transfer threshold, comparing raw amount", currency, reference)
+		return amount
 	}
 
-	return exchangedAmount, balances, nil
+	return amount * float64(rate)
 }
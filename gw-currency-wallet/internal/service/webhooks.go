@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gw-currency-wallet/internal/storages"
+	"gw-currency-wallet/internal/webhook"
+)
+
+// MaxWebhooksPerUser максимальное число вебхуков пользователя
+const MaxWebhooksPerUser = 10
+
+// maxWebhookURLLength максимальная длина адреса вебхука
+const maxWebhookURLLength = 2048
+
+// webhookSecretPrefix начало всех секретов подписи вебхуков
+const webhookSecretPrefix = "whsec_"
+
+// Ограничения числа доставок в истории вебхука
+const (
+	DefaultWebhookDeliveriesLimit = 50
+	MaxWebhookDeliveriesLimit     = 200
+)
+
+// CreateWebhook регистрирует вебхук пользователя на события events (пустой
+// список - все события). Секрет подписи возвращается только здесь, в ответе
+// на создание
+func (s *WalletService) CreateWebhook(ctx context.Context, userID int64, rawURL string, events []string) (*storages.Webhook, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if err := validateWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.storage.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	if len(existing) >= MaxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: at most %d webhooks per user", ErrInvalidArgument, MaxWebhooksPerUser)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	hook := &storages.Webhook{
+		UserID: userID,
+		URL:    rawURL,
+		Secret: webhookSecretPrefix + hex.EncodeToString(buf),
+		Events: events,
+	}
+	if err := s.storage.CreateWebhook(ctx, hook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// ListWebhooks возвращает вебхуки пользователя
+func (s *WalletService) ListWebhooks(ctx context.Context, userID int64) ([]storages.Webhook, error) {
+	webhooks, err := s.storage.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с его доставками
+func (s *WalletService) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	if err := s.storage.DeleteWebhook(ctx, userID, webhookID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает последние доставки вебхука пользователя
+func (s *WalletService) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]storages.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveriesLimit
+	}
+	if limit > MaxWebhookDeliveriesLimit {
+		limit = MaxWebhookDeliveriesLimit
+	}
+
+	deliveries, err := s.storage.ListWebhookDeliveries(ctx, userID, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// enqueueWebhookEvent создает доставки события вебхукам пользователя. Вызывается
+// в транзакции операции, поэтому событие не теряется и не появляется без нее
+func (s *WalletService) enqueueWebhookEvent(ctx context.Context, userID int64, eventType string, data interface{}) error {
+	event := webhook.Event{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	enqueued, err := s.storage.EnqueueWebhookEvent(ctx, storages.WebhookEvent{
+		ID:        event.ID,
+		UserID:    userID,
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if enqueued > 0 {
+		s.logger.Debugf("Enqueued webhook event %s (%s) for user %d: %d deliveries", event.ID, eventType, userID, enqueued)
+	}
+	return nil
+}
+
+// validateWebhookURL проверяет, что адрес вебхука - абсолютный http(s) URL
+func validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("%w: webhook url is required", ErrInvalidArgument)
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return fmt.Errorf("%w: webhook url must not exceed %d characters", ErrInvalidArgument, maxWebhookURLLength)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: webhook url must be an absolute http or https url", ErrInvalidArgument)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: webhook url must not contain credentials", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// normalizeWebhookEvents проверяет типы событий и убирает повторы; пустой
+// список заменяется всеми типами событий
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return append([]string(nil), storages.WebhookEventTypes...), nil
+	}
+
+	seen := make(map[string]bool, len(events))
+	normalized := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !isWebhookEventType(event) {
+			return nil, fmt.Errorf("%w: unknown webhook event %q", ErrInvalidArgument, event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
+
+// isWebhookEventType проверяет, что событие - один из storages.WebhookEventTypes
+func isWebhookEventType(event string) bool {
+	for _, eventType := range storages.WebhookEventTypes {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// WithdrawalApproval параметры вывода с подтверждением, см. SetWithdrawalApproval
+type WithdrawalApproval struct {
+	// Required выводы через API ожидают подтверждения, сумма с комиссией удерживается
+	Required bool
+	// AutoApproveAfter ожидающие дольше выводы подтверждаются автоматически, 0 - только администратором
+	AutoApproveAfter time.Duration
+	// AutoApproveMaxAmount автоматически подтверждаются выводы на сумму не больше
+	// (в валюте вывода), 0 - любые
+	AutoApproveMaxAmount float64
+}
+
+// SetWithdrawalApproval включает вывод с подтверждением
+func (s *WalletService) SetWithdrawalApproval(approval WithdrawalApproval) {
+	s.withdrawalApproval = approval
+	if approval.Required {
+		s.logger.Infof("Withdrawal approval is required (auto approve after: %v, max amount: %.2f)",
+			approval.AutoApproveAfter, approval.AutoApproveMaxAmount)
+	}
+}
+
+// WithdrawalApprovalRequired сообщает, ожидают ли выводы через API подтверждения
+func (s *WalletService) WithdrawalApprovalRequired() bool {
+	return s.withdrawalApproval.Required
+}
+
+// GetHeldBalances возвращает суммы, удержанные ожидающими выводами, по валютам
+// с ненулевым удержанием
+func (s *WalletService) GetHeldBalances(ctx context.Context, userID int64) (storages.UserBalances, error) {
+	balances, err := s.storage.GetAllBalances(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	held := make(storages.UserBalances)
+	for _, balance := range balances {
+		if balance.Held > 0 {
+			held[balance.Currency] = balance.Held
+		}
+	}
+
+	return held, nil
+}
+
+// RequestWithdraw создает ожидающий подтверждения вывод: сумма с комиссией
+// удерживается на балансе и недоступна для других списаний, пока вывод
+// не подтвержден или не отменен. Ожидающие выводы учитываются в лимитах
+func (s *WalletService) RequestWithdraw(ctx context.Context, userID int64, currency string, amount float64) (*storages.Transaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	currency, err := s.validateCurrency(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNotFrozen(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	fee := s.calculateFee(storages.TransactionTypeWithdraw, currency, amount)
+
+	// Ожидающий вывод учитывается в лимитах, поэтому создается в одной транзакции с их проверкой
+	var txID int64
+	err = s.withinLimits(ctx, userID, storages.TransactionTypeWithdraw, currency, amount, func(ctx context.Context) error {
+		var err error
+		if txID, err = s.storage.CreatePendingWithdraw(ctx, userID, currency, amount, fee); err != nil {
+			return fmt.Errorf("failed to withdraw: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawal, err := s.storage.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	s.logger.Infof("Withdrawal pending approval: UserID=%d, Amount=%.2f %s, Fee=%.2f, TxID=%d", userID, amount, currency, fee, txID)
+	return withdrawal, nil
+}
+
+// ListPendingWithdrawals возвращает до limit ожидающих выводов от старых к новым,
+// userID 0 - всех пользователей
+func (s *WalletService) ListPendingWithdrawals(ctx context.Context, userID int64, limit int) ([]storages.Transaction, error) {
+	withdrawals, err := s.storage.ListPendingWithdrawals(ctx, storages.PendingWithdrawalFilter{UserID: userID, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending withdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
+
+// CancelWithdrawal отменяет ожидающий вывод пользователя и снимает удержание
+func (s *WalletService) CancelWithdrawal(ctx context.Context, userID, txID int64) (*storages.Transaction, error) {
+	withdrawal, err := s.storage.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if withdrawal.UserID != userID {
+		return nil, fmt.Errorf("pending withdrawal %d %w", txID, ErrNotFound)
+	}
+
+	withdrawal, err = s.storage.CancelWithdraw(ctx, txID, storages.TransactionStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel withdrawal: %w", err)
+	}
+	return withdrawal, nil
+}
+
+// ApproveWithdrawal подтверждает ожидающий вывод: удержание снимается, сумма
+// и комиссия списываются с баланса. Вывод замороженного пользователя остается
+// ожидающим до снятия заморозки
+func (s *WalletService) ApproveWithdrawal(ctx context.Context, txID int64) (*storages.Transaction, error) {
+	pending, err := s.storage.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	if err := s.checkNotFrozen(ctx, pending.UserID); err != nil {
+		return nil, err
+	}
+
+	notify := s.isLargeTransfer(ctx, pending.FromCurrency, pending.FromAmount)
+	withdrawal, err := s.storage.CompleteWithdraw(ctx, txID, notify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve withdrawal: %w", err)
+	}
+
+	s.logger.Infof("Withdrawal approved: UserID=%d, Amount=%.2f %s, Fee=%.2f, TxID=%d",
+		withdrawal.UserID, withdrawal.FromAmount, withdrawal.FromCurrency, withdrawal.Fee, txID)
+
+	if balances, err := s.GetUserBalances(ctx, withdrawal.UserID); err == nil {
+		s.publishBalances(ctx, withdrawal.UserID, balances)
+	}
+	return withdrawal, nil
+}
+
+// RejectWithdrawal отклоняет ожидающий вывод (статус failed) и снимает удержание
+func (s *WalletService) RejectWithdrawal(ctx context.Context, txID int64) (*storages.Transaction, error) {
+	withdrawal, err := s.storage.CancelWithdraw(ctx, txID, storages.TransactionStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject withdrawal: %w", err)
+	}
+	return withdrawal, nil
+}
+
+// AutoApproveWithdrawals подтверждает до limit выводов, ожидающих к now дольше
+// AutoApproveAfter, и возвращает число подтвержденных. Выводы, обработанные
+// параллельно администратором или другим экземпляром кошелька, и выводы
+// замороженных и закрытых пользователей пропускаются
+func (s *WalletService) AutoApproveWithdrawals(ctx context.Context, now time.Time, limit int) (int, error) {
+	if !s.withdrawalApproval.Required || s.withdrawalApproval.AutoApproveAfter <= 0 {
+		return 0, nil
+	}
+
+	withdrawals, err := s.storage.ListPendingWithdrawals(ctx, storages.PendingWithdrawalFilter{
+		CreatedBefore: now.Add(-s.withdrawalApproval.AutoApproveAfter),
+		MaxAmount:     s.withdrawalApproval.AutoApproveMaxAmount,
+		Limit:         limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending withdrawals: %w", err)
+	}
+
+	var approved int
+	var failed []string
+	for _, withdrawal := range withdrawals {
+		if ctx.Err() != nil {
+			return approved, ctx.Err()
+		}
+		if _, err := s.ApproveWithdrawal(ctx, withdrawal.ID); err != nil {
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrAccountFrozen) || errors.Is(err, ErrAccountClosed) {
+				continue
+			}
+			failed = append(failed, fmt.Sprintf("%d: %v", withdrawal.ID, err))
+			continue
+		}
+		approved++
+	}
+
+	if len(failed) > 0 {
+		return approved, fmt.Errorf("failed to approve withdrawals: %s", strings.Join(failed, "; "))
+	}
+	return approved, nil
+}
@@ -0,0 +1,88 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StageFunc останавливает компонент. ctx истекает по таймауту этапа
+type StageFunc func(ctx context.Context) error
+
+// stage этап остановки
+type stage struct {
+	name    string
+	timeout time.Duration
+	run     StageFunc
+}
+
+// Coordinator останавливает компоненты сервиса по этапам в порядке добавления:
+// каждый следующий этап начинается, когда предыдущий завершился или истек его
+// таймаут. Зависимые компоненты добавляются раньше тех, от которых они зависят
+// (HTTP сервер - раньше Kafka producer, Kafka producer - раньше хранилища)
+type Coordinator struct {
+	stages []stage
+	logger *logrus.Logger
+}
+
+// New создает координатор остановки
+func New(logger *logrus.Logger) *Coordinator {
+	return &Coordinator{logger: logger}
+}
+
+// Add добавляет этап. timeout 0 - этап не ограничен по времени
+func (c *Coordinator) Add(name string, timeout time.Duration, run StageFunc) {
+	c.stages = append(c.stages, stage{name: name, timeout: timeout, run: run})
+}
+
+// Shutdown выполняет этапы по порядку. Ошибка или истекший таймаут этапа не
+// прерывают остановку: оставшиеся этапы выполняются, ошибки возвращаются вместе
+func (c *Coordinator) Shutdown() error {
+	var errs []error
+	for _, s := range c.stages {
+		if err := c.runStage(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runStage выполняет этап и пишет в лог его длительность и результат
+func (c *Coordinator) runStage(s stage) error {
+	ctx := context.Background()
+	cancel := func() {}
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+	defer cancel()
+
+	c.logger.Infof("Shutdown: stopping %s...", s.name)
+	start := time.Now()
+
+	// Этап, не соблюдающий ctx, не задерживает остановку дольше таймаута
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		c.logger.Errorf("Shutdown: %s did not stop within %v", s.name, s.timeout)
+	case err != nil:
+		c.logger.Errorf("Shutdown: %s stopped with error after %v: %v", s.name, elapsed, err)
+	default:
+		c.logger.Infof("Shutdown: %s stopped in %v", s.name, elapsed)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Виды строк CSV с балансами на начало и конец периода
+const (
+	csvOpeningBalance = "opening_balance"
+	csvClosingBalance = "closing_balance"
+)
+
+// csvHeader столбцы выписки. Строки opening_balance и closing_balance содержат
+// только валюту и баланс
+var csvHeader = []string{"time", "transaction_id", "type", "currency", "amount", "balance"}
+
+// csvWriter выписка в CSV: балансы на начало периода, движения, балансы на конец
+type csvWriter struct {
+	out io.Writer
+	w   *csv.Writer
+	to  time.Time
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{out: w, w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteHeader(header Header) error {
+	c.to = header.To.AddDate(0, 0, 1)
+	if err := c.w.Write(csvHeader); err != nil {
+		return err
+	}
+	c.writeBalances(csvOpeningBalance, header.From, header.Opening)
+	return c.flush()
+}
+
+func (c *csvWriter) WriteLines(lines []Line) error {
+	for _, line := range lines {
+		var transactionID string
+		if line.TransactionID != 0 {
+			transactionID = strconv.FormatInt(line.TransactionID, 10)
+		}
+		c.w.Write([]string{
+			line.Time.UTC().Format(time.RFC3339),
+			transactionID,
+			line.Type,
+			line.Currency,
+			formatAmount(line.Amount),
+			formatAmount(line.Balance),
+		})
+	}
+	return c.flush()
+}
+
+func (c *csvWriter) Close(closing map[string]float64) error {
+	c.writeBalances(csvClosingBalance, c.to, closing)
+	return c.flush()
+}
+
+// writeBalances записывает строки балансов на момент at
+func (c *csvWriter) writeBalances(kind string, at time.Time, balances map[string]float64) {
+	for _, currency := range currencies(balances) {
+		c.w.Write([]string{at.UTC().Format(time.RFC3339), "", kind, currency, "", formatAmount(balances[currency])})
+	}
+}
+
+// flush отправляет буфер csv.Writer и возвращает первую ошибку записи
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return flush(c.out)
+}
@@ -0,0 +1,291 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Размеры страницы A4 и разметка, в пунктах
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfTop        = pdfPageHeight - pdfMargin
+	pdfBottom     = pdfMargin + 20
+	pdfFontSize   = 9
+	pdfLineHeight = 13
+)
+
+// Номера объектов, известные до записи страниц: каталог и дерево страниц
+// записываются последними, шрифты - в начале документа
+const (
+	pdfCatalogObject = iota + 1
+	pdfPagesObject
+	pdfFontObject
+	pdfBoldFontObject
+)
+
+// pdfColumn столбец таблицы движений: x - левая граница или, для сумм, правая
+type pdfColumn struct {
+	title string
+	x     float64
+	right bool
+}
+
+var pdfColumns = []pdfColumn{
+	{title: "Time (UTC)", x: pdfMargin},
+	{title: "Transaction", x: 155},
+	{title: "Type", x: 225},
+	{title: "Currency", x: 290},
+	{title: "Amount", x: 450, right: true},
+	{title: "Balance", x: pdfPageWidth - pdfMargin, right: true},
+}
+
+// pdfWriter выписка в PDF со стандартными шрифтами Helvetica. Заполненные страницы
+// сразу записываются в w, поэтому в памяти хранится только текущая страница;
+// дерево страниц и таблица ссылок записываются в Close
+type pdfWriter struct {
+	out     io.Writer
+	written int64
+	err     error
+
+	// offsets смещения объектов по номеру - 1
+	offsets []int64
+	pages   []int
+
+	page  bytes.Buffer
+	y     float64
+	lines int
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{out: w, offsets: make([]int64, pdfBoldFontObject)}
+}
+
+func (p *pdfWriter) WriteHeader(header Header) error {
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.writeObject(pdfFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.writeObject(pdfBoldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	p.startPage()
+	p.text(pdfMargin, p.y, true, 14, "Account statement")
+	p.y -= 2 * pdfLineHeight
+	p.textLine(fmt.Sprintf("User: %s (ID %d)", header.Username, header.UserID))
+	p.textLine(fmt.Sprintf("Period: %s - %s", header.From.Format(DateLayout), header.To.Format(DateLayout)))
+	p.textLine("Generated: " + header.GeneratedAt.UTC().Format(time.RFC3339))
+	p.y -= pdfLineHeight
+	p.writeBalances("Opening balances", header.Opening)
+	p.y -= pdfLineHeight
+	p.tableHeader()
+
+	return p.flush()
+}
+
+func (p *pdfWriter) WriteLines(lines []Line) error {
+	for _, line := range lines {
+		p.ensureSpace(true)
+		var transactionID string
+		if line.TransactionID != 0 {
+			transactionID = strconv.FormatInt(line.TransactionID, 10)
+		}
+		values := []string{
+			line.Time.UTC().Format("2006-01-02 15:04:05"),
+			transactionID,
+			line.Type,
+			line.Currency,
+			formatAmount(line.Amount),
+			formatAmount(line.Balance),
+		}
+		p.row(false, values)
+		p.lines++
+	}
+	return p.flush()
+}
+
+func (p *pdfWriter) Close(closing map[string]float64) error {
+	if p.lines == 0 {
+		p.ensureSpace(true)
+		p.textLine("No transactions in the period")
+	}
+	p.y -= pdfLineHeight
+	p.ensureSpace(false)
+	p.writeBalances("Closing balances", closing)
+	p.finishPage()
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.writeObject(pdfPagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	p.writeObject(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+
+	xref := p.written
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1))
+	for _, offset := range p.offsets {
+		p.write(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, pdfCatalogObject, xref))
+
+	return p.flush()
+}
+
+// writeBalances записывает заголовок и балансы по валютам
+func (p *pdfWriter) writeBalances(title string, balances map[string]float64) {
+	p.text(pdfMargin, p.y, true, pdfFontSize, title)
+	p.y -= pdfLineHeight
+	if len(balances) == 0 {
+		p.textLine("-")
+	}
+	for _, currency := range currencies(balances) {
+		p.ensureSpace(false)
+		p.text(pdfMargin, p.y, false, pdfFontSize, currency)
+		p.textRight(pdfColumns[4].x, p.y, false, formatAmount(balances[currency]))
+		p.y -= pdfLineHeight
+	}
+}
+
+// ensureSpace начинает новую страницу, если на текущей не осталось места для строки.
+// Таблица движений продолжается на новой странице с заголовком столбцов
+func (p *pdfWriter) ensureSpace(table bool) {
+	if p.y >= pdfBottom {
+		return
+	}
+	p.finishPage()
+	p.startPage()
+	if table {
+		p.tableHeader()
+	}
+}
+
+// tableHeader записывает заголовки столбцов и линию под ними
+func (p *pdfWriter) tableHeader() {
+	titles := make([]string, len(pdfColumns))
+	for i, column := range pdfColumns {
+		titles[i] = column.title
+	}
+	p.row(true, titles)
+	fmt.Fprintf(&p.page, "%d %.2f m %d %.2f l S\n", pdfMargin, p.y+pdfLineHeight-3, pdfPageWidth-pdfMargin, p.y+pdfLineHeight-3)
+}
+
+// row записывает строку таблицы
+func (p *pdfWriter) row(bold bool, values []string) {
+	for i, column := range pdfColumns {
+		if column.right {
+			p.textRight(column.x, p.y, bold, values[i])
+		} else {
+			p.text(column.x, p.y, bold, pdfFontSize, values[i])
+		}
+	}
+	p.y -= pdfLineHeight
+}
+
+// textLine записывает строку текста от левого поля
+func (p *pdfWriter) textLine(s string) {
+	p.text(pdfMargin, p.y, false, pdfFontSize, s)
+	p.y -= pdfLineHeight
+}
+
+// textRight записывает текст, выровненный по правой границе right
+func (p *pdfWriter) textRight(right, y float64, bold bool, s string) {
+	p.text(right-textWidth(s, pdfFontSize), y, bold, pdfFontSize, s)
+}
+
+func (p *pdfWriter) text(x, y float64, bold bool, size int, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.page, "BT /%s %d Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapeText(s))
+}
+
+func (p *pdfWriter) startPage() {
+	p.page.Reset()
+	p.y = pdfTop
+}
+
+// finishPage записывает содержимое и объект текущей страницы с номером внизу
+func (p *pdfWriter) finishPage() {
+	number := strconv.Itoa(len(p.pages) + 1)
+	p.textRight(pdfPageWidth-pdfMargin, pdfMargin-15, false, "Page "+number)
+
+	content := p.newObject()
+	p.writeObject(content, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.page.Len(), p.page.String()))
+
+	page := p.newObject()
+	p.writeObject(page, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, pdfBoldFontObject, content,
+	))
+	p.pages = append(p.pages, page)
+	p.page.Reset()
+}
+
+// newObject резервирует номер следующего объекта
+func (p *pdfWriter) newObject() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+// writeObject записывает объект и запоминает его смещение для таблицы ссылок
+func (p *pdfWriter) writeObject(number int, body string) {
+	p.offsets[number-1] = p.written
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", number, body))
+}
+
+// write записывает s в w; после первой ошибки запись прекращается
+func (p *pdfWriter) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := io.WriteString(p.out, s)
+	p.written += int64(n)
+	p.err = err
+}
+
+func (p *pdfWriter) flush() error {
+	if p.err != nil {
+		return p.err
+	}
+	return flush(p.out)
+}
+
+// escapeText экранирует строку PDF. Стандартные шрифты не содержат кириллицы,
+// поэтому символы вне ASCII заменяются на ?
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > '~':
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// textWidth возвращает ширину строки в Helvetica. Ширины заданы для символов сумм
+// и номеров страниц, остальные считаются средней шириной
+func textWidth(s string, size int) float64 {
+	var width int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			width += 556
+		case r == '.' || r == ',' || r == ' ':
+			width += 278
+		case r == '-':
+			width += 333
+		default:
+			width += 556
+		}
+	}
+	return float64(width*size) / 1000
+}
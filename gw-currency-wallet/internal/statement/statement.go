@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Форматы выписки
+const (
+	FormatCSV = "csv"
+	FormatPDF = "pdf"
+)
+
+// DateLayout формат дат периода выписки
+const DateLayout = "2006-01-02"
+
+// Header заголовок выписки
+type Header struct {
+	UserID   int64
+	Username string
+	// From и To первый и последний день периода (UTC) включительно
+	From time.Time
+	To   time.Time
+	// Opening балансы на начало периода
+	Opening     map[string]float64
+	GeneratedAt time.Time
+}
+
+// Line движение по балансу и остаток валюты после него
+type Line struct {
+	Time time.Time
+	// TransactionID транзакция движения, 0 - движение без транзакции
+	TransactionID int64
+	// Type вид движения: opening, deposit, withdraw, exchange, fee
+	Type     string
+	Currency string
+	Amount   float64
+	Balance  float64
+}
+
+// Writer записывает выписку по частям: заголовок, строки движений пачками и
+// балансы на конец периода. После каждой части записанное отправляется клиенту,
+// если w реализует Flush
+type Writer interface {
+	WriteHeader(header Header) error
+	WriteLines(lines []Line) error
+	Close(closing map[string]float64) error
+}
+
+// flusher отправляет буферизованные данные, например http.ResponseController
+type flusher interface {
+	Flush() error
+}
+
+// New создает запись выписки в формате format
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatPDF:
+		return newPDFWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported statement format: %s", format)
+	}
+}
+
+// ContentType возвращает MIME-тип формата
+func ContentType(format string) string {
+	if format == FormatPDF {
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// flush отправляет записанное клиенту, если w это поддерживает
+func flush(w io.Writer) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// formatAmount форматирует сумму с точностью хранения в БД без лишних нулей
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// currencies возвращает коды валют балансов по алфавиту
+func currencies(balances map[string]float64) []string {
+	codes := make([]string, 0, len(balances))
+	for code := range balances {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
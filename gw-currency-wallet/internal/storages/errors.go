@@ -0,0 +1,16 @@
+package storages
+
+import "errors"
+
+// Ошибки хранилищ. Реализации возвращают их обернутыми через %w,
+// вызывающий код проверяет их через errors.Is
+var (
+	// ErrNotFound запись не найдена
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate запись нарушает ограничение уникальности
+	ErrDuplicate = errors.New("already exists")
+	// ErrInsufficientFunds недостаточно средств на балансе для списания
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrBalanceNotEmpty на балансах пользователя остались средства
+	ErrBalanceNotEmpty = errors.New("balance is not empty")
+)
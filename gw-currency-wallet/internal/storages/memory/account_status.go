@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// SetUserStatus изменяет статус учетной записи, если он не менялся в change.ChangedAt
+// или позже, и записывает изменение в историю в той же операции
+func (s *MemoryStorage) SetUserStatus(ctx context.Context, userID int64, change storages.UserStatusChange) (bool, error) {
+	var applied bool
+	err := s.write(ctx, func() error {
+		user, err := s.userLocked(userID)
+		if err != nil {
+			return err
+		}
+
+		// Статус уже изменен более поздней операцией
+		if user.StatusChangedAt != nil && !user.StatusChangedAt.Before(change.ChangedAt) {
+			return nil
+		}
+
+		previous := user.Status
+		changedAt := change.ChangedAt
+		user.Status = change.Status
+		user.FrozenUntil = change.FrozenUntil
+		user.StatusReason = change.Reason
+		user.StatusChangedAt = &changedAt
+		user.UpdatedAt = time.Now()
+		set(s, s.users, userID, user)
+
+		push(s, &s.statusHistory, storages.AccountStatusEvent{
+			ID:             s.newID("account_status_history"),
+			UserID:         userID,
+			Status:         change.Status,
+			PreviousStatus: previous,
+			FrozenUntil:    change.FrozenUntil,
+			Reason:         change.Reason,
+			ChangedBy:      change.ChangedBy,
+			CreatedAt:      change.ChangedAt,
+		})
+
+		applied = true
+		s.logger.Infof("Updated status for user %d: %s -> %s", userID, previous, change.Status)
+		return nil
+	})
+	return applied, err
+}
+
+// GetAccountStatusHistory возвращает до limit последних изменений статуса пользователя
+func (s *MemoryStorage) GetAccountStatusHistory(ctx context.Context, userID int64, limit int) ([]storages.AccountStatusEvent, error) {
+	defer s.lock(ctx)()
+
+	events := make([]storages.AccountStatusEvent, 0)
+	for i := len(s.statusHistory) - 1; i >= 0 && (limit < 0 || len(events) < limit); i-- {
+		if s.statusHistory[i].UserID == userID {
+			events = append(events, s.statusHistory[i])
+		}
+	}
+	return events, nil
+}
+
+// DeleteUser закрывает учетную запись с нулевыми балансами и обезличивает
+// персональные данные пользователя. Транзакции и журнал не изменяются
+func (s *MemoryStorage) DeleteUser(ctx context.Context, userID int64, deletion storages.UserDeletion) error {
+	return s.write(ctx, func() error {
+		// 1. Получаем пользователя, еще не удалившего учетную запись
+		user, ok := s.users[userID]
+		if !ok || user.DeletedAt != nil {
+			return fmt.Errorf("user %w", storages.ErrNotFound)
+		}
+
+		// 2. Проверяем, что на балансах и в удержании не осталось средств
+		var remaining []string
+		for key, balance := range s.balances {
+			if key.userID == userID && (balance.Amount != 0 || balance.Held != 0) {
+				remaining = append(remaining, balance.Currency+" "+strconv.FormatFloat(balance.Amount, 'f', -1, 64))
+			}
+		}
+		if len(remaining) > 0 {
+			sort.Strings(remaining)
+			return fmt.Errorf("%w: %s", storages.ErrBalanceNotEmpty, strings.Join(remaining, ", "))
+		}
+
+		// 3. Обезличиваем пользователя и закрываем учетную запись. Пустой хеш
+		// пароля не совпадает ни с одним паролем
+		for id, other := range s.users {
+			if id != userID && (other.Username == deletion.Username || other.Email == deletion.Email) {
+				return fmt.Errorf("anonymized user %w", storages.ErrDuplicate)
+			}
+		}
+
+		previous := user.Status
+		deletedAt := deletion.DeletedAt
+		user.Username = deletion.Username
+		user.Email = deletion.Email
+		user.PasswordHash = ""
+		user.Status = storages.AccountStatusClosed
+		user.FrozenUntil = nil
+		user.StatusReason = deletion.Reason
+		user.StatusChangedAt = &deletedAt
+		user.DeletedAt = &deletedAt
+		user.UpdatedAt = deletedAt
+		set(s, s.users, userID, user)
+
+		push(s, &s.statusHistory, storages.AccountStatusEvent{
+			ID:             s.newID("account_status_history"),
+			UserID:         userID,
+			Status:         storages.AccountStatusClosed,
+			PreviousStatus: previous,
+			Reason:         deletion.Reason,
+			ChangedBy:      userID,
+			CreatedAt:      deletion.DeletedAt,
+		})
+
+		// 4. Отзываем ключи API и сессии, стираем данные заявок на верификацию;
+		// заявка на рассмотрении отклоняется
+		for id, key := range s.apiKeys {
+			if key.UserID == userID && key.RevokedAt == nil {
+				key.RevokedAt = &deletedAt
+				set(s, s.apiKeys, id, key)
+			}
+		}
+
+		for id, session := range s.sessions {
+			if session.UserID == userID && session.RevokedAt == nil {
+				session.RevokedAt = &deletedAt
+				set(s, s.sessions, id, session)
+			}
+		}
+
+		for id, request := range s.verificationRequests {
+			if request.UserID != userID {
+				continue
+			}
+			request.FullName = ""
+			request.DateOfBirth = ""
+			request.DocumentNumber = ""
+			if request.Status == storages.VerificationStatusPending {
+				request.Status = storages.VerificationStatusRejected
+				request.ReviewComment = deletion.Reason
+			}
+			if request.ReviewedAt == nil {
+				request.ReviewedAt = &deletedAt
+			}
+			set(s, s.verificationRequests, id, request)
+		}
+
+		s.logger.Infof("Deleted user %d: %s -> %s", userID, previous, storages.AccountStatusClosed)
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateAPIKey сохраняет ключ API
+func (s *MemoryStorage) CreateAPIKey(ctx context.Context, key *storages.APIKey) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(key.UserID); err != nil {
+			return err
+		}
+		for _, existing := range s.apiKeys {
+			if existing.KeyHash == key.KeyHash {
+				return fmt.Errorf("API key %w", storages.ErrDuplicate)
+			}
+		}
+
+		key.ID = s.newID("api_keys")
+		key.CreatedAt = time.Now()
+		key.RevokedAt = nil
+		stored := *key
+		stored.Scopes = slices.Clone(key.Scopes)
+		set(s, s.apiKeys, key.ID, stored)
+
+		s.logger.Infof("Created API key %d (%s) for user %d", key.ID, key.Prefix, key.UserID)
+		return nil
+	})
+}
+
+// GetAPIKeyByHash возвращает действующий ключ API по SHA-256
+func (s *MemoryStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*storages.APIKey, error) {
+	defer s.lock(ctx)()
+
+	for _, key := range s.apiKeys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil {
+			key.Scopes = slices.Clone(key.Scopes)
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("API key %w", storages.ErrNotFound)
+}
+
+// ListAPIKeys возвращает действующие ключи API пользователя
+func (s *MemoryStorage) ListAPIKeys(ctx context.Context, userID int64) ([]storages.APIKey, error) {
+	defer s.lock(ctx)()
+
+	keys := []storages.APIKey{}
+	for _, key := range s.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			key.Scopes = slices.Clone(key.Scopes)
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает действующий ключ API пользователя
+func (s *MemoryStorage) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	now := time.Now()
+	err := s.updateAPIKey(ctx, userID, keyID, func(key *storages.APIKey) {
+		key.RevokedAt = &now
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Revoked API key %d of user %d", keyID, userID)
+	return nil
+}
+
+// SetAPIKeyRateLimit задает лимит запросов действующего ключа API пользователя
+func (s *MemoryStorage) SetAPIKeyRateLimit(ctx context.Context, userID, keyID int64, rate float64, burst int) error {
+	if rate < 0 || burst < 0 {
+		return fmt.Errorf("failed to update API key: negative rate limit")
+	}
+
+	return s.updateAPIKey(ctx, userID, keyID, func(key *storages.APIKey) {
+		key.RateLimit = rate
+		key.RateBurst = burst
+	})
+}
+
+// updateAPIKey изменяет действующий ключ API пользователя; ErrNotFound, если ключ не найден или отозван
+func (s *MemoryStorage) updateAPIKey(ctx context.Context, userID, keyID int64, update func(key *storages.APIKey)) error {
+	return s.write(ctx, func() error {
+		key, ok := s.apiKeys[keyID]
+		if !ok || key.UserID != userID || key.RevokedAt != nil {
+			return fmt.Errorf("API key %w", storages.ErrNotFound)
+		}
+
+		update(&key)
+		set(s, s.apiKeys, keyID, key)
+		return nil
+	})
+}
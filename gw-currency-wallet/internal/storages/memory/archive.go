@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// unsentOutboxLocked возвращает ID транзакций с неотправленными записями outbox.
+// Вызывается под s.mu
+func (s *MemoryStorage) unsentOutboxLocked() map[int64]bool {
+	unsent := make(map[int64]bool)
+	for _, entry := range s.outbox {
+		if entry.SentAt == nil {
+			unsent[entry.Transaction.ID] = true
+		}
+	}
+	return unsent
+}
+
+// ListArchivableTransactions возвращает транзакции, которые можно выгрузить в архив:
+// не ожидающие подтверждения и без неотправленных уведомлений
+func (s *MemoryStorage) ListArchivableTransactions(ctx context.Context, before time.Time, afterID int64, limit int) ([]storages.Transaction, error) {
+	defer s.lock(ctx)()
+
+	unsent := s.unsentOutboxLocked()
+	transactions := make([]storages.Transaction, 0)
+	for _, tx := range s.transactions {
+		if tx.Status != storages.TransactionStatusPending && !unsent[tx.ID] && tx.ID > afterID && tx.CreatedAt.Before(before) {
+			transactions = append(transactions, tx)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+
+	return head(transactions, limit), nil
+}
+
+// DeleteArchivedTransactions удаляет выгруженные транзакции вместе с их записями outbox.
+// Перенос сумм в archivedTotals и удаление выполняются в одной операции
+func (s *MemoryStorage) DeleteArchivedTransactions(ctx context.Context, ids []int64) (int64, error) {
+	var deleted int64
+	err := s.write(ctx, func() error {
+		unsent := s.unsentOutboxLocked()
+		totals := make(map[balanceKey]float64)
+		archived := make(map[int64]bool)
+
+		for _, id := range ids {
+			tx, ok := s.transactions[id]
+			if !ok || archived[id] || tx.Status == storages.TransactionStatusPending || unsent[id] {
+				continue
+			}
+			if tx.Status == storages.TransactionStatusCompleted {
+				addCompletedDeltas(totals, &tx)
+			}
+			archived[id] = true
+			remove(s, s.transactions, id)
+			deleted++
+		}
+
+		for key, amount := range totals {
+			set(s, s.archivedTotals, key, s.archivedTotals[key]+amount)
+		}
+		for id, entry := range s.outbox {
+			if archived[entry.Transaction.ID] {
+				remove(s, s.outbox, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Deleted %d archived transactions", deleted)
+	return deleted, nil
+}
@@ -0,0 +1,374 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// ledgerTolerance допустимое расхождение баланса и суммы транзакций,
+// возникающее из-за округления float64 при обновлении балансов
+const ledgerTolerance = 0.000001
+
+// insertEntryLocked добавляет запись журнала. txID 0 - запись без транзакции. Вызывается под s.mu
+func (s *MemoryStorage) insertEntryLocked(userID int64, currency string, amount float64, txID int64, kind string) {
+	entry := storages.LedgerEntry{
+		ID:        s.newID("ledger_entries"),
+		UserID:    userID,
+		Currency:  currency,
+		Amount:    amount,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	}
+	if txID != 0 {
+		entry.TransactionID = &txID
+	}
+	push(s, &s.ledger, entry)
+}
+
+// applyEntryLocked добавляет запись журнала и изменяет баланс-агрегат на ее сумму.
+// Баланса может не быть, если валюта добавлена после регистрации пользователя:
+// тогда он создается. Баланс не может стать отрицательным. Вызывается под s.mu
+func (s *MemoryStorage) applyEntryLocked(userID int64, currency string, amount float64, txID int64, kind string) error {
+	now := time.Now()
+	key := balanceKey{userID, currency}
+	balance, ok := s.balances[key]
+	if !ok {
+		balance = storages.Balance{ID: s.newID("balances"), UserID: userID, Currency: currency, CreatedAt: now}
+	}
+
+	balance.Amount += amount
+	balance.UpdatedAt = now
+	if balance.Amount < 0 {
+		return fmt.Errorf("failed to update balance: %s balance would become negative", currency)
+	}
+
+	s.insertEntryLocked(userID, currency, amount, txID, kind)
+	set(s, s.balances, key, balance)
+	return nil
+}
+
+// FindLedgerViolations проверяет инварианты учета по всем пользователям
+func (s *MemoryStorage) FindLedgerViolations(ctx context.Context) ([]storages.LedgerViolation, error) {
+	defer s.lock(ctx)()
+
+	var violations []storages.LedgerViolation
+	checks := []func() []storages.LedgerViolation{
+		s.findBalanceMismatchesLocked,
+		s.findNegativeBalancesLocked,
+		s.findOrphanTransactionsLocked,
+		s.findBalanceDriftLocked,
+		s.findHoldMismatchesLocked,
+	}
+	for _, check := range checks {
+		violations = append(violations, check()...)
+	}
+
+	s.logger.Debugf("Ledger check found %d violations", len(violations))
+	return violations, nil
+}
+
+// sortedBalancesLocked возвращает балансы в порядке пользователей и валют. Вызывается под s.mu
+func (s *MemoryStorage) sortedBalancesLocked() []storages.Balance {
+	balances := make([]storages.Balance, 0, len(s.balances))
+	for _, balance := range s.balances {
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].UserID != balances[j].UserID {
+			return balances[i].UserID < balances[j].UserID
+		}
+		return balances[i].Currency < balances[j].Currency
+	})
+	return balances
+}
+
+// findBalanceMismatchesLocked находит балансы, не совпадающие с суммой проведенных
+// транзакций. Суммы транзакций, выгруженных в архив, берутся из archivedTotals.
+// Вызывается под s.mu
+func (s *MemoryStorage) findBalanceMismatchesLocked() []storages.LedgerViolation {
+	totals := make(map[balanceKey]float64)
+	for key, amount := range s.archivedTotals {
+		totals[key] += amount
+	}
+	for _, tx := range s.transactions {
+		if tx.Status == storages.TransactionStatusCompleted {
+			addCompletedDeltas(totals, &tx)
+		}
+	}
+
+	var violations []storages.LedgerViolation
+	for _, balance := range s.sortedBalancesLocked() {
+		expected := totals[balanceKey{balance.UserID, balance.Currency}]
+		if math.Abs(balance.Amount-expected) > ledgerTolerance {
+			violations = append(violations, storages.LedgerViolation{
+				Type:     storages.LedgerViolationBalanceMismatch,
+				UserID:   balance.UserID,
+				Currency: balance.Currency,
+				Balance:  balance.Amount,
+				Expected: expected,
+				Details:  fmt.Sprintf("balance %.8f differs from sum of completed transactions %.8f", balance.Amount, expected),
+			})
+		}
+	}
+	return violations
+}
+
+// addCompletedDeltas добавляет к totals изменения балансов проведенной транзакции
+func addCompletedDeltas(totals map[balanceKey]float64, tx *storages.Transaction) {
+	switch tx.Type {
+	case storages.TransactionTypeDeposit:
+		totals[balanceKey{tx.UserID, tx.ToCurrency}] += tx.ToAmount
+	case storages.TransactionTypeExchange:
+		totals[balanceKey{tx.UserID, tx.ToCurrency}] += tx.ToAmount
+		totals[balanceKey{tx.UserID, tx.FromCurrency}] -= tx.FromAmount
+	case storages.TransactionTypeWithdraw, storages.TransactionTypeFee:
+		totals[balanceKey{tx.UserID, tx.FromCurrency}] -= tx.FromAmount
+	}
+}
+
+// findNegativeBalancesLocked находит отрицательные балансы. Вызывается под s.mu
+func (s *MemoryStorage) findNegativeBalancesLocked() []storages.LedgerViolation {
+	var violations []storages.LedgerViolation
+	for _, balance := range s.sortedBalancesLocked() {
+		if balance.Amount < 0 {
+			violations = append(violations, storages.LedgerViolation{
+				Type:     storages.LedgerViolationNegativeBalance,
+				UserID:   balance.UserID,
+				Currency: balance.Currency,
+				Balance:  balance.Amount,
+				Details:  fmt.Sprintf("balance is negative: %.8f", balance.Amount),
+			})
+		}
+	}
+	return violations
+}
+
+// findOrphanTransactionsLocked находит транзакции без пользователя, а также
+// проведенные транзакции по валюте, в которой у пользователя нет баланса.
+// Вызывается под s.mu
+func (s *MemoryStorage) findOrphanTransactionsLocked() []storages.LedgerViolation {
+	transactions := make([]storages.Transaction, 0, len(s.transactions))
+	for _, tx := range s.transactions {
+		transactions = append(transactions, tx)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].UserID != transactions[j].UserID {
+			return transactions[i].UserID < transactions[j].UserID
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+
+	var violations []storages.LedgerViolation
+	for _, tx := range transactions {
+		if _, ok := s.users[tx.UserID]; !ok {
+			violations = append(violations, storages.LedgerViolation{
+				Type:          storages.LedgerViolationOrphanTransaction,
+				UserID:        tx.UserID,
+				Currency:      tx.FromCurrency,
+				TransactionID: tx.ID,
+				Details:       "user does not exist",
+			})
+			continue
+		}
+		if tx.Status != storages.TransactionStatusCompleted {
+			continue
+		}
+
+		currencies := []string{tx.FromCurrency}
+		if tx.ToCurrency != tx.FromCurrency {
+			currencies = append(currencies, tx.ToCurrency)
+		}
+		for _, currency := range currencies {
+			if _, ok := s.balances[balanceKey{tx.UserID, currency}]; !ok {
+				violations = append(violations, storages.LedgerViolation{
+					Type:          storages.LedgerViolationOrphanTransaction,
+					UserID:        tx.UserID,
+					Currency:      currency,
+					TransactionID: tx.ID,
+					Details:       "no balance for transaction currency",
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// FindBalanceDrift находит балансы, не равные сумме записей журнала
+func (s *MemoryStorage) FindBalanceDrift(ctx context.Context) ([]storages.LedgerViolation, error) {
+	defer s.lock(ctx)()
+
+	return s.findBalanceDriftLocked(), nil
+}
+
+// findBalanceDriftLocked сравнивает балансы с суммами записей журнала, в том числе
+// записи без баланса и балансы без записей. Вызывается под s.mu
+func (s *MemoryStorage) findBalanceDriftLocked() []storages.LedgerViolation {
+	totals := s.ledgerTotalsLocked()
+	keys := make([]balanceKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	for key := range s.balances {
+		if _, ok := totals[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].userID != keys[j].userID {
+			return keys[i].userID < keys[j].userID
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	var violations []storages.LedgerViolation
+	for _, key := range keys {
+		balance := s.balances[key].Amount
+		if math.Abs(balance-totals[key]) > ledgerTolerance {
+			violations = append(violations, storages.LedgerViolation{
+				Type:     storages.LedgerViolationBalanceDrift,
+				UserID:   key.userID,
+				Currency: key.currency,
+				Balance:  balance,
+				Expected: totals[key],
+				Details:  fmt.Sprintf("balance %.8f differs from sum of ledger entries %.8f", balance, totals[key]),
+			})
+		}
+	}
+	return violations
+}
+
+// findHoldMismatchesLocked находит балансы, удержание которых не равно сумме
+// ожидающих выводов с комиссиями. Вызывается под s.mu
+func (s *MemoryStorage) findHoldMismatchesLocked() []storages.LedgerViolation {
+	pending := make(map[balanceKey]float64)
+	for _, tx := range s.transactions {
+		if tx.Status == storages.TransactionStatusPending && tx.Type == storages.TransactionTypeWithdraw {
+			pending[balanceKey{tx.UserID, tx.FromCurrency}] += tx.FromAmount + tx.Fee
+		}
+	}
+
+	var violations []storages.LedgerViolation
+	for _, balance := range s.sortedBalancesLocked() {
+		expected := pending[balanceKey{balance.UserID, balance.Currency}]
+		if math.Abs(balance.Held-expected) > ledgerTolerance {
+			violations = append(violations, storages.LedgerViolation{
+				Type:     storages.LedgerViolationHoldMismatch,
+				UserID:   balance.UserID,
+				Currency: balance.Currency,
+				Balance:  balance.Held,
+				Expected: expected,
+				Details:  fmt.Sprintf("held amount %.8f differs from sum of pending withdrawals %.8f", balance.Held, expected),
+			})
+		}
+	}
+	return violations
+}
+
+// ledgerTotalsLocked возвращает суммы записей журнала по балансам. Вызывается под s.mu
+func (s *MemoryStorage) ledgerTotalsLocked() map[balanceKey]float64 {
+	totals := make(map[balanceKey]float64)
+	for _, entry := range s.ledger {
+		totals[balanceKey{entry.UserID, entry.Currency}] += entry.Amount
+	}
+	return totals
+}
+
+// RebuildBalances пересчитывает балансы из журнала
+func (s *MemoryStorage) RebuildBalances(ctx context.Context) (int64, error) {
+	var rebuilt int64
+	err := s.write(ctx, func() error {
+		now := time.Now()
+		totals := s.ledgerTotalsLocked()
+
+		for key, total := range totals {
+			balance, ok := s.balances[key]
+			if ok && balance.Amount == total {
+				continue
+			}
+			if !ok {
+				balance = storages.Balance{ID: s.newID("balances"), UserID: key.userID, Currency: key.currency, CreatedAt: now}
+			}
+			balance.Amount = total
+			balance.UpdatedAt = now
+			set(s, s.balances, key, balance)
+			rebuilt++
+		}
+
+		for key, balance := range s.balances {
+			if _, ok := totals[key]; !ok && balance.Amount != 0 {
+				balance.Amount = 0
+				balance.UpdatedAt = now
+				set(s, s.balances, key, balance)
+				rebuilt++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Rebuilt %d balances from ledger entries", rebuilt)
+	return rebuilt, nil
+}
+
+// GetLedgerEntries возвращает последние записи журнала пользователя
+func (s *MemoryStorage) GetLedgerEntries(ctx context.Context, userID int64, currency string, limit int) ([]storages.LedgerEntry, error) {
+	defer s.lock(ctx)()
+
+	entries := make([]storages.LedgerEntry, 0)
+	for i := len(s.ledger) - 1; i >= 0 && (limit < 0 || len(entries) < limit); i-- {
+		entry := s.ledger[i]
+		if entry.UserID == userID && (currency == "" || entry.Currency == currency) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// GetLedgerEntriesSince возвращает записи журнала пользователя, созданные начиная с since
+func (s *MemoryStorage) GetLedgerEntriesSince(ctx context.Context, userID int64, since time.Time) ([]storages.LedgerEntry, error) {
+	defer s.lock(ctx)()
+
+	entries := make([]storages.LedgerEntry, 0)
+	for _, entry := range s.ledger {
+		if entry.UserID == userID && !entry.CreatedAt.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// ListLedgerEntries возвращает записи журнала пользователя за интервал [from, to) после afterID
+func (s *MemoryStorage) ListLedgerEntries(ctx context.Context, userID int64, from, to time.Time, afterID int64, limit int) ([]storages.LedgerEntry, error) {
+	defer s.lock(ctx)()
+
+	entries := make([]storages.LedgerEntry, 0)
+	for _, entry := range s.ledger {
+		if limit >= 0 && len(entries) >= limit {
+			break
+		}
+		if entry.UserID == userID && entry.ID > afterID && !entry.CreatedAt.Before(from) && entry.CreatedAt.Before(to) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// GetLedgerTotalsBefore возвращает балансы пользователя по журналу на момент before
+func (s *MemoryStorage) GetLedgerTotalsBefore(ctx context.Context, userID int64, before time.Time) (storages.UserBalances, error) {
+	defer s.lock(ctx)()
+
+	totals := make(storages.UserBalances)
+	for _, entry := range s.ledger {
+		if entry.UserID == userID && entry.CreatedAt.Before(before) {
+			totals[entry.Currency] += entry.Amount
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// GetUserLimits возвращает лимиты пользователя
+func (s *MemoryStorage) GetUserLimits(ctx context.Context, userID int64) ([]storages.Limit, error) {
+	defer s.lock(ctx)()
+
+	var limits []storages.Limit
+	for key, limit := range s.limits {
+		if key.userID == userID {
+			limits = append(limits, limit)
+		}
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		a, b := limits[i], limits[j]
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Period < b.Period
+	})
+	return limits, nil
+}
+
+// SetUserLimit создает или обновляет лимит пользователя
+func (s *MemoryStorage) SetUserLimit(ctx context.Context, limit *storages.Limit) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(limit.UserID); err != nil {
+			return err
+		}
+		if limit.Amount < 0 {
+			return fmt.Errorf("failed to set limit: negative amount %.2f", limit.Amount)
+		}
+
+		limit.UpdatedAt = time.Now()
+		set(s, s.limits, limitKey{limit.UserID, limit.Operation, limit.Currency, limit.Period}, *limit)
+
+		s.logger.Infof("Set %s %s limit for user %d: %.2f %s", limit.Period, limit.Operation, limit.UserID, limit.Amount, limit.Currency)
+		return nil
+	})
+}
+
+// DeleteUserLimit удаляет лимит пользователя
+func (s *MemoryStorage) DeleteUserLimit(ctx context.Context, userID int64, operation, currency, period string) error {
+	return s.write(ctx, func() error {
+		key := limitKey{userID, operation, currency, period}
+		if _, ok := s.limits[key]; !ok {
+			return fmt.Errorf("limit %w", storages.ErrNotFound)
+		}
+		remove(s, s.limits, key)
+
+		s.logger.Infof("Deleted %s %s limit for user %d in %s", period, operation, userID, currency)
+		return nil
+	})
+}
+
+// LockUserLimits проверяет, что пользователь существует. Отдельная блокировка не нужна:
+// транзакция WithTransaction удерживает блокировку всего хранилища
+func (s *MemoryStorage) LockUserLimits(ctx context.Context, userID int64) error {
+	defer s.lock(ctx)()
+
+	_, err := s.userLocked(userID)
+	return err
+}
+
+// SumUserOperations возвращает сумму проведенных и ожидающих операций в валюте списания начиная с since
+func (s *MemoryStorage) SumUserOperations(ctx context.Context, userID int64, operation, currency string, since time.Time) (float64, error) {
+	defer s.lock(ctx)()
+
+	var total float64
+	for _, tx := range s.transactions {
+		if tx.UserID != userID || tx.Type != operation || tx.FromCurrency != currency || tx.CreatedAt.Before(since) {
+			continue
+		}
+		if tx.Status == storages.TransactionStatusCompleted || tx.Status == storages.TransactionStatusPending {
+			total += tx.FromAmount
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,216 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateUser создает нового пользователя и нулевые балансы в переданных валютах
+func (s *MemoryStorage) CreateUser(ctx context.Context, user *storages.User, currencies []string) error {
+	return s.write(ctx, func() error {
+		for _, existing := range s.users {
+			if existing.Username == user.Username || existing.Email == user.Email {
+				return fmt.Errorf("user %w", storages.ErrDuplicate)
+			}
+		}
+
+		if user.Role == "" {
+			user.Role = storages.RoleUser
+		}
+
+		now := time.Now()
+		created := storages.User{
+			ID:                s.newID("users"),
+			Username:          user.Username,
+			Email:             user.Email,
+			PasswordHash:      user.PasswordHash,
+			Role:              user.Role,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+			Status:            storages.AccountStatusActive,
+			VerificationLevel: storages.VerificationLevelUnverified,
+		}
+		set(s, s.users, created.ID, created)
+
+		for _, currency := range currencies {
+			balance := &storages.Balance{UserID: created.ID, Currency: currency}
+			if err := s.createBalanceLocked(balance); err != nil {
+				return fmt.Errorf("failed to create initial balance: %w", err)
+			}
+		}
+
+		*user = created
+		s.logger.Infof("Created user: %s (ID: %d)", user.Username, user.ID)
+		return nil
+	})
+}
+
+// GetUserByUsername возвращает пользователя по имени
+func (s *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (*storages.User, error) {
+	return s.findUser(ctx, func(user *storages.User) bool { return user.Username == username })
+}
+
+// GetUserByEmail возвращает пользователя по email
+func (s *MemoryStorage) GetUserByEmail(ctx context.Context, email string) (*storages.User, error) {
+	return s.findUser(ctx, func(user *storages.User) bool { return user.Email == email })
+}
+
+// GetUserByID возвращает пользователя по ID
+func (s *MemoryStorage) GetUserByID(ctx context.Context, userID int64) (*storages.User, error) {
+	defer s.lock(ctx)()
+
+	user, err := s.userLocked(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// findUser возвращает пользователя, для которого match вернула true
+func (s *MemoryStorage) findUser(ctx context.Context, match func(user *storages.User) bool) (*storages.User, error) {
+	defer s.lock(ctx)()
+
+	for _, user := range s.users {
+		if match(&user) {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("user %w", storages.ErrNotFound)
+}
+
+// UpdateUserRole изменяет роль пользователя
+func (s *MemoryStorage) UpdateUserRole(ctx context.Context, userID int64, role string) error {
+	return s.write(ctx, func() error {
+		user, err := s.userLocked(userID)
+		if err != nil {
+			return err
+		}
+
+		user.Role = role
+		user.UpdatedAt = time.Now()
+		set(s, s.users, userID, user)
+
+		s.logger.Infof("Updated role for user %d: %s", userID, role)
+		return nil
+	})
+}
+
+// UpdateUserPassword заменяет хеш пароля пользователя
+func (s *MemoryStorage) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	return s.write(ctx, func() error {
+		user, err := s.userLocked(userID)
+		if err != nil {
+			return err
+		}
+
+		user.PasswordHash = passwordHash
+		user.UpdatedAt = time.Now()
+		set(s, s.users, userID, user)
+
+		s.logger.Infof("Updated password for user %d", userID)
+		return nil
+	})
+}
+
+// ListUsers возвращает страницу пользователей и общее количество найденных.
+// search ищет по вхождению в username или email без учета регистра
+func (s *MemoryStorage) ListUsers(ctx context.Context, search string, limit, offset int) ([]storages.User, int64, error) {
+	defer s.lock(ctx)()
+
+	search = strings.ToLower(search)
+	var found []storages.User
+	for _, user := range s.users {
+		if strings.Contains(strings.ToLower(user.Username), search) || strings.Contains(strings.ToLower(user.Email), search) {
+			found = append(found, user)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+
+	users := []storages.User{}
+	if offset < len(found) {
+		users = append(users, head(found[offset:], limit)...)
+	}
+	return users, int64(len(found)), nil
+}
+
+// GetBalance возвращает баланс пользователя в конкретной валюте
+func (s *MemoryStorage) GetBalance(ctx context.Context, userID int64, currency string) (*storages.Balance, error) {
+	defer s.lock(ctx)()
+
+	balance, ok := s.balances[balanceKey{userID, currency}]
+	if !ok {
+		return nil, fmt.Errorf("balance %w", storages.ErrNotFound)
+	}
+	return &balance, nil
+}
+
+// GetAllBalances возвращает все балансы пользователя в порядке валют
+func (s *MemoryStorage) GetAllBalances(ctx context.Context, userID int64) ([]storages.Balance, error) {
+	defer s.lock(ctx)()
+
+	var balances []storages.Balance
+	for key, balance := range s.balances {
+		if key.userID == userID {
+			balances = append(balances, balance)
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+	return balances, nil
+}
+
+// GetBalanceCurrencies возвращает валюты существующих балансов
+func (s *MemoryStorage) GetBalanceCurrencies(ctx context.Context) ([]string, error) {
+	defer s.lock(ctx)()
+
+	seen := make(map[string]bool)
+	var currencies []string
+	for key := range s.balances {
+		if !seen[key.currency] {
+			seen[key.currency] = true
+			currencies = append(currencies, key.currency)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies, nil
+}
+
+// CreateBalance создает новый баланс. Ненулевая начальная сумма записывается
+// в журнал в той же операции
+func (s *MemoryStorage) CreateBalance(ctx context.Context, balance *storages.Balance) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(balance.UserID); err != nil {
+			return err
+		}
+		return s.createBalanceLocked(balance)
+	})
+}
+
+// createBalanceLocked создает баланс и запись журнала для ненулевой суммы. Вызывается под s.mu
+func (s *MemoryStorage) createBalanceLocked(balance *storages.Balance) error {
+	key := balanceKey{balance.UserID, balance.Currency}
+	if _, ok := s.balances[key]; ok {
+		return fmt.Errorf("balance %w", storages.ErrDuplicate)
+	}
+	if balance.Amount < 0 {
+		return fmt.Errorf("failed to create balance: negative amount %.2f", balance.Amount)
+	}
+
+	now := time.Now()
+	balance.ID = s.newID("balances")
+	balance.Held = 0
+	balance.CreatedAt = now
+	balance.UpdatedAt = now
+	set(s, s.balances, key, *balance)
+
+	if balance.Amount != 0 {
+		s.insertEntryLocked(balance.UserID, balance.Currency, balance.Amount, 0, storages.LedgerEntryOpening)
+	}
+
+	s.logger.Debugf("Created balance for user %d: %.2f %s", balance.UserID, balance.Amount, balance.Currency)
+	return nil
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// insertOutboxLocked создает запись outbox для уведомления о транзакции. Вызывается под s.mu
+func (s *MemoryStorage) insertOutboxLocked(txID int64) {
+	entry := storages.OutboxEntry{
+		ID:          s.newID("outbox"),
+		CreatedAt:   time.Now(),
+		Transaction: storages.Transaction{ID: txID},
+	}
+	set(s, s.outbox, entry.ID, entry)
+}
+
+// FetchPendingOutbox возвращает неотправленные записи outbox, время попытки которых
+// наступило к now, в порядке создания. Отложенные насовсем записи не возвращаются
+func (s *MemoryStorage) FetchPendingOutbox(ctx context.Context, now time.Time, limit int) ([]storages.OutboxEntry, error) {
+	defer s.lock(ctx)()
+
+	var entries []storages.OutboxEntry
+	for _, entry := range s.outbox {
+		if entry.SentAt != nil || entry.ParkedAt != nil || (entry.NextAttemptAt != nil && entry.NextAttemptAt.After(now)) {
+			continue
+		}
+		tx, ok := s.transactions[entry.Transaction.ID]
+		if !ok {
+			continue
+		}
+		entry.Transaction = tx
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return head(entries, limit), nil
+}
+
+// MarkOutboxSent отмечает записи outbox как отправленные
+func (s *MemoryStorage) MarkOutboxSent(ctx context.Context, ids []int64) error {
+	err := s.write(ctx, func() error {
+		now := time.Now()
+		for _, id := range ids {
+			s.updateOutboxLocked(id, func(entry *storages.OutboxEntry) {
+				entry.SentAt = &now
+				entry.Attempts++
+				entry.LastError = ""
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debugf("Marked %d outbox entries as sent", len(ids))
+	return nil
+}
+
+// MarkOutboxFailed увеличивает счетчик попыток, сохраняет причину ошибки
+// и откладывает следующую попытку до retryAt
+func (s *MemoryStorage) MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	return s.write(ctx, func() error {
+		s.updateOutboxLocked(id, func(entry *storages.OutboxEntry) {
+			entry.Attempts++
+			entry.LastError = reason
+			entry.NextAttemptAt = &retryAt
+		})
+		return nil
+	})
+}
+
+// ParkOutbox откладывает запись outbox насовсем
+func (s *MemoryStorage) ParkOutbox(ctx context.Context, id int64) error {
+	return s.write(ctx, func() error {
+		now := time.Now()
+		s.updateOutboxLocked(id, func(entry *storages.OutboxEntry) {
+			entry.ParkedAt = &now
+		})
+		return nil
+	})
+}
+
+// RequeueOutbox возвращает записи outbox в очередь на повторную отправку не раньше retryAt
+func (s *MemoryStorage) RequeueOutbox(ctx context.Context, transactionIDs []int64, reason string, retryAt time.Time) error {
+	err := s.write(ctx, func() error {
+		for id, entry := range s.outbox {
+			if !slices.Contains(transactionIDs, entry.Transaction.ID) {
+				continue
+			}
+			s.updateOutboxLocked(id, func(entry *storages.OutboxEntry) {
+				entry.SentAt = nil
+				entry.Attempts++
+				entry.LastError = reason
+				entry.NextAttemptAt = &retryAt
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Warnf("Requeued %d outbox entries: %s", len(transactionIDs), reason)
+	return nil
+}
+
+// updateOutboxLocked изменяет запись outbox, если она есть. Вызывается под s.mu
+func (s *MemoryStorage) updateOutboxLocked(id int64, update func(entry *storages.OutboxEntry)) {
+	entry, ok := s.outbox[id]
+	if !ok {
+		return
+	}
+	update(&entry)
+	set(s, s.outbox, id, entry)
+}
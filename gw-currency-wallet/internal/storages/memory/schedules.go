@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateSchedule создает регулярную операцию
+func (s *MemoryStorage) CreateSchedule(ctx context.Context, schedule *storages.Schedule) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(schedule.UserID); err != nil {
+			return err
+		}
+		if schedule.Amount <= 0 {
+			return fmt.Errorf("failed to create schedule: amount must be positive")
+		}
+
+		now := time.Now()
+		schedule.ID = s.newID("schedules")
+		schedule.DueAt = schedule.NextRunAt
+		schedule.RunCount = 0
+		schedule.Attempts = 0
+		schedule.LastRunAt = nil
+		schedule.LastError = ""
+		schedule.CreatedAt = now
+		schedule.UpdatedAt = now
+		set(s, s.schedules, schedule.ID, *schedule)
+
+		s.logger.Infof("Created %s %s schedule %d for user %d", schedule.Period, schedule.Operation, schedule.ID, schedule.UserID)
+		return nil
+	})
+}
+
+// GetSchedule возвращает регулярную операцию пользователя
+func (s *MemoryStorage) GetSchedule(ctx context.Context, userID, scheduleID int64) (*storages.Schedule, error) {
+	defer s.lock(ctx)()
+
+	schedule, ok := s.schedules[scheduleID]
+	if !ok || schedule.UserID != userID {
+		return nil, fmt.Errorf("schedule %w", storages.ErrNotFound)
+	}
+	return &schedule, nil
+}
+
+// ListSchedules возвращает регулярные операции пользователя
+func (s *MemoryStorage) ListSchedules(ctx context.Context, userID int64) ([]storages.Schedule, error) {
+	defer s.lock(ctx)()
+
+	return s.selectSchedulesLocked(func(schedule *storages.Schedule) bool {
+		return schedule.UserID == userID
+	}, func(a, b *storages.Schedule) bool {
+		return a.ID < b.ID
+	}), nil
+}
+
+// UpdateSchedule сохраняет изменения регулярной операции пользователя
+func (s *MemoryStorage) UpdateSchedule(ctx context.Context, schedule *storages.Schedule) error {
+	return s.write(ctx, func() error {
+		stored, ok := s.schedules[schedule.ID]
+		if !ok || stored.UserID != schedule.UserID {
+			return fmt.Errorf("schedule %w", storages.ErrNotFound)
+		}
+		if schedule.Amount <= 0 {
+			return fmt.Errorf("failed to update schedule: amount must be positive")
+		}
+
+		now := time.Now()
+		stored.Amount = schedule.Amount
+		stored.Period = schedule.Period
+		stored.StartAt = schedule.StartAt
+		stored.NextRunAt = schedule.NextRunAt
+		stored.DueAt = schedule.NextRunAt
+		stored.Active = schedule.Active
+		stored.Attempts = 0
+		stored.UpdatedAt = now
+		set(s, s.schedules, schedule.ID, stored)
+
+		schedule.DueAt = schedule.NextRunAt
+		schedule.Attempts = 0
+		schedule.UpdatedAt = now
+		return nil
+	})
+}
+
+// DeleteSchedule удаляет регулярную операцию пользователя
+func (s *MemoryStorage) DeleteSchedule(ctx context.Context, userID, scheduleID int64) error {
+	return s.write(ctx, func() error {
+		schedule, ok := s.schedules[scheduleID]
+		if !ok || schedule.UserID != userID {
+			return fmt.Errorf("schedule %w", storages.ErrNotFound)
+		}
+		remove(s, s.schedules, scheduleID)
+
+		s.logger.Infof("Deleted schedule %d of user %d", scheduleID, userID)
+		return nil
+	})
+}
+
+// ListDueSchedules возвращает активные операции, время попытки которых наступило
+func (s *MemoryStorage) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]storages.Schedule, error) {
+	defer s.lock(ctx)()
+
+	schedules := s.selectSchedulesLocked(func(schedule *storages.Schedule) bool {
+		return schedule.Active && !schedule.DueAt.After(now)
+	}, func(a, b *storages.Schedule) bool {
+		if !a.DueAt.Equal(b.DueAt) {
+			return a.DueAt.Before(b.DueAt)
+		}
+		return a.ID < b.ID
+	})
+	return head(schedules, limit), nil
+}
+
+// CompleteScheduleRun завершает запуск и переносит операцию на следующее плановое время
+func (s *MemoryStorage) CompleteScheduleRun(ctx context.Context, scheduleID int64, runCount int, next time.Time, lastError string) (bool, error) {
+	var completed bool
+	err := s.write(ctx, func() error {
+		schedule, ok := s.schedules[scheduleID]
+		if !ok || schedule.RunCount != runCount || !schedule.Active {
+			return nil
+		}
+
+		now := time.Now()
+		schedule.NextRunAt = next
+		schedule.DueAt = next
+		schedule.RunCount++
+		schedule.Attempts = 0
+		schedule.LastRunAt = &now
+		schedule.LastError = lastError
+		schedule.UpdatedAt = now
+		set(s, s.schedules, scheduleID, schedule)
+
+		completed = true
+		return nil
+	})
+	return completed, err
+}
+
+// RetryScheduleRun записывает неудачную попытку и время следующей
+func (s *MemoryStorage) RetryScheduleRun(ctx context.Context, scheduleID int64, runCount int, retryAt time.Time, lastError string) error {
+	return s.write(ctx, func() error {
+		schedule, ok := s.schedules[scheduleID]
+		if !ok || schedule.RunCount != runCount {
+			return nil
+		}
+
+		schedule.DueAt = retryAt
+		schedule.Attempts++
+		schedule.LastError = lastError
+		schedule.UpdatedAt = time.Now()
+		set(s, s.schedules, scheduleID, schedule)
+		return nil
+	})
+}
+
+// selectSchedulesLocked возвращает операции, для которых match вернула true,
+// в порядке less. Вызывается под s.mu
+func (s *MemoryStorage) selectSchedulesLocked(match func(schedule *storages.Schedule) bool, less func(a, b *storages.Schedule) bool) []storages.Schedule {
+	schedules := []storages.Schedule{}
+	for _, schedule := range s.schedules {
+		if match(&schedule) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return less(&schedules[i], &schedules[j]) })
+	return schedules
+}
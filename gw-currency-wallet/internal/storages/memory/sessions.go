@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateSession сохраняет сессию пользователя
+func (s *MemoryStorage) CreateSession(ctx context.Context, session *storages.Session) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(session.UserID); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		session.ID = s.newID("sessions")
+		session.CreatedAt = now
+		session.LastUsedAt = now
+		session.RevokedAt = nil
+		set(s, s.sessions, session.ID, *session)
+
+		s.logger.Infof("Created session %d for user %d", session.ID, session.UserID)
+		return nil
+	})
+}
+
+// GetSession возвращает сессию пользователя
+func (s *MemoryStorage) GetSession(ctx context.Context, userID, sessionID int64) (*storages.Session, error) {
+	defer s.lock(ctx)()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return nil, fmt.Errorf("session %w", storages.ErrNotFound)
+	}
+	return &session, nil
+}
+
+// ListActiveSessions возвращает действующие сессии пользователя, новые первыми
+func (s *MemoryStorage) ListActiveSessions(ctx context.Context, userID int64, now time.Time) ([]storages.Session, error) {
+	defer s.lock(ctx)()
+
+	sessions := []storages.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.Active(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID > sessions[j].ID })
+	return sessions, nil
+}
+
+// TouchSession обновляет время последнего использования действующей сессии
+func (s *MemoryStorage) TouchSession(ctx context.Context, sessionID int64, usedAt time.Time) error {
+	_, err := s.updateSessions(ctx, func(session *storages.Session) bool {
+		if session.ID != sessionID || !session.Active(usedAt) {
+			return false
+		}
+		session.LastUsedAt = usedAt
+		return true
+	})
+	return err
+}
+
+// RevokeSession отзывает действующую сессию пользователя
+func (s *MemoryStorage) RevokeSession(ctx context.Context, userID, sessionID int64, revokedAt time.Time) error {
+	_, err := s.updateSessions(ctx, func(session *storages.Session) bool {
+		if session.ID != sessionID || session.UserID != userID || !session.Active(revokedAt) {
+			return false
+		}
+		session.RevokedAt = &revokedAt
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Revoked session %d of user %d", sessionID, userID)
+	return nil
+}
+
+// RevokeUserSessions отзывает сессии пользователя, кроме exceptID
+func (s *MemoryStorage) RevokeUserSessions(ctx context.Context, userID, exceptID int64, revokedAt time.Time) (int64, error) {
+	revoked, err := s.updateSessions(ctx, func(session *storages.Session) bool {
+		if session.UserID != userID || session.ID == exceptID || !session.Active(revokedAt) {
+			return false
+		}
+		session.RevokedAt = &revokedAt
+		return true
+	})
+	if errors.Is(err, storages.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Revoked %d sessions of user %d", revoked, userID)
+	return revoked, nil
+}
+
+// updateSessions изменяет сессии, для которых update вернула true, и возвращает их число;
+// ErrNotFound, если ни одна действующая сессия не изменена
+func (s *MemoryStorage) updateSessions(ctx context.Context, update func(session *storages.Session) bool) (int64, error) {
+	var updated int64
+	err := s.write(ctx, func() error {
+		for id, session := range s.sessions {
+			if update(&session) {
+				set(s, s.sessions, id, session)
+				updated++
+			}
+		}
+		if updated == 0 {
+			return fmt.Errorf("session %w", storages.ErrNotFound)
+		}
+		return nil
+	})
+	return updated, err
+}
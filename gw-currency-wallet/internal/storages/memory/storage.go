@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/storages"
+)
+
+// MemoryStorage реализует интерфейс Storage в памяти процесса. Используется
+// для запуска без БД (STORAGE_DRIVER=memory): данные теряются при остановке.
+// Операции выполняются последовательно под одной блокировкой, как транзакции
+// SQLite через единственное соединение
+type MemoryStorage struct {
+	logger *logrus.Logger
+
+	mu sync.Mutex
+	// undo отменяет изменения текущей операции или транзакции WithTransaction
+	// в обратном порядке, если она завершилась ошибкой
+	undo   []func()
+	lastID map[string]int64
+
+	users                map[int64]storages.User
+	statusHistory        []storages.AccountStatusEvent
+	balances             map[balanceKey]storages.Balance
+	archivedTotals       map[balanceKey]float64
+	transactions         map[int64]storages.Transaction
+	outbox               map[int64]storages.OutboxEntry
+	ledger               []storages.LedgerEntry
+	limits               map[limitKey]storages.Limit
+	schedules            map[int64]storages.Schedule
+	apiKeys              map[int64]storages.APIKey
+	webhooks             map[int64]storages.Webhook
+	deliveries           map[int64]storages.WebhookDelivery
+	sessions             map[int64]storages.Session
+	verificationRequests map[int64]storages.VerificationRequest
+}
+
+// balanceKey баланс пользователя в валюте
+type balanceKey struct {
+	userID   int64
+	currency string
+}
+
+// limitKey лимит пользователя на операцию в валюте за период
+type limitKey struct {
+	userID    int64
+	operation string
+	currency  string
+	period    string
+}
+
+// New создает пустое хранилище
+func New(logger *logrus.Logger) *MemoryStorage {
+	s := &MemoryStorage{
+		logger:               logger,
+		lastID:               make(map[string]int64),
+		users:                make(map[int64]storages.User),
+		balances:             make(map[balanceKey]storages.Balance),
+		archivedTotals:       make(map[balanceKey]float64),
+		transactions:         make(map[int64]storages.Transaction),
+		outbox:               make(map[int64]storages.OutboxEntry),
+		limits:               make(map[limitKey]storages.Limit),
+		schedules:            make(map[int64]storages.Schedule),
+		apiKeys:              make(map[int64]storages.APIKey),
+		webhooks:             make(map[int64]storages.Webhook),
+		deliveries:           make(map[int64]storages.WebhookDelivery),
+		sessions:             make(map[int64]storages.Session),
+		verificationRequests: make(map[int64]storages.VerificationRequest),
+	}
+
+	logger.Info("Using in-memory storage")
+	return s
+}
+
+// txKey ключ контекста транзакции WithTransaction; значение - хранилище,
+// удерживающее блокировку на время транзакции
+type txKey struct{}
+
+// WithTransaction выполняет fn под блокировкой хранилища. Методы хранилища,
+// вызванные с контекстом fn, выполняются в этой транзакции. Если fn вернула
+// ошибку, ее изменения отменяются. Вложенный вызов использует внешнюю транзакцию
+func (s *MemoryStorage) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTx(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.finishLocked(fn(context.WithValue(ctx, txKey{}, s)))
+}
+
+// inTx сообщает, что ctx принадлежит транзакции WithTransaction этого хранилища
+func (s *MemoryStorage) inTx(ctx context.Context) bool {
+	return ctx.Value(txKey{}) == s
+}
+
+// lock блокирует хранилище на время чтения и возвращает функцию снятия блокировки.
+// Внутри WithTransaction блокировку уже удерживает транзакция
+func (s *MemoryStorage) lock(ctx context.Context) func() {
+	if s.inTx(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// write выполняет изменение fn атомарно: при ошибке изменения fn отменяются.
+// Внутри WithTransaction изменения отменяет транзакция
+func (s *MemoryStorage) write(ctx context.Context, fn func() error) error {
+	if s.inTx(ctx) {
+		return fn()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.finishLocked(fn())
+}
+
+// finishLocked завершает операцию: при ошибке отменяет ее изменения. Вызывается под s.mu
+func (s *MemoryStorage) finishLocked(err error) error {
+	if err != nil {
+		for i := len(s.undo) - 1; i >= 0; i-- {
+			s.undo[i]()
+		}
+	}
+	s.undo = nil
+	return err
+}
+
+// set сохраняет значение по ключу и запоминает, как отменить изменение. Вызывается под s.mu
+func set[K comparable, V any](s *MemoryStorage, m map[K]V, key K, value V) {
+	previous, existed := m[key]
+	s.undo = append(s.undo, func() {
+		if existed {
+			m[key] = previous
+		} else {
+			delete(m, key)
+		}
+	})
+	m[key] = value
+}
+
+// remove удаляет значение по ключу и запоминает, как отменить удаление. Вызывается под s.mu
+func remove[K comparable, V any](s *MemoryStorage, m map[K]V, key K) {
+	previous, ok := m[key]
+	if !ok {
+		return
+	}
+	s.undo = append(s.undo, func() { m[key] = previous })
+	delete(m, key)
+}
+
+// push добавляет запись в конец списка и запоминает, как отменить добавление.
+// Вызывается под s.mu
+func push[V any](s *MemoryStorage, list *[]V, value V) {
+	n := len(*list)
+	s.undo = append(s.undo, func() { *list = (*list)[:n] })
+	*list = append(*list, value)
+}
+
+// head возвращает первые limit записей; отрицательный limit - все записи, как LIMIT в SQL
+func head[V any](list []V, limit int) []V {
+	if limit >= 0 && len(list) > limit {
+		return list[:limit]
+	}
+	return list
+}
+
+// newID возвращает следующий идентификатор записи таблицы. Идентификаторы
+// отмененных изменений не переиспользуются, как последовательности в БД. Вызывается под s.mu
+func (s *MemoryStorage) newID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// userLocked возвращает пользователя или ErrNotFound. Вызывается под s.mu
+func (s *MemoryStorage) userLocked(userID int64) (storages.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return storages.User{}, fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+	return user, nil
+}
+
+// Close ничего не делает: хранилище не держит внешних ресурсов
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// Ping всегда успешен
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
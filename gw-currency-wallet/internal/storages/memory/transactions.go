@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateTransaction создает новую транзакцию
+func (s *MemoryStorage) CreateTransaction(ctx context.Context, tx *storages.Transaction) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(tx.UserID); err != nil {
+			return err
+		}
+
+		tx.ID = s.newID("transactions")
+		tx.Fee = 0
+		tx.CreatedAt = time.Now()
+		tx.CompletedAt = nil
+		set(s, s.transactions, tx.ID, *tx)
+
+		s.logger.Infof("Created transaction: ID=%d, Type=%s, User=%d", tx.ID, tx.Type, tx.UserID)
+		return nil
+	})
+}
+
+// GetTransaction возвращает транзакцию по ID
+func (s *MemoryStorage) GetTransaction(ctx context.Context, txID int64) (*storages.Transaction, error) {
+	defer s.lock(ctx)()
+
+	tx, ok := s.transactions[txID]
+	if !ok {
+		return nil, fmt.Errorf("transaction %w", storages.ErrNotFound)
+	}
+	return &tx, nil
+}
+
+// GetUserTransactions возвращает страницу истории транзакций пользователя.
+// Курсор сравнивается по (created_at, id), поэтому страницы не смещаются,
+// когда появляются новые транзакции
+func (s *MemoryStorage) GetUserTransactions(ctx context.Context, userID int64, filter storages.TransactionFilter) (*storages.TransactionPage, error) {
+	defer s.lock(ctx)()
+
+	var matched []storages.Transaction
+	for _, tx := range s.transactions {
+		if matchesFilter(&tx, userID, filter) {
+			matched = append(matched, tx)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return newerThan(&matched[i], &matched[j]) })
+	total := int64(len(matched))
+
+	if filter.Cursor > 0 {
+		// Курсор чужой или удаленной транзакции не возвращает ничего, как в SQL хранилищах
+		cursor, ok := s.transactions[filter.Cursor]
+		older := matched[:0]
+		for _, tx := range matched {
+			if ok && cursor.UserID == userID && newerThan(&cursor, &tx) {
+				older = append(older, tx)
+			}
+		}
+		matched = older
+	}
+
+	if filter.Offset < len(matched) {
+		matched = matched[filter.Offset:]
+	} else {
+		matched = nil
+	}
+
+	// Берем на одну запись больше, чтобы узнать, есть ли следующая страница
+	pageSize := filter.PageSize()
+	transactions := append(make([]storages.Transaction, 0, pageSize), head(matched, pageSize+1)...)
+
+	page := &storages.TransactionPage{Transactions: transactions, Total: total}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		page.NextCursor = page.Transactions[pageSize-1].ID
+	}
+
+	return page, nil
+}
+
+// matchesFilter сообщает, что транзакция пользователя подходит под фильтр истории без курсора
+func matchesFilter(tx *storages.Transaction, userID int64, filter storages.TransactionFilter) bool {
+	switch {
+	case tx.UserID != userID:
+		return false
+	case !filter.From.IsZero() && tx.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !tx.CreatedAt.Before(filter.To):
+		return false
+	case len(filter.Types) > 0 && !slices.Contains(filter.Types, tx.Type):
+		return false
+	case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, tx.Status):
+		return false
+	}
+	return true
+}
+
+// newerThan сравнивает транзакции по (created_at, id) в порядке истории
+func newerThan(a, b *storages.Transaction) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// UpdateTransactionStatus переводит транзакцию из статуса from в to. Время
+// завершения записывается для конечных статусов
+func (s *MemoryStorage) UpdateTransactionStatus(ctx context.Context, txID int64, from, to string) error {
+	return s.write(ctx, func() error {
+		return s.updateStatusLocked(txID, from, to)
+	})
+}
+
+// updateStatusLocked переводит транзакцию из статуса from в to. Вызывается под s.mu
+func (s *MemoryStorage) updateStatusLocked(txID int64, from, to string) error {
+	tx, ok := s.transactions[txID]
+	if !ok || tx.Status != from {
+		return fmt.Errorf("%s transaction %d %w", from, txID, storages.ErrNotFound)
+	}
+
+	tx.Status = to
+	tx.CompletedAt = nil
+	if to != storages.TransactionStatusPending {
+		now := time.Now()
+		tx.CompletedAt = &now
+	}
+	set(s, s.transactions, txID, tx)
+
+	s.logger.Debugf("Updated transaction %d status from %s to %s", txID, from, to)
+	return nil
+}
+
+// ExecuteDeposit пополняет баланс атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *MemoryStorage) ExecuteDeposit(ctx context.Context, userID int64, currency string, amount float64, notify bool) (int64, error) {
+	var txID int64
+	err := s.write(ctx, func() error {
+		if _, err := s.userLocked(userID); err != nil {
+			return err
+		}
+
+		txID = s.insertCompletedTransactionLocked(userID, storages.TransactionTypeDeposit, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+		return s.applyEntryLocked(userID, currency, amount, txID, storages.TransactionTypeDeposit)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return txID, nil
+}
+
+// ExecuteWithdraw списывает средства атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *MemoryStorage) ExecuteWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64, notify bool) (int64, error) {
+	var txID int64
+	err := s.write(ctx, func() error {
+		// 1. Проверяем достаточность средств, не удержанных ожидающими выводами
+		if err := s.checkAvailableLocked(userID, currency, amount+fee); err != nil {
+			return err
+		}
+
+		// 2. Создаем запись о транзакции и уведомление
+		txID = s.insertCompletedTransactionLocked(userID, storages.TransactionTypeWithdraw, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+
+		// 3. Списываем сумму и комиссию записями журнала
+		if err := s.applyEntryLocked(userID, currency, -amount, txID, storages.TransactionTypeWithdraw); err != nil {
+			return err
+		}
+		return s.insertFeeLocked(userID, currency, fee)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return txID, nil
+}
+
+// ExecuteExchange выполняет обмен валюты атомарно
+func (s *MemoryStorage) ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, fee float64, notify bool) (int64, error) {
+	var txID int64
+	err := s.write(ctx, func() error {
+		// 1. Проверяем достаточность средств исходной валюты с учетом комиссии
+		if err := s.checkAvailableLocked(userID, fromCurrency, fromAmount+fee); err != nil {
+			return err
+		}
+
+		// 2. Создаем запись о транзакции и уведомление
+		txID = s.insertCompletedTransactionLocked(userID, storages.TransactionTypeExchange, fromCurrency, toCurrency, fromAmount, toAmount, quote, notify)
+
+		// 3. Списываем исходную валюту и зачисляем целевую записями журнала,
+		// затем списываем комиссию
+		if err := s.applyEntryLocked(userID, fromCurrency, -fromAmount, txID, storages.TransactionTypeExchange); err != nil {
+			return err
+		}
+		if err := s.applyEntryLocked(userID, toCurrency, toAmount, txID, storages.TransactionTypeExchange); err != nil {
+			return err
+		}
+		return s.insertFeeLocked(userID, fromCurrency, fee)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Exchange completed: User=%d, %.2f %s -> %.2f %s (rate: %.8f, margin: %.4f, source: %s)",
+		userID, fromAmount, fromCurrency, toAmount, toCurrency, quote.Rate, quote.Margin, quote.Source)
+	return txID, nil
+}
+
+// checkAvailableLocked проверяет, что средств, не удержанных ожидающими выводами,
+// хватает на need. Вызывается под s.mu
+func (s *MemoryStorage) checkAvailableLocked(userID int64, currency string, need float64) error {
+	balance, ok := s.balances[balanceKey{userID, currency}]
+	if !ok {
+		return fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, currency)
+	}
+
+	if available := balance.Amount - balance.Held; available < need {
+		return fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, available, need)
+	}
+	return nil
+}
+
+// insertCompletedTransactionLocked создает запись о проведенной транзакции и,
+// если notify, запись outbox для уведомления о ней. Вызывается под s.mu
+func (s *MemoryStorage) insertCompletedTransactionLocked(userID int64, transferType, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, notify bool) int64 {
+	now := time.Now()
+	tx := storages.Transaction{
+		ID:           s.newID("transactions"),
+		UserID:       userID,
+		Type:         transferType,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		FromAmount:   fromAmount,
+		ToAmount:     toAmount,
+		ExchangeRate: quote.Rate,
+		MarketRate:   quote.MarketRate,
+		Margin:       quote.Margin,
+		Source:       quote.Source,
+		Status:       storages.TransactionStatusCompleted,
+		CreatedAt:    now,
+		CompletedAt:  &now,
+	}
+	set(s, s.transactions, tx.ID, tx)
+
+	if notify {
+		s.insertOutboxLocked(tx.ID)
+	}
+	return tx.ID
+}
+
+// insertFeeLocked создает запись о комиссии и списывает ее с баланса записью журнала.
+// Вызывается под s.mu
+func (s *MemoryStorage) insertFeeLocked(userID int64, currency string, fee float64) error {
+	if fee <= 0 {
+		return nil
+	}
+
+	feeTxID := s.insertCompletedTransactionLocked(userID, storages.TransactionTypeFee, currency, currency, fee, 0, storages.ExchangeQuote{}, false)
+	return s.applyEntryLocked(userID, currency, -fee, feeTxID, storages.TransactionTypeFee)
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateVerificationRequest сохраняет заявку в статусе pending
+func (s *MemoryStorage) CreateVerificationRequest(ctx context.Context, request *storages.VerificationRequest) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(request.UserID); err != nil {
+			return err
+		}
+		for _, existing := range s.verificationRequests {
+			if existing.UserID == request.UserID && existing.Status == storages.VerificationStatusPending {
+				return fmt.Errorf("pending verification request %w", storages.ErrDuplicate)
+			}
+		}
+
+		request.ID = s.newID("verification_requests")
+		request.Status = storages.VerificationStatusPending
+		request.ReviewerID = 0
+		request.ReviewComment = ""
+		request.CreatedAt = time.Now()
+		request.ReviewedAt = nil
+		set(s, s.verificationRequests, request.ID, *request)
+
+		s.logger.Infof("Created verification request %d for user %d: level %s", request.ID, request.UserID, request.Level)
+		return nil
+	})
+}
+
+// GetVerificationRequest возвращает заявку на верификацию
+func (s *MemoryStorage) GetVerificationRequest(ctx context.Context, requestID int64) (*storages.VerificationRequest, error) {
+	defer s.lock(ctx)()
+
+	request, ok := s.verificationRequests[requestID]
+	if !ok {
+		return nil, fmt.Errorf("verification request %w", storages.ErrNotFound)
+	}
+	return &request, nil
+}
+
+// ListVerificationRequests возвращает заявки на верификацию по фильтру, новые первыми
+func (s *MemoryStorage) ListVerificationRequests(ctx context.Context, filter storages.VerificationRequestFilter) ([]storages.VerificationRequest, error) {
+	defer s.lock(ctx)()
+
+	requests := make([]storages.VerificationRequest, 0)
+	for _, request := range s.verificationRequests {
+		if (filter.UserID == 0 || request.UserID == filter.UserID) && (filter.Status == "" || request.Status == filter.Status) {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID > requests[j].ID })
+	return head(requests, filter.Limit), nil
+}
+
+// ReviewVerificationRequest рассматривает заявку и при одобрении повышает
+// уровень верификации пользователя
+func (s *MemoryStorage) ReviewVerificationRequest(ctx context.Context, requestID int64, review storages.VerificationReview) (*storages.VerificationRequest, error) {
+	var request storages.VerificationRequest
+	err := s.write(ctx, func() error {
+		// 1. Получаем заявку на рассмотрении
+		var ok bool
+		request, ok = s.verificationRequests[requestID]
+		if !ok || request.Status != storages.VerificationStatusPending {
+			return fmt.Errorf("pending verification request %d %w", requestID, storages.ErrNotFound)
+		}
+
+		// 2. Записываем решение
+		reviewedAt := review.ReviewedAt
+		request.Status = review.Status
+		request.ReviewerID = review.ReviewerID
+		request.ReviewComment = review.Comment
+		request.ReviewedAt = &reviewedAt
+		set(s, s.verificationRequests, requestID, request)
+
+		// 3. Повышаем уровень пользователя
+		if review.Status == storages.VerificationStatusApproved {
+			if user, ok := s.users[request.UserID]; ok {
+				user.VerificationLevel = request.Level
+				user.UpdatedAt = time.Now()
+				set(s, s.users, user.ID, user)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Verification request %d of user %d %s by admin %d", requestID, request.UserID, review.Status, review.ReviewerID)
+	return &request, nil
+}
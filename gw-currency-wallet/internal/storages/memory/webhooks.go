@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateWebhook сохраняет вебхук
+func (s *MemoryStorage) CreateWebhook(ctx context.Context, webhook *storages.Webhook) error {
+	return s.write(ctx, func() error {
+		if _, err := s.userLocked(webhook.UserID); err != nil {
+			return err
+		}
+
+		webhook.ID = s.newID("webhooks")
+		webhook.CreatedAt = time.Now()
+		stored := *webhook
+		stored.Events = slices.Clone(webhook.Events)
+		set(s, s.webhooks, webhook.ID, stored)
+
+		s.logger.Infof("Created webhook %d for user %d", webhook.ID, webhook.UserID)
+		return nil
+	})
+}
+
+// ListWebhooks возвращает вебхуки пользователя
+func (s *MemoryStorage) ListWebhooks(ctx context.Context, userID int64) ([]storages.Webhook, error) {
+	defer s.lock(ctx)()
+
+	webhooks := []storages.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhook.Events = slices.Clone(webhook.Events)
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя вместе с его доставками
+func (s *MemoryStorage) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	return s.write(ctx, func() error {
+		webhook, ok := s.webhooks[webhookID]
+		if !ok || webhook.UserID != userID {
+			return fmt.Errorf("webhook %w", storages.ErrNotFound)
+		}
+
+		remove(s, s.webhooks, webhookID)
+		for id, delivery := range s.deliveries {
+			if delivery.WebhookID == webhookID {
+				remove(s, s.deliveries, id)
+			}
+		}
+
+		s.logger.Infof("Deleted webhook %d of user %d", webhookID, userID)
+		return nil
+	})
+}
+
+// EnqueueWebhookEvent создает доставки события вебхукам пользователя, в списке
+// событий которых есть его тип
+func (s *MemoryStorage) EnqueueWebhookEvent(ctx context.Context, event storages.WebhookEvent) (int64, error) {
+	var enqueued int64
+	err := s.write(ctx, func() error {
+		var webhookIDs []int64
+		for id, webhook := range s.webhooks {
+			if webhook.UserID == event.UserID && slices.Contains(webhook.Events, event.Type) {
+				webhookIDs = append(webhookIDs, id)
+			}
+		}
+		slices.Sort(webhookIDs)
+
+		for _, webhookID := range webhookIDs {
+			delivery := storages.WebhookDelivery{
+				ID:            s.newID("webhook_deliveries"),
+				WebhookID:     webhookID,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				Payload:       slices.Clone(event.Payload),
+				Status:        storages.WebhookDeliveryPending,
+				NextAttemptAt: event.CreatedAt,
+				CreatedAt:     event.CreatedAt,
+			}
+			set(s, s.deliveries, delivery.ID, delivery)
+			enqueued++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return enqueued, nil
+}
+
+// ListWebhookDeliveries возвращает последние доставки вебхука пользователя
+func (s *MemoryStorage) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]storages.WebhookDelivery, error) {
+	defer s.lock(ctx)()
+
+	deliveries := []storages.WebhookDelivery{}
+	if webhook, ok := s.webhooks[webhookID]; !ok || webhook.UserID != userID {
+		return deliveries, nil
+	}
+
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	return head(deliveries, limit), nil
+}
+
+// ListDueWebhookDeliveries возвращает ожидающие доставки, время попытки которых наступило,
+// вместе с адресом и секретом вебхука
+func (s *MemoryStorage) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]storages.WebhookDelivery, error) {
+	defer s.lock(ctx)()
+
+	deliveries := []storages.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		webhook, ok := s.webhooks[delivery.WebhookID]
+		if !ok || delivery.Status != storages.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.URL, delivery.Secret = webhook.URL, webhook.Secret
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.NextAttemptAt.Equal(b.NextAttemptAt) {
+			return a.NextAttemptAt.Before(b.NextAttemptAt)
+		}
+		return a.ID < b.ID
+	})
+	return head(deliveries, limit), nil
+}
+
+// RecordWebhookAttempt сохраняет результат попытки, если предыдущая попытка последняя записанная
+func (s *MemoryStorage) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt storages.WebhookAttempt) (bool, error) {
+	var recorded bool
+	err := s.write(ctx, func() error {
+		delivery, ok := s.deliveries[deliveryID]
+		if !ok || delivery.Attempts != attempt.Attempt-1 || delivery.Status != storages.WebhookDeliveryPending {
+			return nil
+		}
+
+		at := attempt.At
+		delivery.Status = attempt.Status
+		delivery.Attempts = attempt.Attempt
+		delivery.ResponseCode = attempt.ResponseCode
+		delivery.LastError = attempt.Error
+		delivery.NextAttemptAt = attempt.NextAttemptAt
+		if attempt.Status != storages.WebhookDeliveryPending {
+			delivery.NextAttemptAt = at
+		}
+		delivery.DeliveredAt = nil
+		if attempt.Status == storages.WebhookDeliveryDelivered {
+			delivery.DeliveredAt = &at
+		}
+		set(s, s.deliveries, deliveryID, delivery)
+
+		recorded = true
+		return nil
+	})
+	return recorded, err
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreatePendingWithdraw создает ожидающий вывод и удерживает сумму с комиссией
+// атомарно. Удержание изменяется на сохраненные в транзакции суммы, поэтому
+// снятие удержания при подтверждении или отмене возвращает его точно к прежнему значению
+func (s *MemoryStorage) CreatePendingWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64) (int64, error) {
+	var txID int64
+	err := s.write(ctx, func() error {
+		// 1. Проверяем достаточность средств, не удержанных другими выводами
+		if err := s.checkAvailableLocked(userID, currency, amount+fee); err != nil {
+			return err
+		}
+
+		// 2. Создаем транзакцию в статусе pending
+		tx := storages.Transaction{
+			ID:           s.newID("transactions"),
+			UserID:       userID,
+			Type:         storages.TransactionTypeWithdraw,
+			FromCurrency: currency,
+			ToCurrency:   currency,
+			FromAmount:   amount,
+			ToAmount:     amount,
+			ExchangeRate: 1,
+			MarketRate:   1,
+			Status:       storages.TransactionStatusPending,
+			Fee:          fee,
+			CreatedAt:    time.Now(),
+		}
+		set(s, s.transactions, tx.ID, tx)
+		txID = tx.ID
+
+		// 3. Удерживаем сумму с комиссией
+		s.changeHoldLocked(&tx, 1)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Pending withdrawal created: ID=%d, User=%d, %.2f %s, fee %.2f", txID, userID, amount, currency, fee)
+	return txID, nil
+}
+
+// ListPendingWithdrawals возвращает ожидающие выводы по фильтру от старых к новым
+func (s *MemoryStorage) ListPendingWithdrawals(ctx context.Context, filter storages.PendingWithdrawalFilter) ([]storages.Transaction, error) {
+	defer s.lock(ctx)()
+
+	withdrawals := make([]storages.Transaction, 0)
+	for _, tx := range s.transactions {
+		switch {
+		case tx.Status != storages.TransactionStatusPending || tx.Type != storages.TransactionTypeWithdraw:
+		case filter.UserID != 0 && tx.UserID != filter.UserID:
+		case !filter.CreatedBefore.IsZero() && !tx.CreatedAt.Before(filter.CreatedBefore):
+		case filter.MaxAmount != 0 && tx.FromAmount > filter.MaxAmount:
+		default:
+			withdrawals = append(withdrawals, tx)
+		}
+	}
+	sort.Slice(withdrawals, func(i, j int) bool { return newerThan(&withdrawals[j], &withdrawals[i]) })
+
+	return head(withdrawals, filter.Limit), nil
+}
+
+// CompleteWithdraw проводит ожидающий вывод. Смена статуса выполняется только
+// из pending, поэтому подтверждение и отмена одного вывода не выполняются оба
+func (s *MemoryStorage) CompleteWithdraw(ctx context.Context, txID int64, notify bool) (*storages.Transaction, error) {
+	var withdrawal *storages.Transaction
+	err := s.write(ctx, func() error {
+		var err error
+		if withdrawal, err = s.claimPendingWithdrawLocked(txID, storages.TransactionStatusCompleted); err != nil {
+			return err
+		}
+
+		s.changeHoldLocked(withdrawal, -1)
+		if err := s.applyEntryLocked(withdrawal.UserID, withdrawal.FromCurrency, -withdrawal.FromAmount, txID, storages.TransactionTypeWithdraw); err != nil {
+			return err
+		}
+		if err := s.insertFeeLocked(withdrawal.UserID, withdrawal.FromCurrency, withdrawal.Fee); err != nil {
+			return err
+		}
+		if notify {
+			s.insertOutboxLocked(txID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Withdrawal completed: ID=%d, User=%d, %.2f %s", txID, withdrawal.UserID, withdrawal.FromAmount, withdrawal.FromCurrency)
+	return withdrawal, nil
+}
+
+// CancelWithdraw снимает удержание ожидающего вывода и переводит его в status
+func (s *MemoryStorage) CancelWithdraw(ctx context.Context, txID int64, status string) (*storages.Transaction, error) {
+	var withdrawal *storages.Transaction
+	err := s.write(ctx, func() error {
+		var err error
+		if withdrawal, err = s.claimPendingWithdrawLocked(txID, status); err != nil {
+			return err
+		}
+		s.changeHoldLocked(withdrawal, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Withdrawal %s: ID=%d, User=%d, %.2f %s", status, txID, withdrawal.UserID, withdrawal.FromAmount, withdrawal.FromCurrency)
+	return withdrawal, nil
+}
+
+// claimPendingWithdrawLocked переводит ожидающий вывод в status и возвращает его
+// с новым статусом. Вызывается под s.mu
+func (s *MemoryStorage) claimPendingWithdrawLocked(txID int64, status string) (*storages.Transaction, error) {
+	withdrawal, ok := s.transactions[txID]
+	if !ok {
+		return nil, fmt.Errorf("transaction %w", storages.ErrNotFound)
+	}
+	if withdrawal.Type != storages.TransactionTypeWithdraw {
+		return nil, fmt.Errorf("pending withdrawal %d %w", txID, storages.ErrNotFound)
+	}
+
+	if err := s.updateStatusLocked(txID, storages.TransactionStatusPending, status); err != nil {
+		return nil, err
+	}
+
+	withdrawal = s.transactions[txID]
+	return &withdrawal, nil
+}
+
+// changeHoldLocked увеличивает (sign 1) или уменьшает (sign -1) удержание баланса
+// на сумму с комиссией вывода. Вызывается под s.mu
+func (s *MemoryStorage) changeHoldLocked(withdrawal *storages.Transaction, sign float64) {
+	key := balanceKey{withdrawal.UserID, withdrawal.FromCurrency}
+	balance, ok := s.balances[key]
+	if !ok {
+		return
+	}
+
+	balance.Held += sign * (withdrawal.FromAmount + withdrawal.Fee)
+	balance.UpdatedAt = time.Now()
+	set(s, s.balances, key, balance)
+}
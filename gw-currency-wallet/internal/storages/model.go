@@ -1,6 +1,9 @@
 package storages
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User представляет пользователя системы
 type User struct {
@@ -8,8 +11,63 @@ type User struct {
 	Username     string    `db:"username"`
 	Email        string    `db:"email"`
 	PasswordHash string    `db:"password_hash"`
+	Role         string    `db:"role"` // user, admin
 	CreatedAt    time.Time `db:"created_at"`
 	UpdatedAt    time.Time `db:"updated_at"`
+	// Status статус учетной записи: active, frozen, closed. Заморозка запрещает
+	// выводы и обмены до FrozenUntil (nil - до снятия заморозки), закрытие - все операции
+	Status          string     `db:"account_status"`
+	FrozenUntil     *time.Time `db:"frozen_until"`
+	StatusReason    string     `db:"status_reason"`
+	StatusChangedAt *time.Time `db:"status_changed_at"`
+	// VerificationLevel уровень верификации: unverified, basic, full. От уровня
+	// зависят лимиты выводов и обменов
+	VerificationLevel string `db:"verification_level"`
+	// DeletedAt время удаления учетной записи пользователем: персональные данные
+	// обезличены, учетная запись закрыта без возможности открыть ее снова
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// Frozen сообщает, что на момент now выводы и обмены пользователя заморожены.
+// Истекшая временная заморозка не действует
+func (u *User) Frozen(now time.Time) bool {
+	if u.Status != AccountStatusFrozen {
+		return false
+	}
+	return u.FrozenUntil == nil || now.Before(*u.FrozenUntil)
+}
+
+// UserStatusChange изменение статуса учетной записи
+type UserStatusChange struct {
+	Status      string
+	FrozenUntil *time.Time
+	Reason      string
+	// ChangedAt время изменения; изменение не применяется, если статус
+	// уже менялся в это время или позже
+	ChangedAt time.Time
+	// ChangedBy ID администратора, 0 - автоматическое изменение
+	ChangedBy int64
+}
+
+// UserDeletion удаление учетной записи: имя пользователя и email заменяются
+// обезличенными значениями, финансовые записи сохраняются для аудита
+type UserDeletion struct {
+	Username  string
+	Email     string
+	Reason    string
+	DeletedAt time.Time
+}
+
+// AccountStatusEvent запись истории статуса учетной записи для аудита
+type AccountStatusEvent struct {
+	ID             int64      `db:"id" json:"id"`
+	UserID         int64      `db:"user_id" json:"user_id"`
+	Status         string     `db:"status" json:"status"`
+	PreviousStatus string     `db:"previous_status" json:"previous_status"`
+	FrozenUntil    *time.Time `db:"frozen_until" json:"frozen_until,omitempty"`
+	Reason         string     `db:"reason" json:"reason"`
+	ChangedBy      int64      `db:"changed_by" json:"changed_by"` // 0 - автоматическое изменение
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
 }
 
 // Balance представляет баланс пользователя в определенной валюте
@@ -18,42 +76,437 @@ type Balance struct {
 	UserID    int64     `db:"user_id"`
 	Currency  string    `db:"currency"`
 	Amount    float64   `db:"amount"`
+	Held      float64   `db:"held_amount"` // удерживается ожидающими выводами, доступно Amount - Held
 	UpdatedAt time.Time `db:"updated_at"`
 	CreatedAt time.Time `db:"created_at"`
 }
 
 // Transaction представляет транзакцию (пополнение, вывод, обмен)
 type Transaction struct {
-	ID              int64     `db:"id"`
-	UserID          int64     `db:"user_id"`
-	Type            string    `db:"type"` // deposit, withdraw, exchange
-	FromCurrency    string    `db:"from_currency"`
-	ToCurrency      string    `db:"to_currency"`
-	FromAmount      float64   `db:"from_amount"`
-	ToAmount        float64   `db:"to_amount"`
-	ExchangeRate    float64   `db:"exchange_rate"`
-	Status          string    `db:"status"` // pending, completed, failed
-	CreatedAt       time.Time `db:"created_at"`
-	CompletedAt     *time.Time `db:"completed_at"`
+	ID           int64      `db:"id"`
+	UserID       int64      `db:"user_id"`
+	Type         string     `db:"type"` // deposit, withdraw, exchange
+	FromCurrency string     `db:"from_currency"`
+	ToCurrency   string     `db:"to_currency"`
+	FromAmount   float64    `db:"from_amount"`
+	ToAmount     float64    `db:"to_amount"`
+	ExchangeRate float64    `db:"exchange_rate"` // курс для клиента (с наценкой)
+	MarketRate   float64    `db:"market_rate"`   // курс exchanger без наценки
+	Margin       float64    `db:"margin"`        // наценка, доля от курса
+	Source       string     `db:"source"`        // api, scheduled, admin
+	Status       string     `db:"status"`        // pending, completed, failed, cancelled
+	Fee          float64    `db:"fee"`           // комиссия ожидающего вывода, удерживается вместе с суммой
+	CreatedAt    time.Time  `db:"created_at"`
+	CompletedAt  *time.Time `db:"completed_at"`
+}
+
+// OutboxEntry запись outbox: уведомление о транзакции, ожидающее отправки в Kafka
+type OutboxEntry struct {
+	ID        int64     `db:"id"`
+	Attempts  int       `db:"attempts"`
+	LastError string    `db:"last_error"`
+	CreatedAt time.Time `db:"created_at"`
+	// NextAttemptAt время следующей попытки после неудачной, nil - без ожидания
+	NextAttemptAt *time.Time `db:"next_attempt_at"`
+	SentAt        *time.Time `db:"sent_at"`
+	// ParkedAt время, когда запись исчерпала попытки: relay ее больше не выбирает
+	ParkedAt    *time.Time `db:"parked_at"`
+	Transaction Transaction
 }
 
+// UserRole определяет роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// AccountStatus определяет статусы учетной записи
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// VerificationLevel определяет уровни верификации по возрастанию
+const (
+	VerificationLevelUnverified = "unverified"
+	VerificationLevelBasic      = "basic"
+	VerificationLevelFull       = "full"
+)
+
+// VerificationStatus определяет статусы заявок на верификацию
+const (
+	VerificationStatusPending  = "pending"
+	VerificationStatusApproved = "approved"
+	VerificationStatusRejected = "rejected"
+)
+
 // TransactionType определяет типы транзакций
 const (
 	TransactionTypeDeposit  = "deposit"
 	TransactionTypeWithdraw = "withdraw"
 	TransactionTypeExchange = "exchange"
+	// TransactionTypeFee комиссия за вывод или обмен, списывается отдельной записью
+	TransactionTypeFee = "fee"
+)
+
+// ExchangeSource определяет источники операций обмена, для каждого настраивается своя наценка
+const (
+	ExchangeSourceAPI       = "api"
+	ExchangeSourceScheduled = "scheduled"
+	ExchangeSourceAdmin     = "admin"
 )
 
+// ExchangeQuote курс, по которому проводится обмен
+type ExchangeQuote struct {
+	Source     string
+	MarketRate float64 // курс exchanger
+	Margin     float64 // наценка, доля от курса
+	Rate       float64 // курс для клиента: MarketRate * (1 - Margin)
+}
+
 // TransactionStatus определяет статусы транзакций
 const (
 	TransactionStatusPending   = "pending"
 	TransactionStatusCompleted = "completed"
 	TransactionStatusFailed    = "failed"
+	// TransactionStatusCancelled вывод отменен пользователем до подтверждения
+	TransactionStatusCancelled = "cancelled"
+)
+
+// Размер страницы истории транзакций
+const (
+	DefaultTransactionPageSize = 50
+	MaxTransactionPageSize     = 500
+)
+
+// TransactionFilter параметры выборки истории транзакций пользователя.
+// Транзакции возвращаются от новых к старым
+type TransactionFilter struct {
+	// Limit размер страницы: 0 - DefaultTransactionPageSize, не больше MaxTransactionPageSize
+	Limit int
+	// Offset пропускает первые записи. Для глубокой истории используйте Cursor
+	Offset int
+	// Cursor NextCursor предыдущей страницы: возвращаются транзакции старше нее
+	Cursor int64
+	// From и To ограничивают created_at: From <= created_at < To, нулевое время - без границы
+	From time.Time
+	To   time.Time
+	// Types и Statuses оставляют транзакции перечисленных типов и статусов, пусто - все
+	Types    []string
+	Statuses []string
+}
+
+// PageSize возвращает размер страницы с учетом значения по умолчанию и максимума
+func (f TransactionFilter) PageSize() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultTransactionPageSize
+	case f.Limit > MaxTransactionPageSize:
+		return MaxTransactionPageSize
+	default:
+		return f.Limit
+	}
+}
+
+// TransactionPage страница истории транзакций
+type TransactionPage struct {
+	Transactions []Transaction
+	// Total количество транзакций по фильтру без учета страницы
+	Total int64
+	// NextCursor курсор следующей страницы (ID последней транзакции), 0 - страница последняя
+	NextCursor int64
+}
+
+// PendingWithdrawalFilter параметры выборки ожидающих подтверждения выводов.
+// Выводы возвращаются от старых к новым
+type PendingWithdrawalFilter struct {
+	// UserID выводы пользователя, 0 - всех пользователей
+	UserID int64
+	// CreatedBefore выводы, созданные раньше, нулевое время - без границы
+	CreatedBefore time.Time
+	// MaxAmount выводы на сумму не больше, 0 - без ограничения
+	MaxAmount float64
+	Limit     int
+}
+
+// UserBalances представляет балансы пользователя во всех валютах (код валюты -> сумма)
+type UserBalances map[string]float64
+
+// LimitPeriod определяет периоды лимитов на операции
+const (
+	LimitPeriodDaily   = "daily"
+	LimitPeriodMonthly = "monthly"
+)
+
+// Limit ограничение суммы операций пользователя в валюте списания за период
+type Limit struct {
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Operation string    `db:"operation" json:"operation"` // withdraw, exchange
+	Currency  string    `db:"currency" json:"currency"`
+	Period    string    `db:"period" json:"period"` // daily, monthly
+	Amount    float64   `db:"amount" json:"amount"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// LedgerViolationType определяет виды нарушений инвариантов учета
+const (
+	LedgerViolationBalanceMismatch   = "balance_mismatch"
+	LedgerViolationNegativeBalance   = "negative_balance"
+	LedgerViolationOrphanTransaction = "orphan_transaction"
+	// LedgerViolationBalanceDrift баланс не равен сумме записей журнала ledger_entries
+	LedgerViolationBalanceDrift = "balance_drift"
+	// LedgerViolationHoldMismatch удержание баланса не равно сумме ожидающих выводов
+	LedgerViolationHoldMismatch = "hold_mismatch"
+)
+
+// LedgerViolation описывает нарушение инварианта учета для пользователя
+type LedgerViolation struct {
+	Type          string  `json:"type"`
+	UserID        int64   `json:"user_id"`
+	Currency      string  `json:"currency,omitempty"`
+	TransactionID int64   `json:"transaction_id,omitempty"`
+	Balance       float64 `json:"balance"`
+	Expected      float64 `json:"expected"`
+	Details       string  `json:"details"`
+}
+
+// Виды записей журнала, не связанных с транзакцией. Остальные записи имеют
+// вид транзакции (deposit, withdraw, exchange, fee)
+const (
+	// LedgerEntryOpening баланс, существовавший до появления журнала
+	LedgerEntryOpening = "opening"
+	// LedgerEntryAdjustment ручная корректировка по результатам проверки учета
+	LedgerEntryAdjustment = "adjustment"
+)
+
+// LedgerEntry запись журнала изменений баланса. Журнал только дополняется:
+// баланс - сумма записей пользователя в валюте
+type LedgerEntry struct {
+	ID       int64  `db:"id" json:"id"`
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Currency string `db:"currency" json:"currency"`
+	// Amount положительная сумма - зачисление, отрицательная - списание
+	Amount float64 `db:"amount" json:"amount"`
+	// TransactionID транзакция, создавшая запись; сохраняется после выгрузки транзакции в архив
+	TransactionID *int64    `db:"transaction_id" json:"transaction_id,omitempty"`
+	Kind          string    `db:"kind" json:"kind"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// ScheduleOperation определяет операции, выполняемые по расписанию
+const (
+	ScheduleOperationDeposit = "deposit"
+	// ScheduleOperationExchange перевод между валютами пользователя по курсу с наценкой scheduled
+	ScheduleOperationExchange = "exchange"
+)
+
+// SchedulePeriod определяет периодичность регулярных операций
+const (
+	SchedulePeriodDaily   = "daily"
+	SchedulePeriodWeekly  = "weekly"
+	SchedulePeriodMonthly = "monthly"
 )
 
-// UserBalances представляет балансы пользователя во всех валютах
-type UserBalances struct {
-	USD float64 `json:"USD"`
-	EUR float64 `json:"EUR"`
-	RUB float64 `json:"RUB"`
+// Schedule регулярная операция пользователя
+type Schedule struct {
+	ID        int64  `db:"id" json:"id"`
+	UserID    int64  `db:"user_id" json:"user_id"`
+	Operation string `db:"operation" json:"operation"` // deposit, exchange
+	// FromCurrency валюта списания обмена, для пополнения пусто
+	FromCurrency string  `db:"from_currency" json:"from_currency,omitempty"`
+	ToCurrency   string  `db:"to_currency" json:"to_currency"`
+	Amount       float64 `db:"amount" json:"amount"`
+	Period       string  `db:"period" json:"period"` // daily, weekly, monthly
+	// StartAt первое выполнение; следующие отсчитываются от него
+	StartAt time.Time `db:"start_at" json:"start_at"`
+	// NextRunAt плановое время ближайшего выполнения
+	NextRunAt time.Time `db:"next_run_at" json:"next_run_at"`
+	// DueAt время следующей попытки: после ошибки позже NextRunAt
+	DueAt  time.Time `db:"due_at" json:"due_at"`
+	Active bool      `db:"active" json:"active"`
+	// RunCount число завершенных (выполненных или пропущенных) запусков
+	RunCount int `db:"run_count" json:"run_count"`
+	// Attempts число неудачных попыток текущего запуска
+	Attempts  int        `db:"attempts" json:"attempts"`
+	LastRunAt *time.Time `db:"last_run_at" json:"last_run_at,omitempty"`
+	LastError string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// NextOccurrence возвращает первое плановое время после after. Время отсчитывается
+// от StartAt, поэтому пропущенные запуски не накапливаются, а ежемесячная операция,
+// начатая 31-го числа, выполняется в последний день коротких месяцев
+func (s *Schedule) NextOccurrence(after time.Time) time.Time {
+	for n := 1; ; n++ {
+		var next time.Time
+		switch s.Period {
+		case SchedulePeriodWeekly:
+			next = s.StartAt.AddDate(0, 0, 7*n)
+		case SchedulePeriodMonthly:
+			next = addMonths(s.StartAt, n)
+		default:
+			next = s.StartAt.AddDate(0, 0, n)
+		}
+		if next.After(after) {
+			return next
+		}
+	}
+}
+
+// addMonths прибавляет n месяцев, ограничивая день последним днем месяца
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// APIKey ключ доступа к API для интеграции внешних систем без учетных данных
+// пользователя. Хранится только SHA-256 ключа, сам ключ выдается один раз при создании
+type APIKey struct {
+	ID     int64  `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
+	// Prefix начало ключа, по которому пользователь отличает ключи
+	Prefix  string   `db:"prefix" json:"prefix"`
+	KeyHash string   `db:"key_hash" json:"-"`
+	Scopes  []string `db:"scopes" json:"scopes"`
+	// RateLimit запросов в секунду с ключом (не больше RateBurst подряд),
+	// 0 - ключ расходует лимит запросов пользователя
+	RateLimit float64    `db:"rate_limit" json:"rate_limit"`
+	RateBurst int        `db:"rate_burst" json:"rate_burst"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// Session сессия пользователя: вход по паролю и выданные по нему токены. ID сессии
+// записывается в токены, отозванная или истекшая сессия не принимается
+type Session struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+	// Device User-Agent клиента при входе
+	Device    string    `db:"device" json:"device"`
+	IP        string    `db:"ip" json:"ip"`
+	CreatedAt time.Time `db:"created_at" json:"issued_at"`
+	// LastUsedAt время последнего обновления access токена
+	LastUsedAt time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// Active сообщает, что на момент now сессия не отозвана и не истекла
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Типы событий вебхуков
+const (
+	WebhookEventDepositCompleted = "deposit.completed"
+	WebhookEventExchangeExecuted = "exchange.executed"
+)
+
+// WebhookEventTypes типы событий, на которые можно подписать вебхук
+var WebhookEventTypes = []string{WebhookEventDepositCompleted, WebhookEventExchangeExecuted}
+
+// Статусы доставок вебхуков
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // попытки исчерпаны
+)
+
+// Webhook адрес пользователя для уведомлений о событиях счета. Secret - ключ подписи
+// тел запросов HMAC-SHA256, возвращается только при создании
+type Webhook struct {
+	ID     int64  `db:"id" json:"id"`
+	UserID int64  `db:"user_id" json:"user_id"`
+	URL    string `db:"url" json:"url"`
+	Secret string `db:"secret" json:"-"`
+	// Events типы событий, доставляемые вебхуку
+	Events    []string  `db:"events" json:"events"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// WebhookEvent событие счета для доставки вебхукам пользователя
+type WebhookEvent struct {
+	// ID UUID события, одинаковый во всех доставках и повторах
+	ID      string
+	UserID  int64
+	Type    string
+	Payload []byte // JSON тела запроса
+	// CreatedAt время события и первой попытки доставки
+	CreatedAt time.Time
+}
+
+// WebhookDelivery доставка события вебхуку
+type WebhookDelivery struct {
+	ID        int64           `db:"id" json:"id"`
+	WebhookID int64           `db:"webhook_id" json:"webhook_id"`
+	EventID   string          `db:"event_id" json:"event_id"`
+	EventType string          `db:"event_type" json:"event_type"`
+	Payload   json.RawMessage `db:"payload" json:"payload" swaggertype:"object"`
+	Status    string          `db:"status" json:"status"`
+	Attempts  int             `db:"attempts" json:"attempts"`
+	// NextAttemptAt время следующей попытки ожидающей доставки
+	NextAttemptAt time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	// ResponseCode HTTP статус ответа последней попытки, 0 - ответа не было
+	ResponseCode int        `db:"response_code" json:"response_code,omitempty"`
+	LastError    string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	DeliveredAt  *time.Time `db:"delivered_at" json:"delivered_at,omitempty"`
+	// URL и Secret вебхука, заполняются в доставках для отправки
+	URL    string `db:"-" json:"-"`
+	Secret string `db:"-" json:"-"`
+}
+
+// WebhookAttempt результат попытки доставки вебхука
+type WebhookAttempt struct {
+	// Attempt номер попытки, начиная с 1
+	Attempt int
+	// Status delivered, pending (будет повтор в NextAttemptAt) или failed
+	Status        string
+	ResponseCode  int
+	Error         string
+	NextAttemptAt time.Time
+	At            time.Time
+}
+
+// VerificationRequest заявка пользователя на повышение уровня верификации.
+// У пользователя не больше одной заявки в статусе pending
+type VerificationRequest struct {
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+	// Level запрошенный уровень: basic или full
+	Level       string `db:"level" json:"level"`
+	FullName    string `db:"full_name" json:"full_name"`
+	DateOfBirth string `db:"date_of_birth" json:"date_of_birth"` // YYYY-MM-DD
+	Country     string `db:"country" json:"country"`             // ISO 3166-1 alpha-2
+	// DocumentType и DocumentNumber документ, обязателен для уровня full
+	DocumentType   string `db:"document_type" json:"document_type,omitempty"`
+	DocumentNumber string `db:"document_number" json:"document_number,omitempty"`
+	Status         string `db:"status" json:"status"`
+	// ReviewerID администратор, рассмотревший заявку; ReviewComment его комментарий
+	ReviewerID    int64      `db:"reviewer_id" json:"reviewer_id,omitempty"`
+	ReviewComment string     `db:"review_comment" json:"review_comment,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	ReviewedAt    *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+}
+
+// VerificationRequestFilter фильтр заявок на верификацию
+type VerificationRequestFilter struct {
+	// UserID заявки пользователя, 0 - всех пользователей
+	UserID int64
+	// Status заявки в статусе, пусто - в любом
+	Status string
+	Limit  int
+}
+
+// VerificationReview решение администратора по заявке на верификацию
+type VerificationReview struct {
+	Status     string // approved, rejected
+	ReviewerID int64
+	Comment    string
+	ReviewedAt time.Time
 }
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// apiKeyColumns колонки ключа API в порядке scanAPIKey
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, rate_limit, rate_burst, created_at, revoked_at`
+
+// scanAPIKey читает ключ API из строки результата
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (storages.APIKey, error) {
+	var key storages.APIKey
+	var scopes string
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&key.RateLimit,
+		&key.RateBurst,
+		&key.CreatedAt,
+		&revokedAt,
+	)
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, err
+}
+
+// CreateAPIKey сохраняет ключ API
+func (s *PostgresStorage) CreateAPIKey(ctx context.Context, key *storages.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, rate_limit, rate_burst, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		strings.Join(key.Scopes, ","),
+		key.RateLimit,
+		key.RateBurst,
+		now,
+	).Scan(&key.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to create API key: %v", err)
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	key.CreatedAt = now
+
+	s.logger.Infof("Created API key %d (%s) for user %d", key.ID, key.Prefix, key.UserID)
+	return nil
+}
+
+// GetAPIKeyByHash возвращает действующий ключ API по SHA-256
+func (s *PostgresStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*storages.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	key, err := scanAPIKey(s.conn(ctx).QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("API key %w", storages.ErrNotFound)
+		}
+		s.logger.Errorf("Failed to get API key: %v", err)
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys возвращает действующие ключи API пользователя
+func (s *PostgresStorage) ListAPIKeys(ctx context.Context, userID int64) ([]storages.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY id`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to query API keys: %v", err)
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []storages.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			s.logger.Errorf("Failed to scan API key: %v", err)
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating API keys: %v", err)
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает действующий ключ API пользователя
+func (s *PostgresStorage) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	if err := s.updateAPIKey(ctx, query, time.Now(), keyID, userID); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Revoked API key %d of user %d", keyID, userID)
+	return nil
+}
+
+// SetAPIKeyRateLimit задает лимит запросов действующего ключа API пользователя
+func (s *PostgresStorage) SetAPIKeyRateLimit(ctx context.Context, userID, keyID int64, rate float64, burst int) error {
+	query := `UPDATE api_keys SET rate_limit = $1, rate_burst = $2 WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL`
+
+	return s.updateAPIKey(ctx, query, rate, burst, keyID, userID)
+}
+
+// updateAPIKey выполняет изменение ключа API; ErrNotFound, если ключ не найден или отозван
+func (s *PostgresStorage) updateAPIKey(ctx context.Context, query string, args ...any) error {
+	result, err := s.conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to update API key: %v", err)
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key %w", storages.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"gw-currency-wallet/internal/storages"
+)
+
+// ListArchivableTransactions возвращает транзакции, которые можно выгрузить в архив
+func (s *PostgresStorage) ListArchivableTransactions(ctx context.Context, before time.Time, afterID int64, limit int) ([]storages.Transaction, error) {
+	query := `
+		SELECT t.id, t.user_id, t.type, t.from_currency, t.to_currency, t.from_amount, t.to_amount, t.exchange_rate,
+			COALESCE(t.market_rate, t.exchange_rate), t.margin, t.source, t.status, t.created_at, t.completed_at
+		FROM transactions t
+		WHERE t.id > $1 AND t.created_at < $2 AND t.status <> $3
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox o WHERE o.transaction_id = t.id AND o.sent_at IS NULL
+			)
+		ORDER BY t.id
+		LIMIT $4
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, afterID, before, storages.TransactionStatusPending, limit)
+	if err != nil {
+		s.logger.Errorf("Failed to query archivable transactions: %v", err)
+		return nil, fmt.Errorf("failed to query archivable transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]storages.Transaction, 0, limit)
+	for rows.Next() {
+		var tx storages.Transaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.UserID,
+			&tx.Type,
+			&tx.FromCurrency,
+			&tx.ToCurrency,
+			&tx.FromAmount,
+			&tx.ToAmount,
+			&tx.ExchangeRate,
+			&tx.MarketRate,
+			&tx.Margin,
+			&tx.Source,
+			&tx.Status,
+			&tx.CreatedAt,
+			&tx.CompletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan archivable transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan archivable transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating archivable transactions: %v", err)
+		return nil, fmt.Errorf("error iterating archivable transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// DeleteArchivedTransactions удаляет выгруженные транзакции одним запросом:
+// удаление и перенос сумм в archived_totals видны сверке учета одновременно
+func (s *PostgresStorage) DeleteArchivedTransactions(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		WITH deleted AS (
+			DELETE FROM transactions t
+			WHERE t.id = ANY($1) AND t.status <> $2
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox o WHERE o.transaction_id = t.id AND o.sent_at IS NULL
+				)
+			RETURNING t.user_id, t.type, t.status, t.from_currency, t.to_currency, t.from_amount, t.to_amount
+		), deltas AS (
+			SELECT user_id, to_currency AS currency, to_amount AS delta
+			FROM deleted
+			WHERE status = $3 AND type IN ($4, $5)
+			UNION ALL
+			SELECT user_id, from_currency AS currency, -from_amount AS delta
+			FROM deleted
+			WHERE status = $3 AND type IN ($6, $5, $7)
+		), totals AS (
+			INSERT INTO archived_totals (user_id, currency, amount, updated_at)
+			SELECT user_id, currency, SUM(delta), CURRENT_TIMESTAMP
+			FROM deltas
+			GROUP BY user_id, currency
+			ON CONFLICT (user_id, currency) DO UPDATE
+			SET amount = archived_totals.amount + EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+		)
+		SELECT COUNT(*) FROM deleted
+	`
+
+	var deleted int64
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		pq.Array(ids),
+		storages.TransactionStatusPending,
+		storages.TransactionStatusCompleted,
+		storages.TransactionTypeDeposit,
+		storages.TransactionTypeExchange,
+		storages.TransactionTypeWithdraw,
+		storages.TransactionTypeFee,
+	).Scan(&deleted)
+	if err != nil {
+		s.logger.Errorf("Failed to delete archived transactions: %v", err)
+		return 0, fmt.Errorf("failed to delete archived transactions: %w", err)
+	}
+
+	s.logger.Infof("Deleted %d archived transactions", deleted)
+	return deleted, nil
+}
@@ -21,6 +21,9 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// MigrateOnStart применяет миграции схемы при подключении. Без него
+	// сервис только предупреждает о непримененных миграциях
+	MigrateOnStart bool
 }
 
 // PostgresStorage реализует интерфейс Storage для PostgreSQL
@@ -29,8 +32,26 @@ type PostgresStorage struct {
 	logger *logrus.Logger
 }
 
-// New создает новое подключение к PostgreSQL
+// New создает новое подключение к PostgreSQL и проверяет схему БД
 func New(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
+	storage, err := Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := storage.initSchema(ctx, cfg.MigrateOnStart); err != nil {
+		storage.db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// Open подключается к PostgreSQL без проверки схемы (для команды migrate)
+func Open(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
@@ -51,75 +72,35 @@ func New(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	logger.Info("Successfully connected to PostgreSQL")
 
-	storage := &PostgresStorage{
+	return &PostgresStorage{
 		db:     db,
 		logger: logger,
-	}
+	}, nil
+}
 
-	// Инициализация схемы БД
-	if err := storage.initSchema(ctx); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+// initSchema применяет миграции схемы или, если migrate выключен, проверяет,
+// что все миграции применены
+func (s *PostgresStorage) initSchema(ctx context.Context, migrate bool) error {
+	if migrate {
+		return s.MigrateUp(ctx)
 	}
 
-	return storage, nil
-}
-
-// initSchema создает необходимые таблицы, если они не существуют
-func (s *PostgresStorage) initSchema(ctx context.Context) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		username VARCHAR(50) UNIQUE NOT NULL,
-		email VARCHAR(100) UNIQUE NOT NULL,
-		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS balances (
-		id SERIAL PRIMARY KEY,
-		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-		currency VARCHAR(3) NOT NULL,
-		amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(user_id, currency),
-		CHECK (amount >= 0)
-	);
-
-	CREATE TABLE IF NOT EXISTS transactions (
-		id SERIAL PRIMARY KEY,
-		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-		type VARCHAR(20) NOT NULL,
-		from_currency VARCHAR(3),
-		to_currency VARCHAR(3),
-		from_amount NUMERIC(20, 8),
-		to_amount NUMERIC(20, 8),
-		exchange_rate NUMERIC(20, 8),
-		status VARCHAR(20) NOT NULL DEFAULT 'pending',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		completed_at TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	CREATE INDEX IF NOT EXISTS idx_balances_user_currency ON balances(user_id, currency);
-	CREATE INDEX IF NOT EXISTS idx_transactions_user ON transactions(user_id);
-	CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
-	CREATE INDEX IF NOT EXISTS idx_transactions_created ON transactions(created_at DESC);
-	`
-
-	_, err := s.db.ExecContext(ctx, schema)
+	status, err := s.MigrationStatus(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database schema version %d is dirty, fix it manually", status.Version)
+	}
+	if status.Pending() {
+		s.logger.Warnf("Database schema is at version %d, latest is %d: run migrate up", status.Version, status.Latest)
 	}
-
-	s.logger.Info("Database schema initialized")
 	return nil
 }
 
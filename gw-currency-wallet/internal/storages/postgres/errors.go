@@ -0,0 +1,16 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation код ошибки PostgreSQL при нарушении ограничения уникальности
+const uniqueViolation = pq.ErrorCode("23505")
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением ограничения уникальности
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
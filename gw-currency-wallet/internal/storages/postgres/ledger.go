@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// ledgerTolerance допустимое расхождение баланса и суммы транзакций,
+// возникающее из-за округления float64 при обновлении балансов
+const ledgerTolerance = 0.000001
+
+// FindLedgerViolations проверяет инварианты учета по всем пользователям
+func (s *PostgresStorage) FindLedgerViolations(ctx context.Context) ([]storages.LedgerViolation, error) {
+	var violations []storages.LedgerViolation
+
+	checks := []func(ctx context.Context) ([]storages.LedgerViolation, error){
+		s.findBalanceMismatches,
+		s.findNegativeBalances,
+		s.findOrphanTransactions,
+		s.FindBalanceDrift,
+		s.findHoldMismatches,
+	}
+	for _, check := range checks {
+		found, err := check(ctx)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, found...)
+	}
+
+	s.logger.Debugf("Ledger check found %d violations", len(violations))
+	return violations, nil
+}
+
+// findBalanceMismatches находит балансы, не совпадающие с суммой проведенных транзакций.
+// Суммы транзакций, выгруженных в архив, берутся из archived_totals
+func (s *PostgresStorage) findBalanceMismatches(ctx context.Context) ([]storages.LedgerViolation, error) {
+	query := `
+		WITH deltas AS (
+			SELECT user_id, to_currency AS currency, to_amount AS delta
+			FROM transactions
+			WHERE status = $1 AND type IN ($2, $3)
+			UNION ALL
+			SELECT user_id, from_currency AS currency, -from_amount AS delta
+			FROM transactions
+			WHERE status = $1 AND type IN ($4, $3, $6)
+			UNION ALL
+			SELECT user_id, currency, amount AS delta
+			FROM archived_totals
+		), totals AS (
+			SELECT user_id, currency, SUM(delta) AS total
+			FROM deltas
+			GROUP BY user_id, currency
+		)
+		SELECT b.user_id, b.currency, b.amount, COALESCE(t.total, 0)
+		FROM balances b
+		LEFT JOIN totals t ON t.user_id = b.user_id AND t.currency = b.currency
+		WHERE ABS(b.amount - COALESCE(t.total, 0)) > $5
+		ORDER BY b.user_id, b.currency
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query,
+		storages.TransactionStatusCompleted,
+		storages.TransactionTypeDeposit,
+		storages.TransactionTypeExchange,
+		storages.TransactionTypeWithdraw,
+		ledgerTolerance,
+		storages.TransactionTypeFee,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to query balance mismatches: %v", err)
+		return nil, fmt.Errorf("failed to query balance mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []storages.LedgerViolation
+	for rows.Next() {
+		v := storages.LedgerViolation{Type: storages.LedgerViolationBalanceMismatch}
+		if err := rows.Scan(&v.UserID, &v.Currency, &v.Balance, &v.Expected); err != nil {
+			s.logger.Errorf("Failed to scan balance mismatch: %v", err)
+			return nil, fmt.Errorf("failed to scan balance mismatch: %w", err)
+		}
+		v.Details = fmt.Sprintf("balance %.8f differs from sum of completed transactions %.8f", v.Balance, v.Expected)
+		violations = append(violations, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating balance mismatches: %v", err)
+		return nil, fmt.Errorf("error iterating balance mismatches: %w", err)
+	}
+
+	return violations, nil
+}
+
+// findNegativeBalances находит отрицательные балансы
+func (s *PostgresStorage) findNegativeBalances(ctx context.Context) ([]storages.LedgerViolation, error) {
+	query := `
+		SELECT user_id, currency, amount
+		FROM balances
+		WHERE amount < 0
+		ORDER BY user_id, currency
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Errorf("Failed to query negative balances: %v", err)
+		return nil, fmt.Errorf("failed to query negative balances: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []storages.LedgerViolation
+	for rows.Next() {
+		v := storages.LedgerViolation{Type: storages.LedgerViolationNegativeBalance}
+		if err := rows.Scan(&v.UserID, &v.Currency, &v.Balance); err != nil {
+			s.logger.Errorf("Failed to scan negative balance: %v", err)
+			return nil, fmt.Errorf("failed to scan negative balance: %w", err)
+		}
+		v.Details = fmt.Sprintf("balance is negative: %.8f", v.Balance)
+		violations = append(violations, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating negative balances: %v", err)
+		return nil, fmt.Errorf("error iterating negative balances: %w", err)
+	}
+
+	return violations, nil
+}
+
+// findOrphanTransactions находит транзакции без пользователя, а также
+// проведенные транзакции по валюте, в которой у пользователя нет баланса
+func (s *PostgresStorage) findOrphanTransactions(ctx context.Context) ([]storages.LedgerViolation, error) {
+	query := `
+		SELECT t.id, t.user_id, COALESCE(t.from_currency, ''), 'user does not exist'
+		FROM transactions t
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE u.id IS NULL
+		UNION ALL
+		SELECT DISTINCT t.id, t.user_id, c.currency, 'no balance for transaction currency'
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id
+		CROSS JOIN LATERAL (VALUES (t.from_currency), (t.to_currency)) AS c(currency)
+		WHERE t.status = $1
+			AND c.currency IS NOT NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM balances b
+				WHERE b.user_id = t.user_id AND b.currency = c.currency
+			)
+		ORDER BY 2, 1
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, storages.TransactionStatusCompleted)
+	if err != nil {
+		s.logger.Errorf("Failed to query orphan transactions: %v", err)
+		return nil, fmt.Errorf("failed to query orphan transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []storages.LedgerViolation
+	for rows.Next() {
+		v := storages.LedgerViolation{Type: storages.LedgerViolationOrphanTransaction}
+		if err := rows.Scan(&v.TransactionID, &v.UserID, &v.Currency, &v.Details); err != nil {
+			s.logger.Errorf("Failed to scan orphan transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan orphan transaction: %w", err)
+		}
+		violations = append(violations, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating orphan transactions: %v", err)
+		return nil, fmt.Errorf("error iterating orphan transactions: %w", err)
+	}
+
+	return violations, nil
+}
+
+// findHoldMismatches находит балансы, удержание которых не равно сумме
+// ожидающих выводов с комиссиями
+func (s *PostgresStorage) findHoldMismatches(ctx context.Context) ([]storages.LedgerViolation, error) {
+	query := `
+		WITH pending AS (
+			SELECT user_id, from_currency AS currency, SUM(from_amount + fee) AS total
+			FROM transactions
+			WHERE status = $1 AND type = $2
+			GROUP BY user_id, from_currency
+		)
+		SELECT b.user_id, b.currency, b.held_amount, COALESCE(p.total, 0)
+		FROM balances b
+		LEFT JOIN pending p ON p.user_id = b.user_id AND p.currency = b.currency
+		WHERE ABS(b.held_amount - COALESCE(p.total, 0)) > $3
+		ORDER BY b.user_id, b.currency
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query,
+		storages.TransactionStatusPending,
+		storages.TransactionTypeWithdraw,
+		ledgerTolerance,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to query hold mismatches: %v", err)
+		return nil, fmt.Errorf("failed to query hold mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []storages.LedgerViolation
+	for rows.Next() {
+		v := storages.LedgerViolation{Type: storages.LedgerViolationHoldMismatch}
+		if err := rows.Scan(&v.UserID, &v.Currency, &v.Balance, &v.Expected); err != nil {
+			s.logger.Errorf("Failed to scan hold mismatch: %v", err)
+			return nil, fmt.Errorf("failed to scan hold mismatch: %w", err)
+		}
+		v.Details = fmt.Sprintf("held amount %.8f differs from sum of pending withdrawals %.8f", v.Balance, v.Expected)
+		violations = append(violations, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating hold mismatches: %v", err)
+		return nil, fmt.Errorf("error iterating hold mismatches: %w", err)
+	}
+
+	return violations, nil
+}
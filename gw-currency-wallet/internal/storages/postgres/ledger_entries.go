@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// insertEntry добавляет запись журнала внутри tx. txID 0 - запись без транзакции
+func (s *PostgresStorage) insertEntry(ctx context.Context, tx querier, userID int64, currency string, amount float64, txID int64, kind string) error {
+	var transactionID *int64
+	if txID != 0 {
+		transactionID = &txID
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_entries (user_id, currency, amount, transaction_id, kind, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, currency, amount, transactionID, kind, time.Now())
+	if err != nil {
+		s.logger.Errorf("Failed to create ledger entry: %v", err)
+		return fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+	return nil
+}
+
+// applyEntry добавляет запись журнала и изменяет баланс-агрегат на ее сумму.
+// Баланс меняется только относительным обновлением, поэтому параллельные операции
+// не перезаписывают изменения друг друга. Строки баланса может не быть, если
+// валюта добавлена после регистрации пользователя: тогда она создается.
+// Upsert не подходит для списаний: CHECK (amount >= 0) проверяется на
+// вставляемой строке до разрешения конфликта
+func (s *PostgresStorage) applyEntry(ctx context.Context, tx querier, userID int64, currency string, amount float64, txID int64, kind string) error {
+	if err := s.insertEntry(ctx, tx, userID, currency, amount, txID, kind); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE balances SET amount = amount + $1, updated_at = $2
+		WHERE user_id = $3 AND currency = $4
+	`, amount, now, userID, currency)
+	if err != nil {
+		s.logger.Errorf("Failed to update balance: %v", err)
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if affected > 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO balances (user_id, currency, amount, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (user_id, currency)
+		DO UPDATE SET amount = balances.amount + EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+	`, userID, currency, amount, now)
+	if err != nil {
+		s.logger.Errorf("Failed to create balance: %v", err)
+		return fmt.Errorf("failed to create balance: %w", err)
+	}
+	return nil
+}
+
+// FindBalanceDrift находит балансы, не равные сумме записей журнала
+func (s *PostgresStorage) FindBalanceDrift(ctx context.Context) ([]storages.LedgerViolation, error) {
+	query := `
+		WITH totals AS (
+			SELECT user_id, currency, SUM(amount) AS total
+			FROM ledger_entries
+			GROUP BY user_id, currency
+		)
+		SELECT COALESCE(b.user_id, t.user_id), COALESCE(b.currency, t.currency),
+			COALESCE(b.amount, 0), COALESCE(t.total, 0)
+		FROM balances b
+		FULL JOIN totals t ON t.user_id = b.user_id AND t.currency = b.currency
+		WHERE ABS(COALESCE(b.amount, 0) - COALESCE(t.total, 0)) > $1
+		ORDER BY 1, 2
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, ledgerTolerance)
+	if err != nil {
+		s.logger.Errorf("Failed to query balance drift: %v", err)
+		return nil, fmt.Errorf("failed to query balance drift: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []storages.LedgerViolation
+	for rows.Next() {
+		v := storages.LedgerViolation{Type: storages.LedgerViolationBalanceDrift}
+		if err := rows.Scan(&v.UserID, &v.Currency, &v.Balance, &v.Expected); err != nil {
+			s.logger.Errorf("Failed to scan balance drift: %v", err)
+			return nil, fmt.Errorf("failed to scan balance drift: %w", err)
+		}
+		v.Details = fmt.Sprintf("balance %.8f differs from sum of ledger entries %.8f", v.Balance, v.Expected)
+		violations = append(violations, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating balance drift: %v", err)
+		return nil, fmt.Errorf("error iterating balance drift: %w", err)
+	}
+
+	return violations, nil
+}
+
+// RebuildBalances пересчитывает балансы из журнала. Таблица balances блокируется
+// от изменений: операции, начатые раньше, применят свои относительные изменения
+// к пересчитанным балансам после фиксации
+func (s *PostgresStorage) RebuildBalances(ctx context.Context) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE balances IN EXCLUSIVE MODE`); err != nil {
+		s.logger.Errorf("Failed to lock balances: %v", err)
+		return 0, fmt.Errorf("failed to lock balances: %w", err)
+	}
+
+	var rebuilt int64
+	queries := []string{
+		`INSERT INTO balances (user_id, currency, amount, created_at, updated_at)
+		SELECT user_id, currency, SUM(amount), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM ledger_entries
+		GROUP BY user_id, currency
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET amount = EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+		WHERE balances.amount <> EXCLUDED.amount`,
+		`UPDATE balances b
+		SET amount = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE b.amount <> 0 AND NOT EXISTS (
+			SELECT 1 FROM ledger_entries e WHERE e.user_id = b.user_id AND e.currency = b.currency
+		)`,
+	}
+	for _, query := range queries {
+		result, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			s.logger.Errorf("Failed to rebuild balances: %v", err)
+			return 0, fmt.Errorf("failed to rebuild balances: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		rebuilt += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Rebuilt %d balances from ledger entries", rebuilt)
+	return rebuilt, nil
+}
+
+// GetLedgerEntries возвращает последние записи журнала пользователя
+func (s *PostgresStorage) GetLedgerEntries(ctx context.Context, userID int64, currency string, limit int) ([]storages.LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, currency, amount, transaction_id, kind, created_at
+		FROM ledger_entries
+		WHERE user_id = $1 AND ($2 = '' OR currency = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	return s.queryLedgerEntries(ctx, query, userID, currency, limit)
+}
+
+// GetLedgerEntriesSince возвращает записи журнала пользователя, созданные начиная с since
+func (s *PostgresStorage) GetLedgerEntriesSince(ctx context.Context, userID int64, since time.Time) ([]storages.LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, currency, amount, transaction_id, kind, created_at
+		FROM ledger_entries
+		WHERE user_id = $1 AND created_at >= $2
+		ORDER BY id
+	`
+
+	return s.queryLedgerEntries(ctx, query, userID, since)
+}
+
+// ListLedgerEntries возвращает записи журнала пользователя за интервал [from, to) после afterID
+func (s *PostgresStorage) ListLedgerEntries(ctx context.Context, userID int64, from, to time.Time, afterID int64, limit int) ([]storages.LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, currency, amount, transaction_id, kind, created_at
+		FROM ledger_entries
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND id > $4
+		ORDER BY id
+		LIMIT $5
+	`
+
+	return s.queryLedgerEntries(ctx, query, userID, from, to, afterID, limit)
+}
+
+// GetLedgerTotalsBefore возвращает балансы пользователя по журналу на момент before
+func (s *PostgresStorage) GetLedgerTotalsBefore(ctx context.Context, userID int64, before time.Time) (storages.UserBalances, error) {
+	query := `
+		SELECT currency, SUM(amount)
+		FROM ledger_entries
+		WHERE user_id = $1 AND created_at < $2
+		GROUP BY currency
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID, before)
+	if err != nil {
+		s.logger.Errorf("Failed to query ledger totals: %v", err)
+		return nil, fmt.Errorf("failed to query ledger totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(storages.UserBalances)
+	for rows.Next() {
+		var currency string
+		var total float64
+		if err := rows.Scan(&currency, &total); err != nil {
+			s.logger.Errorf("Failed to scan ledger total: %v", err)
+			return nil, fmt.Errorf("failed to scan ledger total: %w", err)
+		}
+		totals[currency] = total
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating ledger totals: %v", err)
+		return nil, fmt.Errorf("error iterating ledger totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// queryLedgerEntries выполняет запрос записей журнала
+func (s *PostgresStorage) queryLedgerEntries(ctx context.Context, query string, args ...interface{}) ([]storages.LedgerEntry, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query ledger entries: %v", err)
+		return nil, fmt.Errorf("failed to query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]storages.LedgerEntry, 0)
+	for rows.Next() {
+		var entry storages.LedgerEntry
+		var transactionID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Currency, &entry.Amount, &transactionID, &entry.Kind, &entry.CreatedAt); err != nil {
+			s.logger.Errorf("Failed to scan ledger entry: %v", err)
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		if transactionID.Valid {
+			entry.TransactionID = &transactionID.Int64
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating ledger entries: %v", err)
+		return nil, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+
+	return entries, nil
+}
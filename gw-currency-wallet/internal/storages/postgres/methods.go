@@ -4,28 +4,38 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"gw-currency-wallet/internal/storages"
 )
 
 // CreateUser создает нового пользователя
-func (s *PostgresStorage) CreateUser(ctx context.Context, user *storages.User) error {
+func (s *PostgresStorage) CreateUser(ctx context.Context, user *storages.User, currencies []string) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (username, email, password_hash, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
+	if user.Role == "" {
+		user.Role = storages.RoleUser
+	}
+
 	now := time.Now()
-	err := s.db.QueryRowContext(ctx, query,
+	err := s.conn(ctx).QueryRowContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
+		user.Role,
 		now,
 		now,
 	).Scan(&user.ID)
 
+	if isUniqueViolation(err) {
+		return fmt.Errorf("user %w", storages.ErrDuplicate)
+	}
+
 	if err != nil {
 		s.logger.Errorf("Failed to create user: %v", err)
 		return fmt.Errorf("failed to create user: %w", err)
@@ -33,9 +43,10 @@ func (s *PostgresStorage) CreateUser(ctx context.Context, user *storages.User) e
 
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	user.Status = storages.AccountStatusActive
+	user.VerificationLevel = storages.VerificationLevelUnverified
 
-	// Создаем начальные балансы для всех валют (0.0)
-	currencies := []string{"USD", "EUR", "RUB"}
+	// Создаем начальные балансы для всех поддерживаемых валют (0.0)
 	for _, currency := range currencies {
 		balance := &storages.Balance{
 			UserID:   user.ID,
@@ -55,23 +66,31 @@ func (s *PostgresStorage) CreateUser(ctx context.Context, user *storages.User) e
 // GetUserByUsername возвращает пользователя по имени
 func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string) (*storages.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at,
+			account_status, frozen_until, status_reason, status_changed_at, verification_level, deleted_at
 		FROM users
 		WHERE username = $1
 	`
 
 	var user storages.User
-	err := s.db.QueryRowContext(ctx, query, username).Scan(
+	err := s.conn(ctx).QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Status,
+		&user.FrozenUntil,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.VerificationLevel,
+		&user.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", storages.ErrNotFound)
 	}
 
 	if err != nil {
@@ -85,23 +104,31 @@ func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string
 // GetUserByEmail возвращает пользователя по email
 func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (*storages.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at,
+			account_status, frozen_until, status_reason, status_changed_at, verification_level, deleted_at
 		FROM users
 		WHERE email = $1
 	`
 
 	var user storages.User
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
+	err := s.conn(ctx).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Status,
+		&user.FrozenUntil,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.VerificationLevel,
+		&user.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", storages.ErrNotFound)
 	}
 
 	if err != nil {
@@ -115,23 +142,31 @@ func (s *PostgresStorage) GetUserByEmail(ctx context.Context, email string) (*st
 // GetUserByID возвращает пользователя по ID
 func (s *PostgresStorage) GetUserByID(ctx context.Context, userID int64) (*storages.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, created_at, updated_at,
+			account_status, frozen_until, status_reason, status_changed_at, verification_level, deleted_at
 		FROM users
 		WHERE id = $1
 	`
 
 	var user storages.User
-	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+	err := s.conn(ctx).QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Status,
+		&user.FrozenUntil,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.VerificationLevel,
+		&user.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", storages.ErrNotFound)
 	}
 
 	if err != nil {
@@ -142,26 +177,155 @@ func (s *PostgresStorage) GetUserByID(ctx context.Context, userID int64) (*stora
 	return &user, nil
 }
 
+// UpdateUserRole изменяет роль пользователя
+func (s *PostgresStorage) UpdateUserRole(ctx context.Context, userID int64, role string) error {
+	query := `
+		UPDATE users
+		SET role = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, role, time.Now(), userID)
+	if err != nil {
+		s.logger.Errorf("Failed to update user role: %v", err)
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+
+	s.logger.Infof("Updated role for user %d: %s", userID, role)
+	return nil
+}
+
+// UpdateUserPassword заменяет хеш пароля пользователя
+func (s *PostgresStorage) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, passwordHash, time.Now(), userID)
+	if err != nil {
+		s.logger.Errorf("Failed to update user password: %v", err)
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+
+	s.logger.Infof("Updated password for user %d", userID)
+	return nil
+}
+
+// ListUsers возвращает страницу пользователей и общее количество найденных.
+// search ищет по вхождению в username или email без учета регистра.
+func (s *PostgresStorage) ListUsers(ctx context.Context, search string, limit, offset int) ([]storages.User, int64, error) {
+	pattern := "%"
+	if search != "" {
+		pattern = "%" + escapeLike(search) + "%"
+	}
+
+	var total int64
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM users
+		WHERE username ILIKE $1 OR email ILIKE $1
+	`, pattern).Scan(&total)
+	if err != nil {
+		s.logger.Errorf("Failed to count users: %v", err)
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `
+		SELECT id, username, email, password_hash, role, created_at, updated_at,
+			account_status, frozen_until, status_reason, status_changed_at, verification_level, deleted_at
+		FROM users
+		WHERE username ILIKE $1 OR email ILIKE $1
+		ORDER BY id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, pattern, limit, offset)
+	if err != nil {
+		s.logger.Errorf("Failed to query users: %v", err)
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]storages.User, 0, limit)
+	for rows.Next() {
+		var user storages.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.PasswordHash,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.Status,
+			&user.FrozenUntil,
+			&user.StatusReason,
+			&user.StatusChangedAt,
+			&user.VerificationLevel,
+			&user.DeletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan user: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating users: %v", err)
+		return nil, 0, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(value)
+}
+
 // GetBalance возвращает баланс пользователя в конкретной валюте
 func (s *PostgresStorage) GetBalance(ctx context.Context, userID int64, currency string) (*storages.Balance, error) {
 	query := `
-		SELECT id, user_id, currency, amount, updated_at, created_at
+		SELECT id, user_id, currency, amount, held_amount, updated_at, created_at
 		FROM balances
 		WHERE user_id = $1 AND currency = $2
 	`
 
 	var balance storages.Balance
-	err := s.db.QueryRowContext(ctx, query, userID, currency).Scan(
+	err := s.conn(ctx).QueryRowContext(ctx, query, userID, currency).Scan(
 		&balance.ID,
 		&balance.UserID,
 		&balance.Currency,
 		&balance.Amount,
+		&balance.Held,
 		&balance.UpdatedAt,
 		&balance.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("balance not found")
+		return nil, fmt.Errorf("balance %w", storages.ErrNotFound)
 	}
 
 	if err != nil {
@@ -175,13 +339,13 @@ func (s *PostgresStorage) GetBalance(ctx context.Context, userID int64, currency
 // GetAllBalances возвращает все балансы пользователя
 func (s *PostgresStorage) GetAllBalances(ctx context.Context, userID int64) ([]storages.Balance, error) {
 	query := `
-		SELECT id, user_id, currency, amount, updated_at, created_at
+		SELECT id, user_id, currency, amount, held_amount, updated_at, created_at
 		FROM balances
 		WHERE user_id = $1
 		ORDER BY currency
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID)
 	if err != nil {
 		s.logger.Errorf("Failed to query balances: %v", err)
 		return nil, fmt.Errorf("failed to query balances: %w", err)
@@ -196,6 +360,7 @@ func (s *PostgresStorage) GetAllBalances(ctx context.Context, userID int64) ([]s
 			&balance.UserID,
 			&balance.Currency,
 			&balance.Amount,
+			&balance.Held,
 			&balance.UpdatedAt,
 			&balance.CreatedAt,
 		)
@@ -214,40 +379,28 @@ func (s *PostgresStorage) GetAllBalances(ctx context.Context, userID int64) ([]s
 	return balances, nil
 }
 
-// UpdateBalance обновляет баланс пользователя
-func (s *PostgresStorage) UpdateBalance(ctx context.Context, balance *storages.Balance) error {
-	query := `
-		UPDATE balances
-		SET amount = $1, updated_at = $2
-		WHERE user_id = $3 AND currency = $4
-	`
-
-	result, err := s.db.ExecContext(ctx, query,
-		balance.Amount,
-		time.Now(),
-		balance.UserID,
-		balance.Currency,
-	)
-
-	if err != nil {
-		s.logger.Errorf("Failed to update balance: %v", err)
-		return fmt.Errorf("failed to update balance: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
+// GetBalanceCurrencies возвращает валюты существующих балансов
+func (s *PostgresStorage) GetBalanceCurrencies(ctx context.Context) ([]string, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `SELECT DISTINCT currency FROM balances ORDER BY currency`)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		s.logger.Errorf("Failed to query balance currencies: %v", err)
+		return nil, fmt.Errorf("failed to query balance currencies: %w", err)
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("balance not found")
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return nil, fmt.Errorf("failed to scan balance currency: %w", err)
+		}
+		currencies = append(currencies, currency)
 	}
-
-	s.logger.Debugf("Updated balance for user %d, %s: %.2f", balance.UserID, balance.Currency, balance.Amount)
-	return nil
+	return currencies, rows.Err()
 }
 
-// CreateBalance создает новый баланс
+// CreateBalance создает новый баланс. Ненулевая начальная сумма записывается
+// в журнал в той же транзакции
 func (s *PostgresStorage) CreateBalance(ctx context.Context, balance *storages.Balance) error {
 	query := `
 		INSERT INTO balances (user_id, currency, amount, created_at, updated_at)
@@ -255,8 +408,15 @@ func (s *PostgresStorage) CreateBalance(ctx context.Context, balance *storages.B
 		RETURNING id
 	`
 
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
-	err := s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		balance.UserID,
 		balance.Currency,
 		balance.Amount,
@@ -264,14 +424,29 @@ func (s *PostgresStorage) CreateBalance(ctx context.Context, balance *storages.B
 		now,
 	).Scan(&balance.ID)
 
+	if isUniqueViolation(err) {
+		return fmt.Errorf("balance %w", storages.ErrDuplicate)
+	}
+
 	if err != nil {
 		s.logger.Errorf("Failed to create balance: %v", err)
 		return fmt.Errorf("failed to create balance: %w", err)
 	}
 
+	if balance.Amount != 0 {
+		if err := s.insertEntry(ctx, tx, balance.UserID, balance.Currency, balance.Amount, 0, storages.LedgerEntryOpening); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	balance.CreatedAt = now
 	balance.UpdatedAt = now
 
-	s.logger.Debugf("Created balance for user %d, %s: %.2f", balance.UserID, balance.Currency, balance.Amount)
+	s.logger.Debugf("Created balance for user %d: %.2f %s", balance.UserID, balance.Amount, balance.Currency)
 	return nil
 }
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS limits;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS balances;
+DROP TABLE IF EXISTS users;
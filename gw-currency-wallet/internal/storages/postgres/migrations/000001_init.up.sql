@@ -0,0 +1,73 @@
+-- Базовая схема. IF NOT EXISTS позволяет применить миграцию к базе,
+-- созданной до перехода на миграции
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(50) UNIQUE NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	role VARCHAR(20) NOT NULL DEFAULT 'user',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS balances (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	currency VARCHAR(3) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, currency),
+	CHECK (amount >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(20) NOT NULL,
+	from_currency VARCHAR(3),
+	to_currency VARCHAR(3),
+	from_amount NUMERIC(20, 8),
+	to_amount NUMERIC(20, 8),
+	exchange_rate NUMERIC(20, 8),
+	market_rate NUMERIC(20, 8),
+	margin NUMERIC(10, 8) NOT NULL DEFAULT 0,
+	source VARCHAR(20) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+	id BIGSERIAL PRIMARY KEY,
+	transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS limits (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	operation VARCHAR(20) NOT NULL,
+	currency VARCHAR(3) NOT NULL,
+	period VARCHAR(10) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, operation, currency, period),
+	CHECK (amount >= 0)
+);
+
+-- Колонки, добавленные в схему до перехода на миграции
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS market_rate NUMERIC(20, 8);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS margin NUMERIC(10, 8) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_balances_user_currency ON balances(user_id, currency);
+CREATE INDEX IF NOT EXISTS idx_transactions_user ON transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
+CREATE INDEX IF NOT EXISTS idx_transactions_created ON transactions(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_user_status_created;
+DROP INDEX IF EXISTS idx_transactions_user_type_created;
+DROP INDEX IF EXISTS idx_transactions_user_created;
+CREATE INDEX IF NOT EXISTS idx_transactions_user ON transactions(user_id);
@@ -0,0 +1,6 @@
+-- История транзакций: фильтр по пользователю (и типу или статусу), сортировка по (created_at, id).
+-- idx_transactions_user заменен индексом idx_transactions_user_created
+DROP INDEX IF EXISTS idx_transactions_user;
+CREATE INDEX IF NOT EXISTS idx_transactions_user_created ON transactions(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_type_created ON transactions(user_id, type, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_status_created ON transactions(user_id, status, created_at DESC, id DESC);
@@ -0,0 +1,9 @@
+-- Суммы проведенных транзакций, выгруженных в архив и удаленных из transactions.
+-- Сверка учета прибавляет их к сумме оставшихся транзакций
+CREATE TABLE IF NOT EXISTS archived_totals (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	currency VARCHAR(3) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, currency)
+);
@@ -0,0 +1,20 @@
+-- Журнал изменений балансов. Записи только добавляются, баланс - сумма записей
+-- пользователя в валюте. transaction_id без внешнего ключа: записи сохраняются
+-- после выгрузки транзакций в архив
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	currency VARCHAR(3) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL,
+	transaction_id INTEGER,
+	kind VARCHAR(20) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_currency ON ledger_entries(user_id, currency, id);
+
+-- Балансы, существовавшие до появления журнала, становятся начальными записями
+INSERT INTO ledger_entries (user_id, currency, amount, kind, created_at)
+SELECT user_id, currency, amount, 'opening', CURRENT_TIMESTAMP
+FROM balances
+WHERE amount <> 0;
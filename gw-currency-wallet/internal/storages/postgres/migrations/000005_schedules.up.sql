@@ -0,0 +1,27 @@
+-- Регулярные операции пользователей. next_run_at - плановое время текущего
+-- выполнения, due_at - время следующей попытки (позже next_run_at после ошибки).
+-- run_count увеличивается при каждом завершенном выполнении и защищает от
+-- повторного выполнения одного и того же запуска
+CREATE TABLE IF NOT EXISTS schedules (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	operation VARCHAR(20) NOT NULL,
+	from_currency VARCHAR(3) NOT NULL DEFAULT '',
+	to_currency VARCHAR(3) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL,
+	period VARCHAR(10) NOT NULL,
+	start_at TIMESTAMP NOT NULL,
+	next_run_at TIMESTAMP NOT NULL,
+	due_at TIMESTAMP NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	run_count INTEGER NOT NULL DEFAULT 0,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_run_at TIMESTAMP,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_user ON schedules(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules(due_at) WHERE active;
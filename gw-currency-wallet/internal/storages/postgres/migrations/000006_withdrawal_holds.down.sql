@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_pending_withdrawals;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fee;
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS balances_held_amount_check;
+ALTER TABLE balances DROP COLUMN IF EXISTS held_amount;
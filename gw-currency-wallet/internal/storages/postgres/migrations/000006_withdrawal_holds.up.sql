@@ -0,0 +1,11 @@
+-- Вывод с подтверждением: ожидающий вывод удерживает сумму с комиссией на балансе
+-- до подтверждения или отмены. held_amount - сумма удержаний, доступно amount - held_amount.
+-- fee хранит комиссию ожидающего вывода, которая списывается при подтверждении
+ALTER TABLE balances ADD COLUMN IF NOT EXISTS held_amount NUMERIC(20, 8) NOT NULL DEFAULT 0;
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS balances_held_amount_check;
+ALTER TABLE balances ADD CONSTRAINT balances_held_amount_check CHECK (held_amount >= 0 AND held_amount <= amount);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee NUMERIC(20, 8) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_pending_withdrawals ON transactions(created_at, id)
+	WHERE status = 'pending' AND type = 'withdraw';
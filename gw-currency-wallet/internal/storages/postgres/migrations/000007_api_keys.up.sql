@@ -0,0 +1,17 @@
+-- Ключи доступа к API для внешних систем. Хранится SHA-256 ключа, scopes
+-- перечисляются через запятую. Отозванные ключи остаются с revoked_at
+CREATE TABLE IF NOT EXISTS api_keys (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	prefix VARCHAR(20) NOT NULL,
+	key_hash VARCHAR(64) NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	rate_limit DOUBLE PRECISION NOT NULL DEFAULT 0,
+	rate_burst INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP,
+	CHECK (rate_limit >= 0 AND rate_burst >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id, id) WHERE revoked_at IS NULL;
@@ -0,0 +1,9 @@
+-- Статус учетной записи: frozen запрещает выводы и обмены до frozen_until
+-- (NULL - до снятия заморозки администратором). status_changed_at - время
+-- последнего изменения статуса, более старые управляющие сообщения не применяются
+ALTER TABLE users ADD COLUMN IF NOT EXISTS account_status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_status_check;
+ALTER TABLE users ADD CONSTRAINT users_account_status_check CHECK (account_status IN ('active', 'frozen'));
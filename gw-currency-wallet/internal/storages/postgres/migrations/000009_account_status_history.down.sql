@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS account_status_history;
+UPDATE users SET account_status = 'frozen', frozen_until = NULL WHERE account_status = 'closed';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_status_check;
+ALTER TABLE users ADD CONSTRAINT users_account_status_check CHECK (account_status IN ('active', 'frozen'));
@@ -0,0 +1,17 @@
+-- Статус closed запрещает все операции с деньгами. account_status_history - история
+-- изменений статуса с причиной и администратором для аудита (changed_by 0 - автоматически)
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_status_check;
+ALTER TABLE users ADD CONSTRAINT users_account_status_check CHECK (account_status IN ('active', 'frozen', 'closed'));
+
+CREATE TABLE IF NOT EXISTS account_status_history (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	status VARCHAR(20) NOT NULL,
+	previous_status VARCHAR(20) NOT NULL,
+	frozen_until TIMESTAMP,
+	reason TEXT NOT NULL,
+	changed_by BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_status_history_user ON account_status_history(user_id, id);
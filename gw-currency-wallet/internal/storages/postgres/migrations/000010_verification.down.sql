@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS verification_requests;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_verification_level_check;
+ALTER TABLE users DROP COLUMN IF EXISTS verification_level;
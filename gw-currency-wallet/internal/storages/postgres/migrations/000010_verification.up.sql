@@ -0,0 +1,28 @@
+-- Уровень верификации пользователя определяет лимиты выводов и обменов.
+-- verification_requests - заявки на повышение уровня; у пользователя не больше
+-- одной заявки на рассмотрении
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_level VARCHAR(20) NOT NULL DEFAULT 'unverified';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_verification_level_check;
+ALTER TABLE users ADD CONSTRAINT users_verification_level_check CHECK (verification_level IN ('unverified', 'basic', 'full'));
+
+CREATE TABLE IF NOT EXISTS verification_requests (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	level VARCHAR(20) NOT NULL,
+	full_name VARCHAR(200) NOT NULL,
+	date_of_birth VARCHAR(10) NOT NULL,
+	country VARCHAR(2) NOT NULL,
+	document_type VARCHAR(30) NOT NULL DEFAULT '',
+	document_number VARCHAR(50) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	reviewer_id BIGINT NOT NULL DEFAULT 0,
+	review_comment TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	reviewed_at TIMESTAMP,
+	CHECK (level IN ('basic', 'full')),
+	CHECK (status IN ('pending', 'approved', 'rejected'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_verification_requests_pending ON verification_requests(user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_verification_requests_user ON verification_requests(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_verification_requests_status ON verification_requests(status, id);
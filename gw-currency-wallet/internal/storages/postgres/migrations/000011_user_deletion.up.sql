@@ -0,0 +1,3 @@
+-- Удаление учетной записи пользователем: deleted_at - время удаления, имя и email
+-- заменяются обезличенными значениями, транзакции и журнал остаются для аудита
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
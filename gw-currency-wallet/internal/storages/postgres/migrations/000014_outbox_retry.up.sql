@@ -0,0 +1,4 @@
+-- Повторы отправки outbox: неудачная запись откладывается до next_attempt_at
+-- (NULL - сразу), после исчерпания попыток получает parked_at и больше не выбирается
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS parked_at TIMESTAMP;
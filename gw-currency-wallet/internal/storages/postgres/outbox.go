@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"gw-currency-wallet/internal/storages"
+)
+
+// FetchPendingOutbox возвращает неотправленные записи outbox, время попытки которых
+// наступило к now, в порядке создания. Отложенные насовсем записи не возвращаются
+func (s *PostgresStorage) FetchPendingOutbox(ctx context.Context, now time.Time, limit int) ([]storages.OutboxEntry, error) {
+	query := `
+		SELECT o.id, o.attempts, o.last_error, o.created_at, o.next_attempt_at,
+			t.id, t.user_id, t.type, t.from_currency, t.to_currency, t.from_amount, t.to_amount,
+			t.exchange_rate, COALESCE(t.market_rate, t.exchange_rate), t.margin, t.source,
+			t.status, t.created_at, t.completed_at
+		FROM outbox o
+		JOIN transactions t ON t.id = o.transaction_id
+		WHERE o.sent_at IS NULL AND o.parked_at IS NULL
+			AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= $1)
+		ORDER BY o.id
+		LIMIT $2
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		s.logger.Errorf("Failed to query outbox: %v", err)
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []storages.OutboxEntry
+	for rows.Next() {
+		var entry storages.OutboxEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Attempts,
+			&entry.LastError,
+			&entry.CreatedAt,
+			&entry.NextAttemptAt,
+			&entry.Transaction.ID,
+			&entry.Transaction.UserID,
+			&entry.Transaction.Type,
+			&entry.Transaction.FromCurrency,
+			&entry.Transaction.ToCurrency,
+			&entry.Transaction.FromAmount,
+			&entry.Transaction.ToAmount,
+			&entry.Transaction.ExchangeRate,
+			&entry.Transaction.MarketRate,
+			&entry.Transaction.Margin,
+			&entry.Transaction.Source,
+			&entry.Transaction.Status,
+			&entry.Transaction.CreatedAt,
+			&entry.Transaction.CompletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan outbox entry: %v", err)
+			return nil, fmt.Errorf("failed to scan outbox entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating outbox: %v", err)
+		return nil, fmt.Errorf("error iterating outbox: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MarkOutboxSent отмечает записи outbox как отправленные
+func (s *PostgresStorage) MarkOutboxSent(ctx context.Context, ids []int64) error {
+	query := `
+		UPDATE outbox
+		SET sent_at = $1, attempts = attempts + 1, last_error = ''
+		WHERE id = ANY($2)
+	`
+
+	if _, err := s.conn(ctx).ExecContext(ctx, query, time.Now(), pq.Array(ids)); err != nil {
+		s.logger.Errorf("Failed to mark outbox entries as sent: %v", err)
+		return fmt.Errorf("failed to mark outbox entries as sent: %w", err)
+	}
+
+	s.logger.Debugf("Marked %d outbox entries as sent", len(ids))
+	return nil
+}
+
+// MarkOutboxFailed увеличивает счетчик попыток, сохраняет причину ошибки
+// и откладывает следующую попытку до retryAt
+func (s *PostgresStorage) MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE id = $3
+	`
+
+	if _, err := s.conn(ctx).ExecContext(ctx, query, reason, retryAt, id); err != nil {
+		s.logger.Errorf("Failed to mark outbox entry as failed: %v", err)
+		return fmt.Errorf("failed to mark outbox entry as failed: %w", err)
+	}
+
+	return nil
+}
+
+// ParkOutbox откладывает запись outbox насовсем
+func (s *PostgresStorage) ParkOutbox(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox
+		SET parked_at = $1
+		WHERE id = $2
+	`
+
+	if _, err := s.conn(ctx).ExecContext(ctx, query, time.Now(), id); err != nil {
+		s.logger.Errorf("Failed to park outbox entry: %v", err)
+		return fmt.Errorf("failed to park outbox entry: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueOutbox возвращает записи outbox в очередь на повторную отправку не раньше retryAt
+func (s *PostgresStorage) RequeueOutbox(ctx context.Context, transactionIDs []int64, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE outbox
+		SET sent_at = NULL, attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE transaction_id = ANY($3)
+	`
+
+	if _, err := s.conn(ctx).ExecContext(ctx, query, reason, retryAt, pq.Array(transactionIDs)); err != nil {
+		s.logger.Errorf("Failed to requeue outbox entries: %v", err)
+		return fmt.Errorf("failed to requeue outbox entries: %w", err)
+	}
+
+	s.logger.Warnf("Requeued %d outbox entries: %s", len(transactionIDs), reason)
+	return nil
+}
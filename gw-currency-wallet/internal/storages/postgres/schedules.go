@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// scheduleColumns колонки регулярной операции в порядке scanSchedule
+const scheduleColumns = `id, user_id, operation, from_currency, to_currency, amount, period,
+	start_at, next_run_at, due_at, active, run_count, attempts, last_run_at, last_error, created_at, updated_at`
+
+// scanSchedule читает регулярную операцию из строки результата
+func scanSchedule(row interface{ Scan(dest ...any) error }) (storages.Schedule, error) {
+	var schedule storages.Schedule
+	var lastRunAt sql.NullTime
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&schedule.Operation,
+		&schedule.FromCurrency,
+		&schedule.ToCurrency,
+		&schedule.Amount,
+		&schedule.Period,
+		&schedule.StartAt,
+		&schedule.NextRunAt,
+		&schedule.DueAt,
+		&schedule.Active,
+		&schedule.RunCount,
+		&schedule.Attempts,
+		&lastRunAt,
+		&schedule.LastError,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	return schedule, err
+}
+
+// CreateSchedule создает регулярную операцию
+func (s *PostgresStorage) CreateSchedule(ctx context.Context, schedule *storages.Schedule) error {
+	query := `
+		INSERT INTO schedules (user_id, operation, from_currency, to_currency, amount, period,
+			start_at, next_run_at, due_at, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $10, $10)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		schedule.UserID,
+		schedule.Operation,
+		schedule.FromCurrency,
+		schedule.ToCurrency,
+		schedule.Amount,
+		schedule.Period,
+		schedule.StartAt,
+		schedule.NextRunAt,
+		schedule.Active,
+		now,
+	).Scan(&schedule.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to create schedule: %v", err)
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	schedule.DueAt = schedule.NextRunAt
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	s.logger.Infof("Created %s %s schedule %d for user %d", schedule.Period, schedule.Operation, schedule.ID, schedule.UserID)
+	return nil
+}
+
+// GetSchedule возвращает регулярную операцию пользователя
+func (s *PostgresStorage) GetSchedule(ctx context.Context, userID, scheduleID int64) (*storages.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1 AND user_id = $2`
+
+	schedule, err := scanSchedule(s.conn(ctx).QueryRowContext(ctx, query, scheduleID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("schedule %w", storages.ErrNotFound)
+		}
+		s.logger.Errorf("Failed to get schedule: %v", err)
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// ListSchedules возвращает регулярные операции пользователя
+func (s *PostgresStorage) ListSchedules(ctx context.Context, userID int64) ([]storages.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE user_id = $1 ORDER BY id`
+
+	return s.querySchedules(ctx, query, userID)
+}
+
+// UpdateSchedule сохраняет изменения регулярной операции пользователя
+func (s *PostgresStorage) UpdateSchedule(ctx context.Context, schedule *storages.Schedule) error {
+	query := `
+		UPDATE schedules
+		SET amount = $1, period = $2, start_at = $3, next_run_at = $4, due_at = $4,
+			active = $5, attempts = 0, updated_at = $6
+		WHERE id = $7 AND user_id = $8
+	`
+
+	now := time.Now()
+	result, err := s.conn(ctx).ExecContext(ctx, query,
+		schedule.Amount,
+		schedule.Period,
+		schedule.StartAt,
+		schedule.NextRunAt,
+		schedule.Active,
+		now,
+		schedule.ID,
+		schedule.UserID,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to update schedule: %v", err)
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("schedule %w", storages.ErrNotFound)
+	}
+
+	schedule.DueAt = schedule.NextRunAt
+	schedule.Attempts = 0
+	schedule.UpdatedAt = now
+	return nil
+}
+
+// DeleteSchedule удаляет регулярную операцию пользователя
+func (s *PostgresStorage) DeleteSchedule(ctx context.Context, userID, scheduleID int64) error {
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM schedules WHERE id = $1 AND user_id = $2`, scheduleID, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to delete schedule: %v", err)
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("schedule %w", storages.ErrNotFound)
+	}
+
+	s.logger.Infof("Deleted schedule %d of user %d", scheduleID, userID)
+	return nil
+}
+
+// ListDueSchedules возвращает активные операции, время попытки которых наступило
+func (s *PostgresStorage) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]storages.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE active AND due_at <= $1
+		ORDER BY due_at, id
+		LIMIT $2`
+
+	return s.querySchedules(ctx, query, now, limit)
+}
+
+// CompleteScheduleRun завершает запуск и переносит операцию на следующее плановое время
+func (s *PostgresStorage) CompleteScheduleRun(ctx context.Context, scheduleID int64, runCount int, next time.Time, lastError string) (bool, error) {
+	query := `
+		UPDATE schedules
+		SET next_run_at = $1, due_at = $1, run_count = run_count + 1, attempts = 0,
+			last_run_at = $2, last_error = $3, updated_at = $2
+		WHERE id = $4 AND run_count = $5 AND active
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, next, time.Now(), lastError, scheduleID, runCount)
+	if err != nil {
+		s.logger.Errorf("Failed to complete schedule run: %v", err)
+		return false, fmt.Errorf("failed to complete schedule run: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RetryScheduleRun записывает неудачную попытку и время следующей
+func (s *PostgresStorage) RetryScheduleRun(ctx context.Context, scheduleID int64, runCount int, retryAt time.Time, lastError string) error {
+	query := `
+		UPDATE schedules
+		SET due_at = $1, attempts = attempts + 1, last_error = $2, updated_at = $3
+		WHERE id = $4 AND run_count = $5
+	`
+
+	if _, err := s.conn(ctx).ExecContext(ctx, query, retryAt, lastError, time.Now(), scheduleID, runCount); err != nil {
+		s.logger.Errorf("Failed to record schedule failure: %v", err)
+		return fmt.Errorf("failed to record schedule failure: %w", err)
+	}
+
+	return nil
+}
+
+// querySchedules выполняет выборку регулярных операций
+func (s *PostgresStorage) querySchedules(ctx context.Context, query string, args ...any) ([]storages.Schedule, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query schedules: %v", err)
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []storages.Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			s.logger.Errorf("Failed to scan schedule: %v", err)
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating schedules: %v", err)
+		return nil, fmt.Errorf("error iterating schedules: %w", err)
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// sessionColumns колонки сессии в порядке scanSession
+const sessionColumns = `id, user_id, device, ip, created_at, last_used_at, expires_at, revoked_at`
+
+// scanSession читает сессию из строки результата
+func scanSession(row interface{ Scan(dest ...any) error }) (storages.Session, error) {
+	var session storages.Session
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.Device,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&revokedAt,
+	)
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, err
+}
+
+// CreateSession сохраняет сессию пользователя
+func (s *PostgresStorage) CreateSession(ctx context.Context, session *storages.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, device, ip, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $4, $5)
+		RETURNING id
+	`
+
+	now := time.Now().UTC()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		session.UserID,
+		session.Device,
+		session.IP,
+		now,
+		session.ExpiresAt,
+	).Scan(&session.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to create session: %v", err)
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	session.CreatedAt = now
+	session.LastUsedAt = now
+
+	s.logger.Infof("Created session %d for user %d", session.ID, session.UserID)
+	return nil
+}
+
+// GetSession возвращает сессию пользователя
+func (s *PostgresStorage) GetSession(ctx context.Context, userID, sessionID int64) (*storages.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1 AND user_id = $2`
+
+	session, err := scanSession(s.conn(ctx).QueryRowContext(ctx, query, sessionID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("session %w", storages.ErrNotFound)
+		}
+		s.logger.Errorf("Failed to get session: %v", err)
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// ListActiveSessions возвращает действующие сессии пользователя, новые первыми
+func (s *PostgresStorage) ListActiveSessions(ctx context.Context, userID int64, now time.Time) ([]storages.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY id DESC
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID, now)
+	if err != nil {
+		s.logger.Errorf("Failed to query sessions: %v", err)
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []storages.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			s.logger.Errorf("Failed to scan session: %v", err)
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating sessions: %v", err)
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession обновляет время последнего использования действующей сессии
+func (s *PostgresStorage) TouchSession(ctx context.Context, sessionID int64, usedAt time.Time) error {
+	query := `UPDATE sessions SET last_used_at = $1 WHERE id = $2 AND revoked_at IS NULL AND expires_at > $1`
+
+	_, err := s.updateSessions(ctx, query, usedAt, sessionID)
+	return err
+}
+
+// RevokeSession отзывает действующую сессию пользователя
+func (s *PostgresStorage) RevokeSession(ctx context.Context, userID, sessionID int64, revokedAt time.Time) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL AND expires_at > $1`
+
+	if _, err := s.updateSessions(ctx, query, revokedAt, sessionID, userID); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Revoked session %d of user %d", sessionID, userID)
+	return nil
+}
+
+// RevokeUserSessions отзывает сессии пользователя, кроме exceptID
+func (s *PostgresStorage) RevokeUserSessions(ctx context.Context, userID, exceptID int64, revokedAt time.Time) (int64, error) {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL AND expires_at > $1`
+
+	revoked, err := s.updateSessions(ctx, query, revokedAt, userID, exceptID)
+	if errors.Is(err, storages.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Revoked %d sessions of user %d", revoked, userID)
+	return revoked, nil
+}
+
+// updateSessions выполняет изменение сессий и возвращает число измененных;
+// ErrNotFound, если ни одна действующая сессия не изменена
+func (s *PostgresStorage) updateSessions(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := s.conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to update session: %v", err)
+		return 0, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("session %w", storages.ErrNotFound)
+	}
+
+	return rowsAffected, nil
+}
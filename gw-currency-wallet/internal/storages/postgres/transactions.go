@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"gw-currency-wallet/internal/storages"
@@ -12,13 +13,13 @@ import (
 // CreateTransaction создает новую транзакцию
 func (s *PostgresStorage) CreateTransaction(ctx context.Context, tx *storages.Transaction) error {
 	query := `
-		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, market_rate, margin, source, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
 
 	now := time.Now()
-	err := s.db.QueryRowContext(ctx, query,
+	err := s.conn(ctx).QueryRowContext(ctx, query,
 		tx.UserID,
 		tx.Type,
 		tx.FromCurrency,
@@ -26,6 +27,9 @@ func (s *PostgresStorage) CreateTransaction(ctx context.Context, tx *storages.Tr
 		tx.FromAmount,
 		tx.ToAmount,
 		tx.ExchangeRate,
+		tx.MarketRate,
+		tx.Margin,
+		tx.Source,
 		tx.Status,
 		now,
 	).Scan(&tx.ID)
@@ -44,13 +48,14 @@ func (s *PostgresStorage) CreateTransaction(ctx context.Context, tx *storages.Tr
 // GetTransaction возвращает транзакцию по ID
 func (s *PostgresStorage) GetTransaction(ctx context.Context, txID int64) (*storages.Transaction, error) {
 	query := `
-		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, status, created_at, completed_at
+		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate,
+			COALESCE(market_rate, exchange_rate), margin, source, status, fee, created_at, completed_at
 		FROM transactions
 		WHERE id = $1
 	`
 
 	var tx storages.Transaction
-	err := s.db.QueryRowContext(ctx, query, txID).Scan(
+	err := s.conn(ctx).QueryRowContext(ctx, query, txID).Scan(
 		&tx.ID,
 		&tx.UserID,
 		&tx.Type,
@@ -59,13 +64,17 @@ func (s *PostgresStorage) GetTransaction(ctx context.Context, txID int64) (*stor
 		&tx.FromAmount,
 		&tx.ToAmount,
 		&tx.ExchangeRate,
+		&tx.MarketRate,
+		&tx.Margin,
+		&tx.Source,
 		&tx.Status,
+		&tx.Fee,
 		&tx.CreatedAt,
 		&tx.CompletedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("transaction not found")
+		return nil, fmt.Errorf("transaction %w", storages.ErrNotFound)
 	}
 
 	if err != nil {
@@ -76,24 +85,46 @@ func (s *PostgresStorage) GetTransaction(ctx context.Context, txID int64) (*stor
 	return &tx, nil
 }
 
-// GetUserTransactions возвращает транзакции пользователя
-func (s *PostgresStorage) GetUserTransactions(ctx context.Context, userID int64, limit int) ([]storages.Transaction, error) {
+// GetUserTransactions возвращает страницу истории транзакций пользователя.
+// Курсор сравнивается по (created_at, id), поэтому страницы не смещаются,
+// когда появляются новые транзакции
+func (s *PostgresStorage) GetUserTransactions(ctx context.Context, userID int64, filter storages.TransactionFilter) (*storages.TransactionPage, error) {
+	where, args := transactionFilterClause(userID, filter)
+
+	var total int64
+	err := s.conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		s.logger.Errorf("Failed to count transactions: %v", err)
+		return nil, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	if filter.Cursor > 0 {
+		args = append(args, filter.Cursor)
+		where += fmt.Sprintf(` AND (created_at, id) < (
+			SELECT created_at, id FROM transactions WHERE id = $%d AND user_id = $1
+		)`, len(args))
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	pageSize := filter.PageSize()
+	args = append(args, pageSize+1, filter.Offset)
 	query := `
-		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, status, created_at, completed_at
+		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate,
+			COALESCE(market_rate, exchange_rate), margin, source, status, fee, created_at, completed_at
 		FROM transactions
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
-	`
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args))
 
-	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		s.logger.Errorf("Failed to query transactions: %v", err)
 		return nil, fmt.Errorf("failed to query transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var transactions []storages.Transaction
+	transactions := make([]storages.Transaction, 0, pageSize)
 	for rows.Next() {
 		var tx storages.Transaction
 		err := rows.Scan(
@@ -105,7 +136,11 @@ func (s *PostgresStorage) GetUserTransactions(ctx context.Context, userID int64,
 			&tx.FromAmount,
 			&tx.ToAmount,
 			&tx.ExchangeRate,
+			&tx.MarketRate,
+			&tx.Margin,
+			&tx.Source,
 			&tx.Status,
+			&tx.Fee,
 			&tx.CreatedAt,
 			&tx.CompletedAt,
 		)
@@ -121,24 +156,64 @@ func (s *PostgresStorage) GetUserTransactions(ctx context.Context, userID int64,
 		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	return transactions, nil
+	page := &storages.TransactionPage{Transactions: transactions, Total: total}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		page.NextCursor = page.Transactions[pageSize-1].ID
+	}
+
+	return page, nil
+}
+
+// transactionFilterClause строит условие WHERE и аргументы для фильтра истории
+// транзакций без курсора. Первый аргумент всегда ID пользователя
+func transactionFilterClause(userID int64, filter storages.TransactionFilter) (string, []interface{}) {
+	where := "user_id = $1"
+	args := []interface{}{userID}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		where += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	for _, in := range []struct {
+		column string
+		values []string
+	}{{"type", filter.Types}, {"status", filter.Statuses}} {
+		if len(in.values) == 0 {
+			continue
+		}
+		placeholders := make([]string, len(in.values))
+		for i, value := range in.values {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		where += fmt.Sprintf(" AND %s IN (%s)", in.column, strings.Join(placeholders, ", "))
+	}
+
+	return where, args
 }
 
-// UpdateTransactionStatus обновляет статус транзакции
-func (s *PostgresStorage) UpdateTransactionStatus(ctx context.Context, txID int64, status string) error {
+// UpdateTransactionStatus переводит транзакцию из статуса from в to. Время
+// завершения записывается для конечных статусов
+func (s *PostgresStorage) UpdateTransactionStatus(ctx context.Context, txID int64, from, to string) error {
 	query := `
 		UPDATE transactions
 		SET status = $1, completed_at = $2
-		WHERE id = $3
+		WHERE id = $3 AND status = $4
 	`
 
 	var completedAt *time.Time
-	if status == storages.TransactionStatusCompleted || status == storages.TransactionStatusFailed {
+	if to != storages.TransactionStatusPending {
 		now := time.Now()
 		completedAt = &now
 	}
 
-	result, err := s.db.ExecContext(ctx, query, status, completedAt, txID)
+	result, err := s.conn(ctx).ExecContext(ctx, query, to, completedAt, txID, from)
 	if err != nil {
 		s.logger.Errorf("Failed to update transaction status: %v", err)
 		return fmt.Errorf("failed to update transaction status: %w", err)
@@ -150,85 +225,216 @@ func (s *PostgresStorage) UpdateTransactionStatus(ctx context.Context, txID int6
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("transaction not found")
+		return fmt.Errorf("%s transaction %d %w", from, txID, storages.ErrNotFound)
 	}
 
-	s.logger.Debugf("Updated transaction %d status to %s", txID, status)
+	s.logger.Debugf("Updated transaction %d status from %s to %s", txID, from, to)
 	return nil
 }
 
+// ExecuteDeposit пополняет баланс атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *PostgresStorage) ExecuteDeposit(ctx context.Context, userID int64, currency string, amount float64, notify bool) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeDeposit, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Зачисляем сумму записью журнала
+	if err := s.applyEntry(ctx, tx, userID, currency, amount, txID, storages.TransactionTypeDeposit); err != nil {
+		return 0, err
+	}
+
+	// 3. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return txID, nil
+}
+
+// ExecuteWithdraw списывает средства атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *PostgresStorage) ExecuteWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64, notify bool) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем средства, не удержанные ожидающими выводами, с блокировкой строки
+	var balance float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount - held_amount FROM balances
+		WHERE user_id = $1 AND currency = $2
+		FOR UPDATE
+	`, userID, currency).Scan(&balance)
+
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, currency)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get balance: %v", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	// 2. Проверяем достаточность средств с учетом комиссии
+	if balance < amount+fee {
+		return 0, fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, balance, amount+fee)
+	}
+
+	// 3. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeWithdraw, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+	if err != nil {
+		return 0, err
+	}
+
+	// 4. Списываем сумму и комиссию записями журнала
+	if err := s.applyEntry(ctx, tx, userID, currency, -amount, txID, storages.TransactionTypeWithdraw); err != nil {
+		return 0, err
+	}
+
+	if err := s.insertFee(ctx, tx, userID, currency, fee); err != nil {
+		return 0, err
+	}
+
+	// 5. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return txID, nil
+}
+
 // ExecuteExchange выполняет обмен валюты атомарно
-func (s *PostgresStorage) ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount, rate float64) error {
+func (s *PostgresStorage) ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, fee float64, notify bool) (int64, error) {
 	// Начинаем транзакцию
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	tx, err := s.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		s.logger.Errorf("Failed to begin transaction: %v", err)
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// 1. Получаем баланс исходной валюты с блокировкой строки
+	// 1. Получаем доступные средства исходной валюты с блокировкой строки
 	var fromBalance float64
 	err = tx.QueryRowContext(ctx, `
-		SELECT amount FROM balances 
+		SELECT amount - held_amount FROM balances
 		WHERE user_id = $1 AND currency = $2
 		FOR UPDATE
 	`, userID, fromCurrency).Scan(&fromBalance)
 
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, fromCurrency)
+	}
+
 	if err != nil {
 		s.logger.Errorf("Failed to get from balance: %v", err)
-		return fmt.Errorf("failed to get balance: %w", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
 	}
 
-	// 2. Проверяем достаточность средств
-	if fromBalance < fromAmount {
-		return fmt.Errorf("insufficient funds: have %.2f, need %.2f", fromBalance, fromAmount)
+	// 2. Проверяем достаточность средств с учетом комиссии
+	if fromBalance < fromAmount+fee {
+		return 0, fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, fromBalance, fromAmount+fee)
 	}
 
-	// 3. Уменьшаем баланс исходной валюты
-	_, err = tx.ExecContext(ctx, `
-		UPDATE balances
-		SET amount = amount - $1, updated_at = $2
-		WHERE user_id = $3 AND currency = $4
-	`, fromAmount, time.Now(), userID, fromCurrency)
-
+	// 3. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeExchange, fromCurrency, toCurrency, fromAmount, toAmount, quote, notify)
 	if err != nil {
-		s.logger.Errorf("Failed to deduct from balance: %v", err)
-		return fmt.Errorf("failed to deduct balance: %w", err)
+		return 0, err
 	}
 
-	// 4. Увеличиваем баланс целевой валюты
-	_, err = tx.ExecContext(ctx, `
-		UPDATE balances
-		SET amount = amount + $1, updated_at = $2
-		WHERE user_id = $3 AND currency = $4
-	`, toAmount, time.Now(), userID, toCurrency)
+	// 4. Списываем исходную валюту и зачисляем целевую записями журнала,
+	// затем списываем комиссию
+	if err := s.applyEntry(ctx, tx, userID, fromCurrency, -fromAmount, txID, storages.TransactionTypeExchange); err != nil {
+		return 0, err
+	}
 
-	if err != nil {
-		s.logger.Errorf("Failed to add to balance: %v", err)
-		return fmt.Errorf("failed to add balance: %w", err)
+	if err := s.applyEntry(ctx, tx, userID, toCurrency, toAmount, txID, storages.TransactionTypeExchange); err != nil {
+		return 0, err
+	}
+
+	if err := s.insertFee(ctx, tx, userID, fromCurrency, fee); err != nil {
+		return 0, err
+	}
+
+	// 5. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// 5. Создаем запись о транзакции
+	s.logger.Infof("Exchange completed: User=%d, %.2f %s -> %.2f %s (rate: %.8f, margin: %.4f, source: %s)",
+		userID, fromAmount, fromCurrency, toAmount, toCurrency, quote.Rate, quote.Margin, quote.Source)
+
+	return txID, nil
+}
+
+// insertCompletedTransaction создает запись о проведенной транзакции внутри tx.
+// При notify в той же транзакции создается запись outbox, поэтому уведомление
+// не теряется, даже если Kafka недоступна в момент операции
+func (s *PostgresStorage) insertCompletedTransaction(ctx context.Context, tx querier, userID int64, transferType, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, notify bool) (int64, error) {
 	now := time.Now()
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, status, created_at, completed_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, userID, storages.TransactionTypeExchange, fromCurrency, toCurrency, fromAmount, toAmount, rate, storages.TransactionStatusCompleted, now, now)
+	var txID int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount,
+			exchange_rate, market_rate, margin, source, status, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, userID, transferType, fromCurrency, toCurrency, fromAmount, toAmount,
+		quote.Rate, quote.MarketRate, quote.Margin, quote.Source, storages.TransactionStatusCompleted, now, now).Scan(&txID)
 
 	if err != nil {
 		s.logger.Errorf("Failed to create transaction record: %v", err)
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// 6. Коммитим транзакцию
-	if err := tx.Commit(); err != nil {
-		s.logger.Errorf("Failed to commit transaction: %v", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if notify {
+		if err := s.insertOutbox(ctx, tx, txID); err != nil {
+			return 0, err
+		}
 	}
 
-	s.logger.Infof("Exchange completed: User=%d, %.2f %s -> %.2f %s (rate: %.8f)",
-		userID, fromAmount, fromCurrency, toAmount, toCurrency, rate)
+	return txID, nil
+}
+
+// insertOutbox создает внутри tx запись outbox для уведомления о транзакции
+func (s *PostgresStorage) insertOutbox(ctx context.Context, tx querier, txID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (transaction_id, created_at)
+		VALUES ($1, $2)
+	`, txID, time.Now())
 
+	if err != nil {
+		s.logger.Errorf("Failed to create outbox record: %v", err)
+		return fmt.Errorf("failed to create outbox record: %w", err)
+	}
 	return nil
 }
+
+// insertFee создает запись о комиссии внутри tx и списывает ее с баланса
+// записью журнала
+func (s *PostgresStorage) insertFee(ctx context.Context, tx querier, userID int64, currency string, fee float64) error {
+	if fee <= 0 {
+		return nil
+	}
+
+	feeTxID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeFee, currency, currency, fee, 0, storages.ExchangeQuote{}, false)
+	if err != nil {
+		return err
+	}
+
+	return s.applyEntry(ctx, tx, userID, currency, -fee, feeTxID, storages.TransactionTypeFee)
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// querier общие методы *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey ключ контекста с транзакцией, открытой WithTransaction
+type txKey struct{}
+
+// WithTransaction выполняет fn в транзакции БД. Методы хранилища, вызванные
+// с контекстом fn, выполняются в этой транзакции. Транзакция фиксируется, если fn
+// вернула nil, иначе откатывается. Вложенный вызов использует внешнюю транзакцию
+func (s *PostgresStorage) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// conn возвращает транзакцию из контекста или пул соединений
+func (s *PostgresStorage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// scopedTx транзакция отдельной операции хранилища. Внутри WithTransaction
+// операция использует внешнюю транзакцию, а фиксацию и откат выполняет WithTransaction
+type scopedTx struct {
+	*sql.Tx
+	owned bool
+}
+
+// Commit фиксирует собственную транзакцию операции
+func (t *scopedTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback откатывает собственную транзакцию операции
+func (t *scopedTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// beginTx начинает транзакцию операции или присоединяется к транзакции из контекста.
+// opts применяются только к собственной транзакции
+func (s *PostgresStorage) beginTx(ctx context.Context, opts *sql.TxOptions) (*scopedTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &scopedTx{Tx: tx}, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedTx{Tx: tx, owned: true}, nil
+}
@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// webhookDeliveryColumns колонки доставки вебхука в порядке scanWebhookDelivery
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.response_code, d.last_error, d.created_at, d.delivered_at`
+
+// scanWebhookDelivery читает доставку вебхука из строки результата; extra - колонки после webhookDeliveryColumns
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }, extra ...any) (storages.WebhookDelivery, error) {
+	var delivery storages.WebhookDelivery
+	var payload string
+	var deliveredAt sql.NullTime
+	dest := []any{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.ResponseCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	delivery.Payload = []byte(payload)
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, err
+}
+
+// CreateWebhook сохраняет вебхук
+func (s *PostgresStorage) CreateWebhook(ctx context.Context, webhook *storages.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, events, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		strings.Join(webhook.Events, ","),
+		now,
+	).Scan(&webhook.ID)
+	if err != nil {
+		s.logger.Errorf("Failed to create webhook: %v", err)
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	webhook.CreatedAt = now
+
+	s.logger.Infof("Created webhook %d for user %d", webhook.ID, webhook.UserID)
+	return nil
+}
+
+// ListWebhooks возвращает вебхуки пользователя
+func (s *PostgresStorage) ListWebhooks(ctx context.Context, userID int64) ([]storages.Webhook, error) {
+	query := `SELECT id, user_id, url, secret, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY id`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to query webhooks: %v", err)
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []storages.Webhook{}
+	for rows.Next() {
+		var webhook storages.Webhook
+		var events string
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Secret, &events, &webhook.CreatedAt); err != nil {
+			s.logger.Errorf("Failed to scan webhook: %v", err)
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhook.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating webhooks: %v", err)
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя; доставки удаляются каскадно
+func (s *PostgresStorage) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to delete webhook: %v", err)
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook %w", storages.ErrNotFound)
+	}
+
+	s.logger.Infof("Deleted webhook %d of user %d", webhookID, userID)
+	return nil
+}
+
+// EnqueueWebhookEvent создает доставки события вебхукам пользователя, в списке
+// событий которых есть его тип
+func (s *PostgresStorage) EnqueueWebhookEvent(ctx context.Context, event storages.WebhookEvent) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at, updated_at)
+		SELECT id, $2, $3, $4, $5, $6, $6, $6
+		FROM webhooks
+		WHERE user_id = $1 AND ',' || events || ',' LIKE '%,' || $3 || ',%'
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query,
+		event.UserID,
+		event.ID,
+		event.Type,
+		string(event.Payload),
+		storages.WebhookDeliveryPending,
+		event.CreatedAt,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to enqueue webhook event: %v", err)
+		return 0, fmt.Errorf("failed to enqueue webhook event: %w", err)
+	}
+
+	enqueued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return enqueued, nil
+}
+
+// ListWebhookDeliveries возвращает последние доставки вебхука пользователя
+func (s *PostgresStorage) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]storages.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.user_id = $1 AND d.webhook_id = $2
+		ORDER BY d.id DESC
+		LIMIT $3`
+
+	return s.queryWebhookDeliveries(ctx, query, false, userID, webhookID, limit)
+}
+
+// ListDueWebhookDeliveries возвращает ожидающие доставки, время попытки которых наступило
+func (s *PostgresStorage) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]storages.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = $1 AND d.next_attempt_at <= $2
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $3`
+
+	return s.queryWebhookDeliveries(ctx, query, true, storages.WebhookDeliveryPending, now, limit)
+}
+
+// RecordWebhookAttempt сохраняет результат попытки, если предыдущая попытка последняя записанная
+func (s *PostgresStorage) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt storages.WebhookAttempt) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, response_code = $3, last_error = $4, next_attempt_at = $5,
+			delivered_at = $6, updated_at = $7
+		WHERE id = $8 AND attempts = $9 AND status = $10
+	`
+
+	var deliveredAt *time.Time
+	if attempt.Status == storages.WebhookDeliveryDelivered {
+		deliveredAt = &attempt.At
+	}
+	nextAttemptAt := attempt.NextAttemptAt
+	if attempt.Status != storages.WebhookDeliveryPending {
+		nextAttemptAt = attempt.At
+	}
+
+	result, err := s.conn(ctx).ExecContext(ctx, query,
+		attempt.Status,
+		attempt.Attempt,
+		attempt.ResponseCode,
+		attempt.Error,
+		nextAttemptAt,
+		deliveredAt,
+		attempt.At,
+		deliveryID,
+		attempt.Attempt-1,
+		storages.WebhookDeliveryPending,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to record webhook attempt: %v", err)
+		return false, fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// queryWebhookDeliveries выполняет запрос доставок; withTarget - запрос возвращает
+// также адрес и секрет вебхука
+func (s *PostgresStorage) queryWebhookDeliveries(ctx context.Context, query string, withTarget bool, args ...any) ([]storages.WebhookDelivery, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query webhook deliveries: %v", err)
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []storages.WebhookDelivery{}
+	for rows.Next() {
+		var url, secret string
+		var extra []any
+		if withTarget {
+			extra = []any{&url, &secret}
+		}
+		delivery, err := scanWebhookDelivery(rows, extra...)
+		if err != nil {
+			s.logger.Errorf("Failed to scan webhook delivery: %v", err)
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		delivery.URL, delivery.Secret = url, secret
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating webhook deliveries: %v", err)
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreatePendingWithdraw создает ожидающий вывод и удерживает сумму с комиссией
+// атомарно. Удержание изменяется на сохраненные в транзакции суммы, поэтому
+// снятие удержания при подтверждении или отмене возвращает его точно к прежнему значению
+func (s *PostgresStorage) CreatePendingWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем средства, не удержанные другими выводами, с блокировкой строки
+	var available float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount - held_amount FROM balances
+		WHERE user_id = $1 AND currency = $2
+		FOR UPDATE
+	`, userID, currency).Scan(&available)
+
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, currency)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get balance: %v", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	// 2. Проверяем достаточность средств с учетом комиссии
+	if available < amount+fee {
+		return 0, fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, available, amount+fee)
+	}
+
+	// 3. Создаем транзакцию в статусе pending
+	var txID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount,
+			exchange_rate, market_rate, margin, source, status, fee, created_at)
+		VALUES ($1, $2, $3, $3, $4, $4, 1, 1, 0, '', $5, $6, $7)
+		RETURNING id
+	`, userID, storages.TransactionTypeWithdraw, currency, amount, storages.TransactionStatusPending, fee, time.Now()).Scan(&txID)
+
+	if err != nil {
+		s.logger.Errorf("Failed to create transaction record: %v", err)
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	// 4. Удерживаем сумму с комиссией
+	if err := s.changeHold(ctx, tx, txID, "+"); err != nil {
+		return 0, err
+	}
+
+	// 5. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Pending withdrawal created: ID=%d, User=%d, %.2f %s, fee %.2f", txID, userID, amount, currency, fee)
+	return txID, nil
+}
+
+// ListPendingWithdrawals возвращает ожидающие выводы по фильтру от старых к новым
+func (s *PostgresStorage) ListPendingWithdrawals(ctx context.Context, filter storages.PendingWithdrawalFilter) ([]storages.Transaction, error) {
+	query := `
+		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate,
+			COALESCE(market_rate, exchange_rate), margin, source, status, fee, created_at, completed_at
+		FROM transactions
+		WHERE status = $1 AND type = $2
+			AND ($3 = 0 OR user_id = $3)
+			AND ($4::timestamp IS NULL OR created_at < $4)
+			AND ($5::numeric = 0 OR from_amount <= $5)
+		ORDER BY created_at, id
+		LIMIT $6
+	`
+
+	var createdBefore *time.Time
+	if !filter.CreatedBefore.IsZero() {
+		createdBefore = &filter.CreatedBefore
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query,
+		storages.TransactionStatusPending,
+		storages.TransactionTypeWithdraw,
+		filter.UserID,
+		createdBefore,
+		filter.MaxAmount,
+		filter.Limit,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to query pending withdrawals: %v", err)
+		return nil, fmt.Errorf("failed to query pending withdrawals: %w", err)
+	}
+	defer rows.Close()
+
+	withdrawals := make([]storages.Transaction, 0)
+	for rows.Next() {
+		var tx storages.Transaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.UserID,
+			&tx.Type,
+			&tx.FromCurrency,
+			&tx.ToCurrency,
+			&tx.FromAmount,
+			&tx.ToAmount,
+			&tx.ExchangeRate,
+			&tx.MarketRate,
+			&tx.Margin,
+			&tx.Source,
+			&tx.Status,
+			&tx.Fee,
+			&tx.CreatedAt,
+			&tx.CompletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan pending withdrawal: %v", err)
+			return nil, fmt.Errorf("failed to scan pending withdrawal: %w", err)
+		}
+		withdrawals = append(withdrawals, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating pending withdrawals: %v", err)
+		return nil, fmt.Errorf("error iterating pending withdrawals: %w", err)
+	}
+
+	return withdrawals, nil
+}
+
+// CompleteWithdraw проводит ожидающий вывод. Смена статуса блокирует строку
+// транзакции, поэтому параллельные подтверждение и отмена одного вывода
+// не выполняются оба
+func (s *PostgresStorage) CompleteWithdraw(ctx context.Context, txID int64, notify bool) (*storages.Transaction, error) {
+	var withdrawal *storages.Transaction
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if withdrawal, err = s.claimPendingWithdraw(ctx, txID, storages.TransactionStatusCompleted); err != nil {
+			return err
+		}
+
+		tx := s.conn(ctx)
+		if err := s.changeHold(ctx, tx, txID, "-"); err != nil {
+			return err
+		}
+		if err := s.applyEntry(ctx, tx, withdrawal.UserID, withdrawal.FromCurrency, -withdrawal.FromAmount, txID, storages.TransactionTypeWithdraw); err != nil {
+			return err
+		}
+		if err := s.insertFee(ctx, tx, withdrawal.UserID, withdrawal.FromCurrency, withdrawal.Fee); err != nil {
+			return err
+		}
+		if notify {
+			return s.insertOutbox(ctx, tx, txID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Withdrawal completed: ID=%d, User=%d, %.2f %s", txID, withdrawal.UserID, withdrawal.FromAmount, withdrawal.FromCurrency)
+	return withdrawal, nil
+}
+
+// CancelWithdraw снимает удержание ожидающего вывода и переводит его в status
+func (s *PostgresStorage) CancelWithdraw(ctx context.Context, txID int64, status string) (*storages.Transaction, error) {
+	var withdrawal *storages.Transaction
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if withdrawal, err = s.claimPendingWithdraw(ctx, txID, status); err != nil {
+			return err
+		}
+		return s.changeHold(ctx, s.conn(ctx), txID, "-")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Withdrawal %s: ID=%d, User=%d, %.2f %s", status, txID, withdrawal.UserID, withdrawal.FromAmount, withdrawal.FromCurrency)
+	return withdrawal, nil
+}
+
+// claimPendingWithdraw переводит ожидающий вывод в status внутри WithTransaction
+// и возвращает его с новым статусом
+func (s *PostgresStorage) claimPendingWithdraw(ctx context.Context, txID int64, status string) (*storages.Transaction, error) {
+	withdrawal, err := s.GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal.Type != storages.TransactionTypeWithdraw {
+		return nil, fmt.Errorf("pending withdrawal %d %w", txID, storages.ErrNotFound)
+	}
+
+	if err := s.UpdateTransactionStatus(ctx, txID, storages.TransactionStatusPending, status); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	withdrawal.Status = status
+	withdrawal.CompletedAt = &now
+	return withdrawal, nil
+}
+
+// changeHold увеличивает (op "+") или уменьшает (op "-") удержание баланса
+// на сумму с комиссией вывода txID
+func (s *PostgresStorage) changeHold(ctx context.Context, tx querier, txID int64, op string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE balances b
+		SET held_amount = b.held_amount `+op+` (t.from_amount + t.fee), updated_at = $1
+		FROM transactions t
+		WHERE t.id = $2 AND b.user_id = t.user_id AND b.currency = t.from_currency
+	`, time.Now(), txID)
+	if err != nil {
+		s.logger.Errorf("Failed to change held amount: %v", err)
+		return fmt.Errorf("failed to change held amount: %w", err)
+	}
+	return nil
+}
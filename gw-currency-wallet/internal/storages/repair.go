@@ -0,0 +1,109 @@
+package storages
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// repairTolerance расхождение, которое не исправляется (точность NUMERIC(20, 8))
+const repairTolerance = 0.000000005
+
+// balanceKey баланс пользователя в валюте
+type balanceKey struct {
+	userID   int64
+	currency string
+}
+
+// RepairSQL формирует SQL, исправляющий расхождения балансов, найденные проверкой
+// учета. Скрипт подходит для PostgreSQL и SQLite и предназначен для ручного
+// применения после разбора инцидента.
+//
+// Источником истины считаются проведенные транзакции (с учетом выгруженных в архив):
+// для balance_mismatch в журнал добавляется запись adjustment на разницу между суммой
+// транзакций и журналом, а баланс изменяется до суммы транзакций. Для balance_drift
+// без balance_mismatch баланс изменяется до суммы журнала. Изменения относительные,
+// поэтому операции, выполненные после проверки, не теряются. Остальные нарушения
+// исправляются вручную и выводятся комментариями
+func RepairSQL(violations []LedgerViolation) string {
+	var keys []balanceKey
+	mismatches := make(map[balanceKey]LedgerViolation)
+	drifts := make(map[balanceKey]LedgerViolation)
+	var manual []LedgerViolation
+
+	for _, v := range violations {
+		key := balanceKey{userID: v.UserID, currency: v.Currency}
+		switch v.Type {
+		case LedgerViolationBalanceMismatch:
+			mismatches[key] = v
+		case LedgerViolationBalanceDrift:
+			drifts[key] = v
+		default:
+			manual = append(manual, v)
+			continue
+		}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	for _, key := range keys {
+		mismatch, hasMismatch := mismatches[key]
+		drift, hasDrift := drifts[key]
+
+		var balance, entryDelta, balanceDelta float64
+		switch {
+		case hasMismatch:
+			ledger := mismatch.Balance
+			if hasDrift {
+				ledger = drift.Expected
+			}
+			balance = mismatch.Balance
+			entryDelta = mismatch.Expected - ledger
+			balanceDelta = mismatch.Expected - balance
+			fmt.Fprintf(&b, "\n-- user %d %s: balance %.8f, sum of completed transactions %.8f, sum of ledger entries %.8f\n",
+				key.userID, key.currency, balance, mismatch.Expected, ledger)
+		default:
+			balance = drift.Balance
+			balanceDelta = drift.Expected - balance
+			fmt.Fprintf(&b, "\n-- user %d %s: balance %.8f, sum of ledger entries %.8f\n",
+				key.userID, key.currency, balance, drift.Expected)
+		}
+
+		currency := quoteString(key.currency)
+		if math.Abs(entryDelta) > repairTolerance {
+			fmt.Fprintf(&b, "INSERT INTO ledger_entries (user_id, currency, amount, kind, created_at) VALUES (%d, %s, %.8f, '%s', CURRENT_TIMESTAMP);\n",
+				key.userID, currency, entryDelta, LedgerEntryAdjustment)
+		}
+		if math.Abs(balanceDelta) > repairTolerance {
+			op := "+"
+			if balanceDelta < 0 {
+				op = "-"
+			}
+			fmt.Fprintf(&b, "UPDATE balances SET amount = amount %s %.8f, updated_at = CURRENT_TIMESTAMP WHERE user_id = %d AND currency = %s;\n",
+				op, math.Abs(balanceDelta), key.userID, currency)
+		}
+	}
+
+	for _, v := range manual {
+		fmt.Fprintf(&b, "\n-- manual review: %s user %d", v.Type, v.UserID)
+		if v.Currency != "" {
+			fmt.Fprintf(&b, " %s", v.Currency)
+		}
+		if v.TransactionID != 0 {
+			fmt.Fprintf(&b, " transaction %d", v.TransactionID)
+		}
+		fmt.Fprintf(&b, ": %s\n", v.Details)
+	}
+
+	b.WriteString("\nCOMMIT;\n")
+	return b.String()
+}
+
+// quoteString экранирует строковый литерал SQL
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// SetUserStatus изменяет статус учетной записи, если он не менялся в change.ChangedAt
+// или позже, и записывает изменение в историю в той же транзакции
+func (s *SQLiteStorage) SetUserStatus(ctx context.Context, userID int64, change storages.UserStatusChange) (bool, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем текущий статус (транзакции сериализуются единственным соединением)
+	var previous string
+	var changedAt *time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT account_status, status_changed_at FROM users
+		WHERE id = $1
+	`, userID).Scan(&previous, &changedAt)
+
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get user status: %v", err)
+		return false, fmt.Errorf("failed to get user status: %w", err)
+	}
+
+	// 2. Статус уже изменен более поздней операцией
+	if changedAt != nil && !changedAt.Before(change.ChangedAt) {
+		return false, nil
+	}
+
+	// 3. Изменяем статус и записываем изменение в историю
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET account_status = $1, frozen_until = $2, status_reason = $3, status_changed_at = $4, updated_at = $5
+		WHERE id = $6
+	`, change.Status, change.FrozenUntil, change.Reason, change.ChangedAt, time.Now(), userID)
+	if err != nil {
+		s.logger.Errorf("Failed to update user status: %v", err)
+		return false, fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO account_status_history (user_id, status, previous_status, frozen_until, reason, changed_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, userID, change.Status, previous, change.FrozenUntil, change.Reason, change.ChangedBy, change.ChangedAt)
+	if err != nil {
+		s.logger.Errorf("Failed to record user status change: %v", err)
+		return false, fmt.Errorf("failed to record user status change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Updated status for user %d: %s -> %s", userID, previous, change.Status)
+	return true, nil
+}
+
+// GetAccountStatusHistory возвращает до limit последних изменений статуса пользователя
+func (s *SQLiteStorage) GetAccountStatusHistory(ctx context.Context, userID int64, limit int) ([]storages.AccountStatusEvent, error) {
+	query := `
+		SELECT id, user_id, status, previous_status, frozen_until, reason, changed_by, created_at
+		FROM account_status_history
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		s.logger.Errorf("Failed to query account status history: %v", err)
+		return nil, fmt.Errorf("failed to query account status history: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]storages.AccountStatusEvent, 0, limit)
+	for rows.Next() {
+		var event storages.AccountStatusEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Status,
+			&event.PreviousStatus,
+			&event.FrozenUntil,
+			&event.Reason,
+			&event.ChangedBy,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan account status event: %v", err)
+			return nil, fmt.Errorf("failed to scan account status event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating account status history: %v", err)
+		return nil, fmt.Errorf("error iterating account status history: %w", err)
+	}
+
+	return events, nil
+}
+
+// DeleteUser закрывает учетную запись с нулевыми балансами и обезличивает
+// персональные данные пользователя. Транзакции и журнал не изменяются
+func (s *SQLiteStorage) DeleteUser(ctx context.Context, userID int64, deletion storages.UserDeletion) error {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем текущий статус (транзакции сериализуются единственным соединением)
+	var previous string
+	err = tx.QueryRowContext(ctx, `
+		SELECT account_status FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(&previous)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get user status: %v", err)
+		return fmt.Errorf("failed to get user status: %w", err)
+	}
+
+	// 2. Проверяем, что на балансах и в удержании не осталось средств
+	rows, err := tx.QueryContext(ctx, `
+		SELECT currency, amount FROM balances
+		WHERE user_id = $1 AND (amount <> 0 OR held_amount <> 0)
+		ORDER BY currency
+	`, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to query balances: %v", err)
+		return fmt.Errorf("failed to query balances: %w", err)
+	}
+
+	var remaining []string
+	for rows.Next() {
+		var currency string
+		var amount float64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			rows.Close()
+			s.logger.Errorf("Failed to scan balance: %v", err)
+			return fmt.Errorf("failed to scan balance: %w", err)
+		}
+		remaining = append(remaining, currency+" "+strconv.FormatFloat(amount, 'f', -1, 64))
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		s.logger.Errorf("Error iterating balances: %v", err)
+		return fmt.Errorf("error iterating balances: %w", err)
+	}
+	rows.Close()
+
+	if len(remaining) > 0 {
+		return fmt.Errorf("%w: %s", storages.ErrBalanceNotEmpty, strings.Join(remaining, ", "))
+	}
+
+	// 3. Обезличиваем пользователя и закрываем учетную запись. Пустой хеш
+	// пароля не совпадает ни с одним паролем
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET username = $1, email = $2, password_hash = '', account_status = $3, frozen_until = NULL,
+			status_reason = $4, status_changed_at = $5, deleted_at = $5, updated_at = $5
+		WHERE id = $6
+	`, deletion.Username, deletion.Email, storages.AccountStatusClosed, deletion.Reason, deletion.DeletedAt, userID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("anonymized user %w", storages.ErrDuplicate)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to anonymize user: %v", err)
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO account_status_history (user_id, status, previous_status, reason, changed_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, storages.AccountStatusClosed, previous, deletion.Reason, userID, deletion.DeletedAt)
+	if err != nil {
+		s.logger.Errorf("Failed to record user status change: %v", err)
+		return fmt.Errorf("failed to record user status change: %w", err)
+	}
+
+	// 4. Отзываем ключи API и сессии, стираем данные заявок на верификацию;
+	// заявка на рассмотрении отклоняется
+	_, err = tx.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = $1
+		WHERE user_id = $2 AND revoked_at IS NULL
+	`, deletion.DeletedAt, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to revoke API keys: %v", err)
+		return fmt.Errorf("failed to revoke API keys: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = $1
+		WHERE user_id = $2 AND revoked_at IS NULL
+	`, deletion.DeletedAt, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to revoke sessions: %v", err)
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE verification_requests
+		SET full_name = '', date_of_birth = '', document_number = '',
+			status = CASE WHEN status = $1 THEN $2 ELSE status END,
+			review_comment = CASE WHEN status = $1 THEN $3 ELSE review_comment END,
+			reviewed_at = COALESCE(reviewed_at, $4)
+		WHERE user_id = $5
+	`, storages.VerificationStatusPending, storages.VerificationStatusRejected, deletion.Reason, deletion.DeletedAt, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to anonymize verification requests: %v", err)
+		return fmt.Errorf("failed to anonymize verification requests: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Deleted user %d: %s -> %s", userID, previous, storages.AccountStatusClosed)
+	return nil
+}
@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// archivableCondition условие транзакций, которые можно выгрузить в архив.
+// Первый параметр запроса - статус pending
+const archivableCondition = `t.status <> $1
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox o WHERE o.transaction_id = t.id AND o.sent_at IS NULL
+	)`
+
+// ListArchivableTransactions возвращает транзакции, которые можно выгрузить в архив
+func (s *SQLiteStorage) ListArchivableTransactions(ctx context.Context, before time.Time, afterID int64, limit int) ([]storages.Transaction, error) {
+	query := `
+		SELECT t.id, t.user_id, t.type, t.from_currency, t.to_currency, t.from_amount, t.to_amount, t.exchange_rate,
+			COALESCE(t.market_rate, t.exchange_rate), t.margin, t.source, t.status, t.created_at, t.completed_at
+		FROM transactions t
+		WHERE ` + archivableCondition + ` AND t.id > $2 AND t.created_at < $3
+		ORDER BY t.id
+		LIMIT $4
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, storages.TransactionStatusPending, afterID, before.In(time.Local), limit)
+	if err != nil {
+		s.logger.Errorf("Failed to query archivable transactions: %v", err)
+		return nil, fmt.Errorf("failed to query archivable transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]storages.Transaction, 0, limit)
+	for rows.Next() {
+		var tx storages.Transaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.UserID,
+			&tx.Type,
+			&tx.FromCurrency,
+			&tx.ToCurrency,
+			&tx.FromAmount,
+			&tx.ToAmount,
+			&tx.ExchangeRate,
+			&tx.MarketRate,
+			&tx.Margin,
+			&tx.Source,
+			&tx.Status,
+			&tx.CreatedAt,
+			&tx.CompletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan archivable transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan archivable transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating archivable transactions: %v", err)
+		return nil, fmt.Errorf("error iterating archivable transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// DeleteArchivedTransactions удаляет выгруженные транзакции. Перенос сумм
+// в archived_totals и удаление выполняются в одной транзакции
+func (s *SQLiteStorage) DeleteArchivedTransactions(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	in := placeholders(len(ids), 2)
+	args := append([]interface{}{storages.TransactionStatusPending}, int64Args(ids)...)
+
+	totalsQuery := fmt.Sprintf(`
+		INSERT INTO archived_totals (user_id, currency, amount, updated_at)
+		SELECT user_id, currency, SUM(delta), CURRENT_TIMESTAMP
+		FROM (
+			SELECT t.user_id, t.to_currency AS currency, t.to_amount AS delta
+			FROM transactions t
+			WHERE t.id IN (%[1]s) AND %[2]s AND t.status = $%[3]d AND t.type IN ($%[4]d, $%[5]d)
+			UNION ALL
+			SELECT t.user_id, t.from_currency AS currency, -t.from_amount AS delta
+			FROM transactions t
+			WHERE t.id IN (%[1]s) AND %[2]s AND t.status = $%[3]d AND t.type IN ($%[6]d, $%[5]d, $%[7]d)
+		)
+		GROUP BY user_id, currency
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET amount = archived_totals.amount + excluded.amount, updated_at = excluded.updated_at
+	`, in, archivableCondition, len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5)
+
+	totalsArgs := append(append([]interface{}{}, args...),
+		storages.TransactionStatusCompleted,
+		storages.TransactionTypeDeposit,
+		storages.TransactionTypeExchange,
+		storages.TransactionTypeWithdraw,
+		storages.TransactionTypeFee,
+	)
+	if _, err := tx.ExecContext(ctx, totalsQuery, totalsArgs...); err != nil {
+		s.logger.Errorf("Failed to update archived totals: %v", err)
+		return 0, fmt.Errorf("failed to update archived totals: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM transactions AS t WHERE t.id IN (`+in+`) AND `+archivableCondition, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to delete archived transactions: %v", err)
+		return 0, fmt.Errorf("failed to delete archived transactions: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Deleted %d archived transactions", deleted)
+	return deleted, nil
+}
@@ -0,0 +1,373 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// MemoryPath путь базы данных в памяти процесса
+const MemoryPath = ":memory:"
+
+// pingTimeout ограничивает ожидание единственного соединения в Ping
+const pingTimeout = time.Second
+
+// Config содержит конфигурацию для подключения к SQLite
+type Config struct {
+	// Path путь к файлу базы данных (MemoryPath для базы в памяти)
+	Path string
+}
+
+// SQLiteStorage реализует интерфейс Storage для SQLite.
+// Предназначено для локальной разработки без Docker
+type SQLiteStorage struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// New открывает (или создает) файл базы данных SQLite
+func New(cfg *Config, logger *logrus.Logger) (*SQLiteStorage, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", cfg.Path)
+	// Файл базы открывается в режиме WAL: запись не переписывает страницы базы
+	// целиком, а резервная копия и sqlite3 могут читать файл, пока сервис работает
+	if cfg.Path != MemoryPath {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite не поддерживает блокировку строк (FOR UPDATE), поэтому все операции
+	// выполняются через одно соединение и транзакции сериализуются
+	db.SetMaxOpenConns(1)
+
+	// Проверка подключения
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.Infof("Successfully opened SQLite database %s", cfg.Path)
+
+	storage := &SQLiteStorage{
+		db:     db,
+		logger: logger,
+	}
+
+	// Инициализация схемы БД
+	if err := storage.initSchema(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// initSchema создает необходимые таблицы, если они не существуют
+func (s *SQLiteStorage) initSchema(ctx context.Context) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username VARCHAR(50) UNIQUE NOT NULL,
+		email VARCHAR(100) UNIQUE NOT NULL,
+		password_hash VARCHAR(255) NOT NULL,
+		role VARCHAR(20) NOT NULL DEFAULT 'user',
+		account_status VARCHAR(20) NOT NULL DEFAULT 'active',
+		frozen_until TIMESTAMP,
+		status_reason TEXT NOT NULL DEFAULT '',
+		status_changed_at TIMESTAMP,
+		verification_level VARCHAR(20) NOT NULL DEFAULT 'unverified',
+		deleted_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS balances (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		currency VARCHAR(3) NOT NULL,
+		amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+		held_amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, currency),
+		CHECK (amount >= 0)
+	);
+
+	CREATE TABLE IF NOT EXISTS transactions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(20) NOT NULL,
+		from_currency VARCHAR(3),
+		to_currency VARCHAR(3),
+		from_amount NUMERIC(20, 8),
+		to_amount NUMERIC(20, 8),
+		exchange_rate NUMERIC(20, 8),
+		market_rate NUMERIC(20, 8),
+		margin NUMERIC(10, 8) NOT NULL DEFAULT 0,
+		source VARCHAR(20) NOT NULL DEFAULT '',
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		fee NUMERIC(20, 8) NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		completed_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		next_attempt_at TIMESTAMP,
+		sent_at TIMESTAMP,
+		parked_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS limits (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		operation VARCHAR(20) NOT NULL,
+		currency VARCHAR(3) NOT NULL,
+		period VARCHAR(10) NOT NULL,
+		amount NUMERIC(20, 8) NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, operation, currency, period),
+		CHECK (amount >= 0)
+	);
+
+	CREATE TABLE IF NOT EXISTS archived_totals (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		currency VARCHAR(3) NOT NULL,
+		amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, currency)
+	);
+
+	CREATE TABLE IF NOT EXISTS ledger_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		currency VARCHAR(3) NOT NULL,
+		amount NUMERIC(20, 8) NOT NULL,
+		transaction_id INTEGER,
+		kind VARCHAR(20) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		operation VARCHAR(20) NOT NULL,
+		from_currency VARCHAR(3) NOT NULL DEFAULT '',
+		to_currency VARCHAR(3) NOT NULL,
+		amount NUMERIC(20, 8) NOT NULL,
+		period VARCHAR(10) NOT NULL,
+		start_at TIMESTAMP NOT NULL,
+		next_run_at TIMESTAMP NOT NULL,
+		due_at TIMESTAMP NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		run_count INTEGER NOT NULL DEFAULT 0,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_run_at TIMESTAMP,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK (amount > 0)
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		prefix VARCHAR(20) NOT NULL,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		rate_limit REAL NOT NULL DEFAULT 0,
+		rate_burst INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		revoked_at TIMESTAMP,
+		CHECK (rate_limit >= 0 AND rate_burst >= 0)
+	);
+
+	CREATE TABLE IF NOT EXISTS account_status_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL,
+		previous_status VARCHAR(20) NOT NULL,
+		frozen_until TIMESTAMP,
+		reason TEXT NOT NULL,
+		changed_by INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS verification_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		level VARCHAR(20) NOT NULL,
+		full_name VARCHAR(200) NOT NULL,
+		date_of_birth VARCHAR(10) NOT NULL,
+		country VARCHAR(2) NOT NULL,
+		document_type VARCHAR(30) NOT NULL DEFAULT '',
+		document_number VARCHAR(50) NOT NULL DEFAULT '',
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		reviewer_id INTEGER NOT NULL DEFAULT 0,
+		review_comment TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		reviewed_at TIMESTAMP,
+		CHECK (level IN ('basic', 'full')),
+		CHECK (status IN ('pending', 'approved', 'rejected'))
+	);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		device VARCHAR(255) NOT NULL DEFAULT '',
+		ip VARCHAR(45) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		secret VARCHAR(100) NOT NULL,
+		events TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event_id VARCHAR(36) NOT NULL,
+		event_type VARCHAR(50) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP,
+		CHECK (status IN ('pending', 'delivered', 'failed'))
+	);
+
+	-- Балансы базы, созданной до появления журнала, становятся начальными записями
+	INSERT INTO ledger_entries (user_id, currency, amount, kind, created_at)
+	SELECT user_id, currency, amount, 'opening', CURRENT_TIMESTAMP
+	FROM balances
+	WHERE amount <> 0 AND NOT EXISTS (SELECT 1 FROM ledger_entries);
+
+	CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+	CREATE INDEX IF NOT EXISTS idx_balances_user_currency ON balances(user_id, currency);
+	-- История транзакций: фильтр по пользователю (и типу или статусу), сортировка по (created_at, id).
+	-- idx_transactions_user заменен индексом idx_transactions_user_created
+	DROP INDEX IF EXISTS idx_transactions_user;
+	CREATE INDEX IF NOT EXISTS idx_transactions_user_created ON transactions(user_id, created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_transactions_user_type_created ON transactions(user_id, type, created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_transactions_user_status_created ON transactions(user_id, status, created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
+	CREATE INDEX IF NOT EXISTS idx_transactions_created ON transactions(created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_currency ON ledger_entries(user_id, currency, id);
+	CREATE INDEX IF NOT EXISTS idx_schedules_user ON schedules(user_id, id);
+	CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules(due_at) WHERE active;
+	CREATE INDEX IF NOT EXISTS idx_transactions_pending_withdrawals ON transactions(created_at, id)
+		WHERE status = 'pending' AND type = 'withdraw';
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id, id) WHERE revoked_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_account_status_history_user ON account_status_history(user_id, id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_verification_requests_pending ON verification_requests(user_id) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_verification_requests_user ON verification_requests(user_id, id);
+	CREATE INDEX IF NOT EXISTS idx_verification_requests_status ON verification_requests(status, id);
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, id) WHERE revoked_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id, id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+	`
+
+	_, err := s.db.ExecContext(ctx, schema)
+	if err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	// Колонки, добавленные после первой версии схемы
+	columns := []struct{ table, column, definition string }{
+		{"transactions", "market_rate", "NUMERIC(20, 8)"},
+		{"transactions", "margin", "NUMERIC(10, 8) NOT NULL DEFAULT 0"},
+		{"transactions", "source", "VARCHAR(20) NOT NULL DEFAULT ''"},
+		{"transactions", "fee", "NUMERIC(20, 8) NOT NULL DEFAULT 0"},
+		{"balances", "held_amount", "NUMERIC(20, 8) NOT NULL DEFAULT 0"},
+		{"users", "account_status", "VARCHAR(20) NOT NULL DEFAULT 'active'"},
+		{"users", "frozen_until", "TIMESTAMP"},
+		{"users", "status_reason", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "status_changed_at", "TIMESTAMP"},
+		{"users", "verification_level", "VARCHAR(20) NOT NULL DEFAULT 'unverified'"},
+		{"users", "deleted_at", "TIMESTAMP"},
+		{"outbox", "next_attempt_at", "TIMESTAMP"},
+		{"outbox", "parked_at", "TIMESTAMP"},
+	}
+	for _, c := range columns {
+		if err := s.addColumnIfNotExists(ctx, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Database schema initialized")
+	return nil
+}
+
+// addColumnIfNotExists добавляет колонку в существующую таблицу.
+// SQLite не поддерживает ALTER TABLE ... ADD COLUMN IF NOT EXISTS
+func (s *SQLiteStorage) addColumnIfNotExists(ctx context.Context, table, column, definition string) error {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`, table, column,
+	).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с базой данных
+func (s *SQLiteStorage) Close() error {
+	if s.db != nil {
+		s.logger.Info("Closing database connection")
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Ping проверяет соединение с базой данных. Соединение одно: если оно занято
+// запросом, база доступна и проверка не ждет его освобождения. Иначе ожидание
+// ограничено pingTimeout, чтобы длинная транзакция не блокировала readiness
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	if s.db.Stats().InUse > 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// GetUserLimits возвращает лимиты пользователя
+func (s *SQLiteStorage) GetUserLimits(ctx context.Context, userID int64) ([]storages.Limit, error) {
+	query := `
+		SELECT user_id, operation, currency, period, amount, updated_at
+		FROM limits
+		WHERE user_id = $1
+		ORDER BY operation, currency, period
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		s.logger.Errorf("Failed to query limits: %v", err)
+		return nil, fmt.Errorf("failed to query limits: %w", err)
+	}
+	defer rows.Close()
+
+	var limits []storages.Limit
+	for rows.Next() {
+		var limit storages.Limit
+		err := rows.Scan(
+			&limit.UserID,
+			&limit.Operation,
+			&limit.Currency,
+			&limit.Period,
+			&limit.Amount,
+			&limit.UpdatedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan limit: %v", err)
+			return nil, fmt.Errorf("failed to scan limit: %w", err)
+		}
+		limits = append(limits, limit)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating limits: %v", err)
+		return nil, fmt.Errorf("error iterating limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// SetUserLimit создает или обновляет лимит пользователя
+func (s *SQLiteStorage) SetUserLimit(ctx context.Context, limit *storages.Limit) error {
+	query := `
+		INSERT INTO limits (user_id, operation, currency, period, amount, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, operation, currency, period)
+		DO UPDATE SET amount = EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+	`
+
+	now := time.Now()
+	_, err := s.conn(ctx).ExecContext(ctx, query,
+		limit.UserID,
+		limit.Operation,
+		limit.Currency,
+		limit.Period,
+		limit.Amount,
+		now,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to set limit: %v", err)
+		return fmt.Errorf("failed to set limit: %w", err)
+	}
+
+	limit.UpdatedAt = now
+
+	s.logger.Infof("Set %s %s limit for user %d: %.2f %s", limit.Period, limit.Operation, limit.UserID, limit.Amount, limit.Currency)
+	return nil
+}
+
+// DeleteUserLimit удаляет лимит пользователя
+func (s *SQLiteStorage) DeleteUserLimit(ctx context.Context, userID int64, operation, currency, period string) error {
+	query := `
+		DELETE FROM limits
+		WHERE user_id = $1 AND operation = $2 AND currency = $3 AND period = $4
+	`
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, userID, operation, currency, period)
+	if err != nil {
+		s.logger.Errorf("Failed to delete limit: %v", err)
+		return fmt.Errorf("failed to delete limit: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("limit %w", storages.ErrNotFound)
+	}
+
+	s.logger.Infof("Deleted %s %s limit for user %d in %s", period, operation, userID, currency)
+	return nil
+}
+
+// LockUserLimits проверяет, что пользователь существует. Блокировка строк не нужна:
+// транзакции SQLite выполняются через одно соединение и уже сериализованы
+func (s *SQLiteStorage) LockUserLimits(ctx context.Context, userID int64) error {
+	var id int64
+	err := s.conn(ctx).QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1`, userID).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %w", storages.ErrNotFound)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get user %d: %v", userID, err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return nil
+}
+
+// SumUserOperations возвращает сумму проведенных и ожидающих операций в валюте списания начиная с since.
+// Время хранится строкой в локальной зоне, поэтому since приводится к ней же
+func (s *SQLiteStorage) SumUserOperations(ctx context.Context, userID int64, operation, currency string, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(from_amount), 0)
+		FROM transactions
+		WHERE user_id = $1 AND type = $2 AND from_currency = $3
+			AND status IN ($4, $6) AND created_at >= $5
+	`
+
+	var total float64
+	err := s.conn(ctx).QueryRowContext(ctx, query, userID, operation, currency, storages.TransactionStatusCompleted, since.In(time.Local), storages.TransactionStatusPending).Scan(&total)
+	if err != nil {
+		s.logger.Errorf("Failed to sum operations: %v", err)
+		return 0, fmt.Errorf("failed to sum operations: %w", err)
+	}
+
+	return total, nil
+}
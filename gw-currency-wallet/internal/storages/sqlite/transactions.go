@@ -0,0 +1,439 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// CreateTransaction создает новую транзакцию
+func (s *SQLiteStorage) CreateTransaction(ctx context.Context, tx *storages.Transaction) error {
+	query := `
+		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate, market_rate, margin, source, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		tx.UserID,
+		tx.Type,
+		tx.FromCurrency,
+		tx.ToCurrency,
+		tx.FromAmount,
+		tx.ToAmount,
+		tx.ExchangeRate,
+		tx.MarketRate,
+		tx.Margin,
+		tx.Source,
+		tx.Status,
+		now,
+	).Scan(&tx.ID)
+
+	if err != nil {
+		s.logger.Errorf("Failed to create transaction: %v", err)
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	tx.CreatedAt = now
+
+	s.logger.Infof("Created transaction: ID=%d, Type=%s, User=%d", tx.ID, tx.Type, tx.UserID)
+	return nil
+}
+
+// GetTransaction возвращает транзакцию по ID
+func (s *SQLiteStorage) GetTransaction(ctx context.Context, txID int64) (*storages.Transaction, error) {
+	query := `
+		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate,
+			COALESCE(market_rate, exchange_rate), margin, source, status, fee, created_at, completed_at
+		FROM transactions
+		WHERE id = $1
+	`
+
+	var tx storages.Transaction
+	err := s.conn(ctx).QueryRowContext(ctx, query, txID).Scan(
+		&tx.ID,
+		&tx.UserID,
+		&tx.Type,
+		&tx.FromCurrency,
+		&tx.ToCurrency,
+		&tx.FromAmount,
+		&tx.ToAmount,
+		&tx.ExchangeRate,
+		&tx.MarketRate,
+		&tx.Margin,
+		&tx.Source,
+		&tx.Status,
+		&tx.Fee,
+		&tx.CreatedAt,
+		&tx.CompletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transaction %w", storages.ErrNotFound)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get transaction: %v", err)
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return &tx, nil
+}
+
+// GetUserTransactions возвращает страницу истории транзакций пользователя.
+// Курсор сравнивается по (created_at, id), поэтому страницы не смещаются,
+// когда появляются новые транзакции
+func (s *SQLiteStorage) GetUserTransactions(ctx context.Context, userID int64, filter storages.TransactionFilter) (*storages.TransactionPage, error) {
+	where, args := transactionFilterClause(userID, filter)
+
+	var total int64
+	err := s.conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		s.logger.Errorf("Failed to count transactions: %v", err)
+		return nil, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	if filter.Cursor > 0 {
+		args = append(args, filter.Cursor)
+		where += fmt.Sprintf(` AND (created_at, id) < (
+			SELECT created_at, id FROM transactions WHERE id = $%d AND user_id = $1
+		)`, len(args))
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	pageSize := filter.PageSize()
+	args = append(args, pageSize+1, filter.Offset)
+	query := `
+		SELECT id, user_id, type, from_currency, to_currency, from_amount, to_amount, exchange_rate,
+			COALESCE(market_rate, exchange_rate), margin, source, status, fee, created_at, completed_at
+		FROM transactions
+		WHERE ` + where + fmt.Sprintf(`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args))
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query transactions: %v", err)
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]storages.Transaction, 0, pageSize)
+	for rows.Next() {
+		var tx storages.Transaction
+		err := rows.Scan(
+			&tx.ID,
+			&tx.UserID,
+			&tx.Type,
+			&tx.FromCurrency,
+			&tx.ToCurrency,
+			&tx.FromAmount,
+			&tx.ToAmount,
+			&tx.ExchangeRate,
+			&tx.MarketRate,
+			&tx.Margin,
+			&tx.Source,
+			&tx.Status,
+			&tx.Fee,
+			&tx.CreatedAt,
+			&tx.CompletedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	page := &storages.TransactionPage{Transactions: transactions, Total: total}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		page.NextCursor = page.Transactions[pageSize-1].ID
+	}
+
+	return page, nil
+}
+
+// transactionFilterClause строит условие WHERE и аргументы для фильтра истории
+// транзакций без курсора. Первый аргумент всегда ID пользователя
+func transactionFilterClause(userID int64, filter storages.TransactionFilter) (string, []interface{}) {
+	where := "user_id = $1"
+	args := []interface{}{userID}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.In(time.Local))
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.In(time.Local))
+		where += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	for _, in := range []struct {
+		column string
+		values []string
+	}{{"type", filter.Types}, {"status", filter.Statuses}} {
+		if len(in.values) == 0 {
+			continue
+		}
+		placeholders := make([]string, len(in.values))
+		for i, value := range in.values {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		where += fmt.Sprintf(" AND %s IN (%s)", in.column, strings.Join(placeholders, ", "))
+	}
+
+	return where, args
+}
+
+// UpdateTransactionStatus переводит транзакцию из статуса from в to. Время
+// завершения записывается для конечных статусов
+func (s *SQLiteStorage) UpdateTransactionStatus(ctx context.Context, txID int64, from, to string) error {
+	query := `
+		UPDATE transactions
+		SET status = $1, completed_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	var completedAt *time.Time
+	if to != storages.TransactionStatusPending {
+		now := time.Now()
+		completedAt = &now
+	}
+
+	result, err := s.conn(ctx).ExecContext(ctx, query, to, completedAt, txID, from)
+	if err != nil {
+		s.logger.Errorf("Failed to update transaction status: %v", err)
+		return fmt.Errorf("failed to update transaction status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s transaction %d %w", from, txID, storages.ErrNotFound)
+	}
+
+	s.logger.Debugf("Updated transaction %d status from %s to %s", txID, from, to)
+	return nil
+}
+
+// ExecuteDeposit пополняет баланс атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *SQLiteStorage) ExecuteDeposit(ctx context.Context, userID int64, currency string, amount float64, notify bool) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeDeposit, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Зачисляем сумму записью журнала
+	if err := s.applyEntry(ctx, tx, userID, currency, amount, txID, storages.TransactionTypeDeposit); err != nil {
+		return 0, err
+	}
+
+	// 3. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return txID, nil
+}
+
+// ExecuteWithdraw списывает средства атомарно вместе с записью о транзакции и,
+// если notify, записью в outbox
+func (s *SQLiteStorage) ExecuteWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64, notify bool) (int64, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем средства, не удержанные ожидающими выводами
+	// (транзакции сериализуются единственным соединением)
+	var balance float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount - held_amount FROM balances
+		WHERE user_id = $1 AND currency = $2
+	`, userID, currency).Scan(&balance)
+
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, currency)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get balance: %v", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	// 2. Проверяем достаточность средств с учетом комиссии
+	if balance < amount+fee {
+		return 0, fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, balance, amount+fee)
+	}
+
+	// 3. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeWithdraw, currency, currency, amount, amount, storages.ExchangeQuote{MarketRate: 1.0, Rate: 1.0}, notify)
+	if err != nil {
+		return 0, err
+	}
+
+	// 4. Списываем сумму и комиссию записями журнала
+	if err := s.applyEntry(ctx, tx, userID, currency, -amount, txID, storages.TransactionTypeWithdraw); err != nil {
+		return 0, err
+	}
+
+	if err := s.insertFee(ctx, tx, userID, currency, fee); err != nil {
+		return 0, err
+	}
+
+	// 5. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return txID, nil
+}
+
+// ExecuteExchange выполняет обмен валюты атомарно
+func (s *SQLiteStorage) ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, fee float64, notify bool) (int64, error) {
+	// Начинаем транзакцию
+	tx, err := s.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем доступные средства исходной валюты
+	var fromBalance float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount - held_amount FROM balances
+		WHERE user_id = $1 AND currency = $2
+	`, userID, fromCurrency).Scan(&fromBalance)
+
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: no %s balance", storages.ErrInsufficientFunds, fromCurrency)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get from balance: %v", err)
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	// 2. Проверяем достаточность средств с учетом комиссии
+	if fromBalance < fromAmount+fee {
+		return 0, fmt.Errorf("%w: have %.2f, need %.2f", storages.ErrInsufficientFunds, fromBalance, fromAmount+fee)
+	}
+
+	// 3. Создаем запись о транзакции и уведомление
+	txID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeExchange, fromCurrency, toCurrency, fromAmount, toAmount, quote, notify)
+	if err != nil {
+		return 0, err
+	}
+
+	// 4. Списываем исходную валюту и зачисляем целевую записями журнала,
+	// затем списываем комиссию
+	if err := s.applyEntry(ctx, tx, userID, fromCurrency, -fromAmount, txID, storages.TransactionTypeExchange); err != nil {
+		return 0, err
+	}
+
+	if err := s.applyEntry(ctx, tx, userID, toCurrency, toAmount, txID, storages.TransactionTypeExchange); err != nil {
+		return 0, err
+	}
+
+	if err := s.insertFee(ctx, tx, userID, fromCurrency, fee); err != nil {
+		return 0, err
+	}
+
+	// 5. Коммитим транзакцию
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Exchange completed: User=%d, %.2f %s -> %.2f %s (rate: %.8f, margin: %.4f, source: %s)",
+		userID, fromAmount, fromCurrency, toAmount, toCurrency, quote.Rate, quote.Margin, quote.Source)
+
+	return txID, nil
+}
+
+// insertCompletedTransaction создает запись о проведенной транзакции внутри tx.
+// При notify в той же транзакции создается запись outbox, поэтому уведомление
+// не теряется, даже если Kafka недоступна в момент операции
+func (s *SQLiteStorage) insertCompletedTransaction(ctx context.Context, tx querier, userID int64, transferType, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote storages.ExchangeQuote, notify bool) (int64, error) {
+	now := time.Now()
+	var txID int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (user_id, type, from_currency, to_currency, from_amount, to_amount,
+			exchange_rate, market_rate, margin, source, status, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, userID, transferType, fromCurrency, toCurrency, fromAmount, toAmount,
+		quote.Rate, quote.MarketRate, quote.Margin, quote.Source, storages.TransactionStatusCompleted, now, now).Scan(&txID)
+
+	if err != nil {
+		s.logger.Errorf("Failed to create transaction record: %v", err)
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if notify {
+		if err := s.insertOutbox(ctx, tx, txID); err != nil {
+			return 0, err
+		}
+	}
+
+	return txID, nil
+}
+
+// insertOutbox создает внутри tx запись outbox для уведомления о транзакции
+func (s *SQLiteStorage) insertOutbox(ctx context.Context, tx querier, txID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (transaction_id, created_at)
+		VALUES ($1, $2)
+	`, txID, time.Now())
+
+	if err != nil {
+		s.logger.Errorf("Failed to create outbox record: %v", err)
+		return fmt.Errorf("failed to create outbox record: %w", err)
+	}
+	return nil
+}
+
+// insertFee создает запись о комиссии внутри tx и списывает ее с баланса
+// записью журнала
+func (s *SQLiteStorage) insertFee(ctx context.Context, tx querier, userID int64, currency string, fee float64) error {
+	if fee <= 0 {
+		return nil
+	}
+
+	feeTxID, err := s.insertCompletedTransaction(ctx, tx, userID, storages.TransactionTypeFee, currency, currency, fee, 0, storages.ExchangeQuote{}, false)
+	if err != nil {
+		return err
+	}
+
+	return s.applyEntry(ctx, tx, userID, currency, -fee, feeTxID, storages.TransactionTypeFee)
+}
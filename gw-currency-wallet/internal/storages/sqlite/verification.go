@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gw-currency-wallet/internal/storages"
+)
+
+// verificationColumns колонки заявки на верификацию в порядке scanVerificationRequest
+const verificationColumns = `id, user_id, level, full_name, date_of_birth, country, document_type, document_number,
+	status, reviewer_id, review_comment, created_at, reviewed_at`
+
+// scanVerificationRequest читает заявку на верификацию из строки результата
+func scanVerificationRequest(row interface{ Scan(dest ...any) error }) (storages.VerificationRequest, error) {
+	var request storages.VerificationRequest
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.Level,
+		&request.FullName,
+		&request.DateOfBirth,
+		&request.Country,
+		&request.DocumentType,
+		&request.DocumentNumber,
+		&request.Status,
+		&request.ReviewerID,
+		&request.ReviewComment,
+		&request.CreatedAt,
+		&reviewedAt,
+	)
+	if reviewedAt.Valid {
+		request.ReviewedAt = &reviewedAt.Time
+	}
+	return request, err
+}
+
+// CreateVerificationRequest сохраняет заявку в статусе pending
+func (s *SQLiteStorage) CreateVerificationRequest(ctx context.Context, request *storages.VerificationRequest) error {
+	query := `
+		INSERT INTO verification_requests (user_id, level, full_name, date_of_birth, country,
+			document_type, document_number, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.conn(ctx).QueryRowContext(ctx, query,
+		request.UserID,
+		request.Level,
+		request.FullName,
+		request.DateOfBirth,
+		request.Country,
+		request.DocumentType,
+		request.DocumentNumber,
+		storages.VerificationStatusPending,
+		now,
+	).Scan(&request.ID)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("pending verification request %w", storages.ErrDuplicate)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to create verification request: %v", err)
+		return fmt.Errorf("failed to create verification request: %w", err)
+	}
+
+	request.Status = storages.VerificationStatusPending
+	request.CreatedAt = now
+
+	s.logger.Infof("Created verification request %d for user %d: level %s", request.ID, request.UserID, request.Level)
+	return nil
+}
+
+// GetVerificationRequest возвращает заявку на верификацию
+func (s *SQLiteStorage) GetVerificationRequest(ctx context.Context, requestID int64) (*storages.VerificationRequest, error) {
+	query := `SELECT ` + verificationColumns + ` FROM verification_requests WHERE id = $1`
+
+	request, err := scanVerificationRequest(s.conn(ctx).QueryRowContext(ctx, query, requestID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("verification request %w", storages.ErrNotFound)
+		}
+		s.logger.Errorf("Failed to get verification request: %v", err)
+		return nil, fmt.Errorf("failed to get verification request: %w", err)
+	}
+
+	return &request, nil
+}
+
+// ListVerificationRequests возвращает заявки на верификацию по фильтру, новые первыми
+func (s *SQLiteStorage) ListVerificationRequests(ctx context.Context, filter storages.VerificationRequestFilter) ([]storages.VerificationRequest, error) {
+	query := `SELECT ` + verificationColumns + `
+		FROM verification_requests
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, filter.UserID, filter.Status, filter.Limit)
+	if err != nil {
+		s.logger.Errorf("Failed to query verification requests: %v", err)
+		return nil, fmt.Errorf("failed to query verification requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := make([]storages.VerificationRequest, 0)
+	for rows.Next() {
+		request, err := scanVerificationRequest(rows)
+		if err != nil {
+			s.logger.Errorf("Failed to scan verification request: %v", err)
+			return nil, fmt.Errorf("failed to scan verification request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating verification requests: %v", err)
+		return nil, fmt.Errorf("error iterating verification requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// ReviewVerificationRequest рассматривает заявку и при одобрении повышает
+// уровень верификации пользователя
+func (s *SQLiteStorage) ReviewVerificationRequest(ctx context.Context, requestID int64, review storages.VerificationReview) (*storages.VerificationRequest, error) {
+	tx, err := s.beginTx(ctx, nil)
+	if err != nil {
+		s.logger.Errorf("Failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Получаем заявку на рассмотрении
+	request, err := scanVerificationRequest(tx.QueryRowContext(ctx, `SELECT `+verificationColumns+`
+		FROM verification_requests
+		WHERE id = $1 AND status = $2
+	`, requestID, storages.VerificationStatusPending))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("pending verification request %d %w", requestID, storages.ErrNotFound)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get verification request: %v", err)
+		return nil, fmt.Errorf("failed to get verification request: %w", err)
+	}
+
+	// 2. Записываем решение
+	_, err = tx.ExecContext(ctx, `
+		UPDATE verification_requests
+		SET status = $1, reviewer_id = $2, review_comment = $3, reviewed_at = $4
+		WHERE id = $5
+	`, review.Status, review.ReviewerID, review.Comment, review.ReviewedAt, requestID)
+	if err != nil {
+		s.logger.Errorf("Failed to review verification request: %v", err)
+		return nil, fmt.Errorf("failed to review verification request: %w", err)
+	}
+
+	// 3. Повышаем уровень пользователя
+	if review.Status == storages.VerificationStatusApproved {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users SET verification_level = $1, updated_at = $2 WHERE id = $3
+		`, request.Level, time.Now(), request.UserID)
+		if err != nil {
+			s.logger.Errorf("Failed to update verification level: %v", err)
+			return nil, fmt.Errorf("failed to update verification level: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to commit transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	reviewedAt := review.ReviewedAt
+	request.Status = review.Status
+	request.ReviewerID = review.ReviewerID
+	request.ReviewComment = review.Comment
+	request.ReviewedAt = &reviewedAt
+
+	s.logger.Infof("Verification request %d of user %d %s by admin %d", requestID, request.UserID, review.Status, review.ReviewerID)
+	return &request, nil
+}
@@ -1,30 +1,218 @@
 package storages
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Storage определяет интерфейс для работы с хранилищем данных
 type Storage interface {
 	// User operations
-	CreateUser(ctx context.Context, user *User) error
+	// CreateUser создает пользователя и нулевые балансы в переданных валютах
+	CreateUser(ctx context.Context, user *User, currencies []string) error
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUserByID(ctx context.Context, userID int64) (*User, error)
-	
+	UpdateUserRole(ctx context.Context, userID int64, role string) error
+	// UpdateUserPassword заменяет хеш пароля; ErrNotFound для неизвестного пользователя
+	UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error
+	ListUsers(ctx context.Context, search string, limit, offset int) ([]User, int64, error)
+	// SetUserStatus изменяет статус учетной записи и записывает изменение в историю.
+	// Возвращает false, если статус уже менялся не раньше change.ChangedAt,
+	// и ErrNotFound для неизвестного пользователя
+	SetUserStatus(ctx context.Context, userID int64, change UserStatusChange) (bool, error)
+	// GetAccountStatusHistory возвращает до limit последних изменений статуса пользователя
+	GetAccountStatusHistory(ctx context.Context, userID int64, limit int) ([]AccountStatusEvent, error)
+	// DeleteUser в одной транзакции закрывает учетную запись, обезличивает
+	// персональные данные, отзывает ключи API и сессии и записывает закрытие в историю.
+	// Возвращает ErrBalanceNotEmpty, если на балансах или в удержании остались средства
+	DeleteUser(ctx context.Context, userID int64, deletion UserDeletion) error
+
 	// Balance operations
+	// Балансы - агрегат журнала ledger_entries и изменяются только атомарными
+	// операциями вместе с записями журнала
 	GetBalance(ctx context.Context, userID int64, currency string) (*Balance, error)
 	GetAllBalances(ctx context.Context, userID int64) ([]Balance, error)
-	UpdateBalance(ctx context.Context, balance *Balance) error
+	// GetBalanceCurrencies возвращает валюты, в которых есть хотя бы один баланс
+	GetBalanceCurrencies(ctx context.Context) ([]string, error)
+	// CreateBalance создает баланс; ненулевая сумма записывается в журнал как opening
 	CreateBalance(ctx context.Context, balance *Balance) error
-	
+
 	// Transaction operations
 	CreateTransaction(ctx context.Context, tx *Transaction) error
 	GetTransaction(ctx context.Context, txID int64) (*Transaction, error)
-	GetUserTransactions(ctx context.Context, userID int64, limit int) ([]Transaction, error)
-	UpdateTransactionStatus(ctx context.Context, txID int64, status string) error
-	
-	// Atomic operations for exchange
-	ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount, rate float64) error
-	
+	// GetUserTransactions возвращает страницу истории транзакций пользователя по фильтру
+	GetUserTransactions(ctx context.Context, userID int64, filter TransactionFilter) (*TransactionPage, error)
+	// UpdateTransactionStatus переводит транзакцию из статуса from в to. Возвращает
+	// ErrNotFound, если транзакции в статусе from нет. Внутри WithTransaction строка
+	// блокируется до фиксации, поэтому переход выполняется один раз
+	UpdateTransactionStatus(ctx context.Context, txID int64, from, to string) error
+
+	// Atomic operations
+	// Возвращают ID созданной записи о транзакции. При notify в той же
+	// транзакции БД создается запись outbox для уведомления в Kafka.
+	// Комиссия fee > 0 списывается в валюте списания отдельной записью типа fee
+	ExecuteDeposit(ctx context.Context, userID int64, currency string, amount float64, notify bool) (int64, error)
+	ExecuteWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64, notify bool) (int64, error)
+	ExecuteExchange(ctx context.Context, userID int64, fromCurrency, toCurrency string, fromAmount, toAmount float64, quote ExchangeQuote, fee float64, notify bool) (int64, error)
+
+	// Pending withdrawal operations
+	// Ожидающий вывод удерживает сумму и комиссию на балансе (held_amount) до
+	// подтверждения или отмены. Списываются только средства, не удержанные выводами
+	// CreatePendingWithdraw создает транзакцию вывода в статусе pending и удерживает
+	// amount + fee. Возвращает ID транзакции или ErrInsufficientFunds
+	CreatePendingWithdraw(ctx context.Context, userID int64, currency string, amount, fee float64) (int64, error)
+	ListPendingWithdrawals(ctx context.Context, filter PendingWithdrawalFilter) ([]Transaction, error)
+	// CompleteWithdraw проводит ожидающий вывод: снимает удержание, списывает сумму
+	// и комиссию и, если notify, создает запись outbox. ErrNotFound - вывод не ожидает подтверждения
+	CompleteWithdraw(ctx context.Context, txID int64, notify bool) (*Transaction, error)
+	// CancelWithdraw снимает удержание ожидающего вывода и переводит его в status
+	// (failed или cancelled). ErrNotFound - вывод не ожидает подтверждения
+	CancelWithdraw(ctx context.Context, txID int64, status string) (*Transaction, error)
+
+	// WithTransaction выполняет fn в транзакции БД: методы, вызванные с контекстом fn,
+	// выполняются в ней, при ошибке fn все изменения откатываются.
+	// Атомарные операции внутри fn присоединяются к этой транзакции
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// Limit operations
+	GetUserLimits(ctx context.Context, userID int64) ([]Limit, error)
+	// SetUserLimit создает или обновляет лимит пользователя
+	SetUserLimit(ctx context.Context, limit *Limit) error
+	DeleteUserLimit(ctx context.Context, userID int64, operation, currency, period string) error
+	// SumUserOperations возвращает сумму проведенных и ожидающих подтверждения
+	// операций типа operation в валюте списания currency, созданных начиная с since
+	SumUserOperations(ctx context.Context, userID int64, operation, currency string, since time.Time) (float64, error)
+	// LockUserLimits блокирует строку пользователя до конца транзакции WithTransaction,
+	// чтобы проверка лимитов и списание одного пользователя не выполнялись параллельно.
+	// ErrNotFound - пользователь не найден
+	LockUserLimits(ctx context.Context, userID int64) error
+
+	// Schedule operations
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	// GetSchedule возвращает регулярную операцию пользователя или ErrNotFound
+	GetSchedule(ctx context.Context, userID, scheduleID int64) (*Schedule, error)
+	ListSchedules(ctx context.Context, userID int64) ([]Schedule, error)
+	// UpdateSchedule сохраняет сумму, период, время запуска и активность операции
+	// пользователя и сбрасывает неудачные попытки
+	UpdateSchedule(ctx context.Context, schedule *Schedule) error
+	DeleteSchedule(ctx context.Context, userID, scheduleID int64) error
+	// ListDueSchedules возвращает до limit активных операций с due_at <= now
+	ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]Schedule, error)
+	// CompleteScheduleRun завершает запуск runCount: переносит операцию на next,
+	// сбрасывает попытки и сохраняет lastError (пусто - выполнена). Возвращает false,
+	// если запуск уже завершен или операция отключена. Внутри WithTransaction строка
+	// блокируется до фиксации, поэтому один запуск выполняется один раз
+	CompleteScheduleRun(ctx context.Context, scheduleID int64, runCount int, next time.Time, lastError string) (bool, error)
+	// RetryScheduleRun записывает неудачную попытку запуска runCount и переносит
+	// следующую попытку на retryAt
+	RetryScheduleRun(ctx context.Context, scheduleID int64, runCount int, retryAt time.Time, lastError string) error
+
+	// API key operations
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	// GetAPIKeyByHash возвращает действующий ключ по SHA-256 или ErrNotFound
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	// ListAPIKeys возвращает действующие ключи пользователя
+	ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error)
+	// RevokeAPIKey отзывает действующий ключ пользователя или возвращает ErrNotFound
+	RevokeAPIKey(ctx context.Context, userID, keyID int64) error
+	// SetAPIKeyRateLimit задает лимит запросов действующего ключа пользователя
+	SetAPIKeyRateLimit(ctx context.Context, userID, keyID int64, rate float64, burst int) error
+
+	// Webhook operations
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	// ListWebhooks возвращает вебхуки пользователя
+	ListWebhooks(ctx context.Context, userID int64) ([]Webhook, error)
+	// DeleteWebhook удаляет вебхук пользователя вместе с его доставками или возвращает ErrNotFound
+	DeleteWebhook(ctx context.Context, userID, webhookID int64) error
+	// EnqueueWebhookEvent создает ожидающие доставки события всем вебхукам пользователя,
+	// подписанным на его тип, и возвращает их число
+	EnqueueWebhookEvent(ctx context.Context, event WebhookEvent) (int64, error)
+	// ListWebhookDeliveries возвращает до limit последних доставок вебхука пользователя
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]WebhookDelivery, error)
+	// ListDueWebhookDeliveries возвращает до limit ожидающих доставок с next_attempt_at <= now
+	// вместе с адресом и секретом вебхука
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	// RecordWebhookAttempt сохраняет результат попытки attempt.Attempt ожидающей доставки.
+	// Возвращает false, если попытка уже записана (доставку обработал другой экземпляр)
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt WebhookAttempt) (bool, error)
+
+	// Session operations
+	CreateSession(ctx context.Context, session *Session) error
+	// GetSession возвращает сессию пользователя (в том числе отозванную) или ErrNotFound
+	GetSession(ctx context.Context, userID, sessionID int64) (*Session, error)
+	// ListActiveSessions возвращает не отозванные и не истекшие на момент now сессии пользователя
+	ListActiveSessions(ctx context.Context, userID int64, now time.Time) ([]Session, error)
+	// TouchSession обновляет время последнего использования действующей сессии
+	// или возвращает ErrNotFound
+	TouchSession(ctx context.Context, sessionID int64, usedAt time.Time) error
+	// RevokeSession отзывает действующую сессию пользователя или возвращает ErrNotFound
+	RevokeSession(ctx context.Context, userID, sessionID int64, revokedAt time.Time) error
+	// RevokeUserSessions отзывает все сессии пользователя, кроме exceptID (0 - все),
+	// и возвращает число отозванных
+	RevokeUserSessions(ctx context.Context, userID, exceptID int64, revokedAt time.Time) (int64, error)
+
+	// Verification operations
+	// CreateVerificationRequest сохраняет заявку в статусе pending или возвращает
+	// ErrDuplicate, если у пользователя уже есть заявка на рассмотрении
+	CreateVerificationRequest(ctx context.Context, request *VerificationRequest) error
+	// GetVerificationRequest возвращает заявку или ErrNotFound
+	GetVerificationRequest(ctx context.Context, requestID int64) (*VerificationRequest, error)
+	// ListVerificationRequests возвращает до filter.Limit заявок, новые первыми
+	ListVerificationRequests(ctx context.Context, filter VerificationRequestFilter) ([]VerificationRequest, error)
+	// ReviewVerificationRequest рассматривает заявку в статусе pending и при одобрении
+	// повышает уровень верификации пользователя в той же транзакции. Рассмотренная
+	// или неизвестная заявка - ErrNotFound
+	ReviewVerificationRequest(ctx context.Context, requestID int64, review VerificationReview) (*VerificationRequest, error)
+
+	// Outbox operations
+	// FetchPendingOutbox возвращает неотправленные и не отложенные насовсем записи,
+	// время попытки которых наступило к now
+	FetchPendingOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error)
+	MarkOutboxSent(ctx context.Context, ids []int64) error
+	// MarkOutboxFailed увеличивает счетчик попыток записи, сохраняет причину ошибки
+	// и откладывает следующую попытку до retryAt
+	MarkOutboxFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// ParkOutbox откладывает запись насовсем: она остается в outbox с последней
+	// ошибкой, но больше не выбирается FetchPendingOutbox
+	ParkOutbox(ctx context.Context, id int64) error
+	// RequeueOutbox возвращает в очередь записи по ID транзакций, доставка которых
+	// не подтвердилась после отметки об отправке (асинхронный режим Kafka),
+	// следующая попытка - не раньше retryAt
+	RequeueOutbox(ctx context.Context, transactionIDs []int64, reason string, retryAt time.Time) error
+
+	// Archive operations
+	// ListArchivableTransactions возвращает до limit транзакций с ID больше afterID,
+	// созданных раньше before, по возрастанию ID. Транзакции в статусе pending
+	// и с неотправленными уведомлениями outbox не возвращаются
+	ListArchivableTransactions(ctx context.Context, before time.Time, afterID int64, limit int) ([]Transaction, error)
+	// DeleteArchivedTransactions удаляет выгруженные в архив транзакции и переносит
+	// их проведенные суммы в archived_totals, чтобы сверка учета сходилась.
+	// Транзакции, ставшие неподходящими для архива, не удаляются. Возвращает число удаленных
+	DeleteArchivedTransactions(ctx context.Context, ids []int64) (int64, error)
+
+	// Ledger audit
+	// Проверяет инварианты учета: баланс равен сумме проведенных транзакций
+	// (включая выгруженные в архив) и сумме записей журнала, удержание равно сумме
+	// ожидающих выводов, нет отрицательных балансов и транзакций без пользователя или баланса
+	FindLedgerViolations(ctx context.Context) ([]LedgerViolation, error)
+	// FindBalanceDrift находит балансы, не равные сумме записей журнала ledger_entries
+	FindBalanceDrift(ctx context.Context) ([]LedgerViolation, error)
+	// RebuildBalances пересчитывает балансы из журнала и возвращает число исправленных
+	RebuildBalances(ctx context.Context) (int64, error)
+	// GetLedgerEntries возвращает до limit последних записей журнала пользователя,
+	// пустая currency - по всем валютам
+	GetLedgerEntries(ctx context.Context, userID int64, currency string, limit int) ([]LedgerEntry, error)
+	// GetLedgerEntriesSince возвращает записи журнала пользователя по всем валютам,
+	// созданные начиная с since, по возрастанию ID
+	GetLedgerEntriesSince(ctx context.Context, userID int64, since time.Time) ([]LedgerEntry, error)
+	// ListLedgerEntries возвращает до limit записей журнала пользователя с ID больше afterID,
+	// созданных в интервале from <= created_at < to, по возрастанию ID
+	ListLedgerEntries(ctx context.Context, userID int64, from, to time.Time, afterID int64, limit int) ([]LedgerEntry, error)
+	// GetLedgerTotalsBefore возвращает суммы записей журнала пользователя по валютам,
+	// созданных раньше before, - балансы на этот момент
+	GetLedgerTotalsBefore(ctx context.Context, userID int64, before time.Time) (UserBalances, error)
+
 	// Health check
 	Ping(ctx context.Context) error
 	Close() error
@@ -0,0 +1,384 @@
+package walletctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gw-currency-wallet/pkg/client"
+)
+
+// command подкоманда walletctl
+type command struct {
+	name    string
+	args    string // аргументы для справки
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+// commands возвращает подкоманды в порядке справки
+func commands() []*command {
+	return []*command{
+		{name: "login", args: "-u USERNAME [-password-stdin]", summary: "Log in and save the session", run: runLogin},
+		{name: "logout", summary: "Remove the saved session", run: runLogout},
+		{name: "balance", args: "[-total CURRENCY]", summary: "Show balances", run: runBalance},
+		{name: "deposit", args: "[-idempotency-key KEY] AMOUNT CURRENCY", summary: "Deposit funds", run: runDeposit},
+		{name: "withdraw", args: "[-idempotency-key KEY] AMOUNT CURRENCY", summary: "Withdraw funds", run: runWithdraw},
+		{name: "exchange", args: "[-idempotency-key KEY] AMOUNT FROM TO", summary: "Exchange currency", run: runExchange},
+		{name: "transactions", args: "[-limit N] [-cursor ID] [-type LIST] [-status LIST] [-from TIME] [-to TIME]", summary: "List transactions, newest first", run: runTransactions},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// errUsagePrinted ошибка флагов команды, уже выведенная пакетом flag вместе со справкой
+var errUsagePrinted = errors.New("invalid flags")
+
+// flags создает набор флагов команды. Ошибки флагов и справку по -h выводит пакет flag
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.env.Stderr, "Usage: walletctl [flags] %s %s\n", name, findCommand(name).args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse разбирает флаги и проверяет число позиционных аргументов
+func parse(fs *flag.FlagSet, args []string, positional int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsagePrinted
+	}
+	if fs.NArg() != positional {
+		return usagef("expected %d arguments, got %d", positional, fs.NArg())
+	}
+	return nil
+}
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("login")
+	username := fs.String("u", "", "Username")
+	passwordStdin := fs.Bool("password-stdin", false, "Read the password from stdin (default env "+EnvPassword+")")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *username == "" {
+		return usagef("-u is required")
+	}
+
+	password := getenv(a.env, EnvPassword, "")
+	if *passwordStdin {
+		line, err := bufio.NewReader(a.env.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		// Пароль не принимается флагом, чтобы не попадать в историю команд и список процессов
+		return usagef("password is required: set %s or use -password-stdin", EnvPassword)
+	}
+
+	tokens, err := a.client.Login(ctx, *username, password)
+	if err != nil {
+		return err
+	}
+	a.session = &Session{URL: a.client.BaseURL(), Username: *username, Tokens: *tokens}
+	if err := a.saveSession(); err != nil {
+		return err
+	}
+
+	if a.json {
+		return a.printJSON(map[string]string{"url": a.session.URL, "username": a.session.Username})
+	}
+	fmt.Fprintf(a.env.Stdout, "Logged in to %s as %s\n", a.session.URL, a.session.Username)
+	return nil
+}
+
+func runLogout(_ context.Context, a *app, args []string) error {
+	fs := a.flags("logout")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := os.Remove(a.tokenFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	if !a.json {
+		fmt.Fprintln(a.env.Stdout, "Logged out")
+	}
+	return nil
+}
+
+func runBalance(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("balance")
+	total := fs.String("total", "", "Show the value of all balances in the currency")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := a.authenticate(); err != nil {
+		return err
+	}
+
+	if *total != "" {
+		result, err := a.client.BalanceTotal(ctx, strings.ToUpper(*total))
+		if err != nil {
+			return err
+		}
+		if a.json {
+			return a.printJSON(result)
+		}
+		w := a.table("CURRENCY", "AMOUNT", "RATE", "VALUE")
+		for _, item := range result.Breakdown {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Currency, amount(item.Amount), rate(item.Rate), amount(item.Value))
+		}
+		fmt.Fprintf(w, "TOTAL\t\t\t%s %s\n", amount(result.Total), result.Currency)
+		return w.Flush()
+	}
+
+	balances, err := a.client.Balance(ctx)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(balances)
+	}
+	return a.printBalances(balances)
+}
+
+func runDeposit(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("deposit")
+	key := idempotencyKeyFlag(fs)
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	value, err := parseAmount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.authenticate(); err != nil {
+		return err
+	}
+
+	result, err := a.client.Deposit(withKey(ctx, *key), client.DepositRequest{Amount: value, Currency: strings.ToUpper(fs.Arg(1))})
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(result)
+	}
+	fmt.Fprintln(a.env.Stdout, result.Message)
+	return a.printBalances(result.NewBalance)
+}
+
+func runWithdraw(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("withdraw")
+	key := idempotencyKeyFlag(fs)
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	value, err := parseAmount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.authenticate(); err != nil {
+		return err
+	}
+
+	result, err := a.client.Withdraw(withKey(ctx, *key), client.WithdrawRequest{Amount: value, Currency: strings.ToUpper(fs.Arg(1))})
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(result)
+	}
+	fmt.Fprintf(a.env.Stdout, "%s (fee %s)\n", result.Message, amount(result.Fee))
+	if result.Status == "pending" {
+		fmt.Fprintf(a.env.Stdout, "Withdrawal %d is waiting for approval\n", result.TransactionID)
+		return nil
+	}
+	return a.printBalances(result.NewBalance)
+}
+
+func runExchange(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("exchange")
+	key := idempotencyKeyFlag(fs)
+	if err := parse(fs, args, 3); err != nil {
+		return err
+	}
+	value, err := parseAmount(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.authenticate(); err != nil {
+		return err
+	}
+
+	req := client.ExchangeRequest{FromCurrency: strings.ToUpper(fs.Arg(1)), ToCurrency: strings.ToUpper(fs.Arg(2)), Amount: value}
+	result, err := a.client.Exchange(withKey(ctx, *key), req)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(result)
+	}
+	fmt.Fprintf(a.env.Stdout, "Exchanged %s %s to %s %s (fee %s %s)\n",
+		amount(req.Amount), req.FromCurrency, amount(result.ExchangedAmount), req.ToCurrency, amount(result.Fee), result.FeeCurrency)
+	return a.printBalances(result.NewBalance)
+}
+
+func runTransactions(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("transactions")
+	var query client.TransactionsQuery
+	fs.IntVar(&query.Limit, "limit", 20, "Number of transactions")
+	fs.Int64Var(&query.Cursor, "cursor", 0, "Next page cursor printed by the previous call")
+	types := fs.String("type", "", "Comma-separated types: deposit, withdraw, exchange, fee")
+	statuses := fs.String("status", "", "Comma-separated statuses: pending, completed, failed, cancelled")
+	from := fs.String("from", "", "Inclusive lower bound of the creation time, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "Exclusive upper bound of the creation time, RFC 3339 or YYYY-MM-DD")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	query.Types = splitList(*types)
+	query.Statuses = splitList(*statuses)
+	var err error
+	if query.From, err = parseTime(*from); err != nil {
+		return usagef("invalid -from: %v", err)
+	}
+	if query.To, err = parseTime(*to); err != nil {
+		return usagef("invalid -to: %v", err)
+	}
+
+	if err := a.authenticate(); err != nil {
+		return err
+	}
+	page, err := a.client.Transactions(ctx, query)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(page)
+	}
+
+	w := a.table("ID", "CREATED", "TYPE", "STATUS", "DEBIT", "CREDIT", "FEE")
+	for _, tx := range page.Transactions {
+		debit, credit := "", ""
+		if tx.FromCurrency != "" {
+			debit = amount(tx.FromAmount) + " " + tx.FromCurrency
+		}
+		if tx.ToCurrency != "" {
+			credit = amount(tx.ToAmount) + " " + tx.ToCurrency
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			tx.ID, tx.CreatedAt.UTC().Format(time.DateTime), tx.Type, tx.Status, debit, credit, amount(tx.Fee))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.env.Stdout, "%d of %d transactions\n", len(page.Transactions), page.Total)
+	if page.NextCursor > 0 {
+		fmt.Fprintf(a.env.Stdout, "Next page: walletctl transactions -cursor %d\n", page.NextCursor)
+	}
+	return nil
+}
+
+// idempotencyKeyFlag флаг ключа идемпотентности операции, изменяющей баланс
+func idempotencyKeyFlag(fs *flag.FlagSet) *string {
+	return fs.String("idempotency-key", "", "Idempotency-Key header of the request (default random)")
+}
+
+func withKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return client.WithIdempotencyKey(ctx, key)
+}
+
+// printBalances выводит балансы по валютам в алфавитном порядке
+func (a *app) printBalances(balances client.Balances) error {
+	currencies := make([]string, 0, len(balances))
+	for currency := range balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	w := a.table("CURRENCY", "BALANCE")
+	for _, currency := range currencies {
+		fmt.Fprintf(w, "%s\t%s\n", currency, amount(balances[currency]))
+	}
+	return w.Flush()
+}
+
+// table создает вывод таблицы с заголовком columns
+func (a *app) table(columns ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(a.env.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	return w
+}
+
+func (a *app) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(a.env.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func parseAmount(value string) (float64, error) {
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, usagef("invalid amount %q", value)
+	}
+	return result, nil
+}
+
+// parseTime разбирает время в RFC 3339 или дату YYYY-MM-DD (начало дня UTC); пустая строка - нулевое время
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// amount форматирует сумму с точностью до сотых, как в логах кошелька; точные суммы выводит -json
+func amount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// rate форматирует курс: курсы обменника хранятся во float32
+func rate(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 32)
+}
@@ -0,0 +1,233 @@
+// Package walletctl - консольный клиент кошелька для проверок после выката и демонстраций:
+// вход, балансы, пополнение, вывод, обмен и история транзакций через pkg/client
+package walletctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gw-currency-wallet/pkg/client"
+)
+
+// Коды завершения
+const (
+	ExitOK    = 0
+	ExitError = 1 // команда не выполнена: ошибка API или сети
+	ExitUsage = 2 // неверные флаги или аргументы
+)
+
+// Переменные окружения, задающие флаги по умолчанию
+const (
+	EnvURL       = "WALLETCTL_URL"
+	EnvTokenFile = "WALLETCTL_TOKEN_FILE"
+	EnvAPIKey    = "WALLETCTL_API_KEY"
+	EnvPassword  = "WALLETCTL_PASSWORD"
+)
+
+// Значения флагов по умолчанию
+const (
+	DefaultURL     = "http://localhost:8080"
+	DefaultTimeout = 30 * time.Second
+)
+
+// userAgent заголовок User-Agent запросов walletctl
+const userAgent = "walletctl"
+
+// Env окружение запуска: потоки ввода-вывода и переменные окружения
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+	// HTTPClient клиент для запросов; nil - клиент pkg/client по умолчанию
+	HTTPClient *http.Client
+}
+
+// Session сессия входа, сохраняемая между запусками. Токены отправляются только на URL,
+// на котором выполнен вход
+type Session struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	client.Tokens
+}
+
+// app состояние одного запуска walletctl
+type app struct {
+	env       Env
+	baseURL   string
+	tokenFile string
+	apiKey    string
+	json      bool
+
+	client  *client.Client
+	session *Session
+}
+
+// usageError ошибка флагов или аргументов команды: выводится со справкой, код ExitUsage
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Run выполняет walletctl с аргументами args (без имени программы) и возвращает код завершения
+func Run(ctx context.Context, env Env, args []string) int {
+	a := &app{env: env}
+
+	fs := flag.NewFlagSet("walletctl", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() { a.usage(fs) }
+	fs.StringVar(&a.baseURL, "url", getenv(env, EnvURL, DefaultURL), "Base URL of the wallet API (env "+EnvURL+")")
+	fs.StringVar(&a.tokenFile, "token-file", getenv(env, EnvTokenFile, ""), "File with the login session (env "+EnvTokenFile+", default <user config dir>/walletctl/session.json)")
+	fs.StringVar(&a.apiKey, "api-key", getenv(env, EnvAPIKey, ""), "API key to use instead of the login session (env "+EnvAPIKey+")")
+	fs.BoolVar(&a.json, "json", false, "Print API responses as JSON")
+	timeout := fs.Duration("timeout", DefaultTimeout, "Timeout of the command")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(env.Stderr, "walletctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return ExitUsage
+	}
+
+	if a.tokenFile == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "walletctl: failed to find config dir, set -token-file: %v\n", err)
+			return ExitUsage
+		}
+		a.tokenFile = filepath.Join(dir, "walletctl", "session.json")
+	}
+
+	var err error
+	a.client, err = client.New(a.baseURL, client.Options{HTTPClient: env.HTTPClient, UserAgent: userAgent, APIKey: a.apiKey})
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "walletctl: %v\n", err)
+		return ExitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	err = cmd.run(ctx, a, fs.Args()[1:])
+
+	// Access токен мог обновиться по refresh токену: сессия сохраняется с новым токеном
+	if a.session != nil && a.client.Tokens() != a.session.Tokens {
+		a.session.Tokens = a.client.Tokens()
+		if saveErr := a.saveSession(); saveErr != nil {
+			fmt.Fprintf(env.Stderr, "walletctl: %v\n", saveErr)
+		}
+	}
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errUsagePrinted):
+		return ExitUsage
+	}
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(env.Stderr, "walletctl %s: %v\nUsage: walletctl [flags] %s %s\n", cmd.name, err, cmd.name, cmd.args)
+		return ExitUsage
+	}
+	fmt.Fprintf(env.Stderr, "walletctl %s: %v\n", cmd.name, err)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.IdempotencyKey != "" {
+		// По ключу запрос можно найти в логах кошелька и сверить с историей операций
+		fmt.Fprintf(env.Stderr, "Idempotency key: %s\n", apiErr.IdempotencyKey)
+	}
+	return ExitError
+}
+
+// usage выводит справку walletctl
+func (a *app) usage(fs *flag.FlagSet) {
+	w := a.env.Stderr
+	fmt.Fprintln(w, "Usage: walletctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\nRun \"walletctl <command> -h\" for the arguments of a command.")
+}
+
+// authenticate готовит клиент к запросам от имени пользователя: с ключом API
+// сессия не нужна, иначе загружается сессия входа
+func (a *app) authenticate() error {
+	if a.apiKey != "" {
+		return nil
+	}
+	return a.loadSession()
+}
+
+// loadSession загружает сессию входа и передает ее токены клиенту
+func (a *app) loadSession() error {
+	data, err := os.ReadFile(a.tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("not logged in, run \"walletctl login\" or set -api-key")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("failed to parse session %s: %w", a.tokenFile, err)
+	}
+	if session.URL != a.client.BaseURL() {
+		return fmt.Errorf("logged in to %s, not %s; run \"walletctl login\" again", session.URL, a.client.BaseURL())
+	}
+
+	a.session = &session
+	a.client.SetTokens(session.Tokens)
+	return nil
+}
+
+// saveSession сохраняет сессию входа; файл доступен только владельцу
+func (a *app) saveSession() error {
+	data, err := json.MarshalIndent(a.session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(a.tokenFile), 0o700); err != nil {
+		return fmt.Errorf("failed to create session dir: %w", err)
+	}
+	if err := os.WriteFile(a.tokenFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func getenv(env Env, key, fallback string) string {
+	if env.Getenv != nil {
+		if value := env.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return fallback
+}
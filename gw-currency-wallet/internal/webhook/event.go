@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Заголовки запросов доставки
+const (
+	// HeaderEvent тип события
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderEventID ID события, одинаковый во всех повторах: по нему получатель отбрасывает дубликаты
+	HeaderEventID = "X-Webhook-Event-Id"
+	// HeaderSignature подпись тела запроса, см. Sign
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event тело запроса доставки события
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// DepositData данные события deposit.completed
+type DepositData struct {
+	TransactionID int64   `json:"transaction_id"`
+	Currency      string  `json:"currency"`
+	Amount        float64 `json:"amount"`
+}
+
+// ExchangeData данные события exchange.executed
+type ExchangeData struct {
+	TransactionID int64   `json:"transaction_id"`
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	FromAmount    float64 `json:"from_amount"`
+	ToAmount      float64 `json:"to_amount"`
+	// Rate курс обмена с наценкой
+	Rate float64 `json:"rate"`
+	// Fee комиссия в валюте FromCurrency, списанная сверх FromAmount
+	Fee    float64 `json:"fee"`
+	Source string  `json:"source"`
+}
+
+// Sign возвращает значение заголовка подписи "t=<unix>,v1=<hex>", где v1 -
+// HMAC-SHA256 строки "<unix>.<body>" с ключом secret. Время в подписи позволяет
+// получателю отклонять повторно отправленные старые запросы
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", unix, signature(secret, unix, body))
+}
+
+// signature вычисляет HMAC-SHA256 подписываемой строки
+func signature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
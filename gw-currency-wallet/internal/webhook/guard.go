@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrDestinationNotAllowed адрес получателя во внутренней сети
+var ErrDestinationNotAllowed = errors.New("webhook destination address is not allowed")
+
+// sharedAddressSpace диапазон CGNAT (RFC 6598), не покрываемый net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsBlockedIP сообщает, что вебхук не доставляется на адрес ip: loopback,
+// частные сети RFC 1918 и ULA, link-local (включая метаданные облака
+// 169.254.169.254), CGNAT, multicast и неуказанный адрес
+func IsBlockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) || (ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// guardedTransport создает транспорт, который проверяет адрес при каждом
+// подключении, уже после разрешения имени: DNS rebinding не обходит проверку.
+// Прокси из окружения не используется, иначе проверялся бы адрес прокси
+func guardedTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlockedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-currency-wallet/internal/polling"
+	"gw-currency-wallet/internal/storages"
+)
+
+// maxResponseBody байт ответа получателя, которые читаются перед закрытием соединения
+const maxResponseBody = 64 << 10
+
+// userAgent User-Agent запросов доставки
+const userAgent = "gw-currency-wallet-webhooks"
+
+// Config параметры доставки вебхуков
+type Config struct {
+	PollInterval time.Duration
+	// BatchSize число доставок, отправляемых одновременно
+	BatchSize int
+	// MaxAttempts число попыток, после которого доставка считается неудачной
+	MaxAttempts int
+	// RetryInterval пауза перед первым повтором доставки (см. polling.Backoff)
+	RetryInterval time.Duration
+	// Timeout время ожидания ответа получателя
+	Timeout time.Duration
+	// AllowPrivateNetworks разрешает доставку на адреса внутренних сетей
+	// (локальная разработка и тесты), см. IsBlockedIP
+	AllowPrivateNetworks bool
+}
+
+// Worker периодически отправляет ожидающие доставки вебхуков. Доставка успешна,
+// если получатель ответил статусом 2xx; иначе она повторяется с растущей паузой,
+// а после MaxAttempts попыток получает статус failed. Доставка "хотя бы один раз":
+// при сбое после отправки или одновременной работе нескольких экземпляров событие
+// может прийти повторно, получатель отбрасывает дубликаты по X-Webhook-Event-Id
+type Worker struct {
+	storage storages.Storage
+	client  *http.Client
+	cfg     Config
+	logger  *logrus.Logger
+}
+
+// NewWorker создает обработчик доставок вебхуков. Перенаправления не выполняются:
+// ответ 3xx считается неудачной попыткой. Адреса внутренних сетей отклоняются
+// при подключении, если не задан AllowPrivateNetworks
+func NewWorker(storage storages.Storage, cfg Config, logger *logrus.Logger) *Worker {
+	return &Worker{
+		storage: storage,
+		client: &http.Client{
+			Transport: guardedTransport(cfg.AllowPrivateNetworks),
+			Timeout:   cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run отправляет доставки до отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Infof("Webhook worker started (interval: %v, batch size: %d, max attempts: %d)",
+		w.cfg.PollInterval, w.cfg.BatchSize, w.cfg.MaxAttempts)
+	polling.Run(ctx, w.cfg.PollInterval, w.cfg.BatchSize, w.RunDue)
+	w.logger.Info("Webhook worker stopped")
+}
+
+// RunDue отправляет одну порцию доставок, время попытки которых наступило к now,
+// и возвращает их количество
+func (w *Worker) RunDue(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := w.storage.ListDueWebhookDeliveries(ctx, now, w.cfg.BatchSize)
+	if err != nil {
+		w.logger.Errorf("Failed to fetch due webhook deliveries: %v", err)
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery storages.WebhookDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, delivery, now)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver выполняет попытку доставки и сохраняет ее результат
+func (w *Worker) deliver(ctx context.Context, delivery storages.WebhookDelivery, now time.Time) {
+	code, err := w.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Попытка прервана остановкой сервиса и будет повторена после запуска
+		return
+	}
+
+	attempt := storages.WebhookAttempt{
+		Attempt:      delivery.Attempts + 1,
+		Status:       storages.WebhookDeliveryDelivered,
+		ResponseCode: code,
+		At:           time.Now(),
+	}
+	switch {
+	case err == nil:
+		w.logger.Debugf("Webhook delivery %d (%s) delivered to webhook %d", delivery.ID, delivery.EventType, delivery.WebhookID)
+	case attempt.Attempt >= w.cfg.MaxAttempts:
+		attempt.Status = storages.WebhookDeliveryFailed
+		attempt.Error = err.Error()
+		w.logger.Warnf("Webhook delivery %d failed after %d attempts: %v", delivery.ID, attempt.Attempt, err)
+	default:
+		attempt.Status = storages.WebhookDeliveryPending
+		attempt.Error = err.Error()
+		attempt.NextAttemptAt = now.Add(polling.Backoff(w.cfg.RetryInterval, attempt.Attempt))
+		w.logger.Warnf("Webhook delivery %d attempt %d failed, retrying at %s: %v",
+			delivery.ID, attempt.Attempt, attempt.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	recorded, err := w.storage.RecordWebhookAttempt(ctx, delivery.ID, attempt)
+	if err != nil {
+		w.logger.Errorf("Failed to record webhook delivery %d attempt: %v", delivery.ID, err)
+		return
+	}
+	if !recorded {
+		w.logger.Debugf("Webhook delivery %d attempt %d already recorded", delivery.ID, attempt.Attempt)
+	}
+}
+
+// send отправляет тело доставки с подписью и возвращает HTTP статус ответа
+func (w *Worker) send(ctx context.Context, delivery storages.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, time.Now(), delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,552 @@
+// Package client - типизированный клиент REST API кошелька для внутренних
+// инструментов и тестов: вход и обновление токена, балансы, пополнение, вывод и обмен
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gw-common/errcodes"
+)
+
+// IdempotencyKeyHeader заголовок с ключом идемпотентности запросов, изменяющих баланс
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// APIKeyHeader заголовок с ключом API (см. Options.APIKey)
+const APIKeyHeader = "X-API-Key"
+
+// Значения Options по умолчанию
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultMaxRetries      = 3
+	DefaultRetryBackoff    = 200 * time.Millisecond
+	DefaultMaxRetryBackoff = 5 * time.Second
+	DefaultUserAgent       = "gw-wallet-client"
+)
+
+// Options настройки клиента. Нулевые значения заменяются значениями по умолчанию
+type Options struct {
+	// HTTPClient клиент для запросов; по умолчанию http.Client с DefaultTimeout
+	HTTPClient *http.Client
+	// MaxRetries число повторов после первой попытки; отрицательное значение отключает повторы
+	MaxRetries int
+	// RetryBackoff и MaxRetryBackoff начальная и максимальная пауза между повторами
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	UserAgent       string
+	// APIKey ключ API кошелька: запросы отправляются с заголовком X-API-Key вместо
+	// access токена. Ключу доступны только чтение и обмен (в зависимости от ключа)
+	APIKey string
+}
+
+// Client клиент API кошелька. Безопасен для использования из нескольких горутин.
+//
+// Повторы: чтение повторяется при сетевых ошибках и ответах 429, 502, 503, 504.
+// Запросы, изменяющие баланс, отправляются с заголовком Idempotency-Key (один ключ
+// на все попытки), но кошелек пока не отбрасывает повторы по ключу, поэтому такие
+// запросы повторяются, только если кошелек их точно не выполнил: соединение не
+// установлено или получен ответ 429 или 503
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+	apiKey     string
+
+	mu     sync.RWMutex
+	tokens Tokens
+}
+
+// New создает клиент API кошелька по адресу baseURL (например, http://localhost:8080)
+func New(baseURL string, opts Options) (*Client, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("invalid wallet base URL: %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    baseURL,
+		httpClient: opts.HTTPClient,
+		maxRetries: opts.MaxRetries,
+		backoff:    opts.RetryBackoff,
+		maxBackoff: opts.MaxRetryBackoff,
+		userAgent:  opts.UserAgent,
+		apiKey:     opts.APIKey,
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	switch {
+	case c.maxRetries == 0:
+		c.maxRetries = DefaultMaxRetries
+	case c.maxRetries < 0:
+		c.maxRetries = 0
+	}
+	if c.backoff <= 0 {
+		c.backoff = DefaultRetryBackoff
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = DefaultMaxRetryBackoff
+	}
+	if c.userAgent == "" {
+		c.userAgent = DefaultUserAgent
+	}
+
+	return c, nil
+}
+
+// BaseURL возвращает адрес API без завершающего "/"
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// SetTokens задает токены, полученные ранее, без входа
+func (c *Client) SetTokens(tokens Tokens) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens = tokens
+}
+
+// Tokens возвращает текущие токены
+func (c *Client) Tokens() Tokens {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tokens
+}
+
+// idempotencyKeyCtx ключ контекста с ключом идемпотентности
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey задает ключ идемпотентности для запроса, изменяющего баланс.
+// Без ключа клиент создает случайный ключ на каждый вызов метода
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// Register регистрирует пользователя
+func (c *Client) Register(ctx context.Context, req RegisterRequest) error {
+	return c.do(ctx, call{method: http.MethodPost, path: "/api/v1/register", body: req, mutating: true}, nil)
+}
+
+// Login выполняет вход и сохраняет токены для следующих запросов
+func (c *Client) Login(ctx context.Context, username, password string) (*Tokens, error) {
+	var tokens Tokens
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/login", body: loginRequest{username, password}}, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetTokens(tokens)
+	return &tokens, nil
+}
+
+// Refresh получает новый access токен по сохраненному refresh токену
+func (c *Client) Refresh(ctx context.Context) (*Tokens, error) {
+	refreshToken := c.Tokens().RefreshToken
+	if refreshToken == "" {
+		return nil, ErrNotAuthenticated
+	}
+
+	var tokens Tokens
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/refresh", body: refreshRequest{refreshToken}}, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.tokens.AccessToken = tokens.AccessToken
+	tokens = c.tokens
+	c.mu.Unlock()
+	return &tokens, nil
+}
+
+// Balance возвращает балансы пользователя
+func (c *Client) Balance(ctx context.Context) (Balances, error) {
+	var resp balanceResponse
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/balance", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Balance, nil
+}
+
+// BalanceTotal возвращает стоимость всех балансов в валюте currency
+func (c *Client) BalanceTotal(ctx context.Context, currency string) (*BalanceTotal, error) {
+	var total BalanceTotal
+	path := "/api/v1/balance/total?currency=" + url.QueryEscape(currency)
+	if err := c.do(ctx, call{method: http.MethodGet, path: path, auth: true}, &total); err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// BalanceHistory возвращает стоимость балансов в валюте base за последние days дней
+func (c *Client) BalanceHistory(ctx context.Context, base string, days int) (*BalanceHistory, error) {
+	var history BalanceHistory
+	path := fmt.Sprintf("/api/v1/balance/history?base=%s&days=%d", url.QueryEscape(base), days)
+	if err := c.do(ctx, call{method: http.MethodGet, path: path, auth: true}, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// Deposit пополняет счет
+func (c *Client) Deposit(ctx context.Context, req DepositRequest) (*DepositResult, error) {
+	var result DepositResult
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/wallet/deposit", body: req, auth: true, mutating: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Withdraw выводит средства со счета
+func (c *Client) Withdraw(ctx context.Context, req WithdrawRequest) (*WithdrawResult, error) {
+	var result WithdrawResult
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/wallet/withdraw", body: req, auth: true, mutating: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PendingWithdrawals возвращает выводы, ожидающие подтверждения
+func (c *Client) PendingWithdrawals(ctx context.Context) ([]Withdrawal, error) {
+	var resp withdrawalsResponse
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/wallet/withdrawals/pending", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Withdrawals, nil
+}
+
+// CancelWithdrawal отменяет ожидающий подтверждения вывод
+func (c *Client) CancelWithdrawal(ctx context.Context, id int64) (*Withdrawal, error) {
+	var withdrawal Withdrawal
+	path := fmt.Sprintf("/api/v1/wallet/withdrawals/%d/cancel", id)
+	if err := c.do(ctx, call{method: http.MethodPost, path: path, auth: true, mutating: true}, &withdrawal); err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// Rates возвращает курсы обмена: "FROM_TO" -> курс
+func (c *Client) Rates(ctx context.Context) (map[string]float32, error) {
+	var resp ratesResponse
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/exchange/rates", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Rates, nil
+}
+
+// Currencies возвращает поддерживаемые валюты
+func (c *Client) Currencies(ctx context.Context) ([]string, error) {
+	var resp currenciesResponse
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/exchange/currencies", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Currencies, nil
+}
+
+// Exchange обменивает валюту
+func (c *Client) Exchange(ctx context.Context, req ExchangeRequest) (*ExchangeResult, error) {
+	var result ExchangeResult
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/exchange", body: req, auth: true, mutating: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Transactions возвращает страницу истории транзакций
+func (c *Client) Transactions(ctx context.Context, query TransactionsQuery) (*TransactionPage, error) {
+	params := url.Values{}
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Offset > 0 {
+		params.Set("offset", strconv.Itoa(query.Offset))
+	}
+	if query.Cursor > 0 {
+		params.Set("cursor", strconv.FormatInt(query.Cursor, 10))
+	}
+	if len(query.Types) > 0 {
+		params.Set("type", strings.Join(query.Types, ","))
+	}
+	if len(query.Statuses) > 0 {
+		params.Set("status", strings.Join(query.Statuses, ","))
+	}
+	if !query.From.IsZero() {
+		params.Set("from", query.From.Format(time.RFC3339))
+	}
+	if !query.To.IsZero() {
+		params.Set("to", query.To.Format(time.RFC3339))
+	}
+
+	path := "/api/v1/transactions"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var page TransactionPage
+	if err := c.do(ctx, call{method: http.MethodGet, path: path, auth: true}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CreateWebhook регистрирует вебхук. Секрет подписи возвращается только здесь
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*CreatedWebhook, error) {
+	var created CreatedWebhook
+	if err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/webhooks", body: req, auth: true, mutating: true}, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Webhooks возвращает вебхуки пользователя
+func (c *Client) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var resp webhooksResponse
+	if err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/webhooks", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Webhooks, nil
+}
+
+// DeleteWebhook удаляет вебхук
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	path := fmt.Sprintf("/api/v1/webhooks/%d", id)
+	return c.do(ctx, call{method: http.MethodDelete, path: path, auth: true, mutating: true}, nil)
+}
+
+// WebhookDeliveries возвращает до limit последних доставок вебхука
+func (c *Client) WebhookDeliveries(ctx context.Context, id int64, limit int) ([]WebhookDelivery, error) {
+	var resp webhookDeliveriesResponse
+	path := fmt.Sprintf("/api/v1/webhooks/%d/deliveries?limit=%d", id, limit)
+	if err := c.do(ctx, call{method: http.MethodGet, path: path, auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Deliveries, nil
+}
+
+// call описание запроса к API
+type call struct {
+	method   string
+	path     string
+	body     interface{}
+	auth     bool // нужен access токен
+	mutating bool // изменяет состояние: отправляется с ключом идемпотентности
+}
+
+// do выполняет запрос с повторами и декодирует ответ в out. При ответе 401 на
+// запрос с токеном токен один раз обновляется по refresh токену
+func (c *Client) do(ctx context.Context, req call, out interface{}) error {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	var key string
+	if req.mutating {
+		key = idempotencyKey(ctx)
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, payload, key)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrNotAuthenticated) {
+				return err
+			}
+			if attempt < c.maxRetries && retryableError(err, req.mutating) {
+				if err := c.wait(ctx, attempt, 0); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("failed to call %s %s: %w", req.method, req.path, err)
+		}
+
+		if resp.StatusCode < http.StatusMultipleChoices {
+			return decodeResponse(resp, out)
+		}
+
+		apiErr := decodeError(resp)
+		apiErr.IdempotencyKey = key
+
+		// Истекший access токен обновляется, запрос повторяется без учета попытки.
+		// Если обновить токен не удалось, возвращается исходная ошибка
+		if resp.StatusCode == http.StatusUnauthorized && req.auth && !refreshed && c.Tokens().RefreshToken != "" {
+			refreshed = true
+			if _, err := c.Refresh(ctx); err == nil {
+				attempt--
+				continue
+			}
+			return apiErr
+		}
+
+		if attempt < c.maxRetries && retryableStatus(resp.StatusCode, req.mutating) {
+			if err := c.wait(ctx, attempt, retryAfter(resp)); err != nil {
+				return err
+			}
+			continue
+		}
+		return apiErr
+	}
+}
+
+// send отправляет одну попытку запроса
+func (c *Client) send(ctx context.Context, req call, payload []byte, key string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		httpReq.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if req.auth && c.apiKey != "" {
+		httpReq.Header.Set(APIKeyHeader, c.apiKey)
+	} else if req.auth {
+		token := c.Tokens().AccessToken
+		if token == "" {
+			return nil, ErrNotAuthenticated
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// wait ждет перед повтором: экспоненциальная пауза со случайным разбросом
+// или Retry-After сервера, но не больше maxBackoff
+func (c *Client) wait(ctx context.Context, attempt int, serverDelay time.Duration) error {
+	delay := c.backoff << attempt
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	// Разброс от половины до полной паузы, чтобы клиенты не повторяли синхронно
+	if n, err := rand.Int(rand.Reader, big.NewInt(int64(delay/2)+1)); err == nil {
+		delay = delay/2 + time.Duration(n.Int64())
+	}
+	if serverDelay > delay {
+		delay = min(serverDelay, c.maxBackoff)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryableError сообщает, можно ли повторить запрос после сетевой ошибки.
+// Изменяющий запрос повторяется, только если соединение не было установлено
+func retryableError(err error, mutating bool) bool {
+	if !mutating {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryableStatus сообщает, можно ли повторить запрос после ответа со статусом status.
+// 429 и 503 означают, что запрос не выполнен; 502 и 504 возвращает прокси,
+// и запрос мог быть выполнен, поэтому они повторяются только для чтения
+func retryableStatus(status int, mutating bool) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return !mutating
+	default:
+		return false
+	}
+}
+
+// retryAfter возвращает паузу из заголовка Retry-After (в секундах)
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// idempotencyKey возвращает ключ из контекста или новый случайный ключ
+func idempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKeyCtx{}).(string); ok && key != "" {
+		return key
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// decodeResponse декодирует успешный ответ в out
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer drain(resp)
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeError разбирает ответ с ошибкой. Ответ не в формате API (например,
+// от прокси) получает общий код реестра по HTTP статусу
+func decodeError(resp *http.Response) *APIError {
+	defer drain(resp)
+
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == nil {
+		code := errcodes.FromHTTPStatus(resp.StatusCode)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       string(code),
+			Number:     code.Number(),
+			Message:    http.StatusText(resp.StatusCode),
+		}
+	}
+
+	body.Error.StatusCode = resp.StatusCode
+	return body.Error
+}
+
+// drain дочитывает и закрывает тело ответа, чтобы соединение вернулось в пул
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gw-common/errcodes"
+)
+
+// Коды ошибок API кошелька (поле error.code ответа) из общего реестра gw-common/errcodes
+const (
+	CodeInvalidRequest      = string(errcodes.InvalidRequest)
+	CodeUnauthorized        = string(errcodes.Unauthorized)
+	CodeForbidden           = string(errcodes.Forbidden)
+	CodeNotFound            = string(errcodes.NotFound)
+	CodeUserExists          = string(errcodes.UserExists)
+	CodeInvalidCredentials  = string(errcodes.InvalidCredentials)
+	CodeInvalidAmount       = string(errcodes.InvalidAmount)
+	CodeUnsupportedCurrency = string(errcodes.UnsupportedCurrency)
+	CodeSameCurrency        = string(errcodes.SameCurrency)
+	CodePayloadTooLarge     = string(errcodes.PayloadTooLarge)
+	CodeWeakPassword        = string(errcodes.WeakPassword)
+	CodeInsufficientFunds   = string(errcodes.InsufficientFunds)
+	CodeLimitExceeded       = string(errcodes.LimitExceeded)
+	CodeRateLimited         = string(errcodes.RateLimited)
+	CodeAccountFrozen       = string(errcodes.AccountFrozen)
+	CodeAccountClosed       = string(errcodes.AccountClosed)
+	CodeBalanceNotEmpty     = string(errcodes.BalanceNotEmpty)
+	CodeServiceUnavailable  = string(errcodes.ServiceUnavailable)
+	CodeStaleRate           = string(errcodes.StaleRate)
+	CodeInternal            = string(errcodes.Internal)
+)
+
+// ErrNotAuthenticated метод требует входа, а токена нет
+var ErrNotAuthenticated = errors.New("client is not authenticated")
+
+// APIError ошибка, возвращенная API кошелька
+type APIError struct {
+	StatusCode int         `json:"-"`
+	Code       string      `json:"code"`
+	Number     int         `json:"number"`
+	Message    string      `json:"message"`
+	Details    interface{} `json:"details,omitempty"`
+	// IdempotencyKey ключ запроса, изменяющего баланс: по нему запрос можно
+	// повторить или сверить с историей операций
+	IdempotencyKey string `json:"-"`
+}
+
+// Error реализует интерфейс error
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("wallet API error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("wallet API error: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsCode сообщает, что err - ошибка API с кодом code
+func IsCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// errorResponse тело ответа с ошибкой
+type errorResponse struct {
+	Error *APIError `json:"error"`
+}
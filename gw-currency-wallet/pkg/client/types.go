@@ -0,0 +1,206 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Balances балансы пользователя: код валюты -> сумма
+type Balances map[string]float64
+
+// Tokens токены, выданные при входе. RefreshToken пуст в ответе на обновление
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// RegisterRequest данные регистрации пользователя
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// DepositRequest запрос на пополнение
+type DepositRequest struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// WithdrawRequest запрос на вывод
+type WithdrawRequest struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// ExchangeRequest запрос на обмен валюты
+type ExchangeRequest struct {
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Amount       float64 `json:"amount"`
+}
+
+// DepositResult результат пополнения
+type DepositResult struct {
+	Message    string   `json:"message"`
+	NewBalance Balances `json:"new_balance"`
+}
+
+// WithdrawResult результат вывода. Если выводы ожидают подтверждения, Status
+// равен "pending", TransactionID - ID вывода, а NewBalance пуст
+type WithdrawResult struct {
+	Message       string   `json:"message"`
+	Fee           float64  `json:"fee"`
+	NewBalance    Balances `json:"new_balance"`
+	TransactionID int64    `json:"transaction_id,omitempty"`
+	Status        string   `json:"status,omitempty"`
+}
+
+// Withdrawal ожидающий подтверждения вывод; сумма с комиссией удержана на балансе
+type Withdrawal struct {
+	ID          int64      `json:"id"`
+	Currency    string     `json:"currency"`
+	Amount      float64    `json:"amount"`
+	Fee         float64    `json:"fee"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ExchangeResult результат обмена
+type ExchangeResult struct {
+	Message         string   `json:"message"`
+	ExchangedAmount float64  `json:"exchanged_amount"`
+	Fee             float64  `json:"fee"`
+	FeeCurrency     string   `json:"fee_currency"`
+	NewBalance      Balances `json:"new_balance"`
+}
+
+// TransactionsQuery фильтр истории транзакций. Нулевые поля не ограничивают выборку,
+// Limit 0 - размер страницы по умолчанию
+type TransactionsQuery struct {
+	Limit    int
+	Offset   int
+	Cursor   int64 // NextCursor предыдущей страницы
+	Types    []string
+	Statuses []string
+	From     time.Time // включительно
+	To       time.Time // не включительно
+}
+
+// Transaction транзакция пользователя
+type Transaction struct {
+	ID           int64      `json:"id"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source,omitempty"`
+	FromCurrency string     `json:"from_currency,omitempty"`
+	ToCurrency   string     `json:"to_currency,omitempty"`
+	FromAmount   float64    `json:"from_amount"`
+	ToAmount     float64    `json:"to_amount"`
+	ExchangeRate float64    `json:"exchange_rate,omitempty"`
+	Fee          float64    `json:"fee"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// TransactionPage страница истории транзакций, от новых к старым. NextCursor 0 - последняя страница
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	Total        int64         `json:"total"`
+	NextCursor   int64         `json:"next_cursor,omitempty"`
+}
+
+// BalanceTotal стоимость всех балансов в одной валюте с разбивкой по валютам
+type BalanceTotal struct {
+	Currency  string             `json:"currency"`
+	Total     float64            `json:"total"`
+	Breakdown []BalanceTotalItem `json:"breakdown"`
+}
+
+// BalanceTotalItem баланс в валюте, курс к валюте итога и стоимость
+type BalanceTotalItem struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Rate     float64 `json:"rate"`
+	Value    float64 `json:"value"`
+}
+
+// BalanceHistory стоимость балансов по дням в базовой валюте
+type BalanceHistory struct {
+	BaseCurrency string                `json:"base_currency"`
+	Points       []BalanceHistoryPoint `json:"points"`
+}
+
+// BalanceHistoryPoint балансы на конец дня (YYYY-MM-DD, UTC) и их стоимость.
+// Балансы валют из Unpriced не имеют курса на этот день и не входят в Value
+type BalanceHistoryPoint struct {
+	Date     string   `json:"date"`
+	Value    float64  `json:"value"`
+	Balances Balances `json:"balances"`
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// CreateWebhookRequest запрос на регистрацию вебхука; пустой Events - все события
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Webhook вебхук пользователя
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatedWebhook созданный вебхук с секретом подписи (см. VerifyWebhookSignature)
+type CreatedWebhook struct {
+	Secret  string  `json:"secret"`
+	Webhook Webhook `json:"webhook"`
+}
+
+// WebhookDelivery доставка события вебхуку
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Тела ответов, в которых результат вложен в поле
+type (
+	balanceResponse struct {
+		Balance Balances `json:"balance"`
+	}
+	ratesResponse struct {
+		Rates map[string]float32 `json:"rates"`
+	}
+	withdrawalsResponse struct {
+		Withdrawals []Withdrawal `json:"withdrawals"`
+	}
+	currenciesResponse struct {
+		Currencies []string `json:"currencies"`
+	}
+	webhooksResponse struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	webhookDeliveriesResponse struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+	}
+	refreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	loginRequest struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+)
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запросов доставки вебхуков
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookEventIDHeader   = "X-Webhook-Event-Id"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Типы событий вебхуков
+const (
+	WebhookEventDepositCompleted = "deposit.completed"
+	WebhookEventExchangeExecuted = "exchange.executed"
+)
+
+// DefaultWebhookTolerance допустимое расхождение времени подписи и времени проверки
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// ErrInvalidWebhookSignature подпись вебхука отсутствует, не совпадает или устарела
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookEvent тело запроса доставки вебхука. Data разбирается в WebhookDepositData
+// или WebhookExchangeData по Type. Повторы доставки имеют тот же ID
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookDepositData данные события deposit.completed
+type WebhookDepositData struct {
+	TransactionID int64   `json:"transaction_id"`
+	Currency      string  `json:"currency"`
+	Amount        float64 `json:"amount"`
+}
+
+// WebhookExchangeData данные события exchange.executed
+type WebhookExchangeData struct {
+	TransactionID int64   `json:"transaction_id"`
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	FromAmount    float64 `json:"from_amount"`
+	ToAmount      float64 `json:"to_amount"`
+	Rate          float64 `json:"rate"`
+	Fee           float64 `json:"fee"`
+	Source        string  `json:"source"`
+}
+
+// VerifyWebhookSignature проверяет заголовок X-Webhook-Signature ("t=<unix>,v1=<hex>")
+// тела body секретом вебхука и разбирает событие. Подпись старше или новее
+// tolerance относительно текущего времени отклоняется; tolerance <= 0 - DefaultWebhookTolerance
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) (*WebhookEvent, error) {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, ErrInvalidWebhookSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event: %w", err)
+	}
+	return &event, nil
+}
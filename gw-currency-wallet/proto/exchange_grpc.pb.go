@@ -1,5 +1,8 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
-// Run './generate-proto.sh' to regenerate this file.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: proto/exchange.proto
 
 package proto
 
@@ -10,11 +13,48 @@ import (
 	status "google.golang.org/grpc/status"
 )
 
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
 const _ = grpc.SupportPackageIsVersion7
 
+const (
+	ExchangeService_GetExchangeRates_FullMethodName           = "/exchange.ExchangeService/GetExchangeRates"
+	ExchangeService_GetExchangeRateForCurrency_FullMethodName = "/exchange.ExchangeService/GetExchangeRateForCurrency"
+	ExchangeService_GetCurrencies_FullMethodName              = "/exchange.ExchangeService/GetCurrencies"
+	ExchangeService_CreateCurrency_FullMethodName             = "/exchange.ExchangeService/CreateCurrency"
+	ExchangeService_SetCurrencyActive_FullMethodName          = "/exchange.ExchangeService/SetCurrencyActive"
+	ExchangeService_GetCallerPairs_FullMethodName             = "/exchange.ExchangeService/GetCallerPairs"
+	ExchangeService_SetCallerPairs_FullMethodName             = "/exchange.ExchangeService/SetCallerPairs"
+	ExchangeService_BulkSetRates_FullMethodName               = "/exchange.ExchangeService/BulkSetRates"
+	ExchangeService_GetRateHistory_FullMethodName             = "/exchange.ExchangeService/GetRateHistory"
+	ExchangeService_GetVersion_FullMethodName                 = "/exchange.ExchangeService/GetVersion"
+)
+
+// ExchangeServiceClient is the client API for ExchangeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ExchangeServiceClient interface {
+	// Получение курсов обмена всех валют
 	GetExchangeRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ExchangeRatesResponse, error)
+	// Получение курса обмена для конкретной валюты
 	GetExchangeRateForCurrency(ctx context.Context, in *CurrencyRequest, opts ...grpc.CallOption) (*ExchangeRateResponse, error)
+	// Получение списка поддерживаемых валют
+	GetCurrencies(ctx context.Context, in *CurrenciesRequest, opts ...grpc.CallOption) (*CurrenciesResponse, error)
+	// Добавление новой валюты
+	CreateCurrency(ctx context.Context, in *CreateCurrencyRequest, opts ...grpc.CallOption) (*Currency, error)
+	// Включение или отключение валюты
+	SetCurrencyActive(ctx context.Context, in *SetCurrencyActiveRequest, opts ...grpc.CallOption) (*Currency, error)
+	// Получение пар валют, разрешенных вызывающей стороне
+	GetCallerPairs(ctx context.Context, in *CallerPairsRequest, opts ...grpc.CallOption) (*CallerPairsResponse, error)
+	// Замена списка пар валют, разрешенных вызывающей стороне
+	SetCallerPairs(ctx context.Context, in *SetCallerPairsRequest, opts ...grpc.CallOption) (*CallerPairsResponse, error)
+	// Массовое обновление курсов в одной транзакции: отсутствующие пары создаются
+	BulkSetRates(ctx context.Context, in *BulkSetRatesRequest, opts ...grpc.CallOption) (*BulkSetRatesResponse, error)
+	// Получение курса пары на конец каждого дня периода
+	GetRateHistory(ctx context.Context, in *RateHistoryRequest, opts ...grpc.CallOption) (*RateHistoryResponse, error)
+	// Получение версии и сведений о сборке сервиса
+	GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionResponse, error)
 }
 
 type exchangeServiceClient struct {
@@ -27,7 +67,7 @@ func NewExchangeServiceClient(cc grpc.ClientConnInterface) ExchangeServiceClient
 
 func (c *exchangeServiceClient) GetExchangeRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ExchangeRatesResponse, error) {
 	out := new(ExchangeRatesResponse)
-	err := c.cc.Invoke(ctx, "/exchange.ExchangeService/GetExchangeRates", in, out, opts...)
+	err := c.cc.Invoke(ctx, ExchangeService_GetExchangeRates_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -36,19 +76,113 @@ func (c *exchangeServiceClient) GetExchangeRates(ctx context.Context, in *Empty,
 
 func (c *exchangeServiceClient) GetExchangeRateForCurrency(ctx context.Context, in *CurrencyRequest, opts ...grpc.CallOption) (*ExchangeRateResponse, error) {
 	out := new(ExchangeRateResponse)
-	err := c.cc.Invoke(ctx, "/exchange.ExchangeService/GetExchangeRateForCurrency", in, out, opts...)
+	err := c.cc.Invoke(ctx, ExchangeService_GetExchangeRateForCurrency_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) GetCurrencies(ctx context.Context, in *CurrenciesRequest, opts ...grpc.CallOption) (*CurrenciesResponse, error) {
+	out := new(CurrenciesResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_GetCurrencies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) CreateCurrency(ctx context.Context, in *CreateCurrencyRequest, opts ...grpc.CallOption) (*Currency, error) {
+	out := new(Currency)
+	err := c.cc.Invoke(ctx, ExchangeService_CreateCurrency_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) SetCurrencyActive(ctx context.Context, in *SetCurrencyActiveRequest, opts ...grpc.CallOption) (*Currency, error) {
+	out := new(Currency)
+	err := c.cc.Invoke(ctx, ExchangeService_SetCurrencyActive_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) GetCallerPairs(ctx context.Context, in *CallerPairsRequest, opts ...grpc.CallOption) (*CallerPairsResponse, error) {
+	out := new(CallerPairsResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_GetCallerPairs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) SetCallerPairs(ctx context.Context, in *SetCallerPairsRequest, opts ...grpc.CallOption) (*CallerPairsResponse, error) {
+	out := new(CallerPairsResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_SetCallerPairs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) BulkSetRates(ctx context.Context, in *BulkSetRatesRequest, opts ...grpc.CallOption) (*BulkSetRatesResponse, error) {
+	out := new(BulkSetRatesResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_BulkSetRates_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) GetRateHistory(ctx context.Context, in *RateHistoryRequest, opts ...grpc.CallOption) (*RateHistoryResponse, error) {
+	out := new(RateHistoryResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_GetRateHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeServiceClient) GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionResponse, error) {
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_GetVersion_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
+// ExchangeServiceServer is the server API for ExchangeService service.
+// All implementations must embed UnimplementedExchangeServiceServer
+// for forward compatibility
 type ExchangeServiceServer interface {
+	// Получение курсов обмена всех валют
 	GetExchangeRates(context.Context, *Empty) (*ExchangeRatesResponse, error)
+	// Получение курса обмена для конкретной валюты
 	GetExchangeRateForCurrency(context.Context, *CurrencyRequest) (*ExchangeRateResponse, error)
+	// Получение списка поддерживаемых валют
+	GetCurrencies(context.Context, *CurrenciesRequest) (*CurrenciesResponse, error)
+	// Добавление новой валюты
+	CreateCurrency(context.Context, *CreateCurrencyRequest) (*Currency, error)
+	// Включение или отключение валюты
+	SetCurrencyActive(context.Context, *SetCurrencyActiveRequest) (*Currency, error)
+	// Получение пар валют, разрешенных вызывающей стороне
+	GetCallerPairs(context.Context, *CallerPairsRequest) (*CallerPairsResponse, error)
+	// Замена списка пар валют, разрешенных вызывающей стороне
+	SetCallerPairs(context.Context, *SetCallerPairsRequest) (*CallerPairsResponse, error)
+	// Массовое обновление курсов в одной транзакции: отсутствующие пары создаются
+	BulkSetRates(context.Context, *BulkSetRatesRequest) (*BulkSetRatesResponse, error)
+	// Получение курса пары на конец каждого дня периода
+	GetRateHistory(context.Context, *RateHistoryRequest) (*RateHistoryResponse, error)
+	// Получение версии и сведений о сборке сервиса
+	GetVersion(context.Context, *Empty) (*VersionResponse, error)
 	mustEmbedUnimplementedExchangeServiceServer()
 }
 
+// UnimplementedExchangeServiceServer must be embedded to have forward compatible implementations.
 type UnimplementedExchangeServiceServer struct {
 }
 
@@ -58,8 +192,35 @@ func (UnimplementedExchangeServiceServer) GetExchangeRates(context.Context, *Emp
 func (UnimplementedExchangeServiceServer) GetExchangeRateForCurrency(context.Context, *CurrencyRequest) (*ExchangeRateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetExchangeRateForCurrency not implemented")
 }
+func (UnimplementedExchangeServiceServer) GetCurrencies(context.Context, *CurrenciesRequest) (*CurrenciesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCurrencies not implemented")
+}
+func (UnimplementedExchangeServiceServer) CreateCurrency(context.Context, *CreateCurrencyRequest) (*Currency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCurrency not implemented")
+}
+func (UnimplementedExchangeServiceServer) SetCurrencyActive(context.Context, *SetCurrencyActiveRequest) (*Currency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCurrencyActive not implemented")
+}
+func (UnimplementedExchangeServiceServer) GetCallerPairs(context.Context, *CallerPairsRequest) (*CallerPairsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCallerPairs not implemented")
+}
+func (UnimplementedExchangeServiceServer) SetCallerPairs(context.Context, *SetCallerPairsRequest) (*CallerPairsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCallerPairs not implemented")
+}
+func (UnimplementedExchangeServiceServer) BulkSetRates(context.Context, *BulkSetRatesRequest) (*BulkSetRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkSetRates not implemented")
+}
+func (UnimplementedExchangeServiceServer) GetRateHistory(context.Context, *RateHistoryRequest) (*RateHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRateHistory not implemented")
+}
+func (UnimplementedExchangeServiceServer) GetVersion(context.Context, *Empty) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
 func (UnimplementedExchangeServiceServer) mustEmbedUnimplementedExchangeServiceServer() {}
 
+// UnsafeExchangeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExchangeServiceServer will
+// result in compilation errors.
 type UnsafeExchangeServiceServer interface {
 	mustEmbedUnimplementedExchangeServiceServer()
 }
@@ -78,7 +239,7 @@ func _ExchangeService_GetExchangeRates_Handler(srv interface{}, ctx context.Cont
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/exchange.ExchangeService/GetExchangeRates",
+		FullMethod: ExchangeService_GetExchangeRates_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(ExchangeServiceServer).GetExchangeRates(ctx, req.(*Empty))
@@ -96,7 +257,7 @@ func _ExchangeService_GetExchangeRateForCurrency_Handler(srv interface{}, ctx co
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/exchange.ExchangeService/GetExchangeRateForCurrency",
+		FullMethod: ExchangeService_GetExchangeRateForCurrency_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return srv.(ExchangeServiceServer).GetExchangeRateForCurrency(ctx, req.(*CurrencyRequest))
@@ -104,6 +265,153 @@ func _ExchangeService_GetExchangeRateForCurrency_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExchangeService_GetCurrencies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CurrenciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).GetCurrencies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_GetCurrencies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).GetCurrencies(ctx, req.(*CurrenciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_CreateCurrency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCurrencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).CreateCurrency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_CreateCurrency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).CreateCurrency(ctx, req.(*CreateCurrencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_SetCurrencyActive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCurrencyActiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).SetCurrencyActive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_SetCurrencyActive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).SetCurrencyActive(ctx, req.(*SetCurrencyActiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_GetCallerPairs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallerPairsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).GetCallerPairs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_GetCallerPairs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).GetCallerPairs(ctx, req.(*CallerPairsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_SetCallerPairs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCallerPairsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).SetCallerPairs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_SetCallerPairs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).SetCallerPairs(ctx, req.(*SetCallerPairsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_BulkSetRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkSetRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).BulkSetRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_BulkSetRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).BulkSetRates(ctx, req.(*BulkSetRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_GetRateHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).GetRateHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_GetRateHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).GetRateHistory(ctx, req.(*RateHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExchangeService_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).GetVersion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ExchangeService_ServiceDesc is the grpc.ServiceDesc for ExchangeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
 var ExchangeService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "exchange.ExchangeService",
 	HandlerType: (*ExchangeServiceServer)(nil),
@@ -116,7 +424,39 @@ var ExchangeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetExchangeRateForCurrency",
 			Handler:    _ExchangeService_GetExchangeRateForCurrency_Handler,
 		},
+		{
+			MethodName: "GetCurrencies",
+			Handler:    _ExchangeService_GetCurrencies_Handler,
+		},
+		{
+			MethodName: "CreateCurrency",
+			Handler:    _ExchangeService_CreateCurrency_Handler,
+		},
+		{
+			MethodName: "SetCurrencyActive",
+			Handler:    _ExchangeService_SetCurrencyActive_Handler,
+		},
+		{
+			MethodName: "GetCallerPairs",
+			Handler:    _ExchangeService_GetCallerPairs_Handler,
+		},
+		{
+			MethodName: "SetCallerPairs",
+			Handler:    _ExchangeService_SetCallerPairs_Handler,
+		},
+		{
+			MethodName: "BulkSetRates",
+			Handler:    _ExchangeService_BulkSetRates_Handler,
+		},
+		{
+			MethodName: "GetRateHistory",
+			Handler:    _ExchangeService_GetRateHistory_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _ExchangeService_GetVersion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "proto/exchange/exchange.proto",
+	Metadata: "proto/exchange.proto",
 }
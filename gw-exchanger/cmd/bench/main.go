@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"gw-common/logger"
+	"gw-exchanger/internal/bench"
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/grpc"
+	"gw-exchanger/internal/storages"
+	"gw-exchanger/internal/storages/cache"
+	"gw-exchanger/internal/storages/memory"
+	"gw-exchanger/internal/storages/postgres"
+	pb "gw-exchanger/proto"
+)
+
+// Хранилища, на которых запускаются измерения
+const (
+	backendMemory   = "memory"
+	backendPostgres = "postgres"
+	backendRemote   = "remote"
+)
+
+func main() {
+	configPath := flag.String("c", "", "Path to config file (DB_* settings for the postgres backend)")
+	backend := flag.String("backend", backendMemory, "Storage backend: memory or postgres")
+	addr := flag.String("addr", "", "Load a running exchanger at host:port instead of an in-process server")
+	token := flag.String("token", "", "API token for -addr")
+	cacheTTL := flag.Duration("cache", 0, "Rates cache refresh interval for the in-process server (0 - read rates from the storage)")
+	concurrency := flag.Int("concurrency", 16, "Concurrent callers in the load phase")
+	duration := flag.Duration("duration", 10*time.Second, "Load phase duration per method")
+	skipBench := flag.Bool("skip-bench", false, "Skip Go benchmarks and run only the load phase")
+	thresholdsPath := flag.String("thresholds", "", "JSON file with regression thresholds (exit code 1 on violations)")
+	flag.Parse()
+
+	// Логи сервера на каждый запрос искажают измерения, поэтому выводятся только ошибки
+	log := logger.New("error")
+
+	var thresholds bench.Thresholds
+	if *thresholdsPath != "" {
+		var err error
+		if thresholds, err = bench.LoadThresholds(*thresholdsPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load thresholds: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *addr != "" {
+		*backend = backendRemote
+		*skipBench = true
+	}
+
+	var (
+		service bench.ExchangeService
+		target  = *addr
+	)
+	if *addr == "" {
+		storage, err := newStorage(*backend, *configPath, log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %s storage: %v\n", *backend, err)
+			os.Exit(2)
+		}
+		defer storage.Close()
+
+		if *cacheTTL > 0 {
+			ratesCache := cache.New(storage, *cacheTTL, log)
+			go ratesCache.Run(ctx)
+			storage = ratesCache
+		}
+
+		server := grpc.NewExchangeServer(storage, log)
+		service = server
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create listener: %v\n", err)
+			os.Exit(2)
+		}
+		srv := grpcServer.NewServer()
+		pb.RegisterExchangeServiceServer(srv, server)
+		go srv.Serve(listener)
+		defer srv.Stop()
+		target = listener.Addr().String()
+	}
+
+	conn, err := grpcServer.Dial(target,
+		grpcServer.WithTransportCredentials(insecure.NewCredentials()),
+		grpcServer.WithUnaryInterceptor(tokenInterceptor(*token)),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to %s: %v\n", target, err)
+		os.Exit(2)
+	}
+	defer conn.Close()
+	client := bench.ClientService(pb.NewExchangeServiceClient(conn))
+
+	pairs, err := ratePairs(ctx, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get exchange rates: %v\n", err)
+		os.Exit(2)
+	}
+
+	var violations []string
+	check := func(method string, checkFn func(bench.Threshold) []string) {
+		if threshold, ok := thresholds.For(*backend, method); ok {
+			violations = append(violations, checkFn(threshold)...)
+		}
+	}
+
+	fmt.Printf("backend: %s, pairs: %d\n", *backend, len(pairs))
+
+	// Go бенчмарки: вызов обработчика без сети, стоимость сервера и хранилища
+	if !*skipBench {
+		fmt.Println("\n== Benchmarks (in-process handler) ==")
+		for _, t := range bench.Targets(service, pairs) {
+			result := bench.Benchmark(t)
+			fmt.Println(result)
+			check(t.Name, func(th bench.Threshold) []string { return th.CheckBenchmark(result) })
+		}
+	}
+
+	// Нагрузка через gRPC: пропускная способность и задержки с учетом транспорта
+	fmt.Printf("\n== Load (gRPC %s, concurrency %d, %v per method) ==\n", target, *concurrency, *duration)
+	for _, t := range bench.Targets(client, pairs) {
+		if ctx.Err() != nil {
+			break
+		}
+		result := bench.Load(ctx, t, *concurrency, *duration)
+		fmt.Println(result)
+		check(t.Name, func(th bench.Threshold) []string { return th.CheckLoad(result) })
+	}
+
+	if len(violations) > 0 {
+		fmt.Printf("\n%d threshold violations:\n", len(violations))
+		for _, v := range violations {
+			fmt.Printf("  - %s\n", v)
+		}
+		os.Exit(1)
+	}
+	if thresholds != nil {
+		fmt.Println("\nAll thresholds passed")
+	}
+}
+
+// newStorage открывает хранилище для измерений
+func newStorage(backend, configPath string, log *logrus.Logger) (storages.Storage, error) {
+	switch backend {
+	case backendMemory:
+		return memory.New(log), nil
+	case backendPostgres:
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return nil, err
+		}
+		return postgres.New(&postgres.Config{
+			Host:            cfg.Database.Host,
+			Port:            cfg.Database.Port,
+			User:            cfg.Database.User,
+			Password:        cfg.Database.Password,
+			DBName:          cfg.Database.DBName,
+			SSLMode:         cfg.Database.SSLMode,
+			MaxOpenConns:    cfg.Database.MaxOpenConns,
+			MaxIdleConns:    cfg.Database.MaxIdleConns,
+			ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		}, log)
+	default:
+		return nil, fmt.Errorf("unknown backend: %q", backend)
+	}
+}
+
+// ratePairs возвращает пары валют с курсами для запросов конвертации
+func ratePairs(ctx context.Context, service bench.ExchangeService) ([]storages.CurrencyPair, error) {
+	rates, err := service.GetExchangeRates(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	if len(rates.Rates) == 0 {
+		return nil, fmt.Errorf("exchanger returned no rates")
+	}
+
+	// Пары сортируются, чтобы прогоны запрашивали курсы в одном порядке
+	pairs := make([]storages.CurrencyPair, 0, len(rates.Rates))
+	for key := range rates.Rates {
+		from, to, ok := strings.Cut(key, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate key %q", key)
+		}
+		pairs = append(pairs, storages.CurrencyPair{FromCurrency: from, ToCurrency: to})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].FromCurrency != pairs[j].FromCurrency {
+			return pairs[i].FromCurrency < pairs[j].FromCurrency
+		}
+		return pairs[i].ToCurrency < pairs[j].ToCurrency
+	})
+	return pairs, nil
+}
+
+// tokenInterceptor добавляет API токен в metadata вызова
+func tokenInterceptor(token string) grpcServer.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpcServer.ClientConn, invoker grpcServer.UnaryInvoker, opts ...grpcServer.CallOption) error {
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, grpc.APITokenHeader, token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,28 @@
+// exchctl - консольный клиент администрирования exchanger по gRPC.
+//
+//	exchctl -addr localhost:50051 -token "$TOKEN" rates
+//	exchctl set-rate USD EUR 0.92
+//	exchctl import -dry-run rates.csv
+//	exchctl -o json health
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gw-exchanger/internal/exchctl"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := exchctl.Run(ctx, exchctl.Env{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
+	}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
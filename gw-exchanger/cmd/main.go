@@ -4,26 +4,33 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"gw-common/buildinfo"
+	"gw-common/logger"
+	"gw-exchanger/internal/app"
 	"gw-exchanger/internal/config"
-	"gw-exchanger/internal/grpc"
-	"gw-exchanger/internal/logger"
+	"gw-exchanger/internal/events"
+	"gw-exchanger/internal/importer"
 	"gw-exchanger/internal/storages/postgres"
-	pb "gw-exchanger/proto"
-	"github.com/sirupsen/logrus"
-	grpcServer "google.golang.org/grpc"
 )
 
 func main() {
 	// Парсинг флагов командной строки
 	configPath := flag.String("c", "", "Path to config file")
+	showVersion := flag.Bool("version", false, "Print version and build info and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Printf("gw-exchanger %s\n", buildinfo.Get())
+		return
+	}
+
 	// Загрузка конфигурации
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -38,94 +45,163 @@ func main() {
 	}
 
 	// Инициализация логгера
-	log := logger.New(cfg.Logger.Level)
-	log.Info("Starting gw-exchanger service...")
+	log, err := logger.NewWithConfig(cfg.Logger)
+	if err != nil {
+		fmt.Printf("Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	log.Infof("Starting gw-exchanger service %s...", buildinfo.Get())
 	log.Infof("Configuration loaded from: %s", *configPath)
 
-	// Подключение к базе данных
-	dbConfig := &postgres.Config{
-		Host:            cfg.Database.Host,
-		Port:            cfg.Database.Port,
-		User:            cfg.Database.User,
-		Password:        cfg.Database.Password,
-		DBName:          cfg.Database.DBName,
-		SSLMode:         cfg.Database.SSLMode,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		MaxIdleConns:    cfg.Database.MaxIdleConns,
-		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	// Команды migrate и import: работа с БД без запуска сервиса
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		os.Exit(runMigrate(&cfg.Database, flag.Args()[1:], log))
+	case "import":
+		os.Exit(runImport(cfg, flag.Args()[1:], log))
+	default:
+		log.Fatalf("Unknown command %q (expected migrate or import)", flag.Arg(0))
+	}
+
+	// Сборка компонентов сервиса
+	application, err := app.New(cfg, app.WithLogger(log), app.WithConfigPath(*configPath))
+	if err != nil {
+		log.Fatalf("Failed to start service: %v", err)
+	}
+
+	// Graceful shutdown по SIGINT и SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := application.Run(ctx); err != nil {
+		log.Errorf("Server stopped with error: %v", err)
+		os.Exit(1)
 	}
+	log.Info("Server stopped gracefully")
+}
 
-	storage, err := postgres.New(dbConfig, log)
+// runMigrate выполняет команду migrate up|down [N]|status и возвращает код выхода процесса
+func runMigrate(cfg *config.DatabaseConfig, args []string, log *logrus.Logger) int {
+	if cfg.Driver != config.DBDriverPostgres {
+		log.Errorf("Migrations are supported only for STORAGE_DRIVER=%s, %s creates its schema on start", config.DBDriverPostgres, cfg.Driver)
+		return 2
+	}
+	if len(args) == 0 || len(args) > 2 {
+		log.Error("Usage: migrate up|down [N]|status")
+		return 2
+	}
+
+	storage, err := postgres.Open(&postgres.Config{
+		Host:            cfg.Host,
+		Port:            cfg.Port,
+		User:            cfg.User,
+		Password:        cfg.Password,
+		DBName:          cfg.DBName,
+		SSLMode:         cfg.SSLMode,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+	}, log)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Errorf("Failed to connect to database: %v", err)
+		return 1
 	}
 	defer storage.Close()
 
-	// Проверка подключения к БД
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	if err := storage.Ping(ctx); err != nil {
-		cancel()
-		log.Fatalf("Database ping failed: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "up":
+		err = storage.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				log.Errorf("Invalid number of migrations to roll back: %s", args[1])
+				return 2
+			}
+		}
+		err = storage.MigrateDown(ctx, steps)
+	case "status":
+		var status postgres.MigrationStatus
+		if status, err = storage.MigrationStatus(ctx); err == nil {
+			log.Infof("Schema version: %d, latest: %d, pending: %t, dirty: %t",
+				status.Version, status.Latest, status.Pending(), status.Dirty)
+		}
+	default:
+		log.Errorf("Unknown migrate command %q (expected up, down or status)", args[0])
+		return 2
 	}
-	cancel()
-	log.Info("Database connection established")
 
-	// Создание gRPC сервера
-	grpcSrv := grpcServer.NewServer(
-		grpcServer.UnaryInterceptor(loggingInterceptor(log)),
-	)
+	if err != nil {
+		log.Errorf("Migration failed: %v", err)
+		return 1
+	}
+	return 0
+}
 
-	exchangeServer := grpc.NewExchangeServer(storage, log)
-	pb.RegisterExchangeServiceServer(grpcSrv, exchangeServer)
+// runImport выполняет команду import <file.csv|file.json>: сохраняет курсы из файла
+// в одной транзакции и возвращает код выхода процесса
+func runImport(cfg *config.Config, args []string, log *logrus.Logger) int {
+	if len(args) != 1 {
+		log.Error("Usage: import <file.csv|file.json>")
+		return 2
+	}
 
-	// Создание listener для gRPC
-	listener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+	format, err := importer.FormatFromPath(args[0])
 	if err != nil {
-		log.Fatalf("Failed to create listener: %v", err)
+		log.Error(err)
+		return 2
 	}
 
-	// Graceful shutdown
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Errorf("Failed to open rates file: %v", err)
+		return 1
+	}
+	defer file.Close()
 
-	// Запуск gRPC сервера в горутине
-	go func() {
-		log.Infof("gRPC server is listening on port %s", cfg.Server.GRPCPort)
-		if err := grpcSrv.Serve(listener); err != nil {
-			log.Fatalf("Failed to serve gRPC: %v", err)
-		}
-	}()
+	rates, err := importer.Parse(file, format)
+	if err != nil {
+		log.Errorf("Failed to parse rates file: %v", err)
+		return 1
+	}
 
-	// Ожидание сигнала завершения
-	<-done
-	log.Info("Shutting down server...")
+	storage, err := app.NewStorage(&cfg.Database, log)
+	if err != nil {
+		log.Errorf("Failed to connect to database: %v", err)
+		return 1
+	}
+	defer storage.Close()
 
-	// Graceful shutdown
-	grpcSrv.GracefulStop()
-	log.Info("Server stopped gracefully")
-}
+	if len(cfg.Kafka.Brokers) > 0 {
+		producer := app.NewProducer(&cfg.Kafka, log)
+		defer producer.Close()
+		storage = events.New(storage, producer, log)
+	}
 
-// loggingInterceptor создает interceptor для логирования gRPC запросов
-func loggingInterceptor(log *logrus.Logger) grpcServer.UnaryServerInterceptor {
-	return func(
-		ctx context.Context,
-		req interface{},
-		info *grpcServer.UnaryServerInfo,
-		handler grpcServer.UnaryHandler,
-	) (interface{}, error) {
-		start := time.Now()
-
-		// Вызов обработчика
-		resp, err := handler(ctx, req)
-
-		// Логирование
-		duration := time.Since(start)
-		if err != nil {
-			log.Errorf("gRPC method: %s, duration: %v, error: %v", info.FullMethod, duration, err)
-		} else {
-			log.Infof("gRPC method: %s, duration: %v, status: success", info.FullMethod, duration)
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx = events.ContextWithSource(ctx, events.SourceImport, "")
+
+	currencies, err := storage.GetAllCurrencies(ctx, true)
+	if err != nil {
+		log.Errorf("Failed to get currencies: %v", err)
+		return 1
+	}
 
-		return resp, err
+	if rates, err = importer.Validate(rates, currencies); err != nil {
+		log.Errorf("Import failed: %v", err)
+		return 1
 	}
+
+	if err := storage.UpsertExchangeRates(ctx, rates); err != nil {
+		log.Errorf("Import failed: %v", err)
+		return 1
+	}
+
+	log.Infof("Imported %d rates from %s", len(rates), args[0])
+	return 0
 }
@@ -0,0 +1,174 @@
+// Package app собирает компоненты gw-exchanger по конфигурации и управляет их
+// жизненным циклом: New создает и связывает компоненты, Run запускает gRPC сервер,
+// сервер метрик и фоновые задачи и останавливает их. cmd/main.go и интеграционные
+// тесты запускают сервис одинаково
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"gw-common/logger"
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/kafka"
+	"gw-exchanger/internal/storages"
+	"gw-exchanger/internal/storages/cache"
+
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+// App собранный сервис курсов валют
+type App struct {
+	cfg        *config.Config
+	configPath string
+	logger     *logrus.Logger
+
+	storage      storages.Storage
+	producer     *kafka.Producer
+	ratesCache   *cache.Storage
+	grpcServer   *grpcServer.Server
+	healthServer *health.Server
+	metricServer *http.Server
+
+	// workers фоновые задачи, запускаемые Run
+	workers []func(ctx context.Context)
+}
+
+// Option настраивает App
+type Option func(*App)
+
+// WithLogger задает логгер; по умолчанию логгер создается по cfg.Logger
+func WithLogger(logger *logrus.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+// WithConfigPath задает файл конфигурации, который перечитывается по SIGHUP;
+// без него перечитывается только окружение
+func WithConfigPath(path string) Option {
+	return func(a *App) {
+		a.configPath = path
+	}
+}
+
+// WithStorage задает хранилище вместо подключения к БД из cfg.Database,
+// например memory.MemoryStorage в интеграционных тестах. App закрывает его при остановке
+func WithStorage(storage storages.Storage) Option {
+	return func(a *App) {
+		a.storage = storage
+	}
+}
+
+// New создает и связывает компоненты сервиса. Если БД недоступна, возвращается
+// ошибка; уже созданные соединения при этом закрываются
+func New(cfg *config.Config, opts ...Option) (*App, error) {
+	a := &App{cfg: cfg}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.logger == nil {
+		log, err := logger.NewWithConfig(cfg.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+		a.logger = log
+	}
+
+	if err := a.build(); err != nil {
+		a.close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Logger возвращает логгер сервиса
+func (a *App) Logger() *logrus.Logger {
+	return a.logger
+}
+
+// Storage возвращает хранилище с событиями изменения курсов и кешем, если они включены
+func (a *App) Storage() storages.Storage {
+	return a.storage
+}
+
+// Run слушает GRPC_PORT и обслуживает запросы до отмены ctx (см. Serve)
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", ":"+a.cfg.Server.GRPCPort)
+	if err != nil {
+		a.close()
+		return fmt.Errorf("failed to create listener: %w", err)
+	}
+	return a.Serve(ctx, listener)
+}
+
+// Serve запускает фоновые задачи, сервер метрик и gRPC сервер на listener и работает
+// до отмены ctx или ошибки gRPC сервера, после чего останавливает компоненты
+func (a *App) Serve(ctx context.Context, listener net.Listener) error {
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, run := range a.workers {
+		workers.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer workers.Done()
+			run(workersCtx)
+		}(run)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		a.logger.Infof("gRPC server is listening on %s (compression: %s)", listener.Addr(), a.cfg.Server.Compression)
+		if err := a.grpcServer.Serve(listener); err != nil {
+			serverErr <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+
+	if a.metricServer != nil {
+		go func() {
+			a.logger.Infof("Metrics server is listening on port %s", a.cfg.Server.MetricsPort)
+			if err := a.metricServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.logger.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-serverErr:
+		a.logger.Error(runErr)
+	}
+	a.logger.Info("Shutting down server...")
+
+	// Graceful shutdown: клиенты видят NOT_SERVING до закрытия соединений
+	stopWorkers()
+	a.healthServer.Shutdown()
+	a.grpcServer.GracefulStop()
+	if a.metricServer != nil {
+		a.metricServer.Close()
+	}
+	workers.Wait()
+	a.close()
+	return runErr
+}
+
+// close закрывает producer и хранилище
+func (a *App) close() {
+	if a.producer != nil {
+		a.producer.Close()
+	}
+	if a.storage != nil {
+		a.storage.Close()
+	}
+}
+
+// addWorker добавляет фоновую задачу, которая работает до отмены ctx
+func (a *App) addWorker(run func(ctx context.Context)) {
+	a.workers = append(a.workers, run)
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gw-common/logger"
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/events"
+	"gw-exchanger/internal/grpc"
+	"gw-exchanger/internal/kafka"
+	"gw-exchanger/internal/metrics"
+	"gw-exchanger/internal/pricing"
+	"gw-exchanger/internal/providers"
+	"gw-exchanger/internal/storages"
+	"gw-exchanger/internal/storages/cache"
+	"gw-exchanger/internal/storages/memory"
+	"gw-exchanger/internal/storages/mysql"
+	"gw-exchanger/internal/storages/postgres"
+	pb "gw-exchanger/proto"
+
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// build создает компоненты сервиса в порядке зависимостей
+func (a *App) build() error {
+	cfg, log := a.cfg, a.logger
+
+	// Подключение к базе данных (драйвер выбирается через STORAGE_DRIVER)
+	if a.storage == nil {
+		storage, err := NewStorage(&cfg.Database, log)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		a.storage = storage
+	}
+
+	// Проверка подключения к БД
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err := a.storage.Ping(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+	log.Info("Database connection established")
+
+	// События изменения курсов в Kafka: публикуются при записи курсов через storage
+	if len(cfg.Kafka.Brokers) > 0 {
+		a.producer = NewProducer(&cfg.Kafka, log)
+		a.storage = events.New(a.storage, a.producer, log)
+	}
+
+	// Кеш курсов в памяти: чтения курсов не обращаются к БД, записи через
+	// storage (плагины, административные методы) сбрасывают кеш
+	if cfg.Rates.CacheTTL > 0 {
+		a.ratesCache = cache.New(a.storage, cfg.Rates.CacheTTL, log)
+		a.storage = a.ratesCache
+		a.addWorker(a.ratesCache.Run)
+		log.Infof("Rates cache enabled (refresh every %s)", cfg.Rates.CacheTTL)
+	}
+
+	// Плагины источников курсов
+	var manager *providers.Manager
+	if len(cfg.Plugins.Plugins) > 0 {
+		providerList := make([]providers.Provider, 0, len(cfg.Plugins.Plugins))
+		for _, plugin := range cfg.Plugins.Plugins {
+			providerList = append(providerList, providers.NewExecProvider(providers.Plugin{
+				Name:    plugin.Name,
+				Path:    plugin.Path,
+				Timeout: plugin.Timeout,
+			}))
+			log.Infof("Rate provider plugin %s: %s (timeout: %v)", plugin.Name, plugin.Path, plugin.Timeout)
+		}
+		manager = providers.NewManager(providerList, a.storage, log)
+		a.addWorker(func(ctx context.Context) {
+			manager.Run(ctx, cfg.Plugins.Interval)
+		})
+	}
+
+	a.newGRPCServer()
+
+	// HTTP сервер метрик Prometheus и состояния плагинов
+	if cfg.Server.MetricsPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(a.ratesCache))
+		if manager != nil {
+			mux.Handle("/health/providers", providers.HealthHandler(manager))
+		}
+		a.metricServer = &http.Server{Addr: ":" + cfg.Server.MetricsPort, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	}
+
+	// Перечитывание конфигурации по SIGHUP без перезапуска
+	reloader := config.NewReloader(a.configPath, cfg, func(next *config.Config) error {
+		if err := logger.SetLevel(log, next.Logger.Level); err != nil {
+			return err
+		}
+		if a.ratesCache != nil {
+			a.ratesCache.SetTTL(next.Rates.CacheTTL)
+		}
+		return nil
+	}, log)
+	a.addWorker(reloader.Run)
+	return nil
+}
+
+// newGRPCServer создает gRPC сервер с сервисом курсов и health check
+func (a *App) newGRPCServer() {
+	cfg, log := a.cfg, a.logger
+
+	// Вызывающие стороны идентифицируются по API токену (API_TOKENS)
+	if len(cfg.Auth.Tokens) == 0 {
+		log.Warn("API_TOKENS is empty, caller authentication is disabled")
+	}
+	callerAuth := grpc.NewCallerAuth(cfg.Auth.Tokens, cfg.Auth.AdminCallers, log)
+
+	a.grpcServer = grpcServer.NewServer(
+		grpcServer.ChainUnaryInterceptor(
+			loggingInterceptor(log),
+			callerAuth.UnaryInterceptor(),
+			grpc.CompressionInterceptor(cfg.Server.Compression, log),
+		),
+		grpcServer.MaxRecvMsgSize(cfg.Server.MaxRecvMsgSize),
+		grpcServer.MaxSendMsgSize(cfg.Server.MaxSendMsgSize),
+		grpcServer.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.Server.KeepaliveMinTime,
+			PermitWithoutStream: cfg.Server.KeepalivePermitWithoutStream,
+		}),
+		grpcServer.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.Server.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.Server.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Server.MaxConnectionAgeGrace,
+			Time:                  cfg.Server.KeepaliveTime,
+			Timeout:               cfg.Server.KeepaliveTimeout,
+		}),
+		grpcServer.ConnectionTimeout(cfg.Server.ConnectionTimeout),
+		grpcServer.MaxConcurrentStreams(uint32(cfg.Server.MaxConcurrentStreams)),
+	)
+
+	exchangeServer := grpc.NewExchangeServer(a.storage, log)
+	if cfg.Rates.CrossRateBase != "" {
+		exchangeServer.EnableCrossRates(cfg.Rates.CrossRateBase)
+		log.Infof("Cross rates enabled via %s", cfg.Rates.CrossRateBase)
+	}
+	if cfg.Rates.Spread > 0 || len(cfg.Rates.PairSpreads) > 0 {
+		exchangeServer.EnableSpreads(&pricing.Spreads{Default: cfg.Rates.Spread, Pairs: cfg.Rates.PairSpreads})
+		log.Infof("Rate spreads enabled: default %.4f%%, %d pair overrides", cfg.Rates.Spread*100, len(cfg.Rates.PairSpreads))
+	}
+	if cfg.Rates.MaxRateAge > 0 {
+		exchangeServer.EnableStalenessGuard(cfg.Rates.MaxRateAge)
+		log.Infof("Rate staleness guard enabled: max rate age %s", cfg.Rates.MaxRateAge)
+	}
+	pb.RegisterExchangeServiceServer(a.grpcServer, exchangeServer)
+
+	// Стандартный health check: клиенты проверяют готовность без запроса курсов
+	a.healthServer = health.NewServer()
+	healthpb.RegisterHealthServer(a.grpcServer, a.healthServer)
+	a.addWorker(func(ctx context.Context) {
+		grpc.RunHealthUpdates(ctx, a.healthServer, a.storage.Ping, cfg.Server.HealthCheckInterval, log)
+	})
+
+	// Reflection позволяет grpcurl получать схему без .proto файлов.
+	// Сервис потоковый, поэтому проверка API токенов к нему не применяется
+	if cfg.Server.Reflection {
+		reflection.Register(a.grpcServer)
+		log.Info("gRPC reflection enabled")
+	}
+}
+
+// NewStorage создает хранилище для драйвера из конфигурации
+func NewStorage(cfg *config.DatabaseConfig, log *logrus.Logger) (storages.Storage, error) {
+	switch cfg.Driver {
+	case config.DBDriverMemory:
+		return memory.New(log), nil
+	case config.DBDriverMySQL:
+		return mysql.New(&mysql.Config{
+			Host:            cfg.Host,
+			Port:            cfg.Port,
+			User:            cfg.User,
+			Password:        cfg.Password,
+			DBName:          cfg.DBName,
+			SSLMode:         cfg.SSLMode,
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+		}, log)
+	default:
+		return postgres.New(&postgres.Config{
+			Host:            cfg.Host,
+			Port:            cfg.Port,
+			User:            cfg.User,
+			Password:        cfg.Password,
+			DBName:          cfg.DBName,
+			SSLMode:         cfg.SSLMode,
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			MigrateOnStart:  cfg.MigrateOnStart,
+		}, log)
+	}
+}
+
+// NewProducer создает producer событий изменения курсов
+func NewProducer(cfg *config.KafkaConfig, log *logrus.Logger) *kafka.Producer {
+	return kafka.NewProducer(&kafka.Config{
+		Brokers:      cfg.Brokers,
+		Topic:        cfg.RatesTopic,
+		RequiredAcks: cfg.RequiredAcks,
+	}, log)
+}
+
+// loggingInterceptor создает interceptor для логирования gRPC запросов
+func loggingInterceptor(log *logrus.Logger) grpcServer.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpcServer.UnaryServerInfo,
+		handler grpcServer.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+
+		// Вызов обработчика
+		resp, err := handler(ctx, req)
+
+		// Логирование
+		duration := time.Since(start)
+		if err != nil {
+			log.Errorf("gRPC method: %s, duration: %v, error: %v", info.FullMethod, duration, err)
+		} else {
+			log.Infof("gRPC method: %s, duration: %v, status: success", info.FullMethod, duration)
+		}
+
+		return resp, err
+	}
+}
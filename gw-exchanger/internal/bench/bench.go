@@ -0,0 +1,219 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gw-exchanger/internal/storages"
+	pb "gw-exchanger/proto"
+)
+
+// Имена нагружаемых методов, они же ключи порогов
+const (
+	MethodGetExchangeRates           = "GetExchangeRates"
+	MethodGetExchangeRateForCurrency = "GetExchangeRateForCurrency"
+)
+
+// ExchangeService методы, которые измеряются. Его реализуют и сервер
+// (вызов без сети), и адаптер gRPC клиента
+type ExchangeService interface {
+	GetExchangeRates(ctx context.Context, req *pb.Empty) (*pb.ExchangeRatesResponse, error)
+	GetExchangeRateForCurrency(ctx context.Context, req *pb.CurrencyRequest) (*pb.ExchangeRateResponse, error)
+}
+
+// Target нагружаемый метод. Call получает номер вызова, чтобы перебирать пары валют
+type Target struct {
+	Name string
+	Call func(ctx context.Context, i int) error
+}
+
+// Targets возвращает измеряемые методы сервиса. Конвертация перебирает pairs по кругу
+func Targets(service ExchangeService, pairs []storages.CurrencyPair) []Target {
+	return []Target{
+		{
+			Name: MethodGetExchangeRates,
+			Call: func(ctx context.Context, _ int) error {
+				_, err := service.GetExchangeRates(ctx, &pb.Empty{})
+				return err
+			},
+		},
+		{
+			Name: MethodGetExchangeRateForCurrency,
+			Call: func(ctx context.Context, i int) error {
+				pair := pairs[i%len(pairs)]
+				_, err := service.GetExchangeRateForCurrency(ctx, &pb.CurrencyRequest{
+					FromCurrency: pair.FromCurrency,
+					ToCurrency:   pair.ToCurrency,
+				})
+				return err
+			},
+		},
+	}
+}
+
+// BenchmarkResult результат Go бенчмарка одного метода
+type BenchmarkResult struct {
+	Name        string
+	Iterations  int
+	NsPerOp     int64
+	AllocsPerOp int64
+	BytesPerOp  int64
+	Err         error // первая ошибка вызова; результат с ошибкой недостоверен
+}
+
+// Benchmark измеряет метод через testing.Benchmark: число итераций подбирается
+// автоматически, как в go test -bench
+func Benchmark(target Target) BenchmarkResult {
+	var (
+		once     sync.Once
+		firstErr error
+	)
+
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if err := target.Call(ctx, i); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}
+	})
+
+	return BenchmarkResult{
+		Name:        target.Name,
+		Iterations:  result.N,
+		NsPerOp:     result.NsPerOp(),
+		AllocsPerOp: result.AllocsPerOp(),
+		BytesPerOp:  result.AllocedBytesPerOp(),
+		Err:         firstErr,
+	}
+}
+
+// LoadResult результат нагрузочного прогона одного метода
+type LoadResult struct {
+	Name        string
+	Concurrency int
+	Duration    time.Duration
+	Requests    int
+	Errors      int
+	RPS         float64
+	P50         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+	Max         time.Duration
+	LastError   string
+}
+
+// ErrorRate доля неуспешных вызовов
+func (r *LoadResult) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Load вызывает метод из concurrency горутин в течение duration и считает
+// пропускную способность и перцентили задержки
+func Load(ctx context.Context, target Target, concurrency int, duration time.Duration) LoadResult {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	type workerStats struct {
+		latencies []time.Duration
+		errors    int
+		lastError error
+	}
+	stats := make([]workerStats, concurrency)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			s := &stats[w]
+			for i := w; ctx.Err() == nil; i += concurrency {
+				callStart := time.Now()
+				err := target.Call(ctx, i)
+				// Вызов, прерванный окончанием прогона, не учитывается
+				if ctx.Err() != nil {
+					return
+				}
+				s.latencies = append(s.latencies, time.Since(callStart))
+				if err != nil {
+					s.errors++
+					s.lastError = err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := LoadResult{Name: target.Name, Concurrency: concurrency, Duration: elapsed}
+	var latencies []time.Duration
+	for _, s := range stats {
+		latencies = append(latencies, s.latencies...)
+		result.Errors += s.errors
+		if s.lastError != nil {
+			result.LastError = s.lastError.Error()
+		}
+	}
+
+	result.Requests = len(latencies)
+	if result.Requests == 0 {
+		return result
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.RPS = float64(result.Requests) / elapsed.Seconds()
+	result.P50 = percentile(latencies, 50)
+	result.P95 = percentile(latencies, 95)
+	result.P99 = percentile(latencies, 99)
+	result.Max = latencies[len(latencies)-1]
+
+	return result
+}
+
+// percentile возвращает перцентиль p отсортированных задержек
+func percentile(sorted []time.Duration, p int) time.Duration {
+	index := (len(sorted)*p+99)/100 - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+// String форматирует результат бенчмарка в стиле go test -bench
+func (r BenchmarkResult) String() string {
+	return fmt.Sprintf("%-28s %10d %12d ns/op %8d B/op %6d allocs/op",
+		r.Name, r.Iterations, r.NsPerOp, r.BytesPerOp, r.AllocsPerOp)
+}
+
+// String форматирует результат нагрузочного прогона
+func (r LoadResult) String() string {
+	return fmt.Sprintf("%-28s %8d req %10.0f req/s  p50=%-10v p95=%-10v p99=%-10v max=%-10v errors=%d",
+		r.Name, r.Requests, r.RPS, r.P50, r.P95, r.P99, r.Max, r.Errors)
+}
+
+// clientService адаптирует gRPC клиент к ExchangeService
+type clientService struct {
+	client pb.ExchangeServiceClient
+}
+
+// ClientService возвращает ExchangeService, вызывающий методы через gRPC клиент
+func ClientService(client pb.ExchangeServiceClient) ExchangeService {
+	return &clientService{client: client}
+}
+
+func (c *clientService) GetExchangeRates(ctx context.Context, req *pb.Empty) (*pb.ExchangeRatesResponse, error) {
+	return c.client.GetExchangeRates(ctx, req)
+}
+
+func (c *clientService) GetExchangeRateForCurrency(ctx context.Context, req *pb.CurrencyRequest) (*pb.ExchangeRateResponse, error) {
+	return c.client.GetExchangeRateForCurrency(ctx, req)
+}
@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Threshold пороги регрессии одного метода. Нулевое значение - порог не проверяется
+type Threshold struct {
+	MaxNsPerOp     int64   `json:"max_ns_per_op"`
+	MaxAllocsPerOp int64   `json:"max_allocs_per_op"`
+	MinRPS         float64 `json:"min_rps"`
+	MaxP99Ms       float64 `json:"max_p99_ms"`
+	MaxErrorRate   float64 `json:"max_error_rate"`
+}
+
+// Thresholds пороги по хранилищу и методу: backend -> метод -> порог
+type Thresholds map[string]map[string]Threshold
+
+// LoadThresholds читает пороги из JSON файла
+func LoadThresholds(path string) (Thresholds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thresholds: %w", err)
+	}
+
+	var thresholds Thresholds
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		return nil, fmt.Errorf("failed to parse thresholds: %w", err)
+	}
+	return thresholds, nil
+}
+
+// For возвращает порог метода для хранилища. ok = false, если порог не задан
+func (t Thresholds) For(backend, method string) (Threshold, bool) {
+	threshold, ok := t[backend][method]
+	return threshold, ok
+}
+
+// CheckBenchmark возвращает описания нарушенных порогов бенчмарка
+func (t Threshold) CheckBenchmark(r BenchmarkResult) []string {
+	var violations []string
+	if r.Err != nil {
+		violations = append(violations, fmt.Sprintf("%s: benchmark call failed: %v", r.Name, r.Err))
+	}
+	if t.MaxNsPerOp > 0 && r.NsPerOp > t.MaxNsPerOp {
+		violations = append(violations, fmt.Sprintf("%s: %d ns/op exceeds %d", r.Name, r.NsPerOp, t.MaxNsPerOp))
+	}
+	if t.MaxAllocsPerOp > 0 && r.AllocsPerOp > t.MaxAllocsPerOp {
+		violations = append(violations, fmt.Sprintf("%s: %d allocs/op exceeds %d", r.Name, r.AllocsPerOp, t.MaxAllocsPerOp))
+	}
+	return violations
+}
+
+// CheckLoad возвращает описания нарушенных порогов нагрузочного прогона.
+// Доля ошибок проверяется всегда: по умолчанию ошибки недопустимы
+func (t Threshold) CheckLoad(r LoadResult) []string {
+	var violations []string
+	if r.Requests == 0 {
+		return []string{fmt.Sprintf("%s: no requests completed", r.Name)}
+	}
+	if rate := r.ErrorRate(); rate > t.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("%s: error rate %.4f exceeds %.4f (last error: %s)", r.Name, rate, t.MaxErrorRate, r.LastError))
+	}
+	if t.MinRPS > 0 && r.RPS < t.MinRPS {
+		violations = append(violations, fmt.Sprintf("%s: %.0f req/s is below %.0f", r.Name, r.RPS, t.MinRPS))
+	}
+	if maxP99 := time.Duration(t.MaxP99Ms * float64(time.Millisecond)); maxP99 > 0 && r.P99 > maxP99 {
+		violations = append(violations, fmt.Sprintf("%s: p99 %v exceeds %v", r.Name, r.P99, maxP99))
+	}
+	return violations
+}
@@ -2,28 +2,67 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
+	"gw-common/configfile"
+	"gw-common/env"
+	"gw-common/logger"
+	"gw-common/utils"
+	"gw-exchanger/internal/pricing"
 )
 
 // Config содержит всю конфигурацию приложения
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
-	Logger   LoggerConfig
+	Logger   logger.Config
+	Auth     AuthConfig
+	Plugins  PluginsConfig
+	Rates    RatesConfig
+	Kafka    KafkaConfig
 }
 
 // ServerConfig содержит конфигурацию сервера
 type ServerConfig struct {
 	GRPCPort string
+	// Compression сжатие ответов: gzip или none
+	Compression string
+	// MaxRecvMsgSize и MaxSendMsgSize ограничения размера сообщений в байтах
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// KeepaliveMinTime минимальный интервал keepalive ping клиентов;
+	// клиенты, пингующие чаще, отключаются
+	KeepaliveMinTime time.Duration
+	// KeepalivePermitWithoutStream разрешает ping клиентов без активных вызовов
+	KeepalivePermitWithoutStream bool
+	// KeepaliveTime и KeepaliveTimeout: сервер пингует соединение после KeepaliveTime
+	// простоя и закрывает его, если ответ не пришел за KeepaliveTimeout
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// MaxConnectionIdle закрывает соединение без вызовов дольше этого времени (0 - без ограничения)
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge и MaxConnectionAgeGrace ограничивают время жизни соединения,
+	// чтобы клиенты переподключались и нагрузка распределялась по новым экземплярам
+	// (0 - без ограничения)
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+	// ConnectionTimeout тайм-аут установки соединения (TLS и HTTP/2 handshake)
+	ConnectionTimeout time.Duration
+	// MaxConcurrentStreams ограничение одновременных вызовов на соединение (0 - без ограничения)
+	MaxConcurrentStreams int
+	// Reflection регистрирует сервис grpc.reflection для grpcurl и подобных клиентов
+	Reflection bool
+	// HealthCheckInterval период проверки БД для grpc.health.v1
+	HealthCheckInterval time.Duration
+	// MetricsPort порт HTTP сервера метрик Prometheus (/metrics), пусто - без метрик
+	MetricsPort string
 }
 
 // DatabaseConfig содержит конфигурацию базы данных
 type DatabaseConfig struct {
+	Driver          string // postgres, mysql, memory
 	Host            string
 	Port            int
 	User            string
@@ -33,18 +72,64 @@ type DatabaseConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// MigrateOnStart применяет миграции PostgreSQL при запуске (см. команду migrate)
+	MigrateOnStart bool
 }
 
-// LoggerConfig содержит конфигурацию логгера
-type LoggerConfig struct {
-	Level string
+// AuthConfig содержит конфигурацию идентификации вызывающих сторон
+type AuthConfig struct {
+	// Tokens API токены вызывающих сторон: токен -> имя. Пустой набор отключает проверку
+	Tokens map[string]string
+	// AdminCallers вызывающие стороны, которым доступны административные методы
+	AdminCallers []string
 }
 
-// Load загружает конфигурацию из файла окружения
+// RatesConfig содержит настройки вычисления курсов
+type RatesConfig struct {
+	// CrossRateBase валюта, через которую вычисляется курс пары без прямого курса.
+	// Пустое значение (CROSS_RATE_BASE=none) отключает кросс-курсы
+	CrossRateBase string
+	// Spread спред между курсами покупки и продажи в долях среднего курса
+	Spread float64
+	// PairSpreads спреды отдельных пар по ключу FROM_TO
+	PairSpreads map[string]float64
+	// MaxRateAge максимальный возраст курса (по updated_at), 0 - без проверки
+	MaxRateAge time.Duration
+	// CacheTTL период обновления кеша курсов в памяти, 0 - курсы читаются из БД
+	CacheTTL time.Duration
+}
+
+// KafkaConfig содержит конфигурацию событий изменения курсов
+type KafkaConfig struct {
+	// Brokers адреса брокеров; пустой список отключает события
+	Brokers []string
+	// RatesTopic топик событий изменения курсов
+	RatesTopic string
+	// RequiredAcks подтверждения брокеров: all, one, none
+	RequiredAcks string
+}
+
+// PluginsConfig содержит конфигурацию внешних плагинов источников курсов
+type PluginsConfig struct {
+	// Plugins плагины в порядке опроса
+	Plugins []PluginConfig
+	// Interval период обновления курсов
+	Interval time.Duration
+}
+
+// PluginConfig описание одного плагина
+type PluginConfig struct {
+	Name    string
+	Path    string
+	Timeout time.Duration
+}
+
+// Load загружает конфигурацию из переменных окружения и файла конфигурации
+// (.env, YAML или JSON, см. gw-common/configfile). Переменные окружения важнее файла
 func Load(configPath string) (*Config, error) {
 	// Загрузка переменных окружения из файла
 	if configPath != "" {
-		if err := godotenv.Load(configPath); err != nil {
+		if err := configfile.Load(configPath); err != nil {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 	}
@@ -52,51 +137,202 @@ func Load(configPath string) (*Config, error) {
 	cfg := &Config{}
 
 	// Загрузка конфигурации сервера
-	cfg.Server.GRPCPort = getEnv("GRPC_PORT", DefaultGRPCPort)
-
-	// Загрузка конфигурации базы данных
-	cfg.Database.Host = getEnv("DB_HOST", DefaultDBHost)
-	cfg.Database.Port = getEnvInt("DB_PORT", DefaultDBPort)
-	cfg.Database.User = getEnv("DB_USER", DefaultDBUser)
-	cfg.Database.Password = getEnv("DB_PASSWORD", DefaultDBPassword)
-	cfg.Database.DBName = getEnv("DB_NAME", DefaultDBName)
-	cfg.Database.SSLMode = getEnv("DB_SSLMODE", DefaultDBSSLMode)
-	cfg.Database.MaxOpenConns = getEnvInt("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns)
-	cfg.Database.MaxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns)
-	cfg.Database.ConnMaxLifetime = getEnvDuration("DB_CONN_MAX_LIFETIME", DefaultDBConnMaxLifetime)
+	cfg.Server.GRPCPort = env.String("GRPC_PORT", DefaultGRPCPort)
+	cfg.Server.Compression = env.String("GRPC_COMPRESSION", DefaultGRPCCompression)
+	cfg.Server.MaxRecvMsgSize = env.Int("GRPC_MAX_RECV_MSG_SIZE", DefaultGRPCMaxRecvMsgSize)
+	cfg.Server.MaxSendMsgSize = env.Int("GRPC_MAX_SEND_MSG_SIZE", DefaultGRPCMaxSendMsgSize)
+	cfg.Server.KeepaliveMinTime = env.Duration("GRPC_KEEPALIVE_MIN_TIME", DefaultGRPCKeepaliveMinTime)
+	cfg.Server.KeepalivePermitWithoutStream = env.Bool("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", DefaultGRPCKeepalivePermitWithoutStream)
+	cfg.Server.KeepaliveTime = env.Duration("GRPC_KEEPALIVE_TIME", DefaultGRPCKeepaliveTime)
+	cfg.Server.KeepaliveTimeout = env.Duration("GRPC_KEEPALIVE_TIMEOUT", DefaultGRPCKeepaliveTimeout)
+	cfg.Server.MaxConnectionIdle = env.Duration("GRPC_MAX_CONNECTION_IDLE", 0)
+	cfg.Server.MaxConnectionAge = env.Duration("GRPC_MAX_CONNECTION_AGE", 0)
+	cfg.Server.MaxConnectionAgeGrace = env.Duration("GRPC_MAX_CONNECTION_AGE_GRACE", 0)
+	cfg.Server.ConnectionTimeout = env.Duration("GRPC_CONNECTION_TIMEOUT", DefaultGRPCConnectionTimeout)
+	cfg.Server.MaxConcurrentStreams = env.Int("GRPC_MAX_CONCURRENT_STREAMS", 0)
+	cfg.Server.Reflection = env.Bool("GRPC_REFLECTION", DefaultGRPCReflection)
+	cfg.Server.HealthCheckInterval = env.Duration("GRPC_HEALTH_CHECK_INTERVAL", DefaultGRPCHealthCheckInterval)
+	cfg.Server.MetricsPort = env.String("METRICS_PORT", "")
+
+	// Загрузка конфигурации базы данных. STORAGE_DRIVER - общее для сервисов имя,
+	// DB_DRIVER сохранен для совместимости
+	cfg.Database.Driver = env.String("STORAGE_DRIVER", env.String("DB_DRIVER", DefaultDBDriver))
+	cfg.Database.Host = env.String("DB_HOST", DefaultDBHost)
+	defaultPort := DefaultDBPort
+	if cfg.Database.Driver == DBDriverMySQL {
+		defaultPort = DefaultDBMySQLPort
+	}
+	cfg.Database.Port = env.Int("DB_PORT", defaultPort)
+	cfg.Database.User = env.String("DB_USER", DefaultDBUser)
+	cfg.Database.Password = env.String("DB_PASSWORD", DefaultDBPassword)
+	cfg.Database.DBName = env.String("DB_NAME", DefaultDBName)
+	cfg.Database.SSLMode = env.String("DB_SSLMODE", DefaultDBSSLMode)
+	cfg.Database.MaxOpenConns = env.Int("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns)
+	cfg.Database.MaxIdleConns = env.Int("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns)
+	cfg.Database.ConnMaxLifetime = env.Duration("DB_CONN_MAX_LIFETIME", DefaultDBConnMaxLifetime)
+	cfg.Database.MigrateOnStart = env.Bool("DB_MIGRATE_ON_START", DefaultDBMigrateOnStart)
 
 	// Загрузка конфигурации логгера
-	cfg.Logger.Level = getEnv("LOG_LEVEL", DefaultLogLevel)
+	cfg.Logger = logger.FromEnv()
+
+	// Загрузка API токенов вызывающих сторон
+	tokens, err := parseTokens(env.String("API_TOKENS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Auth.Tokens = tokens
+	cfg.Auth.AdminCallers = env.List("ADMIN_CALLERS", DefaultAdminCallers)
+
+	// Загрузка плагинов источников курсов
+	plugins, err := parsePlugins(
+		env.String("RATE_PLUGINS", ""),
+		env.String("RATE_PLUGIN_TIMEOUTS", ""),
+		env.Duration("RATE_PLUGIN_TIMEOUT", DefaultRatePluginTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Plugins.Plugins = plugins
+	cfg.Plugins.Interval = env.Duration("RATE_PLUGIN_INTERVAL", DefaultRatePluginInterval)
+
+	// Загрузка базовой валюты кросс-курсов; none отключает кросс-курсы
+	if base := env.String("CROSS_RATE_BASE", DefaultCrossRateBase); !strings.EqualFold(base, CrossRateNone) {
+		cfg.Rates.CrossRateBase = utils.NormalizeCurrency(base)
+		if err := utils.ValidateCurrencyCode(cfg.Rates.CrossRateBase); err != nil {
+			return nil, fmt.Errorf("invalid CROSS_RATE_BASE: %w", err)
+		}
+	}
+
+	// Загрузка спредов курсов покупки и продажи
+	cfg.Rates.Spread, err = parseSpread(env.String("RATE_SPREAD", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_SPREAD: %w", err)
+	}
+	cfg.Rates.PairSpreads, err = parsePairSpreads(env.String("RATE_SPREADS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Rates.MaxRateAge = env.Duration("MAX_RATE_AGE", 0)
+	cfg.Rates.CacheTTL = env.Duration("RATES_CACHE_TTL", DefaultRatesCacheTTL)
+
+	// Загрузка конфигурации Kafka; без брокеров события изменения курсов не отправляются
+	cfg.Kafka.Brokers = env.List("KAFKA_BROKERS", nil)
+	cfg.Kafka.RatesTopic = env.String("KAFKA_RATES_TOPIC", DefaultKafkaRatesTopic)
+	cfg.Kafka.RequiredAcks = env.String("KAFKA_REQUIRED_ACKS", DefaultKafkaRequiredAcks)
 
 	return cfg, nil
 }
 
-// getEnv получает переменную окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// parseTokens разбирает API токены вида "caller:token" через запятую
+func parseTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		caller, token, ok := strings.Cut(item, ":")
+		caller = strings.TrimSpace(caller)
+		token = strings.TrimSpace(token)
+		if !ok || caller == "" || token == "" {
+			return nil, fmt.Errorf("invalid API_TOKENS entry %q: expected caller:token", item)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("invalid API_TOKENS: duplicate token for caller %s", caller)
+		}
+		tokens[token] = caller
 	}
-	return defaultValue
+	return tokens, nil
 }
 
-// getEnvInt получает целочисленную переменную окружения или возвращает значение по умолчанию
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// parsePlugins разбирает плагины вида "name:path" через запятую и таймауты
+// отдельных плагинов вида "name:duration"; остальным назначается defaultTimeout
+func parsePlugins(value, timeouts string, defaultTimeout time.Duration) ([]PluginConfig, error) {
+	var plugins []PluginConfig
+	index := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, path, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		path = strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid RATE_PLUGINS entry %q: expected name:path", item)
+		}
+		if _, exists := index[name]; exists {
+			return nil, fmt.Errorf("invalid RATE_PLUGINS: duplicate plugin %s", name)
+		}
+
+		index[name] = len(plugins)
+		plugins = append(plugins, PluginConfig{Name: name, Path: path, Timeout: defaultTimeout})
+	}
+
+	for _, item := range strings.Split(timeouts, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid RATE_PLUGIN_TIMEOUTS entry %q: expected name:duration", item)
+		}
+		i, exists := index[name]
+		if !exists {
+			return nil, fmt.Errorf("invalid RATE_PLUGIN_TIMEOUTS: unknown plugin %s", name)
 		}
+		plugins[i].Timeout = timeout
 	}
-	return defaultValue
+
+	return plugins, nil
 }
 
-// getEnvDuration получает переменную окружения типа duration или возвращает значение по умолчанию
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+// parseSpread разбирает спред в процентах: "0.5" или "0.5%". Пустое значение - без спреда
+func parseSpread(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if value == "" {
+		return 0, nil
+	}
+
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid spread %q: expected percent", value)
+	}
+	spread := percent / 100
+	if spread < 0 || spread >= pricing.MaxSpread {
+		return 0, fmt.Errorf("spread %s%% must be at least 0%% and less than 100%%", value)
+	}
+	return spread, nil
+}
+
+// parsePairSpreads разбирает спреды пар вида "FROM_TO:percent" через запятую
+func parsePairSpreads(value string) (map[string]float64, error) {
+	spreads := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pair, percent, ok := strings.Cut(item, ":")
+		from, to, pairOK := strings.Cut(strings.TrimSpace(pair), "_")
+		from, to = utils.NormalizeCurrency(from), utils.NormalizeCurrency(to)
+		if !ok || !pairOK || utils.ValidateCurrencyCode(from) != nil || utils.ValidateCurrencyCode(to) != nil {
+			return nil, fmt.Errorf("invalid RATE_SPREADS entry %q: expected FROM_TO:percent", item)
 		}
+		spread, err := parseSpread(percent)
+		if err != nil || strings.TrimSpace(percent) == "" {
+			return nil, fmt.Errorf("invalid RATE_SPREADS entry %q: expected FROM_TO:percent", item)
+		}
+		spreads[pricing.PairKey(from, to)] = spread
 	}
-	return defaultValue
+	return spreads, nil
 }
 
 // Validate проверяет корректность конфигурации
@@ -105,21 +341,79 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("GRPC_PORT is required")
 	}
 
-	if c.Database.Host == "" {
-		return fmt.Errorf("DB_HOST is required")
+	if c.Server.Compression != "gzip" && c.Server.Compression != "none" {
+		return fmt.Errorf("invalid GRPC_COMPRESSION: %s (expected gzip or none)", c.Server.Compression)
+	}
+
+	if c.Server.MaxRecvMsgSize <= 0 || c.Server.MaxSendMsgSize <= 0 {
+		return fmt.Errorf("GRPC_MAX_RECV_MSG_SIZE and GRPC_MAX_SEND_MSG_SIZE must be positive")
+	}
+
+	if c.Server.KeepaliveMinTime <= 0 || c.Server.HealthCheckInterval <= 0 {
+		return fmt.Errorf("GRPC_KEEPALIVE_MIN_TIME and GRPC_HEALTH_CHECK_INTERVAL must be positive")
+	}
+
+	if c.Server.KeepaliveTime <= 0 || c.Server.KeepaliveTimeout <= 0 || c.Server.ConnectionTimeout <= 0 {
+		return fmt.Errorf("GRPC_KEEPALIVE_TIME, GRPC_KEEPALIVE_TIMEOUT and GRPC_CONNECTION_TIMEOUT must be positive")
+	}
+
+	if c.Server.MaxConnectionIdle < 0 || c.Server.MaxConnectionAge < 0 || c.Server.MaxConnectionAgeGrace < 0 {
+		return fmt.Errorf("GRPC_MAX_CONNECTION_IDLE, GRPC_MAX_CONNECTION_AGE and GRPC_MAX_CONNECTION_AGE_GRACE must not be negative")
+	}
+
+	if c.Server.MaxConcurrentStreams < 0 {
+		return fmt.Errorf("GRPC_MAX_CONCURRENT_STREAMS must not be negative")
+	}
+
+	if c.Rates.MaxRateAge < 0 {
+		return fmt.Errorf("MAX_RATE_AGE must not be negative")
+	}
+
+	if c.Rates.CacheTTL < 0 {
+		return fmt.Errorf("RATES_CACHE_TTL must not be negative")
+	}
+
+	// Курсы плагинов обновляются раз в RATE_PLUGIN_INTERVAL и не должны устаревать между обновлениями
+	if c.Rates.MaxRateAge > 0 && len(c.Plugins.Plugins) > 0 && c.Rates.MaxRateAge <= c.Plugins.Interval {
+		return fmt.Errorf("MAX_RATE_AGE must be greater than RATE_PLUGIN_INTERVAL")
 	}
 
-	if c.Database.User == "" {
-		return fmt.Errorf("DB_USER is required")
+	switch c.Kafka.RequiredAcks {
+	case "all", "one", "none":
+	default:
+		return fmt.Errorf("invalid KAFKA_REQUIRED_ACKS: %s (expected all, one or none)", c.Kafka.RequiredAcks)
+	}
+
+	switch c.Database.Driver {
+	case DBDriverPostgres, DBDriverMySQL:
+		if c.Database.Host == "" {
+			return fmt.Errorf("DB_HOST is required")
+		}
+		if c.Database.User == "" {
+			return fmt.Errorf("DB_USER is required")
+		}
+		if c.Database.DBName == "" {
+			return fmt.Errorf("DB_NAME is required")
+		}
+	case DBDriverMemory:
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER: %s (expected %s, %s or %s)",
+			c.Database.Driver, DBDriverPostgres, DBDriverMySQL, DBDriverMemory)
+	}
+
+	for _, plugin := range c.Plugins.Plugins {
+		if plugin.Timeout <= 0 {
+			return fmt.Errorf("RATE_PLUGIN_TIMEOUT must be positive")
+		}
 	}
 
-	if c.Database.DBName == "" {
-		return fmt.Errorf("DB_NAME is required")
+	if len(c.Plugins.Plugins) > 0 && c.Plugins.Interval <= 0 {
+		return fmt.Errorf("RATE_PLUGIN_INTERVAL must be positive")
 	}
 
 	// Проверка уровня логирования
-	if _, err := logrus.ParseLevel(c.Logger.Level); err != nil {
-		return fmt.Errorf("invalid log level: %s", c.Logger.Level)
+	if err := c.Logger.Validate(); err != nil {
+		return err
 	}
 
 	return nil
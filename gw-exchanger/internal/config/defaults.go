@@ -4,14 +4,39 @@ import "time"
 
 // Значения по умолчанию для конфигурации сервера
 const (
-	DefaultGRPCPort = "50051"
-	DefaultLogLevel = "info"
+	DefaultGRPCPort        = "50051"
+	DefaultGRPCCompression = "none"
+	// Справочник курсов и история могут быть крупнее запросов
+	DefaultGRPCMaxRecvMsgSize = 4 << 20
+	DefaultGRPCMaxSendMsgSize = 16 << 20
+	// Клиент кошелька по умолчанию пингует раз в 30s
+	DefaultGRPCKeepaliveMinTime             = 10 * time.Second
+	DefaultGRPCKeepalivePermitWithoutStream = true
+	// Значения по умолчанию grpc-go
+	DefaultGRPCKeepaliveTime       = 2 * time.Hour
+	DefaultGRPCKeepaliveTimeout    = 20 * time.Second
+	DefaultGRPCConnectionTimeout   = 120 * time.Second
+	DefaultGRPCReflection          = false
+	DefaultGRPCHealthCheckInterval = 10 * time.Second
+)
+
+// DefaultAdminCallers вызывающие стороны с доступом к административным методам
+var DefaultAdminCallers = []string{"wallet"}
+
+// Поддерживаемые драйверы базы данных
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverMySQL    = "mysql"
+	// DBDriverMemory хранилище в памяти процесса: данные теряются при перезапуске
+	DBDriverMemory = "memory"
 )
 
 // Значения по умолчанию для конфигурации базы данных
 const (
+	DefaultDBDriver          = DBDriverPostgres
 	DefaultDBHost            = "localhost"
 	DefaultDBPort            = 5432
+	DefaultDBMySQLPort       = 3306 // при STORAGE_DRIVER=mysql
 	DefaultDBUser            = "exchanger_user"
 	DefaultDBPassword        = "exchanger_password"
 	DefaultDBName            = "exchanger_db"
@@ -19,4 +44,27 @@ const (
 	DefaultDBMaxOpenConns    = 25
 	DefaultDBMaxIdleConns    = 5
 	DefaultDBConnMaxLifetime = 5 * time.Minute
+	DefaultDBMigrateOnStart  = true
+)
+
+// Базовая валюта кросс-курсов: по умолчанию и значение, отключающее кросс-курсы
+const (
+	DefaultCrossRateBase = "USD"
+	CrossRateNone        = "none"
+)
+
+// DefaultRatesCacheTTL период обновления кеша курсов: курсы, записанные другими
+// экземплярами сервиса или напрямую в БД, видны не позже чем через этот интервал
+const DefaultRatesCacheTTL = 5 * time.Second
+
+// Значения по умолчанию для событий изменения курсов в Kafka
+const (
+	DefaultKafkaRatesTopic   = "rates-updates"
+	DefaultKafkaRequiredAcks = "all"
+)
+
+// Значения по умолчанию для плагинов источников курсов
+const (
+	DefaultRatePluginTimeout  = 5 * time.Second
+	DefaultRatePluginInterval = time.Minute
 )
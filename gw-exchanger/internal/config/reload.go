@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReloadResult изменения конфигурации после перечитывания
+type ReloadResult struct {
+	// Applied изменившиеся настройки, примененные без перезапуска
+	Applied []string
+	// RestartRequired разделы конфигурации с изменениями, вступающими в силу после перезапуска
+	RestartRequired []string
+}
+
+// ApplyFunc применяет к компонентам сервиса настройки, изменяемые без перезапуска
+type ApplyFunc func(cfg *Config) error
+
+// Reloader перечитывает конфигурацию по SIGHUP. Без перезапуска применяются уровень
+// логирования (LOG_LEVEL) и интервал обновления кеша курсов (RATES_CACHE_TTL)
+type Reloader struct {
+	path   string
+	apply  ApplyFunc
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	current *Config
+}
+
+// NewReloader создает перечитывание конфигурации из файла path поверх текущей current
+func NewReloader(path string, current *Config, apply ApplyFunc, logger *logrus.Logger) *Reloader {
+	return &Reloader{
+		path:    path,
+		apply:   apply,
+		logger:  logger,
+		current: current,
+	}
+}
+
+// Reload перечитывает файл конфигурации и окружение и применяет изменившиеся настройки.
+// Ошибка в конфигурации не меняет действующие настройки
+func (r *Reloader) Reload() (ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.path)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+	if err := next.Validate(); err != nil {
+		return ReloadResult{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Остальные настройки действуют до перезапуска
+	cfg := *r.current
+	applyReloadable(&cfg, next)
+	if err := r.apply(&cfg); err != nil {
+		return ReloadResult{}, err
+	}
+
+	result := ReloadResult{
+		Applied:         reloadableChanges(r.current, &cfg),
+		RestartRequired: restartRequired(&cfg, next),
+	}
+	r.current = &cfg
+
+	r.logger.Infof("Config reloaded (applied: %v, restart required: %v)", result.Applied, result.RestartRequired)
+	return result, nil
+}
+
+// Run перечитывает конфигурацию по SIGHUP до отмены контекста
+func (r *Reloader) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.logger.Info("Received SIGHUP, reloading config...")
+			if _, err := r.Reload(); err != nil {
+				r.logger.Errorf("Failed to reload config: %v", err)
+			}
+		}
+	}
+}
+
+// applyReloadable переносит из src в dst настройки, изменяемые без перезапуска
+func applyReloadable(dst, src *Config) {
+	dst.Logger.Level = src.Logger.Level
+	// Кеш курсов включается и отключается (RATES_CACHE_TTL=0) только при запуске
+	if dst.Rates.CacheTTL > 0 && src.Rates.CacheTTL > 0 {
+		dst.Rates.CacheTTL = src.Rates.CacheTTL
+	}
+}
+
+// reloadableChanges возвращает переменные окружения настроек, которые различаются
+// в действующей конфигурации old и примененной next
+func reloadableChanges(old, next *Config) []string {
+	changed := []string{}
+	add := func(name string, differ bool) {
+		if differ {
+			changed = append(changed, name)
+		}
+	}
+	add("LOG_LEVEL", old.Logger.Level != next.Logger.Level)
+	add("RATES_CACHE_TTL", old.Rates.CacheTTL != next.Rates.CacheTTL)
+	return changed
+}
+
+// restartRequired возвращает разделы, в которых applied и next различаются
+// после переноса изменяемых без перезапуска настроек
+func restartRequired(applied, next *Config) []string {
+	sections := []string{}
+	a, n := reflect.ValueOf(applied).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), n.Field(i).Interface()) {
+			sections = append(sections, a.Type().Field(i).Name)
+		}
+	}
+	return sections
+}
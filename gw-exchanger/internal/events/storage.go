@@ -0,0 +1,171 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-exchanger/internal/kafka"
+	"gw-exchanger/internal/storages"
+)
+
+// Источники изменения курсов
+const (
+	SourcePlugin  = "plugin"
+	SourceAPI     = "api"
+	SourceImport  = "import"
+	SourceUnknown = "unknown"
+)
+
+// ratePrecision знаков после запятой в exchange_rates (NUMERIC(20, 8)):
+// курсы, равные с этой точностью, не считаются изменившимися
+const ratePrecision = 1e8
+
+// Publisher отправляет события изменения курсов
+type Publisher interface {
+	PublishRateChanges(ctx context.Context, messages []kafka.RateChangeMessage) error
+}
+
+// sourceKey ключ контекста с источником изменения курсов
+type sourceKey struct{}
+
+// ContextWithSource возвращает контекст с источником изменения курсов,
+// например ContextWithSource(ctx, SourcePlugin, "ecb") - plugin:ecb
+func ContextWithSource(ctx context.Context, source, name string) context.Context {
+	if name != "" {
+		source += ":" + name
+	}
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFromContext возвращает источник изменения курсов из контекста
+func SourceFromContext(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok {
+		return source
+	}
+	return SourceUnknown
+}
+
+// Storage публикует событие для каждого курса, значение которого изменилось
+// при записи через этот Storage. Прежние значения читаются перед записью;
+// записи курсов через один Storage выполняются последовательно, поэтому
+// old_rate одного события совпадает с new_rate предыдущего. Остальные методы
+// передаются хранилищу без изменений
+type Storage struct {
+	storages.Storage
+	publisher Publisher
+	logger    *logrus.Logger
+
+	writeMu sync.Mutex
+}
+
+// New создает хранилище, публикующее изменения курсов storage
+func New(storage storages.Storage, publisher Publisher, logger *logrus.Logger) *Storage {
+	return &Storage{
+		Storage:   storage,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// UpdateExchangeRate обновляет курс и публикует его изменение
+func (s *Storage) UpdateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	return s.write(ctx, []storages.ExchangeRate{*rate}, func() error {
+		return s.Storage.UpdateExchangeRate(ctx, rate)
+	})
+}
+
+// CreateExchangeRate создает курс и публикует его
+func (s *Storage) CreateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	return s.write(ctx, []storages.ExchangeRate{*rate}, func() error {
+		return s.Storage.CreateExchangeRate(ctx, rate)
+	})
+}
+
+// UpsertExchangeRates обновляет курсы и публикует изменившиеся
+func (s *Storage) UpsertExchangeRates(ctx context.Context, rates []storages.ExchangeRate) error {
+	return s.write(ctx, rates, func() error {
+		return s.Storage.UpsertExchangeRates(ctx, rates)
+	})
+}
+
+// write выполняет запись курсов rates и публикует события для изменившихся
+func (s *Storage) write(ctx context.Context, rates []storages.ExchangeRate, write func() error) error {
+	s.writeMu.Lock()
+	previous, err := s.currentRates(ctx, rates)
+	if err != nil {
+		s.writeMu.Unlock()
+		return fmt.Errorf("failed to get current rates: %w", err)
+	}
+	err = write()
+	s.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	source := SourceFromContext(ctx)
+	now := time.Now()
+	var messages []kafka.RateChangeMessage
+	for _, rate := range rates {
+		pair := storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}
+		old, exists := previous[pair]
+		if exists && sameRate(old, rate.Rate) {
+			continue
+		}
+		messages = append(messages, kafka.RateChangeMessage{
+			FromCurrency: rate.FromCurrency,
+			ToCurrency:   rate.ToCurrency,
+			OldRate:      old,
+			NewRate:      rate.Rate,
+			Source:       source,
+			Timestamp:    now,
+		})
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// Курсы уже сохранены: ошибка отправки не отменяет запись
+	if err := s.publisher.PublishRateChanges(ctx, messages); err != nil {
+		s.logger.Errorf("Failed to publish %d rate changes: %v", len(messages), err)
+		return nil
+	}
+	s.logger.Infof("Published %d rate changes (source: %s)", len(messages), source)
+	return nil
+}
+
+// currentRates возвращает текущие курсы пар из rates. Для одного курса читается
+// только его пара, для нескольких - все курсы одним запросом
+func (s *Storage) currentRates(ctx context.Context, rates []storages.ExchangeRate) (map[storages.CurrencyPair]float64, error) {
+	current := make(map[storages.CurrencyPair]float64, len(rates))
+
+	if len(rates) == 1 {
+		rate, err := s.Storage.GetExchangeRate(ctx, rates[0].FromCurrency, rates[0].ToCurrency)
+		if errors.Is(err, storages.ErrNotFound) {
+			return current, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		current[storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}] = rate.Rate
+		return current, nil
+	}
+
+	all, err := s.Storage.GetAllExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range all {
+		current[storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}] = rate.Rate
+	}
+	return current, nil
+}
+
+// sameRate сравнивает курсы с точностью хранения в БД
+func sameRate(a, b float64) bool {
+	return math.Round(a*ratePrecision) == math.Round(b*ratePrecision)
+}
@@ -0,0 +1,338 @@
+package exchctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gw-common/buildinfo"
+	"gw-common/utils"
+	"gw-exchanger/internal/importer"
+	"gw-exchanger/internal/storages"
+	pb "gw-exchanger/proto"
+)
+
+// command подкоманда exchctl
+type command struct {
+	name    string
+	args    string // аргументы для справки
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+// commands возвращает подкоманды в порядке справки
+func commands() []*command {
+	return []*command{
+		{name: "rates", args: "[FROM TO]", summary: "List rates or show the rate of a pair", run: runRates},
+		{name: "set-rate", args: "FROM TO RATE", summary: "Set the rate of a pair (admin)", run: runSetRate},
+		{name: "import", args: "[-format csv|json] [-dry-run] FILE", summary: "Import rates from a CSV or JSON file (admin)", run: runImport},
+		{name: "health", summary: "Check the gRPC health status of the service", run: runHealth},
+		{name: "version", summary: "Show the version and build info of the service", run: runVersion},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// errUsagePrinted ошибка флагов команды, уже выведенная пакетом flag вместе со справкой
+var errUsagePrinted = errors.New("invalid flags")
+
+// flags создает набор флагов команды. Ошибки флагов и справку по -h выводит пакет flag
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.env.Stderr, "Usage: exchctl [flags] %s %s\n", name, findCommand(name).args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse разбирает флаги и проверяет число позиционных аргументов
+func parse(fs *flag.FlagSet, args []string, positional ...int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsagePrinted
+	}
+	for _, n := range positional {
+		if fs.NArg() == n {
+			return nil
+		}
+	}
+	return usagef("unexpected number of arguments: %d", fs.NArg())
+}
+
+// rateRow курс пары в выводе команды rates
+type rateRow struct {
+	Pair         string  `json:"pair"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Rate         float32 `json:"rate"`
+	Bid          float32 `json:"bid,omitempty"`
+	Ask          float32 `json:"ask,omitempty"`
+	Stale        bool    `json:"stale,omitempty"`
+	Derived      bool    `json:"derived,omitempty"`
+	BaseCurrency string  `json:"base_currency,omitempty"`
+}
+
+func runRates(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("rates")
+	if err := parse(fs, args, 0, 2); err != nil {
+		return err
+	}
+
+	var rows []rateRow
+	if fs.NArg() == 2 {
+		resp, err := a.exchange.GetExchangeRateForCurrency(ctx, &pb.CurrencyRequest{
+			FromCurrency: utils.NormalizeCurrency(fs.Arg(0)),
+			ToCurrency:   utils.NormalizeCurrency(fs.Arg(1)),
+		})
+		if err != nil {
+			return err
+		}
+		rows = append(rows, rateRow{
+			Pair:         resp.FromCurrency + "_" + resp.ToCurrency,
+			FromCurrency: resp.FromCurrency,
+			ToCurrency:   resp.ToCurrency,
+			Rate:         resp.Rate,
+			Bid:          resp.Bid,
+			Ask:          resp.Ask,
+			Derived:      resp.Derived,
+			BaseCurrency: resp.BaseCurrency,
+		})
+	} else {
+		resp, err := a.exchange.GetExchangeRates(ctx, &pb.Empty{})
+		if err != nil {
+			return err
+		}
+		stale := make(map[string]bool, len(resp.Stale))
+		for _, pair := range resp.Stale {
+			stale[pair] = true
+		}
+		for pair, rate := range resp.Rates {
+			from, to, _ := strings.Cut(pair, "_")
+			rows = append(rows, rateRow{
+				Pair:         pair,
+				FromCurrency: from,
+				ToCurrency:   to,
+				Rate:         rate,
+				Bid:          resp.Bids[pair],
+				Ask:          resp.Asks[pair],
+				Stale:        stale[pair],
+			})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Pair < rows[j].Pair })
+	}
+
+	if a.output == OutputJSON {
+		return a.printJSON(rows)
+	}
+	w := a.table("PAIR", "RATE", "BID", "ASK", "NOTE")
+	for _, row := range rows {
+		var note string
+		switch {
+		case row.Stale:
+			note = "stale"
+		case row.Derived:
+			note = "cross via " + row.BaseCurrency
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.Pair, formatRate(row.Rate), formatRate(row.Bid), formatRate(row.Ask), note)
+	}
+	return w.Flush()
+}
+
+func runSetRate(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("set-rate")
+	if err := parse(fs, args, 3); err != nil {
+		return err
+	}
+	rate, err := strconv.ParseFloat(fs.Arg(2), 64)
+	if err != nil {
+		return usagef("invalid rate %q", fs.Arg(2))
+	}
+
+	update := &pb.RateUpdate{
+		FromCurrency: utils.NormalizeCurrency(fs.Arg(0)),
+		ToCurrency:   utils.NormalizeCurrency(fs.Arg(1)),
+		Rate:         rate,
+	}
+	resp, err := a.exchange.BulkSetRates(ctx, &pb.BulkSetRatesRequest{Rates: []*pb.RateUpdate{update}})
+	if err != nil {
+		return err
+	}
+
+	if a.output == OutputJSON {
+		return a.printJSON(map[string]int32{"upserted": resp.Upserted})
+	}
+	fmt.Fprintf(a.env.Stdout, "Set %s -> %s rate to %s\n", update.FromCurrency, update.ToCurrency, strconv.FormatFloat(rate, 'g', -1, 64))
+	return nil
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("import")
+	format := fs.String("format", "", "File format: csv or json (default by the file extension, csv for stdin)")
+	dryRun := fs.Bool("dry-run", false, "Validate the rates against the currencies of the service without saving them")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = importer.FormatCSV
+		if path != "-" {
+			var err error
+			if *format, err = importer.FormatFromPath(path); err != nil {
+				return usagef("%v", err)
+			}
+		}
+	}
+
+	var r io.Reader = a.env.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open rates file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	rates, err := importer.Parse(r, *format)
+	if err != nil {
+		return fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	// Курсы проверяются сервисом в одной транзакции с сохранением; пробный запуск
+	// выполняет ту же проверку по справочнику валют сервиса
+	if *dryRun {
+		resp, err := a.exchange.GetCurrencies(ctx, &pb.CurrenciesRequest{IncludeInactive: true})
+		if err != nil {
+			return err
+		}
+		currencies := make([]storages.Currency, 0, len(resp.Currencies))
+		for _, c := range resp.Currencies {
+			currencies = append(currencies, storages.Currency{Code: c.Code, Name: c.Name, IsActive: c.IsActive})
+		}
+		if rates, err = importer.Validate(rates, currencies); err != nil {
+			return err
+		}
+	}
+
+	var upserted int32
+	if !*dryRun {
+		updates := make([]*pb.RateUpdate, 0, len(rates))
+		for _, rate := range rates {
+			updates = append(updates, &pb.RateUpdate{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency, Rate: rate.Rate})
+		}
+		resp, err := a.exchange.BulkSetRates(ctx, &pb.BulkSetRatesRequest{Rates: updates})
+		if err != nil {
+			return err
+		}
+		upserted = resp.Upserted
+	}
+
+	if a.output == OutputJSON {
+		return a.printJSON(struct {
+			DryRun   bool  `json:"dry_run"`
+			Rates    int   `json:"rates"`
+			Upserted int32 `json:"upserted"`
+		}{*dryRun, len(rates), upserted})
+	}
+	if *dryRun {
+		fmt.Fprintf(a.env.Stdout, "%d rates are valid, nothing was saved (dry run)\n", len(rates))
+		return nil
+	}
+	fmt.Fprintf(a.env.Stdout, "Imported %d rates from %s\n", upserted, path)
+	return nil
+}
+
+// healthRow статус сервиса в выводе команды health
+type healthRow struct {
+	Service string `json:"service"`
+	Status  string `json:"status"`
+}
+
+func runHealth(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("health")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	// Пустое имя - общий статус сервера, второе - статус сервиса курсов
+	var rows []healthRow
+	serving := true
+	for _, service := range []string{"", pb.ExchangeService_ServiceDesc.ServiceName} {
+		resp, err := a.health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			serving = false
+		}
+		if service == "" {
+			service = "(server)"
+		}
+		rows = append(rows, healthRow{Service: service, Status: resp.Status.String()})
+	}
+
+	if a.output == OutputJSON {
+		if err := a.printJSON(rows); err != nil {
+			return err
+		}
+	} else {
+		w := a.table("SERVICE", "STATUS")
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s\t%s\n", row.Service, row.Status)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if !serving {
+		return errNotServing
+	}
+	return nil
+}
+
+func runVersion(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("version")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	resp, err := a.exchange.GetVersion(ctx, &pb.Empty{})
+	if err != nil {
+		return err
+	}
+
+	info := buildinfo.Info{Version: resp.Version, Commit: resp.Commit, BuildTime: resp.BuildTime, GoVersion: resp.GoVersion}
+	if a.output == OutputJSON {
+		return a.printJSON(info)
+	}
+	w := a.table("VERSION", "COMMIT", "BUILD TIME", "GO")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Version, info.Commit, info.BuildTime, info.GoVersion)
+	return w.Flush()
+}
+
+// formatRate форматирует курс float32 по его десятичной записи; 0 - курса нет
+func formatRate(rate float32) string {
+	if rate == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(rate), 'g', -1, 32)
+}
@@ -0,0 +1,194 @@
+// Package exchctl - консольный клиент администрирования exchanger по gRPC: курсы,
+// установка курса, импорт курсов из файла и проверка состояния сервиса
+package exchctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gw-common/errcodes"
+	"gw-exchanger/internal/grpc"
+	pb "gw-exchanger/proto"
+)
+
+// Коды завершения
+const (
+	ExitOK    = 0
+	ExitError = 1 // команда не выполнена: ошибка сервиса, сети или сервис не готов
+	ExitUsage = 2 // неверные флаги или аргументы
+)
+
+// Переменные окружения, задающие флаги по умолчанию
+const (
+	EnvAddr  = "EXCHCTL_ADDR"
+	EnvToken = "EXCHCTL_TOKEN"
+)
+
+// Значения флагов по умолчанию
+const (
+	DefaultAddr    = "localhost:50051"
+	DefaultTimeout = 30 * time.Second
+)
+
+// Форматы вывода
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Env окружение запуска: потоки ввода-вывода и переменные окружения
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+}
+
+// app состояние одного запуска exchctl
+type app struct {
+	env    Env
+	output string
+
+	exchange pb.ExchangeServiceClient
+	health   healthpb.HealthClient
+}
+
+// usageError ошибка аргументов команды: выводится со строкой справки, код ExitUsage
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// errNotServing сервис ответил на проверку состояния, но не готов; статус уже выведен
+var errNotServing = errors.New("service is not serving")
+
+// Run выполняет exchctl с аргументами args (без имени программы) и возвращает код завершения
+func Run(ctx context.Context, env Env, args []string) int {
+	a := &app{env: env}
+
+	fs := flag.NewFlagSet("exchctl", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() { a.usage(fs) }
+	addr := fs.String("addr", getenv(env, EnvAddr, DefaultAddr), "Exchanger gRPC address host:port (env "+EnvAddr+")")
+	token := fs.String("token", getenv(env, EnvToken, ""), "API token of an admin caller (env "+EnvToken+")")
+	fs.StringVar(&a.output, "o", OutputTable, "Output format: table or json")
+	timeout := fs.Duration("timeout", DefaultTimeout, "Timeout of the command")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if a.output != OutputTable && a.output != OutputJSON {
+		fmt.Fprintf(env.Stderr, "exchctl: invalid output format %q (expected table or json)\n", a.output)
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(env.Stderr, "exchctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return ExitUsage
+	}
+
+	// Соединение устанавливается при первом вызове, поэтому справка команд работает без сервиса
+	conn, err := grpcServer.Dial(*addr, grpcServer.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "exchctl: failed to connect to %s: %v\n", *addr, err)
+		return ExitError
+	}
+	defer conn.Close()
+	a.exchange = pb.NewExchangeServiceClient(conn)
+	a.health = healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	if *token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, grpc.APITokenHeader, *token)
+	}
+
+	err = cmd.run(ctx, a, fs.Args()[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errUsagePrinted):
+		return ExitUsage
+	case errors.Is(err, errNotServing):
+		return ExitError
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(env.Stderr, "exchctl %s: %v\nUsage: exchctl [flags] %s %s\n", cmd.name, err, cmd.name, cmd.args)
+		return ExitUsage
+	}
+	fmt.Fprintf(env.Stderr, "exchctl %s: %s\n", cmd.name, describeError(err))
+	return ExitError
+}
+
+// usage выводит справку exchctl
+func (a *app) usage(fs *flag.FlagSet) {
+	w := a.env.Stderr
+	fmt.Fprintln(w, "Usage: exchctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\nRun \"exchctl <command> -h\" for the arguments of a command.")
+}
+
+// describeError описывает ошибку gRPC кодом из реестра ошибок и сообщением сервиса
+func describeError(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s: %s", errcodes.FromError(err), st.Message())
+}
+
+// table создает вывод таблицы с заголовком columns
+func (a *app) table(columns ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(a.env.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	return w
+}
+
+func (a *app) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(a.env.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func getenv(env Env, key, fallback string) string {
+	if env.Getenv != nil {
+		if value := env.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package grpc
+
+import (
+	"context"
+
+	"gw-common/errcodes"
+	pb "gw-exchanger/proto"
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// APITokenHeader ключ metadata с API токеном вызывающей стороны
+const APITokenHeader = "x-api-token"
+
+// adminMethods методы, доступные только административным вызывающим сторонам
+var adminMethods = map[string]bool{
+	pb.ExchangeService_CreateCurrency_FullMethodName:    true,
+	pb.ExchangeService_SetCurrencyActive_FullMethodName: true,
+	pb.ExchangeService_GetCallerPairs_FullMethodName:    true,
+	pb.ExchangeService_SetCallerPairs_FullMethodName:    true,
+	pb.ExchangeService_BulkSetRates_FullMethodName:      true,
+}
+
+// callerKey ключ контекста с именем вызывающей стороны
+type callerKey struct{}
+
+// CallerAuth определяет вызывающую сторону по API токену
+type CallerAuth struct {
+	tokens map[string]string
+	admins map[string]bool
+	logger *logrus.Logger
+}
+
+// NewCallerAuth создает CallerAuth. tokens отображает токен в имя вызывающей стороны;
+// если токены не заданы, проверка отключена и все вызовы анонимны и не ограничены
+func NewCallerAuth(tokens map[string]string, adminCallers []string, logger *logrus.Logger) *CallerAuth {
+	admins := make(map[string]bool, len(adminCallers))
+	for _, caller := range adminCallers {
+		admins[caller] = true
+	}
+
+	return &CallerAuth{
+		tokens: tokens,
+		admins: admins,
+		logger: logger,
+	}
+}
+
+// UnaryInterceptor проверяет API токен и сохраняет имя вызывающей стороны в контексте
+func (a *CallerAuth) UnaryInterceptor() grpcServer.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpcServer.UnaryServerInfo,
+		handler grpcServer.UnaryHandler,
+	) (interface{}, error) {
+		if len(a.tokens) == 0 {
+			return handler(ctx, req)
+		}
+
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(APITokenHeader); len(values) > 0 {
+				token = values[0]
+			}
+		}
+
+		caller, ok := a.tokens[token]
+		if !ok {
+			a.logger.Warnf("Rejected %s: missing or unknown API token", info.FullMethod)
+			return nil, errcodes.GRPCError(errcodes.Unauthorized, "invalid API token")
+		}
+
+		if adminMethods[info.FullMethod] && !a.admins[caller] {
+			a.logger.Warnf("Rejected %s for caller %s: admin access required", info.FullMethod, caller)
+			return nil, errcodes.GRPCError(errcodes.Forbidden, "admin access required")
+		}
+
+		return handler(context.WithValue(ctx, callerKey{}, caller), req)
+	}
+}
+
+// CallerFromContext возвращает имя вызывающей стороны; пустая строка для анонимных вызовов
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
@@ -0,0 +1,33 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Режимы сжатия ответов
+const (
+	CompressionGzip = gzip.Name
+	CompressionNone = "none"
+)
+
+// CompressionInterceptor сжимает ответы алгоритмом compressor, если клиент его поддерживает.
+// Запросы, сжатые gzip, принимаются независимо от настройки
+func CompressionInterceptor(compressor string, logger *logrus.Logger) grpcServer.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpcServer.UnaryServerInfo,
+		handler grpcServer.UnaryHandler,
+	) (interface{}, error) {
+		if compressor != CompressionNone {
+			if err := grpcServer.SetSendCompressor(ctx, compressor); err != nil {
+				logger.Debugf("Response compression %s skipped for %s: %v", compressor, info.FullMethod, err)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	pb "gw-exchanger/proto"
+)
+
+// RunHealthUpdates обновляет статус стандартного сервиса grpc.health.v1 по доступности БД:
+// без БД exchanger не может отдавать курсы, поэтому клиенты видят NOT_SERVING.
+// Проверка выполняется сразу и затем с интервалом interval до отмены контекста
+func RunHealthUpdates(ctx context.Context, healthServer *health.Server, ping func(ctx context.Context) error, interval time.Duration, logger *logrus.Logger) {
+	serving := true
+	update := func() {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		err := ping(checkCtx)
+		cancel()
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		if (err == nil) != serving {
+			if err != nil {
+				logger.Warnf("Database is unavailable, reporting NOT_SERVING: %v", err)
+			} else {
+				logger.Info("Database is available, reporting SERVING")
+			}
+			serving = err == nil
+		}
+
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus(pb.ExchangeService_ServiceDesc.ServiceName, status)
+	}
+
+	update()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
@@ -2,18 +2,40 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"gw-common/buildinfo"
+	"gw-common/errcodes"
+	"gw-common/utils"
+	"gw-exchanger/internal/events"
+	"gw-exchanger/internal/importer"
+	"gw-exchanger/internal/pricing"
 	"gw-exchanger/internal/storages"
 	pb "gw-exchanger/proto"
-	"github.com/sirupsen/logrus"
 )
 
+// MaxRateHistoryDays максимальное число дней в запросе GetRateHistory
+const MaxRateHistoryDays = 366
+
+// historyDateLayout формат дат истории курсов
+const historyDateLayout = "2006-01-02"
+
 // ExchangeServer реализует gRPC сервис ExchangeService
 type ExchangeServer struct {
 	pb.UnimplementedExchangeServiceServer
 	storage storages.Storage
 	logger  *logrus.Logger
+	// crossRateBase базовая валюта кросс-курсов, пусто - кросс-курсы отключены
+	crossRateBase string
+	// spreads спреды курсов покупки и продажи, nil - bid и ask равны среднему курсу
+	spreads *pricing.Spreads
+	// maxRateAge максимальный возраст курса, 0 - без проверки
+	maxRateAge time.Duration
 }
 
 // NewExchangeServer создает новый экземпляр ExchangeServer
@@ -24,6 +46,22 @@ func NewExchangeServer(storage storages.Storage, logger *logrus.Logger) *Exchang
 	}
 }
 
+// EnableCrossRates включает вычисление курса пары без прямого курса через базовую валюту
+func (s *ExchangeServer) EnableCrossRates(base string) {
+	s.crossRateBase = base
+}
+
+// EnableSpreads включает спреды: bid и ask рассчитываются вокруг курса из БД
+func (s *ExchangeServer) EnableSpreads(spreads *pricing.Spreads) {
+	s.spreads = spreads
+}
+
+// EnableStalenessGuard запрещает курсы, обновленные раньше maxAge назад: пара не
+// возвращается GetExchangeRateForCurrency и отмечается устаревшей в GetExchangeRates
+func (s *ExchangeServer) EnableStalenessGuard(maxAge time.Duration) {
+	s.maxRateAge = maxAge
+}
+
 // GetExchangeRates возвращает все курсы обмена валют
 func (s *ExchangeServer) GetExchangeRates(ctx context.Context, req *pb.Empty) (*pb.ExchangeRatesResponse, error) {
 	s.logger.Info("Received GetExchangeRates request")
@@ -31,21 +69,45 @@ func (s *ExchangeServer) GetExchangeRates(ctx context.Context, req *pb.Empty) (*
 	rates, err := s.storage.GetAllExchangeRates(ctx)
 	if err != nil {
 		s.logger.Errorf("Failed to get exchange rates: %v", err)
-		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get exchange rates")
+	}
+
+	allowed, err := s.allowedPairs(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Преобразование данных из БД в формат protobuf
 	ratesMap := make(map[string]float32)
+	bids := make(map[string]float32)
+	asks := make(map[string]float32)
+	var stale []string
 	for _, rate := range rates {
+		if allowed != nil && !allowed[storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}] {
+			continue
+		}
 		key := fmt.Sprintf("%s_%s", rate.FromCurrency, rate.ToCurrency)
+		bid, ask := s.spreads.Apply(rate.FromCurrency, rate.ToCurrency, rate.Rate)
 		ratesMap[key] = float32(rate.Rate)
+		bids[key] = float32(bid)
+		asks[key] = float32(ask)
+		if s.isStale(&rate) {
+			stale = append(stale, key)
+		}
 	}
+	sort.Strings(stale)
 
 	response := &pb.ExchangeRatesResponse{
 		Rates: ratesMap,
+		Bids:  bids,
+		Asks:  asks,
+		Stale: stale,
 	}
 
-	s.logger.Infof("Successfully retrieved %d exchange rates", len(rates))
+	if len(stale) > 0 {
+		s.logger.Warnf("Stale exchange rates (older than %s): %s", s.maxRateAge, strings.Join(stale, ", "))
+	}
+	s.logger.Infof("Successfully retrieved %d exchange rates", len(ratesMap))
 	return response, nil
 }
 
@@ -57,7 +119,7 @@ func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb
 	// Валидация входных данных
 	if req.FromCurrency == "" || req.ToCurrency == "" {
 		s.logger.Warn("Invalid currency request: empty currency code")
-		return nil, fmt.Errorf("from_currency and to_currency are required")
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, "from_currency and to_currency are required")
 	}
 
 	// Проверка, что валюты разные
@@ -67,25 +129,465 @@ func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb
 			FromCurrency: req.FromCurrency,
 			ToCurrency:   req.ToCurrency,
 			Rate:         1.0,
+			Bid:          1.0,
+			Ask:          1.0,
 		}, nil
 	}
 
-	// Получение курса из БД
+	// Проверка, что пара разрешена вызывающей стороне
+	allowed, err := s.allowedPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil && !allowed[storages.CurrencyPair{FromCurrency: req.FromCurrency, ToCurrency: req.ToCurrency}] {
+		s.logger.Warnf("Currency pair %s -> %s is not allowed for caller %s",
+			req.FromCurrency, req.ToCurrency, CallerFromContext(ctx))
+		return nil, errcodes.GRPCErrorf(errcodes.PairNotAllowed, "currency pair %s->%s is not allowed",
+			req.FromCurrency, req.ToCurrency)
+	}
+
+	// Получение курса из БД; без прямой пары курс вычисляется через базовую валюту
+	derived := false
 	rate, err := s.storage.GetExchangeRate(ctx, req.FromCurrency, req.ToCurrency)
+	if errors.Is(err, storages.ErrNotFound) && s.crossRateBase != "" &&
+		req.FromCurrency != s.crossRateBase && req.ToCurrency != s.crossRateBase {
+		cross, crossErr := s.crossRate(ctx, req.FromCurrency, req.ToCurrency)
+		switch {
+		case crossErr == nil:
+			rate, err, derived = cross, nil, true
+		case !errors.Is(crossErr, storages.ErrNotFound):
+			err = crossErr
+		}
+	}
 	if err != nil {
 		s.logger.Errorf("Failed to get exchange rate for %s -> %s: %v",
 			req.FromCurrency, req.ToCurrency, err)
-		return nil, fmt.Errorf("exchange rate not found: %w", err)
+		return nil, storageError(err, "failed to get exchange rate")
 	}
 
+	// Устаревший курс не возвращается, чтобы не обменивать по курсу остановленного источника
+	if s.isStale(rate) {
+		age := time.Since(rate.UpdatedAt).Truncate(time.Second)
+		s.logger.Warnf("Exchange rate %s -> %s is stale: updated %s ago, max age %s",
+			req.FromCurrency, req.ToCurrency, age, s.maxRateAge)
+		return nil, errcodes.GRPCErrorf(errcodes.StaleRate, "exchange rate %s->%s is stale: updated %s ago, max age %s",
+			req.FromCurrency, req.ToCurrency, age, s.maxRateAge)
+	}
+
+	bid, ask := s.spreads.Apply(rate.FromCurrency, rate.ToCurrency, rate.Rate)
 	response := &pb.ExchangeRateResponse{
 		FromCurrency: rate.FromCurrency,
 		ToCurrency:   rate.ToCurrency,
 		Rate:         float32(rate.Rate),
+		Bid:          float32(bid),
+		Ask:          float32(ask),
+	}
+	if derived {
+		response.Derived = true
+		response.BaseCurrency = s.crossRateBase
+	}
+
+	s.logger.Infof("Successfully retrieved exchange rate: %s -> %s = %.8f (bid: %.8f, ask: %.8f, derived: %t)",
+		rate.FromCurrency, rate.ToCurrency, rate.Rate, bid, ask, derived)
+
+	return response, nil
+}
+
+// GetCurrencies возвращает список поддерживаемых валют
+func (s *ExchangeServer) GetCurrencies(ctx context.Context, req *pb.CurrenciesRequest) (*pb.CurrenciesResponse, error) {
+	s.logger.Infof("Received GetCurrencies request (include_inactive=%t)", req.IncludeInactive)
+
+	currencies, err := s.storage.GetAllCurrencies(ctx, req.IncludeInactive)
+	if err != nil {
+		s.logger.Errorf("Failed to get currencies: %v", err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get currencies")
+	}
+
+	response := &pb.CurrenciesResponse{
+		Currencies: make([]*pb.Currency, 0, len(currencies)),
+	}
+	for i := range currencies {
+		response.Currencies = append(response.Currencies, toProtoCurrency(&currencies[i]))
+	}
+
+	s.logger.Infof("Successfully retrieved %d currencies", len(currencies))
+	return response, nil
+}
+
+// CreateCurrency добавляет новую валюту. Валюта сразу активна,
+// курсы для нее добавляются отдельно в exchange_rates
+func (s *ExchangeServer) CreateCurrency(ctx context.Context, req *pb.CreateCurrencyRequest) (*pb.Currency, error) {
+	s.logger.Infof("Received CreateCurrency request: %s", req.Code)
+
+	code := utils.NormalizeCurrency(req.Code)
+	if err := utils.ValidateCurrencyCode(code); err != nil {
+		s.logger.Warnf("Invalid currency request: %v", err)
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, err.Error())
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		s.logger.Warn("Invalid currency request: empty name")
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, "name is required")
+	}
+
+	currency := &storages.Currency{
+		Code:     code,
+		Name:     name,
+		IsActive: true,
+	}
+	if err := s.storage.CreateCurrency(ctx, currency); err != nil {
+		s.logger.Errorf("Failed to create currency %s: %v", code, err)
+		return nil, storageError(err, "failed to create currency")
+	}
+
+	s.logger.Infof("Successfully created currency: %s", code)
+	return toProtoCurrency(currency), nil
+}
+
+// SetCurrencyActive включает или отключает валюту. Курсы отключенной
+// валюты не возвращаются, пока она не будет включена снова
+func (s *ExchangeServer) SetCurrencyActive(ctx context.Context, req *pb.SetCurrencyActiveRequest) (*pb.Currency, error) {
+	s.logger.Infof("Received SetCurrencyActive request: %s -> %t", req.Code, req.IsActive)
+
+	code := utils.NormalizeCurrency(req.Code)
+	if err := utils.ValidateCurrencyCode(code); err != nil {
+		s.logger.Warnf("Invalid currency request: %v", err)
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, err.Error())
+	}
+
+	if err := s.storage.SetCurrencyActive(ctx, code, req.IsActive); err != nil {
+		s.logger.Errorf("Failed to update currency %s: %v", code, err)
+		return nil, storageError(err, "failed to update currency")
+	}
+
+	currency, err := s.storage.GetCurrency(ctx, code)
+	if err != nil {
+		s.logger.Errorf("Failed to get currency %s: %v", code, err)
+		return nil, storageError(err, "failed to get currency")
+	}
+
+	s.logger.Infof("Successfully set currency %s active=%t", code, currency.IsActive)
+	return toProtoCurrency(currency), nil
+}
+
+// GetCallerPairs возвращает пары валют, разрешенные вызывающей стороне
+func (s *ExchangeServer) GetCallerPairs(ctx context.Context, req *pb.CallerPairsRequest) (*pb.CallerPairsResponse, error) {
+	s.logger.Infof("Received GetCallerPairs request: %s", req.Caller)
+
+	caller := strings.TrimSpace(req.Caller)
+	if caller == "" {
+		s.logger.Warn("Invalid caller pairs request: empty caller")
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, "caller is required")
+	}
+
+	pairs, err := s.storage.GetCallerPairs(ctx, caller)
+	if err != nil {
+		s.logger.Errorf("Failed to get pairs for caller %s: %v", caller, err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get caller pairs")
+	}
+
+	return toProtoCallerPairs(caller, pairs), nil
+}
+
+// SetCallerPairs заменяет список пар валют, разрешенных вызывающей стороне.
+// Пустой список снимает ограничения
+func (s *ExchangeServer) SetCallerPairs(ctx context.Context, req *pb.SetCallerPairsRequest) (*pb.CallerPairsResponse, error) {
+	s.logger.Infof("Received SetCallerPairs request: %s (%d pairs)", req.Caller, len(req.Pairs))
+
+	caller := strings.TrimSpace(req.Caller)
+	if caller == "" {
+		s.logger.Warn("Invalid caller pairs request: empty caller")
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, "caller is required")
+	}
+
+	pairs := make([]storages.CurrencyPair, 0, len(req.Pairs))
+	seen := make(map[storages.CurrencyPair]bool, len(req.Pairs))
+	for _, p := range req.Pairs {
+		pair := storages.CurrencyPair{
+			FromCurrency: utils.NormalizeCurrency(p.FromCurrency),
+			ToCurrency:   utils.NormalizeCurrency(p.ToCurrency),
+		}
+		if err := utils.ValidateCurrencyCode(pair.FromCurrency); err != nil {
+			s.logger.Warnf("Invalid caller pairs request: %v", err)
+			return nil, errcodes.GRPCError(errcodes.InvalidRequest, err.Error())
+		}
+		if err := utils.ValidateCurrencyCode(pair.ToCurrency); err != nil {
+			s.logger.Warnf("Invalid caller pairs request: %v", err)
+			return nil, errcodes.GRPCError(errcodes.InvalidRequest, err.Error())
+		}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		pairs = append(pairs, pair)
+	}
+
+	if err := s.storage.SetCallerPairs(ctx, caller, pairs); err != nil {
+		s.logger.Errorf("Failed to set pairs for caller %s: %v", caller, err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to set caller pairs")
+	}
+
+	s.logger.Infof("Successfully set %d pairs for caller %s", len(pairs), caller)
+	return toProtoCallerPairs(caller, pairs), nil
+}
+
+// BulkSetRates обновляет курсы и создает отсутствующие пары в одной транзакции.
+// Курсы проверяются заранее: при ошибке в любом из них не сохраняется ни один
+func (s *ExchangeServer) BulkSetRates(ctx context.Context, req *pb.BulkSetRatesRequest) (*pb.BulkSetRatesResponse, error) {
+	s.logger.Infof("Received BulkSetRates request (%d rates)", len(req.Rates))
+
+	rates := make([]storages.ExchangeRate, 0, len(req.Rates))
+	for _, r := range req.Rates {
+		rates = append(rates, storages.ExchangeRate{
+			FromCurrency: r.FromCurrency,
+			ToCurrency:   r.ToCurrency,
+			Rate:         r.Rate,
+		})
+	}
+
+	currencies, err := s.storage.GetAllCurrencies(ctx, true)
+	if err != nil {
+		s.logger.Errorf("Failed to get currencies: %v", err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get currencies")
+	}
+
+	rates, err = importer.Validate(rates, currencies)
+	if err != nil {
+		s.logger.Warnf("Invalid bulk rates request: %v", err)
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, err.Error())
+	}
+
+	ctx = events.ContextWithSource(ctx, events.SourceAPI, CallerFromContext(ctx))
+	if err := s.storage.UpsertExchangeRates(ctx, rates); err != nil {
+		s.logger.Errorf("Failed to upsert %d rates: %v", len(rates), err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to upsert rates")
 	}
 
-	s.logger.Infof("Successfully retrieved exchange rate: %s -> %s = %.8f",
-		rate.FromCurrency, rate.ToCurrency, rate.Rate)
+	s.logger.Infof("Successfully upserted %d rates", len(rates))
+	return &pb.BulkSetRatesResponse{Upserted: int32(len(rates))}, nil
+}
+
+// GetRateHistory возвращает курс пары на конец каждого дня периода. Без истории
+// прямой пары курс вычисляется через базовую валюту кросс-курсов. Проверка
+// устаревания к истории не применяется
+func (s *ExchangeServer) GetRateHistory(ctx context.Context, req *pb.RateHistoryRequest) (*pb.RateHistoryResponse, error) {
+	s.logger.Infof("Received GetRateHistory request: %s -> %s, %s..%s",
+		req.FromCurrency, req.ToCurrency, req.StartDate, req.EndDate)
+
+	if req.FromCurrency == "" || req.ToCurrency == "" {
+		s.logger.Warn("Invalid rate history request: empty currency code")
+		return nil, errcodes.GRPCError(errcodes.InvalidRequest, "from_currency and to_currency are required")
+	}
+
+	start, err := time.Parse(historyDateLayout, req.StartDate)
+	if err != nil {
+		return nil, errcodes.GRPCErrorf(errcodes.InvalidRequest, "invalid start_date %q, expected YYYY-MM-DD", req.StartDate)
+	}
+	end, err := time.Parse(historyDateLayout, req.EndDate)
+	if err != nil {
+		return nil, errcodes.GRPCErrorf(errcodes.InvalidRequest, "invalid end_date %q, expected YYYY-MM-DD", req.EndDate)
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	if days < 1 || days > MaxRateHistoryDays {
+		s.logger.Warnf("Invalid rate history period: %s..%s", req.StartDate, req.EndDate)
+		return nil, errcodes.GRPCErrorf(errcodes.InvalidRequest, "end_date must not be before start_date and the period must not exceed %d days", MaxRateHistoryDays)
+	}
+
+	response := &pb.RateHistoryResponse{
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+	}
+
+	if req.FromCurrency == req.ToCurrency {
+		for day := 0; day < days; day++ {
+			response.Points = append(response.Points, &pb.RatePoint{
+				Date: start.AddDate(0, 0, day).Format(historyDateLayout),
+				Rate: 1.0,
+			})
+		}
+		return response, nil
+	}
+
+	allowed, err := s.allowedPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil && !allowed[storages.CurrencyPair{FromCurrency: req.FromCurrency, ToCurrency: req.ToCurrency}] {
+		s.logger.Warnf("Currency pair %s -> %s is not allowed for caller %s",
+			req.FromCurrency, req.ToCurrency, CallerFromContext(ctx))
+		return nil, errcodes.GRPCErrorf(errcodes.PairNotAllowed, "currency pair %s->%s is not allowed",
+			req.FromCurrency, req.ToCurrency)
+	}
+
+	closes, err := s.dailyRates(ctx, req.FromCurrency, req.ToCurrency, start, days)
+	if err != nil {
+		return nil, err
+	}
+	if len(closes) == 0 && s.crossRateBase != "" &&
+		req.FromCurrency != s.crossRateBase && req.ToCurrency != s.crossRateBase {
+		// Кросс-курс дня есть только в дни, когда известны обе пары
+		fromLeg, err := s.dailyRates(ctx, req.FromCurrency, s.crossRateBase, start, days)
+		if err != nil {
+			return nil, err
+		}
+		toLeg, err := s.dailyRates(ctx, s.crossRateBase, req.ToCurrency, start, days)
+		if err != nil {
+			return nil, err
+		}
+		for day, fromRate := range fromLeg {
+			if toRate, ok := toLeg[day]; ok {
+				closes[day] = fromRate * toRate
+			}
+		}
+		if len(closes) > 0 {
+			response.Derived = true
+			response.BaseCurrency = s.crossRateBase
+		}
+	}
+	if len(closes) == 0 {
+		s.logger.Warnf("Exchange rate history not found: %s -> %s, %s..%s",
+			req.FromCurrency, req.ToCurrency, req.StartDate, req.EndDate)
+		return nil, errcodes.GRPCErrorf(errcodes.NotFound, "exchange rate history %s->%s not found for %s..%s",
+			req.FromCurrency, req.ToCurrency, req.StartDate, req.EndDate)
+	}
+
+	for day := 0; day < days; day++ {
+		if rate, ok := closes[day]; ok {
+			response.Points = append(response.Points, &pb.RatePoint{
+				Date: start.AddDate(0, 0, day).Format(historyDateLayout),
+				Rate: rate,
+			})
+		}
+	}
 
+	s.logger.Infof("Successfully retrieved %d daily rates: %s -> %s (derived: %t)",
+		len(response.Points), req.FromCurrency, req.ToCurrency, response.Derived)
 	return response, nil
 }
+
+// GetVersion возвращает версию, коммит и время сборки сервиса
+func (s *ExchangeServer) GetVersion(ctx context.Context, req *pb.Empty) (*pb.VersionResponse, error) {
+	info := buildinfo.Get()
+	return &pb.VersionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildTime: info.BuildTime,
+		GoVersion: info.GoVersion,
+	}, nil
+}
+
+// dailyRates возвращает курс пары на конец каждого из days дней начиная с start
+// по номеру дня. Дни до первой записи истории пропускаются
+func (s *ExchangeServer) dailyRates(ctx context.Context, fromCurrency, toCurrency string, start time.Time, days int) (map[int]float64, error) {
+	until := start.AddDate(0, 0, days)
+	points, err := s.storage.GetRateHistory(ctx, fromCurrency, toCurrency, start, until)
+	if err != nil {
+		s.logger.Errorf("Failed to get rate history for %s -> %s: %v", fromCurrency, toCurrency, err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get rate history")
+	}
+
+	closes := make(map[int]float64, days)
+	next := 0
+	for day := 0; day < days; day++ {
+		dayEnd := start.AddDate(0, 0, day+1)
+		for next < len(points) && points[next].RecordedAt.Before(dayEnd) {
+			next++
+		}
+		if next > 0 {
+			closes[day] = points[next-1].Rate
+		}
+	}
+	return closes, nil
+}
+
+// isStale проверяет, что курс обновлен раньше maxRateAge назад
+func (s *ExchangeServer) isStale(rate *storages.ExchangeRate) bool {
+	return s.maxRateAge > 0 && time.Since(rate.UpdatedAt) > s.maxRateAge
+}
+
+// crossRate вычисляет курс from -> to как произведение курсов from -> base (fromLeg)
+// и base -> to (toLeg). Обратные курсы не используются. Время обновления - более раннее из двух
+func (s *ExchangeServer) crossRate(ctx context.Context, fromCurrency, toCurrency string) (*storages.ExchangeRate, error) {
+	fromLeg, err := s.storage.GetExchangeRate(ctx, fromCurrency, s.crossRateBase)
+	if err != nil {
+		return nil, err
+	}
+	toLeg, err := s.storage.GetExchangeRate(ctx, s.crossRateBase, toCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt := fromLeg.UpdatedAt
+	if toLeg.UpdatedAt.Before(updatedAt) {
+		updatedAt = toLeg.UpdatedAt
+	}
+	return &storages.ExchangeRate{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         fromLeg.Rate * toLeg.Rate,
+		UpdatedAt:    updatedAt,
+	}, nil
+}
+
+// allowedPairs возвращает пары, разрешенные текущей вызывающей стороне,
+// или nil, если ограничений нет
+func (s *ExchangeServer) allowedPairs(ctx context.Context) (map[storages.CurrencyPair]bool, error) {
+	caller := CallerFromContext(ctx)
+	if caller == "" {
+		return nil, nil
+	}
+
+	pairs, err := s.storage.GetCallerPairs(ctx, caller)
+	if err != nil {
+		s.logger.Errorf("Failed to get pairs for caller %s: %v", caller, err)
+		return nil, errcodes.GRPCError(errcodes.Internal, "failed to get caller pairs")
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	allowed := make(map[storages.CurrencyPair]bool, len(pairs))
+	for _, pair := range pairs {
+		allowed[pair] = true
+	}
+	return allowed, nil
+}
+
+// toProtoCallerPairs преобразует список разрешенных пар в формат protobuf
+func toProtoCallerPairs(caller string, pairs []storages.CurrencyPair) *pb.CallerPairsResponse {
+	response := &pb.CallerPairsResponse{
+		Caller: caller,
+		Pairs:  make([]*pb.CurrencyPair, 0, len(pairs)),
+	}
+	for _, pair := range pairs {
+		response.Pairs = append(response.Pairs, &pb.CurrencyPair{
+			FromCurrency: pair.FromCurrency,
+			ToCurrency:   pair.ToCurrency,
+		})
+	}
+	return response
+}
+
+// toProtoCurrency преобразует валюту из БД в формат protobuf
+func toProtoCurrency(currency *storages.Currency) *pb.Currency {
+	return &pb.Currency{
+		Code:     currency.Code,
+		Name:     currency.Name,
+		IsActive: currency.IsActive,
+	}
+}
+
+// storageError переводит ошибку хранилища в gRPC статус: ErrNotFound в NotFound,
+// ErrDuplicate в AlreadyExists, остальные ошибки в Internal без подробностей:
+// они уже записаны в лог вызывающим обработчиком
+func storageError(err error, message string) error {
+	switch {
+	case errors.Is(err, storages.ErrNotFound):
+		return errcodes.GRPCErrorf(errcodes.NotFound, "%s: %v", message, err)
+	case errors.Is(err, storages.ErrDuplicate):
+		return errcodes.GRPCErrorf(errcodes.AlreadyExists, "%s: %v", message, err)
+	default:
+		return errcodes.GRPCError(errcodes.Internal, message)
+	}
+}
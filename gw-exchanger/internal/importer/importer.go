@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gw-common/utils"
+	"gw-exchanger/internal/storages"
+)
+
+// Форматы файлов с курсами
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ErrInvalidRates курсы не прошли проверку; импорт не выполняется целиком
+var ErrInvalidRates = errors.New("invalid rates")
+
+// rateRecord курс в JSON файле, формат совпадает с ответом плагина
+type rateRecord struct {
+	FromCurrency string  `json:"from"`
+	ToCurrency   string  `json:"to"`
+	Rate         float64 `json:"rate"`
+}
+
+// FormatFromPath определяет формат файла по расширению
+func FormatFromPath(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported rates file extension %q (expected .csv or .json)", ext)
+	}
+}
+
+// Parse читает курсы в формате format:
+//   - csv: строки from,to,rate; первая строка может быть заголовком
+//   - json: {"rates": [{"from": "USD", "to": "EUR", "rate": 0.92}]}, как у плагинов
+func Parse(r io.Reader, format string) ([]storages.ExchangeRate, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSON:
+		return parseJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported rates format %q", format)
+	}
+}
+
+// Validate нормализует коды валют и проверяет курсы: валюты есть в справочнике
+// currencies (в том числе отключенные), валюты пары различаются, курс положителен,
+// пары не повторяются. Возвращает ErrInvalidRates со всеми нарушениями
+func Validate(rates []storages.ExchangeRate, currencies []storages.Currency) ([]storages.ExchangeRate, error) {
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: no rates", ErrInvalidRates)
+	}
+
+	known := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		known[c.Code] = true
+	}
+
+	var violations []string
+	seen := make(map[storages.CurrencyPair]int, len(rates))
+	normalized := make([]storages.ExchangeRate, 0, len(rates))
+	for i, rate := range rates {
+		n := i + 1
+		pair := storages.CurrencyPair{
+			FromCurrency: utils.NormalizeCurrency(rate.FromCurrency),
+			ToCurrency:   utils.NormalizeCurrency(rate.ToCurrency),
+		}
+
+		switch {
+		case !known[pair.FromCurrency]:
+			violations = append(violations, fmt.Sprintf("rate %d: unknown currency %q", n, rate.FromCurrency))
+		case !known[pair.ToCurrency]:
+			violations = append(violations, fmt.Sprintf("rate %d: unknown currency %q", n, rate.ToCurrency))
+		case pair.FromCurrency == pair.ToCurrency:
+			violations = append(violations, fmt.Sprintf("rate %d: same currency %s", n, pair.FromCurrency))
+		case !(rate.Rate > 0) || math.IsInf(rate.Rate, 0):
+			violations = append(violations, fmt.Sprintf("rate %d: rate must be positive, got %v", n, rate.Rate))
+		default:
+			if first, ok := seen[pair]; ok {
+				violations = append(violations, fmt.Sprintf("rate %d: duplicate pair %s -> %s (rate %d)", n, pair.FromCurrency, pair.ToCurrency, first))
+				continue
+			}
+			seen[pair] = n
+			normalized = append(normalized, storages.ExchangeRate{
+				FromCurrency: pair.FromCurrency,
+				ToCurrency:   pair.ToCurrency,
+				Rate:         rate.Rate,
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRates, strings.Join(violations, "; "))
+	}
+	return normalized, nil
+}
+
+// parseCSV читает строки from,to,rate
+func parseCSV(r io.Reader) ([]storages.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []storages.ExchangeRate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			// Заголовок from,to,rate
+			if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "from") {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[2])
+		}
+
+		rates = append(rates, storages.ExchangeRate{
+			FromCurrency: record[0],
+			ToCurrency:   record[1],
+			Rate:         rate,
+		})
+	}
+	return rates, nil
+}
+
+// parseJSON читает объект {"rates": [...]}
+func parseJSON(r io.Reader) ([]storages.ExchangeRate, error) {
+	var file struct {
+		Rates []rateRecord `json:"rates"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	rates := make([]storages.ExchangeRate, 0, len(file.Rates))
+	for _, record := range file.Rates {
+		rates = append(rates, storages.ExchangeRate{
+			FromCurrency: record.FromCurrency,
+			ToCurrency:   record.ToCurrency,
+			Rate:         record.Rate,
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// RateChangeSchemaVersion текущая версия схемы RateChangeMessage
+const RateChangeSchemaVersion = 1
+
+// SourceService имя сервиса в заголовке SourceHeader
+const SourceService = "gw-exchanger"
+
+// Заголовки сообщений Kafka, как у сообщений кошелька
+const (
+	// EventIDHeader идентификатор события для дедупликации
+	EventIDHeader = "event_id"
+	// SchemaVersionHeader версия схемы тела сообщения
+	SchemaVersionHeader = "schema_version"
+	// SourceHeader сервис, отправивший сообщение
+	SourceHeader = "source"
+)
+
+// Режимы подтверждения записи брокерами
+const (
+	RequiredAcksAll  = "all"
+	RequiredAcksOne  = "one"
+	RequiredAcksNone = "none"
+)
+
+// RateChangeMessage событие изменения курса пары
+type RateChangeMessage struct {
+	EventID      string `json:"event_id"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	// OldRate курс до изменения, 0 - пара создана
+	OldRate float64 `json:"old_rate"`
+	NewRate float64 `json:"new_rate"`
+	// Source источник изменения: plugin:<name>, api:<caller>, import
+	Source        string    `json:"source"`
+	Timestamp     time.Time `json:"timestamp"`
+	SchemaVersion int       `json:"schema_version"`
+}
+
+// Config содержит конфигурацию Kafka producer
+type Config struct {
+	Brokers []string
+	// Topic топик событий изменения курсов
+	Topic string
+	// RequiredAcks подтверждения брокеров: all, one, none
+	RequiredAcks string
+}
+
+// Producer отправляет события изменения курсов. Запись асинхронная:
+// изменение курса не ждет брокеров, ошибки доставки пишутся в лог
+type Producer struct {
+	writer *kafka.Writer
+	logger *logrus.Logger
+}
+
+// NewProducer создает Kafka producer событий изменения курсов
+func NewProducer(cfg *Config, logger *logrus.Logger) *Producer {
+	var acks kafka.RequiredAcks
+	if err := acks.UnmarshalText([]byte(cfg.RequiredAcks)); err != nil {
+		logger.Warnf("Invalid Kafka required acks %q, using %q", cfg.RequiredAcks, RequiredAcksAll)
+		acks = kafka.RequireAll
+	}
+
+	p := &Producer{logger: logger}
+	p.writer = &kafka.Writer{
+		Addr:  kafka.TCP(cfg.Brokers...),
+		Topic: cfg.Topic,
+		// Ключ по паре сохраняет порядок изменений одной пары в партиции
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: acks,
+		Async:        true,
+		Compression:  kafka.Snappy,
+		BatchTimeout: 10 * time.Millisecond,
+		Completion:   p.onCompletion,
+	}
+
+	logger.Infof("Kafka producer initialized (topic: %s, required acks: %s)", cfg.Topic, acks)
+	return p
+}
+
+// PublishRateChanges ставит события изменения курсов в очередь на отправку
+func (p *Producer) PublishRateChanges(ctx context.Context, messages []RateChangeMessage) error {
+	batch := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		kafkaMessage, err := EncodeRateChange(message)
+		if err != nil {
+			p.logger.Errorf("Failed to marshal Kafka message: %v", err)
+			return err
+		}
+		batch = append(batch, kafkaMessage)
+	}
+
+	if err := p.writer.WriteMessages(ctx, batch...); err != nil {
+		p.logger.Errorf("Failed to send messages to Kafka topic %s: %v", p.writer.Topic, err)
+		return fmt.Errorf("failed to send messages: %w", err)
+	}
+
+	p.logger.Debugf("Queued %d rate change events for Kafka", len(messages))
+	return nil
+}
+
+// onCompletion получает результат асинхронной записи пачки сообщений
+func (p *Producer) onCompletion(messages []kafka.Message, err error) {
+	if err != nil {
+		p.logger.Errorf("Failed to deliver %d rate change events to Kafka: %v", len(messages), err)
+		return
+	}
+	p.logger.Debugf("Delivered %d rate change events to Kafka", len(messages))
+}
+
+// EncodeRateChange сериализует событие текущей версии схемы в сообщение Kafka.
+// Пустой event_id заполняется из пары и времени изменения
+func EncodeRateChange(message RateChangeMessage) (kafka.Message, error) {
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+	if message.EventID == "" {
+		message.EventID = fmt.Sprintf("rate-%s-%s-%d", message.FromCurrency, message.ToCurrency, message.Timestamp.UnixNano())
+	}
+	message.SchemaVersion = RateChangeSchemaVersion
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal rate change: %w", err)
+	}
+
+	return kafka.Message{
+		Key:   []byte(message.FromCurrency + "_" + message.ToCurrency),
+		Value: messageBytes,
+		Time:  message.Timestamp,
+		Headers: []kafka.Header{
+			{Key: EventIDHeader, Value: []byte(message.EventID)},
+			{Key: SchemaVersionHeader, Value: []byte(strconv.Itoa(message.SchemaVersion))},
+			{Key: SourceHeader, Value: []byte(SourceService)},
+		},
+	}, nil
+}
+
+// Close закрывает Kafka producer, дожидаясь отправки событий из очереди
+func (p *Producer) Close() error {
+	p.logger.Info("Closing Kafka producer")
+	return p.writer.Close()
+}
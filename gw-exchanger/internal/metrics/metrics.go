@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gw-exchanger/internal/storages/cache"
+)
+
+// Handler отдает метрики в текстовом формате Prometheus. ratesCache может быть nil,
+// если кеш курсов отключен
+func Handler(ratesCache *cache.Storage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+
+		if ratesCache != nil {
+			stats := ratesCache.Stats()
+			writeMetric(&b, "exchanger_rates_cache_hits_total", "counter",
+				"Rate reads served from the in-memory cache", stats.Hits)
+			writeMetric(&b, "exchanger_rates_cache_misses_total", "counter",
+				"Rate reads that loaded rates from the database", stats.Misses)
+			writeMetric(&b, "exchanger_rates_cache_hit_ratio", "gauge",
+				"Share of rate reads served from the cache", stats.HitRatio)
+			writeMetric(&b, "exchanger_rates_cache_refreshes_total", "counter",
+				"Rate loads from the database", stats.Refreshes)
+			writeMetric(&b, "exchanger_rates_cache_refresh_errors_total", "counter",
+				"Failed rate loads from the database", stats.RefreshErrors)
+			writeMetric(&b, "exchanger_rates_cache_invalidations_total", "counter",
+				"Cache invalidations on rate and currency writes", stats.Invalidations)
+			writeMetric(&b, "exchanger_rates_cache_rates", "gauge",
+				"Rates held in the cache", stats.Rates)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(b.String()))
+	})
+}
+
+// writeMetric записывает метрику с описанием и типом
+func writeMetric(b *strings.Builder, name, metricType, help string, value interface{}) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, metricType, name, value)
+}
@@ -0,0 +1,36 @@
+package pricing
+
+// MaxSpread верхняя граница спреда (не включительно): курс покупки остается положительным
+const MaxSpread = 1.0
+
+// Spreads спреды курсов: разница между курсами продажи и покупки в долях среднего курса.
+// Курсы в БД считаются средними
+type Spreads struct {
+	// Default спред пар без собственного значения
+	Default float64
+	// Pairs спреды отдельных пар по ключу FROM_TO
+	Pairs map[string]float64
+}
+
+// PairKey ключ пары в виде FROM_TO
+func PairKey(fromCurrency, toCurrency string) string {
+	return fromCurrency + "_" + toCurrency
+}
+
+// For возвращает спред пары. Без настроек спред нулевой
+func (s *Spreads) For(fromCurrency, toCurrency string) float64 {
+	if s == nil {
+		return 0
+	}
+	if spread, ok := s.Pairs[PairKey(fromCurrency, toCurrency)]; ok {
+		return spread
+	}
+	return s.Default
+}
+
+// Apply рассчитывает курсы покупки (bid) и продажи (ask) пары вокруг среднего курса:
+// bid = mid * (1 - spread/2), ask = mid * (1 + spread/2)
+func (s *Spreads) Apply(fromCurrency, toCurrency string, mid float64) (bid, ask float64) {
+	half := s.For(fromCurrency, toCurrency) / 2
+	return mid * (1 - half), mid * (1 + half)
+}
@@ -0,0 +1,127 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Команды, которые exchanger передает плагину первым аргументом
+const (
+	CommandRates  = "rates"
+	CommandHealth = "health"
+)
+
+// maxStderrInError максимальная длина stderr плагина в тексте ошибки
+const maxStderrInError = 512
+
+// MaxOutputSize максимальный размер stdout плагина. Плагин с большим выводом
+// завершается, вызов считается ошибкой
+const MaxOutputSize = 1 << 20
+
+// waitDelay время ожидания закрытия вывода после завершения плагина по таймауту:
+// дочерние процессы плагина могут держать stdout открытым
+const waitDelay = time.Second
+
+// ExecProvider провайдер на основе внешнего исполняемого файла.
+//
+// Контракт плагина:
+//   - "<path> rates" печатает в stdout {"rates":[{"from":"USD","to":"EUR","rate":0.92}]}
+//     и завершается с кодом 0;
+//   - "<path> health" завершается с кодом 0, если источник курсов доступен.
+//
+// Ненулевой код выхода считается ошибкой, stderr попадает в текст ошибки.
+// Вывод больше MaxOutputSize также считается ошибкой.
+type ExecProvider struct {
+	plugin Plugin
+}
+
+// NewExecProvider создает провайдер для плагина
+func NewExecProvider(plugin Plugin) *ExecProvider {
+	return &ExecProvider{plugin: plugin}
+}
+
+// ratesResponse ответ плагина на команду rates
+type ratesResponse struct {
+	Rates []Rate `json:"rates"`
+}
+
+// Name возвращает имя плагина
+func (p *ExecProvider) Name() string {
+	return p.plugin.Name
+}
+
+// FetchRates запускает плагин с командой rates и разбирает его ответ
+func (p *ExecProvider) FetchRates(ctx context.Context) ([]Rate, error) {
+	output, err := p.run(ctx, CommandRates)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ratesResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid rates: %w", p.plugin.Name, err)
+	}
+
+	return response.Rates, nil
+}
+
+// HealthCheck запускает плагин с командой health
+func (p *ExecProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.run(ctx, CommandHealth)
+	return err
+}
+
+// run запускает плагин с командой и возвращает stdout. Вызов ограничен таймаутом плагина
+func (p *ExecProvider) run(ctx context.Context, command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.plugin.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.plugin.Path, command)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s %s failed: %w", p.plugin.Name, command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin %s %s failed: %w", p.plugin.Name, command, err)
+	}
+
+	// Читается на байт больше лимита, чтобы отличить вывод ровно MaxOutputSize от большего
+	output, readErr := io.ReadAll(io.LimitReader(stdout, MaxOutputSize+1))
+	oversized := len(output) > MaxOutputSize
+	if oversized {
+		// Остаток вывода не читается, плагин завершается
+		cancel()
+	}
+	err = cmd.Wait()
+
+	switch {
+	case oversized:
+		return nil, fmt.Errorf("plugin %s %s output exceeds %d bytes", p.plugin.Name, command, MaxOutputSize)
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("plugin %s %s timed out after %v", p.plugin.Name, command, p.plugin.Timeout)
+	case err == nil && readErr != nil:
+		err = readErr
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > maxStderrInError {
+			message = message[:maxStderrInError] + "..."
+		}
+		if message != "" {
+			return nil, fmt.Errorf("plugin %s %s failed: %w: %s", p.plugin.Name, command, err, message)
+		}
+		return nil, fmt.Errorf("plugin %s %s failed: %w", p.plugin.Name, command, err)
+	}
+
+	return output, nil
+}
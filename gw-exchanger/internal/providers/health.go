@@ -0,0 +1,42 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthResponse ответ эндпоинта состояния провайдеров
+type healthResponse struct {
+	Status    string  `json:"status"` // ok, degraded или unavailable
+	Providers []State `json:"providers"`
+}
+
+// HealthHandler отдает состояния провайдеров в JSON. Статус unavailable (код 503)
+// означает, что ни один провайдер не обновил курсы при последней проверке;
+// до первой проверки провайдеры считаются недоступными
+func HealthHandler(manager *Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states := manager.States()
+
+		healthy := 0
+		for _, state := range states {
+			if state.Healthy {
+				healthy++
+			}
+		}
+
+		response := healthResponse{Status: "ok", Providers: states}
+		code := http.StatusOK
+		switch {
+		case healthy == 0:
+			response.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+		case healthy < len(states):
+			response.Status = "degraded"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(response)
+	})
+}
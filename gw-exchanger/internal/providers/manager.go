@@ -0,0 +1,166 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-common/utils"
+	"gw-exchanger/internal/events"
+	"gw-exchanger/internal/storages"
+)
+
+// State состояние провайдера по результатам последних вызовов
+type State struct {
+	Name         string    `json:"name"`
+	Healthy      bool      `json:"healthy"`
+	LastCheckAt  time.Time `json:"last_check_at"`
+	LastUpdateAt time.Time `json:"last_update_at"` // время последнего успешного обновления курсов
+	RatesUpdated int       `json:"rates_updated"`  // число курсов, обновленных последним вызовом
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// Manager периодически опрашивает провайдеров и сохраняет их курсы в БД.
+// Провайдеры опрашиваются по порядку, поэтому при совпадении пар курс
+// последнего провайдера перезаписывает курсы предыдущих
+type Manager struct {
+	providers []Provider
+	storage   storages.Storage
+	logger    *logrus.Logger
+
+	mu     sync.RWMutex
+	states map[string]*State
+}
+
+// NewManager создает менеджер провайдеров
+func NewManager(providers []Provider, storage storages.Storage, logger *logrus.Logger) *Manager {
+	states := make(map[string]*State, len(providers))
+	for _, p := range providers {
+		states[p.Name()] = &State{Name: p.Name()}
+	}
+
+	return &Manager{
+		providers: providers,
+		storage:   storage,
+		logger:    logger,
+		states:    states,
+	}
+}
+
+// Run обновляет курсы сразу и затем с интервалом interval до отмены контекста
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	m.RefreshAll(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.RefreshAll(ctx)
+		}
+	}
+}
+
+// RefreshAll проверяет провайдеров и обновляет курсы доступных.
+// Недоступный провайдер пропускается до следующего цикла
+func (m *Manager) RefreshAll(ctx context.Context) {
+	for _, provider := range m.providers {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := provider.HealthCheck(ctx); err != nil {
+			m.setState(provider.Name(), 0, err)
+			continue
+		}
+
+		updated, err := m.refresh(events.ContextWithSource(ctx, events.SourcePlugin, provider.Name()), provider)
+		m.setState(provider.Name(), updated, err)
+	}
+}
+
+// refresh получает курсы провайдера и сохраняет их в одной транзакции.
+// Возвращает число сохраненных курсов
+func (m *Manager) refresh(ctx context.Context, provider Provider) (int, error) {
+	rates, err := provider.FetchRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	currencies, err := m.storage.GetAllCurrencies(ctx, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get currencies: %w", err)
+	}
+	known := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		known[c.Code] = true
+	}
+
+	valid := make([]storages.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		from := utils.NormalizeCurrency(rate.FromCurrency)
+		to := utils.NormalizeCurrency(rate.ToCurrency)
+
+		// Курсы неизвестных валют и некорректные значения пропускаются:
+		// валюты добавляются в справочник только через CreateCurrency
+		if !known[from] || !known[to] || from == to || rate.Rate <= 0 {
+			m.logger.Warnf("Provider %s: skipping rate %s -> %s = %v", provider.Name(), rate.FromCurrency, rate.ToCurrency, rate.Rate)
+			continue
+		}
+
+		valid = append(valid, storages.ExchangeRate{FromCurrency: from, ToCurrency: to, Rate: rate.Rate})
+	}
+	if len(valid) == 0 {
+		return 0, nil
+	}
+
+	if err := m.storage.UpsertExchangeRates(ctx, valid); err != nil {
+		return 0, fmt.Errorf("failed to save rates: %w", err)
+	}
+	return len(valid), nil
+}
+
+// States возвращает копии состояний провайдеров в порядке опроса
+func (m *Manager) States() []State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	states := make([]State, 0, len(m.providers))
+	for _, p := range m.providers {
+		states = append(states, *m.states[p.Name()])
+	}
+	return states
+}
+
+// setState сохраняет результат вызова провайдера и логирует смену доступности
+func (m *Manager) setState(name string, updated int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := m.states[name]
+	wasHealthy := state.Healthy
+
+	state.LastCheckAt = time.Now()
+	state.RatesUpdated = updated
+	if err != nil {
+		state.Healthy = false
+		state.LastError = err.Error()
+		if wasHealthy || state.LastUpdateAt.IsZero() {
+			m.logger.Warnf("Rate provider %s is unavailable: %v", name, err)
+		}
+		return
+	}
+
+	state.Healthy = true
+	state.LastError = ""
+	state.LastUpdateAt = state.LastCheckAt
+	if !wasHealthy {
+		m.logger.Infof("Rate provider %s is available", name)
+	}
+	m.logger.Debugf("Rate provider %s updated %d rates", name, updated)
+}
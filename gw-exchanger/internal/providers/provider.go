@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// Provider источник курсов валют, подключаемый без перекомпиляции exchanger
+type Provider interface {
+	// Name возвращает имя провайдера из конфигурации
+	Name() string
+
+	// FetchRates возвращает текущие курсы провайдера
+	FetchRates(ctx context.Context) ([]Rate, error)
+
+	// HealthCheck проверяет, что провайдер готов отдавать курсы
+	HealthCheck(ctx context.Context) error
+}
+
+// Rate курс обмена, полученный от провайдера
+type Rate struct {
+	FromCurrency string  `json:"from"`
+	ToCurrency   string  `json:"to"`
+	Rate         float64 `json:"rate"`
+}
+
+// Plugin описание внешнего плагина провайдера
+type Plugin struct {
+	Name string
+	// Path путь к исполняемому файлу плагина
+	Path string
+	// Timeout ограничение одного вызова плагина; по истечении процесс завершается
+	Timeout time.Duration
+}
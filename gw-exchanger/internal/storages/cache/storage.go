@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-exchanger/internal/storages"
+)
+
+// Stats метрики кеша курсов
+type Stats struct {
+	Hits          int64   `json:"hits_total"`           // чтения, обслуженные из кеша
+	Misses        int64   `json:"misses_total"`         // чтения, загрузившие курсы из БД
+	Refreshes     int64   `json:"refreshes_total"`      // загрузки курсов из БД
+	RefreshErrors int64   `json:"refresh_errors_total"` // неудачные загрузки
+	Invalidations int64   `json:"invalidations_total"`  // сбросы кеша при записи
+	Rates         int     `json:"rates"`                // курсов в кеше
+	HitRatio      float64 `json:"hit_ratio"`            // доля чтений из кеша
+}
+
+// Storage кеширует курсы хранилища в памяти процесса. Все курсы загружаются
+// одним запросом GetAllExchangeRates и обновляются с интервалом ttl (Run),
+// при записи курсов и валют через этот Storage и при чтении после истечения ttl.
+// Остальные методы передаются хранилищу без изменений
+type Storage struct {
+	storages.Storage
+	logger *logrus.Logger
+	// ttlChanged сообщает Run о новом интервале обновления
+	ttlChanged chan struct{}
+
+	// loadMu не дает одновременным промахам загружать курсы несколько раз
+	loadMu   sync.Mutex
+	mu       sync.RWMutex
+	rates    []storages.ExchangeRate
+	index    map[storages.CurrencyPair]int
+	loadedAt time.Time
+	ttl      time.Duration
+	// version увеличивается при каждой записи: загрузка, начатая до записи, не сохраняется
+	version int64
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	refreshes     atomic.Int64
+	refreshErrors atomic.Int64
+	invalidations atomic.Int64
+}
+
+// New создает кеш курсов поверх storage
+func New(storage storages.Storage, ttl time.Duration, logger *logrus.Logger) *Storage {
+	return &Storage{
+		Storage:    storage,
+		ttl:        ttl,
+		logger:     logger,
+		ttlChanged: make(chan struct{}, 1),
+	}
+}
+
+// Run загружает курсы сразу и затем с интервалом ttl до отмены контекста
+func (s *Storage) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.TTL())
+	defer ticker.Stop()
+
+	for {
+		if err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warnf("Failed to refresh rates cache: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.ttlChanged:
+			ticker.Reset(s.TTL())
+		}
+	}
+}
+
+// TTL возвращает интервал обновления курсов
+func (s *Storage) TTL() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ttl
+}
+
+// SetTTL меняет интервал обновления курсов без перезапуска. Run загружает курсы
+// сразу и продолжает с новым интервалом
+func (s *Storage) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+
+	select {
+	case s.ttlChanged <- struct{}{}:
+	default:
+	}
+}
+
+// GetExchangeRate возвращает курс пары активных валют из кеша
+func (s *Storage) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*storages.ExchangeRate, error) {
+	if err := s.ensureFresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, ok := s.index[storages.CurrencyPair{FromCurrency: fromCurrency, ToCurrency: toCurrency}]
+	if !ok {
+		return nil, fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, fromCurrency, toCurrency)
+	}
+	rate := s.rates[i]
+	return &rate, nil
+}
+
+// GetAllExchangeRates возвращает копию всех курсов активных валют из кеша
+func (s *Storage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
+	if err := s.ensureFresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]storages.ExchangeRate(nil), s.rates...), nil
+}
+
+// UpdateExchangeRate обновляет курс и сбрасывает кеш
+func (s *Storage) UpdateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	defer s.invalidate()
+	return s.Storage.UpdateExchangeRate(ctx, rate)
+}
+
+// CreateExchangeRate создает курс и сбрасывает кеш
+func (s *Storage) CreateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	defer s.invalidate()
+	return s.Storage.CreateExchangeRate(ctx, rate)
+}
+
+// UpsertExchangeRates обновляет курсы и сбрасывает кеш
+func (s *Storage) UpsertExchangeRates(ctx context.Context, rates []storages.ExchangeRate) error {
+	defer s.invalidate()
+	return s.Storage.UpsertExchangeRates(ctx, rates)
+}
+
+// CreateCurrency добавляет валюту и сбрасывает кеш
+func (s *Storage) CreateCurrency(ctx context.Context, currency *storages.Currency) error {
+	defer s.invalidate()
+	return s.Storage.CreateCurrency(ctx, currency)
+}
+
+// SetCurrencyActive включает или отключает валюту и сбрасывает кеш:
+// курсы отключенной валюты не возвращаются
+func (s *Storage) SetCurrencyActive(ctx context.Context, code string, active bool) error {
+	defer s.invalidate()
+	return s.Storage.SetCurrencyActive(ctx, code, active)
+}
+
+// Stats возвращает метрики кеша
+func (s *Storage) Stats() Stats {
+	s.mu.RLock()
+	rates := len(s.rates)
+	s.mu.RUnlock()
+
+	stats := Stats{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Refreshes:     s.refreshes.Load(),
+		RefreshErrors: s.refreshErrors.Load(),
+		Invalidations: s.invalidations.Load(),
+		Rates:         rates,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// ensureFresh загружает курсы, если кеш сброшен или устарел
+func (s *Storage) ensureFresh(ctx context.Context) error {
+	if s.fresh() {
+		s.hits.Add(1)
+		return nil
+	}
+
+	s.misses.Add(1)
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	// Курсы могли загрузить, пока вызов ждал loadMu
+	if s.fresh() {
+		return nil
+	}
+	return s.load(ctx)
+}
+
+// refresh загружает курсы независимо от состояния кеша
+func (s *Storage) refresh(ctx context.Context) error {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	return s.load(ctx)
+}
+
+// load загружает все курсы из хранилища. Вызывается под loadMu
+func (s *Storage) load(ctx context.Context) error {
+	s.mu.RLock()
+	version := s.version
+	s.mu.RUnlock()
+
+	s.refreshes.Add(1)
+	rates, err := s.Storage.GetAllExchangeRates(ctx)
+	if err != nil {
+		s.refreshErrors.Add(1)
+		return err
+	}
+
+	index := make(map[storages.CurrencyPair]int, len(rates))
+	for i, rate := range rates {
+		index[storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}] = i
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates = rates
+	s.index = index
+	// Запись во время загрузки: курсы отдаются, но следующее чтение загрузит их снова
+	if s.version == version {
+		s.loadedAt = time.Now()
+	}
+	return nil
+}
+
+// fresh проверяет, что курсы загружены не раньше ttl назад и не сброшены записью
+func (s *Storage) fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return !s.loadedAt.IsZero() && time.Since(s.loadedAt) <= s.ttl
+}
+
+// invalidate сбрасывает кеш: следующее чтение загрузит курсы из хранилища
+func (s *Storage) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.loadedAt = time.Time{}
+	s.invalidations.Add(1)
+}
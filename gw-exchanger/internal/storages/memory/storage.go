@@ -0,0 +1,305 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-exchanger/internal/storages"
+)
+
+// MemoryStorage реализует интерфейс Storage в памяти процесса. Используется
+// для запуска без БД (STORAGE_DRIVER=memory) и нагрузочными тестами как эталон
+// без затрат на БД: разница с PostgreSQL показывает, сколько времени запроса
+// уходит в хранилище
+type MemoryStorage struct {
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	nextID      int64
+	rates       map[storages.CurrencyPair]storages.ExchangeRate
+	history     map[storages.CurrencyPair][]storages.RatePoint
+	currencies  map[string]storages.Currency
+	callerPairs map[string][]storages.CurrencyPair
+}
+
+// New создает хранилище с начальными валютами и курсами, как у пустой БД
+func New(logger *logrus.Logger) *MemoryStorage {
+	s := &MemoryStorage{
+		logger:      logger,
+		rates:       make(map[storages.CurrencyPair]storages.ExchangeRate),
+		history:     make(map[storages.CurrencyPair][]storages.RatePoint),
+		currencies:  make(map[string]storages.Currency),
+		callerPairs: make(map[string][]storages.CurrencyPair),
+	}
+
+	now := time.Now()
+	for _, currency := range storages.SeedCurrencies {
+		currency.ID = s.newID()
+		currency.CreatedAt = now
+		s.currencies[currency.Code] = currency
+	}
+	for _, rate := range storages.SeedExchangeRates {
+		rate.ID = s.newID()
+		rate.CreatedAt = now
+		rate.UpdatedAt = now
+		s.rates[pairOf(&rate)] = rate
+		s.history[pairOf(&rate)] = []storages.RatePoint{{Rate: rate.Rate, RecordedAt: now}}
+	}
+
+	logger.Info("Using in-memory storage")
+	return s
+}
+
+// GetExchangeRate возвращает курс обмена для пары активных валют
+func (s *MemoryStorage) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*storages.ExchangeRate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rate, ok := s.rates[storages.CurrencyPair{FromCurrency: fromCurrency, ToCurrency: toCurrency}]
+	if !ok || !s.activeLocked(&rate) {
+		return nil, fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, fromCurrency, toCurrency)
+	}
+	return &rate, nil
+}
+
+// GetAllExchangeRates возвращает курсы активных валют в порядке пар
+func (s *MemoryStorage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rates := make([]storages.ExchangeRate, 0, len(s.rates))
+	for _, rate := range s.rates {
+		if s.activeLocked(&rate) {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].FromCurrency != rates[j].FromCurrency {
+			return rates[i].FromCurrency < rates[j].FromCurrency
+		}
+		return rates[i].ToCurrency < rates[j].ToCurrency
+	})
+
+	return rates, nil
+}
+
+// UpdateExchangeRate обновляет существующий курс обмена
+func (s *MemoryStorage) UpdateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.rates[pairOf(rate)]
+	if !ok {
+		return fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, rate.FromCurrency, rate.ToCurrency)
+	}
+
+	now := time.Now()
+	s.recordLocked(&existing, rate.Rate, now)
+	existing.Rate = rate.Rate
+	existing.UpdatedAt = now
+	s.rates[pairOf(rate)] = existing
+	return nil
+}
+
+// CreateExchangeRate создает новый курс обмена
+func (s *MemoryStorage) CreateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rates[pairOf(rate)]; ok {
+		return fmt.Errorf("exchange rate %w for %s to %s", storages.ErrDuplicate, rate.FromCurrency, rate.ToCurrency)
+	}
+
+	now := time.Now()
+	rate.ID = s.newID()
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+	s.rates[pairOf(rate)] = *rate
+	s.history[pairOf(rate)] = append(s.history[pairOf(rate)], storages.RatePoint{Rate: rate.Rate, RecordedAt: now})
+	return nil
+}
+
+// UpsertExchangeRates обновляет курсы и создает отсутствующие пары
+func (s *MemoryStorage) UpsertExchangeRates(ctx context.Context, rates []storages.ExchangeRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, rate := range rates {
+		existing, ok := s.rates[pairOf(&rate)]
+		if !ok {
+			existing = rate
+			existing.ID = s.newID()
+			existing.CreatedAt = now
+			existing.Rate = 0
+		}
+		s.recordLocked(&existing, rate.Rate, now)
+		existing.Rate = rate.Rate
+		existing.UpdatedAt = now
+		s.rates[pairOf(&rate)] = existing
+	}
+	return nil
+}
+
+// GetRateHistory возвращает историю курса пары в (since, until] и последнее значение до since
+func (s *MemoryStorage) GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, since, until time.Time) ([]storages.RatePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var points []storages.RatePoint
+	for _, point := range s.history[storages.CurrencyPair{FromCurrency: fromCurrency, ToCurrency: toCurrency}] {
+		switch {
+		case !point.RecordedAt.After(since):
+			points = append(points[:0], point)
+		case !point.RecordedAt.After(until):
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+// RecordRateHistory добавляет значение курса пары в историю на момент at, не меняя
+// текущий курс. Заполняет историю за прошедшие дни в тестах
+func (s *MemoryStorage) RecordRateHistory(fromCurrency, toCurrency string, rate float64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pair := storages.CurrencyPair{FromCurrency: fromCurrency, ToCurrency: toCurrency}
+	history := append(s.history[pair], storages.RatePoint{Rate: rate, RecordedAt: at})
+	sort.SliceStable(history, func(i, j int) bool { return history[i].RecordedAt.Before(history[j].RecordedAt) })
+	s.history[pair] = history
+}
+
+// GetAllCurrencies возвращает валюты по коду; неактивные только при includeInactive
+func (s *MemoryStorage) GetAllCurrencies(ctx context.Context, includeInactive bool) ([]storages.Currency, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	currencies := make([]storages.Currency, 0, len(s.currencies))
+	for _, currency := range s.currencies {
+		if currency.IsActive || includeInactive {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+
+	return currencies, nil
+}
+
+// GetCurrency возвращает валюту по коду
+func (s *MemoryStorage) GetCurrency(ctx context.Context, code string) (*storages.Currency, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	currency, ok := s.currencies[code]
+	if !ok {
+		return nil, fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+	return &currency, nil
+}
+
+// CreateCurrency добавляет новую валюту
+func (s *MemoryStorage) CreateCurrency(ctx context.Context, currency *storages.Currency) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.currencies[currency.Code]; ok {
+		return fmt.Errorf("currency %w: %s", storages.ErrDuplicate, currency.Code)
+	}
+
+	currency.ID = s.newID()
+	currency.CreatedAt = time.Now()
+	s.currencies[currency.Code] = *currency
+	return nil
+}
+
+// SetCurrencyActive включает или отключает валюту
+func (s *MemoryStorage) SetCurrencyActive(ctx context.Context, code string, active bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	currency, ok := s.currencies[code]
+	if !ok {
+		return fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+
+	currency.IsActive = active
+	s.currencies[code] = currency
+	return nil
+}
+
+// GetCallerPairs возвращает пары валют, разрешенные вызывающей стороне
+func (s *MemoryStorage) GetCallerPairs(ctx context.Context, caller string) ([]storages.CurrencyPair, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]storages.CurrencyPair(nil), s.callerPairs[caller]...), nil
+}
+
+// SetCallerPairs заменяет список разрешенных пар вызывающей стороны
+func (s *MemoryStorage) SetCallerPairs(ctx context.Context, caller string, pairs []storages.CurrencyPair) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(pairs) == 0 {
+		delete(s.callerPairs, caller)
+		return nil
+	}
+
+	sorted := append([]storages.CurrencyPair(nil), pairs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].FromCurrency != sorted[j].FromCurrency {
+			return sorted[i].FromCurrency < sorted[j].FromCurrency
+		}
+		return sorted[i].ToCurrency < sorted[j].ToCurrency
+	})
+	s.callerPairs[caller] = sorted
+	return nil
+}
+
+// Close ничего не делает: хранилище не держит внешних ресурсов
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+// Ping всегда успешен
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// activeLocked сообщает, что обе валюты курса активны. Курс валюты, которой нет
+// в справочнике, считается активным, как в SQL хранилищах. Вызывается под s.mu
+func (s *MemoryStorage) activeLocked(rate *storages.ExchangeRate) bool {
+	for _, code := range []string{rate.FromCurrency, rate.ToCurrency} {
+		if currency, ok := s.currencies[code]; ok && !currency.IsActive {
+			return false
+		}
+	}
+	return true
+}
+
+// recordLocked добавляет курс rate в историю, если он отличается от текущего курса
+// existing с точностью хранения в БД. Вызывается под s.mu до записи курса
+func (s *MemoryStorage) recordLocked(existing *storages.ExchangeRate, rate float64, now time.Time) {
+	if math.Round(existing.Rate*1e8) == math.Round(rate*1e8) {
+		return
+	}
+	pair := pairOf(existing)
+	s.history[pair] = append(s.history[pair], storages.RatePoint{Rate: rate, RecordedAt: now})
+}
+
+// newID возвращает следующий идентификатор записи. Вызывается под s.mu или при создании
+func (s *MemoryStorage) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// pairOf возвращает пару валют курса
+func pairOf(rate *storages.ExchangeRate) storages.CurrencyPair {
+	return storages.CurrencyPair{FromCurrency: rate.FromCurrency, ToCurrency: rate.ToCurrency}
+}
@@ -12,10 +12,23 @@ type ExchangeRate struct {
 	CreatedAt    time.Time `db:"created_at"`
 }
 
+// RatePoint значение курса пары в истории курсов
+type RatePoint struct {
+	Rate       float64   `db:"rate"`
+	RecordedAt time.Time `db:"recorded_at"`
+}
+
 // Currency представляет поддерживаемую валюту
 type Currency struct {
 	ID        int64     `db:"id"`
 	Code      string    `db:"code"`
 	Name      string    `db:"name"`
+	IsActive  bool      `db:"is_active"`
 	CreatedAt time.Time `db:"created_at"`
 }
+
+// CurrencyPair пара валют, разрешенная вызывающей стороне
+type CurrencyPair struct {
+	FromCurrency string `db:"from_currency"`
+	ToCurrency   string `db:"to_currency"`
+}
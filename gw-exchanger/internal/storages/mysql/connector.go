@@ -0,0 +1,222 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gw-exchanger/internal/storages"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// Config содержит конфигурацию для подключения к MySQL/MariaDB
+type Config struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// MySQLStorage реализует интерфейс Storage для MySQL/MariaDB
+type MySQLStorage struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// New создает новое подключение к MySQL
+func New(cfg *Config, logger *logrus.Logger) (*MySQLStorage, error) {
+	dsnConfig := mysql.NewConfig()
+	dsnConfig.User = cfg.User
+	dsnConfig.Passwd = cfg.Password
+	dsnConfig.Net = "tcp"
+	dsnConfig.Addr = cfg.Host + ":" + strconv.Itoa(cfg.Port)
+	dsnConfig.DBName = cfg.DBName
+	dsnConfig.TLSConfig = tlsConfig(cfg.SSLMode)
+	// TIMESTAMP сканируется в time.Time только с parseTime
+	dsnConfig.ParseTime = true
+	// RowsAffected должен учитывать найденные, а не только измененные строки,
+	// иначе обновление тем же значением выглядит как "не найдено"
+	dsnConfig.ClientFoundRows = true
+
+	db, err := sql.Open("mysql", dsnConfig.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Настройка пула соединений
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Проверка подключения
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.Info("Successfully connected to MySQL")
+
+	storage := &MySQLStorage{
+		db:     db,
+		logger: logger,
+	}
+
+	// Инициализация схемы БД
+	if err := storage.initSchema(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// tlsConfig переводит DB_SSLMODE в терминах PostgreSQL в параметр tls драйвера MySQL
+func tlsConfig(sslMode string) string {
+	switch sslMode {
+	case "", "disable":
+		return "false"
+	case "verify-ca", "verify-full":
+		return "true"
+	default:
+		return "skip-verify"
+	}
+}
+
+// initSchema создает необходимые таблицы, если они не существуют.
+// Драйвер не выполняет несколько запросов за раз, поэтому они идут по одному
+func (s *MySQLStorage) initSchema(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS currencies (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			code VARCHAR(3) NOT NULL UNIQUE,
+			name VARCHAR(100) NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS exchange_rates (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			from_currency VARCHAR(3) NOT NULL,
+			to_currency VARCHAR(3) NOT NULL,
+			rate DECIMAL(20, 8) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE KEY idx_exchange_rates_currencies (from_currency, to_currency)
+		)`,
+		`CREATE TABLE IF NOT EXISTS exchange_rate_history (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			from_currency VARCHAR(3) NOT NULL,
+			to_currency VARCHAR(3) NOT NULL,
+			rate DECIMAL(20, 8) NOT NULL,
+			recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			INDEX idx_exchange_rate_history_pair (from_currency, to_currency, recorded_at)
+		)`,
+		`CREATE TABLE IF NOT EXISTS caller_pairs (
+			caller VARCHAR(64) NOT NULL,
+			from_currency VARCHAR(3) NOT NULL,
+			to_currency VARCHAR(3) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (caller, from_currency, to_currency)
+		)`,
+	}
+
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	s.logger.Info("Database schema initialized")
+
+	// Добавляем начальные данные, если таблица пустая
+	if err := s.seedInitialData(ctx); err != nil {
+		return err
+	}
+
+	return s.seedRateHistory(ctx)
+}
+
+// seedRateHistory записывает текущие курсы в пустую историю курсов: базы,
+// созданные до появления истории, и курсы начальных данных
+func (s *MySQLStorage) seedRateHistory(ctx context.Context) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM exchange_rate_history").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count rate history: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+		SELECT from_currency, to_currency, rate, COALESCE(updated_at, CURRENT_TIMESTAMP)
+		FROM exchange_rates
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to seed rate history: %w", err)
+	}
+	return nil
+}
+
+// seedInitialData добавляет начальные данные о валютах и курсах
+func (s *MySQLStorage) seedInitialData(ctx context.Context) error {
+	// Проверяем, есть ли уже данные
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM currencies").Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		s.logger.Info("Database already contains data, skipping seed")
+		return nil
+	}
+
+	// Добавляем валюты
+	for _, curr := range storages.SeedCurrencies {
+		_, err := s.db.ExecContext(ctx,
+			"INSERT IGNORE INTO currencies (code, name) VALUES (?, ?)",
+			curr.Code, curr.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert currency %s: %w", curr.Code, err)
+		}
+	}
+
+	// Добавляем начальные курсы обмена
+	for _, rate := range storages.SeedExchangeRates {
+		_, err := s.db.ExecContext(ctx,
+			"INSERT IGNORE INTO exchange_rates (from_currency, to_currency, rate) VALUES (?, ?, ?)",
+			rate.FromCurrency, rate.ToCurrency, rate.Rate,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert rate %s->%s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+	}
+
+	s.logger.Info("Initial data seeded successfully")
+	return nil
+}
+
+// Close закрывает соединение с базой данных
+func (s *MySQLStorage) Close() error {
+	if s.db != nil {
+		s.logger.Info("Closing database connection")
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Ping проверяет соединение с базой данных
+func (s *MySQLStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
@@ -0,0 +1,16 @@
+package mysql
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// duplicateEntry код ошибки MySQL ER_DUP_ENTRY при нарушении ограничения уникальности
+const duplicateEntry = 1062
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением ограничения уникальности
+func isUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry
+}
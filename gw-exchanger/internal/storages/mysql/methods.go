@@ -0,0 +1,504 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gw-exchanger/internal/storages"
+)
+
+// insertHistoryQuery добавляет значение курса в историю
+const insertHistoryQuery = `
+	INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+	VALUES (?, ?, ?, ?)
+`
+
+// recordHistoryQuery добавляет значение курса в историю, если оно отличается от
+// текущего курса пары с точностью хранения. Выполняется до записи курса;
+// параметры пары и курса передаются дважды
+const recordHistoryQuery = `
+	INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+	SELECT ?, ?, ?, ? FROM DUAL
+	WHERE NOT EXISTS (
+		SELECT 1 FROM exchange_rates
+		WHERE from_currency = ? AND to_currency = ? AND rate = ROUND(?, 8)
+	)
+`
+
+// GetExchangeRate возвращает курс обмена для конкретной пары валют
+func (s *MySQLStorage) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*storages.ExchangeRate, error) {
+	query := `
+		SELECT id, from_currency, to_currency, rate, updated_at, created_at
+		FROM exchange_rates r
+		WHERE from_currency = ? AND to_currency = ?
+			AND NOT EXISTS (
+				SELECT 1 FROM currencies c
+				WHERE NOT c.is_active AND c.code IN (r.from_currency, r.to_currency)
+			)
+	`
+
+	var rate storages.ExchangeRate
+	err := s.db.QueryRowContext(ctx, query, fromCurrency, toCurrency).Scan(
+		&rate.ID,
+		&rate.FromCurrency,
+		&rate.ToCurrency,
+		&rate.Rate,
+		&rate.UpdatedAt,
+		&rate.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		s.logger.Warnf("Exchange rate not found: %s -> %s", fromCurrency, toCurrency)
+		return nil, fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, fromCurrency, toCurrency)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get exchange rate: %v", err)
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved exchange rate: %s -> %s = %.8f", fromCurrency, toCurrency, rate.Rate)
+	return &rate, nil
+}
+
+// GetAllExchangeRates возвращает все курсы обмена активных валют
+func (s *MySQLStorage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
+	query := `
+		SELECT id, from_currency, to_currency, rate, updated_at, created_at
+		FROM exchange_rates r
+		-- Курсы с участием отключенных валют не возвращаются
+		WHERE NOT EXISTS (
+			SELECT 1 FROM currencies c
+			WHERE NOT c.is_active AND c.code IN (r.from_currency, r.to_currency)
+		)
+		ORDER BY from_currency, to_currency
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		s.logger.Errorf("Failed to query exchange rates: %v", err)
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []storages.ExchangeRate
+	for rows.Next() {
+		var rate storages.ExchangeRate
+		err := rows.Scan(
+			&rate.ID,
+			&rate.FromCurrency,
+			&rate.ToCurrency,
+			&rate.Rate,
+			&rate.UpdatedAt,
+			&rate.CreatedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan exchange rate: %v", err)
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating exchange rates: %v", err)
+		return nil, fmt.Errorf("error iterating exchange rates: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d exchange rates", len(rates))
+	return rates, nil
+}
+
+// UpdateExchangeRate обновляет курс обмена и записывает изменившийся курс в историю
+func (s *MySQLStorage) UpdateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, recordHistoryQuery, rate.FromCurrency, rate.ToCurrency, rate.Rate, now, rate.FromCurrency, rate.ToCurrency, rate.Rate); err != nil {
+		s.logger.Errorf("Failed to record exchange rate history: %v", err)
+		return fmt.Errorf("failed to record exchange rate history: %w", err)
+	}
+
+	query := `
+		UPDATE exchange_rates
+		SET rate = ?, updated_at = ?
+		WHERE from_currency = ? AND to_currency = ?
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		rate.Rate,
+		now,
+		rate.FromCurrency,
+		rate.ToCurrency,
+	)
+
+	if err != nil {
+		s.logger.Errorf("Failed to update exchange rate: %v", err)
+		return fmt.Errorf("failed to update exchange rate: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		s.logger.Warnf("No rows updated for %s -> %s", rate.FromCurrency, rate.ToCurrency)
+		return fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, rate.FromCurrency, rate.ToCurrency)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Updated exchange rate: %s -> %s = %.8f", rate.FromCurrency, rate.ToCurrency, rate.Rate)
+	return nil
+}
+
+// CreateExchangeRate создает новый курс обмена и записывает его в историю
+func (s *MySQLStorage) CreateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, query,
+		rate.FromCurrency,
+		rate.ToCurrency,
+		rate.Rate,
+		now,
+		now,
+	)
+
+	if err != nil {
+		s.logger.Errorf("Failed to create exchange rate: %v", err)
+		return fmt.Errorf("failed to create exchange rate: %w", err)
+	}
+
+	// MySQL не поддерживает RETURNING
+	rate.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get inserted id: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, insertHistoryQuery, rate.FromCurrency, rate.ToCurrency, rate.Rate, now); err != nil {
+		s.logger.Errorf("Failed to record exchange rate history: %v", err)
+		return fmt.Errorf("failed to record exchange rate history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+
+	s.logger.Infof("Created exchange rate: %s -> %s = %.8f (ID: %d)",
+		rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.ID)
+	return nil
+}
+
+// UpsertExchangeRates обновляет курсы и создает отсутствующие пары в одной транзакции,
+// изменившиеся курсы записываются в историю
+func (s *MySQLStorage) UpsertExchangeRates(ctx context.Context, rates []storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE rate = VALUES(rate), updated_at = VALUES(updated_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	historyStmt, err := tx.PrepareContext(ctx, recordHistoryQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare history insert: %w", err)
+	}
+	defer historyStmt.Close()
+
+	now := time.Now()
+	for _, rate := range rates {
+		// История записывается до обновления: сравнение идет с прежним курсом
+		if _, err := historyStmt.ExecContext(ctx, rate.FromCurrency, rate.ToCurrency, rate.Rate, now, rate.FromCurrency, rate.ToCurrency, rate.Rate); err != nil {
+			s.logger.Errorf("Failed to record exchange rate history: %v", err)
+			return fmt.Errorf("failed to record exchange rate history %s -> %s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+		if _, err := stmt.ExecContext(ctx, rate.FromCurrency, rate.ToCurrency, rate.Rate, now, now); err != nil {
+			s.logger.Errorf("Failed to upsert exchange rate: %v", err)
+			return fmt.Errorf("failed to upsert exchange rate %s -> %s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Upserted %d exchange rates", len(rates))
+	return nil
+}
+
+// GetRateHistory возвращает историю курса пары в (since, until] и последнее значение до since
+func (s *MySQLStorage) GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, since, until time.Time) ([]storages.RatePoint, error) {
+	query := `
+		(
+			SELECT id, rate, recorded_at
+			FROM exchange_rate_history
+			WHERE from_currency = ? AND to_currency = ? AND recorded_at <= ?
+			ORDER BY recorded_at DESC, id DESC
+			LIMIT 1
+		)
+		UNION ALL
+		(
+			SELECT id, rate, recorded_at
+			FROM exchange_rate_history
+			WHERE from_currency = ? AND to_currency = ? AND recorded_at > ? AND recorded_at <= ?
+		)
+		ORDER BY recorded_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, fromCurrency, toCurrency, since, fromCurrency, toCurrency, since, until)
+	if err != nil {
+		s.logger.Errorf("Failed to query exchange rate history: %v", err)
+		return nil, fmt.Errorf("failed to query exchange rate history: %w", err)
+	}
+	defer rows.Close()
+
+	var points []storages.RatePoint
+	for rows.Next() {
+		var id int64
+		var point storages.RatePoint
+		if err := rows.Scan(&id, &point.Rate, &point.RecordedAt); err != nil {
+			s.logger.Errorf("Failed to scan exchange rate history: %v", err)
+			return nil, fmt.Errorf("failed to scan exchange rate history: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating exchange rate history: %v", err)
+		return nil, fmt.Errorf("error iterating exchange rate history: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d history points for %s -> %s", len(points), fromCurrency, toCurrency)
+	return points, nil
+}
+
+// GetAllCurrencies возвращает валюты; неактивные только при includeInactive
+func (s *MySQLStorage) GetAllCurrencies(ctx context.Context, includeInactive bool) ([]storages.Currency, error) {
+	query := `
+		SELECT id, code, name, is_active, created_at
+		FROM currencies
+		WHERE is_active OR ?
+		ORDER BY code
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		s.logger.Errorf("Failed to query currencies: %v", err)
+		return nil, fmt.Errorf("failed to query currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var currencies []storages.Currency
+	for rows.Next() {
+		var currency storages.Currency
+		err := rows.Scan(
+			&currency.ID,
+			&currency.Code,
+			&currency.Name,
+			&currency.IsActive,
+			&currency.CreatedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan currency: %v", err)
+			return nil, fmt.Errorf("failed to scan currency: %w", err)
+		}
+		currencies = append(currencies, currency)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating currencies: %v", err)
+		return nil, fmt.Errorf("error iterating currencies: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d currencies", len(currencies))
+	return currencies, nil
+}
+
+// GetCurrency возвращает валюту по коду
+func (s *MySQLStorage) GetCurrency(ctx context.Context, code string) (*storages.Currency, error) {
+	query := `
+		SELECT id, code, name, is_active, created_at
+		FROM currencies
+		WHERE code = ?
+	`
+
+	var currency storages.Currency
+	err := s.db.QueryRowContext(ctx, query, code).Scan(
+		&currency.ID,
+		&currency.Code,
+		&currency.Name,
+		&currency.IsActive,
+		&currency.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		s.logger.Warnf("Currency not found: %s", code)
+		return nil, fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get currency: %v", err)
+		return nil, fmt.Errorf("failed to get currency: %w", err)
+	}
+
+	return &currency, nil
+}
+
+// CreateCurrency добавляет новую валюту
+func (s *MySQLStorage) CreateCurrency(ctx context.Context, currency *storages.Currency) error {
+	query := `
+		INSERT INTO currencies (code, name, is_active, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, query,
+		currency.Code,
+		currency.Name,
+		currency.IsActive,
+		now,
+	)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("currency %w: %s", storages.ErrDuplicate, currency.Code)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to create currency: %v", err)
+		return fmt.Errorf("failed to create currency: %w", err)
+	}
+
+	currency.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get inserted id: %w", err)
+	}
+
+	currency.CreatedAt = now
+
+	s.logger.Infof("Created currency: %s - %s (ID: %d)", currency.Code, currency.Name, currency.ID)
+	return nil
+}
+
+// SetCurrencyActive включает или отключает валюту
+func (s *MySQLStorage) SetCurrencyActive(ctx context.Context, code string, active bool) error {
+	query := `
+		UPDATE currencies
+		SET is_active = ?
+		WHERE code = ?
+	`
+
+	result, err := s.db.ExecContext(ctx, query, active, code)
+	if err != nil {
+		s.logger.Errorf("Failed to update currency: %v", err)
+		return fmt.Errorf("failed to update currency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		s.logger.Warnf("No rows updated for currency %s", code)
+		return fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+
+	s.logger.Infof("Set currency %s active=%t", code, active)
+	return nil
+}
+
+// GetCallerPairs возвращает пары валют, разрешенные вызывающей стороне
+func (s *MySQLStorage) GetCallerPairs(ctx context.Context, caller string) ([]storages.CurrencyPair, error) {
+	query := `
+		SELECT from_currency, to_currency
+		FROM caller_pairs
+		WHERE caller = ?
+		ORDER BY from_currency, to_currency
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, caller)
+	if err != nil {
+		s.logger.Errorf("Failed to query caller pairs: %v", err)
+		return nil, fmt.Errorf("failed to query caller pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []storages.CurrencyPair
+	for rows.Next() {
+		var pair storages.CurrencyPair
+		if err := rows.Scan(&pair.FromCurrency, &pair.ToCurrency); err != nil {
+			s.logger.Errorf("Failed to scan caller pair: %v", err)
+			return nil, fmt.Errorf("failed to scan caller pair: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating caller pairs: %v", err)
+		return nil, fmt.Errorf("error iterating caller pairs: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// SetCallerPairs заменяет список разрешенных пар вызывающей стороны в одной транзакции
+func (s *MySQLStorage) SetCallerPairs(ctx context.Context, caller string, pairs []storages.CurrencyPair) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM caller_pairs WHERE caller = ?", caller); err != nil {
+		s.logger.Errorf("Failed to delete caller pairs: %v", err)
+		return fmt.Errorf("failed to delete caller pairs: %w", err)
+	}
+
+	for _, pair := range pairs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO caller_pairs (caller, from_currency, to_currency) VALUES (?, ?, ?)",
+			caller, pair.FromCurrency, pair.ToCurrency,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to insert caller pair: %v", err)
+			return fmt.Errorf("failed to insert caller pair %s->%s: %w", pair.FromCurrency, pair.ToCurrency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Set %d allowed pairs for caller %s", len(pairs), caller)
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"gw-exchanger/internal/storages"
+
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
@@ -21,6 +23,9 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// MigrateOnStart применяет миграции схемы при подключении. Без него
+	// сервис только предупреждает о непримененных миграциях
+	MigrateOnStart bool
 }
 
 // PostgresStorage реализует интерфейс Storage для PostgreSQL
@@ -29,8 +34,27 @@ type PostgresStorage struct {
 	logger *logrus.Logger
 }
 
-// New создает новое подключение к PostgreSQL
+// New создает новое подключение к PostgreSQL, проверяет схему БД
+// и добавляет начальные данные
 func New(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
+	storage, err := Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := storage.initSchema(ctx, cfg.MigrateOnStart); err != nil {
+		storage.db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// Open подключается к PostgreSQL без проверки схемы (для команды migrate)
+func Open(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
@@ -56,49 +80,32 @@ func New(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
 
 	logger.Info("Successfully connected to PostgreSQL")
 
-	storage := &PostgresStorage{
+	return &PostgresStorage{
 		db:     db,
 		logger: logger,
-	}
+	}, nil
+}
 
-	// Инициализация схемы БД
-	if err := storage.initSchema(ctx); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+// initSchema применяет миграции схемы или, если migrate выключен, проверяет,
+// что все миграции применены. Начальные данные добавляются только в актуальную схему
+func (s *PostgresStorage) initSchema(ctx context.Context, migrate bool) error {
+	if migrate {
+		if err := s.MigrateUp(ctx); err != nil {
+			return err
+		}
 	}
 
-	return storage, nil
-}
-
-// initSchema создает необходимые таблицы, если они не существуют
-func (s *PostgresStorage) initSchema(ctx context.Context) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS currencies (
-		id SERIAL PRIMARY KEY,
-		code VARCHAR(3) UNIQUE NOT NULL,
-		name VARCHAR(100) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS exchange_rates (
-		id SERIAL PRIMARY KEY,
-		from_currency VARCHAR(3) NOT NULL,
-		to_currency VARCHAR(3) NOT NULL,
-		rate NUMERIC(20, 8) NOT NULL,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(from_currency, to_currency)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_exchange_rates_currencies 
-		ON exchange_rates(from_currency, to_currency);
-	`
-
-	_, err := s.db.ExecContext(ctx, schema)
+	status, err := s.MigrationStatus(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database schema version %d is dirty, fix it manually", status.Version)
+	}
+	if status.Pending() {
+		s.logger.Warnf("Database schema is at version %d, latest is %d: run migrate up, skipping seed", status.Version, status.Latest)
+		return nil
 	}
-
-	s.logger.Info("Database schema initialized")
 
 	// Добавляем начальные данные, если таблица пустая
 	return s.seedInitialData(ctx)
@@ -119,46 +126,31 @@ func (s *PostgresStorage) seedInitialData(ctx context.Context) error {
 	}
 
 	// Добавляем валюты
-	currencies := []struct {
-		code string
-		name string
-	}{
-		{"USD", "US Dollar"},
-		{"EUR", "Euro"},
-		{"RUB", "Russian Ruble"},
-	}
-
-	for _, curr := range currencies {
+	for _, curr := range storages.SeedCurrencies {
 		_, err := s.db.ExecContext(ctx,
 			"INSERT INTO currencies (code, name) VALUES ($1, $2) ON CONFLICT (code) DO NOTHING",
-			curr.code, curr.name,
+			curr.Code, curr.Name,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert currency %s: %w", curr.code, err)
+			return fmt.Errorf("failed to insert currency %s: %w", curr.Code, err)
 		}
 	}
 
 	// Добавляем начальные курсы обмена
-	rates := []struct {
-		from string
-		to   string
-		rate float64
-	}{
-		{"USD", "EUR", 0.92},
-		{"USD", "RUB", 92.50},
-		{"EUR", "USD", 1.09},
-		{"EUR", "RUB", 100.54},
-		{"RUB", "USD", 0.0108},
-		{"RUB", "EUR", 0.0099},
-	}
-
-	for _, rate := range rates {
+	for _, rate := range storages.SeedExchangeRates {
 		_, err := s.db.ExecContext(ctx,
 			"INSERT INTO exchange_rates (from_currency, to_currency, rate) VALUES ($1, $2, $3) ON CONFLICT (from_currency, to_currency) DO NOTHING",
-			rate.from, rate.to, rate.rate,
+			rate.FromCurrency, rate.ToCurrency, rate.Rate,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert rate %s->%s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+		_, err = s.db.ExecContext(ctx,
+			"INSERT INTO exchange_rate_history (from_currency, to_currency, rate) VALUES ($1, $2, $3)",
+			rate.FromCurrency, rate.ToCurrency, rate.Rate,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert rate %s->%s: %w", rate.from, rate.to, err)
+			return fmt.Errorf("failed to insert rate history %s->%s: %w", rate.FromCurrency, rate.ToCurrency, err)
 		}
 	}
 
@@ -9,12 +9,33 @@ import (
 	"gw-exchanger/internal/storages"
 )
 
+// insertHistoryQuery добавляет значение курса в историю
+const insertHistoryQuery = `
+	INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+	VALUES ($1, $2, $3, $4)
+`
+
+// recordHistoryQuery добавляет значение курса в историю, если оно отличается от
+// текущего курса пары с точностью хранения. Выполняется до записи курса
+const recordHistoryQuery = `
+	INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+	SELECT $1::VARCHAR, $2::VARCHAR, $3::NUMERIC, $4::TIMESTAMP
+	WHERE NOT EXISTS (
+		SELECT 1 FROM exchange_rates
+		WHERE from_currency = $1 AND to_currency = $2 AND rate = ROUND($3::NUMERIC, 8)
+	)
+`
+
 // GetExchangeRate возвращает курс обмена для конкретной пары валют
 func (s *PostgresStorage) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*storages.ExchangeRate, error) {
 	query := `
 		SELECT id, from_currency, to_currency, rate, updated_at, created_at
-		FROM exchange_rates
+		FROM exchange_rates r
 		WHERE from_currency = $1 AND to_currency = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM currencies c
+				WHERE NOT c.is_active AND c.code IN (r.from_currency, r.to_currency)
+			)
 	`
 
 	var rate storages.ExchangeRate
@@ -29,7 +50,7 @@ func (s *PostgresStorage) GetExchangeRate(ctx context.Context, fromCurrency, toC
 
 	if err == sql.ErrNoRows {
 		s.logger.Warnf("Exchange rate not found: %s -> %s", fromCurrency, toCurrency)
-		return nil, fmt.Errorf("exchange rate not found for %s to %s", fromCurrency, toCurrency)
+		return nil, fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, fromCurrency, toCurrency)
 	}
 
 	if err != nil {
@@ -41,11 +62,16 @@ func (s *PostgresStorage) GetExchangeRate(ctx context.Context, fromCurrency, toC
 	return &rate, nil
 }
 
-// GetAllExchangeRates возвращает все курсы обмена
+// GetAllExchangeRates возвращает все курсы обмена активных валют
 func (s *PostgresStorage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
 	query := `
 		SELECT id, from_currency, to_currency, rate, updated_at, created_at
-		FROM exchange_rates
+		FROM exchange_rates r
+		-- Курсы с участием отключенных валют не возвращаются
+		WHERE NOT EXISTS (
+			SELECT 1 FROM currencies c
+			WHERE NOT c.is_active AND c.code IN (r.from_currency, r.to_currency)
+		)
 		ORDER BY from_currency, to_currency
 	`
 
@@ -83,17 +109,29 @@ func (s *PostgresStorage) GetAllExchangeRates(ctx context.Context) ([]storages.E
 	return rates, nil
 }
 
-// UpdateExchangeRate обновляет существующий курс обмена
+// UpdateExchangeRate обновляет курс обмена и записывает изменившийся курс в историю
 func (s *PostgresStorage) UpdateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, recordHistoryQuery, rate.FromCurrency, rate.ToCurrency, rate.Rate, now); err != nil {
+		s.logger.Errorf("Failed to record exchange rate history: %v", err)
+		return fmt.Errorf("failed to record exchange rate history: %w", err)
+	}
+
 	query := `
 		UPDATE exchange_rates
 		SET rate = $1, updated_at = $2
 		WHERE from_currency = $3 AND to_currency = $4
 	`
 
-	result, err := s.db.ExecContext(ctx, query,
+	result, err := tx.ExecContext(ctx, query,
 		rate.Rate,
-		time.Now(),
+		now,
 		rate.FromCurrency,
 		rate.ToCurrency,
 	)
@@ -110,15 +148,25 @@ func (s *PostgresStorage) UpdateExchangeRate(ctx context.Context, rate *storages
 
 	if rowsAffected == 0 {
 		s.logger.Warnf("No rows updated for %s -> %s", rate.FromCurrency, rate.ToCurrency)
-		return fmt.Errorf("exchange rate not found for %s to %s", rate.FromCurrency, rate.ToCurrency)
+		return fmt.Errorf("exchange rate %w for %s to %s", storages.ErrNotFound, rate.FromCurrency, rate.ToCurrency)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	s.logger.Infof("Updated exchange rate: %s -> %s = %.8f", rate.FromCurrency, rate.ToCurrency, rate.Rate)
 	return nil
 }
 
-// CreateExchangeRate создает новый курс обмена
+// CreateExchangeRate создает новый курс обмена и записывает его в историю
 func (s *PostgresStorage) CreateExchangeRate(ctx context.Context, rate *storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO exchange_rates (from_currency, to_currency, rate, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5)
@@ -126,7 +174,7 @@ func (s *PostgresStorage) CreateExchangeRate(ctx context.Context, rate *storages
 	`
 
 	now := time.Now()
-	err := s.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		rate.FromCurrency,
 		rate.ToCurrency,
 		rate.Rate,
@@ -139,6 +187,15 @@ func (s *PostgresStorage) CreateExchangeRate(ctx context.Context, rate *storages
 		return fmt.Errorf("failed to create exchange rate: %w", err)
 	}
 
+	if _, err := tx.ExecContext(ctx, insertHistoryQuery, rate.FromCurrency, rate.ToCurrency, rate.Rate, now); err != nil {
+		s.logger.Errorf("Failed to record exchange rate history: %v", err)
+		return fmt.Errorf("failed to record exchange rate history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	rate.CreatedAt = now
 	rate.UpdatedAt = now
 
@@ -146,3 +203,293 @@ func (s *PostgresStorage) CreateExchangeRate(ctx context.Context, rate *storages
 		rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.ID)
 	return nil
 }
+
+// UpsertExchangeRates обновляет курсы и создает отсутствующие пары в одной транзакции,
+// изменившиеся курсы записываются в историю
+func (s *PostgresStorage) UpsertExchangeRates(ctx context.Context, rates []storages.ExchangeRate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (from_currency, to_currency)
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	historyStmt, err := tx.PrepareContext(ctx, recordHistoryQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare history insert: %w", err)
+	}
+	defer historyStmt.Close()
+
+	now := time.Now()
+	for _, rate := range rates {
+		// История записывается до обновления: сравнение идет с прежним курсом
+		if _, err := historyStmt.ExecContext(ctx, rate.FromCurrency, rate.ToCurrency, rate.Rate, now); err != nil {
+			s.logger.Errorf("Failed to record exchange rate history: %v", err)
+			return fmt.Errorf("failed to record exchange rate history %s -> %s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+		if _, err := stmt.ExecContext(ctx, rate.FromCurrency, rate.ToCurrency, rate.Rate, now); err != nil {
+			s.logger.Errorf("Failed to upsert exchange rate: %v", err)
+			return fmt.Errorf("failed to upsert exchange rate %s -> %s: %w", rate.FromCurrency, rate.ToCurrency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Upserted %d exchange rates", len(rates))
+	return nil
+}
+
+// GetRateHistory возвращает историю курса пары в (since, until] и последнее значение до since
+func (s *PostgresStorage) GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, since, until time.Time) ([]storages.RatePoint, error) {
+	query := `
+		(
+			SELECT id, rate, recorded_at
+			FROM exchange_rate_history
+			WHERE from_currency = $1 AND to_currency = $2 AND recorded_at <= $3
+			ORDER BY recorded_at DESC, id DESC
+			LIMIT 1
+		)
+		UNION ALL
+		(
+			SELECT id, rate, recorded_at
+			FROM exchange_rate_history
+			WHERE from_currency = $1 AND to_currency = $2 AND recorded_at > $3 AND recorded_at <= $4
+		)
+		ORDER BY recorded_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, fromCurrency, toCurrency, since, until)
+	if err != nil {
+		s.logger.Errorf("Failed to query exchange rate history: %v", err)
+		return nil, fmt.Errorf("failed to query exchange rate history: %w", err)
+	}
+	defer rows.Close()
+
+	var points []storages.RatePoint
+	for rows.Next() {
+		var id int64
+		var point storages.RatePoint
+		if err := rows.Scan(&id, &point.Rate, &point.RecordedAt); err != nil {
+			s.logger.Errorf("Failed to scan exchange rate history: %v", err)
+			return nil, fmt.Errorf("failed to scan exchange rate history: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating exchange rate history: %v", err)
+		return nil, fmt.Errorf("error iterating exchange rate history: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d history points for %s -> %s", len(points), fromCurrency, toCurrency)
+	return points, nil
+}
+
+// GetAllCurrencies возвращает валюты; неактивные только при includeInactive
+func (s *PostgresStorage) GetAllCurrencies(ctx context.Context, includeInactive bool) ([]storages.Currency, error) {
+	query := `
+		SELECT id, code, name, is_active, created_at
+		FROM currencies
+		WHERE is_active OR $1
+		ORDER BY code
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		s.logger.Errorf("Failed to query currencies: %v", err)
+		return nil, fmt.Errorf("failed to query currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var currencies []storages.Currency
+	for rows.Next() {
+		var currency storages.Currency
+		err := rows.Scan(
+			&currency.ID,
+			&currency.Code,
+			&currency.Name,
+			&currency.IsActive,
+			&currency.CreatedAt,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to scan currency: %v", err)
+			return nil, fmt.Errorf("failed to scan currency: %w", err)
+		}
+		currencies = append(currencies, currency)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating currencies: %v", err)
+		return nil, fmt.Errorf("error iterating currencies: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d currencies", len(currencies))
+	return currencies, nil
+}
+
+// GetCurrency возвращает валюту по коду
+func (s *PostgresStorage) GetCurrency(ctx context.Context, code string) (*storages.Currency, error) {
+	query := `
+		SELECT id, code, name, is_active, created_at
+		FROM currencies
+		WHERE code = $1
+	`
+
+	var currency storages.Currency
+	err := s.db.QueryRowContext(ctx, query, code).Scan(
+		&currency.ID,
+		&currency.Code,
+		&currency.Name,
+		&currency.IsActive,
+		&currency.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		s.logger.Warnf("Currency not found: %s", code)
+		return nil, fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to get currency: %v", err)
+		return nil, fmt.Errorf("failed to get currency: %w", err)
+	}
+
+	return &currency, nil
+}
+
+// CreateCurrency добавляет новую валюту
+func (s *PostgresStorage) CreateCurrency(ctx context.Context, currency *storages.Currency) error {
+	query := `
+		INSERT INTO currencies (code, name, is_active, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := s.db.QueryRowContext(ctx, query,
+		currency.Code,
+		currency.Name,
+		currency.IsActive,
+		now,
+	).Scan(&currency.ID)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("currency %w: %s", storages.ErrDuplicate, currency.Code)
+	}
+
+	if err != nil {
+		s.logger.Errorf("Failed to create currency: %v", err)
+		return fmt.Errorf("failed to create currency: %w", err)
+	}
+
+	currency.CreatedAt = now
+
+	s.logger.Infof("Created currency: %s - %s (ID: %d)", currency.Code, currency.Name, currency.ID)
+	return nil
+}
+
+// SetCurrencyActive включает или отключает валюту
+func (s *PostgresStorage) SetCurrencyActive(ctx context.Context, code string, active bool) error {
+	query := `
+		UPDATE currencies
+		SET is_active = $1
+		WHERE code = $2
+	`
+
+	result, err := s.db.ExecContext(ctx, query, active, code)
+	if err != nil {
+		s.logger.Errorf("Failed to update currency: %v", err)
+		return fmt.Errorf("failed to update currency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		s.logger.Warnf("No rows updated for currency %s", code)
+		return fmt.Errorf("currency %w: %s", storages.ErrNotFound, code)
+	}
+
+	s.logger.Infof("Set currency %s active=%t", code, active)
+	return nil
+}
+
+// GetCallerPairs возвращает пары валют, разрешенные вызывающей стороне
+func (s *PostgresStorage) GetCallerPairs(ctx context.Context, caller string) ([]storages.CurrencyPair, error) {
+	query := `
+		SELECT from_currency, to_currency
+		FROM caller_pairs
+		WHERE caller = $1
+		ORDER BY from_currency, to_currency
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, caller)
+	if err != nil {
+		s.logger.Errorf("Failed to query caller pairs: %v", err)
+		return nil, fmt.Errorf("failed to query caller pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []storages.CurrencyPair
+	for rows.Next() {
+		var pair storages.CurrencyPair
+		if err := rows.Scan(&pair.FromCurrency, &pair.ToCurrency); err != nil {
+			s.logger.Errorf("Failed to scan caller pair: %v", err)
+			return nil, fmt.Errorf("failed to scan caller pair: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.logger.Errorf("Error iterating caller pairs: %v", err)
+		return nil, fmt.Errorf("error iterating caller pairs: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// SetCallerPairs заменяет список разрешенных пар вызывающей стороны в одной транзакции
+func (s *PostgresStorage) SetCallerPairs(ctx context.Context, caller string, pairs []storages.CurrencyPair) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM caller_pairs WHERE caller = $1", caller); err != nil {
+		s.logger.Errorf("Failed to delete caller pairs: %v", err)
+		return fmt.Errorf("failed to delete caller pairs: %w", err)
+	}
+
+	for _, pair := range pairs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO caller_pairs (caller, from_currency, to_currency) VALUES ($1, $2, $3)",
+			caller, pair.FromCurrency, pair.ToCurrency,
+		)
+		if err != nil {
+			s.logger.Errorf("Failed to insert caller pair: %v", err)
+			return fmt.Errorf("failed to insert caller pair %s->%s: %w", pair.FromCurrency, pair.ToCurrency, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Infof("Set %d allowed pairs for caller %s", len(pairs), caller)
+	return nil
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS caller_pairs;
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS currencies;
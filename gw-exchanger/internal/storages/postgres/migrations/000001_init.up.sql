@@ -0,0 +1,33 @@
+-- Базовая схема. IF NOT EXISTS позволяет применить миграцию к базе,
+-- созданной до перехода на миграции
+CREATE TABLE IF NOT EXISTS currencies (
+	id SERIAL PRIMARY KEY,
+	code VARCHAR(3) UNIQUE NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Колонка, добавленная в схему до перехода на миграции
+ALTER TABLE currencies ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	id SERIAL PRIMARY KEY,
+	from_currency VARCHAR(3) NOT NULL,
+	to_currency VARCHAR(3) NOT NULL,
+	rate NUMERIC(20, 8) NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(from_currency, to_currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currencies
+	ON exchange_rates(from_currency, to_currency);
+
+CREATE TABLE IF NOT EXISTS caller_pairs (
+	caller VARCHAR(64) NOT NULL,
+	from_currency VARCHAR(3) NOT NULL,
+	to_currency VARCHAR(3) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (caller, from_currency, to_currency)
+);
@@ -0,0 +1,16 @@
+-- История курсов: значение записывается при каждом изменении курса пары.
+-- Текущие курсы становятся первыми записями истории
+CREATE TABLE IF NOT EXISTS exchange_rate_history (
+	id BIGSERIAL PRIMARY KEY,
+	from_currency VARCHAR(3) NOT NULL,
+	to_currency VARCHAR(3) NOT NULL,
+	rate NUMERIC(20, 8) NOT NULL,
+	recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_history_pair
+	ON exchange_rate_history(from_currency, to_currency, recorded_at);
+
+INSERT INTO exchange_rate_history (from_currency, to_currency, rate, recorded_at)
+SELECT from_currency, to_currency, rate, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM exchange_rates;
@@ -0,0 +1,18 @@
+package storages
+
+// SeedCurrencies валюты, добавляемые при первом запуске в пустую БД
+var SeedCurrencies = []Currency{
+	{Code: "USD", Name: "US Dollar", IsActive: true},
+	{Code: "EUR", Name: "Euro", IsActive: true},
+	{Code: "RUB", Name: "Russian Ruble", IsActive: true},
+}
+
+// SeedExchangeRates начальные курсы обмена для SeedCurrencies
+var SeedExchangeRates = []ExchangeRate{
+	{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.92},
+	{FromCurrency: "USD", ToCurrency: "RUB", Rate: 92.50},
+	{FromCurrency: "EUR", ToCurrency: "USD", Rate: 1.09},
+	{FromCurrency: "EUR", ToCurrency: "RUB", Rate: 100.54},
+	{FromCurrency: "RUB", ToCurrency: "USD", Rate: 0.0108},
+	{FromCurrency: "RUB", ToCurrency: "EUR", Rate: 0.0099},
+}
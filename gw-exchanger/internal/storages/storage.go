@@ -1,14 +1,17 @@
 package storages
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Storage определяет интерфейс для работы с хранилищем данных
 // Это позволяет легко заменить PostgreSQL на другую БД
 type Storage interface {
-	// GetExchangeRate возвращает курс обмена для конкретной пары валют
+	// GetExchangeRate возвращает курс обмена для конкретной пары активных валют
 	GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (*ExchangeRate, error)
 
-	// GetAllExchangeRates возвращает все курсы обмена
+	// GetAllExchangeRates возвращает все курсы обмена, кроме курсов отключенных валют
 	GetAllExchangeRates(ctx context.Context) ([]ExchangeRate, error)
 
 	// UpdateExchangeRate обновляет курс обмена
@@ -17,6 +20,34 @@ type Storage interface {
 	// CreateExchangeRate создает новый курс обмена
 	CreateExchangeRate(ctx context.Context, rate *ExchangeRate) error
 
+	// UpsertExchangeRates обновляет курсы и создает отсутствующие пары в одной транзакции
+	UpsertExchangeRates(ctx context.Context, rates []ExchangeRate) error
+
+	// GetRateHistory возвращает значения курса пары, записанные в историю в (since, until],
+	// по возрастанию времени, и первым - последнее значение до since, если оно есть.
+	// Методы записи курсов добавляют значение в историю, только если курс изменился
+	GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, since, until time.Time) ([]RatePoint, error)
+
+	// GetAllCurrencies возвращает валюты; неактивные только при includeInactive
+	GetAllCurrencies(ctx context.Context, includeInactive bool) ([]Currency, error)
+
+	// GetCurrency возвращает валюту по коду
+	GetCurrency(ctx context.Context, code string) (*Currency, error)
+
+	// CreateCurrency добавляет новую валюту
+	CreateCurrency(ctx context.Context, currency *Currency) error
+
+	// SetCurrencyActive включает или отключает валюту
+	SetCurrencyActive(ctx context.Context, code string, active bool) error
+
+	// GetCallerPairs возвращает пары валют, разрешенные вызывающей стороне.
+	// Пустой список означает отсутствие ограничений
+	GetCallerPairs(ctx context.Context, caller string) ([]CurrencyPair, error)
+
+	// SetCallerPairs заменяет список разрешенных пар вызывающей стороны.
+	// Пустой список снимает ограничения
+	SetCallerPairs(ctx context.Context, caller string, pairs []CurrencyPair) error
+
 	// Close закрывает соединение с БД
 	Close() error
 
@@ -0,0 +1,1451 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+	grpcServer "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gw-common/buildinfo"
+	"gw-common/configfile"
+	"gw-common/env"
+	"gw-common/errcodes"
+	exchangerlogger "gw-common/logger"
+	"gw-exchanger/internal/app"
+	"gw-exchanger/internal/config"
+	"gw-exchanger/internal/events"
+	"gw-exchanger/internal/exchctl"
+	"gw-exchanger/internal/grpc"
+	"gw-exchanger/internal/importer"
+	"gw-exchanger/internal/kafka"
+	"gw-exchanger/internal/providers"
+	"gw-exchanger/internal/storages"
+	"gw-exchanger/internal/storages/cache"
+	"gw-exchanger/internal/storages/memory"
+	"gw-exchanger/internal/storages/mysql"
+	"gw-exchanger/internal/storages/postgres"
+	pb "gw-exchanger/proto"
+)
+
+// newTestLogger создает логгер без вывода
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// recordingPublisher сохраняет опубликованные события изменения курсов
+type recordingPublisher struct {
+	mu       sync.Mutex
+	messages []kafka.RateChangeMessage
+	err      error
+}
+
+func (p *recordingPublisher) PublishRateChanges(ctx context.Context, messages []kafka.RateChangeMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+// take возвращает опубликованные события и очищает список
+func (p *recordingPublisher) take() []kafka.RateChangeMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := p.messages
+	p.messages = nil
+	return messages
+}
+
+// staticProvider провайдер с фиксированными курсами
+type staticProvider struct {
+	name  string
+	rates []providers.Rate
+}
+
+func (p *staticProvider) Name() string { return p.name }
+
+func (p *staticProvider) FetchRates(ctx context.Context) ([]providers.Rate, error) {
+	return p.rates, nil
+}
+
+func (p *staticProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func TestEncodeRateChange(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message, err := kafka.EncodeRateChange(kafka.RateChangeMessage{
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		OldRate:      0.92,
+		NewRate:      0.93,
+		Source:       "plugin:ecb",
+		Timestamp:    timestamp,
+	})
+	if err != nil {
+		t.Fatalf("EncodeRateChange failed: %v", err)
+	}
+
+	if string(message.Key) != "USD_EUR" {
+		t.Errorf("Expected key USD_EUR, got %q", message.Key)
+	}
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers[kafka.SourceHeader] != kafka.SourceService || headers[kafka.SchemaVersionHeader] != "1" || headers[kafka.EventIDHeader] == "" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+
+	var decoded kafka.RateChangeMessage
+	if err := json.Unmarshal(message.Value, &decoded); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if decoded.EventID != headers[kafka.EventIDHeader] || decoded.OldRate != 0.92 || decoded.NewRate != 0.93 ||
+		decoded.Source != "plugin:ecb" || !decoded.Timestamp.Equal(timestamp) || decoded.SchemaVersion != kafka.RateChangeSchemaVersion {
+		t.Errorf("Unexpected message: %+v", decoded)
+	}
+
+	// Повторная отправка того же изменения получает тот же event_id
+	again, err := kafka.EncodeRateChange(decoded)
+	if err != nil {
+		t.Fatalf("EncodeRateChange failed: %v", err)
+	}
+	if string(again.Headers[0].Value) != decoded.EventID {
+		t.Errorf("Expected event_id %s, got %s", decoded.EventID, again.Headers[0].Value)
+	}
+}
+
+func TestRateChangeEvents(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+
+	publisher := &recordingPublisher{}
+	base := memory.New(logger)
+	if err := base.CreateCurrency(ctx, &storages.Currency{Code: "GBP", Name: "British Pound", IsActive: true}); err != nil {
+		t.Fatalf("CreateCurrency failed: %v", err)
+	}
+	storage := events.New(base, publisher, logger)
+
+	// Изменившийся курс, тот же курс и новая пара
+	err := storage.UpsertExchangeRates(events.ContextWithSource(ctx, events.SourceImport, ""), []storages.ExchangeRate{
+		{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.95},
+		{FromCurrency: "USD", ToCurrency: "RUB", Rate: 92.50},
+		{FromCurrency: "USD", ToCurrency: "GBP", Rate: 0.79},
+	})
+	if err != nil {
+		t.Fatalf("UpsertExchangeRates failed: %v", err)
+	}
+
+	messages := publisher.take()
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", messages)
+	}
+	if m := messages[0]; m.FromCurrency != "USD" || m.ToCurrency != "EUR" || m.OldRate != 0.92 || m.NewRate != 0.95 || m.Source != "import" {
+		t.Errorf("Unexpected USD->EUR event: %+v", m)
+	}
+	if m := messages[1]; m.ToCurrency != "GBP" || m.OldRate != 0 || m.NewRate != 0.79 {
+		t.Errorf("Unexpected USD->GBP event: %+v", m)
+	}
+
+	// Одиночное обновление без источника в контексте
+	if err := storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.96}); err != nil {
+		t.Fatalf("UpdateExchangeRate failed: %v", err)
+	}
+	messages = publisher.take()
+	if len(messages) != 1 || messages[0].OldRate != 0.95 || messages[0].NewRate != 0.96 || messages[0].Source != events.SourceUnknown {
+		t.Errorf("Unexpected update events: %+v", messages)
+	}
+
+	// Ошибка записи: событий нет
+	if err := storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "EUR", ToCurrency: "GBP", Rate: 0.85}); !errors.Is(err, storages.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if messages := publisher.take(); len(messages) != 0 {
+		t.Errorf("Expected no events for a failed write, got %+v", messages)
+	}
+
+	// BulkSetRates: источник - вызывающая сторона
+	server := grpc.NewExchangeServer(storage, logger)
+	_, err = server.BulkSetRates(ctx, &pb.BulkSetRatesRequest{Rates: []*pb.RateUpdate{
+		{FromCurrency: "eur", ToCurrency: "usd", Rate: 1.05},
+		{FromCurrency: "USD", ToCurrency: "GBP", Rate: 0.79},
+	}})
+	if err != nil {
+		t.Fatalf("BulkSetRates failed: %v", err)
+	}
+	messages = publisher.take()
+	if len(messages) != 1 || messages[0].FromCurrency != "EUR" || messages[0].OldRate != 1.09 || messages[0].Source != events.SourceAPI {
+		t.Errorf("Unexpected BulkSetRates events: %+v", messages)
+	}
+
+	// Плагины: повторное обновление теми же курсами событий не создает
+	manager := providers.NewManager([]providers.Provider{&staticProvider{
+		name:  "ecb",
+		rates: []providers.Rate{{FromCurrency: "EUR", ToCurrency: "RUB", Rate: 101}},
+	}}, storage, logger)
+	manager.RefreshAll(ctx)
+	messages = publisher.take()
+	if len(messages) != 1 || messages[0].Source != "plugin:ecb" || messages[0].OldRate != 100.54 || messages[0].NewRate != 101 {
+		t.Errorf("Unexpected plugin events: %+v", messages)
+	}
+	manager.RefreshAll(ctx)
+	if messages := publisher.take(); len(messages) != 0 {
+		t.Errorf("Expected no events for unchanged rates, got %+v", messages)
+	}
+
+	// Ошибка отправки не отменяет запись курса
+	publisher.err = errors.New("broker unavailable")
+	if err := storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "RUB", ToCurrency: "USD", Rate: 0.011}); err != nil {
+		t.Fatalf("Expected write to succeed when publishing fails, got %v", err)
+	}
+	rate, err := base.GetExchangeRate(ctx, "RUB", "USD")
+	if err != nil || rate.Rate != 0.011 {
+		t.Errorf("Expected RUB->USD rate 0.011, got %+v (%v)", rate, err)
+	}
+}
+
+func TestRateHistory(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+
+	storage := memory.New(logger)
+	day := func(d, hour int) time.Time { return time.Date(2024, 5, d, hour, 0, 0, 0, time.UTC) }
+	storage.RecordRateHistory("USD", "EUR", 0.90, day(1, 10))
+	storage.RecordRateHistory("USD", "EUR", 0.95, day(3, 9))
+	storage.RecordRateHistory("USD", "EUR", 0.91, day(3, 15))
+	server := grpc.NewExchangeServer(storage, logger)
+
+	// Курс на конец дня; день до первой записи пропускается
+	resp, err := server.GetRateHistory(ctx, &pb.RateHistoryRequest{
+		FromCurrency: "USD", ToCurrency: "EUR", StartDate: "2024-04-30", EndDate: "2024-05-04",
+	})
+	if err != nil {
+		t.Fatalf("GetRateHistory failed: %v", err)
+	}
+	expected := []struct {
+		date string
+		rate float64
+	}{{"2024-05-01", 0.90}, {"2024-05-02", 0.90}, {"2024-05-03", 0.91}, {"2024-05-04", 0.91}}
+	if len(resp.Points) != len(expected) || resp.Derived {
+		t.Fatalf("Unexpected history: %+v", resp)
+	}
+	for i, point := range resp.Points {
+		if point.Date != expected[i].date || point.Rate != expected[i].rate {
+			t.Errorf("Point %d: expected %s %v, got %s %v", i, expected[i].date, expected[i].rate, point.Date, point.Rate)
+		}
+	}
+
+	// Изменение курса записывается в историю, запись того же курса - нет
+	before := time.Now().Add(-time.Second)
+	for _, rate := range []float64{0.93, 0.93} {
+		if err := storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: rate}); err != nil {
+			t.Fatalf("UpdateExchangeRate failed: %v", err)
+		}
+	}
+	points, err := storage.GetRateHistory(ctx, "USD", "EUR", before, time.Now())
+	if err != nil || len(points) != 3 || points[0].Rate != 0.91 || points[1].Rate != 0.92 || points[2].Rate != 0.93 {
+		t.Errorf("Expected previous rate, seed rate and one change, got %+v (%v)", points, err)
+	}
+
+	// Кросс-курс через базовую валюту
+	if err := storage.CreateCurrency(ctx, &storages.Currency{Code: "GBP", Name: "British Pound", IsActive: true}); err != nil {
+		t.Fatalf("CreateCurrency failed: %v", err)
+	}
+	storage.RecordRateHistory("EUR", "USD", 1.10, day(1, 0))
+	storage.RecordRateHistory("USD", "GBP", 0.80, day(2, 0))
+	server.EnableCrossRates("USD")
+	resp, err = server.GetRateHistory(ctx, &pb.RateHistoryRequest{
+		FromCurrency: "EUR", ToCurrency: "GBP", StartDate: "2024-05-01", EndDate: "2024-05-02",
+	})
+	if err != nil {
+		t.Fatalf("GetRateHistory failed: %v", err)
+	}
+	if !resp.Derived || resp.BaseCurrency != "USD" || len(resp.Points) != 1 ||
+		resp.Points[0].Date != "2024-05-02" || math.Abs(resp.Points[0].Rate-0.88) > 1e-9 {
+		t.Errorf("Unexpected cross rate history: %+v", resp)
+	}
+
+	// Ошибки запроса
+	for name, req := range map[string]*pb.RateHistoryRequest{
+		"invalid date":  {FromCurrency: "USD", ToCurrency: "EUR", StartDate: "05/01/2024", EndDate: "2024-05-04"},
+		"reversed":      {FromCurrency: "USD", ToCurrency: "EUR", StartDate: "2024-05-04", EndDate: "2024-05-01"},
+		"too long":      {FromCurrency: "USD", ToCurrency: "EUR", StartDate: "2023-01-01", EndDate: "2024-05-01"},
+		"no history":    {FromCurrency: "RUB", ToCurrency: "GBP", StartDate: "2024-05-01", EndDate: "2024-05-04"},
+		"missing codes": {StartDate: "2024-05-01", EndDate: "2024-05-04"},
+	} {
+		if _, err := server.GetRateHistory(ctx, req); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestExchctl(t *testing.T) {
+	logger := newTestLogger()
+	storage := memory.New(logger)
+
+	callerAuth := grpc.NewCallerAuth(map[string]string{"admin-token": "admin", "wallet-token": "wallet"}, []string{"admin"}, logger)
+	srv := grpcServer.NewServer(grpcServer.UnaryInterceptor(callerAuth.UnaryInterceptor()))
+	pb.RegisterExchangeServiceServer(srv, grpc.NewExchangeServer(storage, logger))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthServer)
+	healthServer.SetServingStatus(pb.ExchangeService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go srv.Serve(listener)
+	defer srv.Stop()
+
+	env := map[string]string{exchctl.EnvAddr: listener.Addr().String(), exchctl.EnvToken: "admin-token"}
+	run := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := exchctl.Run(context.Background(), exchctl.Env{
+			Stdin:  strings.NewReader(stdin),
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Getenv: func(key string) string { return env[key] },
+		}, args)
+		return code, stdout.String(), stderr.String()
+	}
+
+	if code, stdout, stderr := run("", "health"); code != exchctl.ExitOK || strings.Count(stdout, "SERVING") != 2 {
+		t.Fatalf("Expected SERVING, got %d: %s %s", code, stdout, stderr)
+	}
+	healthServer.SetServingStatus(pb.ExchangeService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	if code, stdout, _ := run("", "health"); code != exchctl.ExitError || !strings.Contains(stdout, "NOT_SERVING") {
+		t.Errorf("Expected NOT_SERVING with exit code 1, got %d: %s", code, stdout)
+	}
+
+	// Версию сервиса получает любая вызывающая сторона
+	code, stdout, stderr := run("", "-token", "wallet-token", "-o", "json", "version")
+	var version buildinfo.Info
+	if code != exchctl.ExitOK || json.Unmarshal([]byte(stdout), &version) != nil {
+		t.Fatalf("Failed to get version: %d %s %s", code, stdout, stderr)
+	}
+	if version != buildinfo.Get() {
+		t.Errorf("Expected build info %+v, got %+v", buildinfo.Get(), version)
+	}
+
+	code, stdout, stderr = run("", "-o", "json", "rates")
+	var rates []struct {
+		Pair string  `json:"pair"`
+		Rate float64 `json:"rate"`
+	}
+	if code != exchctl.ExitOK || json.Unmarshal([]byte(stdout), &rates) != nil {
+		t.Fatalf("Failed to list rates: %d %s %s", code, stdout, stderr)
+	}
+	if len(rates) != len(storages.SeedExchangeRates) || rates[0].Pair != "EUR_RUB" {
+		t.Errorf("Expected seed rates ordered by pair, got %+v", rates)
+	}
+
+	if code, _, stderr := run("", "set-rate", "usd", "eur", "0.95"); code != exchctl.ExitOK {
+		t.Fatalf("Failed to set rate: %d %s", code, stderr)
+	}
+	if code, stdout, _ := run("", "rates", "USD", "EUR"); code != exchctl.ExitOK || !strings.Contains(stdout, "USD_EUR  0.95") {
+		t.Errorf("Expected USD_EUR rate 0.95, got %d: %s", code, stdout)
+	}
+	if code, _, stderr := run("", "-token", "wallet-token", "set-rate", "USD", "EUR", "0.9"); code != exchctl.ExitError || !strings.Contains(stderr, string(errcodes.Forbidden)) {
+		t.Errorf("Expected forbidden for a non-admin caller, got %d: %s", code, stderr)
+	}
+	if code, _, _ := run("", "set-rate", "USD", "EUR"); code != exchctl.ExitUsage {
+		t.Errorf("Expected usage error, got %d", code)
+	}
+
+	// Пробный импорт проверяет курсы по справочнику валют сервиса и ничего не сохраняет
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(path, []byte("from,to,rate\nUSD,EUR,0.97\nUSD,GBP,0.8\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write rates file: %v", err)
+	}
+	if code, _, stderr := run("", "import", "-dry-run", path); code != exchctl.ExitError || !strings.Contains(stderr, `unknown currency "GBP"`) {
+		t.Errorf("Expected unknown currency, got %d: %s", code, stderr)
+	}
+	if code, stdout, stderr := run("usd,eur,0.97\nusd,rub,95\n", "import", "-dry-run", "-"); code != exchctl.ExitOK || !strings.Contains(stdout, "2 rates are valid") {
+		t.Errorf("Failed to validate rates from stdin: %d %s %s", code, stdout, stderr)
+	}
+	if rate, err := storage.GetExchangeRate(context.Background(), "USD", "EUR"); err != nil || rate.Rate != 0.95 {
+		t.Errorf("Expected dry run to keep rate 0.95, got %+v (%v)", rate, err)
+	}
+	if code, stdout, stderr := run("usd,eur,0.97\nusd,rub,95\n", "import", "-"); code != exchctl.ExitOK || !strings.Contains(stdout, "Imported 2 rates") {
+		t.Fatalf("Failed to import rates: %d %s %s", code, stdout, stderr)
+	}
+	if rate, err := storage.GetExchangeRate(context.Background(), "USD", "RUB"); err != nil || rate.Rate != 95 {
+		t.Errorf("Expected imported USD->RUB rate 95, got %+v (%v)", rate, err)
+	}
+}
+
+// unsetenv убирает переменные окружения на время теста; после теста значения восстанавливаются
+func unsetenv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	unsetenv(t, "GRPC_PORT", "DB_HOST", "DB_PORT", "DB_MAX_OPEN_CONNS", "API_TOKENS", "ADMIN_CALLERS", "KAFKA_BROKERS", "RATE_SPREAD")
+
+	// Вложенные ключи и плоские имена переменных дают одну схему; окружение важнее файла
+	yamlPath := filepath.Join(dir, "exchanger.yaml")
+	yamlConfig := `
+grpc:
+  port: "50052"
+db:
+  host: db.internal
+  port: 5433
+  max-open-conns: 40
+api_tokens:
+  - wallet:wallet-token
+  - admin:admin-token
+ADMIN_CALLERS: admin
+kafka:
+  brokers: [kafka-1:9092, kafka-2:9092]
+rate_spread: ~
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlConfig), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("DB_HOST", "db.override")
+
+	cfg, err := config.Load(yamlPath)
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+	if cfg.Server.GRPCPort != "50052" || cfg.Database.Port != 5433 || cfg.Database.MaxOpenConns != 40 {
+		t.Errorf("Unexpected values from YAML config: %+v %+v", cfg.Server, cfg.Database)
+	}
+	if cfg.Database.Host != "db.override" {
+		t.Errorf("Expected environment to override the config file, got DB host %q", cfg.Database.Host)
+	}
+	if cfg.Auth.Tokens["admin-token"] != "admin" || cfg.Auth.Tokens["wallet-token"] != "wallet" {
+		t.Errorf("Unexpected API tokens from YAML list: %v", cfg.Auth.Tokens)
+	}
+	if strings.Join(cfg.Kafka.Brokers, ",") != "kafka-1:9092,kafka-2:9092" || strings.Join(cfg.Auth.AdminCallers, ",") != "admin" {
+		t.Errorf("Unexpected lists from YAML config: %v %v", cfg.Kafka.Brokers, cfg.Auth.AdminCallers)
+	}
+	if _, set := os.LookupEnv("RATE_SPREAD"); set {
+		t.Error("Expected null value to keep the default")
+	}
+
+	// JSON читается по той же схеме
+	vars, err := configfile.Parse(strings.NewReader(`{"grpc": {"port": 50053, "reflection": true}, "DB_HOST": "json-db"}`), configfile.FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON config: %v", err)
+	}
+	if vars["GRPC_PORT"] != "50053" || vars["GRPC_REFLECTION"] != "true" || vars["DB_HOST"] != "json-db" {
+		t.Errorf("Unexpected values from JSON config: %v", vars)
+	}
+
+	for _, path := range []string{"config.env", ".env", "local.conf"} {
+		if format := configfile.FormatFromPath(path); format != configfile.FormatEnv {
+			t.Errorf("Expected %s to be read as .env, got %s", path, format)
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"json trailing comma", configfile.FormatJSON, `{"grpc": {"port": 1},}`},
+		{"duplicate setting", configfile.FormatYAML, "db:\n  host: a\nDB_HOST: b\n"},
+		{"list of objects", configfile.FormatYAML, "api_tokens:\n  - caller: wallet\n"},
+		{"not an object", configfile.FormatYAML, "- GRPC_PORT\n"},
+		{"unknown format", "toml", "GRPC_PORT = 1"},
+	}
+	for _, tc := range invalid {
+		if _, err := configfile.Parse(strings.NewReader(tc.data), tc.format); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	unsetenv(t, "LOG_LEVEL", "RATES_CACHE_TTL", "GRPC_PORT")
+	logger := newTestLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "exchanger.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("log_level: info\nrates_cache_ttl: 1h\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	base := memory.New(logger)
+	if err := base.UpsertExchangeRates(ctx, []storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.9}}); err != nil {
+		t.Fatalf("UpsertExchangeRates failed: %v", err)
+	}
+	ratesCache := cache.New(base, cfg.Rates.CacheTTL, logger)
+	go ratesCache.Run(ctx)
+
+	reloader := config.NewReloader(path, cfg, func(next *config.Config) error {
+		if err := exchangerlogger.SetLevel(logger, next.Logger.Level); err != nil {
+			return err
+		}
+		ratesCache.SetTTL(next.Rates.CacheTTL)
+		return nil
+	}, logger)
+
+	rateOf := func() float64 {
+		rate, err := ratesCache.GetExchangeRate(ctx, "USD", "EUR")
+		if err != nil {
+			return 0
+		}
+		return rate.Rate
+	}
+	waitRate := func(expected float64) bool {
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if rateOf() == expected {
+				return true
+			}
+		}
+		return false
+	}
+	if !waitRate(0.9) {
+		t.Fatalf("Expected cache to load USD->EUR rate, got %v", rateOf())
+	}
+
+	// Новый интервал обновления кеша действует сразу; смена порта ждет перезапуска
+	write("log_level: debug\nrates_cache_ttl: 20ms\ngrpc_port: \"50099\"\n")
+	result, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if strings.Join(result.Applied, ",") != "LOG_LEVEL,RATES_CACHE_TTL" || strings.Join(result.RestartRequired, ",") != "Server" {
+		t.Errorf("Unexpected reload result: %+v", result)
+	}
+	if logger.GetLevel() != logrus.DebugLevel || ratesCache.TTL() != 20*time.Millisecond {
+		t.Errorf("Expected debug log level and 20ms cache TTL, got %v and %v", logger.GetLevel(), ratesCache.TTL())
+	}
+	if err := base.UpsertExchangeRates(ctx, []storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.95}}); err != nil {
+		t.Fatalf("UpsertExchangeRates failed: %v", err)
+	}
+	if !waitRate(0.95) {
+		t.Errorf("Expected cache to refresh with new TTL, got %v", rateOf())
+	}
+
+	// Кеш не отключается без перезапуска, ошибка в конфигурации ничего не меняет
+	write("log_level: debug\nrates_cache_ttl: 0s\ngrpc_port: \"50099\"\n")
+	if result, err = reloader.Reload(); err != nil || len(result.Applied) != 0 {
+		t.Errorf("Expected RATES_CACHE_TTL=0 to require a restart, got %+v (%v)", result, err)
+	}
+	write("log_level: verbose\n")
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("Expected error for invalid log level")
+	}
+	if logger.GetLevel() != logrus.DebugLevel || ratesCache.TTL() != 20*time.Millisecond {
+		t.Error("Expected settings to stay unchanged after failed reload")
+	}
+}
+
+func TestEmbeddedApp(t *testing.T) {
+	unsetenv(t, "KAFKA_BROKERS", "API_TOKENS", "ADMIN_CALLERS", "METRICS_PORT")
+	path := filepath.Join(t.TempDir(), "exchanger.yaml")
+	if err := os.WriteFile(path, []byte("api_tokens: [admin:admin-token]\nadmin_callers: admin\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Хранилище в памяти вместо БД: сервис собирается так же, как в cmd/main.go
+	application, err := app.New(cfg, app.WithLogger(newTestLogger()), app.WithStorage(memory.New(newTestLogger())))
+	if err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- application.Serve(ctx, listener)
+	}()
+
+	env := map[string]string{exchctl.EnvAddr: listener.Addr().String(), exchctl.EnvToken: "admin-token"}
+	run := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := exchctl.Run(context.Background(), exchctl.Env{
+			Stdin:  strings.NewReader(""),
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Getenv: func(key string) string { return env[key] },
+		}, args)
+		return code, stdout.String() + stderr.String()
+	}
+
+	// Статус SERVING выставляется первой проверкой хранилища
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, output := run("health")
+		if code == exchctl.ExitOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected SERVING, got %d: %s", code, output)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if code, output := run("set-rate", "USD", "EUR", "0.95"); code != exchctl.ExitOK {
+		t.Fatalf("Failed to set rate: %d %s", code, output)
+	}
+	if code, output := run("rates", "USD", "EUR"); code != exchctl.ExitOK || !strings.Contains(output, "USD_EUR  0.95") {
+		t.Errorf("Expected USD_EUR rate 0.95 from the cached storage, got %d: %s", code, output)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("App did not stop after context cancel")
+	}
+	if code, _ := run("health"); code == exchctl.ExitOK {
+		t.Error("Expected stopped app to refuse connections")
+	}
+}
+
+// TestMemoryStorageDriver проверяет запуск без БД: STORAGE_DRIVER=memory не требует
+// параметров подключения, а хранилище создается с начальными курсами
+func TestMemoryStorageDriver(t *testing.T) {
+	unsetenv(t, "KAFKA_BROKERS", "METRICS_PORT", "DB_DRIVER")
+	t.Setenv("STORAGE_DRIVER", config.DBDriverMemory)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.Database.Host = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected memory driver to pass validation without DB_HOST: %v", err)
+	}
+
+	application, err := app.New(cfg, app.WithLogger(newTestLogger()))
+	if err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	rates, err := application.Storage().GetAllExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get rates: %v", err)
+	}
+	if len(rates) != len(storages.SeedExchangeRates) {
+		t.Errorf("Expected %d seed rates, got %d", len(storages.SeedExchangeRates), len(rates))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := application.Serve(ctx, listener); err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestCallerPairs(t *testing.T) {
+	logger := newTestLogger()
+	server := grpc.NewExchangeServer(memory.New(logger), logger)
+	auth := grpc.NewCallerAuth(map[string]string{
+		"ops-token":       "ops",
+		"wallet-token":    "wallet",
+		"reporting-token": "reporting",
+	}, []string{"ops"}, logger)
+
+	// call выполняет метод сервера через проверку API токена, как gRPC сервер
+	call := func(token, method string, req interface{}, handler grpcServer.UnaryHandler) (interface{}, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpc.APITokenHeader, token))
+		return auth.UnaryInterceptor()(ctx, req, &grpcServer.UnaryServerInfo{FullMethod: method}, handler)
+	}
+	rate := func(token, from, to string) (*pb.ExchangeRateResponse, error) {
+		resp, err := call(token, pb.ExchangeService_GetExchangeRateForCurrency_FullMethodName,
+			&pb.CurrencyRequest{FromCurrency: from, ToCurrency: to},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return server.GetExchangeRateForCurrency(ctx, req.(*pb.CurrencyRequest))
+			})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*pb.ExchangeRateResponse), nil
+	}
+	rates := func(token string) map[string]float32 {
+		resp, err := call(token, pb.ExchangeService_GetExchangeRates_FullMethodName, &pb.Empty{},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return server.GetExchangeRates(ctx, req.(*pb.Empty))
+			})
+		if err != nil {
+			t.Fatalf("GetExchangeRates failed: %v", err)
+		}
+		return resp.(*pb.ExchangeRatesResponse).Rates
+	}
+	setPairs := func(token string, req *pb.SetCallerPairsRequest) (*pb.CallerPairsResponse, error) {
+		resp, err := call(token, pb.ExchangeService_SetCallerPairs_FullMethodName, req,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return server.SetCallerPairs(ctx, req.(*pb.SetCallerPairsRequest))
+			})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*pb.CallerPairsResponse), nil
+	}
+
+	walletPairs := &pb.SetCallerPairsRequest{Caller: "wallet", Pairs: []*pb.CurrencyPair{
+		{FromCurrency: "usd", ToCurrency: "eur"},
+		{FromCurrency: "EUR", ToCurrency: "USD"},
+		{FromCurrency: "USD", ToCurrency: "EUR"},
+	}}
+	// Список пар меняет только администратор
+	if _, err := setPairs("wallet-token", walletPairs); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied for non-admin caller, got %v", err)
+	}
+	resp, err := setPairs("ops-token", walletPairs)
+	if err != nil {
+		t.Fatalf("SetCallerPairs failed: %v", err)
+	}
+	if len(resp.Pairs) != 2 {
+		t.Errorf("Expected 2 normalized pairs without duplicates, got %+v", resp.Pairs)
+	}
+
+	// Разрешенная пара
+	if resp, err := rate("wallet-token", "USD", "EUR"); err != nil || resp.Rate != 0.92 {
+		t.Errorf("Expected USD->EUR rate 0.92, got %+v (%v)", resp, err)
+	}
+
+	// Пара вне списка отклоняется
+	_, err = rate("wallet-token", "USD", "RUB")
+	if status.Code(err) != codes.PermissionDenied || errcodes.FromError(err) != errcodes.PairNotAllowed {
+		t.Errorf("Expected PermissionDenied with %s, got %v", errcodes.PairNotAllowed, err)
+	}
+
+	// Список курсов содержит только разрешенные пары
+	if got := rates("wallet-token"); len(got) != 2 || got["USD_EUR"] == 0 || got["EUR_USD"] == 0 {
+		t.Errorf("Expected only allowed pairs, got %v", got)
+	}
+
+	// Вызывающая сторона без списка пар не ограничена
+	if got := rates("reporting-token"); len(got) != len(storages.SeedExchangeRates) {
+		t.Errorf("Expected all %d rates for caller without pairs, got %v", len(storages.SeedExchangeRates), got)
+	}
+	if _, err := rate("reporting-token", "USD", "RUB"); err != nil {
+		t.Errorf("Expected USD->RUB to be allowed for caller without pairs, got %v", err)
+	}
+
+	// Неизвестный токен отклоняется до обработки запроса
+	if _, err := rate("unknown-token", "USD", "EUR"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for unknown caller, got %v", err)
+	}
+
+	// Пустой список снимает ограничения
+	if _, err := setPairs("ops-token", &pb.SetCallerPairsRequest{Caller: "wallet"}); err != nil {
+		t.Fatalf("SetCallerPairs failed: %v", err)
+	}
+	if _, err := rate("wallet-token", "USD", "RUB"); err != nil {
+		t.Errorf("Expected USD->RUB to be allowed after clearing pairs, got %v", err)
+	}
+}
+
+// writePlugin создает исполняемый скрипт плагина во временном каталоге
+func writePlugin(t *testing.T, name, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func TestExecProvider(t *testing.T) {
+	ctx := context.Background()
+	newProvider := func(name, script string, timeout time.Duration) *providers.ExecProvider {
+		return providers.NewExecProvider(providers.Plugin{Name: name, Path: writePlugin(t, name, script), Timeout: timeout})
+	}
+
+	// Успешный вызов: курсы из stdout
+	ok := newProvider("ok", `[ "$1" = rates ] && echo '{"rates":[{"from":"USD","to":"EUR","rate":0.93}]}'; exit 0`, 5*time.Second)
+	if err := ok.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+	rates, err := ok.FetchRates(ctx)
+	if err != nil || len(rates) != 1 || rates[0].FromCurrency != "USD" || rates[0].Rate != 0.93 {
+		t.Fatalf("Unexpected rates: %+v (%v)", rates, err)
+	}
+
+	// Ненулевой код выхода: stderr в тексте ошибки
+	failing := newProvider("failing", "echo 'source is down' >&2; exit 3", 5*time.Second)
+	if _, err := failing.FetchRates(ctx); err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "source is down") {
+		t.Errorf("Expected exit status and stderr in error, got %v", err)
+	}
+
+	// Таймаут: процесс завершается, вызов не ждет плагин
+	slow := newProvider("slow", "sleep 10", 200*time.Millisecond)
+	start := time.Now()
+	if _, err := slow.FetchRates(ctx); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected plugin to be stopped on timeout, took %v", elapsed)
+	}
+
+	// Вывод больше лимита: ошибка без чтения всего вывода
+	noisy := newProvider("noisy", "yes 0123456789", 5*time.Second)
+	start = time.Now()
+	if _, err := noisy.FetchRates(ctx); err == nil || !strings.Contains(err.Error(), "output exceeds") {
+		t.Errorf("Expected output limit error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected plugin to be stopped on oversized output, took %v", elapsed)
+	}
+
+	// Вывод ровно в лимит допустим
+	exact := newProvider("exact", fmt.Sprintf("head -c %d /dev/zero", providers.MaxOutputSize), 5*time.Second)
+	if err := exact.HealthCheck(ctx); err != nil {
+		t.Errorf("Expected output of MaxOutputSize to be accepted, got %v", err)
+	}
+}
+
+func TestProviderStates(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+	storage := memory.New(logger)
+
+	ok := providers.NewExecProvider(providers.Plugin{
+		Name:    "ok",
+		Path:    writePlugin(t, "ok", `[ "$1" = rates ] && echo '{"rates":[{"from":"USD","to":"EUR","rate":0.93}]}'; exit 0`),
+		Timeout: 5 * time.Second,
+	})
+	down := providers.NewExecProvider(providers.Plugin{
+		Name:    "down",
+		Path:    writePlugin(t, "down", "echo 'no route to host' >&2; exit 1"),
+		Timeout: 5 * time.Second,
+	})
+	manager := providers.NewManager([]providers.Provider{ok, down}, storage, logger)
+	handler := providers.HealthHandler(manager)
+
+	health := func() (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health/providers", nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid health response %q: %v", recorder.Body.String(), err)
+		}
+		return recorder.Code, body
+	}
+
+	// До первой проверки провайдеры недоступны
+	if code, body := health(); code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("Expected unavailable before first refresh, got %d %v", code, body)
+	}
+
+	manager.RefreshAll(ctx)
+
+	states := manager.States()
+	if len(states) != 2 || states[0].Name != "ok" || states[1].Name != "down" {
+		t.Fatalf("Expected states in polling order, got %+v", states)
+	}
+	if s := states[0]; !s.Healthy || s.RatesUpdated != 1 || s.LastUpdateAt.IsZero() || s.LastError != "" {
+		t.Errorf("Unexpected healthy provider state: %+v", s)
+	}
+	if s := states[1]; s.Healthy || s.LastCheckAt.IsZero() || !s.LastUpdateAt.IsZero() || !strings.Contains(s.LastError, "no route to host") {
+		t.Errorf("Unexpected failing provider state: %+v", s)
+	}
+
+	code, body := health()
+	if code != http.StatusOK || body["status"] != "degraded" {
+		t.Errorf("Expected degraded status, got %d %v", code, body)
+	}
+	list, _ := body["providers"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 providers in response, got %v", body["providers"])
+	}
+	if p, _ := list[1].(map[string]interface{}); p["name"] != "down" || p["healthy"] != false || p["last_error"] == nil {
+		t.Errorf("Unexpected provider in response: %v", p)
+	}
+}
+
+func TestCrossRate(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+
+	storage := memory.New(logger)
+	for _, code := range []string{"GBP", "CHF", "JPY"} {
+		if err := storage.CreateCurrency(ctx, &storages.Currency{Code: code, Name: code, IsActive: true}); err != nil {
+			t.Fatalf("CreateCurrency failed: %v", err)
+		}
+	}
+	// Для CHF известен только курс USD -> CHF, для JPY курсов нет
+	err := storage.UpsertExchangeRates(ctx, []storages.ExchangeRate{
+		{FromCurrency: "GBP", ToCurrency: "USD", Rate: 1.25},
+		{FromCurrency: "GBP", ToCurrency: "RUB", Rate: 120},
+		{FromCurrency: "USD", ToCurrency: "CHF", Rate: 0.9},
+	})
+	if err != nil {
+		t.Fatalf("UpsertExchangeRates failed: %v", err)
+	}
+
+	server := grpc.NewExchangeServer(storage, logger)
+	rate := func(from, to string) (*pb.ExchangeRateResponse, error) {
+		return server.GetExchangeRateForCurrency(ctx, &pb.CurrencyRequest{FromCurrency: from, ToCurrency: to})
+	}
+
+	// Без базовой валюты пары без прямого курса не находятся
+	if _, err := rate("GBP", "EUR"); errcodes.FromError(err) != errcodes.NotFound {
+		t.Errorf("Expected NOT_FOUND without cross rates, got %v", err)
+	}
+
+	server.EnableCrossRates("USD")
+
+	for name, tc := range map[string]struct {
+		from, to string
+		rate     float64
+		derived  bool
+	}{
+		"direct pair":            {"USD", "EUR", 0.92, false},
+		"direct pair over cross": {"GBP", "RUB", 120, false},
+		"via base":               {"GBP", "EUR", 1.25 * 0.92, true},
+		"via base to new pair":   {"EUR", "CHF", 1.09 * 0.9, true},
+	} {
+		resp, err := rate(tc.from, tc.to)
+		if err != nil {
+			t.Errorf("%s: GetExchangeRateForCurrency failed: %v", name, err)
+			continue
+		}
+		if resp.Derived != tc.derived || math.Abs(float64(resp.Rate)-tc.rate) > 1e-5 {
+			t.Errorf("%s: expected rate %v (derived: %t), got %+v", name, tc.rate, tc.derived, resp)
+		}
+		if tc.derived && resp.BaseCurrency != "USD" {
+			t.Errorf("%s: expected base currency USD, got %q", name, resp.BaseCurrency)
+		}
+		if !tc.derived && resp.BaseCurrency != "" {
+			t.Errorf("%s: expected no base currency for direct pair, got %q", name, resp.BaseCurrency)
+		}
+	}
+
+	for name, pair := range map[string][2]string{
+		// Обратные курсы не вычисляются: есть только USD -> CHF
+		"inverse pair":      {"CHF", "USD"},
+		"inverse first leg": {"CHF", "EUR"},
+		"missing leg":       {"EUR", "JPY"},
+		"no legs":           {"JPY", "GBP"},
+	} {
+		if _, err := rate(pair[0], pair[1]); errcodes.FromError(err) != errcodes.NotFound {
+			t.Errorf("%s: expected NOT_FOUND for %s -> %s, got %v", name, pair[0], pair[1], err)
+		}
+	}
+
+	// Дневной ряд кросс-курса: произведение курсов обеих пар на конец дня,
+	// дни без курса одной из пар пропускаются
+	day := func(d, hour int) time.Time { return time.Date(2024, 6, d, hour, 0, 0, 0, time.UTC) }
+	storage.RecordRateHistory("GBP", "USD", 1.20, day(1, 12))
+	storage.RecordRateHistory("GBP", "USD", 1.30, day(3, 12))
+	storage.RecordRateHistory("USD", "JPY", 150, day(2, 8))
+	storage.RecordRateHistory("USD", "JPY", 160, day(4, 8))
+	resp, err := server.GetRateHistory(ctx, &pb.RateHistoryRequest{
+		FromCurrency: "GBP", ToCurrency: "JPY", StartDate: "2024-06-01", EndDate: "2024-06-04",
+	})
+	if err != nil {
+		t.Fatalf("GetRateHistory failed: %v", err)
+	}
+	expected := []struct {
+		date string
+		rate float64
+	}{{"2024-06-02", 1.20 * 150}, {"2024-06-03", 1.30 * 150}, {"2024-06-04", 1.30 * 160}}
+	if !resp.Derived || resp.BaseCurrency != "USD" || len(resp.Points) != len(expected) {
+		t.Fatalf("Unexpected cross rate history: %+v", resp)
+	}
+	for i, point := range resp.Points {
+		if point.Date != expected[i].date || math.Abs(point.Rate-expected[i].rate) > 1e-9 {
+			t.Errorf("Point %d: expected %s %v, got %s %v", i, expected[i].date, expected[i].rate, point.Date, point.Rate)
+		}
+	}
+
+	// Ряды пар без общих дней не дают кросс-курса
+	storage.RecordRateHistory("CHF", "USD", 1.1, day(10, 12))
+	if _, err := server.GetRateHistory(ctx, &pb.RateHistoryRequest{
+		FromCurrency: "CHF", ToCurrency: "JPY", StartDate: "2024-06-05", EndDate: "2024-06-09",
+	}); errcodes.FromError(err) != errcodes.NotFound {
+		t.Errorf("Expected NOT_FOUND for history without common days, got %v", err)
+	}
+}
+
+// pausingStorage после установки pause останавливает следующую загрузку всех
+// курсов после чтения из хранилища, пока тест не закроет release
+type pausingStorage struct {
+	storages.Storage
+	pause   atomic.Bool
+	loaded  chan struct{}
+	release chan struct{}
+}
+
+func (s *pausingStorage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
+	rates, err := s.Storage.GetAllExchangeRates(ctx)
+	if s.pause.CompareAndSwap(true, false) {
+		s.loaded <- struct{}{}
+		<-s.release
+	}
+	return rates, err
+}
+
+func TestRatesCache(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+
+	rateOf := func(ratesCache *cache.Storage, from, to string) float64 {
+		t.Helper()
+		rate, err := ratesCache.GetExchangeRate(ctx, from, to)
+		if err != nil {
+			t.Fatalf("GetExchangeRate %s -> %s failed: %v", from, to, err)
+		}
+		return rate.Rate
+	}
+
+	t.Run("invalidation on write", func(t *testing.T) {
+		ratesCache := cache.New(memory.New(logger), time.Hour, logger)
+
+		if rate := rateOf(ratesCache, "USD", "EUR"); rate != 0.92 {
+			t.Fatalf("Expected seed rate 0.92, got %v", rate)
+		}
+		rateOf(ratesCache, "USD", "RUB")
+		if stats := ratesCache.Stats(); stats.Misses != 1 || stats.Hits != 1 || stats.Refreshes != 1 {
+			t.Errorf("Expected one load and one hit, got %+v", stats)
+		}
+
+		if err := ratesCache.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.95}); err != nil {
+			t.Fatalf("UpdateExchangeRate failed: %v", err)
+		}
+		if rate := rateOf(ratesCache, "USD", "EUR"); rate != 0.95 {
+			t.Errorf("Expected updated rate 0.95 right after write, got %v", rate)
+		}
+
+		// Отключение валюты убирает ее курсы из кеша
+		if err := ratesCache.SetCurrencyActive(ctx, "EUR", false); err != nil {
+			t.Fatalf("SetCurrencyActive failed: %v", err)
+		}
+		if _, err := ratesCache.GetExchangeRate(ctx, "USD", "EUR"); !errors.Is(err, storages.ErrNotFound) {
+			t.Errorf("Expected rate of disabled currency to be not found, got %v", err)
+		}
+		if stats := ratesCache.Stats(); stats.Invalidations != 2 || stats.Refreshes != 3 {
+			t.Errorf("Expected 2 invalidations and 3 loads, got %+v", stats)
+		}
+	})
+
+	t.Run("write during load", func(t *testing.T) {
+		base := &pausingStorage{Storage: memory.New(logger), loaded: make(chan struct{}), release: make(chan struct{})}
+		ratesCache := cache.New(base, time.Hour, logger)
+		base.pause.Store(true)
+
+		// Чтение загружает курсы, запись приходит до окончания загрузки
+		done := make(chan float64)
+		go func() {
+			rate, _ := ratesCache.GetExchangeRate(ctx, "USD", "EUR")
+			done <- rate.Rate
+		}()
+		<-base.loaded
+		if err := ratesCache.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.97}); err != nil {
+			t.Fatalf("UpdateExchangeRate failed: %v", err)
+		}
+		close(base.release)
+		if rate := <-done; rate != 0.92 {
+			t.Errorf("Expected the load started before the write to return 0.92, got %v", rate)
+		}
+
+		// Загрузка, начатая до записи, не считается свежей
+		if rate := rateOf(ratesCache, "USD", "EUR"); rate != 0.97 {
+			t.Errorf("Expected reload after the write, got %v", rate)
+		}
+		if stats := ratesCache.Stats(); stats.Refreshes != 2 || stats.Misses != 2 {
+			t.Errorf("Expected 2 loads, got %+v", stats)
+		}
+	})
+
+	t.Run("ttl expiry", func(t *testing.T) {
+		base := memory.New(logger)
+		ratesCache := cache.New(base, 100*time.Millisecond, logger)
+
+		rateOf(ratesCache, "USD", "EUR")
+		// Запись в обход кеша видна только после истечения ttl
+		if err := base.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.99}); err != nil {
+			t.Fatalf("UpdateExchangeRate failed: %v", err)
+		}
+		if rate := rateOf(ratesCache, "USD", "EUR"); rate != 0.92 {
+			t.Errorf("Expected cached rate 0.92 within ttl, got %v", rate)
+		}
+		time.Sleep(150 * time.Millisecond)
+		if rate := rateOf(ratesCache, "USD", "EUR"); rate != 0.99 {
+			t.Errorf("Expected rate 0.99 after ttl, got %v", rate)
+		}
+		if stats := ratesCache.Stats(); stats.Refreshes != 2 || stats.Invalidations != 0 {
+			t.Errorf("Expected 2 loads without invalidations, got %+v", stats)
+		}
+	})
+}
+
+func TestImporterValidate(t *testing.T) {
+	currencies := []storages.Currency{
+		{Code: "USD", IsActive: true},
+		{Code: "EUR", IsActive: true},
+		{Code: "GBP", IsActive: false},
+	}
+
+	// Коды нормализуются, отключенные валюты допустимы
+	rates, err := importer.Validate([]storages.ExchangeRate{
+		{FromCurrency: " usd", ToCurrency: "eur ", Rate: 0.92},
+		{FromCurrency: "EUR", ToCurrency: "GBP", Rate: 0.85},
+	}, currencies)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(rates) != 2 || rates[0].FromCurrency != "USD" || rates[0].ToCurrency != "EUR" || rates[1].ToCurrency != "GBP" {
+		t.Errorf("Unexpected normalized rates: %+v", rates)
+	}
+
+	for name, tc := range map[string]struct {
+		rates    []storages.ExchangeRate
+		expected []string
+	}{
+		"empty":         {nil, []string{"no rates"}},
+		"zero rate":     {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0}}, []string{"rate 1: rate must be positive, got 0"}},
+		"negative rate": {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: -1.5}}, []string{"rate 1: rate must be positive, got -1.5"}},
+		"nan rate":      {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: math.NaN()}}, []string{"rate must be positive, got NaN"}},
+		"infinite rate": {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "EUR", Rate: math.Inf(1)}}, []string{"rate must be positive, got +Inf"}},
+		"unknown from":  {[]storages.ExchangeRate{{FromCurrency: "JPY", ToCurrency: "EUR", Rate: 0.006}}, []string{`rate 1: unknown currency "JPY"`}},
+		"unknown to":    {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "xyz", Rate: 1}}, []string{`rate 1: unknown currency "xyz"`}},
+		"same currency": {[]storages.ExchangeRate{{FromCurrency: "USD", ToCurrency: "usd", Rate: 1}}, []string{"rate 1: same currency USD"}},
+		"duplicate pair": {[]storages.ExchangeRate{
+			{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.92},
+			{FromCurrency: "usd", ToCurrency: "eur", Rate: 0.93},
+		}, []string{"rate 2: duplicate pair USD -> EUR (rate 1)"}},
+		// Все нарушения перечисляются в одной ошибке
+		"several violations": {[]storages.ExchangeRate{
+			{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.92},
+			{FromCurrency: "USD", ToCurrency: "JPY", Rate: 150},
+			{FromCurrency: "EUR", ToCurrency: "USD", Rate: -1},
+		}, []string{`rate 2: unknown currency "JPY"`, "rate 3: rate must be positive"}},
+	} {
+		rates, err := importer.Validate(tc.rates, currencies)
+		if !errors.Is(err, importer.ErrInvalidRates) || rates != nil {
+			t.Errorf("%s: expected ErrInvalidRates without rates, got %+v (%v)", name, rates, err)
+			continue
+		}
+		for _, expected := range tc.expected {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("%s: expected %q in error, got %v", name, expected, err)
+			}
+		}
+	}
+}
+
+func TestImporterParse(t *testing.T) {
+	rates, err := importer.Parse(strings.NewReader("from,to,rate\nUSD, EUR, 0.92\neur,usd,1.09\n"), importer.FormatCSV)
+	if err != nil || len(rates) != 2 || rates[0].ToCurrency != "EUR" || rates[1].Rate != 1.09 {
+		t.Fatalf("Unexpected csv rates: %+v (%v)", rates, err)
+	}
+	rates, err = importer.Parse(strings.NewReader(`{"rates":[{"from":"USD","to":"EUR","rate":0.92}]}`), importer.FormatJSON)
+	if err != nil || len(rates) != 1 || rates[0].Rate != 0.92 {
+		t.Fatalf("Unexpected json rates: %+v (%v)", rates, err)
+	}
+
+	for name, tc := range map[string]struct {
+		input, format, expected string
+	}{
+		"missing column":     {"USD,EUR\n", importer.FormatCSV, "wrong number of fields"},
+		"extra column":       {"USD,EUR,0.92,x\n", importer.FormatCSV, "wrong number of fields"},
+		"invalid rate":       {"USD,EUR,0.92\nUSD,RUB,ninety\n", importer.FormatCSV, `line 2: invalid rate "ninety"`},
+		"header not first":   {"USD,EUR,0.92\nfrom,to,rate\n", importer.FormatCSV, `line 2: invalid rate "rate"`},
+		"unterminated quote": {"\"USD,EUR,0.92\n", importer.FormatCSV, "failed to read csv"},
+		"malformed json":     {`{"rates":[{"from":"USD"`, importer.FormatJSON, "failed to decode json"},
+		"wrong json type":    {`{"rates":[{"from":"USD","to":"EUR","rate":"0.92"}]}`, importer.FormatJSON, "failed to decode json"},
+		"unknown format":     {"", "xml", "unsupported rates format"},
+	} {
+		if _, err := importer.Parse(strings.NewReader(tc.input), tc.format); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected %q error, got %v", name, tc.expected, err)
+		}
+	}
+
+	if _, err := importer.FormatFromPath("rates.txt"); err == nil {
+		t.Error("Expected error for unsupported extension")
+	}
+	if format, err := importer.FormatFromPath("rates.CSV"); err != nil || format != importer.FormatCSV {
+		t.Errorf("Expected csv format, got %q (%v)", format, err)
+	}
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	// Каждая версия миграций имеет up и down, версии идут подряд с 1
+	dir := filepath.Join("..", "internal", "storages", "postgres", "migrations")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+
+	files := make(map[uint]map[string]bool)
+	for _, entry := range entries {
+		var version uint
+		var rest string
+		if _, err := fmt.Sscanf(entry.Name(), "%06d_%s", &version, &rest); err != nil {
+			t.Fatalf("%s: unexpected migration file name %s", dir, entry.Name())
+		}
+		direction := "up"
+		if strings.HasSuffix(rest, ".down.sql") {
+			direction = "down"
+		} else if !strings.HasSuffix(rest, ".up.sql") {
+			t.Fatalf("%s: migration %s must end with .up.sql or .down.sql", dir, entry.Name())
+		}
+		if files[version] == nil {
+			files[version] = make(map[string]bool)
+		}
+		files[version][direction] = true
+	}
+
+	for version := uint(1); version <= uint(len(files)); version++ {
+		if !files[version]["up"] || !files[version]["down"] {
+			t.Fatalf("%s: migration %d must have up and down files, got %v", dir, version, files[version])
+		}
+	}
+
+	latest, err := postgres.LatestMigrationVersion()
+	if err != nil {
+		t.Fatalf("Failed to read embedded migrations: %v", err)
+	}
+	if latest != 2 {
+		t.Errorf("Expected latest exchanger migration 2, got %d", latest)
+	}
+}
+
+// TestMySQLDefaultPort проверяет порт по умолчанию для драйвера MySQL: явный DB_PORT важнее
+func TestMySQLDefaultPort(t *testing.T) {
+	unsetenv(t, "DB_PORT", "DB_DRIVER")
+	t.Setenv("STORAGE_DRIVER", config.DBDriverMySQL)
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database.Port != config.DefaultDBMySQLPort {
+		t.Errorf("Expected MySQL port %d, got %d", config.DefaultDBMySQLPort, cfg.Database.Port)
+	}
+
+	t.Setenv("DB_PORT", "3307")
+	if cfg, err = config.Load(""); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database.Port != 3307 {
+		t.Errorf("Expected explicit DB_PORT 3307, got %d", cfg.Database.Port)
+	}
+
+	unsetenv(t, "STORAGE_DRIVER", "DB_PORT")
+	if cfg, err = config.Load(""); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database.Port != config.DefaultDBPort {
+		t.Errorf("Expected PostgreSQL port %d, got %d", config.DefaultDBPort, cfg.Database.Port)
+	}
+}
+
+// newTestMySQLStorage подключается к MySQL из GW_TEST_MYSQL_* и пересоздает схему
+// с начальными данными. Без GW_TEST_MYSQL_HOST тест пропускается
+func newTestMySQLStorage(t *testing.T) *mysql.MySQLStorage {
+	t.Helper()
+	host := os.Getenv("GW_TEST_MYSQL_HOST")
+	if host == "" {
+		t.Skip("GW_TEST_MYSQL_HOST is not set")
+	}
+
+	cfg := &mysql.Config{
+		Host:         host,
+		Port:         env.Int("GW_TEST_MYSQL_PORT", config.DefaultDBMySQLPort),
+		User:         env.String("GW_TEST_MYSQL_USER", "root"),
+		Password:     env.String("GW_TEST_MYSQL_PASSWORD", ""),
+		DBName:       env.String("GW_TEST_MYSQL_DB", "exchanger_test"),
+		SSLMode:      env.String("GW_TEST_MYSQL_SSLMODE", "disable"),
+		MaxOpenConns: 5,
+		MaxIdleConns: 1,
+	}
+
+	dsn := mysqldriver.NewConfig()
+	dsn.User = cfg.User
+	dsn.Passwd = cfg.Password
+	dsn.Net = "tcp"
+	dsn.Addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	dsn.DBName = cfg.DBName
+	db, err := sql.Open("mysql", dsn.FormatDSN())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Схема создается при подключении: без таблиц хранилище добавит начальные данные
+	for _, table := range []string{"caller_pairs", "exchange_rate_history", "exchange_rates", "currencies"} {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			t.Fatalf("Failed to drop %s: %v", table, err)
+		}
+	}
+
+	storage, err := mysql.New(cfg, newTestLogger())
+	if err != nil {
+		t.Fatalf("Failed to connect to MySQL: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+// TestMySQLStorage проверяет хранилище MySQL на реальной базе: начальные данные,
+// обновление курсов с историей, отключение валют и пары вызывающих сторон
+func TestMySQLStorage(t *testing.T) {
+	storage := newTestMySQLStorage(t)
+	ctx := context.Background()
+	start := time.Now()
+
+	rates, err := storage.GetAllExchangeRates(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get rates: %v", err)
+	}
+	if len(rates) != len(storages.SeedExchangeRates) {
+		t.Errorf("Expected %d seed rates, got %d", len(storages.SeedExchangeRates), len(rates))
+	}
+	rate, err := storage.GetExchangeRate(ctx, "USD", "EUR")
+	if err != nil || rate.Rate != 0.92 {
+		t.Fatalf("Expected seed USD->EUR 0.92, got %+v (%v)", rate, err)
+	}
+
+	// Обновление тем же значением не ошибка и не пишет историю
+	for i := 0; i < 2; i++ {
+		if err := storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.95}); err != nil {
+			t.Fatalf("Failed to update rate: %v", err)
+		}
+	}
+	err = storage.UpdateExchangeRate(ctx, &storages.ExchangeRate{FromCurrency: "USD", ToCurrency: "JPY", Rate: 150})
+	if !errors.Is(err, storages.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing pair, got %v", err)
+	}
+
+	history, err := storage.GetRateHistory(ctx, "USD", "EUR", start.Add(-24*time.Hour), start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get rate history: %v", err)
+	}
+	if len(history) != 2 || history[0].Rate != 0.92 || history[1].Rate != 0.95 {
+		t.Errorf("Expected history 0.92 -> 0.95, got %+v", history)
+	}
+
+	// Пакетное обновление меняет существующие пары и создает новые
+	err = storage.UpsertExchangeRates(ctx, []storages.ExchangeRate{
+		{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.96},
+		{FromCurrency: "GBP", ToCurrency: "USD", Rate: 1.27},
+	})
+	if err != nil {
+		t.Fatalf("Failed to upsert rates: %v", err)
+	}
+	if rate, err := storage.GetExchangeRate(ctx, "GBP", "USD"); err != nil || rate.Rate != 1.27 {
+		t.Errorf("Expected upserted GBP->USD 1.27, got %+v (%v)", rate, err)
+	}
+
+	// Курсы отключенной валюты не возвращаются
+	if err := storage.CreateCurrency(ctx, &storages.Currency{Code: "GBP", Name: "British Pound", IsActive: true}); err != nil {
+		t.Fatalf("Failed to create currency: %v", err)
+	}
+	err = storage.CreateCurrency(ctx, &storages.Currency{Code: "GBP", Name: "British Pound", IsActive: true})
+	if !errors.Is(err, storages.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for existing currency, got %v", err)
+	}
+	if err := storage.SetCurrencyActive(ctx, "GBP", false); err != nil {
+		t.Fatalf("Failed to disable currency: %v", err)
+	}
+	if _, err := storage.GetExchangeRate(ctx, "GBP", "USD"); !errors.Is(err, storages.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for disabled currency, got %v", err)
+	}
+	if err := storage.SetCurrencyActive(ctx, "XXX", true); !errors.Is(err, storages.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing currency, got %v", err)
+	}
+
+	// Пары вызывающей стороны заменяются целиком и возвращаются по порядку
+	pairs := []storages.CurrencyPair{{FromCurrency: "USD", ToCurrency: "RUB"}, {FromCurrency: "EUR", ToCurrency: "USD"}}
+	if err := storage.SetCallerPairs(ctx, "wallet", pairs); err != nil {
+		t.Fatalf("Failed to set caller pairs: %v", err)
+	}
+	if err := storage.SetCallerPairs(ctx, "wallet", pairs[:1]); err != nil {
+		t.Fatalf("Failed to replace caller pairs: %v", err)
+	}
+	got, err := storage.GetCallerPairs(ctx, "wallet")
+	if err != nil {
+		t.Fatalf("Failed to get caller pairs: %v", err)
+	}
+	if len(got) != 1 || got[0] != pairs[0] {
+		t.Errorf("Expected replaced pairs %v, got %v", pairs[:1], got)
+	}
+}
+
+// unavailableStorage отвечает ошибкой базы на чтение курсов и валют
+type unavailableStorage struct {
+	*memory.MemoryStorage
+}
+
+func (s *unavailableStorage) GetAllExchangeRates(ctx context.Context) ([]storages.ExchangeRate, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (s *unavailableStorage) GetAllCurrencies(ctx context.Context, includeInactive bool) ([]storages.Currency, error) {
+	return nil, errors.New("connection refused")
+}
+
+// TestStorageErrorsUseRegistryCodes проверяет, что ошибки хранилища возвращаются
+// кодом реестра Internal без подробностей
+func TestStorageErrorsUseRegistryCodes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	server := grpc.NewExchangeServer(&unavailableStorage{MemoryStorage: memory.New(logger)}, logger)
+	ctx := context.Background()
+
+	_, ratesErr := server.GetExchangeRates(ctx, &pb.Empty{})
+	_, currenciesErr := server.GetCurrencies(ctx, &pb.CurrenciesRequest{})
+	_, bulkErr := server.BulkSetRates(ctx, &pb.BulkSetRatesRequest{})
+	for name, err := range map[string]error{
+		"GetExchangeRates": ratesErr,
+		"GetCurrencies":    currenciesErr,
+		"BulkSetRates":     bulkErr,
+	} {
+		if status.Code(err) != codes.Internal || errcodes.FromError(err) != errcodes.Internal {
+			t.Errorf("%s: expected internal_error status, got %v", name, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("%s: expected storage details to stay in logs, got %v", name, err)
+		}
+	}
+}
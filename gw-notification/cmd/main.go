@@ -9,19 +9,31 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"gw-common/buildinfo"
+	"gw-common/logger"
+	"gw-notification/internal/app"
+	"gw-notification/internal/backfill"
 	"gw-notification/internal/config"
-	"gw-notification/internal/kafka"
-	"gw-notification/internal/logger"
+	"gw-notification/internal/storages"
 	"gw-notification/internal/storages/mongodb"
-	"gw-notification/pkg"
-	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages/postgres"
 )
 
 func main() {
 	// Парсинг флагов командной строки
 	configPath := flag.String("c", "", "Path to config file")
+	backfillPath := flag.String("backfill", "", "Import historical transactions exported from wallet (CSV/JSON) and exit")
+	backfillFormat := flag.String("backfill-format", "", "Backfill file format: csv or json (default: by file extension)")
+	backfillMinAmount := flag.Float64("backfill-min-amount", 0, "Skip backfill records with a smaller amount")
+	showVersion := flag.Bool("version", false, "Print version and build info and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Printf("gw-notification %s\n", buildinfo.Get())
+		return
+	}
+
 	// Загрузка конфигурации
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -36,23 +48,73 @@ func main() {
 	}
 
 	// Инициализация логгера
-	log := logger.New(cfg.Logger.Level)
-	log.Infof("Starting %s service...", cfg.Service.Name)
+	log, err := logger.NewWithConfig(cfg.Logger)
+	if err != nil {
+		fmt.Printf("Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	log.Infof("Starting %s service %s...", cfg.Service.Name, buildinfo.Get())
 	log.Infof("Configuration loaded from: %s", *configPath)
 
-	// Подключение к MongoDB
-	mongoConfig := &mongodb.Config{
-		URI:         cfg.MongoDB.URI,
-		Database:    cfg.MongoDB.Database,
-		Collection:  cfg.MongoDB.Collection,
-		Timeout:     cfg.MongoDB.Timeout,
-		MaxPoolSize: cfg.MongoDB.MaxPoolSize,
-		MinPoolSize: cfg.MongoDB.MinPoolSize,
+	// Команды обслуживания: migrate up|status
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		switch cfg.Storage.Driver {
+		case config.StorageDriverMongoDB:
+			os.Exit(runMigrate(app.MongoConfig(cfg), flag.Args()[1:], log))
+		case config.StorageDriverPostgres:
+			os.Exit(runPostgresMigrate(app.PostgresConfig(cfg), flag.Args()[1:], log))
+		default:
+			log.Fatalf("Command migrate requires STORAGE_DRIVER=%s or %s",
+				config.StorageDriverMongoDB, config.StorageDriverPostgres)
+		}
+	default:
+		log.Fatalf("Unknown command %q (expected migrate)", flag.Arg(0))
 	}
 
-	storage, err := mongodb.New(mongoConfig, log)
+	// Режим импорта истории: загрузка выгрузки кошелька без запуска consumer
+	if *backfillPath != "" {
+		storage, err := app.ConnectStorage(cfg, log)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Импортированная история попадает и в ClickHouse
+		if storage, err = app.WithAnalytics(cfg, storage, log); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(runBackfill(storage, *backfillPath, *backfillFormat, *backfillMinAmount, cfg.Processing.BatchSize, log))
+	}
+
+	// Сборка компонентов сервиса
+	application, err := app.New(cfg, app.WithLogger(log), app.WithConfigPath(*configPath))
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		log.Fatalf("Failed to start service: %v", err)
+	}
+
+	// Graceful shutdown по SIGINT и SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := application.Run(ctx); err != nil {
+		log.Errorf("Service stopped with error: %v", err)
+		os.Exit(1)
+	}
+	log.Info("Service stopped gracefully")
+}
+
+// runMigrate выполняет команду migrate up|status и возвращает код завершения.
+// Миграции MongoDB применяются только вперед, откат выполняется новой миграцией
+func runMigrate(mongoConfig *mongodb.Config, args []string, log *logrus.Logger) int {
+	if len(args) != 1 {
+		log.Error("Usage: migrate up|status")
+		return 2
+	}
+
+	storage, err := mongodb.Open(mongoConfig, log)
+	if err != nil {
+		log.Errorf("Failed to connect to MongoDB: %v", err)
+		return 1
 	}
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -60,150 +122,96 @@ func main() {
 		storage.Close(ctx)
 	}()
 
-	// Проверка подключения к MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	if err := storage.Ping(ctx); err != nil {
-		cancel()
-		log.Fatalf("MongoDB ping failed: %v", err)
-	}
-	cancel()
-	log.Info("MongoDB connection established")
-
-	// Создание Kafka consumer
-	kafkaConfig := &kafka.Config{
-		Brokers:       cfg.Kafka.Brokers,
-		Topic:         cfg.Kafka.Topic,
-		GroupID:       cfg.Kafka.GroupID,
-		Partition:     cfg.Kafka.Partition,
-		MinBytes:      cfg.Kafka.MinBytes,
-		MaxBytes:      cfg.Kafka.MaxBytes,
-		MaxWait:       cfg.Kafka.MaxWait,
-		BatchSize:     cfg.Processing.BatchSize,
-		Workers:       cfg.Processing.Workers,
-		FlushInterval: cfg.Processing.FlushInterval,
-		RetryAttempts: cfg.Processing.RetryAttempts,
-		RetryDelay:    cfg.Processing.RetryDelay,
-	}
-
-	consumer := kafka.NewConsumer(kafkaConfig, storage, log)
-	defer consumer.Close()
-
-	// Контекст для graceful shutdown
-	ctx, cancel = context.WithCancel(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), mongodb.MigrateTimeout)
 	defer cancel()
 
-	// Обработка сигналов завершения
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	// Запуск consumer в горутине
-	consumerErr := make(chan error, 1)
-	go func() {
-		consumerErr <- consumer.Start(ctx)
-	}()
-
-	// Запуск горутины для вывода статистики
-	statsTicker := time.NewTicker(30 * time.Second)
-	defer statsTicker.Stop()
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-statsTicker.C:
-				printStatistics(log, consumer, storage)
-			}
-		}
-	}()
-
-	log.Info("Service is running. Press Ctrl+C to stop...")
-
-	// Ожидание сигнала завершения или ошибки
-	select {
-	case <-sigChan:
-		log.Info("Received shutdown signal...")
-	case err := <-consumerErr:
-		if err != nil {
-			log.Errorf("Consumer error: %v", err)
+	switch args[0] {
+	case "up":
+		err = storage.Migrate(ctx)
+	case "status":
+		var status mongodb.MigrationStatus
+		if status, err = storage.MigrationStatus(ctx); err == nil {
+			log.Infof("Schema version: %d, latest: %d, pending: %v, unknown: %v",
+				status.Version, status.Latest, status.Pending, status.Unknown)
 		}
+	default:
+		log.Errorf("Unknown migrate command %q (expected up or status)", args[0])
+		return 2
 	}
 
-	// Graceful shutdown
-	log.Info("Shutting down service...")
-	cancel()
-
-	// Даем время на завершение обработки
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Processing.MaxProcessingTime)
-	defer shutdownCancel()
-
-	// Ждем завершения consumer
-	select {
-	case <-shutdownCtx.Done():
-		log.Warn("Shutdown timeout exceeded, forcing exit")
-	case err := <-consumerErr:
-		if err != nil && err != context.Canceled {
-			log.Errorf("Consumer shutdown error: %v", err)
-		}
+	if err != nil {
+		log.Errorf("Migration failed: %v", err)
+		return 1
 	}
-
-	// Финальная статистика
-	printFinalStatistics(log, consumer, storage)
-
-	log.Info("Service stopped gracefully")
+	return 0
 }
 
-// printStatistics выводит текущую статистику
-func printStatistics(log *logrus.Logger, consumer *kafka.Consumer, storage *mongodb.MongoStorage) {
-	// Статистика consumer
-	consumerStats := consumer.GetStatistics()
+// runPostgresMigrate выполняет команду migrate up|status для PostgreSQL и возвращает
+// код завершения
+func runPostgresMigrate(postgresConfig *postgres.Config, args []string, log *logrus.Logger) int {
+	if len(args) != 1 {
+		log.Error("Usage: migrate up|status")
+		return 2
+	}
 
-	log.Infof("Consumer Statistics: Processed=%d, Failed=%d, Rate=%.2f msg/s, Uptime=%.0fs",
-		consumerStats["messages_processed"],
-		consumerStats["messages_failed"],
-		consumerStats["processing_rate"],
-		consumerStats["uptime_seconds"])
+	storage, err := postgres.Open(postgresConfig, log)
+	if err != nil {
+		log.Errorf("Failed to connect to PostgreSQL: %v", err)
+		return 1
+	}
+	defer storage.Close(context.Background())
 
-	// Статистика хранилища
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), postgres.MigrateTimeout)
 	defer cancel()
 
-	storageStats, err := storage.GetStatistics(ctx)
-	if err != nil {
-		log.Warnf("Failed to get storage statistics: %v", err)
-		return
+	switch args[0] {
+	case "up":
+		err = storage.MigrateUp(ctx)
+	case "status":
+		var status postgres.MigrationStatus
+		if status, err = storage.MigrationStatus(ctx); err == nil {
+			log.Infof("Schema version: %d, latest: %d, pending: %v, dirty: %v",
+				status.Version, status.Latest, status.Pending(), status.Dirty)
+		}
+	default:
+		log.Errorf("Unknown migrate command %q (expected up or status)", args[0])
+		return 2
 	}
 
-	log.Infof("Storage Statistics: Total=%d, Failed=%d, AvgAmount=%.2f, TotalAmount=%.2f",
-		storageStats.TotalProcessed,
-		storageStats.TotalFailed,
-		storageStats.AverageAmount,
-		storageStats.TotalAmount)
+	if err != nil {
+		log.Errorf("Migration failed: %v", err)
+		return 1
+	}
+	return 0
 }
 
-// printFinalStatistics выводит финальную статистику перед завершением
-func printFinalStatistics(log *logrus.Logger, consumer *kafka.Consumer, storage *mongodb.MongoStorage) {
-	log.Info("=== Final Statistics ===")
-
-	consumerStats := consumer.GetStatistics()
-	duration := pkg.FormatDuration(time.Duration(consumerStats["uptime_seconds"].(float64) * float64(time.Second)))
+// runBackfill импортирует выгрузку транзакций кошелька и возвращает код завершения.
+// Импорт можно прервать сигналом и запустить повторно: дубликаты не сохраняются
+func runBackfill(storage storages.Storage, path, format string, minAmount float64, batchSize int, log *logrus.Logger) int {
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		storage.Close(ctx)
+	}()
 
-	log.Infof("Total Messages Processed: %d", consumerStats["messages_processed"])
-	log.Infof("Total Messages Failed: %d", consumerStats["messages_failed"])
-	log.Infof("Average Processing Rate: %.2f msg/s", consumerStats["processing_rate"])
-	log.Infof("Total Uptime: %s", duration)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	log.Infof("Starting backfill from %s", path)
 
-	storageStats, err := storage.GetStatistics(ctx)
+	importer := backfill.NewImporter(storage, batchSize, minAmount, log)
+	result, err := importer.ImportFile(ctx, path, format)
 	if err != nil {
-		log.Warnf("Failed to get final storage statistics: %v", err)
-		return
+		if result != nil {
+			log.Errorf("Backfill stopped after %d records (saved %d): %v", result.Read, result.Saved, err)
+		} else {
+			log.Errorf("Backfill failed: %v", err)
+		}
+		return 1
 	}
 
-	log.Infof("Total Transfers in DB: %d", storageStats.TotalProcessed)
-	log.Infof("Average Transfer Amount: %.2f", storageStats.AverageAmount)
-	log.Infof("Total Amount Processed: %.2f", storageStats.TotalAmount)
-	log.Info("========================")
+	if result.Invalid > 0 {
+		log.Warnf("Backfill skipped %d invalid records", result.Invalid)
+	}
+	return 0
 }
@@ -0,0 +1,196 @@
+// Package analytics дублирует сохраненные крупные переводы в ClickHouse для
+// аналитических запросов. Основное хранилище остается источником данных для
+// API, правил и отчетов; ClickHouse получает копию пакетов consumer
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gw-notification/internal/storages"
+)
+
+// ClickHouseConfig содержит параметры подключения к HTTP интерфейсу ClickHouse
+type ClickHouseConfig struct {
+	URL      string
+	Database string
+	Table    string
+	User     string
+	Password string
+	Timeout  time.Duration
+}
+
+// ClickHouseWriter пишет переводы в таблицу ClickHouse запросами INSERT ... FORMAT JSONEachRow
+type ClickHouseWriter struct {
+	url      string
+	table    string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// NewClickHouseWriter создает writer для таблицы database.table
+func NewClickHouseWriter(cfg ClickHouseConfig) *ClickHouseWriter {
+	table := quoteIdentifier(cfg.Table)
+	if cfg.Database != "" {
+		table = quoteIdentifier(cfg.Database) + "." + table
+	}
+	return &ClickHouseWriter{
+		url:      strings.TrimRight(cfg.URL, "/"),
+		table:    table,
+		user:     cfg.User,
+		password: cfg.Password,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// transferRow строка таблицы ClickHouse. DedupKey - event_id, а для старых
+// сообщений без него ID перевода: повторная запись пакета после сбоя схлопывается
+// движком ReplacingMergeTree
+type transferRow struct {
+	DedupKey          string  `json:"dedup_key"`
+	EventID           string  `json:"event_id"`
+	ID                string  `json:"id"`
+	UserID            int64   `json:"user_id"`
+	Type              string  `json:"type"`
+	FromCurrency      string  `json:"from_currency"`
+	ToCurrency        string  `json:"to_currency"`
+	Amount            float64 `json:"amount"`
+	Timestamp         string  `json:"timestamp"`
+	ProcessedAt       string  `json:"processed_at"`
+	Status            string  `json:"status"`
+	UserStatus        string  `json:"user_status"`
+	ConvertedAmount   float64 `json:"converted_amount"`
+	ConvertedCurrency string  `json:"converted_currency"`
+	TraceID           string  `json:"trace_id"`
+	Source            string  `json:"source"`
+}
+
+// clickHouseTimeLayout формат DateTime64(3) в JSONEachRow
+const clickHouseTimeLayout = "2006-01-02 15:04:05.000"
+
+// newTransferRow преобразует перевод в строку таблицы
+func newTransferRow(t *storages.LargeTransfer) transferRow {
+	row := transferRow{
+		EventID:           t.EventID,
+		UserID:            t.UserID,
+		Type:              t.Type,
+		FromCurrency:      t.FromCurrency,
+		ToCurrency:        t.ToCurrency,
+		Amount:            t.Amount,
+		Timestamp:         t.Timestamp.UTC().Format(clickHouseTimeLayout),
+		ProcessedAt:       t.ProcessedAt.UTC().Format(clickHouseTimeLayout),
+		Status:            t.Status,
+		UserStatus:        t.UserStatus,
+		ConvertedAmount:   t.ConvertedAmount,
+		ConvertedCurrency: t.ConvertedCurrency,
+		TraceID:           t.TraceID,
+		Source:            t.Source,
+	}
+	if !t.ID.IsZero() {
+		row.ID = t.ID.Hex()
+	}
+	row.DedupKey = row.EventID
+	if row.DedupKey == "" {
+		row.DedupKey = row.ID
+	}
+	return row
+}
+
+// Insert записывает пакет переводов одним запросом
+func (w *ClickHouseWriter) Insert(ctx context.Context, transfers []storages.LargeTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i := range transfers {
+		if err := encoder.Encode(newTransferRow(&transfers[i])); err != nil {
+			return fmt.Errorf("failed to encode transfer: %w", err)
+		}
+	}
+
+	return w.exec(ctx, "INSERT INTO "+w.table+" FORMAT JSONEachRow", &body)
+}
+
+// CreateTable создает таблицу переводов, если ее нет
+func (w *ClickHouseWriter) CreateTable(ctx context.Context) error {
+	return w.exec(ctx, "", strings.NewReader(`CREATE TABLE IF NOT EXISTS `+w.table+` (
+	dedup_key String,
+	event_id String,
+	id String,
+	user_id Int64,
+	type LowCardinality(String),
+	from_currency LowCardinality(String),
+	to_currency LowCardinality(String),
+	amount Decimal(20, 8),
+	timestamp DateTime64(3, 'UTC'),
+	processed_at DateTime64(3, 'UTC'),
+	status LowCardinality(String),
+	user_status LowCardinality(String),
+	converted_amount Decimal(20, 8),
+	converted_currency LowCardinality(String),
+	trace_id String,
+	source LowCardinality(String)
+)
+ENGINE = ReplacingMergeTree(processed_at)
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (user_id, timestamp, dedup_key)`))
+}
+
+// Ping проверяет доступность ClickHouse
+func (w *ClickHouseWriter) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url+"/ping", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create ClickHouse request: %w", err)
+	}
+	return w.do(req)
+}
+
+// exec выполняет запрос: query передается параметром, тело - данными запроса.
+// Без query запросом считается тело
+func (w *ClickHouseWriter) exec(ctx context.Context, query string, body io.Reader) error {
+	target := w.url + "/"
+	if query != "" {
+		target += "?" + url.Values{"query": {query}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		return fmt.Errorf("failed to create ClickHouse request: %w", err)
+	}
+	if w.user != "" {
+		req.Header.Set("X-ClickHouse-User", w.user)
+		req.Header.Set("X-ClickHouse-Key", w.password)
+	}
+	return w.do(req)
+}
+
+// do отправляет запрос; ответ со статусом не 2xx считается ошибкой с текстом ответа
+func (w *ClickHouseWriter) do(req *http.Request) error {
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ClickHouse request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ClickHouse returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// quoteIdentifier экранирует имя базы или таблицы обратными кавычками
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
@@ -0,0 +1,257 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// Режимы записи и поведение при заполненной очереди
+const (
+	ModeAsync = "async"
+	ModeSync  = "sync"
+
+	OverflowBlock = "block"
+	OverflowDrop  = "drop"
+)
+
+// Writer пишет пакет переводов в аналитическую базу
+type Writer interface {
+	Insert(ctx context.Context, transfers []storages.LargeTransfer) error
+}
+
+// SinkConfig содержит режим записи и параметры очереди
+type SinkConfig struct {
+	Mode          string // async или sync
+	QueueSize     int    // емкость очереди в переводах
+	BatchSize     int
+	FlushInterval time.Duration
+	RetryAttempts int
+	RetryDelay    time.Duration
+	Overflow      string // block или drop
+	BlockTimeout  time.Duration
+}
+
+// Sink передает сохраненные переводы в Writer. В режиме sync запись идет в
+// вызывающей горутине и ошибка возвращается consumer, который повторит пакет.
+// В режиме async переводы ставятся в ограниченную очередь, фоновая горутина
+// пишет их пакетами; при заполненной очереди consumer либо ждет (block), что
+// замедляет чтение из Kafka вместо роста памяти, либо переводы отбрасываются (drop)
+type Sink struct {
+	writer Writer
+	cfg    SinkConfig
+	logger *logrus.Logger
+
+	queue  chan storages.LargeTransfer
+	mu     sync.RWMutex // закрытие очереди не пересекается с отправкой в нее
+	closed bool
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	written   atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+	lastError atomic.Value // string
+}
+
+// SinkStats счетчики записи в аналитическую базу
+type SinkStats struct {
+	Mode      string `json:"mode"`
+	Queued    int    `json:"queued"`
+	Written   int64  `json:"written"`
+	Dropped   int64  `json:"dropped"` // отброшены при заполненной очереди
+	Failed    int64  `json:"failed"`  // не записаны после всех повторов
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewSink создает sink; в режиме async запускается фоновая запись, которая
+// работает до Close
+func NewSink(writer Writer, cfg SinkConfig, logger *logrus.Logger) *Sink {
+	s := &Sink{
+		writer: writer,
+		cfg:    cfg,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	if cfg.Mode != ModeAsync {
+		close(s.done)
+		return s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.queue = make(chan storages.LargeTransfer, cfg.QueueSize)
+	s.cancel = cancel
+	go s.run(ctx)
+	return s
+}
+
+// Write передает пакет в аналитическую базу. В режиме async ошибка не
+// возвращается: переводы, не попавшие в очередь, учитываются в Dropped
+func (s *Sink) Write(ctx context.Context, transfers []storages.LargeTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	if s.cfg.Mode == ModeSync {
+		if err := s.writer.Insert(ctx, transfers); err != nil {
+			s.recordFailed(len(transfers), err)
+			return fmt.Errorf("failed to write transfers to analytics: %w", err)
+		}
+		s.written.Add(int64(len(transfers)))
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.drop(len(transfers), "sink is closed")
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	if s.cfg.Overflow == OverflowBlock {
+		timer := time.NewTimer(s.cfg.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for i := range transfers {
+		select {
+		case s.queue <- transfers[i]:
+			continue
+		default:
+		}
+		if timeout == nil {
+			s.drop(len(transfers)-i, "queue is full")
+			return nil
+		}
+		select {
+		case s.queue <- transfers[i]:
+		case <-timeout:
+			s.drop(len(transfers)-i, "queue is full after "+s.cfg.BlockTimeout.String())
+			return nil
+		case <-ctx.Done():
+			s.drop(len(transfers)-i, ctx.Err().Error())
+			return nil
+		}
+	}
+	return nil
+}
+
+// run собирает переводы из очереди в пакеты и пишет их до закрытия очереди
+func (s *Sink) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storages.LargeTransfer, 0, s.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			s.insert(ctx, batch)
+			batch = make([]storages.LargeTransfer, 0, s.cfg.BatchSize)
+		}
+	}
+
+	for {
+		select {
+		case transfer, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, transfer)
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// insert пишет пакет с повторами. Пока идут повторы, очередь не разбирается,
+// поэтому недоступность ClickHouse доходит до consumer через заполненную очередь
+func (s *Sink) insert(ctx context.Context, batch []storages.LargeTransfer) {
+	var err error
+retry:
+	for attempt := 0; attempt < s.cfg.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				break retry
+			case <-time.After(s.cfg.RetryDelay):
+			}
+		}
+		if err = s.writer.Insert(ctx, batch); err == nil {
+			s.written.Add(int64(len(batch)))
+			return
+		}
+		s.logger.Warnf("Attempt %d/%d: Failed to write %d transfers to analytics: %v",
+			attempt+1, s.cfg.RetryAttempts, len(batch), err)
+	}
+	s.logger.Errorf("Failed to write %d transfers to analytics after %d attempts: %v",
+		len(batch), s.cfg.RetryAttempts, err)
+	s.recordFailed(len(batch), err)
+}
+
+// Close дописывает очередь и останавливает фоновую запись. Если ctx истекает
+// раньше, запись прерывается, а незаписанные переводы учитываются в Failed
+func (s *Sink) Close(ctx context.Context) error {
+	if s.cfg.Mode != ModeAsync {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		// Отмена прерывает текущую запись, остаток очереди учитывается в Failed без повторов
+		s.cancel()
+		<-s.done
+		err = fmt.Errorf("analytics sink stopped before flushing the queue: %w", ctx.Err())
+	}
+
+	stats := s.Stats()
+	s.logger.Infof("Analytics sink stopped: Written=%d, Dropped=%d, Failed=%d",
+		stats.Written, stats.Dropped, stats.Failed)
+	return err
+}
+
+// Stats возвращает счетчики записи
+func (s *Sink) Stats() SinkStats {
+	stats := SinkStats{
+		Mode:    s.cfg.Mode,
+		Queued:  len(s.queue),
+		Written: s.written.Load(),
+		Dropped: s.dropped.Load(),
+		Failed:  s.failed.Load(),
+	}
+	if lastError, ok := s.lastError.Load().(string); ok {
+		stats.LastError = lastError
+	}
+	return stats
+}
+
+// drop учитывает переводы, не попавшие в очередь
+func (s *Sink) drop(count int, reason string) {
+	s.dropped.Add(int64(count))
+	s.logger.Warnf("Dropped %d transfers for analytics: %s", count, reason)
+}
+
+// recordFailed учитывает переводы, которые не удалось записать
+func (s *Sink) recordFailed(count int, err error) {
+	s.failed.Add(int64(count))
+	s.lastError.Store(err.Error())
+}
@@ -0,0 +1,48 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+
+	"gw-notification/internal/storages"
+)
+
+// Storage дублирует переводы, сохраненные в основном хранилище, в Sink.
+// Остальные методы выполняются основным хранилищем
+type Storage struct {
+	storages.Storage
+	sink *Sink
+}
+
+// NewStorage оборачивает хранилище записью переводов в sink
+func NewStorage(storage storages.Storage, sink *Sink) *Storage {
+	return &Storage{Storage: storage, sink: sink}
+}
+
+// SaveTransfer сохраняет перевод и передает его в sink
+func (s *Storage) SaveTransfer(ctx context.Context, transfer *storages.LargeTransfer) error {
+	if err := s.Storage.SaveTransfer(ctx, transfer); err != nil {
+		return err
+	}
+	return s.sink.Write(ctx, []storages.LargeTransfer{*transfer})
+}
+
+// SaveTransferBatch сохраняет пакет и передает его в sink. Переводы, уже
+// сохраненные раньше, тоже передаются: при повторе пакета после ошибки sync
+// записи ClickHouse получает их снова и схлопывает по dedup_key
+func (s *Storage) SaveTransferBatch(ctx context.Context, transfers []storages.LargeTransfer) error {
+	if err := s.Storage.SaveTransferBatch(ctx, transfers); err != nil {
+		return err
+	}
+	return s.sink.Write(ctx, transfers)
+}
+
+// Close дописывает очередь sink и закрывает основное хранилище
+func (s *Storage) Close(ctx context.Context) error {
+	return errors.Join(s.sink.Close(ctx), s.Storage.Close(ctx))
+}
+
+// Sink возвращает sink для статистики
+func (s *Storage) Sink() *Sink {
+	return s.sink
+}
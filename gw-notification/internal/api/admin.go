@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gw-common/errcodes"
+	"gw-notification/internal/kafka"
+	"gw-notification/internal/storages"
+)
+
+// Параметры сводки по умолчанию
+const (
+	defaultSummaryWindow   = time.Hour
+	defaultSummaryTopUsers = 10
+	summaryQueryTimeout    = 10 * time.Second
+)
+
+// SummaryResponse сводка для панели мониторинга
+type SummaryResponse struct {
+	GeneratedAt  time.Time               `json:"generated_at"`
+	Window       string                  `json:"window"`
+	Consumer     ConsumerSummary         `json:"consumer"`
+	Transfers    *storages.Summary       `json:"transfers"`
+	Storage      *storages.HealthDetails `json:"storage"`
+	FailureRates map[string]float64      `json:"failure_rates"`
+	Errors       map[string]string       `json:"errors,omitempty"`
+}
+
+// ConsumerSummary состояние и статистика consumer
+type ConsumerSummary struct {
+	kafka.Health
+	Statistics kafka.Statistics `json:"statistics"`
+}
+
+// handleSummary возвращает агрегированную сводку: состояние consumer, отставание,
+// количество переводов по типам и валютам, топ пользователей и долю сообщений,
+// пропущенных consumer без сохранения (failure_rates.consumer).
+// Параметры: window (duration, по умолчанию 1h, не больше QueryLimits.MaxWindow),
+// top (по умолчанию 10, не больше QueryLimits.MaxLimit).
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	window := defaultSummaryWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, errcodes.InvalidRequest, "Invalid window: "+value)
+			return
+		}
+		if s.limits.MaxWindow > 0 && parsed > s.limits.MaxWindow {
+			writeError(w, errcodes.InvalidRequest, "Window exceeds maximum of "+s.limits.MaxWindow.String())
+			return
+		}
+		window = parsed
+	}
+
+	topUsers := defaultSummaryTopUsers
+	if value := r.URL.Query().Get("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, errcodes.InvalidRequest, "Invalid top: "+value)
+			return
+		}
+		if s.limits.MaxLimit > 0 && parsed > s.limits.MaxLimit {
+			writeError(w, errcodes.InvalidRequest, "Top exceeds maximum of "+strconv.Itoa(s.limits.MaxLimit))
+			return
+		}
+		topUsers = parsed
+	}
+
+	now := time.Now()
+	stats := s.consumer.GetStatistics()
+	response := SummaryResponse{
+		GeneratedAt: now,
+		Window:      window.String(),
+		Consumer: ConsumerSummary{
+			Health:     s.consumer.Health(),
+			Statistics: stats,
+		},
+		FailureRates: map[string]float64{
+			"consumer": failureRate(stats.MessagesFailed, stats.MessagesProcessed),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), summaryQueryTimeout)
+	defer cancel()
+
+	response.Errors = map[string]string{}
+
+	health, err := s.storage.Health(ctx)
+	if err != nil {
+		response.Errors["storage"] = err.Error()
+	}
+	response.Storage = health
+
+	summary, err := s.storage.GetSummary(ctx, now.Add(-window), topUsers)
+	if err != nil {
+		// Сводка остается полезной и без данных хранилища
+		s.logger.Errorf("Failed to build transfers summary: %v", err)
+		response.Errors["transfers"] = err.Error()
+	} else {
+		response.Transfers = summary
+	}
+
+	if len(response.Errors) == 0 {
+		response.Errors = nil
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ConsumerStateResponse результат приостановки или возобновления consumer
+type ConsumerStateResponse struct {
+	// Changed false, если consumer уже был в запрошенном состоянии
+	Changed  bool         `json:"changed"`
+	Consumer kafka.Health `json:"consumer"`
+}
+
+// handleConsumerPause приостанавливает чтение сообщений из Kafka без выхода
+// из группы, например на время обслуживания MongoDB
+func (s *Server) handleConsumerPause(w http.ResponseWriter, r *http.Request) {
+	s.setConsumerPaused(w, r, true)
+}
+
+// handleConsumerResume возобновляет чтение сообщений из Kafka
+func (s *Server) handleConsumerResume(w http.ResponseWriter, r *http.Request) {
+	s.setConsumerPaused(w, r, false)
+}
+
+// setConsumerPaused переключает паузу consumer и возвращает его состояние
+func (s *Server) setConsumerPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.consumer == nil {
+		writeError(w, errcodes.ServiceUnavailable, "Consumer is not running")
+		return
+	}
+
+	var changed bool
+	if paused {
+		changed = s.consumer.Pause()
+	} else {
+		changed = s.consumer.Resume()
+	}
+	if changed {
+		s.logger.Infof("Consumer paused=%t by admin request from %s", paused, r.RemoteAddr)
+	}
+
+	writeJSON(w, http.StatusOK, ConsumerStateResponse{Changed: changed, Consumer: s.consumer.Health()})
+}
+
+// failureRate возвращает долю неудачных операций
+func failureRate(failed, succeeded int64) float64 {
+	total := failed + succeeded
+	if total == 0 {
+		return 0
+	}
+	return float64(failed) / float64(total)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"gw-common/errcodes"
+	"gw-notification/internal/config"
+)
+
+// ConfigReloader перечитывает конфигурацию сервиса без перезапуска (config.Reloader)
+type ConfigReloader interface {
+	Reload() (config.ReloadResult, error)
+}
+
+// SetConfigReloader включает перечитывание конфигурации через POST /admin/config/reload
+func (s *Server) SetConfigReloader(reloader ConfigReloader) {
+	s.reloader = reloader
+}
+
+// handleConfigReload перечитывает конфигурацию, как SIGHUP: LOG_LEVEL применяется
+// сразу, остальные изменившиеся разделы перечислены в restart_required
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.reloader == nil {
+		writeError(w, errcodes.ServiceUnavailable, "Config reload is not available")
+		return
+	}
+
+	result, err := s.reloader.Reload()
+	if err != nil {
+		s.logger.Errorf("Failed to reload config: %v", err)
+		// Ответ только администраторам: причина нужна для исправления конфигурации
+		writeError(w, errcodes.Internal, "Failed to reload config: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
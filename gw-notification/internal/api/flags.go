@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gw-common/errcodes"
+	"gw-notification/internal/storages"
+)
+
+// defaultFlagsLimit число отметок в ответе по умолчанию
+const defaultFlagsLimit = 50
+
+// FlagsResponse список отметок подозрительной активности
+type FlagsResponse struct {
+	Flags []storages.SuspiciousFlag `json:"flags"`
+}
+
+// handleFlags возвращает отметки подозрительной активности, начиная с последней.
+// Параметры: user_id, since (RFC3339), limit (по умолчанию 50, не больше QueryLimits.MaxLimit)
+func (s *Server) handleFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter := storages.FlagFilter{Limit: defaultFlagsLimit}
+
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || userID <= 0 {
+			writeError(w, errcodes.InvalidRequest, "Invalid user_id: "+value)
+			return
+		}
+		filter.UserID = userID
+	}
+
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, errcodes.InvalidRequest, "Invalid since: "+value)
+			return
+		}
+		filter.Since = since
+	}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, errcodes.InvalidRequest, "Invalid limit: "+value)
+			return
+		}
+		if s.limits.MaxLimit > 0 && parsed > s.limits.MaxLimit {
+			writeError(w, errcodes.InvalidRequest, "Limit exceeds maximum of "+strconv.Itoa(s.limits.MaxLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), summaryQueryTimeout)
+	defer cancel()
+
+	flags, err := s.storage.GetFlags(ctx, filter)
+	if err != nil {
+		s.logger.Errorf("Failed to get flags: %v", err)
+		writeError(w, errcodes.Internal, "Failed to get flags")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, FlagsResponse{Flags: flags})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gw-common/errcodes"
+	"gw-notification/internal/reports"
+	"gw-notification/internal/storages"
+)
+
+// defaultReportsLimit число отчетов в ответе по умолчанию
+const defaultReportsLimit = 30
+
+// ReportsResponse список отчетов по крупным переводам
+type ReportsResponse struct {
+	Reports []storages.Report `json:"reports"`
+}
+
+// handleReports возвращает сохраненные отчеты, начиная с последнего периода.
+// Параметры: period (daily или weekly, по умолчанию все), since (дата YYYY-MM-DD
+// начала периода), limit (по умолчанию 30, не больше QueryLimits.MaxLimit)
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter := storages.ReportFilter{
+		Period: r.URL.Query().Get("period"),
+		Limit:  defaultReportsLimit,
+	}
+	if filter.Period != "" && !reports.ValidPeriod(filter.Period) {
+		writeError(w, errcodes.InvalidRequest, "Invalid period: "+filter.Period)
+		return
+	}
+
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			writeError(w, errcodes.InvalidRequest, "Invalid since: "+value)
+			return
+		}
+		filter.Since = since
+	}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, errcodes.InvalidRequest, "Invalid limit: "+value)
+			return
+		}
+		if s.limits.MaxLimit > 0 && parsed > s.limits.MaxLimit {
+			writeError(w, errcodes.InvalidRequest, "Limit exceeds maximum of "+strconv.Itoa(s.limits.MaxLimit))
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), summaryQueryTimeout)
+	defer cancel()
+
+	result, err := s.storage.GetReports(ctx, filter)
+	if err != nil {
+		s.logger.Errorf("Failed to get reports: %v", err)
+		writeError(w, errcodes.Internal, "Failed to get reports")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ReportsResponse{Reports: result})
+}
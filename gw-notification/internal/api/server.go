@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-common/buildinfo"
+	"gw-common/errcodes"
+	"gw-notification/internal/kafka"
+	"gw-notification/internal/storages"
+)
+
+// readyTimeout ограничение времени проверки готовности
+const readyTimeout = 3 * time.Second
+
+// Состояния проверки Kafka в ответе readiness
+const (
+	kafkaStatusOK          = "ok"
+	kafkaStatusUnavailable = "unavailable"
+)
+
+// KafkaHealth результат проверки доступности брокеров Kafka
+type KafkaHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// APIError ошибка API в общем формате сервисов gw-project: код из реестра
+// gw-common/errcodes, его числовое значение и сообщение
+type APIError struct {
+	Code    string `json:"code"`
+	Number  int    `json:"number"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse тело ответа с ошибкой
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// Server HTTP сервер служебного API сервиса уведомлений
+type Server struct {
+	httpServer *http.Server
+	consumer   *kafka.Consumer
+	storage    storages.Storage
+	adminToken string
+	limits     QueryLimits
+	// stallTimeout время без прогресса при непрочитанных сообщениях,
+	// после которого consumer считается зависшим
+	stallTimeout time.Duration
+	reloader     ConfigReloader
+	logger       *logrus.Logger
+}
+
+// QueryLimits ограничения параметров запросов служебного API
+type QueryLimits struct {
+	MaxLimit  int           // максимальное число записей в ответе
+	MaxWindow time.Duration // максимальный период выборки
+}
+
+// NewServer создает HTTP сервер служебного API
+func NewServer(port, adminToken string, limits QueryLimits, stallTimeout time.Duration, consumer *kafka.Consumer, storage storages.Storage, logger *logrus.Logger) *Server {
+	s := &Server{
+		consumer:     consumer,
+		storage:      storage,
+		adminToken:   adminToken,
+		limits:       limits,
+		stallTimeout: stallTimeout,
+		logger:       logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", s.handleLive)
+	mux.HandleFunc("/health/ready", s.handleReady)
+	// Прежний адрес readiness сохранен для совместимости
+	mux.HandleFunc("/ready", s.handleReady)
+	mux.HandleFunc("/version", s.handleVersion)
+	mux.Handle("/admin/summary", s.adminOnly(http.HandlerFunc(s.handleSummary)))
+	mux.Handle("/admin/reports", s.adminOnly(http.HandlerFunc(s.handleReports)))
+	mux.Handle("/admin/flags", s.adminOnly(http.HandlerFunc(s.handleFlags)))
+	mux.Handle("/admin/consumer/pause", s.adminOnly(http.HandlerFunc(s.handleConsumerPause)))
+	mux.Handle("/admin/consumer/resume", s.adminOnly(http.HandlerFunc(s.handleConsumerResume)))
+	mux.Handle("/admin/config/reload", s.adminOnly(http.HandlerFunc(s.handleConfigReload)))
+
+	s.httpServer = &http.Server{
+		Addr:         ":" + port,
+		Handler:      mux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	return s
+}
+
+// Handler возвращает обработчик запросов служебного API
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Start запускает HTTP сервер (блокирующий вызов)
+func (s *Server) Start() error {
+	s.logger.Infof("HTTP server is listening on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown останавливает HTTP сервер
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down HTTP server")
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleVersion возвращает версию, коммит и время сборки сервиса
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+// handleLive сообщает, что процесс работает: 503, если consumer завис и его
+// нужно перезапустить. Недоступность MongoDB и Kafka на liveness не влияет
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if s.consumer == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"live": true})
+		return
+	}
+
+	health := s.consumer.Health()
+	if err := s.consumer.Stalled(s.stallTimeout); err != nil {
+		s.logger.Errorf("Liveness check failed: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"live": false, "error": err.Error(), "consumer": health})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"live": true, "consumer": health})
+}
+
+// handleReady сообщает о готовности сервиса принимать нагрузку: 200, если
+// доступны хранилище и брокеры Kafka, иначе 503. В ответе возвращается
+// состояние каждой зависимости
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errcodes.MethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	ready := true
+	response := map[string]interface{}{}
+
+	details, err := s.storage.Health(ctx)
+	if err != nil {
+		s.logger.Warnf("Readiness check failed: %v", err)
+		ready = false
+	}
+	response["storage"] = details
+
+	if s.consumer != nil {
+		kafkaHealth := KafkaHealth{Status: kafkaStatusOK}
+		if err := s.consumer.PingBrokers(ctx); err != nil {
+			s.logger.Warnf("Readiness check failed: %v", err)
+			kafkaHealth = KafkaHealth{Status: kafkaStatusUnavailable, Error: err.Error()}
+			ready = false
+		}
+		response["kafka"] = kafkaHealth
+	}
+
+	response["ready"] = ready
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
+
+// adminOnly проверяет токен администратора, если он задан в конфигурации
+func (s *Server) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken != "" {
+			token := r.Header.Get("X-Admin-Token")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+				writeError(w, errcodes.Unauthorized, "Unauthorized")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeError отвечает ошибкой со статусом из реестра кодов
+func writeError(w http.ResponseWriter, code errcodes.Code, message string) {
+	writeJSON(w, code.HTTPStatus(), ErrorResponse{Error: APIError{
+		Code:    string(code),
+		Number:  code.Number(),
+		Message: message,
+	}})
+}
+
+// writeJSON сериализует ответ в JSON
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,216 @@
+// Package app собирает компоненты gw-notification по конфигурации и управляет их
+// жизненным циклом: New создает и связывает компоненты, Run запускает consumers,
+// служебный HTTP API и фоновые задачи и останавливает их. cmd/main.go и
+// интеграционные тесты запускают сервис одинаково
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-common/logger"
+	"gw-notification/internal/api"
+	"gw-notification/internal/config"
+	"gw-notification/internal/kafka"
+	"gw-notification/internal/storages"
+)
+
+// App собранный сервис уведомлений
+type App struct {
+	cfg        *config.Config
+	configPath string
+	logger     *logrus.Logger
+
+	storage    storages.Storage
+	consumer   *kafka.Consumer
+	userEvents *kafka.UserEventsConsumer
+	freezer    *kafka.FreezePublisher
+	httpServer *api.Server
+
+	// workers фоновые задачи, запускаемые Run
+	workers []func(ctx context.Context)
+}
+
+// Option настраивает App
+type Option func(*App)
+
+// WithLogger задает логгер; по умолчанию логгер создается по cfg.Logger
+func WithLogger(logger *logrus.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+// WithConfigPath задает файл конфигурации, который перечитывается по SIGHUP и
+// POST /admin/config/reload; без него перечитывается только окружение
+func WithConfigPath(path string) Option {
+	return func(a *App) {
+		a.configPath = path
+	}
+}
+
+// WithStorage задает хранилище вместо создаваемого по STORAGE_DRIVER, например мок в
+// интеграционных тестах. App закрывает его при остановке
+func WithStorage(storage storages.Storage) Option {
+	return func(a *App) {
+		a.storage = storage
+	}
+}
+
+// New создает и связывает компоненты сервиса. Если хранилище недоступно,
+// возвращается ошибка; уже созданные соединения при этом закрываются
+func New(cfg *config.Config, opts ...Option) (*App, error) {
+	a := &App{cfg: cfg}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.logger == nil {
+		log, err := logger.NewWithConfig(cfg.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+		a.logger = log
+	}
+
+	if err := a.build(); err != nil {
+		a.close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Logger возвращает логгер сервиса
+func (a *App) Logger() *logrus.Logger {
+	return a.logger
+}
+
+// Storage возвращает хранилище
+func (a *App) Storage() storages.Storage {
+	return a.storage
+}
+
+// Consumer возвращает consumer крупных переводов
+func (a *App) Consumer() *kafka.Consumer {
+	return a.consumer
+}
+
+// Handler возвращает обработчик служебного HTTP API для вызова без сетевого сервера
+func (a *App) Handler() http.Handler {
+	return a.httpServer.Handler()
+}
+
+// Run запускает consumers, служебный HTTP API и фоновые задачи и работает до
+// отмены ctx или ошибки consumer. При остановке обработка сообщений завершается
+// в пределах MAX_PROCESSING_TIME, затем соединения закрываются
+func (a *App) Run(ctx context.Context) error {
+	log := a.logger
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+	for _, run := range a.workers {
+		workers.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer workers.Done()
+			run(workersCtx)
+		}(run)
+	}
+
+	consumerErr := make(chan error, 1)
+	go func() {
+		consumerErr <- a.consumer.Start(workersCtx)
+	}()
+
+	go func() {
+		if err := a.httpServer.Start(); err != nil {
+			log.Errorf("HTTP server error: %v", err)
+		}
+	}()
+
+	log.Info("Service is running. Press Ctrl+C to stop...")
+
+	// Ожидание отмены или ошибки consumer
+	var runErr error
+	consumerStopped := false
+	select {
+	case <-ctx.Done():
+		log.Info("Received shutdown signal...")
+	case err := <-consumerErr:
+		consumerStopped = true
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Errorf("Consumer error: %v", err)
+			runErr = fmt.Errorf("consumer failed: %w", err)
+		}
+	}
+
+	// Graceful shutdown
+	log.Info("Shutting down service...")
+	stopWorkers()
+
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := a.httpServer.Shutdown(httpCtx); err != nil {
+		log.Errorf("HTTP server shutdown error: %v", err)
+	}
+	httpCancel()
+
+	// Даем время на завершение обработки
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), a.cfg.Processing.MaxProcessingTime)
+	defer shutdownCancel()
+
+	// Ждем завершения consumer и фоновых задач
+	if !consumerStopped {
+		select {
+		case <-shutdownCtx.Done():
+		case err := <-consumerErr:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Errorf("Consumer shutdown error: %v", err)
+			}
+		}
+	}
+	finished := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-shutdownCtx.Done():
+	}
+	if shutdownCtx.Err() != nil {
+		log.Warn("Shutdown timeout exceeded, forcing exit")
+	}
+
+	// Финальная статистика
+	printFinalStatistics(log, a.consumer, a.storage)
+
+	a.close()
+	return runErr
+}
+
+// close закрывает consumers, publisher и хранилище
+func (a *App) close() {
+	if a.userEvents != nil {
+		a.userEvents.Close()
+	}
+	if a.freezer != nil {
+		a.freezer.Close()
+	}
+	if a.consumer != nil {
+		a.consumer.Close()
+	}
+	if a.storage != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		a.storage.Close(ctx)
+	}
+}
+
+// addWorker добавляет фоновую задачу, которая работает до отмены ctx
+func (a *App) addWorker(run func(ctx context.Context)) {
+	a.workers = append(a.workers, run)
+}
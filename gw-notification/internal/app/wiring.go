@@ -0,0 +1,390 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-common/kafkasecurity"
+	"gw-common/logger"
+	"gw-common/utils"
+	"gw-notification/internal/analytics"
+	"gw-notification/internal/api"
+	"gw-notification/internal/config"
+	"gw-notification/internal/kafka"
+	"gw-notification/internal/reports"
+	"gw-notification/internal/retention"
+	"gw-notification/internal/rules"
+	"gw-notification/internal/storages"
+	"gw-notification/internal/storages/memory"
+	"gw-notification/internal/storages/mongodb"
+	"gw-notification/internal/storages/postgres"
+)
+
+// build создает компоненты сервиса в порядке зависимостей
+func (a *App) build() error {
+	cfg, log := a.cfg, a.logger
+
+	// Подключение к хранилищу (выбирается через STORAGE_DRIVER)
+	if a.storage == nil {
+		storage, err := ConnectStorage(cfg, log)
+		if err != nil {
+			return err
+		}
+		a.storage = storage
+	}
+
+	// Копия сохраненных переводов в ClickHouse для аналитики
+	storage, err := WithAnalytics(cfg, a.storage, log)
+	if err != nil {
+		return err
+	}
+	a.storage = storage
+
+	// SASL и TLS соединений с Kafka
+	kafkaSecurity, err := kafkasecurity.New(cfg.Kafka.Security)
+	if err != nil {
+		return fmt.Errorf("invalid Kafka security settings: %w", err)
+	}
+	if cfg.Kafka.Security.PlaintextCredentials() {
+		log.Warn("Kafka SASL PLAIN without TLS sends credentials in clear text")
+	}
+
+	// Создание Kafka consumer
+	kafkaConfig := &kafka.Config{
+		Brokers:       cfg.Kafka.Brokers,
+		Topic:         cfg.Kafka.Topic,
+		GroupID:       cfg.Kafka.GroupID,
+		Partition:     cfg.Kafka.Partition,
+		MinBytes:      cfg.Kafka.MinBytes,
+		MaxBytes:      cfg.Kafka.MaxBytes,
+		MaxWait:       cfg.Kafka.MaxWait,
+		BatchSize:     cfg.Processing.BatchSize,
+		Workers:       cfg.Processing.Workers,
+		FlushInterval: cfg.Processing.FlushInterval,
+		RetryAttempts: cfg.Processing.RetryAttempts,
+		RetryDelay:    cfg.Processing.RetryDelay,
+		DrainTimeout:  cfg.Processing.MaxProcessingTime,
+		MessageFormat: cfg.Kafka.MessageFormat,
+		Security:      kafkaSecurity,
+	}
+
+	a.consumer = kafka.NewConsumer(kafkaConfig, storage, log)
+
+	// Правила обнаружения подозрительной активности по сохраненным переводам
+	if cfg.Rules.Enabled {
+		ruleAlerters := alerters(cfg.Rules, log)
+		// Заморозка выводов и обменов в кошельке по новым отметкам
+		if cfg.Rules.FreezeTopic != "" {
+			a.freezer = kafka.NewFreezePublisher(kafkaConfig, cfg.Rules.FreezeTopic, cfg.Rules.FreezeDuration, log)
+			ruleAlerters = append(ruleAlerters, a.freezer)
+		}
+		engine := rules.NewEngine(storage, ruleSet(cfg.Rules), ruleAlerters, cfg.Rules.HistorySize, log)
+		a.consumer.OnSaved(engine.EvaluateBatch)
+		log.Infof("Suspicious activity rules enabled (alerts: %s)", cfg.Rules.AlertChannel)
+	}
+
+	// Consumer событий пользователей кошелька
+	if cfg.Kafka.UserEventsTopic != "" {
+		a.userEvents = kafka.NewUserEventsConsumer(kafkaConfig, cfg.Kafka.UserEventsTopic, storage, log)
+		userEvents := a.userEvents
+		a.addWorker(func(ctx context.Context) {
+			if err := userEvents.Start(ctx); err != nil {
+				log.Errorf("User events consumer error: %v", err)
+			}
+		})
+	}
+
+	// Служебный HTTP API
+	queryLimits := api.QueryLimits{
+		MaxLimit:  cfg.Query.MaxLimit,
+		MaxWindow: cfg.Query.MaxWindow,
+	}
+	a.httpServer = api.NewServer(cfg.HTTP.Port, cfg.HTTP.AdminToken, queryLimits, cfg.Processing.StallTimeout, a.consumer, storage, log)
+
+	// Перечитывание конфигурации без перезапуска: по SIGHUP и POST /admin/config/reload
+	reloader := config.NewReloader(a.configPath, cfg, func(next *config.Config) error {
+		return logger.SetLevel(log, next.Logger.Level)
+	}, log)
+	a.httpServer.SetConfigReloader(reloader)
+	a.addWorker(reloader.Run)
+
+	// Очистка переводов старше срока хранения
+	if cfg.Retention.Period > 0 {
+		purger := retention.NewPurger(storage, cfg.Retention.Period, cfg.Retention.Interval, cfg.Retention.BatchSize, log)
+		a.addWorker(purger.Run)
+	}
+
+	// Отчеты по крупным переводам за завершившиеся дни и недели
+	if cfg.Reports.Enabled {
+		generator := reports.NewGenerator(storage, reports.Config{
+			Periods:  cfg.Reports.Periods,
+			Delay:    cfg.Reports.Delay,
+			Interval: cfg.Reports.CheckInterval,
+			TopUsers: cfg.Reports.TopUsers,
+		}, reportPublishers(cfg.Reports), log)
+		a.addWorker(generator.Run)
+	}
+
+	// Вывод статистики
+	a.addWorker(func(ctx context.Context) {
+		statsTicker := time.NewTicker(30 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-statsTicker.C:
+				printStatistics(log, a.consumer, storage)
+			}
+		}
+	})
+	return nil
+}
+
+// MongoConfig возвращает параметры подключения к MongoDB из конфигурации
+func MongoConfig(cfg *config.Config) *mongodb.Config {
+	return &mongodb.Config{
+		URI:         cfg.MongoDB.URI,
+		Database:    cfg.MongoDB.Database,
+		Collection:  cfg.MongoDB.Collection,
+		Timeout:     cfg.MongoDB.Timeout,
+		MaxPoolSize: cfg.MongoDB.MaxPoolSize,
+		MinPoolSize: cfg.MongoDB.MinPoolSize,
+
+		QueryMaxTime:   cfg.Query.MaxTime,
+		QueryMaxLimit:  cfg.Query.MaxLimit,
+		QueryBatchSize: int32(cfg.Query.BatchSize),
+
+		MigrateOnStart: cfg.MongoDB.MigrateOnStart,
+	}
+}
+
+// PostgresConfig возвращает параметры подключения к PostgreSQL из конфигурации
+func PostgresConfig(cfg *config.Config) *postgres.Config {
+	return &postgres.Config{
+		Host:            cfg.Postgres.Host,
+		Port:            cfg.Postgres.Port,
+		User:            cfg.Postgres.User,
+		Password:        cfg.Postgres.Password,
+		DBName:          cfg.Postgres.DBName,
+		SSLMode:         cfg.Postgres.SSLMode,
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+
+		QueryMaxTime:  cfg.Query.MaxTime,
+		QueryMaxLimit: cfg.Query.MaxLimit,
+
+		MigrateOnStart: cfg.Postgres.MigrateOnStart,
+	}
+}
+
+// ConnectStorage создает хранилище из STORAGE_DRIVER. К MongoDB и PostgreSQL
+// подключается и проверяет подключение
+func ConnectStorage(cfg *config.Config, log *logrus.Logger) (storages.Storage, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageDriverMemory:
+		return memory.New(log), nil
+	case config.StorageDriverPostgres:
+		storage, err := postgres.New(PostgresConfig(cfg), log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
+		log.Info("PostgreSQL connection established")
+		return storage, nil
+	}
+
+	storage, err := mongodb.New(MongoConfig(cfg), log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := storage.Ping(ctx); err != nil {
+		storage.Close(ctx)
+		return nil, fmt.Errorf("MongoDB ping failed: %w", err)
+	}
+	log.Info("MongoDB connection established")
+	return storage, nil
+}
+
+// WithAnalytics оборачивает хранилище записью переводов в ClickHouse, если
+// CLICKHOUSE_MODE не off. Закрытие обертки дописывает очередь async
+func WithAnalytics(cfg *config.Config, storage storages.Storage, log *logrus.Logger) (storages.Storage, error) {
+	if cfg.ClickHouse.Mode == config.ClickHouseModeOff {
+		return storage, nil
+	}
+
+	sink, err := NewAnalyticsSink(cfg.ClickHouse, log)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("ClickHouse analytics sink enabled (mode: %s)", cfg.ClickHouse.Mode)
+	return analytics.NewStorage(storage, sink), nil
+}
+
+// NewAnalyticsSink проверяет доступность ClickHouse, создает таблицу и возвращает
+// sink. В режиме sync недоступный ClickHouse - ошибка запуска, в режиме async -
+// предупреждение: переводы копятся в очереди до его появления
+func NewAnalyticsSink(cfg config.ClickHouseConfig, log *logrus.Logger) (*analytics.Sink, error) {
+	writer := analytics.NewClickHouseWriter(analytics.ClickHouseConfig{
+		URL:      cfg.URL,
+		Database: cfg.Database,
+		Table:    cfg.Table,
+		User:     cfg.User,
+		Password: cfg.Password,
+		Timeout:  cfg.Timeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	err := writer.Ping(ctx)
+	if err == nil && cfg.CreateTable {
+		err = writer.CreateTable(ctx)
+	}
+	if err != nil {
+		if cfg.Mode == config.ClickHouseModeSync {
+			return nil, fmt.Errorf("ClickHouse is unavailable: %w", err)
+		}
+		log.Warnf("ClickHouse is unavailable, transfers will be queued: %v", err)
+	}
+
+	return analytics.NewSink(writer, analytics.SinkConfig{
+		Mode:          cfg.Mode,
+		QueueSize:     cfg.QueueSize,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		RetryAttempts: cfg.RetryAttempts,
+		RetryDelay:    cfg.RetryDelay,
+		Overflow:      cfg.Overflow,
+		BlockTimeout:  cfg.BlockTimeout,
+	}, log), nil
+}
+
+// ruleSet возвращает включенные правила обнаружения подозрительной активности
+func ruleSet(cfg config.RulesConfig) []rules.Rule {
+	var set []rules.Rule
+	if cfg.VelocityCount > 0 {
+		set = append(set, rules.VelocityRule{Count: cfg.VelocityCount, Window: cfg.VelocityWindow})
+	}
+	if cfg.SpikeFactor > 0 {
+		set = append(set, rules.SpikeRule{Factor: cfg.SpikeFactor, MinHistory: cfg.SpikeMinHistory})
+	}
+	return set
+}
+
+// alerters возвращает канал оповещений о подозрительной активности
+func alerters(cfg config.RulesConfig, log *logrus.Logger) []rules.Alerter {
+	if cfg.AlertChannel == rules.AlertChannelWebhook {
+		return []rules.Alerter{rules.NewWebhookAlerter(cfg.AlertWebhookURL, cfg.AlertWebhookTimeout)}
+	}
+	return []rules.Alerter{rules.NewLogAlerter(log)}
+}
+
+// reportPublishers возвращает настроенные каналы отправки сводок отчетов
+func reportPublishers(cfg config.ReportsConfig) []reports.Publisher {
+	var publishers []reports.Publisher
+	if cfg.WebhookURL != "" {
+		publishers = append(publishers, reports.NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookTimeout))
+	}
+	if cfg.SMTPAddr != "" {
+		publishers = append(publishers, reports.NewEmailPublisher(reports.EmailConfig{
+			SMTPAddr: cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.EmailFrom,
+			To:       cfg.EmailTo,
+		}))
+	}
+	return publishers
+}
+
+// printStatistics выводит текущую статистику
+func printStatistics(log *logrus.Logger, consumer *kafka.Consumer, storage storages.Storage) {
+	// Статистика consumer
+	consumerStats := consumer.GetStatistics()
+
+	log.Infof("Consumer Statistics: Processed=%d, Failed=%d, Rate=%.2f msg/s, Uptime=%.0fs",
+		consumerStats.MessagesProcessed,
+		consumerStats.MessagesFailed,
+		consumerStats.ProcessingRate,
+		consumerStats.UptimeSeconds)
+
+	// Разбивка по партициям и воркерам помогает найти перекос или зависший воркер
+	for _, p := range consumerStats.Partitions {
+		log.Infof("Partition %d: Processed=%d, Failed=%d, AvgFlush=%.1fms, MaxFlush=%.1fms",
+			p.ID, p.Processed, p.Failed, p.AvgFlushLatencyMs, p.MaxFlushLatencyMs)
+	}
+	for _, w := range consumerStats.Workers {
+		log.Debugf("Worker %d: Processed=%d, Failed=%d, Flushes=%d, AvgFlush=%.1fms, LastFlush=%s",
+			w.ID, w.Processed, w.Failed, w.Flushes, w.AvgFlushLatencyMs, w.LastFlushAt.Format(time.RFC3339))
+	}
+
+	// Статистика хранилища
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health, err := storage.Health(ctx)
+	if err != nil {
+		log.Warnf("Storage health check failed: %v", err)
+	} else {
+		lastWrite := "never"
+		if health.LastWriteAt != nil {
+			lastWrite = health.LastWriteAt.Format(time.RFC3339)
+		}
+		log.Infof("Storage Health: Latency=%.1fms, Pool=%d/%d (in use/open, max %d), LastWrite=%s",
+			health.LatencyMs, health.Pool.InUse, health.Pool.Open, health.Pool.MaxSize, lastWrite)
+		if health.Retention != nil {
+			log.Infof("Retention: PurgedTotal=%d, LastPurged=%d, LastPurge=%s",
+				health.Retention.PurgedTotal, health.Retention.LastPurged, health.Retention.LastPurgeAt.Format(time.RFC3339))
+		}
+	}
+
+	if analyticsStorage, ok := storage.(*analytics.Storage); ok {
+		stats := analyticsStorage.Sink().Stats()
+		log.Infof("Analytics Sink: Mode=%s, Queued=%d, Written=%d, Dropped=%d, Failed=%d",
+			stats.Mode, stats.Queued, stats.Written, stats.Dropped, stats.Failed)
+	}
+
+	storageStats, err := storage.GetStatistics(ctx)
+	if err != nil {
+		log.Warnf("Failed to get storage statistics: %v", err)
+		return
+	}
+
+	log.Infof("Storage Statistics: Total=%d, Failed=%d, AvgAmount=%.2f, TotalAmount=%.2f",
+		storageStats.TotalProcessed,
+		storageStats.TotalFailed,
+		storageStats.AverageAmount,
+		storageStats.TotalAmount)
+}
+
+// printFinalStatistics выводит финальную статистику перед завершением
+func printFinalStatistics(log *logrus.Logger, consumer *kafka.Consumer, storage storages.Storage) {
+	log.Info("=== Final Statistics ===")
+
+	consumerStats := consumer.GetStatistics()
+	duration := utils.FormatDuration(time.Duration(consumerStats.UptimeSeconds * float64(time.Second)))
+
+	log.Infof("Total Messages Processed: %d", consumerStats.MessagesProcessed)
+	log.Infof("Total Messages Failed: %d", consumerStats.MessagesFailed)
+	log.Infof("Average Processing Rate: %.2f msg/s", consumerStats.ProcessingRate)
+	log.Infof("Total Uptime: %s", duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	storageStats, err := storage.GetStatistics(ctx)
+	if err != nil {
+		log.Warnf("Failed to get final storage statistics: %v", err)
+		return
+	}
+
+	log.Infof("Total Transfers in DB: %d", storageStats.TotalProcessed)
+	log.Infof("Average Transfer Amount: %.2f", storageStats.AverageAmount)
+	log.Infof("Total Amount Processed: %.2f", storageStats.TotalAmount)
+	log.Info("========================")
+}
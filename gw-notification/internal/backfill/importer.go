@@ -0,0 +1,233 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// Форматы файлов экспорта
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// walletStatusCompleted статус проведенной транзакции кошелька
+const walletStatusCompleted = "completed"
+
+// Result итог импорта
+type Result struct {
+	Read    int // прочитано записей
+	Saved   int // передано в хранилище; дубликаты по event_id хранилище пропускает
+	Skipped int // не проведенные транзакции и суммы меньше порога
+	Invalid int // записи, не прошедшие проверку
+	Batches int
+}
+
+// Importer загружает в хранилище историю транзакций, выгруженную из кошелька.
+// Записи проходят ту же проверку, что и сообщения Kafka, и сохраняются тем же
+// пакетным методом хранилища, поэтому повторный импорт и пересечение с уже
+// полученными сообщениями не создают дубликатов
+type Importer struct {
+	storage   storages.Storage
+	batchSize int
+	minAmount float64
+	logger    *logrus.Logger
+}
+
+// NewImporter создает импортер. Записи с суммой меньше minAmount (в валюте
+// транзакции) пропускаются: выгрузка кошелька содержит все транзакции, а не только крупные
+func NewImporter(storage storages.Storage, batchSize int, minAmount float64, logger *logrus.Logger) *Importer {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	return &Importer{
+		storage:   storage,
+		batchSize: batchSize,
+		minAmount: minAmount,
+		logger:    logger,
+	}
+}
+
+// ImportFile импортирует файл. Если format не задан, он определяется по расширению
+func (i *Importer) ImportFile(ctx context.Context, path, format string) (*Result, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "jsonl" || format == "ndjson" {
+			format = FormatJSON
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backfill file: %w", err)
+	}
+	defer file.Close()
+
+	return i.Import(ctx, file, format)
+}
+
+// Import читает записи из r и сохраняет их пакетами. При ошибке хранилища импорт
+// прерывается и возвращается результат до ошибки: повторный запуск безопасен
+func (i *Importer) Import(ctx context.Context, r io.Reader, format string) (*Result, error) {
+	var records recordReader
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		reader, err := newCSVReader(r)
+		if err != nil {
+			return nil, err
+		}
+		records = reader
+	case FormatJSON:
+		records = newJSONReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported backfill format: %q", format)
+	}
+
+	result := &Result{}
+	batch := make([]storages.LargeTransfer, 0, i.batchSize)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		rec, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		result.Read++
+		if err != nil {
+			if isFatal(err) {
+				return result, fmt.Errorf("failed to read record %d: %w", result.Read, err)
+			}
+			i.logger.Warnf("Skipping invalid record %d: %v", result.Read, err)
+			result.Invalid++
+			continue
+		}
+
+		transfer, skip, err := i.toTransfer(rec)
+		switch {
+		case err != nil:
+			i.logger.Warnf("Skipping invalid record %d: %v", result.Read, err)
+			result.Invalid++
+			continue
+		case skip:
+			result.Skipped++
+			continue
+		}
+
+		batch = append(batch, *transfer)
+		if len(batch) >= i.batchSize {
+			if err := i.flush(ctx, batch, result); err != nil {
+				return result, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if err := i.flush(ctx, batch, result); err != nil {
+		return result, err
+	}
+
+	i.logger.Infof("Backfill completed: Read=%d, Saved=%d, Skipped=%d, Invalid=%d, Batches=%d",
+		result.Read, result.Saved, result.Skipped, result.Invalid, result.Batches)
+
+	return result, nil
+}
+
+// toTransfer проверяет запись и преобразует ее в перевод. skip сообщает, что
+// запись корректна, но импортировать ее не нужно
+func (i *Importer) toTransfer(rec *record) (*storages.LargeTransfer, bool, error) {
+	if rec.Status != "" && rec.Status != walletStatusCompleted {
+		return nil, true, nil
+	}
+
+	msg := rec.message()
+	transfer, err := msg.ToTransfer()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Без идентификатора события повторный импорт создал бы дубликаты
+	if transfer.EventID == "" {
+		return nil, false, fmt.Errorf("record has neither event_id nor transaction_id")
+	}
+
+	if transfer.Amount < i.minAmount {
+		return nil, true, nil
+	}
+
+	return transfer, false, nil
+}
+
+// flush сохраняет пакет переводов
+func (i *Importer) flush(ctx context.Context, batch []storages.LargeTransfer, result *Result) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := i.storage.SaveTransferBatch(ctx, batch); err != nil {
+		return fmt.Errorf("failed to save backfill batch: %w", err)
+	}
+
+	result.Saved += len(batch)
+	result.Batches++
+	i.logger.Debugf("Backfill batch saved: size=%d, total=%d", len(batch), result.Saved)
+	return nil
+}
+
+// record запись выгрузки. Поддерживаются поля сообщений Kafka и столбцы таблицы
+// transactions кошелька (id, from_amount, status, created_at, completed_at)
+type record struct {
+	EventID       string     `json:"event_id"`
+	TransactionID int64      `json:"transaction_id"`
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Type          string     `json:"type"`
+	FromCurrency  string     `json:"from_currency"`
+	ToCurrency    string     `json:"to_currency"`
+	Amount        float64    `json:"amount"`
+	FromAmount    float64    `json:"from_amount"`
+	Status        string     `json:"status"`
+	Timestamp     time.Time  `json:"timestamp"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+// message приводит запись к сообщению Kafka. Время события, как и в кошельке, -
+// время проведения транзакции, а если его нет - время создания
+func (r *record) message() storages.KafkaMessage {
+	msg := storages.KafkaMessage{
+		EventID:       r.EventID,
+		TransactionID: r.TransactionID,
+		UserID:        r.UserID,
+		Type:          strings.ToLower(r.Type),
+		FromCurrency:  strings.ToUpper(r.FromCurrency),
+		ToCurrency:    strings.ToUpper(r.ToCurrency),
+		Amount:        r.Amount,
+		Timestamp:     r.Timestamp,
+	}
+
+	if msg.TransactionID == 0 {
+		msg.TransactionID = r.ID
+	}
+	if msg.Amount == 0 {
+		msg.Amount = r.FromAmount
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = r.CreatedAt
+		if r.CompletedAt != nil {
+			msg.Timestamp = *r.CompletedAt
+		}
+	}
+
+	return msg
+}
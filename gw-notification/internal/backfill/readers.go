@@ -0,0 +1,200 @@
+package backfill
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordReader читает записи выгрузки. Возвращает io.EOF после последней записи
+type recordReader interface {
+	Next() (*record, error)
+}
+
+// fatalError ошибка, после которой чтение файла продолжить нельзя
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+
+// isFatal сообщает, что ошибка прерывает чтение файла
+func isFatal(err error) bool {
+	var fatal *fatalError
+	return errors.As(err, &fatal)
+}
+
+// jsonReader читает JSON массив записей или записи, разделенные переводом строки
+type jsonReader struct {
+	reader  *bufio.Reader
+	decoder *json.Decoder
+	array   bool
+	started bool
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	reader := bufio.NewReader(r)
+	return &jsonReader{reader: reader, decoder: json.NewDecoder(reader)}
+}
+
+// Next возвращает следующую запись. Некорректный JSON прерывает чтение,
+// запись с полем неверного типа пропускается
+func (r *jsonReader) Next() (*record, error) {
+	if !r.started {
+		r.started = true
+		if err := r.openArray(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.array && !r.decoder.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := r.decoder.Decode(&raw); err != nil {
+		if err == io.EOF && !r.array {
+			return nil, io.EOF
+		}
+		return nil, &fatalError{fmt.Errorf("invalid JSON: %w", err)}
+	}
+
+	var rec record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return &rec, nil
+}
+
+// openArray определяет формат по первому символу и пропускает начало массива
+func (r *jsonReader) openArray() error {
+	for {
+		b, err := r.reader.Peek(1)
+		if err != nil {
+			return nil // пустой файл, Decode вернет io.EOF
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.reader.ReadByte()
+			continue
+		case '[':
+			if _, err := r.decoder.Token(); err != nil {
+				return &fatalError{fmt.Errorf("invalid JSON: %w", err)}
+			}
+			r.array = true
+		}
+		return nil
+	}
+}
+
+// csvReader читает CSV с заголовком. Имена столбцов совпадают с полями JSON
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+// Next возвращает следующую запись. Строка с неверным числом полей или
+// значением пропускается, ошибка разбора CSV прерывает чтение
+func (r *csvReader) Next() (*record, error) {
+	row, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		if errors.Is(err, csv.ErrFieldCount) {
+			return nil, err
+		}
+		return nil, &fatalError{err}
+	}
+
+	var rec record
+	for i, value := range row {
+		if err := rec.set(r.columns[i], strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", r.columns[i], value, err)
+		}
+	}
+	return &rec, nil
+}
+
+// set заполняет поле записи по имени столбца. Неизвестные столбцы пропускаются
+func (r *record) set(column, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var err error
+	switch column {
+	case "event_id":
+		r.EventID = value
+	case "transaction_id":
+		r.TransactionID, err = strconv.ParseInt(value, 10, 64)
+	case "id":
+		r.ID, err = strconv.ParseInt(value, 10, 64)
+	case "user_id":
+		r.UserID, err = strconv.ParseInt(value, 10, 64)
+	case "type":
+		r.Type = value
+	case "from_currency":
+		r.FromCurrency = value
+	case "to_currency":
+		r.ToCurrency = value
+	case "amount":
+		r.Amount, err = strconv.ParseFloat(value, 64)
+	case "from_amount":
+		r.FromAmount, err = strconv.ParseFloat(value, 64)
+	case "status":
+		r.Status = value
+	case "timestamp":
+		r.Timestamp, err = parseTime(value)
+	case "created_at":
+		r.CreatedAt, err = parseTime(value)
+	case "completed_at":
+		var t time.Time
+		if t, err = parseTime(value); err == nil {
+			r.CompletedAt = &t
+		}
+	}
+	return err
+}
+
+// timeLayouts форматы времени: RFC 3339 и вывод PostgreSQL (COPY ... CSV)
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseTime разбирает время в одном из форматов timeLayouts. Время без зоны считается UTC
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format")
+}
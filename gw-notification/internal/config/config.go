@@ -2,22 +2,30 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
+	"gw-common/configfile"
+	"gw-common/env"
+	"gw-common/kafkasecurity"
+	"gw-common/logger"
 )
 
 // Config содержит всю конфигурацию приложения
 type Config struct {
 	Service    ServiceConfig
+	HTTP       HTTPConfig
+	Storage    StorageConfig
 	MongoDB    MongoDBConfig
+	Postgres   PostgresConfig
 	Kafka      KafkaConfig
 	Processing ProcessingConfig
-	Logger     LoggerConfig
+	Query      QueryConfig
+	Retention  RetentionConfig
+	Reports    ReportsConfig
+	Rules      RulesConfig
+	ClickHouse ClickHouseConfig
+	Logger     logger.Config
 }
 
 // ServiceConfig содержит конфигурацию сервиса
@@ -25,6 +33,19 @@ type ServiceConfig struct {
 	Name string
 }
 
+// HTTPConfig содержит конфигурацию служебного HTTP API
+type HTTPConfig struct {
+	Port       string
+	AdminToken string
+}
+
+// StorageConfig содержит выбор хранилища
+type StorageConfig struct {
+	// Driver mongodb, postgres или memory. Хранилище memory держит данные в памяти
+	// процесса и теряет их при перезапуске: для демонстраций и быстрых тестов без БД
+	Driver string
+}
+
 // MongoDBConfig содержит конфигурацию MongoDB
 type MongoDBConfig struct {
 	URI         string
@@ -33,6 +54,23 @@ type MongoDBConfig struct {
 	Timeout     time.Duration
 	MaxPoolSize uint64
 	MinPoolSize uint64
+	// MigrateOnStart применяет миграции схемы MongoDB при запуске (см. команду migrate)
+	MigrateOnStart bool
+}
+
+// PostgresConfig содержит конфигурацию PostgreSQL для STORAGE_DRIVER=postgres
+type PostgresConfig struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// MigrateOnStart применяет миграции схемы PostgreSQL при запуске (см. команду migrate)
+	MigrateOnStart bool
 }
 
 // KafkaConfig содержит конфигурацию Kafka
@@ -44,28 +82,117 @@ type KafkaConfig struct {
 	MinBytes  int
 	MaxBytes  int
 	MaxWait   time.Duration
+	// UserEventsTopic топик событий жизненного цикла пользователей кошелька,
+	// пустое значение отключает их обработку
+	UserEventsTopic string
+	// MessageFormat формат сообщений без заголовка content-type: json или protobuf
+	MessageFormat string
+	// Security SASL и TLS соединений с брокерами (KAFKA_SASL_*, KAFKA_TLS_*)
+	Security kafkasecurity.Config
 }
 
 // ProcessingConfig содержит конфигурацию обработки
 type ProcessingConfig struct {
-	BatchSize          int
-	Workers            int
-	FlushInterval      time.Duration
-	MaxProcessingTime  time.Duration
-	RetryAttempts      int
-	RetryDelay         time.Duration
+	BatchSize         int
+	Workers           int
+	FlushInterval     time.Duration
+	MaxProcessingTime time.Duration
+	RetryAttempts     int
+	RetryDelay        time.Duration
+	// StallTimeout время без прогресса при непрочитанных сообщениях,
+	// после которого /health/live сообщает о зависании consumer
+	StallTimeout time.Duration
+}
+
+// QueryConfig содержит ограничения запросов служебного API к MongoDB.
+// Запросы используют общий пул соединений с записью переводов,
+// поэтому один тяжелый запрос не должен занимать его надолго
+type QueryConfig struct {
+	MaxLimit  int           // максимальное число записей в ответе
+	MaxWindow time.Duration // максимальный период выборки
+	MaxTime   time.Duration // maxTimeMS запросов к MongoDB
+	BatchSize int           // размер пакета курсора
+}
+
+// RetentionConfig содержит срок хранения крупных переводов
+type RetentionConfig struct {
+	Period    time.Duration // срок хранения с момента обработки, 0 - хранить бессрочно
+	Interval  time.Duration // пауза между очистками
+	BatchSize int           // число документов, удаляемых за одну операцию
+}
+
+// ReportsConfig содержит расписание и каналы отправки отчетов по крупным переводам
+type ReportsConfig struct {
+	Enabled       bool
+	Periods       []string      // daily, weekly
+	Delay         time.Duration // задержка после окончания периода для опоздавших сообщений
+	CheckInterval time.Duration // пауза между проверками расписания
+	TopUsers      int           // размер топа пользователей в отчете
+
+	// WebhookURL адрес для JSON сводки, пусто - не отправлять
+	WebhookURL     string
+	WebhookTimeout time.Duration
+	// SMTPAddr адрес SMTP сервера host:port, пусто - не отправлять письма
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	EmailFrom    string
+	EmailTo      []string
 }
 
-// LoggerConfig содержит конфигурацию логгера
-type LoggerConfig struct {
-	Level string
+// RulesConfig содержит параметры правил обнаружения подозрительной активности
+type RulesConfig struct {
+	Enabled bool
+	// VelocityCount число крупных переводов за VelocityWindow, 0 - правило отключено
+	VelocityCount  int
+	VelocityWindow time.Duration
+	// SpikeFactor кратность превышения средней суммы, 0 - правило отключено
+	SpikeFactor     float64
+	SpikeMinHistory int // минимум предыдущих переводов для сравнения со средней
+	HistorySize     int // число последних переводов пользователя для правил
+
+	AlertChannel        string // log, webhook
+	AlertWebhookURL     string
+	AlertWebhookTimeout time.Duration
+
+	// FreezeTopic топик управляющих сообщений кошельку: пользователь с новой
+	// отметкой замораживается на FreezeDuration. Пусто - не замораживать
+	FreezeTopic    string
+	FreezeDuration time.Duration
 }
 
-// Load загружает конфигурацию из файла окружения
+// ClickHouseConfig содержит параметры записи крупных переводов в ClickHouse для аналитики
+type ClickHouseConfig struct {
+	// Mode off, async или sync. В async сохраненные пакеты ставятся в очередь и
+	// пишутся фоном, в sync пакет считается сохраненным после записи в обе базы
+	Mode     string
+	URL      string // адрес HTTP интерфейса
+	Database string
+	Table    string
+	User     string
+	Password string
+	Timeout  time.Duration // срок одного запроса к ClickHouse
+	// CreateTable создает таблицу при запуске, если ее нет
+	CreateTable bool
+
+	// Параметры очереди async
+	QueueSize     int           // емкость очереди в переводах
+	BatchSize     int           // максимум переводов в одном INSERT
+	FlushInterval time.Duration // пауза перед записью неполного пакета
+	RetryAttempts int
+	RetryDelay    time.Duration
+	// Overflow поведение при заполненной очереди: block задерживает consumer не
+	// дольше BlockTimeout, затем пакет отбрасывается; drop отбрасывает сразу
+	Overflow     string
+	BlockTimeout time.Duration
+}
+
+// Load загружает конфигурацию из переменных окружения и файла конфигурации
+// (.env, YAML или JSON, см. gw-common/configfile). Переменные окружения важнее файла
 func Load(configPath string) (*Config, error) {
 	// Загрузка переменных окружения из файла
 	if configPath != "" {
-		if err := godotenv.Load(configPath); err != nil {
+		if err := configfile.Load(configPath); err != nil {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 	}
@@ -73,76 +200,147 @@ func Load(configPath string) (*Config, error) {
 	cfg := &Config{}
 
 	// Service
-	cfg.Service.Name = getEnv("SERVICE_NAME", DefaultServiceName)
+	cfg.Service.Name = env.String("SERVICE_NAME", DefaultServiceName)
+
+	// HTTP
+	cfg.HTTP.Port = env.String("HTTP_PORT", DefaultHTTPPort)
+	cfg.HTTP.AdminToken = env.String("ADMIN_TOKEN", "")
+
+	// Storage
+	cfg.Storage.Driver = env.String("STORAGE_DRIVER", DefaultStorageDriver)
 
 	// MongoDB
-	cfg.MongoDB.URI = getEnv("MONGO_URI", DefaultMongoURI)
-	cfg.MongoDB.Database = getEnv("MONGO_DATABASE", DefaultMongoDatabase)
-	cfg.MongoDB.Collection = getEnv("MONGO_COLLECTION", DefaultMongoCollection)
-	cfg.MongoDB.Timeout = getEnvDuration("MONGO_TIMEOUT", DefaultMongoTimeout)
-	cfg.MongoDB.MaxPoolSize = uint64(getEnvInt("MONGO_MAX_POOL_SIZE", DefaultMongoMaxPoolSize))
-	cfg.MongoDB.MinPoolSize = uint64(getEnvInt("MONGO_MIN_POOL_SIZE", DefaultMongoMinPoolSize))
+	cfg.MongoDB.URI = env.String("MONGO_URI", DefaultMongoURI)
+	cfg.MongoDB.Database = env.String("MONGO_DATABASE", DefaultMongoDatabase)
+	cfg.MongoDB.Collection = env.String("MONGO_COLLECTION", DefaultMongoCollection)
+	cfg.MongoDB.Timeout = env.Duration("MONGO_TIMEOUT", DefaultMongoTimeout)
+	cfg.MongoDB.MaxPoolSize = uint64(env.Int("MONGO_MAX_POOL_SIZE", DefaultMongoMaxPoolSize))
+	cfg.MongoDB.MinPoolSize = uint64(env.Int("MONGO_MIN_POOL_SIZE", DefaultMongoMinPoolSize))
+	cfg.MongoDB.MigrateOnStart = env.Bool("MONGO_MIGRATE_ON_START", DefaultMongoMigrateOnStart)
+
+	// PostgreSQL
+	cfg.Postgres.Host = env.String("DB_HOST", DefaultDBHost)
+	cfg.Postgres.Port = env.Int("DB_PORT", DefaultDBPort)
+	cfg.Postgres.User = env.String("DB_USER", DefaultDBUser)
+	cfg.Postgres.Password = env.String("DB_PASSWORD", DefaultDBPassword)
+	cfg.Postgres.DBName = env.String("DB_NAME", DefaultDBName)
+	cfg.Postgres.SSLMode = env.String("DB_SSLMODE", DefaultDBSSLMode)
+	cfg.Postgres.MaxOpenConns = env.Int("DB_MAX_OPEN_CONNS", DefaultDBMaxOpenConns)
+	cfg.Postgres.MaxIdleConns = env.Int("DB_MAX_IDLE_CONNS", DefaultDBMaxIdleConns)
+	cfg.Postgres.ConnMaxLifetime = env.Duration("DB_CONN_MAX_LIFETIME", DefaultDBConnMaxLifetime)
+	cfg.Postgres.MigrateOnStart = env.Bool("DB_MIGRATE_ON_START", DefaultDBMigrateOnStart)
 
 	// Kafka
-	brokers := getEnv("KAFKA_BROKERS", DefaultKafkaBrokers)
+	brokers := env.String("KAFKA_BROKERS", DefaultKafkaBrokers)
 	cfg.Kafka.Brokers = strings.Split(brokers, ",")
-	cfg.Kafka.Topic = getEnv("KAFKA_TOPIC", DefaultKafkaTopic)
-	cfg.Kafka.GroupID = getEnv("KAFKA_GROUP_ID", DefaultKafkaGroupID)
-	cfg.Kafka.Partition = getEnvInt("KAFKA_PARTITION", DefaultKafkaPartition)
-	cfg.Kafka.MinBytes = getEnvInt("KAFKA_MIN_BYTES", DefaultKafkaMinBytes)
-	cfg.Kafka.MaxBytes = getEnvInt("KAFKA_MAX_BYTES", DefaultKafkaMaxBytes)
-	cfg.Kafka.MaxWait = getEnvDuration("KAFKA_MAX_WAIT", DefaultKafkaMaxWait)
+	cfg.Kafka.Topic = env.String("KAFKA_TOPIC", DefaultKafkaTopic)
+	cfg.Kafka.GroupID = env.String("KAFKA_GROUP_ID", DefaultKafkaGroupID)
+	cfg.Kafka.Partition = env.Int("KAFKA_PARTITION", DefaultKafkaPartition)
+	cfg.Kafka.MinBytes = env.Int("KAFKA_MIN_BYTES", DefaultKafkaMinBytes)
+	cfg.Kafka.MaxBytes = env.Int("KAFKA_MAX_BYTES", DefaultKafkaMaxBytes)
+	cfg.Kafka.MaxWait = env.Duration("KAFKA_MAX_WAIT", DefaultKafkaMaxWait)
+	cfg.Kafka.UserEventsTopic = env.String("KAFKA_USER_EVENTS_TOPIC", DefaultKafkaUserEventsTopic)
+	cfg.Kafka.MessageFormat = strings.ToLower(env.String("KAFKA_MESSAGE_FORMAT", DefaultKafkaMessageFormat))
+	cfg.Kafka.Security = kafkasecurity.FromEnv()
 
 	// Processing
-	cfg.Processing.BatchSize = getEnvInt("BATCH_SIZE", DefaultBatchSize)
-	cfg.Processing.Workers = getEnvInt("WORKERS", DefaultWorkers)
-	cfg.Processing.FlushInterval = getEnvDuration("FLUSH_INTERVAL", DefaultFlushInterval)
-	cfg.Processing.MaxProcessingTime = getEnvDuration("MAX_PROCESSING_TIME", DefaultMaxProcessingTime)
-	cfg.Processing.RetryAttempts = getEnvInt("RETRY_ATTEMPTS", DefaultRetryAttempts)
-	cfg.Processing.RetryDelay = getEnvDuration("RETRY_DELAY", DefaultRetryDelay)
+	cfg.Processing.BatchSize = env.Int("BATCH_SIZE", DefaultBatchSize)
+	cfg.Processing.Workers = env.Int("WORKERS", DefaultWorkers)
+	cfg.Processing.FlushInterval = env.Duration("FLUSH_INTERVAL", DefaultFlushInterval)
+	cfg.Processing.MaxProcessingTime = env.Duration("MAX_PROCESSING_TIME", DefaultMaxProcessingTime)
+	cfg.Processing.RetryAttempts = env.Int("RETRY_ATTEMPTS", DefaultRetryAttempts)
+	cfg.Processing.RetryDelay = env.Duration("RETRY_DELAY", DefaultRetryDelay)
+	cfg.Processing.StallTimeout = env.Duration("CONSUMER_STALL_TIMEOUT", DefaultConsumerStallTimeout)
 
-	// Logger
-	cfg.Logger.Level = getEnv("LOG_LEVEL", DefaultLogLevel)
+	// Query
+	cfg.Query.MaxLimit = env.Int("QUERY_MAX_LIMIT", DefaultQueryMaxLimit)
+	cfg.Query.MaxWindow = env.Duration("QUERY_MAX_WINDOW", DefaultQueryMaxWindow)
+	cfg.Query.MaxTime = env.Duration("QUERY_MAX_TIME", DefaultQueryMaxTime)
+	cfg.Query.BatchSize = env.Int("QUERY_BATCH_SIZE", DefaultQueryBatchSize)
 
-	return cfg, nil
-}
+	// Retention
+	cfg.Retention.Period = env.Duration("RETENTION_PERIOD", DefaultRetentionPeriod)
+	cfg.Retention.Interval = env.Duration("RETENTION_INTERVAL", DefaultRetentionInterval)
+	cfg.Retention.BatchSize = env.Int("RETENTION_BATCH_SIZE", DefaultRetentionBatchSize)
 
-// getEnv получает переменную окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+	// Reports
+	cfg.Reports.Enabled = env.Bool("REPORTS_ENABLED", DefaultReportsEnabled)
+	cfg.Reports.Periods = env.SplitList(strings.ToLower(env.String("REPORTS_PERIODS", DefaultReportsPeriods)))
+	cfg.Reports.Delay = env.Duration("REPORTS_DELAY", DefaultReportsDelay)
+	cfg.Reports.CheckInterval = env.Duration("REPORTS_CHECK_INTERVAL", DefaultReportsCheckInterval)
+	cfg.Reports.TopUsers = env.Int("REPORTS_TOP_USERS", DefaultReportsTopUsers)
+	cfg.Reports.WebhookURL = env.String("REPORTS_WEBHOOK_URL", "")
+	cfg.Reports.WebhookTimeout = env.Duration("REPORTS_WEBHOOK_TIMEOUT", DefaultReportsWebhookTimeout)
+	cfg.Reports.SMTPAddr = env.String("REPORTS_SMTP_ADDR", "")
+	cfg.Reports.SMTPUsername = env.String("REPORTS_SMTP_USERNAME", "")
+	cfg.Reports.SMTPPassword = env.String("REPORTS_SMTP_PASSWORD", "")
+	cfg.Reports.EmailFrom = env.String("REPORTS_EMAIL_FROM", "")
+	cfg.Reports.EmailTo = env.SplitList(env.String("REPORTS_EMAIL_TO", ""))
 
-// getEnvInt получает целочисленную переменную окружения
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
+	// Rules
+	cfg.Rules.Enabled = env.Bool("RULES_ENABLED", DefaultRulesEnabled)
+	cfg.Rules.VelocityCount = env.Int("RULES_VELOCITY_COUNT", DefaultRulesVelocityCount)
+	cfg.Rules.VelocityWindow = env.Duration("RULES_VELOCITY_WINDOW", DefaultRulesVelocityWindow)
+	cfg.Rules.SpikeFactor = env.Float("RULES_SPIKE_FACTOR", DefaultRulesSpikeFactor)
+	cfg.Rules.SpikeMinHistory = env.Int("RULES_SPIKE_MIN_HISTORY", DefaultRulesSpikeMinHistory)
+	cfg.Rules.HistorySize = env.Int("RULES_HISTORY_SIZE", DefaultRulesHistorySize)
+	cfg.Rules.AlertChannel = strings.ToLower(env.String("ALERTS_CHANNEL", DefaultAlertsChannel))
+	cfg.Rules.AlertWebhookURL = env.String("ALERTS_WEBHOOK_URL", "")
+	cfg.Rules.AlertWebhookTimeout = env.Duration("ALERTS_WEBHOOK_TIMEOUT", DefaultAlertsWebhookTimeout)
+	cfg.Rules.FreezeTopic = env.String("RULES_FREEZE_TOPIC", "")
+	cfg.Rules.FreezeDuration = env.Duration("RULES_FREEZE_DURATION", DefaultRulesFreezeDuration)
 
-// getEnvDuration получает переменную окружения типа duration
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
-	}
-	return defaultValue
+	// ClickHouse
+	cfg.ClickHouse.Mode = strings.ToLower(env.String("CLICKHOUSE_MODE", DefaultClickHouseMode))
+	cfg.ClickHouse.URL = env.String("CLICKHOUSE_URL", DefaultClickHouseURL)
+	cfg.ClickHouse.Database = env.String("CLICKHOUSE_DATABASE", DefaultClickHouseDatabase)
+	cfg.ClickHouse.Table = env.String("CLICKHOUSE_TABLE", DefaultClickHouseTable)
+	cfg.ClickHouse.User = env.String("CLICKHOUSE_USER", DefaultClickHouseUser)
+	cfg.ClickHouse.Password = env.String("CLICKHOUSE_PASSWORD", "")
+	cfg.ClickHouse.Timeout = env.Duration("CLICKHOUSE_TIMEOUT", DefaultClickHouseTimeout)
+	cfg.ClickHouse.CreateTable = env.Bool("CLICKHOUSE_CREATE_TABLE", DefaultClickHouseCreateTable)
+	cfg.ClickHouse.QueueSize = env.Int("CLICKHOUSE_QUEUE_SIZE", DefaultClickHouseQueueSize)
+	cfg.ClickHouse.BatchSize = env.Int("CLICKHOUSE_BATCH_SIZE", DefaultClickHouseBatchSize)
+	cfg.ClickHouse.FlushInterval = env.Duration("CLICKHOUSE_FLUSH_INTERVAL", DefaultClickHouseFlushInterval)
+	cfg.ClickHouse.RetryAttempts = env.Int("CLICKHOUSE_RETRY_ATTEMPTS", DefaultClickHouseRetryAttempts)
+	cfg.ClickHouse.RetryDelay = env.Duration("CLICKHOUSE_RETRY_DELAY", DefaultClickHouseRetryDelay)
+	cfg.ClickHouse.Overflow = strings.ToLower(env.String("CLICKHOUSE_OVERFLOW", DefaultClickHouseOverflow))
+	cfg.ClickHouse.BlockTimeout = env.Duration("CLICKHOUSE_BLOCK_TIMEOUT", DefaultClickHouseBlockTimeout)
+
+	// Logger
+	cfg.Logger = logger.FromEnv()
+
+	return cfg, nil
 }
 
 // Validate проверяет корректность конфигурации
 func (c *Config) Validate() error {
-	if c.MongoDB.URI == "" {
-		return fmt.Errorf("MONGO_URI is required")
+	if c.HTTP.Port == "" {
+		return fmt.Errorf("HTTP_PORT is required")
 	}
 
-	if c.MongoDB.Database == "" {
-		return fmt.Errorf("MONGO_DATABASE is required")
+	switch c.Storage.Driver {
+	case StorageDriverMongoDB:
+		if c.MongoDB.URI == "" {
+			return fmt.Errorf("MONGO_URI is required")
+		}
+		if c.MongoDB.Database == "" {
+			return fmt.Errorf("MONGO_DATABASE is required")
+		}
+	case StorageDriverPostgres:
+		if c.Postgres.Host == "" {
+			return fmt.Errorf("DB_HOST is required")
+		}
+		if c.Postgres.User == "" {
+			return fmt.Errorf("DB_USER is required")
+		}
+		if c.Postgres.DBName == "" {
+			return fmt.Errorf("DB_NAME is required")
+		}
+	case StorageDriverMemory:
+	default:
+		return fmt.Errorf("unsupported STORAGE_DRIVER: %s (expected %s, %s or %s)",
+			c.Storage.Driver, StorageDriverMongoDB, StorageDriverPostgres, StorageDriverMemory)
 	}
 
 	if len(c.Kafka.Brokers) == 0 {
@@ -153,6 +351,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("KAFKA_TOPIC is required")
 	}
 
+	switch c.Kafka.MessageFormat {
+	case "json", "protobuf":
+	default:
+		return fmt.Errorf("invalid KAFKA_MESSAGE_FORMAT: %s (expected json or protobuf)", c.Kafka.MessageFormat)
+	}
+
+	if err := c.Kafka.Security.Validate(); err != nil {
+		return err
+	}
+
 	if c.Processing.BatchSize <= 0 {
 		return fmt.Errorf("BATCH_SIZE must be positive")
 	}
@@ -161,8 +369,129 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("WORKERS must be positive")
 	}
 
-	if _, err := logrus.ParseLevel(c.Logger.Level); err != nil {
-		return fmt.Errorf("invalid log level: %s", c.Logger.Level)
+	if c.Processing.StallTimeout <= 0 {
+		return fmt.Errorf("CONSUMER_STALL_TIMEOUT must be positive")
+	}
+
+	if c.Query.MaxLimit <= 0 {
+		return fmt.Errorf("QUERY_MAX_LIMIT must be positive")
+	}
+
+	if c.Query.MaxWindow <= 0 {
+		return fmt.Errorf("QUERY_MAX_WINDOW must be positive")
+	}
+
+	if c.Query.MaxTime <= 0 {
+		return fmt.Errorf("QUERY_MAX_TIME must be positive")
+	}
+
+	if c.Query.BatchSize <= 0 {
+		return fmt.Errorf("QUERY_BATCH_SIZE must be positive")
+	}
+
+	if c.Retention.Period < 0 {
+		return fmt.Errorf("RETENTION_PERIOD must not be negative")
+	}
+	if c.Retention.Period > 0 {
+		if c.Retention.Interval <= 0 {
+			return fmt.Errorf("RETENTION_INTERVAL must be positive")
+		}
+		if c.Retention.BatchSize <= 0 {
+			return fmt.Errorf("RETENTION_BATCH_SIZE must be positive")
+		}
+	}
+
+	if c.Reports.Enabled {
+		if len(c.Reports.Periods) == 0 {
+			return fmt.Errorf("REPORTS_PERIODS is required when REPORTS_ENABLED=true")
+		}
+		for _, period := range c.Reports.Periods {
+			if period != "daily" && period != "weekly" {
+				return fmt.Errorf("invalid REPORTS_PERIODS: %s (expected daily or weekly)", period)
+			}
+		}
+		if c.Reports.Delay < 0 {
+			return fmt.Errorf("REPORTS_DELAY must not be negative")
+		}
+		if c.Reports.CheckInterval <= 0 {
+			return fmt.Errorf("REPORTS_CHECK_INTERVAL must be positive")
+		}
+		if c.Reports.TopUsers <= 0 {
+			return fmt.Errorf("REPORTS_TOP_USERS must be positive")
+		}
+		if c.Reports.SMTPAddr != "" && (c.Reports.EmailFrom == "" || len(c.Reports.EmailTo) == 0) {
+			return fmt.Errorf("REPORTS_EMAIL_FROM and REPORTS_EMAIL_TO are required with REPORTS_SMTP_ADDR")
+		}
+	}
+
+	if c.Rules.Enabled {
+		if c.Rules.VelocityCount < 0 || (c.Rules.VelocityCount > 0 && c.Rules.VelocityWindow <= 0) {
+			return fmt.Errorf("RULES_VELOCITY_COUNT must not be negative and RULES_VELOCITY_WINDOW must be positive")
+		}
+		if c.Rules.SpikeFactor < 0 || (c.Rules.SpikeFactor > 0 && c.Rules.SpikeFactor <= 1) {
+			return fmt.Errorf("RULES_SPIKE_FACTOR must be greater than 1 (0 disables the rule)")
+		}
+		if c.Rules.SpikeMinHistory < 1 {
+			return fmt.Errorf("RULES_SPIKE_MIN_HISTORY must be positive")
+		}
+		if c.Rules.HistorySize < c.Rules.VelocityCount || c.Rules.HistorySize < c.Rules.SpikeMinHistory+1 {
+			return fmt.Errorf("RULES_HISTORY_SIZE must cover RULES_VELOCITY_COUNT and RULES_SPIKE_MIN_HISTORY")
+		}
+		if c.Query.MaxLimit < c.Rules.HistorySize {
+			return fmt.Errorf("RULES_HISTORY_SIZE must not exceed QUERY_MAX_LIMIT")
+		}
+		switch c.Rules.AlertChannel {
+		case "log":
+		case "webhook":
+			if c.Rules.AlertWebhookURL == "" {
+				return fmt.Errorf("ALERTS_WEBHOOK_URL is required for ALERTS_CHANNEL=webhook")
+			}
+		default:
+			return fmt.Errorf("invalid ALERTS_CHANNEL: %s (expected log or webhook)", c.Rules.AlertChannel)
+		}
+		if c.Rules.FreezeTopic != "" && c.Rules.FreezeDuration <= 0 {
+			return fmt.Errorf("RULES_FREEZE_DURATION must be positive")
+		}
+	}
+
+	switch c.ClickHouse.Mode {
+	case ClickHouseModeOff:
+	case ClickHouseModeAsync, ClickHouseModeSync:
+		if c.ClickHouse.URL == "" || c.ClickHouse.Table == "" {
+			return fmt.Errorf("CLICKHOUSE_URL and CLICKHOUSE_TABLE are required when CLICKHOUSE_MODE=%s", c.ClickHouse.Mode)
+		}
+		if c.ClickHouse.Timeout <= 0 {
+			return fmt.Errorf("CLICKHOUSE_TIMEOUT must be positive")
+		}
+		if c.ClickHouse.Mode == ClickHouseModeSync {
+			break
+		}
+		if c.ClickHouse.QueueSize <= 0 || c.ClickHouse.BatchSize <= 0 {
+			return fmt.Errorf("CLICKHOUSE_QUEUE_SIZE and CLICKHOUSE_BATCH_SIZE must be positive")
+		}
+		if c.ClickHouse.FlushInterval <= 0 {
+			return fmt.Errorf("CLICKHOUSE_FLUSH_INTERVAL must be positive")
+		}
+		if c.ClickHouse.RetryAttempts < 1 {
+			return fmt.Errorf("CLICKHOUSE_RETRY_ATTEMPTS must be positive")
+		}
+		switch c.ClickHouse.Overflow {
+		case ClickHouseOverflowBlock:
+			if c.ClickHouse.BlockTimeout <= 0 {
+				return fmt.Errorf("CLICKHOUSE_BLOCK_TIMEOUT must be positive")
+			}
+		case ClickHouseOverflowDrop:
+		default:
+			return fmt.Errorf("invalid CLICKHOUSE_OVERFLOW: %s (expected %s or %s)",
+				c.ClickHouse.Overflow, ClickHouseOverflowBlock, ClickHouseOverflowDrop)
+		}
+	default:
+		return fmt.Errorf("invalid CLICKHOUSE_MODE: %s (expected %s, %s or %s)",
+			c.ClickHouse.Mode, ClickHouseModeOff, ClickHouseModeAsync, ClickHouseModeSync)
+	}
+
+	if err := c.Logger.Validate(); err != nil {
+		return err
 	}
 
 	return nil
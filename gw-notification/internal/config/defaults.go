@@ -5,7 +5,23 @@ import "time"
 // Service defaults
 const (
 	DefaultServiceName = "gw-notification"
-	DefaultLogLevel    = "info"
+)
+
+// HTTP defaults
+const (
+	DefaultHTTPPort = "8081"
+)
+
+// Поддерживаемые хранилища
+const (
+	StorageDriverMongoDB  = "mongodb"
+	StorageDriverPostgres = "postgres"
+	StorageDriverMemory   = "memory"
+)
+
+// Storage defaults
+const (
+	DefaultStorageDriver = StorageDriverMongoDB
 )
 
 // MongoDB defaults
@@ -16,6 +32,22 @@ const (
 	DefaultMongoTimeout     = 10 * time.Second
 	DefaultMongoMaxPoolSize = 100
 	DefaultMongoMinPoolSize = 10
+
+	DefaultMongoMigrateOnStart = true
+)
+
+// PostgreSQL defaults
+const (
+	DefaultDBHost            = "localhost"
+	DefaultDBPort            = 5432
+	DefaultDBUser            = "notification_user"
+	DefaultDBPassword        = "notification_password"
+	DefaultDBName            = "notification_db"
+	DefaultDBSSLMode         = "disable"
+	DefaultDBMaxOpenConns    = 25
+	DefaultDBMaxIdleConns    = 5
+	DefaultDBConnMaxLifetime = 5 * time.Minute
+	DefaultDBMigrateOnStart  = true
 )
 
 // Kafka defaults
@@ -27,14 +59,88 @@ const (
 	DefaultKafkaMinBytes  = 1
 	DefaultKafkaMaxBytes  = 10485760 // 10MB
 	DefaultKafkaMaxWait   = 500 * time.Millisecond
+
+	DefaultKafkaUserEventsTopic = "user-lifecycle"
+	DefaultKafkaMessageFormat   = "json"
 )
 
 // Processing defaults
 const (
-	DefaultBatchSize          = 100
-	DefaultWorkers            = 10
-	DefaultFlushInterval      = 5 * time.Second
-	DefaultMaxProcessingTime  = 30 * time.Second
-	DefaultRetryAttempts      = 3
-	DefaultRetryDelay         = 1 * time.Second
+	DefaultBatchSize         = 100
+	DefaultWorkers           = 10
+	DefaultFlushInterval     = 5 * time.Second
+	DefaultMaxProcessingTime = 30 * time.Second
+	DefaultRetryAttempts     = 3
+	DefaultRetryDelay        = 1 * time.Second
+	// Зависание должно быть заметно дольше повторов сохранения пакета
+	DefaultConsumerStallTimeout = 5 * time.Minute
+)
+
+// Query defaults
+const (
+	DefaultQueryMaxLimit  = 100
+	DefaultQueryMaxWindow = 30 * 24 * time.Hour
+	DefaultQueryMaxTime   = 5 * time.Second
+	DefaultQueryBatchSize = 100
+)
+
+// Retention defaults
+const (
+	// По умолчанию переводы хранятся бессрочно
+	DefaultRetentionPeriod    = 0
+	DefaultRetentionInterval  = 1 * time.Hour
+	DefaultRetentionBatchSize = 1000
+)
+
+// Reports defaults
+const (
+	DefaultReportsEnabled        = false
+	DefaultReportsPeriods        = "daily,weekly"
+	DefaultReportsDelay          = 1 * time.Hour
+	DefaultReportsCheckInterval  = 10 * time.Minute
+	DefaultReportsTopUsers       = 10
+	DefaultReportsWebhookTimeout = 10 * time.Second
+)
+
+// Rules defaults
+const (
+	DefaultRulesEnabled         = false
+	DefaultRulesVelocityCount   = 3
+	DefaultRulesVelocityWindow  = 10 * time.Minute
+	DefaultRulesSpikeFactor     = 10.0
+	DefaultRulesSpikeMinHistory = 3
+	DefaultRulesHistorySize     = 50
+	DefaultAlertsChannel        = "log"
+	DefaultAlertsWebhookTimeout = 10 * time.Second
+	DefaultRulesFreezeDuration  = 24 * time.Hour
+)
+
+// Режимы записи в ClickHouse и поведение при заполненной очереди
+const (
+	ClickHouseModeOff   = "off"
+	ClickHouseModeAsync = "async"
+	ClickHouseModeSync  = "sync"
+
+	ClickHouseOverflowBlock = "block"
+	ClickHouseOverflowDrop  = "drop"
+)
+
+// ClickHouse defaults
+const (
+	DefaultClickHouseMode        = ClickHouseModeOff
+	DefaultClickHouseURL         = "http://localhost:8123"
+	DefaultClickHouseDatabase    = "default"
+	DefaultClickHouseTable       = "large_transfers"
+	DefaultClickHouseUser        = "default"
+	DefaultClickHouseTimeout     = 10 * time.Second
+	DefaultClickHouseCreateTable = true
+
+	// Очередь async выдерживает около минуты простоя ClickHouse при 150 переводах в секунду
+	DefaultClickHouseQueueSize     = 10000
+	DefaultClickHouseBatchSize     = 1000
+	DefaultClickHouseFlushInterval = 1 * time.Second
+	DefaultClickHouseRetryAttempts = 3
+	DefaultClickHouseRetryDelay    = 1 * time.Second
+	DefaultClickHouseOverflow      = ClickHouseOverflowBlock
+	DefaultClickHouseBlockTimeout  = 5 * time.Second
 )
@@ -2,19 +2,32 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"sort"
 	"sync"
 	"time"
 
-	"gw-notification/internal/storages"
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
+	"gw-common/kafkasecurity"
+	"gw-notification/internal/storages"
 )
 
+// maxFlushRetryDelay ограничение паузы между повторами сохранения пакета
+const maxFlushRetryDelay = time.Minute
+
+// MessageReader источник сообщений consumer: *kafka.Reader группы потребителей
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
 // Consumer Kafka consumer для получения сообщений
 type Consumer struct {
-	reader        *kafka.Reader
+	reader        MessageReader
+	brokers       []string
+	dialer        *kafka.Dialer
 	storage       storages.Storage
 	logger        *logrus.Logger
 	batchSize     int
@@ -22,12 +35,98 @@ type Consumer struct {
 	flushInterval time.Duration
 	retryAttempts int
 	retryDelay    time.Duration
-
-	// Статистика
+	// drainTimeout время на сохранение полученных сообщений при остановке
+	drainTimeout time.Duration
+	// format формат сообщений без заголовка content-type
+	format string
+	// onSaved вызывается после сохранения каждого пакета, nil - не вызывается
+	onSaved SavedHandler
+
+	// offsets упорядочивает коммиты смещений, commitMu не дает воркерам
+	// закоммитить меньшую позицию после большей
+	offsets  *OffsetTracker
+	commitMu sync.Mutex
+
+	// Статистика. messagesFailed - сообщения, пропущенные без сохранения
+	// (не удалось разобрать); пакеты, которые не удалось сохранить, не
+	// пропускаются и попадают в messagesProcessed после сохранения
 	mu                sync.RWMutex
 	messagesProcessed int64
 	messagesFailed    int64
 	startTime         time.Time
+
+	// Разбивка статистики по воркерам и партициям Kafka
+	workerStats    map[int]*ProcessingStats
+	partitionStats map[int]*ProcessingStats
+
+	// Состояние для проверки работоспособности
+	running     bool
+	lastFetchAt time.Time
+	lastFlushAt time.Time
+	lastError   string
+
+	// Пауза чтения: resumed закрывается при Resume, cancelFetch прерывает
+	// ожидающий FetchMessage при Pause
+	paused      bool
+	pausedAt    time.Time
+	resumedAt   time.Time
+	resumed     chan struct{}
+	cancelFetch context.CancelFunc
+}
+
+// SavedHandler получает сохраненный пакет переводов, например для проверки
+// правилами. Вызывается в горутине воркера после коммита пакета
+type SavedHandler func(ctx context.Context, transfers []storages.LargeTransfer)
+
+// Health описывает текущее состояние consumer
+type Health struct {
+	Running     bool      `json:"running"`
+	Lag         int64     `json:"lag"`
+	LastFetchAt time.Time `json:"last_fetch_at"`
+	LastFlushAt time.Time `json:"last_flush_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	Paused      bool      `json:"paused"`
+	PausedAt    time.Time `json:"paused_at"`
+}
+
+// ProcessingStats статистика обработки одного воркера или одной партиции
+type ProcessingStats struct {
+	ID                int       `json:"id"`
+	Processed         int64     `json:"processed"`
+	Failed            int64     `json:"failed"`
+	Flushes           int64     `json:"flushes"`
+	AvgFlushLatencyMs float64   `json:"avg_flush_latency_ms"`
+	MaxFlushLatencyMs float64   `json:"max_flush_latency_ms"`
+	LastFlushAt       time.Time `json:"last_flush_at"`
+
+	totalFlushDuration time.Duration
+}
+
+// addFlush учитывает успешный flush пакета
+func (s *ProcessingStats) addFlush(count int64, duration time.Duration) {
+	s.Processed += count
+	s.Flushes++
+	s.totalFlushDuration += duration
+	s.AvgFlushLatencyMs = durationMs(s.totalFlushDuration) / float64(s.Flushes)
+	if ms := durationMs(duration); ms > s.MaxFlushLatencyMs {
+		s.MaxFlushLatencyMs = ms
+	}
+	s.LastFlushAt = time.Now()
+}
+
+// durationMs переводит duration в миллисекунды
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// snapshotStats возвращает копию статистики, отсортированную по ID
+func snapshotStats(stats map[int]*ProcessingStats) []ProcessingStats {
+	result := make([]ProcessingStats, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
 }
 
 // Config конфигурация consumer
@@ -44,27 +143,42 @@ type Config struct {
 	FlushInterval time.Duration
 	RetryAttempts int
 	RetryDelay    time.Duration
+	// DrainTimeout время на сохранение и коммит уже полученных сообщений
+	// после отмены контекста Start, 0 - без ограничения
+	DrainTimeout time.Duration
+	// MessageFormat формат сообщений без заголовка content-type: json или protobuf
+	MessageFormat string
+	// Security SASL и TLS соединений с брокерами, nil - без аутентификации
+	Security *kafkasecurity.Security
 }
 
 // NewConsumer создает новый Kafka consumer
 func NewConsumer(cfg *Config, storage storages.Storage, logger *logrus.Logger) *Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:   cfg.Brokers,
-		Topic:     cfg.Topic,
-		GroupID:   cfg.GroupID,
-		Partition: cfg.Partition,
-		MinBytes:  cfg.MinBytes,
-		MaxBytes:  cfg.MaxBytes,
-		MaxWait:   cfg.MaxWait,
-		Logger:    kafka.LoggerFunc(logger.Debugf),
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		GroupID:     cfg.GroupID,
+		Partition:   cfg.Partition,
+		MinBytes:    cfg.MinBytes,
+		MaxBytes:    cfg.MaxBytes,
+		MaxWait:     cfg.MaxWait,
+		Dialer:      cfg.Security.Dialer(),
+		Logger:      kafka.LoggerFunc(logger.Debugf),
 		ErrorLogger: kafka.LoggerFunc(logger.Errorf),
 	})
 
-	logger.Infof("Kafka consumer initialized: Topic=%s, GroupID=%s, Brokers=%v",
-		cfg.Topic, cfg.GroupID, cfg.Brokers)
+	logger.Infof("Kafka consumer initialized: Topic=%s, GroupID=%s, Brokers=%v, Security=%s",
+		cfg.Topic, cfg.GroupID, cfg.Brokers, cfg.Security)
+
+	return NewConsumerWithReader(cfg, reader, storage, logger)
+}
 
+// NewConsumerWithReader создает consumer, читающий сообщения из reader
+func NewConsumerWithReader(cfg *Config, reader MessageReader, storage storages.Storage, logger *logrus.Logger) *Consumer {
 	return &Consumer{
 		reader:        reader,
+		brokers:       cfg.Brokers,
+		dialer:        cfg.Security.Dialer(),
 		storage:       storage,
 		logger:        logger,
 		batchSize:     cfg.BatchSize,
@@ -72,16 +186,43 @@ func NewConsumer(cfg *Config, storage storages.Storage, logger *logrus.Logger) *
 		flushInterval: cfg.FlushInterval,
 		retryAttempts: cfg.RetryAttempts,
 		retryDelay:    cfg.RetryDelay,
+		drainTimeout:  cfg.DrainTimeout,
+		format:        cfg.MessageFormat,
+		offsets:       NewOffsetTracker(),
 		startTime:     time.Now(),
+
+		workerStats:    make(map[int]*ProcessingStats),
+		partitionStats: make(map[int]*ProcessingStats),
 	}
 }
 
-// Start запускает consumer
+// OnSaved задает обработчик сохраненных пакетов. Вызывается до Start
+func (c *Consumer) OnSaved(handler SavedHandler) {
+	c.onSaved = handler
+}
+
+// Start запускает consumer (блокирующий вызов). После отмены ctx чтение
+// прекращается, а воркеры дообрабатывают уже полученные сообщения, сохраняют
+// незавершенные пакеты и коммитят их не дольше DrainTimeout
 func (c *Consumer) Start(ctx context.Context) error {
 	c.logger.Info("Starting Kafka consumer...")
+	c.setRunning(true)
+	defer c.setRunning(false)
+
+	// Воркеры работают в контексте, который переживает отмену ctx:
+	// иначе сохранение и коммит последних пакетов прервались бы
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	stopDrain := context.AfterFunc(ctx, func() {
+		c.logger.Info("Draining in-flight messages...")
+		if c.drainTimeout > 0 {
+			time.AfterFunc(c.drainTimeout, cancelWork)
+		}
+	})
+	defer stopDrain()
 
 	// Создаем канал для сообщений
-	messages := make(chan kafka.Message, c.batchSize*2)
+	messages := make(chan Delivery, c.batchSize*2)
 
 	// Запускаем воркеры для обработки
 	var wg sync.WaitGroup
@@ -89,7 +230,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			c.processMessages(ctx, messages, workerID)
+			c.processMessages(workCtx, messages, workerID)
 		}(i)
 	}
 
@@ -106,50 +247,114 @@ func (c *Consumer) Start(ctx context.Context) error {
 	return nil
 }
 
-// readMessages читает сообщения из Kafka
-func (c *Consumer) readMessages(ctx context.Context, messages chan<- kafka.Message) {
+// readMessages читает сообщения из Kafka, пока ctx не отменен.
+// На паузе FetchMessage не вызывается
+func (c *Consumer) readMessages(ctx context.Context, messages chan<- Delivery) {
 	for {
-		select {
-		case <-ctx.Done():
+		fetchCtx, cancelFetch, ok := c.waitResumed(ctx)
+		if !ok {
 			c.logger.Info("Stopping message reading...")
 			return
-		default:
-			msg, err := c.reader.FetchMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					return
-				}
-				c.logger.Errorf("Failed to fetch message: %v", err)
-				time.Sleep(c.retryDelay)
+		}
+
+		msg, err := c.reader.FetchMessage(fetchCtx)
+		cancelFetch()
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("Stopping message reading...")
+				return
+			}
+			if fetchCtx.Err() != nil {
+				// Ожидание прервано вызовом Pause
 				continue
 			}
+			c.logger.Errorf("Failed to fetch message: %v", err)
+			c.recordError(err)
+			sleepContext(ctx, c.retryDelay)
+			continue
+		}
+
+		c.recordFetch()
+		messages <- c.offsets.Track(msg)
+	}
+}
+
+// waitResumed ждет снятия паузы и возвращает контекст очередного FetchMessage,
+// который прерывается вызовом Pause. Возвращает false, если ctx отменен
+func (c *Consumer) waitResumed(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+	for {
+		c.mu.Lock()
+		if !c.paused {
+			fetchCtx, cancel := context.WithCancel(ctx)
+			c.cancelFetch = cancel
+			c.mu.Unlock()
+			return fetchCtx, cancel, true
+		}
+		resumed := c.resumed
+		c.mu.Unlock()
 
-			messages <- msg
+		select {
+		case <-ctx.Done():
+			return nil, nil, false
+		case <-resumed:
 		}
 	}
 }
 
-// processMessages обрабатывает сообщения из канала
-func (c *Consumer) processMessages(ctx context.Context, messages <-chan kafka.Message, workerID int) {
+// Pause приостанавливает чтение сообщений, например на время обслуживания
+// MongoDB. Consumer остается в группе: reader продолжает отправлять heartbeat,
+// а партиции не переназначаются. Уже полученные сообщения сохраняются воркерами.
+// Возвращает false, если consumer уже на паузе
+func (c *Consumer) Pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return false
+	}
+	c.paused = true
+	c.pausedAt = time.Now()
+	c.resumed = make(chan struct{})
+	if c.cancelFetch != nil {
+		c.cancelFetch()
+	}
+
+	c.logger.Info("Kafka consumer paused")
+	return true
+}
+
+// Resume возобновляет чтение сообщений. Возвращает false, если consumer не на паузе
+func (c *Consumer) Resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return false
+	}
+	c.paused = false
+	c.pausedAt = time.Time{}
+	c.resumedAt = time.Now()
+	close(c.resumed)
+
+	c.logger.Info("Kafka consumer resumed")
+	return true
+}
+
+// processMessages обрабатывает сообщения из канала до его закрытия.
+// ctx отменяется только по истечении времени на дообработку при остановке
+func (c *Consumer) processMessages(ctx context.Context, messages <-chan Delivery, workerID int) {
 	batch := make([]storages.LargeTransfer, 0, c.batchSize)
-	kafkaMessages := make([]kafka.Message, 0, c.batchSize)
+	kafkaMessages := make([]Delivery, 0, c.batchSize)
 
 	ticker := time.NewTicker(c.flushInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ctx.Done():
-			// Сохраняем оставшиеся сообщения перед выходом
-			if len(batch) > 0 {
-				c.flushBatch(ctx, batch, kafkaMessages)
-			}
-			return
-
 		case <-ticker.C:
 			// Периодическое сохранение пакета
 			if len(batch) > 0 {
-				c.flushBatch(ctx, batch, kafkaMessages)
+				c.flushBatch(ctx, workerID, batch, kafkaMessages)
 				batch = batch[:0]
 				kafkaMessages = kafkaMessages[:0]
 			}
@@ -158,18 +363,18 @@ func (c *Consumer) processMessages(ctx context.Context, messages <-chan kafka.Me
 			if !ok {
 				// Канал закрыт, сохраняем оставшееся
 				if len(batch) > 0 {
-					c.flushBatch(ctx, batch, kafkaMessages)
+					c.flushBatch(ctx, workerID, batch, kafkaMessages)
 				}
 				return
 			}
 
 			// Парсим сообщение
-			transfer, err := c.parseMessage(msg)
+			transfer, err := c.parseMessage(msg.Message)
 			if err != nil {
 				c.logger.Errorf("Worker %d: Failed to parse message: %v", workerID, err)
-				c.incrementFailed()
+				c.recordFailed(workerID, []Delivery{msg})
 				// Все равно коммитим, чтобы не блокировать очередь
-				if err := c.reader.CommitMessages(ctx, msg); err != nil {
+				if err := c.commit(ctx, msg); err != nil {
 					c.logger.Errorf("Worker %d: Failed to commit failed message: %v", workerID, err)
 				}
 				continue
@@ -181,7 +386,7 @@ func (c *Consumer) processMessages(ctx context.Context, messages <-chan kafka.Me
 
 			// Если пакет заполнен, сохраняем
 			if len(batch) >= c.batchSize {
-				c.flushBatch(ctx, batch, kafkaMessages)
+				c.flushBatch(ctx, workerID, batch, kafkaMessages)
 				batch = batch[:0]
 				kafkaMessages = kafkaMessages[:0]
 			}
@@ -189,95 +394,240 @@ func (c *Consumer) processMessages(ctx context.Context, messages <-chan kafka.Me
 	}
 }
 
-// parseMessage парсит сообщение из Kafka
+// parseMessage разбирает сообщение декодером его версии схемы и проверяет перевод
 func (c *Consumer) parseMessage(msg kafka.Message) (*storages.LargeTransfer, error) {
-	var kafkaMsg storages.KafkaMessage
-	if err := json.Unmarshal(msg.Value, &kafkaMsg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	kafkaMsg, err := DecodeTransferMessage(msg, c.format)
+	if err != nil {
+		return nil, err
+	}
+	if kafkaMsg.SchemaVersion > LatestSchemaVersion {
+		c.logger.Debugf("Decoding message of schema version %d as version %d", kafkaMsg.SchemaVersion, LatestSchemaVersion)
 	}
 
-	transfer := &storages.LargeTransfer{
-		UserID:       kafkaMsg.UserID,
-		Type:         kafkaMsg.Type,
-		FromCurrency: kafkaMsg.FromCurrency,
-		ToCurrency:   kafkaMsg.ToCurrency,
-		Amount:       kafkaMsg.Amount,
-		Timestamp:    kafkaMsg.Timestamp,
+	transfer, err := kafkaMsg.ToTransfer()
+	if err != nil {
+		return nil, err
 	}
 
+	// Без идентификатора от producer дубликаты при повторной доставке
+	// отсекаются по хешу содержимого
+	if transfer.EventID == "" {
+		transfer.EventID = transfer.ContentEventID()
+	}
 	return transfer, nil
 }
 
-// flushBatch сохраняет пакет сообщений в MongoDB
-func (c *Consumer) flushBatch(ctx context.Context, batch []storages.LargeTransfer, messages []kafka.Message) {
+// headerValue возвращает значение заголовка сообщения или пустую строку
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// flushBatch сохраняет пакет сообщений в MongoDB и коммитит его. Несохраненный
+// пакет не коммитится: после RetryAttempts неудачных попыток сохранение
+// повторяется с растущей паузой, пока не пройдет или не истечет время на
+// дообработку при остановке. Пока воркер повторяет пакет, он не разбирает канал,
+// поэтому чтение из Kafka останавливается, когда канал заполнится; сообщения,
+// не сохраненные до остановки, будут получены повторно после перезапуска
+func (c *Consumer) flushBatch(ctx context.Context, workerID int, batch []storages.LargeTransfer, messages []Delivery) {
 	if len(batch) == 0 {
 		return
 	}
 
 	start := time.Now()
 
-	// Пытаемся сохранить пакет с повторами
-	var err error
-	for attempt := 0; attempt < c.retryAttempts; attempt++ {
-		err = c.storage.SaveTransferBatch(ctx, batch)
+	backoff := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := c.storage.SaveTransferBatch(ctx, batch)
 		if err == nil {
 			break
 		}
-
-		c.logger.Warnf("Attempt %d/%d: Failed to save batch: %v",
-			attempt+1, c.retryAttempts, err)
-
-		if attempt < c.retryAttempts-1 {
-			time.Sleep(c.retryDelay)
+		c.recordError(err)
+
+		delay := c.retryDelay
+		if attempt < c.retryAttempts {
+			c.logger.Warnf("Attempt %d/%d: Failed to save batch: %v", attempt, c.retryAttempts, err)
+		} else {
+			delay = backoff
+			backoff = min(backoff*2, maxFlushRetryDelay)
+			c.logger.Errorf("Worker %d: Failed to save batch of %d messages after %d attempts, retrying in %v: %v",
+				workerID, len(batch), attempt, delay, err)
 		}
-	}
 
-	if err != nil {
-		c.logger.Errorf("Failed to save batch after %d attempts: %v", c.retryAttempts, err)
-		c.incrementFailed()
-		return
+		if !sleepContext(ctx, delay) {
+			c.logger.Errorf("Worker %d: Stopped before saving batch of %d messages, they will be redelivered after restart",
+				workerID, len(batch))
+			return
+		}
 	}
 
 	// Коммитим сообщения в Kafka
-	if err := c.reader.CommitMessages(ctx, messages...); err != nil {
+	if err := c.commit(ctx, messages...); err != nil {
 		c.logger.Errorf("Failed to commit messages: %v", err)
+		c.recordError(err)
 		return
 	}
+	c.recordFlush()
 
 	duration := time.Since(start)
-	c.incrementProcessed(int64(len(batch)))
+	c.recordProcessed(workerID, messages, duration)
 
 	c.logger.Infof("Flushed batch: size=%d, duration=%v, rate=%.2f msg/s",
 		len(batch), duration, float64(len(batch))/duration.Seconds())
+
+	if c.onSaved != nil {
+		c.onSaved(ctx, batch)
+	}
+}
+
+// commit отмечает сообщения обработанными и коммитит позиции партиций, до
+// которых обработаны все полученные сообщения. Сообщения партиции, перед
+// которыми еще есть несохраненные, будут закоммичены вместе с ними позже
+func (c *Consumer) commit(ctx context.Context, messages ...Delivery) error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	positions := c.offsets.Complete(messages...)
+	if len(positions) == 0 {
+		return nil
+	}
+	return c.reader.CommitMessages(ctx, positions...)
+}
+
+// recordProcessed учитывает успешно сохраненный пакет в общей статистике,
+// статистике воркера и статистике партиций
+func (c *Consumer) recordProcessed(workerID int, messages []Delivery, flushDuration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messagesProcessed += int64(len(messages))
+	c.workerStatsLocked(workerID).addFlush(int64(len(messages)), flushDuration)
+
+	for partition, count := range countByPartition(messages) {
+		c.partitionStatsLocked(partition).addFlush(count, flushDuration)
+	}
+}
+
+// recordFailed учитывает сообщения, пропущенные без сохранения
+func (c *Consumer) recordFailed(workerID int, messages []Delivery) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messagesFailed += int64(len(messages))
+	c.workerStatsLocked(workerID).Failed += int64(len(messages))
+
+	for partition, count := range countByPartition(messages) {
+		c.partitionStatsLocked(partition).Failed += count
+	}
+}
+
+// workerStatsLocked возвращает статистику воркера, создавая ее при необходимости.
+// Вызывается под c.mu.
+func (c *Consumer) workerStatsLocked(workerID int) *ProcessingStats {
+	stats, ok := c.workerStats[workerID]
+	if !ok {
+		stats = &ProcessingStats{ID: workerID}
+		c.workerStats[workerID] = stats
+	}
+	return stats
+}
+
+// partitionStatsLocked возвращает статистику партиции, создавая ее при необходимости.
+// Вызывается под c.mu.
+func (c *Consumer) partitionStatsLocked(partition int) *ProcessingStats {
+	stats, ok := c.partitionStats[partition]
+	if !ok {
+		stats = &ProcessingStats{ID: partition}
+		c.partitionStats[partition] = stats
+	}
+	return stats
+}
+
+// countByPartition считает количество сообщений в каждой партиции
+func countByPartition(messages []Delivery) map[int]int64 {
+	counts := make(map[int]int64)
+	for _, msg := range messages {
+		counts[msg.Partition]++
+	}
+	return counts
 }
 
-// incrementProcessed увеличивает счетчик обработанных сообщений
-func (c *Consumer) incrementProcessed(count int64) {
+// setRunning отмечает, запущен ли цикл чтения consumer
+func (c *Consumer) setRunning(running bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.messagesProcessed += count
+	c.running = running
 }
 
-// incrementFailed увеличивает счетчик неудачных сообщений
-func (c *Consumer) incrementFailed() {
+// recordFetch запоминает время последнего полученного сообщения
+func (c *Consumer) recordFetch() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.messagesFailed++
+	c.lastFetchAt = time.Now()
+}
+
+// recordFlush запоминает время последнего успешного сохранения пакета
+func (c *Consumer) recordFlush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastFlushAt = time.Now()
+	c.lastError = ""
+}
+
+// recordError запоминает последнюю ошибку чтения или сохранения
+func (c *Consumer) recordError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastError = err.Error()
+}
+
+// Health возвращает текущее состояние consumer, включая отставание от топика
+func (c *Consumer) Health() Health {
+	lag := c.reader.Stats().Lag
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return Health{
+		Running:     c.running,
+		Lag:         lag,
+		LastFetchAt: c.lastFetchAt,
+		LastFlushAt: c.lastFlushAt,
+		LastError:   c.lastError,
+		Paused:      c.paused,
+		PausedAt:    c.pausedAt,
+	}
+}
+
+// Statistics статистика обработки consumer
+type Statistics struct {
+	MessagesProcessed int64             `json:"messages_processed"`
+	MessagesFailed    int64             `json:"messages_failed"` // пропущены без сохранения (не удалось разобрать)
+	ProcessingRate    float64           `json:"processing_rate"` // сообщений в секунду
+	UptimeSeconds     float64           `json:"uptime_seconds"`
+	Uncommitted       int               `json:"uncommitted"`
+	Workers           []ProcessingStats `json:"workers"`
+	Partitions        []ProcessingStats `json:"partitions"`
 }
 
 // GetStatistics возвращает статистику обработки
-func (c *Consumer) GetStatistics() map[string]interface{} {
+func (c *Consumer) GetStatistics() Statistics {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	duration := time.Since(c.startTime)
-	rate := float64(c.messagesProcessed) / duration.Seconds()
-
-	return map[string]interface{}{
-		"messages_processed": c.messagesProcessed,
-		"messages_failed":    c.messagesFailed,
-		"processing_rate":    rate,
-		"uptime_seconds":     duration.Seconds(),
+	return Statistics{
+		MessagesProcessed: c.messagesProcessed,
+		MessagesFailed:    c.messagesFailed,
+		ProcessingRate:    float64(c.messagesProcessed) / duration.Seconds(),
+		UptimeSeconds:     duration.Seconds(),
+		Uncommitted:       c.offsets.Pending(),
+		Workers:           snapshotStats(c.workerStats),
+		Partitions:        snapshotStats(c.partitionStats),
 	}
 }
 
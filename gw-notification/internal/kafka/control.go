@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// ControlActionFreeze временная заморозка выводов и обменов пользователя в кошельке
+const ControlActionFreeze = "freeze"
+
+// ControlMessage управляющее сообщение кошельку
+type ControlMessage struct {
+	EventID   string    `json:"event_id"`
+	Action    string    `json:"action"`
+	UserID    int64     `json:"user_id"`
+	Rule      string    `json:"rule,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Until     time.Time `json:"until"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FreezePublisher просит кошелек временно заморозить выводы и обмены
+// пользователя с новой отметкой подозрительной активности. Подключается
+// к правилам как оповещение (rules.Alerter)
+type FreezePublisher struct {
+	writer   *kafka.Writer
+	duration time.Duration
+	logger   *logrus.Logger
+}
+
+// NewFreezePublisher создает отправку заморозок в топик topic на duration
+func NewFreezePublisher(cfg *Config, topic string, duration time.Duration, logger *logrus.Logger) *FreezePublisher {
+	// Запись синхронная с подтверждением всех реплик: отметка сохраняется
+	// до оповещения, и повторной отправки при потере сообщения не будет
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    cfg.Security.Transport(),
+	}
+
+	logger.Infof("Freeze publisher initialized: Topic=%s, Duration=%v", topic, duration)
+
+	return &FreezePublisher{writer: writer, duration: duration, logger: logger}
+}
+
+// Name имя канала для логов
+func (p *FreezePublisher) Name() string {
+	return "wallet_freeze"
+}
+
+// Alert отправляет кошельку заморозку пользователя на duration от времени отметки.
+// event_id сообщения выводится из ID отметки, поэтому кошелек распознает повторы
+func (p *FreezePublisher) Alert(ctx context.Context, flag *storages.SuspiciousFlag) error {
+	message := ControlMessage{
+		EventID:   "freeze:" + flag.ID,
+		Action:    ControlActionFreeze,
+		UserID:    flag.UserID,
+		Rule:      flag.Rule,
+		Reason:    flag.Reason,
+		Until:     flag.FlaggedAt.Add(p.duration),
+		Timestamp: flag.FlaggedAt,
+	}
+
+	value, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal control message: %w", err)
+	}
+
+	// Ключ по пользователю сохраняет порядок сообщений одного пользователя
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(fmt.Sprintf("user_%d", flag.UserID)),
+		Value:   value,
+		Headers: []kafka.Header{{Key: EventIDHeader, Value: []byte(message.EventID)}},
+		Time:    message.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send control message: %w", err)
+	}
+
+	p.logger.Infof("Requested freeze of user %d until %s (%s)", flag.UserID, message.Until.Format(time.RFC3339), flag.Rule)
+	return nil
+}
+
+// Close закрывает writer
+func (p *FreezePublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,205 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+	"gw-notification/internal/storages"
+	pb "gw-notification/proto"
+)
+
+// Заголовки сообщений о переводах от gw-currency-wallet
+const (
+	// EventIDHeader идентификатор события, если его нет в теле
+	EventIDHeader       = "event_id"
+	MessageIDHeader     = "message_id"
+	SchemaVersionHeader = "schema_version"
+	TraceIDHeader       = "trace_id"
+	SourceHeader        = "source"
+	ContentTypeHeader   = "content-type"
+)
+
+// Форматы сериализации сообщений о переводах
+const (
+	MessageFormatJSON     = "json"
+	MessageFormatProtobuf = "protobuf"
+)
+
+// Значения ContentTypeHeader
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// LatestSchemaVersion последняя версия схемы сообщений о переводах, известная сервису
+const LatestSchemaVersion = 2
+
+// transferDecoder разбирает тело сообщения одной версии схемы
+type transferDecoder func(value []byte) (*storages.KafkaMessage, error)
+
+// transferDecoders декодеры по версиям схемы. Версия 1 - сообщения без
+// schema_version, версия 2 добавила message_id и converted_amount/currency
+var transferDecoders = map[int]transferDecoder{
+	1: decodeTransferV1,
+	2: decodeTransferV2,
+}
+
+// transferMessageV1 тело сообщения версии 1
+type transferMessageV1 struct {
+	EventID       string    `json:"event_id"`
+	TransactionID int64     `json:"transaction_id,omitempty"`
+	UserID        int64     `json:"user_id"`
+	Type          string    `json:"type"`
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	Amount        float64   `json:"amount"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// DecodeTransferMessage разбирает сообщение о переводе. Формат определяется
+// заголовком content-type, без него используется defaultFormat.
+// Для JSON версия схемы берется из заголовка schema_version, затем из тела, без
+// нее сообщение считается версией 1. Сообщения более новых версий разбираются
+// последним известным декодером: поля в схему только добавляются.
+// Идентификаторы, которых нет в теле, дополняются из заголовков
+func DecodeTransferMessage(msg kafka.Message, defaultFormat string) (*storages.KafkaMessage, error) {
+	format := defaultFormat
+	switch contentType := headerValue(msg, ContentTypeHeader); contentType {
+	case "":
+	case ContentTypeJSON:
+		format = MessageFormatJSON
+	case ContentTypeProtobuf:
+		format = MessageFormatProtobuf
+	default:
+		return nil, fmt.Errorf("unsupported content type: %q", contentType)
+	}
+
+	var message *storages.KafkaMessage
+	var err error
+	if format == MessageFormatProtobuf {
+		message, err = decodeTransferProtobuf(msg)
+	} else {
+		message, err = decodeTransferJSON(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if message.EventID == "" {
+		message.EventID = headerValue(msg, EventIDHeader)
+	}
+	if message.MessageID == "" {
+		message.MessageID = headerValue(msg, MessageIDHeader)
+	}
+	message.TraceID = headerValue(msg, TraceIDHeader)
+	message.Source = headerValue(msg, SourceHeader)
+
+	return message, nil
+}
+
+// decodeTransferJSON разбирает JSON сообщение декодером его версии схемы
+func decodeTransferJSON(msg kafka.Message) (*storages.KafkaMessage, error) {
+	version, err := schemaVersion(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, ok := transferDecoders[min(version, LatestSchemaVersion)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported schema version: %d", version)
+	}
+
+	message, err := decoder(msg.Value)
+	if err != nil {
+		return nil, err
+	}
+	message.SchemaVersion = version
+	return message, nil
+}
+
+// decodeTransferProtobuf разбирает сообщение notification.LargeTransfer.
+// Protobuf схема появилась в версии 2, совместимость версий обеспечивают номера полей
+func decodeTransferProtobuf(msg kafka.Message) (*storages.KafkaMessage, error) {
+	var transfer pb.LargeTransfer
+	if err := proto.Unmarshal(msg.Value, &transfer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+	}
+
+	message := &storages.KafkaMessage{
+		EventID:           transfer.GetEventId(),
+		TransactionID:     transfer.GetTransactionId(),
+		UserID:            transfer.GetUserId(),
+		Type:              transfer.GetType(),
+		FromCurrency:      transfer.GetFromCurrency(),
+		ToCurrency:        transfer.GetToCurrency(),
+		Amount:            transfer.GetAmount(),
+		MessageID:         transfer.GetMessageId(),
+		SchemaVersion:     int(transfer.GetSchemaVersion()),
+		ConvertedAmount:   transfer.GetConvertedAmount(),
+		ConvertedCurrency: transfer.GetConvertedCurrency(),
+	}
+	if transfer.GetTimestamp() != nil {
+		message.Timestamp = transfer.GetTimestamp().AsTime()
+	}
+	if message.SchemaVersion == 0 {
+		message.SchemaVersion = LatestSchemaVersion
+	}
+	return message, nil
+}
+
+// schemaVersion возвращает версию схемы из заголовка или тела сообщения
+func schemaVersion(msg kafka.Message) (int, error) {
+	if value := headerValue(msg, SchemaVersionHeader); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 1 {
+			return 0, fmt.Errorf("invalid schema version header: %q", value)
+		}
+		return version, nil
+	}
+
+	var envelope struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	if envelope.SchemaVersion < 0 {
+		return 0, fmt.Errorf("invalid schema version: %d", envelope.SchemaVersion)
+	}
+	if envelope.SchemaVersion == 0 {
+		return 1, nil
+	}
+	return envelope.SchemaVersion, nil
+}
+
+// decodeTransferV1 разбирает сообщение версии 1
+func decodeTransferV1(value []byte) (*storages.KafkaMessage, error) {
+	var v1 transferMessageV1
+	if err := json.Unmarshal(value, &v1); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	return &storages.KafkaMessage{
+		EventID:       v1.EventID,
+		TransactionID: v1.TransactionID,
+		UserID:        v1.UserID,
+		Type:          v1.Type,
+		FromCurrency:  v1.FromCurrency,
+		ToCurrency:    v1.ToCurrency,
+		Amount:        v1.Amount,
+		Timestamp:     v1.Timestamp,
+	}, nil
+}
+
+// decodeTransferV2 разбирает сообщение версии 2
+func decodeTransferV2(value []byte) (*storages.KafkaMessage, error) {
+	var message storages.KafkaMessage
+	if err := json.Unmarshal(value, &message); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return &message, nil
+}
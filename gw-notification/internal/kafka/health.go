@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PingBrokers проверяет, что хотя бы один брокер Kafka принимает соединения
+func (c *Consumer) PingBrokers(ctx context.Context) error {
+	dialer := c.dialer
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+
+	var lastErr error
+	for _, broker := range c.brokers {
+		conn, err := dialer.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		conn.Close()
+		return nil
+	}
+
+	return fmt.Errorf("no Kafka brokers reachable: %w", lastErr)
+}
+
+// Stalled возвращает ошибку, если consumer завис: цикл чтения остановлен или
+// в топике есть непрочитанные сообщения, а consumer дольше timeout не получал
+// и не сохранял их. Недоступность Kafka зависанием не считается: цикл чтения
+// продолжает попытки, а отставание неизвестно. Consumer на паузе не считается
+// зависшим
+func (c *Consumer) Stalled(timeout time.Duration) error {
+	health := c.Health()
+	if !health.Running {
+		return fmt.Errorf("consumer is not running")
+	}
+	if health.Paused || health.Lag <= 0 {
+		return nil
+	}
+
+	// Время паузы не считается простоем
+	c.mu.RLock()
+	lastProgress := c.startTime
+	resumedAt := c.resumedAt
+	c.mu.RUnlock()
+	for _, t := range []time.Time{resumedAt, health.LastFetchAt, health.LastFlushAt} {
+		if t.After(lastProgress) {
+			lastProgress = t
+		}
+	}
+
+	if idle := time.Since(lastProgress); idle > timeout {
+		return fmt.Errorf("no progress for %v with lag %d", idle.Round(time.Second), health.Lag)
+	}
+	return nil
+}
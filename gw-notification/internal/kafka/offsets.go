@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// OffsetTracker следит за порядком обработки сообщений в каждой партиции.
+// Воркеры сохраняют пакеты независимо, поэтому сообщение с большим смещением
+// может быть сохранено раньше меньшего. Коммит смещения в Kafka подтверждает
+// все предыдущие сообщения партиции, поэтому трекер разрешает коммит только до
+// смещения, все сообщения до которого включительно уже обработаны
+type OffsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+// partitionKey идентифицирует партицию топика
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+// partitionOffsets полученные и еще не закоммиченные смещения партиции
+type partitionOffsets struct {
+	// generation номер чтения партиции, растет при каждом сбросе после перебалансировки
+	generation uint64
+	// pending смещения в порядке получения (по возрастанию)
+	pending []int64
+	// done обработанные смещения из pending
+	done map[int64]bool
+}
+
+// Delivery полученное сообщение и поколение партиции, в котором оно получено.
+// Завершение сообщения из прежнего поколения трекер игнорирует
+type Delivery struct {
+	kafka.Message
+	generation uint64
+}
+
+// NewOffsetTracker создает пустой трекер смещений
+func NewOffsetTracker() *OffsetTracker {
+	return &OffsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+// Track регистрирует полученное сообщение. Вызывается в порядке чтения, до
+// передачи сообщения воркерам. Смещение не больше уже полученного означает, что
+// партиция перечитывается после перебалансировки: прежнее состояние сбрасывается
+// и начинается новое поколение
+func (t *OffsetTracker) Track(msg kafka.Message) Delivery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	} else if len(p.pending) > 0 && msg.Offset <= p.pending[len(p.pending)-1] {
+		p = &partitionOffsets{generation: p.generation + 1, done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+	p.pending = append(p.pending, msg.Offset)
+	return Delivery{Message: msg, generation: p.generation}
+}
+
+// Complete отмечает сообщения обработанными и возвращает по одному сообщению
+// на каждую партицию, позиция которой продвинулась: его смещение - наибольшее,
+// до которого включительно обработаны все полученные сообщения партиции.
+// Сообщения, полученные до перебалансировки, пропускаются: их смещения могут
+// совпадать с перечитываемыми, которые еще не обработаны
+func (t *OffsetTracker) Complete(deliveries ...Delivery) []kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	touched := make(map[partitionKey]bool)
+	for _, msg := range deliveries {
+		key := partitionKey{topic: msg.Topic, partition: msg.Partition}
+		p, ok := t.partitions[key]
+		if ok && msg.generation == p.generation && len(p.pending) > 0 &&
+			msg.Offset >= p.pending[0] && msg.Offset <= p.pending[len(p.pending)-1] {
+			p.done[msg.Offset] = true
+			touched[key] = true
+		}
+	}
+
+	var commits []kafka.Message
+	for key := range touched {
+		p := t.partitions[key]
+
+		advanced := false
+		var offset int64
+		for len(p.pending) > 0 && p.done[p.pending[0]] {
+			offset = p.pending[0]
+			delete(p.done, offset)
+			p.pending = p.pending[1:]
+			advanced = true
+		}
+
+		if advanced {
+			commits = append(commits, kafka.Message{Topic: key.topic, Partition: key.partition, Offset: offset})
+		}
+	}
+
+	sort.Slice(commits, func(i, j int) bool {
+		if commits[i].Topic != commits[j].Topic {
+			return commits[i].Topic < commits[j].Topic
+		}
+		return commits[i].Partition < commits[j].Partition
+	})
+	return commits
+}
+
+// Pending возвращает количество полученных, но еще не закоммиченных сообщений
+func (t *OffsetTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := 0
+	for _, p := range t.partitions {
+		total += len(p.pending)
+	}
+	return total
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// UserEventsConsumer читает события жизненного цикла пользователей кошелька
+// и отключает доставку уведомлений замороженным и удаленным пользователям.
+// События редкие, поэтому обрабатываются по одному, без пакетов и воркеров
+type UserEventsConsumer struct {
+	reader     *kafka.Reader
+	storage    storages.Storage
+	retryDelay time.Duration
+	logger     *logrus.Logger
+}
+
+// NewUserEventsConsumer создает consumer событий пользователей для топика topic
+func NewUserEventsConsumer(cfg *Config, topic string, storage storages.Storage, logger *logrus.Logger) *UserEventsConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		Topic:       topic,
+		GroupID:     cfg.GroupID,
+		MinBytes:    cfg.MinBytes,
+		MaxBytes:    cfg.MaxBytes,
+		MaxWait:     cfg.MaxWait,
+		Dialer:      cfg.Security.Dialer(),
+		Logger:      kafka.LoggerFunc(logger.Debugf),
+		ErrorLogger: kafka.LoggerFunc(logger.Errorf),
+	})
+
+	logger.Infof("User events consumer initialized: Topic=%s, GroupID=%s", topic, cfg.GroupID)
+
+	return &UserEventsConsumer{
+		reader:     reader,
+		storage:    storage,
+		retryDelay: cfg.RetryDelay,
+		logger:     logger,
+	}
+}
+
+// Start читает события до отмены контекста (блокирующий вызов).
+// Сообщение коммитится только после применения: событие нельзя потерять,
+// иначе представления кошелька и сервиса уведомлений о пользователе разойдутся
+func (c *UserEventsConsumer) Start(ctx context.Context) error {
+	c.logger.Info("Starting user events consumer...")
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("User events consumer stopped")
+				return nil
+			}
+			c.logger.Errorf("Failed to fetch user event: %v", err)
+			if !sleepContext(ctx, c.retryDelay) {
+				return nil
+			}
+			continue
+		}
+
+		for {
+			err := c.HandleMessage(ctx, msg)
+			if err == nil {
+				break
+			}
+			c.logger.Errorf("Failed to apply user event, retrying in %v: %v", c.retryDelay, err)
+			if !sleepContext(ctx, c.retryDelay) {
+				return nil
+			}
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			c.logger.Errorf("Failed to commit user event: %v", err)
+		}
+	}
+}
+
+// HandleMessage применяет одно событие. Некорректные сообщения пропускаются
+// с ошибкой в логе, ошибка возвращается только при сбое хранилища
+func (c *UserEventsConsumer) HandleMessage(ctx context.Context, msg kafka.Message) error {
+	event, err := parseUserEvent(msg)
+	if err != nil {
+		c.logger.Errorf("Skipping invalid user event at offset %d: %v", msg.Offset, err)
+		return nil
+	}
+
+	if _, err := c.storage.ApplyUserLifecycle(ctx, event); err != nil {
+		return fmt.Errorf("failed to apply user event %s: %w", event.EventID, err)
+	}
+	return nil
+}
+
+// parseUserEvent разбирает и проверяет событие пользователя
+func parseUserEvent(msg kafka.Message) (*storages.UserLifecycleMessage, error) {
+	var event storages.UserLifecycleMessage
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user event: %w", err)
+	}
+
+	if event.EventID == "" {
+		event.EventID = headerValue(msg, EventIDHeader)
+	}
+
+	switch event.Event {
+	case storages.UserEventFrozen, storages.UserEventDeleted, storages.UserEventActivated:
+	default:
+		return nil, fmt.Errorf("unknown user event: %q", event.Event)
+	}
+
+	if event.UserID <= 0 {
+		return nil, fmt.Errorf("invalid user ID: %d", event.UserID)
+	}
+
+	if event.Timestamp.IsZero() {
+		return nil, fmt.Errorf("user event without timestamp")
+	}
+
+	return &event, nil
+}
+
+// sleepContext ждет d или отмены контекста. Возвращает false, если контекст отменен
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Close закрывает consumer
+func (c *UserEventsConsumer) Close() error {
+	c.logger.Info("Closing user events consumer")
+	return c.reader.Close()
+}
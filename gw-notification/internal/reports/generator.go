@@ -0,0 +1,155 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// generateTimeout ограничение времени построения и сохранения одного отчета
+const generateTimeout = 5 * time.Minute
+
+// Config содержит расписание генерации отчетов
+type Config struct {
+	// Periods периоды отчетов: daily, weekly
+	Periods []string
+	// Delay задержка после окончания периода, за которую успевают прийти
+	// опоздавшие сообщения о переводах периода
+	Delay time.Duration
+	// Interval пауза между проверками, не пора ли строить отчет
+	Interval time.Duration
+	// TopUsers размер топа пользователей по сумме переводов
+	TopUsers int
+}
+
+// Generator строит отчеты по крупным переводам за завершившиеся дни и недели,
+// сохраняет их в коллекцию отчетов и отправляет сводку издателям.
+// Несколько экземпляров сервиса могут работать одновременно: отчет за период
+// сохраняется один раз, и сводку отправляет только сохранивший его экземпляр
+type Generator struct {
+	storage    storages.Storage
+	cfg        Config
+	publishers []Publisher
+	logger     *logrus.Logger
+}
+
+// NewGenerator создает генератор отчетов
+func NewGenerator(storage storages.Storage, cfg Config, publishers []Publisher, logger *logrus.Logger) *Generator {
+	return &Generator{
+		storage:    storage,
+		cfg:        cfg,
+		publishers: publishers,
+		logger:     logger,
+	}
+}
+
+// ValidPeriod проверяет, что период отчета поддерживается
+func ValidPeriod(period string) bool {
+	return period == storages.ReportPeriodDaily || period == storages.ReportPeriodWeekly
+}
+
+// PeriodBounds возвращает границы периода [start, end), содержащего t, в UTC.
+// Неделя начинается в понедельник
+func PeriodBounds(period string, t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	if period == storages.ReportPeriodWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		start := day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
+
+// ReportID возвращает идентификатор отчета за период, начинающийся в start
+func ReportID(period string, start time.Time) string {
+	return period + "-" + start.UTC().Format("2006-01-02")
+}
+
+// Run проверяет расписание при запуске и затем каждые Interval до отмены контекста
+func (g *Generator) Run(ctx context.Context) {
+	g.logger.Infof("Report generator started (periods: %v, delay: %v, interval: %v, publishers: %d)",
+		g.cfg.Periods, g.cfg.Delay, g.cfg.Interval, len(g.publishers))
+
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := g.GenerateDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			g.logger.Errorf("Report generation failed, retrying in %v: %v", g.cfg.Interval, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			g.logger.Info("Report generator stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GenerateDue строит отчеты за последний период каждого типа, завершившийся
+// не позже now - Delay, и возвращает число новых отчетов. Пропущенные ранее
+// периоды (сервис был остановлен) не достраиваются
+func (g *Generator) GenerateDue(ctx context.Context, now time.Time) (int, error) {
+	created := 0
+	var firstErr error
+	for _, period := range g.cfg.Periods {
+		current, _ := PeriodBounds(period, now.Add(-g.cfg.Delay))
+		start, _ := PeriodBounds(period, current.Add(-time.Nanosecond))
+
+		_, ok, err := g.Generate(ctx, period, start)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, firstErr
+}
+
+// Generate строит и сохраняет отчет за период, начинающийся в start. Если отчет
+// уже сохранен, возвращает false и сводку не отправляет. Ошибки издателей
+// логируются: отчет остается доступным через API
+func (g *Generator) Generate(ctx context.Context, period string, start time.Time) (*storages.Report, bool, error) {
+	if !ValidPeriod(period) {
+		return nil, false, fmt.Errorf("unknown report period: %q", period)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	start, end := PeriodBounds(period, start)
+	report, err := g.storage.BuildReport(ctx, period, start, end, g.cfg.TopUsers)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build %s report: %w", period, err)
+	}
+	report.ID = ReportID(period, start)
+	report.GeneratedAt = time.Now().UTC()
+
+	created, err := g.storage.SaveReport(ctx, report)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save %s report: %w", period, err)
+	}
+	if !created {
+		g.logger.Debugf("Report %s already exists, skipping", report.ID)
+		return report, false, nil
+	}
+
+	g.logger.Infof("Generated report %s: %d transfers, %d users", report.ID, report.Total, report.Users)
+
+	for _, publisher := range g.publishers {
+		if err := publisher.Publish(ctx, report); err != nil {
+			g.logger.Errorf("Failed to publish report %s via %s: %v", report.ID, publisher.Name(), err)
+		}
+	}
+	return report, true, nil
+}
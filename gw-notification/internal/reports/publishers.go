@@ -0,0 +1,161 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"gw-notification/internal/storages"
+)
+
+// Publisher отправляет сводку нового отчета
+type Publisher interface {
+	// Name имя канала для логов
+	Name() string
+	Publish(ctx context.Context, report *storages.Report) error
+}
+
+// ReportSummary сводка отчета без построчной разбивки
+type ReportSummary struct {
+	ID          string                         `json:"id"`
+	Period      string                         `json:"period"`
+	PeriodStart time.Time                      `json:"period_start"`
+	PeriodEnd   time.Time                      `json:"period_end"`
+	Total       int64                          `json:"total"`
+	Users       int64                          `json:"users"`
+	ByCurrency  []storages.ReportCurrencyTotal `json:"by_currency"`
+	TopUsers    []storages.UserAlertCount      `json:"top_users"`
+}
+
+// Summarize возвращает сводку отчета
+func Summarize(report *storages.Report) ReportSummary {
+	return ReportSummary{
+		ID:          report.ID,
+		Period:      report.Period,
+		PeriodStart: report.PeriodStart,
+		PeriodEnd:   report.PeriodEnd,
+		Total:       report.Total,
+		Users:       report.Users,
+		ByCurrency:  report.ByCurrency,
+		TopUsers:    report.TopUsers,
+	}
+}
+
+// FormatSummary возвращает текст сводки для письма
+func FormatSummary(report *storages.Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Large transfers %s report for %s - %s (UTC)\n\n",
+		report.Period, report.PeriodStart.UTC().Format("2006-01-02"), report.PeriodEnd.UTC().Add(-time.Nanosecond).Format("2006-01-02"))
+	fmt.Fprintf(&b, "Transfers: %d\nUsers: %d\n", report.Total, report.Users)
+
+	if len(report.ByCurrency) > 0 {
+		b.WriteString("\nBy currency:\n")
+		for _, c := range report.ByCurrency {
+			fmt.Fprintf(&b, "  %s: %d transfers, %.2f total, %d users\n", c.Currency, c.Count, c.TotalAmount, c.Users)
+		}
+	}
+	if len(report.TopUsers) > 0 {
+		b.WriteString("\nTop users:\n")
+		for _, u := range report.TopUsers {
+			fmt.Fprintf(&b, "  user %d: %d transfers, %.2f total\n", u.UserID, u.Count, u.TotalAmount)
+		}
+	}
+	return b.String()
+}
+
+// WebhookPublisher отправляет сводку POST запросом с JSON телом ReportSummary
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher создает издателя сводок в webhook
+func NewWebhookPublisher(url string, timeout time.Duration) *WebhookPublisher {
+	return &WebhookPublisher{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Name имя канала для логов
+func (p *WebhookPublisher) Name() string {
+	return "webhook"
+}
+
+// Publish отправляет сводку; ответ со статусом не 2xx считается ошибкой
+func (p *WebhookPublisher) Publish(ctx context.Context, report *storages.Report) error {
+	body, err := json.Marshal(Summarize(report))
+	if err != nil {
+		return fmt.Errorf("failed to marshal report summary: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailConfig содержит настройки отправки сводок по почте
+type EmailConfig struct {
+	SMTPAddr string // host:port
+	Username string // пусто - без аутентификации
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailPublisher отправляет текстовую сводку письмом через SMTP
+type EmailPublisher struct {
+	cfg EmailConfig
+}
+
+// NewEmailPublisher создает издателя сводок по почте
+func NewEmailPublisher(cfg EmailConfig) *EmailPublisher {
+	return &EmailPublisher{cfg: cfg}
+}
+
+// Name имя канала для логов
+func (p *EmailPublisher) Name() string {
+	return "email"
+}
+
+// Publish отправляет письмо. smtp.SendMail не принимает контекст, поэтому
+// отправка ограничена только таймаутами SMTP сервера
+func (p *EmailPublisher) Publish(ctx context.Context, report *storages.Report) error {
+	var auth smtp.Auth
+	if p.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(p.cfg.SMTPAddr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", p.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(p.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Large transfers report %s\r\n", report.ID)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(FormatSummary(report), "\n", "\r\n"))
+
+	if err := smtp.SendMail(p.cfg.SMTPAddr, auth, p.cfg.From, p.cfg.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
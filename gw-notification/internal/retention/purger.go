@@ -0,0 +1,65 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// Purger периодически удаляет переводы старше срока хранения, чтобы коллекция
+// крупных переводов не росла неограниченно. Несколько экземпляров сервиса могут
+// очищать одновременно: удаление идет по _id и не дублируется
+type Purger struct {
+	storage storages.Storage
+	// period срок хранения перевода с момента обработки (processed_at)
+	period time.Duration
+	// interval пауза между очистками
+	interval  time.Duration
+	batchSize int
+	logger    *logrus.Logger
+}
+
+// NewPurger создает фоновую очистку устаревших переводов
+func NewPurger(storage storages.Storage, period, interval time.Duration, batchSize int, logger *logrus.Logger) *Purger {
+	return &Purger{
+		storage:   storage,
+		period:    period,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run очищает коллекцию при запуске и затем каждые interval до отмены контекста
+func (p *Purger) Run(ctx context.Context) {
+	p.logger.Infof("Retention purger started (period: %v, interval: %v)", p.period, p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PurgeOnce(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Errorf("Retention purge failed, retrying in %v: %v", p.interval, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Retention purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce удаляет переводы, обработанные раньше now - period, и возвращает их число
+func (p *Purger) PurgeOnce(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-p.period)
+
+	purged, err := p.storage.PurgeTransfers(ctx, cutoff, p.batchSize)
+	if purged > 0 {
+		p.logger.Infof("Purged %d transfers processed before %s", purged, cutoff.Format(time.RFC3339))
+	}
+	return purged, err
+}
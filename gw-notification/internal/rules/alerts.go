@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// Каналы оповещений
+const (
+	AlertChannelLog     = "log"
+	AlertChannelWebhook = "webhook"
+)
+
+// Alerter отправляет оповещение о новой отметке подозрительной активности
+type Alerter interface {
+	// Name имя канала для логов
+	Name() string
+	Alert(ctx context.Context, flag *storages.SuspiciousFlag) error
+}
+
+// LogAlerter пишет оповещение в лог с уровнем error, чтобы его подхватил
+// сбор логов
+type LogAlerter struct {
+	logger *logrus.Logger
+}
+
+// NewLogAlerter создает оповещение через лог
+func NewLogAlerter(logger *logrus.Logger) *LogAlerter {
+	return &LogAlerter{logger: logger}
+}
+
+// Name имя канала для логов
+func (a *LogAlerter) Name() string {
+	return AlertChannelLog
+}
+
+// Alert пишет отметку в лог с полями для фильтрации
+func (a *LogAlerter) Alert(ctx context.Context, flag *storages.SuspiciousFlag) error {
+	a.logger.WithFields(logrus.Fields{
+		"alert":    "suspicious_activity",
+		"user_id":  flag.UserID,
+		"rule":     flag.Rule,
+		"event_id": flag.EventID,
+	}).Errorf("Suspicious activity: %s", flag.Reason)
+	return nil
+}
+
+// WebhookAlerter отправляет отметку POST запросом с JSON телом SuspiciousFlag
+type WebhookAlerter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAlerter создает оповещение через webhook
+func NewWebhookAlerter(url string, timeout time.Duration) *WebhookAlerter {
+	return &WebhookAlerter{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Name имя канала для логов
+func (a *WebhookAlerter) Name() string {
+	return AlertChannelWebhook
+}
+
+// Alert отправляет отметку; ответ со статусом не 2xx считается ошибкой
+func (a *WebhookAlerter) Alert(ctx context.Context, flag *storages.SuspiciousFlag) error {
+	body, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gw-notification/internal/storages"
+)
+
+// Engine проверяет сохраненные переводы правилами, сохраняет отметки
+// подозрительной активности и отправляет по новым отметкам оповещения
+type Engine struct {
+	storage  storages.Storage
+	rules    []Rule
+	alerters []Alerter
+	// historySize число последних переводов пользователя, по которым считаются правила
+	historySize int
+	logger      *logrus.Logger
+}
+
+// NewEngine создает движок правил
+func NewEngine(storage storages.Storage, rules []Rule, alerters []Alerter, historySize int, logger *logrus.Logger) *Engine {
+	return &Engine{
+		storage:     storage,
+		rules:       rules,
+		alerters:    alerters,
+		historySize: historySize,
+		logger:      logger,
+	}
+}
+
+// EvaluateBatch проверяет пакет переводов, уже сохраненных в хранилище.
+// История загружается один раз на пользователя и включает переводы пакета.
+// Ошибки логируются и не влияют на обработку сообщений
+func (e *Engine) EvaluateBatch(ctx context.Context, transfers []storages.LargeTransfer) {
+	byUser := make(map[int64][]storages.LargeTransfer)
+	var users []int64
+	for _, t := range transfers {
+		if _, ok := byUser[t.UserID]; !ok {
+			users = append(users, t.UserID)
+		}
+		byUser[t.UserID] = append(byUser[t.UserID], t)
+	}
+
+	for _, userID := range users {
+		history, err := e.storage.GetTransfersByUser(ctx, userID, e.historySize)
+		if err != nil {
+			e.logger.Errorf("Failed to load transfer history of user %d for rules: %v", userID, err)
+			continue
+		}
+
+		for _, transfer := range byUser[userID] {
+			e.evaluate(ctx, transfer, history)
+		}
+	}
+}
+
+// evaluate проверяет перевод всеми правилами
+func (e *Engine) evaluate(ctx context.Context, transfer storages.LargeTransfer, history []storages.LargeTransfer) {
+	eventID := transfer.EventID
+	if eventID == "" {
+		eventID = transfer.ContentEventID()
+	}
+
+	for _, rule := range e.rules {
+		reason, flagged := rule.Evaluate(transfer, history)
+		if !flagged {
+			continue
+		}
+
+		flag := &storages.SuspiciousFlag{
+			ID:         rule.Name() + ":" + eventID,
+			UserID:     transfer.UserID,
+			Rule:       rule.Name(),
+			Reason:     reason,
+			EventID:    eventID,
+			Amount:     transfer.Amount,
+			Currency:   transfer.FromCurrency,
+			TransferAt: transfer.Timestamp,
+			FlaggedAt:  time.Now().UTC(),
+		}
+
+		created, err := e.storage.SaveFlag(ctx, flag)
+		if err != nil {
+			e.logger.Errorf("Failed to save %s flag for user %d: %v", rule.Name(), transfer.UserID, err)
+			continue
+		}
+		if !created {
+			// Перевод доставлен повторно, оповещение уже отправлено
+			continue
+		}
+
+		e.logger.Warnf("User %d flagged by %s rule: %s", transfer.UserID, rule.Name(), reason)
+		for _, alerter := range e.alerters {
+			if err := alerter.Alert(ctx, flag); err != nil {
+				e.logger.Errorf("Failed to send %s alert via %s: %v", flag.ID, alerter.Name(), err)
+			}
+		}
+	}
+}
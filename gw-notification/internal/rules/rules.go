@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"gw-notification/internal/storages"
+)
+
+// Имена правил
+const (
+	RuleVelocity = "velocity"
+	RuleSpike    = "amount_spike"
+)
+
+// Rule правило обнаружения подозрительной активности
+type Rule interface {
+	// Name имя правила, входит в ID отметки
+	Name() string
+	// Evaluate проверяет перевод по истории пользователя: его сохраненным
+	// переводам по убыванию timestamp, включая сам перевод.
+	// Возвращает причину отметки и true, если перевод подозрительный
+	Evaluate(transfer storages.LargeTransfer, history []storages.LargeTransfer) (string, bool)
+}
+
+// VelocityRule отмечает пользователя, сделавшего Count и больше крупных
+// переводов за Window, считая проверяемый
+type VelocityRule struct {
+	Count  int
+	Window time.Duration
+}
+
+// Name имя правила
+func (r VelocityRule) Name() string {
+	return RuleVelocity
+}
+
+// Evaluate считает переводы с timestamp в (t - Window, t]
+func (r VelocityRule) Evaluate(transfer storages.LargeTransfer, history []storages.LargeTransfer) (string, bool) {
+	from := transfer.Timestamp.Add(-r.Window)
+
+	count := 0
+	for _, t := range history {
+		if t.Timestamp.After(from) && !t.Timestamp.After(transfer.Timestamp) {
+			count++
+		}
+	}
+
+	if count < r.Count {
+		return "", false
+	}
+	return fmt.Sprintf("%d large transfers within %v", count, r.Window), true
+}
+
+// SpikeRule отмечает перевод, сумма которого в Factor и больше раз превышает
+// среднюю сумму предыдущих переводов пользователя в той же валюте. Правило
+// не срабатывает, пока предыдущих переводов меньше MinHistory
+type SpikeRule struct {
+	Factor     float64
+	MinHistory int
+}
+
+// Name имя правила
+func (r SpikeRule) Name() string {
+	return RuleSpike
+}
+
+// Evaluate сравнивает сумму со средней по переводам раньше проверяемого
+func (r SpikeRule) Evaluate(transfer storages.LargeTransfer, history []storages.LargeTransfer) (string, bool) {
+	var total float64
+	count := 0
+	for _, t := range history {
+		if t.FromCurrency == transfer.FromCurrency && t.Timestamp.Before(transfer.Timestamp) {
+			total += t.Amount
+			count++
+		}
+	}
+
+	if count == 0 || count < r.MinHistory {
+		return "", false
+	}
+
+	average := total / float64(count)
+	if transfer.Amount < average*r.Factor {
+		return "", false
+	}
+	return fmt.Sprintf("amount %.2f %s is %.1fx the average %.2f of %d previous transfers",
+		transfer.Amount, transfer.FromCurrency, transfer.Amount/average, average, count), true
+}
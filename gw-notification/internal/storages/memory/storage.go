@@ -0,0 +1,568 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gw-notification/internal/storages"
+)
+
+// userEventStatuses статус пользователя для каждого события жизненного цикла
+var userEventStatuses = map[string]string{
+	storages.UserEventFrozen:    storages.UserStatusFrozen,
+	storages.UserEventDeleted:   storages.UserStatusDeleted,
+	storages.UserEventActivated: storages.UserStatusActive,
+}
+
+// MemoryStorage реализует интерфейс Storage в памяти процесса (STORAGE_DRIVER=memory).
+// Данные теряются при перезапуске: хранилище предназначено для демонстраций и
+// быстрых тестов без MongoDB. Дедупликация по event_id, статусы пользователей
+// и агрегаты совпадают с MongoDB
+type MemoryStorage struct {
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	transfers   []storages.LargeTransfer
+	eventIDs    map[string]struct{}
+	preferences map[int64]storages.UserPreferences
+	reports     map[string]storages.Report
+	flags       map[string]storages.SuspiciousFlag
+
+	// Состояние для Health
+	lastWriteAt time.Time
+	retention   *storages.RetentionStats
+}
+
+// New создает пустое хранилище
+func New(logger *logrus.Logger) *MemoryStorage {
+	logger.Info("Using in-memory storage")
+	return &MemoryStorage{
+		logger:      logger,
+		eventIDs:    make(map[string]struct{}),
+		preferences: make(map[int64]storages.UserPreferences),
+		reports:     make(map[string]storages.Report),
+		flags:       make(map[string]storages.SuspiciousFlag),
+	}
+}
+
+// SaveTransfer сохраняет информацию о крупном переводе. Повторно доставленный
+// перевод с тем же event_id пропускается
+func (s *MemoryStorage) SaveTransfer(ctx context.Context, transfer *storages.LargeTransfer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transfer.ProcessedAt = time.Now()
+	transfer.Status = storages.StatusProcessed
+	if !s.insertLocked(transfer) {
+		s.logger.Debugf("Skipping duplicate transfer: EventID=%s", transfer.EventID)
+		return nil
+	}
+	s.lastWriteAt = transfer.ProcessedAt
+
+	s.logger.Debugf("Saved transfer: UserID=%d, Amount=%.2f, Type=%s",
+		transfer.UserID, transfer.Amount, transfer.Type)
+
+	return nil
+}
+
+// SaveTransferBatch сохраняет пакет переводов, пропуская дубликаты по event_id
+func (s *MemoryStorage) SaveTransferBatch(ctx context.Context, transfers []storages.LargeTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	inserted := 0
+	for i := range transfers {
+		transfers[i].ProcessedAt = now
+		transfers[i].Status = storages.StatusProcessed
+		if s.insertLocked(&transfers[i]) {
+			inserted++
+		}
+	}
+	if inserted > 0 {
+		s.lastWriteAt = now
+	}
+
+	s.logger.Infof("Saved batch of %d transfers (inserted: %d, duplicates: %d)",
+		len(transfers), inserted, len(transfers)-inserted)
+
+	return nil
+}
+
+// insertLocked добавляет перевод, если перевода с тем же event_id еще нет.
+// Перевод пользователя с отключенной доставкой помечается его статусом
+func (s *MemoryStorage) insertLocked(transfer *storages.LargeTransfer) bool {
+	if transfer.EventID != "" {
+		if _, ok := s.eventIDs[transfer.EventID]; ok {
+			return false
+		}
+		s.eventIDs[transfer.EventID] = struct{}{}
+	}
+
+	transfer.UserStatus = ""
+	if preferences, ok := s.preferences[transfer.UserID]; ok && !preferences.DeliveryEnabled {
+		transfer.UserStatus = preferences.Status
+	}
+	transfer.ID = primitive.NewObjectID()
+	s.transfers = append(s.transfers, *transfer)
+	return true
+}
+
+// GetTransfer получает перевод по ID
+func (s *MemoryStorage) GetTransfer(ctx context.Context, id string) (*storages.LargeTransfer, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID format: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, transfer := range s.transfers {
+		if transfer.ID == objectID {
+			return &transfer, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to get transfer: transfer %s not found", id)
+}
+
+// GetTransfersByUser получает переводы пользователя, начиная с последнего по времени операции
+func (s *MemoryStorage) GetTransfersByUser(ctx context.Context, userID int64, limit int) ([]storages.LargeTransfer, error) {
+	s.mu.RLock()
+	var transfers []storages.LargeTransfer
+	for _, transfer := range s.transfers {
+		if transfer.UserID == userID {
+			transfers = append(transfers, transfer)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Timestamp.After(transfers[j].Timestamp)
+	})
+	return limitSlice(transfers, limit), nil
+}
+
+// GetRecentTransfers получает последние обработанные переводы
+func (s *MemoryStorage) GetRecentTransfers(ctx context.Context, limit int) ([]storages.LargeTransfer, error) {
+	s.mu.RLock()
+	transfers := make([]storages.LargeTransfer, len(s.transfers))
+	copy(transfers, s.transfers)
+	s.mu.RUnlock()
+
+	// Переводы добавляются по порядку обработки: последние в конце
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].ProcessedAt.After(transfers[j].ProcessedAt)
+	})
+	return limitSlice(transfers, limit), nil
+}
+
+// GetStatistics возвращает статистику обработки
+func (s *MemoryStorage) GetStatistics(ctx context.Context) (*storages.Statistics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := &storages.Statistics{}
+	for _, transfer := range s.transfers {
+		switch transfer.Status {
+		case storages.StatusProcessed:
+			stats.TotalProcessed++
+		case storages.StatusFailed:
+			stats.TotalFailed++
+		}
+		stats.TotalAmount += transfer.Amount
+		if transfer.ProcessedAt.After(stats.LastProcessedAt) {
+			stats.LastProcessedAt = transfer.ProcessedAt
+		}
+	}
+	if len(s.transfers) > 0 {
+		stats.AverageAmount = stats.TotalAmount / float64(len(s.transfers))
+	}
+
+	return stats, nil
+}
+
+// GetSummary возвращает сводку по переводам начиная с указанного момента
+func (s *MemoryStorage) GetSummary(ctx context.Context, since time.Time, topUsersLimit int) (*storages.Summary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type groupKey struct{ transferType, currency string }
+	groups := make(map[groupKey]*storages.TransferGroupCount)
+	users := make(map[int64]*storages.UserAlertCount)
+
+	summary := &storages.Summary{
+		Since:          since,
+		ByTypeCurrency: []storages.TransferGroupCount{},
+		TopUsers:       []storages.UserAlertCount{},
+	}
+	for _, transfer := range s.transfers {
+		if transfer.Timestamp.Before(since) {
+			continue
+		}
+		summary.Total++
+		if transfer.Status == storages.StatusFailed {
+			summary.Failed++
+		}
+
+		key := groupKey{transfer.Type, transfer.FromCurrency}
+		group, ok := groups[key]
+		if !ok {
+			group = &storages.TransferGroupCount{Type: transfer.Type, Currency: transfer.FromCurrency}
+			groups[key] = group
+		}
+		group.Count++
+		group.TotalAmount += transfer.Amount
+
+		addUserAlert(users, transfer)
+	}
+
+	for _, group := range groups {
+		summary.ByTypeCurrency = append(summary.ByTypeCurrency, *group)
+	}
+	sort.Slice(summary.ByTypeCurrency, func(i, j int) bool {
+		a, b := summary.ByTypeCurrency[i], summary.ByTypeCurrency[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Currency < b.Currency
+	})
+
+	for _, user := range users {
+		summary.TopUsers = append(summary.TopUsers, *user)
+	}
+	sort.Slice(summary.TopUsers, func(i, j int) bool {
+		a, b := summary.TopUsers[i], summary.TopUsers[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.TotalAmount != b.TotalAmount {
+			return a.TotalAmount > b.TotalAmount
+		}
+		return a.UserID < b.UserID
+	})
+	summary.TopUsers = limitSlice(summary.TopUsers, topUsersLimit)
+
+	return summary, nil
+}
+
+// ApplyUserLifecycle обновляет настройки доставки пользователя и помечает его переводы.
+// Событие старше сохраненного пропускается, как и в MongoDB
+func (s *MemoryStorage) ApplyUserLifecycle(ctx context.Context, event *storages.UserLifecycleMessage) (int64, error) {
+	status, ok := userEventStatuses[event.Event]
+	if !ok {
+		return 0, fmt.Errorf("unknown user event: %s", event.Event)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.preferences[event.UserID]; ok && !current.UpdatedAt.Before(event.Timestamp) {
+		s.logger.Infof("Skipping outdated user event: EventID=%s, UserID=%d, Event=%s",
+			event.EventID, event.UserID, event.Event)
+		return 0, nil
+	}
+	s.preferences[event.UserID] = storages.UserPreferences{
+		UserID:          event.UserID,
+		Status:          status,
+		DeliveryEnabled: status == storages.UserStatusActive,
+		Reason:          event.Reason,
+		UpdatedAt:       event.Timestamp,
+	}
+
+	// Переводы активного пользователя не помечаются
+	userStatus := status
+	if status == storages.UserStatusActive {
+		userStatus = ""
+	}
+
+	var modified int64
+	for i := range s.transfers {
+		if s.transfers[i].UserID == event.UserID && s.transfers[i].UserStatus != userStatus {
+			s.transfers[i].UserStatus = userStatus
+			modified++
+		}
+	}
+
+	s.logger.Infof("Applied user event: UserID=%d, Event=%s, Transfers=%d",
+		event.UserID, event.Event, modified)
+
+	return modified, nil
+}
+
+// GetUserPreferences возвращает настройки доставки пользователя. Для пользователя
+// без событий возвращаются настройки по умолчанию: активен, доставка включена
+func (s *MemoryStorage) GetUserPreferences(ctx context.Context, userID int64) (*storages.UserPreferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	preferences, ok := s.preferences[userID]
+	if !ok {
+		return &storages.UserPreferences{
+			UserID:          userID,
+			Status:          storages.UserStatusActive,
+			DeliveryEnabled: true,
+		}, nil
+	}
+	return &preferences, nil
+}
+
+// PurgeTransfers удаляет переводы, обработанные раньше before. Хранилище в памяти
+// удаляет их за один проход, batchSize только проверяется
+func (s *MemoryStorage) PurgeTransfers(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.transfers[:0]
+	var purged int64
+	for _, transfer := range s.transfers {
+		if transfer.ProcessedAt.Before(before) {
+			purged++
+			continue
+		}
+		kept = append(kept, transfer)
+	}
+	s.transfers = kept
+
+	// Идентификаторы событий остаются: повторная доставка удаленного перевода
+	// не должна сохранить его снова
+	retention := &storages.RetentionStats{LastPurged: purged, LastPurgeAt: time.Now()}
+	if s.retention != nil {
+		retention.PurgedTotal = s.retention.PurgedTotal
+	}
+	retention.PurgedTotal += purged
+	s.retention = retention
+
+	return purged, nil
+}
+
+// BuildReport агрегирует переводы с timestamp в [start, end) по дням,
+// пользователям и валютам
+func (s *MemoryStorage) BuildReport(ctx context.Context, period string, start, end time.Time, topUsers int) (*storages.Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type rowKey struct {
+		day      string
+		userID   int64
+		currency string
+	}
+	rows := make(map[rowKey]*storages.ReportRow)
+	currencies := make(map[string]*storages.ReportCurrencyTotal)
+	currencyUsers := make(map[string]map[int64]struct{})
+	users := make(map[int64]*storages.UserAlertCount)
+
+	report := &storages.Report{
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ByCurrency:  []storages.ReportCurrencyTotal{},
+		TopUsers:    []storages.UserAlertCount{},
+		Rows:        []storages.ReportRow{},
+	}
+	for _, transfer := range s.transfers {
+		if transfer.Timestamp.Before(start) || !transfer.Timestamp.Before(end) {
+			continue
+		}
+		report.Total++
+
+		key := rowKey{transfer.Timestamp.UTC().Format("2006-01-02"), transfer.UserID, transfer.FromCurrency}
+		row, ok := rows[key]
+		if !ok {
+			row = &storages.ReportRow{Day: key.day, UserID: key.userID, Currency: key.currency}
+			rows[key] = row
+		}
+		row.Count++
+		row.TotalAmount += transfer.Amount
+
+		currency, ok := currencies[transfer.FromCurrency]
+		if !ok {
+			currency = &storages.ReportCurrencyTotal{Currency: transfer.FromCurrency}
+			currencies[transfer.FromCurrency] = currency
+			currencyUsers[transfer.FromCurrency] = make(map[int64]struct{})
+		}
+		currency.Count++
+		currency.TotalAmount += transfer.Amount
+		currencyUsers[transfer.FromCurrency][transfer.UserID] = struct{}{}
+
+		addUserAlert(users, transfer)
+	}
+	report.Users = int64(len(users))
+
+	for code, currency := range currencies {
+		currency.Users = int64(len(currencyUsers[code]))
+		report.ByCurrency = append(report.ByCurrency, *currency)
+	}
+	sort.Slice(report.ByCurrency, func(i, j int) bool {
+		a, b := report.ByCurrency[i], report.ByCurrency[j]
+		if a.TotalAmount != b.TotalAmount {
+			return a.TotalAmount > b.TotalAmount
+		}
+		return a.Currency < b.Currency
+	})
+
+	for _, user := range users {
+		report.TopUsers = append(report.TopUsers, *user)
+	}
+	sort.Slice(report.TopUsers, func(i, j int) bool {
+		a, b := report.TopUsers[i], report.TopUsers[j]
+		if a.TotalAmount != b.TotalAmount {
+			return a.TotalAmount > b.TotalAmount
+		}
+		return a.UserID < b.UserID
+	})
+	report.TopUsers = limitSlice(report.TopUsers, topUsers)
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Currency < b.Currency
+	})
+
+	return report, nil
+}
+
+// SaveReport сохраняет отчет, если отчета с тем же ID еще нет
+func (s *MemoryStorage) SaveReport(ctx context.Context, report *storages.Report) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reports[report.ID]; ok {
+		return false, nil
+	}
+	s.reports[report.ID] = *report
+	return true, nil
+}
+
+// GetReports возвращает отчеты по убыванию начала периода
+func (s *MemoryStorage) GetReports(ctx context.Context, filter storages.ReportFilter) ([]storages.Report, error) {
+	s.mu.RLock()
+	reports := []storages.Report{}
+	for _, report := range s.reports {
+		if filter.Period != "" && report.Period != filter.Period {
+			continue
+		}
+		if !filter.Since.IsZero() && report.PeriodStart.Before(filter.Since) {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].PeriodStart.Equal(reports[j].PeriodStart) {
+			return reports[i].PeriodStart.After(reports[j].PeriodStart)
+		}
+		return reports[i].Period < reports[j].Period
+	})
+	return limitSlice(reports, filter.Limit), nil
+}
+
+// SaveFlag сохраняет отметку, если отметки с тем же ID еще нет
+func (s *MemoryStorage) SaveFlag(ctx context.Context, flag *storages.SuspiciousFlag) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.flags[flag.ID]; ok {
+		return false, nil
+	}
+	s.flags[flag.ID] = *flag
+	return true, nil
+}
+
+// GetFlags возвращает отметки по убыванию времени выставления
+func (s *MemoryStorage) GetFlags(ctx context.Context, filter storages.FlagFilter) ([]storages.SuspiciousFlag, error) {
+	s.mu.RLock()
+	flags := []storages.SuspiciousFlag{}
+	for _, flag := range s.flags {
+		if filter.UserID > 0 && flag.UserID != filter.UserID {
+			continue
+		}
+		if !filter.Since.IsZero() && flag.FlaggedAt.Before(filter.Since) {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].FlaggedAt.Equal(flags[j].FlaggedAt) {
+			return flags[i].FlaggedAt.After(flags[j].FlaggedAt)
+		}
+		return flags[i].ID < flags[j].ID
+	})
+	return limitSlice(flags, filter.Limit), nil
+}
+
+// Health возвращает состояние хранилища. Хранилище в памяти всегда доступно
+func (s *MemoryStorage) Health(ctx context.Context) (*storages.HealthDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	details := &storages.HealthDetails{
+		Status:    storages.HealthStatusOK,
+		CheckedAt: time.Now(),
+	}
+	if !s.lastWriteAt.IsZero() {
+		lastWrite := s.lastWriteAt
+		details.LastWriteAt = &lastWrite
+	}
+	if s.retention != nil {
+		retention := *s.retention
+		details.Retention = &retention
+	}
+	return details, nil
+}
+
+// Ping всегда успешен
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close ничего не освобождает
+func (s *MemoryStorage) Close(ctx context.Context) error {
+	return nil
+}
+
+// addUserAlert учитывает перевод в итогах пользователя
+func addUserAlert(users map[int64]*storages.UserAlertCount, transfer storages.LargeTransfer) {
+	user, ok := users[transfer.UserID]
+	if !ok {
+		user = &storages.UserAlertCount{UserID: transfer.UserID}
+		users[transfer.UserID] = user
+	}
+	user.Count++
+	user.TotalAmount += transfer.Amount
+}
+
+// limitSlice возвращает первые limit элементов, непозитивный лимит - все
+func limitSlice[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
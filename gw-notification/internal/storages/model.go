@@ -1,6 +1,11 @@
 package storages
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,6 +14,7 @@ import (
 // LargeTransfer представляет крупный денежный перевод
 type LargeTransfer struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID      string             `bson:"event_id,omitempty" json:"event_id,omitempty"` // идентификатор события от wallet (для дедупликации)
 	UserID       int64              `bson:"user_id" json:"user_id"`
 	Type         string             `bson:"type" json:"type"` // deposit, withdraw, exchange
 	FromCurrency string             `bson:"from_currency,omitempty" json:"from_currency,omitempty"`
@@ -18,6 +24,13 @@ type LargeTransfer struct {
 	ProcessedAt  time.Time          `bson:"processed_at" json:"processed_at"`
 	Status       string             `bson:"status" json:"status"` // processed, failed
 	ErrorMessage string             `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	UserStatus   string             `bson:"user_status,omitempty" json:"user_status,omitempty"` // frozen, deleted; пусто для активных пользователей
+
+	// Поля схемы сообщений версии 2 и заголовков Kafka, пустые для старых сообщений
+	ConvertedAmount   float64 `bson:"converted_amount,omitempty" json:"converted_amount,omitempty"`
+	ConvertedCurrency string  `bson:"converted_currency,omitempty" json:"converted_currency,omitempty"`
+	TraceID           string  `bson:"trace_id,omitempty" json:"trace_id,omitempty"`
+	Source            string  `bson:"source,omitempty" json:"source,omitempty"`
 }
 
 // TransferType определяет типы переводов
@@ -35,20 +48,266 @@ const (
 
 // KafkaMessage представляет сообщение из Kafka
 type KafkaMessage struct {
-	UserID       int64     `json:"user_id"`
-	Type         string    `json:"type"`
-	FromCurrency string    `json:"from_currency"`
-	ToCurrency   string    `json:"to_currency"`
-	Amount       float64   `json:"amount"`
-	Timestamp    time.Time `json:"timestamp"`
+	EventID       string    `json:"event_id"`
+	TransactionID int64     `json:"transaction_id,omitempty"`
+	UserID        int64     `json:"user_id"`
+	Type          string    `json:"type"`
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	Amount        float64   `json:"amount"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	// Поля версии схемы 2
+	MessageID         string  `json:"message_id,omitempty"`
+	SchemaVersion     int     `json:"schema_version,omitempty"`
+	ConvertedAmount   float64 `json:"converted_amount,omitempty"`
+	ConvertedCurrency string  `json:"converted_currency,omitempty"`
+
+	// Заполняются из заголовков Kafka
+	TraceID string `json:"-"`
+	Source  string `json:"-"`
+}
+
+// EventIDFromTransaction возвращает идентификатор события для транзакции кошелька
+// в том же формате, что и wallet, чтобы импортированные переводы и сообщения Kafka
+// об одной транзакции считались дубликатами
+func EventIDFromTransaction(transactionID int64) string {
+	if transactionID <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("wallet-tx-%d", transactionID)
+}
+
+// ToTransfer проверяет сообщение и преобразует его в перевод. Если идентификатора
+// события нет, он строится из идентификатора транзакции
+func (m *KafkaMessage) ToTransfer() (*LargeTransfer, error) {
+	eventID := m.EventID
+	if eventID == "" {
+		eventID = EventIDFromTransaction(m.TransactionID)
+	}
+
+	transfer := &LargeTransfer{
+		EventID:      eventID,
+		UserID:       m.UserID,
+		Type:         m.Type,
+		FromCurrency: m.FromCurrency,
+		ToCurrency:   m.ToCurrency,
+		Amount:       m.Amount,
+		Timestamp:    m.Timestamp,
+
+		ConvertedAmount:   m.ConvertedAmount,
+		ConvertedCurrency: m.ConvertedCurrency,
+		TraceID:           m.TraceID,
+		Source:            m.Source,
+	}
+
+	if err := transfer.Validate(); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// ContentEventID возвращает детерминированный идентификатор события из
+// содержимого перевода: повторная доставка того же сообщения дает тот же
+// идентификатор. Используется, если producer не передал event_id и transaction_id
+func (t *LargeTransfer) ContentEventID() string {
+	content := strings.Join([]string{
+		strconv.FormatInt(t.UserID, 10),
+		t.Type,
+		t.FromCurrency,
+		t.ToCurrency,
+		strconv.FormatFloat(t.Amount, 'f', -1, 64),
+		t.Timestamp.UTC().Format(time.RFC3339Nano),
+	}, "|")
+
+	sum := sha256.Sum256([]byte(content))
+	return "content-" + hex.EncodeToString(sum[:16])
+}
+
+// Validate проверяет обязательные поля перевода
+func (t *LargeTransfer) Validate() error {
+	switch t.Type {
+	case TransferTypeDeposit, TransferTypeWithdraw, TransferTypeExchange:
+	default:
+		return fmt.Errorf("invalid transfer type: %q", t.Type)
+	}
+
+	if t.UserID <= 0 {
+		return fmt.Errorf("invalid user ID: %d", t.UserID)
+	}
+
+	if t.Amount <= 0 {
+		return fmt.Errorf("transfer amount must be positive, got %v", t.Amount)
+	}
+
+	if t.Timestamp.IsZero() {
+		return fmt.Errorf("transfer without timestamp")
+	}
+
+	return nil
+}
+
+// UserEvent определяет события жизненного цикла пользователя кошелька
+const (
+	UserEventFrozen    = "user_frozen"
+	UserEventDeleted   = "user_deleted"
+	UserEventActivated = "user_activated"
+)
+
+// UserStatus определяет статусы пользователя
+const (
+	UserStatusActive  = "active"
+	UserStatusFrozen  = "frozen"
+	UserStatusDeleted = "deleted"
+)
+
+// UserLifecycleMessage представляет событие жизненного цикла пользователя из Kafka
+type UserLifecycleMessage struct {
+	EventID   string    `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	Event     string    `json:"event"` // user_frozen, user_deleted, user_activated
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// UserPreferences представляет настройки доставки уведомлений пользователя
+type UserPreferences struct {
+	UserID          int64     `bson:"_id" json:"user_id"`
+	Status          string    `bson:"status" json:"status"`
+	DeliveryEnabled bool      `bson:"delivery_enabled" json:"delivery_enabled"`
+	Reason          string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"` // время события в кошельке
 }
 
 // Statistics представляет статистику обработки
 type Statistics struct {
-	TotalProcessed   int64     `bson:"total_processed" json:"total_processed"`
-	TotalFailed      int64     `bson:"total_failed" json:"total_failed"`
-	LastProcessedAt  time.Time `bson:"last_processed_at" json:"last_processed_at"`
-	AverageAmount    float64   `bson:"average_amount" json:"average_amount"`
-	TotalAmount      float64   `bson:"total_amount" json:"total_amount"`
-	ProcessingRate   float64   `json:"processing_rate"` // messages per second
+	TotalProcessed  int64     `bson:"total_processed" json:"total_processed"`
+	TotalFailed     int64     `bson:"total_failed" json:"total_failed"`
+	LastProcessedAt time.Time `bson:"last_processed_at" json:"last_processed_at"`
+	AverageAmount   float64   `bson:"average_amount" json:"average_amount"`
+	TotalAmount     float64   `bson:"total_amount" json:"total_amount"`
+	ProcessingRate  float64   `json:"processing_rate"` // messages per second
+}
+
+// TransferGroupCount количество переводов в разрезе типа и валюты
+type TransferGroupCount struct {
+	Type        string  `bson:"type" json:"type"`
+	Currency    string  `bson:"currency" json:"currency"`
+	Count       int64   `bson:"count" json:"count"`
+	TotalAmount float64 `bson:"total_amount" json:"total_amount"`
+}
+
+// UserAlertCount количество уведомлений о крупных переводах по пользователю
+type UserAlertCount struct {
+	UserID      int64   `bson:"_id" json:"user_id"`
+	Count       int64   `bson:"count" json:"count"`
+	TotalAmount float64 `bson:"total_amount" json:"total_amount"`
+}
+
+// Summary представляет сводку по переводам за период
+type Summary struct {
+	Since          time.Time            `json:"since"`
+	Total          int64                `json:"total"`
+	Failed         int64                `json:"failed"`
+	ByTypeCurrency []TransferGroupCount `json:"by_type_currency"`
+	TopUsers       []UserAlertCount     `json:"top_users"`
+}
+
+// HealthStatus определяет состояния хранилища
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthDetails представляет состояние подключения к хранилищу
+type HealthDetails struct {
+	Status      string     `json:"status"` // ok, unavailable
+	LatencyMs   float64    `json:"latency_ms"`
+	Pool        PoolStats  `json:"pool"`
+	LastWriteAt *time.Time `json:"last_write_at,omitempty"` // время последней успешной записи
+	// Retention результаты очистки устаревших переводов, пусто до первой очистки
+	Retention *RetentionStats `json:"retention,omitempty"`
+	CheckedAt time.Time       `json:"checked_at"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// RetentionStats представляет результаты очистки устаревших переводов
+type RetentionStats struct {
+	PurgedTotal int64     `json:"purged_total"` // удалено с запуска сервиса
+	LastPurged  int64     `json:"last_purged"`  // удалено последней очисткой
+	LastPurgeAt time.Time `json:"last_purge_at"`
+}
+
+// PoolStats представляет состояние пула соединений
+type PoolStats struct {
+	MaxSize uint64 `json:"max_size"`
+	MinSize uint64 `json:"min_size"`
+	Open    int64  `json:"open"`   // открытые соединения
+	InUse   int64  `json:"in_use"` // соединения, выданные из пула
+}
+
+// Периоды отчетов по крупным переводам
+const (
+	ReportPeriodDaily  = "daily"
+	ReportPeriodWeekly = "weekly"
+)
+
+// ReportRow итог крупных переводов пользователя в одной валюте за день
+type ReportRow struct {
+	Day         string  `bson:"day" json:"day"` // YYYY-MM-DD, UTC
+	UserID      int64   `bson:"user_id" json:"user_id"`
+	Currency    string  `bson:"currency" json:"currency"`
+	Count       int64   `bson:"count" json:"count"`
+	TotalAmount float64 `bson:"total_amount" json:"total_amount"`
+}
+
+// ReportCurrencyTotal итог крупных переводов в валюте за период
+type ReportCurrencyTotal struct {
+	Currency    string  `bson:"currency" json:"currency"`
+	Count       int64   `bson:"count" json:"count"`
+	TotalAmount float64 `bson:"total_amount" json:"total_amount"`
+	Users       int64   `bson:"users" json:"users"`
+}
+
+// Report представляет отчет по крупным переводам за день или неделю.
+// Переводы относятся к периоду по времени операции в кошельке (timestamp)
+type Report struct {
+	ID          string                `bson:"_id" json:"id"` // daily-2024-02-02, weekly-2024-01-29
+	Period      string                `bson:"period" json:"period"`
+	PeriodStart time.Time             `bson:"period_start" json:"period_start"`
+	PeriodEnd   time.Time             `bson:"period_end" json:"period_end"`
+	GeneratedAt time.Time             `bson:"generated_at" json:"generated_at"`
+	Total       int64                 `bson:"total" json:"total"`
+	Users       int64                 `bson:"users" json:"users"`
+	ByCurrency  []ReportCurrencyTotal `bson:"by_currency" json:"by_currency"`
+	TopUsers    []UserAlertCount      `bson:"top_users" json:"top_users"`
+	Rows        []ReportRow           `bson:"rows" json:"rows"`
+}
+
+// ReportFilter параметры выборки отчетов
+type ReportFilter struct {
+	Period string    // пусто - все периоды
+	Since  time.Time // отчеты с началом периода не раньше Since, нулевое - без ограничения
+	Limit  int
+}
+
+// SuspiciousFlag отметка подозрительной активности пользователя, выставленная
+// правилом при обработке перевода
+type SuspiciousFlag struct {
+	ID         string    `bson:"_id" json:"id"` // <правило>:<event_id перевода>
+	UserID     int64     `bson:"user_id" json:"user_id"`
+	Rule       string    `bson:"rule" json:"rule"`
+	Reason     string    `bson:"reason" json:"reason"`
+	EventID    string    `bson:"event_id" json:"event_id"`
+	Amount     float64   `bson:"amount" json:"amount"`
+	Currency   string    `bson:"currency" json:"currency"`
+	TransferAt time.Time `bson:"transfer_at" json:"transfer_at"` // время перевода в кошельке
+	FlaggedAt  time.Time `bson:"flagged_at" json:"flagged_at"`
+}
+
+// FlagFilter параметры выборки отметок подозрительной активности
+type FlagFilter struct {
+	UserID int64     // 0 - все пользователи
+	Since  time.Time // отметки не раньше Since, нулевое - без ограничения
+	Limit  int
 }
@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,12 +14,20 @@ import (
 
 // Config содержит конфигурацию для подключения к MongoDB
 type Config struct {
-	URI            string
-	Database       string
-	Collection     string
-	Timeout        time.Duration
-	MaxPoolSize    uint64
-	MinPoolSize    uint64
+	URI         string
+	Database    string
+	Collection  string
+	Timeout     time.Duration
+	MaxPoolSize uint64
+	MinPoolSize uint64
+
+	// Ограничения запросов на чтение
+	QueryMaxTime   time.Duration // maxTimeMS, 0 - без ограничения
+	QueryMaxLimit  int           // максимальное число документов в выборке, 0 - без ограничения
+	QueryBatchSize int32         // размер пакета курсора, 0 - по умолчанию драйвера
+
+	// MigrateOnStart применяет миграции схемы при подключении (см. команду migrate)
+	MigrateOnStart bool
 }
 
 // MongoStorage реализует интерфейс Storage для MongoDB
@@ -26,20 +35,60 @@ type MongoStorage struct {
 	client     *mongo.Client
 	database   *mongo.Database
 	collection *mongo.Collection
-	logger     *logrus.Logger
+	// preferences настройки доставки уведомлений пользователей
+	preferences *mongo.Collection
+	query       queryLimits
+	logger      *logrus.Logger
+
+	// Состояние для Health
+	pool        *poolMonitor
+	poolMaxSize uint64
+	poolMinSize uint64
+	lastWriteAt atomic.Int64 // UnixNano последней успешной записи, 0 - записей не было
+
+	// Результаты очистки устаревших переводов для Health
+	purgedTotal atomic.Int64
+	lastPurged  atomic.Int64
+	lastPurgeAt atomic.Int64 // UnixNano последней очистки, 0 - очистки не было
 }
 
-// New создает новое подключение к MongoDB
+// queryLimits ограничения запросов на чтение
+type queryLimits struct {
+	maxTime   time.Duration
+	maxLimit  int
+	batchSize int32
+}
+
+// New создает новое подключение к MongoDB и применяет миграции схемы
 func New(cfg *Config, logger *logrus.Logger) (*MongoStorage, error) {
+	storage, err := Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.initSchema(cfg.MigrateOnStart); err != nil {
+		closeCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		storage.Close(closeCtx)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// Open создает подключение к MongoDB без применения миграций
+func Open(cfg *Config, logger *logrus.Logger) (*MongoStorage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
 
 	// Настройка опций клиента
+	pool := &poolMonitor{}
 	clientOptions := options.Client().
 		ApplyURI(cfg.URI).
 		SetMaxPoolSize(cfg.MaxPoolSize).
 		SetMinPoolSize(cfg.MinPoolSize).
-		SetServerSelectionTimeout(cfg.Timeout)
+		SetServerSelectionTimeout(cfg.Timeout).
+		SetPoolMonitor(pool.monitor())
 
 	// Подключение к MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -59,62 +108,22 @@ func New(cfg *Config, logger *logrus.Logger) (*MongoStorage, error) {
 	collection := database.Collection(cfg.Collection)
 
 	storage := &MongoStorage{
-		client:     client,
-		database:   database,
-		collection: collection,
-		logger:     logger,
-	}
-
-	// Создание индексов
-	if err := storage.createIndexes(ctx); err != nil {
-		return nil, fmt.Errorf("failed to create indexes: %w", err)
-	}
-
-	return storage, nil
-}
-
-// createIndexes создает необходимые индексы
-func (s *MongoStorage) createIndexes(ctx context.Context) error {
-	indexes := []mongo.IndexModel{
-		{
-			Keys: map[string]interface{}{
-				"user_id": 1,
-			},
-		},
-		{
-			Keys: map[string]interface{}{
-				"timestamp": -1,
-			},
-		},
-		{
-			Keys: map[string]interface{}{
-				"processed_at": -1,
-			},
-		},
-		{
-			Keys: map[string]interface{}{
-				"type": 1,
-			},
-		},
-		{
-			Keys: map[string]interface{}{
-				"status": 1,
-			},
+		client:      client,
+		database:    database,
+		collection:  collection,
+		preferences: database.Collection(userPreferencesCollection),
+		query: queryLimits{
+			maxTime:   cfg.QueryMaxTime,
+			maxLimit:  cfg.QueryMaxLimit,
+			batchSize: cfg.QueryBatchSize,
 		},
-		{
-			Keys: map[string]interface{}{
-				"amount": -1,
-			},
-		},
-	}
-
-	indexNames, err := s.collection.Indexes().CreateMany(ctx, indexes)
-	if err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
+		logger:      logger,
+		pool:        pool,
+		poolMaxSize: cfg.MaxPoolSize,
+		poolMinSize: cfg.MinPoolSize,
 	}
 
-	s.logger.Infof("Created %d indexes: %v", len(indexNames), indexNames)
-	return nil
+	return storage, nil
 }
 
 // Ping проверяет соединение с базой данных
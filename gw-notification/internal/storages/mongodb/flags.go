@@ -0,0 +1,56 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gw-notification/internal/storages"
+)
+
+// suspiciousUsersCollection коллекция отметок подозрительной активности пользователей
+const suspiciousUsersCollection = "suspicious_users"
+
+// SaveFlag вставляет отметку с детерминированным _id: повторная обработка
+// того же перевода не создает вторую отметку
+func (s *MongoStorage) SaveFlag(ctx context.Context, flag *storages.SuspiciousFlag) (bool, error) {
+	_, err := s.database.Collection(suspiciousUsersCollection).InsertOne(ctx, flag)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to save flag %s: %v", flag.ID, err)
+		return false, fmt.Errorf("failed to save flag: %w", err)
+	}
+	return true, nil
+}
+
+// GetFlags возвращает отметки по убыванию времени выставления
+func (s *MongoStorage) GetFlags(ctx context.Context, filter storages.FlagFilter) ([]storages.SuspiciousFlag, error) {
+	query := bson.M{}
+	if filter.UserID > 0 {
+		query["user_id"] = filter.UserID
+	}
+	if !filter.Since.IsZero() {
+		query["flagged_at"] = bson.M{"$gte": filter.Since}
+	}
+
+	opts := s.findOptions(filter.Limit).
+		SetSort(bson.D{{Key: "flagged_at", Value: -1}})
+
+	cursor, err := s.database.Collection(suspiciousUsersCollection).Find(ctx, query, opts)
+	if err != nil {
+		s.logger.Errorf("Failed to query flags: %v", err)
+		return nil, fmt.Errorf("failed to query flags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	flags := []storages.SuspiciousFlag{}
+	if err := cursor.All(ctx, &flags); err != nil {
+		s.logger.Errorf("Failed to decode flags: %v", err)
+		return nil, fmt.Errorf("failed to decode flags: %w", err)
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,80 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gw-notification/internal/storages"
+)
+
+// poolMonitor считает открытые и выданные соединения пула по событиям драйвера
+type poolMonitor struct {
+	open  atomic.Int64
+	inUse atomic.Int64
+}
+
+// monitor возвращает PoolMonitor для опций клиента
+func (m *poolMonitor) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				m.open.Add(1)
+			case event.ConnectionClosed:
+				m.open.Add(-1)
+			case event.GetSucceeded:
+				m.inUse.Add(1)
+			case event.ConnectionReturned:
+				m.inUse.Add(-1)
+			}
+		},
+	}
+}
+
+// Health возвращает состояние подключения к MongoDB
+func (s *MongoStorage) Health(ctx context.Context) (*storages.HealthDetails, error) {
+	start := time.Now()
+	err := s.client.Ping(ctx, readpref.Primary())
+
+	details := &storages.HealthDetails{
+		Status:    storages.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Pool: storages.PoolStats{
+			MaxSize: s.poolMaxSize,
+			MinSize: s.poolMinSize,
+			Open:    s.pool.open.Load(),
+			InUse:   s.pool.inUse.Load(),
+		},
+		CheckedAt: time.Now(),
+	}
+
+	if lastWrite := s.lastWriteAt.Load(); lastWrite > 0 {
+		t := time.Unix(0, lastWrite)
+		details.LastWriteAt = &t
+	}
+
+	if lastPurge := s.lastPurgeAt.Load(); lastPurge > 0 {
+		details.Retention = &storages.RetentionStats{
+			PurgedTotal: s.purgedTotal.Load(),
+			LastPurged:  s.lastPurged.Load(),
+			LastPurgeAt: time.Unix(0, lastPurge),
+		}
+	}
+
+	if err != nil {
+		details.Status = storages.HealthStatusUnavailable
+		details.Error = err.Error()
+		return details, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+
+	return details, nil
+}
+
+// markWrite запоминает время последней успешной записи
+func (s *MongoStorage) markWrite() {
+	s.lastWriteAt.Store(time.Now().UnixNano())
+}
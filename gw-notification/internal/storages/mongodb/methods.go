@@ -2,29 +2,48 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
-	"gw-notification/internal/storages"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"gw-notification/internal/storages"
 )
 
+// duplicateKeyErrorCode код ошибки MongoDB при нарушении уникального индекса
+const duplicateKeyErrorCode = 11000
+
 // SaveTransfer сохраняет информацию о крупном переводе
 func (s *MongoStorage) SaveTransfer(ctx context.Context, transfer *storages.LargeTransfer) error {
 	transfer.ProcessedAt = time.Now()
 	transfer.Status = storages.StatusProcessed
 
-	result, err := s.collection.InsertOne(ctx, transfer)
+	transfers := []storages.LargeTransfer{*transfer}
+	if err := s.annotateUserStatus(ctx, transfers); err != nil {
+		s.logger.Errorf("Failed to annotate transfer: %v", err)
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+	transfer.UserStatus = transfers[0].UserStatus
+
+	result, err := s.collection.BulkWrite(ctx, []mongo.WriteModel{transferWriteModel(*transfer)})
+	if mongo.IsDuplicateKeyError(err) || (err == nil && result.InsertedCount+result.UpsertedCount == 0) {
+		s.logger.Debugf("Skipping duplicate transfer: EventID=%s", transfer.EventID)
+		return nil
+	}
 	if err != nil {
 		s.logger.Errorf("Failed to save transfer: %v", err)
 		return fmt.Errorf("failed to save transfer: %w", err)
 	}
 
-	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
-		transfer.ID = oid
+	for _, id := range result.UpsertedIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			transfer.ID = oid
+		}
 	}
+	s.markWrite()
 
 	s.logger.Debugf("Saved transfer: UserID=%d, Amount=%.2f, Type=%s",
 		transfer.UserID, transfer.Amount, transfer.Type)
@@ -38,29 +57,85 @@ func (s *MongoStorage) SaveTransferBatch(ctx context.Context, transfers []storag
 		return nil
 	}
 
-	// Подготовка документов для вставки
-	documents := make([]interface{}, len(transfers))
+	// Переводы пользователей с отключенной доставкой помечаются их статусом
+	if err := s.annotateUserStatus(ctx, transfers); err != nil {
+		s.logger.Errorf("Failed to annotate transfer batch: %v", err)
+		return fmt.Errorf("failed to save transfer batch: %w", err)
+	}
+
+	// Подготовка операций записи
+	models := make([]mongo.WriteModel, len(transfers))
 	now := time.Now()
 
 	for i := range transfers {
 		transfers[i].ProcessedAt = now
 		transfers[i].Status = storages.StatusProcessed
-		documents[i] = transfers[i]
+		models[i] = transferWriteModel(transfers[i])
 	}
 
-	// Вставка пакетом
-	result, err := s.collection.InsertMany(ctx, documents)
-	if err != nil {
+	// Запись пакетом без упорядочивания: ошибка одного документа не прерывает
+	// запись остальных документов пакета
+	opts := options.BulkWrite().SetOrdered(false)
+	result, err := s.collection.BulkWrite(ctx, models, opts)
+	raced, onlyDuplicates := countDuplicateKeyErrors(err)
+	if !onlyDuplicates {
 		s.logger.Errorf("Failed to save transfer batch: %v", err)
 		return fmt.Errorf("failed to save transfer batch: %w", err)
 	}
 
-	s.logger.Infof("Saved batch of %d transfers (inserted: %d)",
-		len(transfers), len(result.InsertedIDs))
+	// При ошибке дубликата результат содержит данные об успешных операциях
+	inserted := 0
+	if result != nil {
+		inserted = int(result.InsertedCount + result.UpsertedCount)
+	}
+	if inserted > 0 {
+		s.markWrite()
+	}
+
+	s.logger.Infof("Saved batch of %d transfers (inserted: %d, duplicates: %d)",
+		len(transfers), inserted, len(transfers)-inserted)
+	if raced > 0 {
+		s.logger.Debugf("Concurrent upserts of %d transfers resolved as duplicates", raced)
+	}
 
 	return nil
 }
 
+// transferWriteModel возвращает операцию записи перевода. Перевод с event_id
+// записывается upsert-ом по event_id: повторно доставленное сообщение находит
+// существующий документ и не меняет его. Перевод без идентификатора вставляется
+func transferWriteModel(transfer storages.LargeTransfer) mongo.WriteModel {
+	if transfer.EventID == "" {
+		return mongo.NewInsertOneModel().SetDocument(transfer)
+	}
+	// Условие $type повторяет фильтр частичного индекса event_id_unique,
+	// иначе планировщик не сможет использовать индекс для поиска
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.M{"event_id": bson.M{"$eq": transfer.EventID, "$type": "string"}}).
+		SetUpdate(bson.M{"$setOnInsert": transfer}).
+		SetUpsert(true)
+}
+
+// countDuplicateKeyErrors возвращает число дубликатов в ошибке пакетной вставки
+// и признак того, что других ошибок в ней нет
+func countDuplicateKeyErrors(err error) (int, bool) {
+	if err == nil {
+		return 0, true
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return 0, false
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyErrorCode {
+			return 0, false
+		}
+	}
+	return len(bulkErr.WriteErrors), true
+}
+
 // GetTransfer получает перевод по ID
 func (s *MongoStorage) GetTransfer(ctx context.Context, id string) (*storages.LargeTransfer, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -83,9 +158,8 @@ func (s *MongoStorage) GetTransfer(ctx context.Context, id string) (*storages.La
 // GetTransfersByUser получает переводы пользователя
 func (s *MongoStorage) GetTransfersByUser(ctx context.Context, userID int64, limit int) ([]storages.LargeTransfer, error) {
 	filter := bson.M{"user_id": userID}
-	opts := options.Find().
-		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
-		SetLimit(int64(limit))
+	opts := s.findOptions(limit).
+		SetSort(bson.D{{Key: "timestamp", Value: -1}})
 
 	cursor, err := s.collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -106,9 +180,8 @@ func (s *MongoStorage) GetTransfersByUser(ctx context.Context, userID int64, lim
 
 // GetRecentTransfers получает последние переводы
 func (s *MongoStorage) GetRecentTransfers(ctx context.Context, limit int) ([]storages.LargeTransfer, error) {
-	opts := options.Find().
-		SetSort(bson.D{{Key: "processed_at", Value: -1}}).
-		SetLimit(int64(limit))
+	opts := s.findOptions(limit).
+		SetSort(bson.D{{Key: "processed_at", Value: -1}})
 
 	cursor, err := s.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
@@ -158,7 +231,7 @@ func (s *MongoStorage) GetStatistics(ctx context.Context) (*storages.Statistics,
 		},
 	}
 
-	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	cursor, err := s.collection.Aggregate(ctx, pipeline, s.aggregateOptions())
 	if err != nil {
 		s.logger.Errorf("Failed to get statistics: %v", err)
 		return nil, fmt.Errorf("failed to get statistics: %w", err)
@@ -192,3 +265,139 @@ func (s *MongoStorage) GetStatistics(ctx context.Context) (*storages.Statistics,
 
 	return stats, nil
 }
+
+// GetSummary возвращает сводку по переводам начиная с указанного момента.
+// Все разрезы считаются одним агрегационным запросом через $facet.
+func (s *MongoStorage) GetSummary(ctx context.Context, since time.Time, topUsersLimit int) (*storages.Summary, error) {
+	topUsersLimit = int(s.clampLimit(topUsersLimit))
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"timestamp": bson.M{"$gte": since}}},
+		{
+			"$facet": bson.M{
+				"totals": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":   nil,
+							"total": bson.M{"$sum": 1},
+							"failed": bson.M{
+								"$sum": bson.M{
+									"$cond": []interface{}{
+										bson.M{"$eq": []string{"$status", storages.StatusFailed}},
+										1,
+										0,
+									},
+								},
+							},
+						},
+					},
+				},
+				"by_type_currency": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":          bson.M{"type": "$type", "currency": "$from_currency"},
+							"count":        bson.M{"$sum": 1},
+							"total_amount": bson.M{"$sum": "$amount"},
+						},
+					},
+					{
+						"$project": bson.M{
+							"_id":          0,
+							"type":         "$_id.type",
+							"currency":     "$_id.currency",
+							"count":        1,
+							"total_amount": 1,
+						},
+					},
+					{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "type", Value: 1}, {Key: "currency", Value: 1}}},
+				},
+				"top_users": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":          "$user_id",
+							"count":        bson.M{"$sum": 1},
+							"total_amount": bson.M{"$sum": "$amount"},
+						},
+					},
+					{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "total_amount", Value: -1}}},
+					{"$limit": topUsersLimit},
+				},
+			},
+		},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline, s.aggregateOptions())
+	if err != nil {
+		s.logger.Errorf("Failed to get summary: %v", err)
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Totals []struct {
+			Total  int64 `bson:"total"`
+			Failed int64 `bson:"failed"`
+		} `bson:"totals"`
+		ByTypeCurrency []storages.TransferGroupCount `bson:"by_type_currency"`
+		TopUsers       []storages.UserAlertCount     `bson:"top_users"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		s.logger.Errorf("Failed to decode summary: %v", err)
+		return nil, fmt.Errorf("failed to decode summary: %w", err)
+	}
+
+	summary := &storages.Summary{
+		Since:          since,
+		ByTypeCurrency: []storages.TransferGroupCount{},
+		TopUsers:       []storages.UserAlertCount{},
+	}
+	if len(results) > 0 {
+		if len(results[0].Totals) > 0 {
+			summary.Total = results[0].Totals[0].Total
+			summary.Failed = results[0].Totals[0].Failed
+		}
+		if results[0].ByTypeCurrency != nil {
+			summary.ByTypeCurrency = results[0].ByTypeCurrency
+		}
+		if results[0].TopUsers != nil {
+			summary.TopUsers = results[0].TopUsers
+		}
+	}
+
+	return summary, nil
+}
+
+// findOptions возвращает опции выборки с ограничениями запросов на чтение:
+// maxTimeMS, размером пакета курсора и лимитом не больше QueryMaxLimit
+func (s *MongoStorage) findOptions(limit int) *options.FindOptions {
+	opts := options.Find().SetLimit(s.clampLimit(limit))
+	if s.query.maxTime > 0 {
+		opts.SetMaxTime(s.query.maxTime)
+	}
+	if s.query.batchSize > 0 {
+		opts.SetBatchSize(s.query.batchSize)
+	}
+	return opts
+}
+
+// aggregateOptions возвращает опции агрегации с ограничениями запросов на чтение
+func (s *MongoStorage) aggregateOptions() *options.AggregateOptions {
+	opts := options.Aggregate()
+	if s.query.maxTime > 0 {
+		opts.SetMaxTime(s.query.maxTime)
+	}
+	if s.query.batchSize > 0 {
+		opts.SetBatchSize(s.query.batchSize)
+	}
+	return opts
+}
+
+// clampLimit ограничивает число документов в выборке значением QueryMaxLimit.
+// Непозитивный лимит заменяется максимальным
+func (s *MongoStorage) clampLimit(limit int) int64 {
+	if s.query.maxLimit > 0 && (limit <= 0 || limit > s.query.maxLimit) {
+		return int64(s.query.maxLimit)
+	}
+	return int64(limit)
+}
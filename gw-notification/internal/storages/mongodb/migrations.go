@@ -0,0 +1,327 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// migrationsCollection хранит примененные версии миграций и блокировку раннера
+	migrationsCollection = "schema_migrations"
+	// migrationLockID идентификатор документа блокировки в migrationsCollection
+	migrationLockID = "lock"
+	// migrationLockTTL время, после которого блокировка упавшего экземпляра считается устаревшей
+	migrationLockTTL = 15 * time.Minute
+	// migrationLockRetry интервал ожидания блокировки, занятой другим экземпляром
+	migrationLockRetry = time.Second
+	// MigrateTimeout ограничение времени применения миграций (построение индексов
+	// на большой коллекции может занимать минуты)
+	MigrateTimeout = 10 * time.Minute
+
+	// mongoCodeIndexNotFound код ошибки MongoDB при удалении несуществующего индекса
+	mongoCodeIndexNotFound = 27
+)
+
+// migration версионное изменение схемы MongoDB (индексы, преобразование документов).
+// Примененную миграцию менять нельзя - изменения добавляются новой версией.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, s *MongoStorage) error
+}
+
+// migrations список миграций в порядке версий, версии идут подряд начиная с 1
+var migrations = []migration{
+	{
+		version:     1,
+		description: "large transfers base indexes",
+		up: func(ctx context.Context, s *MongoStorage) error {
+			return createIndexes(ctx, s.collection,
+				mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "processed_at", Value: -1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "type", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "amount", Value: -1}}},
+				mongo.IndexModel{
+					// Уникальный индекс по идентификатору события защищает от дубликатов
+					// при повторной доставке сообщений. Документы без event_id не индексируются.
+					Keys: bson.D{{Key: "event_id", Value: 1}},
+					Options: options.Index().
+						SetName("event_id_unique").
+						SetUnique(true).
+						SetPartialFilterExpression(bson.M{"event_id": bson.M{"$type": "string"}}),
+				},
+			)
+		},
+	},
+	{
+		version:     2,
+		description: "compound user_id+timestamp index for transfers by user",
+		up: func(ctx context.Context, s *MongoStorage) error {
+			// Составной индекс покрывает выборку по пользователю с сортировкой по времени,
+			// одиночный user_id_1 становится его префиксом и удаляется после построения нового
+			if err := createIndexes(ctx, s.collection, mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}},
+				Options: options.Index().SetName("user_id_timestamp"),
+			}); err != nil {
+				return err
+			}
+			return dropIndex(ctx, s.collection, "user_id_1")
+		},
+	},
+	{
+		version:     3,
+		description: "reports period+period_start index",
+		up: func(ctx context.Context, s *MongoStorage) error {
+			return createIndexes(ctx, s.database.Collection(reportsCollection), mongo.IndexModel{
+				Keys:    bson.D{{Key: "period", Value: 1}, {Key: "period_start", Value: -1}},
+				Options: options.Index().SetName("period_period_start"),
+			})
+		},
+	},
+	{
+		version:     4,
+		description: "suspicious_users user_id+flagged_at and flagged_at indexes",
+		up: func(ctx context.Context, s *MongoStorage) error {
+			return createIndexes(ctx, s.database.Collection(suspiciousUsersCollection),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "flagged_at", Value: -1}},
+					Options: options.Index().SetName("user_id_flagged_at"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "flagged_at", Value: -1}},
+					Options: options.Index().SetName("flagged_at"),
+				},
+			)
+		},
+	},
+}
+
+// MigrationStatus состояние миграций схемы MongoDB
+type MigrationStatus struct {
+	Version int   // последняя примененная версия, 0 - миграции не применялись
+	Latest  int   // последняя версия, известная сервису
+	Pending []int // версии, ожидающие применения, по порядку
+	Unknown []int // примененные версии, отсутствующие в сервисе (схема новее сборки)
+}
+
+// LatestMigrationVersion возвращает номер последней миграции, известной сервису
+func LatestMigrationVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// PlanMigrations сопоставляет примененные версии со списком миграций сервиса
+func PlanMigrations(applied []int) MigrationStatus {
+	status := MigrationStatus{Latest: LatestMigrationVersion()}
+
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+		if v > status.Version {
+			status.Version = v
+		}
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.version] = true
+		if !done[m.version] {
+			status.Pending = append(status.Pending, m.version)
+		}
+	}
+	for _, v := range applied {
+		if !known[v] {
+			status.Unknown = append(status.Unknown, v)
+		}
+	}
+	sort.Ints(status.Unknown)
+
+	return status
+}
+
+// MigrationStatus возвращает состояние миграций схемы
+func (s *MongoStorage) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	return PlanMigrations(applied), nil
+}
+
+// Migrate применяет непримененные миграции по порядку. Одновременно миграции
+// выполняет только один экземпляр сервиса, остальные ждут блокировку.
+func (s *MongoStorage) Migrate(ctx context.Context) error {
+	release, err := s.acquireMigrationLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Unknown) > 0 {
+		s.logger.Warnf("MongoDB schema has migrations %v unknown to this build, latest known is %d", status.Unknown, status.Latest)
+	}
+	if len(status.Pending) == 0 {
+		s.logger.Infof("MongoDB schema is up to date (version %d)", status.Version)
+		return nil
+	}
+
+	for _, m := range migrations {
+		if !slices.Contains(status.Pending, m.version) {
+			continue
+		}
+
+		started := time.Now()
+		if err := m.up(ctx, s); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.description, err)
+		}
+
+		_, err := s.database.Collection(migrationsCollection).InsertOne(ctx, bson.M{
+			"_id":         m.version,
+			"description": m.description,
+			"applied_at":  time.Now().UTC(),
+			"duration_ms": time.Since(started).Milliseconds(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+
+		s.logger.Infof("Applied MongoDB migration %d (%s) in %v", m.version, m.description, time.Since(started))
+	}
+
+	return nil
+}
+
+// initSchema применяет миграции при запуске либо предупреждает о непримененных
+func (s *MongoStorage) initSchema(migrate bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), MigrateTimeout)
+	defer cancel()
+
+	if migrate {
+		return s.Migrate(ctx)
+	}
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Pending) > 0 {
+		s.logger.Warnf("MongoDB schema migrations %v are not applied: run migrate up", status.Pending)
+	}
+	return nil
+}
+
+// appliedMigrations возвращает версии, записанные в migrationsCollection
+func (s *MongoStorage) appliedMigrations(ctx context.Context) ([]int, error) {
+	cursor, err := s.database.Collection(migrationsCollection).Find(ctx,
+		bson.M{"_id": bson.M{"$type": "number"}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var applied []int
+	for cursor.Next(ctx) {
+		var doc struct {
+			Version int `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode applied migration: %w", err)
+		}
+		applied = append(applied, doc.Version)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// acquireMigrationLock захватывает блокировку раннера миграций. Блокировка
+// старше migrationLockTTL принадлежит упавшему экземпляру и снимается.
+func (s *MongoStorage) acquireMigrationLock(ctx context.Context) (func(), error) {
+	coll := s.database.Collection(migrationsCollection)
+	owner := primitive.NewObjectID().Hex()
+
+	for {
+		_, err := coll.InsertOne(ctx, bson.M{
+			"_id":       migrationLockID,
+			"owner":     owner,
+			"locked_at": time.Now().UTC(),
+		})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		stale, err := coll.DeleteOne(ctx, bson.M{
+			"_id":       migrationLockID,
+			"locked_at": bson.M{"$lt": time.Now().UTC().Add(-migrationLockTTL)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if stale.DeletedCount > 0 {
+			s.logger.Warn("Removed stale MongoDB migration lock")
+			continue
+		}
+
+		s.logger.Info("Waiting for MongoDB migrations running on another instance")
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", ctx.Err())
+		case <-time.After(migrationLockRetry):
+		}
+	}
+
+	release := func() {
+		// Блокировка снимается и при отмененном контексте миграций
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if _, err := coll.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner}); err != nil {
+			s.logger.Errorf("Failed to release MongoDB migration lock: %v", err)
+		}
+	}
+	return release, nil
+}
+
+// createIndexes создает индексы коллекции. Повторное создание индекса
+// с той же спецификацией ничего не меняет.
+func createIndexes(ctx context.Context, coll *mongo.Collection, indexes ...mongo.IndexModel) error {
+	if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", coll.Name(), err)
+	}
+	return nil
+}
+
+// dropIndex удаляет индекс коллекции, отсутствие индекса ошибкой не считается
+func dropIndex(ctx context.Context, coll *mongo.Collection, name string) error {
+	_, err := coll.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == mongoCodeIndexNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to drop index %s on %s: %w", name, coll.Name(), err)
+	}
+	return nil
+}
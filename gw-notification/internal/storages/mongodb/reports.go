@@ -0,0 +1,183 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gw-notification/internal/storages"
+)
+
+// reportsCollection коллекция отчетов по крупным переводам
+const reportsCollection = "reports"
+
+// BuildReport агрегирует переводы периода одним запросом через $facet.
+// Отчет строится фоновой задачей, поэтому QUERY_MAX_TIME к нему не применяется:
+// время ограничивает контекст генератора
+func (s *MongoStorage) BuildReport(ctx context.Context, period string, start, end time.Time, topUsers int) (*storages.Report, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"timestamp": bson.M{"$gte": start, "$lt": end}}},
+		{
+			"$facet": bson.M{
+				"totals": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":   nil,
+							"total": bson.M{"$sum": 1},
+							"users": bson.M{"$addToSet": "$user_id"},
+						},
+					},
+					{"$project": bson.M{"_id": 0, "total": 1, "users": bson.M{"$size": "$users"}}},
+				},
+				"by_currency": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":          "$from_currency",
+							"count":        bson.M{"$sum": 1},
+							"total_amount": bson.M{"$sum": "$amount"},
+							"users":        bson.M{"$addToSet": "$user_id"},
+						},
+					},
+					{
+						"$project": bson.M{
+							"_id":          0,
+							"currency":     "$_id",
+							"count":        1,
+							"total_amount": 1,
+							"users":        bson.M{"$size": "$users"},
+						},
+					},
+					{"$sort": bson.D{{Key: "total_amount", Value: -1}, {Key: "currency", Value: 1}}},
+				},
+				"top_users": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":          "$user_id",
+							"count":        bson.M{"$sum": 1},
+							"total_amount": bson.M{"$sum": "$amount"},
+						},
+					},
+					{"$sort": bson.D{{Key: "total_amount", Value: -1}, {Key: "_id", Value: 1}}},
+					{"$limit": topUsers},
+				},
+				"rows": []bson.M{
+					{
+						"$group": bson.M{
+							"_id": bson.M{
+								"day":      bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$timestamp"}},
+								"user_id":  "$user_id",
+								"currency": "$from_currency",
+							},
+							"count":        bson.M{"$sum": 1},
+							"total_amount": bson.M{"$sum": "$amount"},
+						},
+					},
+					{
+						"$project": bson.M{
+							"_id":          0,
+							"day":          "$_id.day",
+							"user_id":      "$_id.user_id",
+							"currency":     "$_id.currency",
+							"count":        1,
+							"total_amount": 1,
+						},
+					},
+					{"$sort": bson.D{{Key: "day", Value: 1}, {Key: "user_id", Value: 1}, {Key: "currency", Value: 1}}},
+				},
+			},
+		},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		s.logger.Errorf("Failed to build %s report: %v", period, err)
+		return nil, fmt.Errorf("failed to build report: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Totals []struct {
+			Total int64 `bson:"total"`
+			Users int64 `bson:"users"`
+		} `bson:"totals"`
+		ByCurrency []storages.ReportCurrencyTotal `bson:"by_currency"`
+		TopUsers   []storages.UserAlertCount      `bson:"top_users"`
+		Rows       []storages.ReportRow           `bson:"rows"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		s.logger.Errorf("Failed to decode %s report: %v", period, err)
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+
+	report := &storages.Report{
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ByCurrency:  []storages.ReportCurrencyTotal{},
+		TopUsers:    []storages.UserAlertCount{},
+		Rows:        []storages.ReportRow{},
+	}
+	if len(results) > 0 {
+		if len(results[0].Totals) > 0 {
+			report.Total = results[0].Totals[0].Total
+			report.Users = results[0].Totals[0].Users
+		}
+		if results[0].ByCurrency != nil {
+			report.ByCurrency = results[0].ByCurrency
+		}
+		if results[0].TopUsers != nil {
+			report.TopUsers = results[0].TopUsers
+		}
+		if results[0].Rows != nil {
+			report.Rows = results[0].Rows
+		}
+	}
+
+	return report, nil
+}
+
+// SaveReport вставляет отчет с детерминированным _id. Если отчет за период
+// уже сохранен другим экземпляром сервиса, вставка падает на уникальном _id
+// и отчет не перезаписывается
+func (s *MongoStorage) SaveReport(ctx context.Context, report *storages.Report) (bool, error) {
+	_, err := s.database.Collection(reportsCollection).InsertOne(ctx, report)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to save report %s: %v", report.ID, err)
+		return false, fmt.Errorf("failed to save report: %w", err)
+	}
+	return true, nil
+}
+
+// GetReports возвращает отчеты по убыванию начала периода
+func (s *MongoStorage) GetReports(ctx context.Context, filter storages.ReportFilter) ([]storages.Report, error) {
+	query := bson.M{}
+	if filter.Period != "" {
+		query["period"] = filter.Period
+	}
+	if !filter.Since.IsZero() {
+		query["period_start"] = bson.M{"$gte": filter.Since}
+	}
+
+	opts := s.findOptions(filter.Limit).
+		SetSort(bson.D{{Key: "period_start", Value: -1}, {Key: "period", Value: 1}})
+
+	cursor, err := s.database.Collection(reportsCollection).Find(ctx, query, opts)
+	if err != nil {
+		s.logger.Errorf("Failed to query reports: %v", err)
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := []storages.Report{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		s.logger.Errorf("Failed to decode reports: %v", err)
+		return nil, fmt.Errorf("failed to decode reports: %w", err)
+	}
+
+	return reports, nil
+}
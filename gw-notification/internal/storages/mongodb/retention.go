@@ -0,0 +1,64 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurgeTransfers удаляет переводы, обработанные раньше before. Удаление идет
+// пакетами по _id, чтобы не держать одну долгую операцию на большой коллекции
+// и не мешать записи consumer
+func (s *MongoStorage) PurgeTransfers(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	filter := bson.M{"processed_at": bson.M{"$lt": before}}
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.D{{Key: "processed_at", Value: 1}}).
+		SetLimit(int64(batchSize))
+
+	var purged int64
+	defer func() {
+		s.purgedTotal.Add(purged)
+		s.lastPurged.Store(purged)
+		s.lastPurgeAt.Store(time.Now().UnixNano())
+	}()
+
+	for {
+		cursor, err := s.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return purged, fmt.Errorf("failed to find expired transfers: %w", err)
+		}
+
+		var ids []struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &ids); err != nil {
+			return purged, fmt.Errorf("failed to decode expired transfers: %w", err)
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		batch := make([]interface{}, len(ids))
+		for i, doc := range ids {
+			batch[i] = doc.ID
+		}
+
+		result, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": batch}})
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired transfers: %w", err)
+		}
+		purged += result.DeletedCount
+
+		if len(ids) < batchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gw-notification/internal/storages"
+)
+
+// userPreferencesCollection коллекция настроек доставки уведомлений пользователей
+const userPreferencesCollection = "user_preferences"
+
+// userEventStatuses статус пользователя для каждого события жизненного цикла
+var userEventStatuses = map[string]string{
+	storages.UserEventFrozen:    storages.UserStatusFrozen,
+	storages.UserEventDeleted:   storages.UserStatusDeleted,
+	storages.UserEventActivated: storages.UserStatusActive,
+}
+
+// ApplyUserLifecycle обновляет настройки доставки пользователя и помечает его переводы.
+// События применяются по времени из кошелька: событие старше сохраненного пропускается,
+// поэтому повторная или переупорядоченная доставка не откатывает статус
+func (s *MongoStorage) ApplyUserLifecycle(ctx context.Context, event *storages.UserLifecycleMessage) (int64, error) {
+	status, ok := userEventStatuses[event.Event]
+	if !ok {
+		return 0, fmt.Errorf("unknown user event: %s", event.Event)
+	}
+
+	// Upsert с фильтром по времени: если документ с более новым событием уже есть,
+	// фильтр не совпадет и вставка упадет на уникальном _id
+	filter := bson.M{"_id": event.UserID, "updated_at": bson.M{"$lt": event.Timestamp}}
+	update := bson.M{"$set": bson.M{
+		"status":           status,
+		"delivery_enabled": status == storages.UserStatusActive,
+		"reason":           event.Reason,
+		"updated_at":       event.Timestamp,
+	}}
+
+	_, err := s.preferences.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		s.logger.Infof("Skipping outdated user event: EventID=%s, UserID=%d, Event=%s",
+			event.EventID, event.UserID, event.Event)
+		return 0, nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to update user preferences: %v", err)
+		return 0, fmt.Errorf("failed to update user preferences: %w", err)
+	}
+
+	// Переводы активного пользователя не помечаются
+	transferUpdate := bson.M{"$unset": bson.M{"user_status": ""}}
+	if status != storages.UserStatusActive {
+		transferUpdate = bson.M{"$set": bson.M{"user_status": status}}
+	}
+
+	result, err := s.collection.UpdateMany(ctx, bson.M{"user_id": event.UserID}, transferUpdate)
+	if err != nil {
+		s.logger.Errorf("Failed to annotate user transfers: %v", err)
+		return 0, fmt.Errorf("failed to annotate user transfers: %w", err)
+	}
+
+	s.logger.Infof("Applied user event: UserID=%d, Event=%s, Transfers=%d",
+		event.UserID, event.Event, result.ModifiedCount)
+
+	return result.ModifiedCount, nil
+}
+
+// GetUserPreferences возвращает настройки доставки пользователя. Для пользователя
+// без событий возвращаются настройки по умолчанию: активен, доставка включена
+func (s *MongoStorage) GetUserPreferences(ctx context.Context, userID int64) (*storages.UserPreferences, error) {
+	var preferences storages.UserPreferences
+	err := s.preferences.FindOne(ctx, bson.M{"_id": userID}).Decode(&preferences)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &storages.UserPreferences{
+			UserID:          userID,
+			Status:          storages.UserStatusActive,
+			DeliveryEnabled: true,
+		}, nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to get user preferences: %v", err)
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	return &preferences, nil
+}
+
+// annotateUserStatus помечает переводы пользователей с отключенной доставкой,
+// чтобы переводы, пришедшие после события кошелька, не отличались от сохраненных ранее
+func (s *MongoStorage) annotateUserStatus(ctx context.Context, transfers []storages.LargeTransfer) error {
+	userIDs := make([]int64, 0, len(transfers))
+	for _, t := range transfers {
+		userIDs = append(userIDs, t.UserID)
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": userIDs}, "delivery_enabled": false}
+	cursor, err := s.preferences.Find(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to query user preferences: %w", err)
+	}
+
+	var disabled []storages.UserPreferences
+	if err := cursor.All(ctx, &disabled); err != nil {
+		return fmt.Errorf("failed to decode user preferences: %w", err)
+	}
+	if len(disabled) == 0 {
+		return nil
+	}
+
+	statuses := make(map[int64]string, len(disabled))
+	for _, p := range disabled {
+		statuses[p.UserID] = p.Status
+	}
+	for i := range transfers {
+		transfers[i].UserStatus = statuses[transfers[i].UserID]
+	}
+
+	return nil
+}
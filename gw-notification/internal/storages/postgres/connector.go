@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Config содержит конфигурацию для подключения к PostgreSQL
+type Config struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// Ограничения запросов на чтение
+	QueryMaxTime  time.Duration // срок запроса, 0 - без ограничения
+	QueryMaxLimit int           // максимальное число строк в выборке, 0 - без ограничения
+
+	// MigrateOnStart применяет миграции схемы при подключении. Без него
+	// сервис только предупреждает о непримененных миграциях
+	MigrateOnStart bool
+}
+
+// PostgresStorage реализует интерфейс Storage для PostgreSQL. Переводы, настройки
+// пользователей и отметки хранятся в таблицах, отчеты - в JSONB
+type PostgresStorage struct {
+	db     *sql.DB
+	query  queryLimits
+	logger *logrus.Logger
+
+	// Состояние для Health
+	poolMaxSize uint64
+	poolMinSize uint64
+	lastWriteAt atomic.Int64 // UnixNano последней успешной записи, 0 - записей не было
+
+	// Результаты очистки устаревших переводов для Health
+	purgedTotal atomic.Int64
+	lastPurged  atomic.Int64
+	lastPurgeAt atomic.Int64 // UnixNano последней очистки, 0 - очистки не было
+}
+
+// queryLimits ограничения запросов на чтение
+type queryLimits struct {
+	maxTime  time.Duration
+	maxLimit int
+}
+
+// New создает новое подключение к PostgreSQL и применяет миграции схемы
+func New(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
+	storage, err := Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), MigrateTimeout)
+	defer cancel()
+
+	if err := storage.initSchema(ctx, cfg.MigrateOnStart); err != nil {
+		storage.db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// Open подключается к PostgreSQL без применения миграций (для команды migrate)
+func Open(cfg *Config, logger *logrus.Logger) (*PostgresStorage, error) {
+	dsn := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Настройка пула соединений
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Проверка подключения
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.Info("Successfully connected to PostgreSQL")
+
+	return &PostgresStorage{
+		db: db,
+		query: queryLimits{
+			maxTime:  cfg.QueryMaxTime,
+			maxLimit: cfg.QueryMaxLimit,
+		},
+		logger:      logger,
+		poolMaxSize: uint64(cfg.MaxOpenConns),
+		poolMinSize: uint64(cfg.MaxIdleConns),
+	}, nil
+}
+
+// initSchema применяет миграции при запуске либо проверяет, что все миграции применены
+func (s *PostgresStorage) initSchema(ctx context.Context, migrate bool) error {
+	if migrate {
+		if err := s.MigrateUp(ctx); err != nil {
+			return err
+		}
+	}
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database schema version %d is dirty, fix it manually", status.Version)
+	}
+	if status.Pending() {
+		s.logger.Warnf("Database schema is at version %d, latest is %d: run migrate up", status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Close закрывает соединение с базой данных
+func (s *PostgresStorage) Close(ctx context.Context) error {
+	if s.db != nil {
+		s.logger.Info("Closing database connection")
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Ping проверяет соединение с базой данных
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
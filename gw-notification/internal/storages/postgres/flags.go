@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gw-notification/internal/storages"
+)
+
+// SaveFlag вставляет отметку с детерминированным ID: повторная обработка
+// того же перевода не создает вторую отметку
+func (s *PostgresStorage) SaveFlag(ctx context.Context, flag *storages.SuspiciousFlag) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO suspicious_flags (id, user_id, rule, reason, event_id, amount, currency, transfer_at, flagged_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO NOTHING`,
+		flag.ID, flag.UserID, flag.Rule, flag.Reason, flag.EventID, flag.Amount, flag.Currency, flag.TransferAt, flag.FlaggedAt,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to save flag %s: %v", flag.ID, err)
+		return false, fmt.Errorf("failed to save flag: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to save flag: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetFlags возвращает отметки по убыванию времени выставления
+func (s *PostgresStorage) GetFlags(ctx context.Context, filter storages.FlagFilter) ([]storages.SuspiciousFlag, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.UserID > 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("flagged_at >= $%d", len(args)))
+	}
+
+	query := `SELECT id, user_id, rule, reason, event_id, amount, currency, transfer_at, flagged_at FROM suspicious_flags`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, s.limitArg(filter.Limit))
+	query += fmt.Sprintf(" ORDER BY flagged_at DESC, id LIMIT $%d", len(args))
+
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	flags := []storages.SuspiciousFlag{}
+	err := scanRows(ctx, s.db, func(rows *sql.Rows) error {
+		var flag storages.SuspiciousFlag
+		err := rows.Scan(&flag.ID, &flag.UserID, &flag.Rule, &flag.Reason, &flag.EventID,
+			&flag.Amount, &flag.Currency, &flag.TransferAt, &flag.FlaggedAt)
+		if err != nil {
+			return err
+		}
+		flags = append(flags, flag)
+		return nil
+	}, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query flags: %v", err)
+		return nil, fmt.Errorf("failed to query flags: %w", err)
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gw-notification/internal/storages"
+)
+
+// Health возвращает состояние подключения к PostgreSQL
+func (s *PostgresStorage) Health(ctx context.Context) (*storages.HealthDetails, error) {
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+
+	stats := s.db.Stats()
+	details := &storages.HealthDetails{
+		Status:    storages.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Pool: storages.PoolStats{
+			MaxSize: s.poolMaxSize,
+			MinSize: s.poolMinSize,
+			Open:    int64(stats.OpenConnections),
+			InUse:   int64(stats.InUse),
+		},
+		CheckedAt: time.Now(),
+	}
+
+	if lastWrite := s.lastWriteAt.Load(); lastWrite > 0 {
+		t := time.Unix(0, lastWrite)
+		details.LastWriteAt = &t
+	}
+
+	if lastPurge := s.lastPurgeAt.Load(); lastPurge > 0 {
+		details.Retention = &storages.RetentionStats{
+			PurgedTotal: s.purgedTotal.Load(),
+			LastPurged:  s.lastPurged.Load(),
+			LastPurgeAt: time.Unix(0, lastPurge),
+		}
+	}
+
+	if err != nil {
+		details.Status = storages.HealthStatusUnavailable
+		details.Error = err.Error()
+		return details, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,399 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gw-notification/internal/storages"
+)
+
+// transferColumns колонки перевода в порядке scanTransfer
+const transferColumns = `
+	id, COALESCE(event_id, ''), user_id, type, from_currency, to_currency, amount,
+	timestamp, processed_at, status, error_message, user_status,
+	converted_amount, converted_currency, trace_id, source`
+
+// insertTransferQuery вставляет перевод. Повторно доставленное сообщение с тем же
+// event_id не меняет сохраненный перевод; переводы без event_id вставляются всегда
+const insertTransferQuery = `
+	INSERT INTO large_transfers (
+		id, event_id, user_id, type, from_currency, to_currency, amount,
+		timestamp, processed_at, status, error_message, user_status,
+		converted_amount, converted_currency, trace_id, source
+	)
+	VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	ON CONFLICT (event_id) WHERE event_id IS NOT NULL DO NOTHING`
+
+// execer общий интерфейс *sql.DB и *sql.Tx для записи
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer общий интерфейс *sql.DB и *sql.Tx для чтения
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scanner общий интерфейс *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// SaveTransfer сохраняет информацию о крупном переводе
+func (s *PostgresStorage) SaveTransfer(ctx context.Context, transfer *storages.LargeTransfer) error {
+	transfer.ProcessedAt = time.Now()
+	transfer.Status = storages.StatusProcessed
+
+	transfers := []storages.LargeTransfer{*transfer}
+	if err := s.annotateUserStatus(ctx, s.db, transfers); err != nil {
+		s.logger.Errorf("Failed to annotate transfer: %v", err)
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+	transfer.UserStatus = transfers[0].UserStatus
+
+	inserted, err := insertTransfer(ctx, s.db, transfer)
+	if err != nil {
+		s.logger.Errorf("Failed to save transfer: %v", err)
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+	if !inserted {
+		s.logger.Debugf("Skipping duplicate transfer: EventID=%s", transfer.EventID)
+		return nil
+	}
+	s.markWrite()
+
+	s.logger.Debugf("Saved transfer: UserID=%d, Amount=%.2f, Type=%s",
+		transfer.UserID, transfer.Amount, transfer.Type)
+
+	return nil
+}
+
+// SaveTransferBatch сохраняет пакет переводов в одной транзакции
+func (s *PostgresStorage) SaveTransferBatch(ctx context.Context, transfers []storages.LargeTransfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Переводы пользователей с отключенной доставкой помечаются их статусом
+	if err := s.annotateUserStatus(ctx, tx, transfers); err != nil {
+		s.logger.Errorf("Failed to annotate transfer batch: %v", err)
+		return fmt.Errorf("failed to save transfer batch: %w", err)
+	}
+
+	now := time.Now()
+	inserted := 0
+	for i := range transfers {
+		transfers[i].ProcessedAt = now
+		transfers[i].Status = storages.StatusProcessed
+
+		ok, err := insertTransfer(ctx, tx, &transfers[i])
+		if err != nil {
+			s.logger.Errorf("Failed to save transfer batch: %v", err)
+			return fmt.Errorf("failed to save transfer batch: %w", err)
+		}
+		if ok {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Errorf("Failed to save transfer batch: %v", err)
+		return fmt.Errorf("failed to save transfer batch: %w", err)
+	}
+	if inserted > 0 {
+		s.markWrite()
+	}
+
+	s.logger.Infof("Saved batch of %d transfers (inserted: %d, duplicates: %d)",
+		len(transfers), inserted, len(transfers)-inserted)
+
+	return nil
+}
+
+// insertTransfer вставляет перевод с новым ID и сообщает, был ли он вставлен
+func insertTransfer(ctx context.Context, exec execer, transfer *storages.LargeTransfer) (bool, error) {
+	id := primitive.NewObjectID()
+	result, err := exec.ExecContext(ctx, insertTransferQuery,
+		id.Hex(), transfer.EventID, transfer.UserID, transfer.Type,
+		transfer.FromCurrency, transfer.ToCurrency, transfer.Amount,
+		transfer.Timestamp, transfer.ProcessedAt, transfer.Status,
+		transfer.ErrorMessage, transfer.UserStatus,
+		transfer.ConvertedAmount, transfer.ConvertedCurrency, transfer.TraceID, transfer.Source,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	transfer.ID = id
+	return true, nil
+}
+
+// GetTransfer получает перевод по ID
+func (s *PostgresStorage) GetTransfer(ctx context.Context, id string) (*storages.LargeTransfer, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, fmt.Errorf("invalid ID format: %w", err)
+	}
+
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT `+transferColumns+` FROM large_transfers WHERE id = $1`, id)
+	transfer, err := scanTransfer(row)
+	if err != nil {
+		s.logger.Errorf("Failed to get transfer: %v", err)
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// GetTransfersByUser получает переводы пользователя
+func (s *PostgresStorage) GetTransfersByUser(ctx context.Context, userID int64, limit int) ([]storages.LargeTransfer, error) {
+	query := `SELECT ` + transferColumns + `
+		FROM large_transfers
+		WHERE user_id = $1
+		ORDER BY timestamp DESC
+		LIMIT $2`
+
+	transfers, err := s.queryTransfers(ctx, query, userID, s.limitArg(limit))
+	if err != nil {
+		s.logger.Errorf("Failed to query transfers: %v", err)
+		return nil, fmt.Errorf("failed to query transfers: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d transfers for user %d", len(transfers), userID)
+	return transfers, nil
+}
+
+// GetRecentTransfers получает последние переводы
+func (s *PostgresStorage) GetRecentTransfers(ctx context.Context, limit int) ([]storages.LargeTransfer, error) {
+	query := `SELECT ` + transferColumns + `
+		FROM large_transfers
+		ORDER BY processed_at DESC
+		LIMIT $1`
+
+	transfers, err := s.queryTransfers(ctx, query, s.limitArg(limit))
+	if err != nil {
+		s.logger.Errorf("Failed to query recent transfers: %v", err)
+		return nil, fmt.Errorf("failed to query recent transfers: %w", err)
+	}
+
+	s.logger.Debugf("Retrieved %d recent transfers", len(transfers))
+	return transfers, nil
+}
+
+// queryTransfers выполняет выборку переводов с ограничениями запросов на чтение
+func (s *PostgresStorage) queryTransfers(ctx context.Context, query string, args ...interface{}) ([]storages.LargeTransfer, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []storages.LargeTransfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// scanTransfer читает перевод из строки выборки с колонками transferColumns
+func scanTransfer(row scanner) (storages.LargeTransfer, error) {
+	var transfer storages.LargeTransfer
+	var id string
+	err := row.Scan(
+		&id, &transfer.EventID, &transfer.UserID, &transfer.Type,
+		&transfer.FromCurrency, &transfer.ToCurrency, &transfer.Amount,
+		&transfer.Timestamp, &transfer.ProcessedAt, &transfer.Status,
+		&transfer.ErrorMessage, &transfer.UserStatus,
+		&transfer.ConvertedAmount, &transfer.ConvertedCurrency, &transfer.TraceID, &transfer.Source,
+	)
+	if err != nil {
+		return transfer, err
+	}
+
+	transfer.ID, err = primitive.ObjectIDFromHex(id)
+	return transfer, err
+}
+
+// GetStatistics возвращает статистику обработки
+func (s *PostgresStorage) GetStatistics(ctx context.Context) (*storages.Statistics, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = $1),
+			COUNT(*) FILTER (WHERE status = $2),
+			COALESCE(AVG(amount), 0),
+			COALESCE(SUM(amount), 0),
+			MAX(processed_at)
+		FROM large_transfers`
+
+	stats := &storages.Statistics{}
+	var lastProcessed sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, storages.StatusProcessed, storages.StatusFailed).Scan(
+		&stats.TotalProcessed,
+		&stats.TotalFailed,
+		&stats.AverageAmount,
+		&stats.TotalAmount,
+		&lastProcessed,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to get statistics: %v", err)
+		return nil, fmt.Errorf("failed to get statistics: %w", err)
+	}
+	stats.LastProcessedAt = lastProcessed.Time
+
+	s.logger.Debugf("Statistics: Processed=%d, Failed=%d, Avg=%.2f",
+		stats.TotalProcessed, stats.TotalFailed, stats.AverageAmount)
+
+	return stats, nil
+}
+
+// GetSummary возвращает сводку по переводам начиная с указанного момента.
+// Разрезы считаются в одной читающей транзакции, поэтому итоги согласованы
+func (s *PostgresStorage) GetSummary(ctx context.Context, since time.Time, topUsersLimit int) (*storages.Summary, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	summary := &storages.Summary{
+		Since:          since,
+		ByTypeCurrency: []storages.TransferGroupCount{},
+		TopUsers:       []storages.UserAlertCount{},
+	}
+
+	err := s.readTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE status = $2)
+			FROM large_transfers
+			WHERE timestamp >= $1`, since, storages.StatusFailed,
+		).Scan(&summary.Total, &summary.Failed)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT type, from_currency, COUNT(*), SUM(amount)
+			FROM large_transfers
+			WHERE timestamp >= $1
+			GROUP BY type, from_currency
+			ORDER BY COUNT(*) DESC, type, from_currency`, since)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var group storages.TransferGroupCount
+			if err := rows.Scan(&group.Type, &group.Currency, &group.Count, &group.TotalAmount); err != nil {
+				rows.Close()
+				return err
+			}
+			summary.ByTypeCurrency = append(summary.ByTypeCurrency, group)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		topUsers, err := queryUserAlerts(ctx, tx, `
+			SELECT user_id, COUNT(*), SUM(amount)
+			FROM large_transfers
+			WHERE timestamp >= $1
+			GROUP BY user_id
+			ORDER BY COUNT(*) DESC, SUM(amount) DESC, user_id
+			LIMIT $2`, since, s.limitArg(topUsersLimit))
+		if err != nil {
+			return err
+		}
+		summary.TopUsers = append(summary.TopUsers, topUsers...)
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorf("Failed to get summary: %v", err)
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// queryUserAlerts читает итоги пользователей из выборки user_id, count, total_amount
+func queryUserAlerts(ctx context.Context, q queryer, query string, args ...interface{}) ([]storages.UserAlertCount, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []storages.UserAlertCount{}
+	for rows.Next() {
+		var user storages.UserAlertCount
+		if err := rows.Scan(&user.UserID, &user.Count, &user.TotalAmount); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// readTx выполняет fn в читающей транзакции с единым снимком данных
+func (s *PostgresStorage) readTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// readContext ограничивает запрос на чтение сроком QueryMaxTime
+func (s *PostgresStorage) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.query.maxTime > 0 {
+		return context.WithTimeout(ctx, s.query.maxTime)
+	}
+	return ctx, func() {}
+}
+
+// limitArg возвращает параметр LIMIT не больше QueryMaxLimit. Непозитивный лимит
+// заменяется максимальным, без максимума - NULL (все строки)
+func (s *PostgresStorage) limitArg(limit int) interface{} {
+	if s.query.maxLimit > 0 && (limit <= 0 || limit > s.query.maxLimit) {
+		return s.query.maxLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
+
+// markWrite запоминает время последней успешной записи
+func (s *PostgresStorage) markWrite() {
+	s.lastWriteAt.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepg "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationsFS версионные миграции схемы: NNNNNN_name.up.sql и NNNNNN_name.down.sql
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// MigrateTimeout ограничение времени применения миграций (построение индексов
+// на большой таблице может занимать минуты)
+const MigrateTimeout = 10 * time.Minute
+
+// MigrationStatus состояние миграций схемы БД
+type MigrationStatus struct {
+	// Version примененная версия, 0 - миграции не применялись
+	Version uint
+	// Latest последняя версия среди встроенных миграций
+	Latest uint
+	// Dirty миграция Version завершилась ошибкой: схему нужно исправить вручную
+	// и отметить версию через migrate force
+	Dirty bool
+}
+
+// Pending сообщает, что есть непримененные миграции
+func (s MigrationStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// MigrateUp применяет все непримененные миграции
+func (s *PostgresStorage) MigrateUp(ctx context.Context) error {
+	m, err := s.newMigrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	s.logger.Infof("Database schema is at version %d", version)
+	return nil
+}
+
+// MigrationStatus возвращает примененную и последнюю версии схемы
+func (s *PostgresStorage) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	m, err := s.newMigrate(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	defer m.Close()
+
+	var status MigrationStatus
+	status.Version, status.Dirty, err = m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if status.Latest, err = LatestMigrationVersion(); err != nil {
+		return MigrationStatus{}, err
+	}
+	return status, nil
+}
+
+// LatestMigrationVersion возвращает последнюю версию среди встроенных миграций
+func LatestMigrationVersion() (uint, error) {
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer src.Close()
+
+	return lastVersion(src)
+}
+
+// lastVersion проходит версии источника миграций до последней
+func lastVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// newMigrate создает migrate на отдельном соединении из пула. Закрытие migrate
+// закрывает только это соединение, пул хранилища продолжает работать
+func (s *PostgresStorage) newMigrate(ctx context.Context) (*migrate.Migrate, error) {
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	driver, err := migratepg.WithConnection(ctx, conn, &migratepg.Config{})
+	if err != nil {
+		conn.Close()
+		src.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		src.Close()
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	m.Log = migrateLogger{s}
+	return m, nil
+}
+
+// migrateLogger передает сообщения migrate в логгер хранилища
+type migrateLogger struct {
+	s *PostgresStorage
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	l.s.logger.Info("Migration: " + strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
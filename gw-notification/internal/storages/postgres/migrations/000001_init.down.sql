@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS suspicious_flags;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS user_preferences;
+DROP TABLE IF EXISTS large_transfers;
@@ -0,0 +1,71 @@
+-- Крупные переводы. id - шестнадцатеричный ObjectID, как в MongoDB, чтобы
+-- идентификаторы в API не зависели от хранилища
+CREATE TABLE IF NOT EXISTS large_transfers (
+	id CHAR(24) PRIMARY KEY,
+	event_id VARCHAR(128),
+	user_id BIGINT NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	from_currency VARCHAR(3) NOT NULL DEFAULT '',
+	to_currency VARCHAR(3) NOT NULL DEFAULT '',
+	amount NUMERIC(20, 8) NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	processed_at TIMESTAMPTZ NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	error_message TEXT NOT NULL DEFAULT '',
+	user_status VARCHAR(20) NOT NULL DEFAULT '',
+	converted_amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+	converted_currency VARCHAR(3) NOT NULL DEFAULT '',
+	trace_id VARCHAR(64) NOT NULL DEFAULT '',
+	source VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+-- Дедупликация повторно доставленных сообщений; переводы без event_id не ограничиваются
+CREATE UNIQUE INDEX IF NOT EXISTS idx_large_transfers_event_id
+	ON large_transfers(event_id) WHERE event_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_large_transfers_user_timestamp
+	ON large_transfers(user_id, timestamp DESC);
+
+CREATE INDEX IF NOT EXISTS idx_large_transfers_timestamp
+	ON large_transfers(timestamp);
+
+CREATE INDEX IF NOT EXISTS idx_large_transfers_processed_at
+	ON large_transfers(processed_at);
+
+-- Настройки доставки уведомлений по событиям жизненного цикла пользователей кошелька
+CREATE TABLE IF NOT EXISTS user_preferences (
+	user_id BIGINT PRIMARY KEY,
+	status VARCHAR(20) NOT NULL,
+	delivery_enabled BOOLEAN NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Отчеты хранятся целиком в JSONB: строки отчета читаются только вместе с ним
+CREATE TABLE IF NOT EXISTS reports (
+	id VARCHAR(64) PRIMARY KEY,
+	period VARCHAR(20) NOT NULL,
+	period_start TIMESTAMPTZ NOT NULL,
+	body JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_period_start
+	ON reports(period_start DESC, period);
+
+CREATE TABLE IF NOT EXISTS suspicious_flags (
+	id VARCHAR(200) PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	rule VARCHAR(50) NOT NULL,
+	reason TEXT NOT NULL,
+	event_id VARCHAR(128) NOT NULL,
+	amount NUMERIC(20, 8) NOT NULL,
+	currency VARCHAR(3) NOT NULL DEFAULT '',
+	transfer_at TIMESTAMPTZ NOT NULL,
+	flagged_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_suspicious_flags_user_flagged_at
+	ON suspicious_flags(user_id, flagged_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_suspicious_flags_flagged_at
+	ON suspicious_flags(flagged_at DESC);
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gw-notification/internal/storages"
+)
+
+// BuildReport агрегирует переводы периода в одной транзакции на чтение, чтобы
+// итоги и строки отчета сходились. Отчет строится фоновой задачей, поэтому
+// QUERY_MAX_TIME к нему не применяется: время ограничивает контекст генератора
+func (s *PostgresStorage) BuildReport(ctx context.Context, period string, start, end time.Time, topUsers int) (*storages.Report, error) {
+	report := &storages.Report{
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ByCurrency:  []storages.ReportCurrencyTotal{},
+		TopUsers:    []storages.UserAlertCount{},
+		Rows:        []storages.ReportRow{},
+	}
+
+	err := s.readTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*), COUNT(DISTINCT user_id)
+			FROM large_transfers
+			WHERE timestamp >= $1 AND timestamp < $2`, start, end,
+		).Scan(&report.Total, &report.Users)
+		if err != nil {
+			return err
+		}
+
+		if err := scanRows(ctx, tx, func(rows *sql.Rows) error {
+			var total storages.ReportCurrencyTotal
+			if err := rows.Scan(&total.Currency, &total.Count, &total.TotalAmount, &total.Users); err != nil {
+				return err
+			}
+			report.ByCurrency = append(report.ByCurrency, total)
+			return nil
+		}, `
+			SELECT from_currency, COUNT(*), SUM(amount), COUNT(DISTINCT user_id)
+			FROM large_transfers
+			WHERE timestamp >= $1 AND timestamp < $2
+			GROUP BY from_currency
+			ORDER BY SUM(amount) DESC, from_currency`, start, end); err != nil {
+			return err
+		}
+
+		users, err := queryUserAlerts(ctx, tx, `
+			SELECT user_id, COUNT(*), SUM(amount)
+			FROM large_transfers
+			WHERE timestamp >= $1 AND timestamp < $2
+			GROUP BY user_id
+			ORDER BY SUM(amount) DESC, user_id
+			LIMIT $3`, start, end, topUsers)
+		if err != nil {
+			return err
+		}
+		report.TopUsers = append(report.TopUsers, users...)
+
+		return scanRows(ctx, tx, func(rows *sql.Rows) error {
+			var row storages.ReportRow
+			if err := rows.Scan(&row.Day, &row.UserID, &row.Currency, &row.Count, &row.TotalAmount); err != nil {
+				return err
+			}
+			report.Rows = append(report.Rows, row)
+			return nil
+		}, `
+			SELECT to_char(timestamp AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, user_id, from_currency,
+				COUNT(*), SUM(amount)
+			FROM large_transfers
+			WHERE timestamp >= $1 AND timestamp < $2
+			GROUP BY day, user_id, from_currency
+			ORDER BY day, user_id, from_currency`, start, end)
+	})
+	if err != nil {
+		s.logger.Errorf("Failed to build %s report: %v", period, err)
+		return nil, fmt.Errorf("failed to build report: %w", err)
+	}
+
+	return report, nil
+}
+
+// SaveReport вставляет отчет с детерминированным ID. Если отчет за период
+// уже сохранен другим экземпляром сервиса, вставка пропускается
+// и отчет не перезаписывается
+func (s *PostgresStorage) SaveReport(ctx context.Context, report *storages.Report) (bool, error) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO reports (id, period, period_start, body)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING`,
+		report.ID, report.Period, report.PeriodStart, body,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to save report %s: %v", report.ID, err)
+		return false, fmt.Errorf("failed to save report: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to save report: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetReports возвращает отчеты по убыванию начала периода
+func (s *PostgresStorage) GetReports(ctx context.Context, filter storages.ReportFilter) ([]storages.Report, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Period != "" {
+		args = append(args, filter.Period)
+		conditions = append(conditions, fmt.Sprintf("period = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("period_start >= $%d", len(args)))
+	}
+
+	query := `SELECT body FROM reports`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, s.limitArg(filter.Limit))
+	query += fmt.Sprintf(" ORDER BY period_start DESC, period LIMIT $%d", len(args))
+
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	reports := []storages.Report{}
+	err := scanRows(ctx, s.db, func(rows *sql.Rows) error {
+		var body []byte
+		if err := rows.Scan(&body); err != nil {
+			return err
+		}
+		var report storages.Report
+		if err := json.Unmarshal(body, &report); err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		return nil
+	}, query, args...)
+	if err != nil {
+		s.logger.Errorf("Failed to query reports: %v", err)
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// scanRows выполняет выборку и передает каждую строку в scan
+func scanRows(ctx context.Context, q queryer, scan func(rows *sql.Rows) error, query string, args ...interface{}) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
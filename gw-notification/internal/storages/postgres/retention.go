@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PurgeTransfers удаляет переводы, обработанные раньше before. Удаление идет
+// пакетами, чтобы не держать одну долгую транзакцию на большой таблице
+// и не мешать записи consumer
+func (s *PostgresStorage) PurgeTransfers(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive")
+	}
+
+	var purged int64
+	defer func() {
+		s.purgedTotal.Add(purged)
+		s.lastPurged.Store(purged)
+		s.lastPurgeAt.Store(time.Now().UnixNano())
+	}()
+
+	for {
+		result, err := s.db.ExecContext(ctx, `
+			DELETE FROM large_transfers
+			WHERE id IN (
+				SELECT id FROM large_transfers
+				WHERE processed_at < $1
+				ORDER BY processed_at
+				LIMIT $2
+			)`, before, batchSize)
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired transfers: %w", err)
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to delete expired transfers: %w", err)
+		}
+		purged += deleted
+
+		if deleted < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
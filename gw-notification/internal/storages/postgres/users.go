@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"gw-notification/internal/storages"
+)
+
+// userEventStatuses статус пользователя для каждого события жизненного цикла
+var userEventStatuses = map[string]string{
+	storages.UserEventFrozen:    storages.UserStatusFrozen,
+	storages.UserEventDeleted:   storages.UserStatusDeleted,
+	storages.UserEventActivated: storages.UserStatusActive,
+}
+
+// ApplyUserLifecycle обновляет настройки доставки пользователя и помечает его переводы
+// в одной транзакции. События применяются по времени из кошелька: событие старше
+// сохраненного пропускается, поэтому повторная или переупорядоченная доставка не
+// откатывает статус
+func (s *PostgresStorage) ApplyUserLifecycle(ctx context.Context, event *storages.UserLifecycleMessage) (int64, error) {
+	status, ok := userEventStatuses[event.Event]
+	if !ok {
+		return 0, fmt.Errorf("unknown user event: %s", event.Event)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Условие WHERE пропускает обновление, если сохранено более новое событие
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO user_preferences (user_id, status, delivery_enabled, reason, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			delivery_enabled = EXCLUDED.delivery_enabled,
+			reason = EXCLUDED.reason,
+			updated_at = EXCLUDED.updated_at
+		WHERE user_preferences.updated_at < EXCLUDED.updated_at`,
+		event.UserID, status, status == storages.UserStatusActive, event.Reason, event.Timestamp,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to update user preferences: %v", err)
+		return 0, fmt.Errorf("failed to update user preferences: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("failed to update user preferences: %w", err)
+	} else if affected == 0 {
+		s.logger.Infof("Skipping outdated user event: EventID=%s, UserID=%d, Event=%s",
+			event.EventID, event.UserID, event.Event)
+		return 0, nil
+	}
+
+	// Переводы активного пользователя не помечаются
+	userStatus := status
+	if status == storages.UserStatusActive {
+		userStatus = ""
+	}
+
+	result, err = tx.ExecContext(ctx,
+		`UPDATE large_transfers SET user_status = $2 WHERE user_id = $1 AND user_status <> $2`,
+		event.UserID, userStatus,
+	)
+	if err != nil {
+		s.logger.Errorf("Failed to annotate user transfers: %v", err)
+		return 0, fmt.Errorf("failed to annotate user transfers: %w", err)
+	}
+	modified, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to annotate user transfers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit user event: %w", err)
+	}
+
+	s.logger.Infof("Applied user event: UserID=%d, Event=%s, Transfers=%d",
+		event.UserID, event.Event, modified)
+
+	return modified, nil
+}
+
+// GetUserPreferences возвращает настройки доставки пользователя. Для пользователя
+// без событий возвращаются настройки по умолчанию: активен, доставка включена
+func (s *PostgresStorage) GetUserPreferences(ctx context.Context, userID int64) (*storages.UserPreferences, error) {
+	preferences := storages.UserPreferences{UserID: userID}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT status, delivery_enabled, reason, updated_at FROM user_preferences WHERE user_id = $1`,
+		userID,
+	).Scan(&preferences.Status, &preferences.DeliveryEnabled, &preferences.Reason, &preferences.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &storages.UserPreferences{
+			UserID:          userID,
+			Status:          storages.UserStatusActive,
+			DeliveryEnabled: true,
+		}, nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to get user preferences: %v", err)
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	return &preferences, nil
+}
+
+// annotateUserStatus помечает переводы пользователей с отключенной доставкой,
+// чтобы переводы, пришедшие после события кошелька, не отличались от сохраненных ранее
+func (s *PostgresStorage) annotateUserStatus(ctx context.Context, q queryer, transfers []storages.LargeTransfer) error {
+	userIDs := make([]int64, 0, len(transfers))
+	for _, t := range transfers {
+		userIDs = append(userIDs, t.UserID)
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT user_id, status FROM user_preferences WHERE user_id = ANY($1) AND NOT delivery_enabled`,
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query user preferences: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[int64]string)
+	for rows.Next() {
+		var userID int64
+		var status string
+		if err := rows.Scan(&userID, &status); err != nil {
+			return fmt.Errorf("failed to decode user preferences: %w", err)
+		}
+		statuses[userID] = status
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to decode user preferences: %w", err)
+	}
+
+	for i := range transfers {
+		transfers[i].UserStatus = statuses[transfers[i].UserID]
+	}
+	return nil
+}
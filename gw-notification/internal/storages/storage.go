@@ -1,6 +1,9 @@
 package storages
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Storage определяет интерфейс для работы с хранилищем данных
 type Storage interface {
@@ -22,6 +25,43 @@ type Storage interface {
 	// GetStatistics возвращает статистику обработки
 	GetStatistics(ctx context.Context) (*Statistics, error)
 
+	// GetSummary возвращает сводку по переводам начиная с указанного момента
+	GetSummary(ctx context.Context, since time.Time, topUsersLimit int) (*Summary, error)
+
+	// ApplyUserLifecycle обновляет настройки доставки пользователя по событию
+	// кошелька и помечает его сохраненные переводы. Устаревшие события
+	// пропускаются. Возвращает число помеченных переводов
+	ApplyUserLifecycle(ctx context.Context, event *UserLifecycleMessage) (int64, error)
+
+	// GetUserPreferences возвращает настройки доставки пользователя
+	GetUserPreferences(ctx context.Context, userID int64) (*UserPreferences, error)
+
+	// PurgeTransfers удаляет переводы, обработанные раньше before, пакетами
+	// по batchSize документов и возвращает число удаленных
+	PurgeTransfers(ctx context.Context, before time.Time, batchSize int) (int64, error)
+
+	// BuildReport агрегирует переводы с timestamp в [start, end) по дням,
+	// пользователям и валютам. topUsers - размер топа пользователей по сумме
+	BuildReport(ctx context.Context, period string, start, end time.Time, topUsers int) (*Report, error)
+
+	// SaveReport сохраняет отчет, если отчета с тем же ID еще нет.
+	// Возвращает true, если отчет сохранен этим вызовом
+	SaveReport(ctx context.Context, report *Report) (bool, error)
+
+	// GetReports возвращает отчеты, начиная с последнего периода
+	GetReports(ctx context.Context, filter ReportFilter) ([]Report, error)
+
+	// SaveFlag сохраняет отметку подозрительной активности, если отметки
+	// с тем же ID еще нет. Возвращает true, если отметка сохранена этим вызовом
+	SaveFlag(ctx context.Context, flag *SuspiciousFlag) (bool, error)
+
+	// GetFlags возвращает отметки подозрительной активности, начиная с последней
+	GetFlags(ctx context.Context, filter FlagFilter) ([]SuspiciousFlag, error)
+
+	// Health возвращает состояние подключения: задержку ping, пул соединений
+	// и время последней записи. Details заполняются и при ошибке
+	Health(ctx context.Context) (*HealthDetails, error)
+
 	// Health check
 	Ping(ctx context.Context) error
 	Close(ctx context.Context) error
@@ -2,21 +2,57 @@ package tests
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	_ "github.com/lib/pq"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gw-common/buildinfo"
+	"gw-common/env"
+	"gw-common/errcodes"
+	notificationlogger "gw-common/logger"
+	"gw-notification/internal/analytics"
+	"gw-notification/internal/api"
+	"gw-notification/internal/app"
+	"gw-notification/internal/backfill"
+	"gw-notification/internal/config"
+	"gw-notification/internal/kafka"
+	"gw-notification/internal/reports"
+	"gw-notification/internal/retention"
+	"gw-notification/internal/rules"
 	"gw-notification/internal/storages"
+	"gw-notification/internal/storages/mongodb"
+	"gw-notification/internal/storages/postgres"
+	pb "gw-notification/proto"
 )
 
 // MockStorage - мок для Storage
 type MockStorage struct {
-	transfers []storages.LargeTransfer
+	transfers   []storages.LargeTransfer
+	preferences map[int64]*storages.UserPreferences
+	reports     []storages.Report
+	flags       []storages.SuspiciousFlag
+	healthErr   error
 }
 
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
-		transfers: make([]storages.LargeTransfer, 0),
+		transfers:   make([]storages.LargeTransfer, 0),
+		preferences: make(map[int64]*storages.UserPreferences),
 	}
 }
 
@@ -26,10 +62,25 @@ func (m *MockStorage) SaveTransfer(ctx context.Context, transfer *storages.Large
 }
 
 func (m *MockStorage) SaveTransferBatch(ctx context.Context, transfers []storages.LargeTransfer) error {
-	m.transfers = append(m.transfers, transfers...)
+	// Как уникальный индекс event_id в MongoDB: дубликаты пропускаются
+	for _, t := range transfers {
+		if t.EventID != "" && m.hasEvent(t.EventID) {
+			continue
+		}
+		m.transfers = append(m.transfers, t)
+	}
 	return nil
 }
 
+func (m *MockStorage) hasEvent(eventID string) bool {
+	for _, t := range m.transfers {
+		if t.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MockStorage) GetTransfer(ctx context.Context, id string) (*storages.LargeTransfer, error) {
 	if len(m.transfers) > 0 {
 		return &m.transfers[0], nil
@@ -77,6 +128,136 @@ func (m *MockStorage) GetStatistics(ctx context.Context) (*storages.Statistics,
 	return stats, nil
 }
 
+func (m *MockStorage) GetSummary(ctx context.Context, since time.Time, topUsersLimit int) (*storages.Summary, error) {
+	return &storages.Summary{Since: since, Total: int64(len(m.transfers))}, nil
+}
+
+func (m *MockStorage) ApplyUserLifecycle(ctx context.Context, event *storages.UserLifecycleMessage) (int64, error) {
+	if p, ok := m.preferences[event.UserID]; ok && !p.UpdatedAt.Before(event.Timestamp) {
+		return 0, nil
+	}
+
+	status := map[string]string{
+		storages.UserEventFrozen:    storages.UserStatusFrozen,
+		storages.UserEventDeleted:   storages.UserStatusDeleted,
+		storages.UserEventActivated: storages.UserStatusActive,
+	}[event.Event]
+	m.preferences[event.UserID] = &storages.UserPreferences{
+		UserID:          event.UserID,
+		Status:          status,
+		DeliveryEnabled: status == storages.UserStatusActive,
+		Reason:          event.Reason,
+		UpdatedAt:       event.Timestamp,
+	}
+
+	var annotated int64
+	for i := range m.transfers {
+		if m.transfers[i].UserID == event.UserID {
+			m.transfers[i].UserStatus = status
+			if status == storages.UserStatusActive {
+				m.transfers[i].UserStatus = ""
+			}
+			annotated++
+		}
+	}
+	return annotated, nil
+}
+
+func (m *MockStorage) GetUserPreferences(ctx context.Context, userID int64) (*storages.UserPreferences, error) {
+	if p, ok := m.preferences[userID]; ok {
+		return p, nil
+	}
+	return &storages.UserPreferences{UserID: userID, Status: storages.UserStatusActive, DeliveryEnabled: true}, nil
+}
+
+func (m *MockStorage) PurgeTransfers(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	kept := m.transfers[:0]
+	for _, t := range m.transfers {
+		if !t.ProcessedAt.Before(before) {
+			kept = append(kept, t)
+		}
+	}
+	purged := int64(len(m.transfers) - len(kept))
+	m.transfers = kept
+	return purged, nil
+}
+
+func (m *MockStorage) BuildReport(ctx context.Context, period string, start, end time.Time, topUsers int) (*storages.Report, error) {
+	report := &storages.Report{Period: period, PeriodStart: start, PeriodEnd: end}
+	rows := make(map[storages.ReportRow]*storages.ReportRow)
+	users := make(map[int64]bool)
+	for _, t := range m.transfers {
+		if t.Timestamp.Before(start) || !t.Timestamp.Before(end) {
+			continue
+		}
+		report.Total++
+		users[t.UserID] = true
+
+		key := storages.ReportRow{Day: t.Timestamp.UTC().Format("2006-01-02"), UserID: t.UserID, Currency: t.FromCurrency}
+		row, ok := rows[key]
+		if !ok {
+			row = &key
+			rows[key] = row
+		}
+		row.Count++
+		row.TotalAmount += t.Amount
+	}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	report.Users = int64(len(users))
+	return report, nil
+}
+
+func (m *MockStorage) SaveReport(ctx context.Context, report *storages.Report) (bool, error) {
+	for _, r := range m.reports {
+		if r.ID == report.ID {
+			return false, nil
+		}
+	}
+	m.reports = append(m.reports, *report)
+	return true, nil
+}
+
+func (m *MockStorage) GetReports(ctx context.Context, filter storages.ReportFilter) ([]storages.Report, error) {
+	var result []storages.Report
+	for _, r := range m.reports {
+		if (filter.Period == "" || r.Period == filter.Period) && !r.PeriodStart.Before(filter.Since) {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockStorage) SaveFlag(ctx context.Context, flag *storages.SuspiciousFlag) (bool, error) {
+	for _, f := range m.flags {
+		if f.ID == flag.ID {
+			return false, nil
+		}
+	}
+	m.flags = append(m.flags, *flag)
+	return true, nil
+}
+
+func (m *MockStorage) GetFlags(ctx context.Context, filter storages.FlagFilter) ([]storages.SuspiciousFlag, error) {
+	var result []storages.SuspiciousFlag
+	for _, f := range m.flags {
+		if filter.UserID == 0 || f.UserID == filter.UserID {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockStorage) Health(ctx context.Context) (*storages.HealthDetails, error) {
+	details := &storages.HealthDetails{Status: storages.HealthStatusOK, CheckedAt: time.Now()}
+	if m.healthErr != nil {
+		details.Status = storages.HealthStatusUnavailable
+		details.Error = m.healthErr.Error()
+	}
+	return details, m.healthErr
+}
+
 func (m *MockStorage) Ping(ctx context.Context) error {
 	return nil
 }
@@ -232,6 +413,202 @@ func TestKafkaMessageParsing(t *testing.T) {
 	}
 }
 
+// TestConsumerDeduplicatesEventID проверяет, что повторная доставка события wallet
+// сохраняется один раз, а event_id берется из тела или заголовка сообщения
+func TestConsumerDeduplicatesEventID(t *testing.T) {
+	body := `{"user_id": 7, "type": "deposit", "from_currency": "USD", "to_currency": "USD",
+		"amount": 50000, "timestamp": "2024-02-02T15:04:05Z"%s}`
+	reader := &fakeReader{messages: []kafkago.Message{
+		{Topic: "large-transfers", Offset: 0, Value: []byte(fmt.Sprintf(body, `, "event_id": "wallet-tx-42"`))},
+		// Повторная доставка того же события с идентификатором только в заголовке
+		{Topic: "large-transfers", Offset: 1, Value: []byte(fmt.Sprintf(body, "")),
+			Headers: []kafkago.Header{{Key: kafka.EventIDHeader, Value: []byte("wallet-tx-42")}}},
+		{Topic: "large-transfers", Offset: 2, Value: []byte(fmt.Sprintf(body, `, "event_id": "wallet-tx-43"`))},
+	}}
+	storage := NewMockStorage()
+
+	consumer := kafka.NewConsumerWithReader(&kafka.Config{
+		BatchSize:     3,
+		Workers:       1,
+		FlushInterval: time.Hour,
+		RetryAttempts: 1,
+		RetryDelay:    time.Millisecond,
+	}, reader, storage, logrus.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.Commits()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consumer did not stop after context cancellation")
+	}
+
+	// Дубликат коммитится вместе с пакетом, но не сохраняется повторно
+	if commits := reader.Commits(); len(commits) != 1 || commits[0].Offset != 2 {
+		t.Fatalf("Expected commit at offset 2, got %+v", commits)
+	}
+	if len(storage.transfers) != 2 {
+		t.Fatalf("Expected 2 saved transfers, got %+v", storage.transfers)
+	}
+	if storage.transfers[0].EventID != "wallet-tx-42" || storage.transfers[1].EventID != "wallet-tx-43" {
+		t.Errorf("Unexpected saved event IDs: %q, %q", storage.transfers[0].EventID, storage.transfers[1].EventID)
+	}
+}
+
+func TestDecodeTransferMessage(t *testing.T) {
+	// Версия 1: без schema_version, идентификатор события в заголовке
+	v1, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value:   []byte(`{"user_id": 7, "type": "deposit", "from_currency": "USD", "to_currency": "USD", "amount": 50000, "timestamp": "2024-02-02T15:04:05Z"}`),
+		Headers: []kafkago.Header{{Key: kafka.EventIDHeader, Value: []byte("wallet-tx-1")}},
+	}, kafka.MessageFormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode v1 message: %v", err)
+	}
+	if v1.SchemaVersion != 1 || v1.EventID != "wallet-tx-1" || v1.Amount != 50000 {
+		t.Errorf("Unexpected v1 message: %+v", v1)
+	}
+
+	// Версия 2 с заголовками трассировки
+	v2, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value: []byte(`{"event_id": "wallet-tx-2", "user_id": 7, "type": "exchange", "from_currency": "USD", "to_currency": "EUR",
+			"amount": 50000, "timestamp": "2024-02-02T15:04:05Z", "message_id": "m-2", "schema_version": 2,
+			"converted_amount": 46000, "converted_currency": "EUR"}`),
+		Headers: []kafkago.Header{
+			{Key: kafka.SchemaVersionHeader, Value: []byte("2")},
+			{Key: kafka.TraceIDHeader, Value: []byte("trace-2")},
+			{Key: kafka.SourceHeader, Value: []byte("gw-currency-wallet")},
+		},
+	}, kafka.MessageFormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode v2 message: %v", err)
+	}
+	if v2.SchemaVersion != 2 || v2.MessageID != "m-2" || v2.ConvertedAmount != 46000 || v2.ConvertedCurrency != "EUR" ||
+		v2.TraceID != "trace-2" || v2.Source != "gw-currency-wallet" {
+		t.Errorf("Unexpected v2 message: %+v", v2)
+	}
+
+	transfer, err := v2.ToTransfer()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transfer.ConvertedAmount != 46000 || transfer.TraceID != "trace-2" {
+		t.Errorf("Expected v2 fields in transfer, got %+v", transfer)
+	}
+
+	// Более новая версия разбирается последним известным декодером, неизвестные поля игнорируются
+	v3, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value: []byte(`{"event_id": "wallet-tx-3", "user_id": 7, "type": "withdraw", "from_currency": "USD", "amount": 50000,
+			"timestamp": "2024-02-02T15:04:05Z", "schema_version": 3, "channel": "mobile"}`),
+	}, kafka.MessageFormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode v3 message: %v", err)
+	}
+	if v3.SchemaVersion != 3 || v3.EventID != "wallet-tx-3" {
+		t.Errorf("Unexpected v3 message: %+v", v3)
+	}
+
+	if _, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value:   []byte(`{}`),
+		Headers: []kafkago.Header{{Key: kafka.SchemaVersionHeader, Value: []byte("v2")}},
+	}, kafka.MessageFormatJSON); err == nil {
+		t.Error("Expected error for invalid schema version header")
+	}
+}
+
+func TestDecodeProtobufTransferMessage(t *testing.T) {
+	timestamp := time.Date(2024, 2, 2, 15, 4, 5, 0, time.UTC)
+	value, err := proto.Marshal(&pb.LargeTransfer{
+		EventId:           "wallet-tx-4",
+		UserId:            7,
+		Type:              storages.TransferTypeExchange,
+		FromCurrency:      "USD",
+		ToCurrency:        "EUR",
+		Amount:            50000,
+		Timestamp:         timestamppb.New(timestamp),
+		SchemaVersion:     2,
+		ConvertedAmount:   46000,
+		ConvertedCurrency: "EUR",
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal protobuf message: %v", err)
+	}
+
+	// Формат определяется заголовком независимо от формата по умолчанию
+	message, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value:   value,
+		Headers: []kafkago.Header{{Key: kafka.ContentTypeHeader, Value: []byte(kafka.ContentTypeProtobuf)}},
+	}, kafka.MessageFormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to decode protobuf message: %v", err)
+	}
+	if message.EventID != "wallet-tx-4" || message.Amount != 50000 || !message.Timestamp.Equal(timestamp) ||
+		message.ConvertedCurrency != "EUR" || message.SchemaVersion != 2 {
+		t.Errorf("Unexpected protobuf message: %+v", message)
+	}
+
+	// Без заголовка используется формат по умолчанию
+	if _, err := kafka.DecodeTransferMessage(kafkago.Message{Value: value}, kafka.MessageFormatProtobuf); err != nil {
+		t.Errorf("Failed to decode protobuf message without content type: %v", err)
+	}
+	if _, err := kafka.DecodeTransferMessage(kafkago.Message{Value: value}, kafka.MessageFormatJSON); err == nil {
+		t.Error("Expected error for protobuf message decoded as JSON")
+	}
+	if _, err := kafka.DecodeTransferMessage(kafkago.Message{
+		Value:   value,
+		Headers: []kafkago.Header{{Key: kafka.ContentTypeHeader, Value: []byte("avro/binary")}},
+	}, kafka.MessageFormatJSON); err == nil {
+		t.Error("Expected error for unsupported content type")
+	}
+}
+
+func TestContentEventID(t *testing.T) {
+	transfer := storages.LargeTransfer{
+		UserID:       7,
+		Type:         storages.TransferTypeExchange,
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Amount:       50000,
+		Timestamp:    time.Date(2024, 2, 2, 15, 4, 5, 0, time.UTC),
+	}
+
+	first := transfer
+	first.EventID = transfer.ContentEventID()
+	if !strings.HasPrefix(first.EventID, "content-") {
+		t.Fatalf("Expected content-based event_id, got %q", first.EventID)
+	}
+
+	// Повторная доставка дает тот же идентификатор, в том числе в другой зоне
+	second := transfer
+	second.Timestamp = transfer.Timestamp.In(time.FixedZone("MSK", 3*60*60))
+	second.EventID = second.ContentEventID()
+	if second.EventID != first.EventID {
+		t.Errorf("Expected stable event_id %q, got %q", first.EventID, second.EventID)
+	}
+
+	third := transfer
+	third.Amount = 50001
+	third.EventID = third.ContentEventID()
+	if third.EventID == first.EventID {
+		t.Error("Expected different event_id for different amount")
+	}
+
+	// Дубликаты пакета отбрасываются хранилищем
+	storage := NewMockStorage()
+	if err := storage.SaveTransferBatch(context.Background(), []storages.LargeTransfer{first, second, third}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(storage.transfers) != 2 {
+		t.Errorf("Expected 2 stored transfers, got %d", len(storage.transfers))
+	}
+}
+
 func TestTransferValidation(t *testing.T) {
 	transfer := &storages.LargeTransfer{
 		UserID: 1,
@@ -255,3 +632,1512 @@ func TestTransferValidation(t *testing.T) {
 		t.Fatal("Transfer amount must be positive")
 	}
 }
+
+func TestBackfillImport(t *testing.T) {
+	storage := NewMockStorage()
+	importer := backfill.NewImporter(storage, 2, 10000, logrus.New())
+	ctx := context.Background()
+
+	// Выгрузка таблицы transactions кошелька: незавершенные транзакции и мелкие
+	// суммы пропускаются, некорректные строки не прерывают импорт
+	csvData := `id,user_id,type,from_currency,to_currency,from_amount,status,created_at,completed_at
+1,10,deposit,USD,USD,50000,completed,2024-01-10 09:00:00+00,2024-01-10 09:00:01+00
+2,10,withdraw,USD,,20000,failed,2024-01-11 09:00:00+00,
+3,11,exchange,usd,eur,15000.5,completed,2024-01-12 09:00:00+00,
+4,12,deposit,EUR,EUR,500,completed,2024-01-13 09:00:00+00,
+5,13,refund,EUR,EUR,90000,completed,2024-01-14 09:00:00+00,
+6,abc,deposit,EUR,EUR,90000,completed,2024-01-14 09:00:00+00,
+`
+	result, err := importer.Import(ctx, strings.NewReader(csvData), backfill.FormatCSV)
+	if err != nil {
+		t.Fatalf("Failed to import CSV: %v", err)
+	}
+	if result.Read != 6 || result.Saved != 2 || result.Skipped != 2 || result.Invalid != 2 {
+		t.Fatalf("Unexpected CSV result: %+v", result)
+	}
+	if len(storage.transfers) != 2 {
+		t.Fatalf("Expected 2 transfers, got %d", len(storage.transfers))
+	}
+
+	first := storage.transfers[0]
+	if first.EventID != "wallet-tx-1" || first.Amount != 50000 || !first.Timestamp.Equal(time.Date(2024, 1, 10, 9, 0, 1, 0, time.UTC)) {
+		t.Errorf("Unexpected first transfer: %+v", first)
+	}
+	if second := storage.transfers[1]; second.FromCurrency != "USD" || second.ToCurrency != "EUR" {
+		t.Errorf("Expected normalized currencies, got %s -> %s", second.FromCurrency, second.ToCurrency)
+	}
+
+	// JSON в формате сообщений Kafka: перевод, уже полученный через Kafka
+	// или предыдущий импорт, не дублируется
+	jsonData := `[
+		{"event_id": "wallet-tx-1", "user_id": 10, "type": "deposit", "from_currency": "USD", "amount": 50000, "timestamp": "2024-01-10T09:00:01Z"},
+		{"transaction_id": 7, "user_id": 14, "type": "withdraw", "from_currency": "RUB", "amount": 3000000, "timestamp": "2024-01-15T09:00:00Z"},
+		{"user_id": 15, "type": "deposit", "from_currency": "USD", "amount": 70000, "timestamp": "2024-01-16T09:00:00Z"}
+	]`
+	result, err = importer.Import(ctx, strings.NewReader(jsonData), backfill.FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to import JSON: %v", err)
+	}
+	if result.Read != 3 || result.Invalid != 1 {
+		t.Fatalf("Unexpected JSON result: %+v", result)
+	}
+	if len(storage.transfers) != 3 || storage.transfers[2].EventID != "wallet-tx-7" {
+		t.Fatalf("Expected duplicate to be skipped, got %+v", storage.transfers)
+	}
+
+	// JSON Lines
+	jsonLines := `{"event_id": "wallet-tx-8", "user_id": 16, "type": "deposit", "from_currency": "USD", "amount": 20000, "timestamp": "2024-01-17T09:00:00Z"}
+{"event_id": "wallet-tx-9", "user_id": 16, "type": "deposit", "from_currency": "USD", "amount": 25000, "timestamp": "2024-01-18T09:00:00Z"}
+`
+	result, err = importer.Import(ctx, strings.NewReader(jsonLines), backfill.FormatJSON)
+	if err != nil {
+		t.Fatalf("Failed to import JSON lines: %v", err)
+	}
+	if result.Saved != 2 || result.Batches != 1 {
+		t.Fatalf("Unexpected JSON lines result: %+v", result)
+	}
+
+	// Поврежденный JSON прерывает импорт
+	if _, err := importer.Import(ctx, strings.NewReader(`[{"user_id": 1,`), backfill.FormatJSON); err == nil {
+		t.Error("Expected error for truncated JSON")
+	}
+	if _, err := importer.Import(ctx, strings.NewReader(""), "xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestSummaryQueryLimits(t *testing.T) {
+	limits := api.QueryLimits{MaxLimit: 20, MaxWindow: 24 * time.Hour}
+	server := api.NewServer("0", "", limits, time.Minute, nil, NewMockStorage(), logrus.New())
+
+	for _, query := range []string{"window=48h", "top=21", "window=-1h", "top=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/summary?"+query, nil)
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d", http.StatusBadRequest, query, w.Code)
+		}
+
+		var body api.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to parse error response: %v", err)
+		}
+		if body.Error.Code != string(errcodes.InvalidRequest) || body.Error.Number != errcodes.InvalidRequest.Number() {
+			t.Errorf("Expected invalid_request error for %q, got %+v", query, body.Error)
+		}
+	}
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	storage := NewMockStorage()
+	server := api.NewServer("0", "", api.QueryLimits{}, time.Minute, nil, storage, logrus.New())
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Ready   bool                    `json:"ready"`
+		Storage *storages.HealthDetails `json:"storage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !response.Ready || response.Storage == nil || response.Storage.Status != storages.HealthStatusOK {
+		t.Errorf("Expected ready storage, got %+v", response)
+	}
+
+	// Недоступное хранилище переводит сервис в состояние not ready
+	storage.healthErr = errors.New("connection refused")
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Ready || response.Storage.Status != storages.HealthStatusUnavailable {
+		t.Errorf("Expected unavailable storage, got %+v", response)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	storage := NewMockStorage()
+	server := api.NewServer("0", "", api.QueryLimits{}, time.Minute, nil, storage, logrus.New())
+
+	// Без consumer liveness зависит только от процесса, readiness - от хранилища
+	for _, path := range []string{"/health/live", "/health/ready"} {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+
+	// Недоступность хранилища не влияет на liveness: перезапуск ее не исправит
+	storage.healthErr = errors.New("connection refused")
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected ready status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected live status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/health/live", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	server := api.NewServer("0", "admin-token", api.QueryLimits{}, time.Minute, nil, NewMockStorage(), logrus.New())
+
+	// Сведения о сборке доступны без токена администратора
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info buildinfo.Info
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &info) != nil {
+		t.Fatalf("Expected build info, got %d %s", w.Code, w.Body.String())
+	}
+	if info != buildinfo.Get() || info.Version != buildinfo.DefaultVersion {
+		t.Errorf("Expected %+v, got %+v", buildinfo.Get(), info)
+	}
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	consumer := kafka.NewConsumer(&kafka.Config{
+		Brokers:       []string{"localhost:9092"},
+		Topic:         "large-transfers",
+		GroupID:       "test",
+		BatchSize:     10,
+		Workers:       2,
+		FlushInterval: time.Second,
+		RetryAttempts: 1,
+		RetryDelay:    10 * time.Millisecond,
+	}, NewMockStorage(), logrus.New())
+	defer consumer.Close()
+
+	server := api.NewServer("0", "secret", api.QueryLimits{}, time.Minute, consumer, NewMockStorage(), logrus.New())
+
+	call := func(method, path string) (int, api.ConsumerStateResponse) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Admin-Token", "secret")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		var response api.ConsumerStateResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+		}
+		return w.Code, response
+	}
+
+	code, response := call(http.MethodPost, "/admin/consumer/pause")
+	if code != http.StatusOK || !response.Changed || !response.Consumer.Paused || response.Consumer.PausedAt.IsZero() {
+		t.Fatalf("Expected paused consumer, got %d %+v", code, response)
+	}
+	// Повторная пауза ничего не меняет
+	if _, response = call(http.MethodPost, "/admin/consumer/pause"); response.Changed || !response.Consumer.Paused {
+		t.Errorf("Expected unchanged paused consumer, got %+v", response)
+	}
+	if code, _ = call(http.MethodGet, "/admin/consumer/pause"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+
+	// На паузе FetchMessage не вызывается, и consumer останавливается сразу после отмены
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	if !consumer.Health().Running || consumer.Stalled(time.Nanosecond) != nil {
+		t.Errorf("Expected running paused consumer not to be stalled, got %+v", consumer.Health())
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consumer did not stop after context cancellation")
+	}
+
+	code, response = call(http.MethodPost, "/admin/consumer/resume")
+	if code != http.StatusOK || !response.Changed || response.Consumer.Paused {
+		t.Errorf("Expected resumed consumer, got %d %+v", code, response)
+	}
+	if _, response = call(http.MethodPost, "/admin/consumer/resume"); response.Changed {
+		t.Errorf("Expected unchanged resumed consumer, got %+v", response)
+	}
+
+	// Без токена администратора состояние не меняется
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/consumer/pause", nil))
+	if w.Code != http.StatusUnauthorized || consumer.Health().Paused {
+		t.Errorf("Expected unauthorized request to be rejected, got %d", w.Code)
+	}
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := kafka.NewOffsetTracker()
+	msg := func(partition int, offset int64) kafkago.Message {
+		return kafkago.Message{Topic: "large-transfers", Partition: partition, Offset: offset}
+	}
+	positions := func(commits []kafkago.Message) map[int]int64 {
+		result := make(map[int]int64)
+		for _, m := range commits {
+			result[m.Partition] = m.Offset
+		}
+		return result
+	}
+
+	first := make(map[int64]kafka.Delivery)
+	for offset := int64(10); offset < 15; offset++ {
+		first[offset] = tracker.Track(msg(0, offset))
+	}
+	p1a := tracker.Track(msg(1, 3))
+	p1b := tracker.Track(msg(1, 4))
+
+	// Воркер сохранил более поздние сообщения раньше: коммитить нечего
+	if commits := tracker.Complete(first[12], first[13]); len(commits) != 0 {
+		t.Fatalf("Expected no commits before lower offsets are done, got %+v", commits)
+	}
+
+	got := positions(tracker.Complete(first[10], p1b))
+	if len(got) != 1 || got[0] != 10 {
+		t.Errorf("Expected commit of partition 0 at offset 10 only, got %v", got)
+	}
+
+	got = positions(tracker.Complete(first[11], p1a))
+	if got[0] != 13 || got[1] != 4 {
+		t.Errorf("Expected commits at 13 and 4, got %v", got)
+	}
+	if pending := tracker.Pending(); pending != 1 {
+		t.Errorf("Expected 1 pending message, got %d", pending)
+	}
+
+	// После перебалансировки партиция читается заново с закоммиченной позиции
+	reread14 := tracker.Track(msg(0, 14))
+	reread15 := tracker.Track(msg(0, 15))
+	// Воркер завершил сообщение 14, полученное до перебалансировки: перечитанное
+	// сообщение с тем же смещением еще не сохранено, позиция не продвигается
+	if commits := tracker.Complete(first[14]); len(commits) != 0 {
+		t.Errorf("Expected completion from previous generation to be ignored, got %+v", commits)
+	}
+	if commits := tracker.Complete(reread15); len(commits) != 0 {
+		t.Errorf("Expected no commits after rewind, got %+v", commits)
+	}
+	got = positions(tracker.Complete(reread14))
+	if got[0] != 15 {
+		t.Errorf("Expected commit at offset 15, got %v", got)
+	}
+	if commits := tracker.Complete(first[12]); len(commits) != 0 || tracker.Pending() != 0 {
+		t.Errorf("Expected stale completion to be ignored, got %+v", commits)
+	}
+}
+
+// fakeReader отдает заданные сообщения и запоминает коммиты
+type fakeReader struct {
+	mu       sync.Mutex
+	messages []kafkago.Message
+	commits  []kafkago.Message
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafkago.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits = append(r.commits, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Commits() []kafkago.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]kafkago.Message(nil), r.commits...)
+}
+
+func (r *fakeReader) Stats() kafkago.ReaderStats { return kafkago.ReaderStats{} }
+
+func (r *fakeReader) Close() error { return nil }
+
+// failingStorage не сохраняет пакеты, пока не вызван recover
+type failingStorage struct {
+	*MockStorage
+	mu       sync.Mutex
+	failing  bool
+	attempts int
+}
+
+func (s *failingStorage) SaveTransferBatch(ctx context.Context, transfers []storages.LargeTransfer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failing {
+		return errors.New("storage unavailable")
+	}
+	return s.MockStorage.SaveTransferBatch(ctx, transfers)
+}
+
+func (s *failingStorage) state() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, len(s.transfers)
+}
+
+func (s *failingStorage) recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = false
+}
+
+func TestConsumerDoesNotCommitFailedBatch(t *testing.T) {
+	reader := &fakeReader{}
+	for offset := int64(0); offset < 3; offset++ {
+		reader.messages = append(reader.messages, kafkago.Message{
+			Topic:     "large-transfers",
+			Partition: 0,
+			Offset:    offset,
+			Value: []byte(fmt.Sprintf(`{"event_id": "wallet-tx-%d", "user_id": 7, "type": "deposit",
+				"from_currency": "USD", "to_currency": "USD", "amount": 50000, "timestamp": "2024-02-02T15:04:05Z"}`, offset)),
+		})
+	}
+	storage := &failingStorage{MockStorage: NewMockStorage(), failing: true}
+
+	consumer := kafka.NewConsumerWithReader(&kafka.Config{
+		BatchSize:     3,
+		Workers:       1,
+		FlushInterval: time.Hour,
+		RetryAttempts: 2,
+		RetryDelay:    time.Millisecond,
+	}, reader, storage, logrus.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+
+	// Хранилище недоступно: пакет повторяется дольше RetryAttempts, смещения не коммитятся
+	deadline := time.Now().Add(5 * time.Second)
+	for attempts, _ := storage.state(); attempts < 4 && time.Now().Before(deadline); attempts, _ = storage.state() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if attempts, _ := storage.state(); attempts < 4 {
+		t.Fatalf("Expected batch to be retried past RetryAttempts, got %d attempts", attempts)
+	}
+	if commits := reader.Commits(); len(commits) != 0 {
+		t.Fatalf("Expected no commits while storage fails, got %+v", commits)
+	}
+	stats := consumer.GetStatistics()
+	if stats.Uncommitted != 3 || stats.MessagesFailed != 0 {
+		t.Errorf("Expected 3 uncommitted and no skipped messages, got %v", stats)
+	}
+	if health := consumer.Health(); health.LastError != "storage unavailable" {
+		t.Errorf("Expected storage error in health, got %+v", health)
+	}
+
+	// После восстановления хранилища пакет сохраняется и коммитится
+	storage.recover()
+	for len(reader.Commits()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	commits := reader.Commits()
+	if len(commits) != 1 || commits[0].Offset != 2 {
+		t.Fatalf("Expected commit at offset 2 after recovery, got %+v", commits)
+	}
+	if _, saved := storage.state(); saved != 3 {
+		t.Errorf("Expected 3 saved transfers, got %d", saved)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consumer did not stop after context cancellation")
+	}
+}
+
+func TestSummaryResponse(t *testing.T) {
+	reader := &fakeReader{messages: []kafkago.Message{
+		{Topic: "large-transfers", Partition: 0, Offset: 0, Value: []byte(`{"event_id": "wallet-tx-1", "user_id": 7, "type": "deposit",
+			"from_currency": "USD", "to_currency": "USD", "amount": 50000, "timestamp": "2024-02-02T15:04:05Z"}`)},
+		{Topic: "large-transfers", Partition: 0, Offset: 1, Value: []byte(`not json`)},
+		{Topic: "large-transfers", Partition: 1, Offset: 0, Value: []byte(`{"event_id": "wallet-tx-2", "user_id": 8, "type": "withdraw",
+			"from_currency": "EUR", "to_currency": "EUR", "amount": 30000, "timestamp": "2024-02-02T15:05:05Z"}`)},
+		{Topic: "large-transfers", Partition: 1, Offset: 1, Value: []byte(`{"event_id": "wallet-tx-3", "user_id": 8, "type": "withdraw",
+			"from_currency": "EUR", "to_currency": "EUR", "amount": 40000, "timestamp": "2024-02-02T15:06:05Z"}`)},
+	}}
+	storage := NewMockStorage()
+	consumer := kafka.NewConsumerWithReader(&kafka.Config{
+		BatchSize:     3,
+		Workers:       1,
+		FlushInterval: 10 * time.Millisecond,
+		RetryAttempts: 1,
+		RetryDelay:    time.Millisecond,
+	}, reader, storage, logrus.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for stats := consumer.GetStatistics(); stats.MessagesProcessed+stats.MessagesFailed < 4 && time.Now().Before(deadline); stats = consumer.GetStatistics() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	server := api.NewServer("0", "", api.QueryLimits{}, time.Minute, consumer, storage, logrus.New())
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/summary?window=2h&top=5", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	for _, field := range []string{"generated_at", "window", "consumer", "transfers", "storage", "failure_rates"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("Expected %s in summary, got %s", field, w.Body.String())
+		}
+	}
+	if _, ok := raw["errors"]; ok {
+		t.Errorf("Expected no errors in summary, got %s", raw["errors"])
+	}
+
+	var summary api.SummaryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	if summary.Window != "2h0m0s" || summary.Transfers == nil || summary.Transfers.Total != 3 {
+		t.Errorf("Unexpected window or transfers: %s", w.Body.String())
+	}
+	stats := summary.Consumer.Statistics
+	if stats.MessagesProcessed != 3 || stats.MessagesFailed != 1 || stats.Uncommitted != 0 {
+		t.Errorf("Unexpected consumer statistics: %+v", stats)
+	}
+	if len(stats.Partitions) != 2 || stats.Partitions[0].Processed != 1 || stats.Partitions[0].Failed != 1 || stats.Partitions[1].Processed != 2 {
+		t.Errorf("Unexpected partition statistics: %+v", stats.Partitions)
+	}
+	// Доля считается только по сообщениям consumer: 1 пропущенное из 4
+	if len(summary.FailureRates) != 1 || summary.FailureRates["consumer"] != 0.25 {
+		t.Errorf("Expected only consumer failure rate 0.25, got %v", summary.FailureRates)
+	}
+}
+
+func TestProcessingStats(t *testing.T) {
+	transfer := func(partition int, offset int64) kafkago.Message {
+		return kafkago.Message{Topic: "large-transfers", Partition: partition, Offset: offset, Value: []byte(fmt.Sprintf(
+			`{"event_id": "wallet-tx-%d-%d", "user_id": 7, "type": "deposit", "from_currency": "USD", "to_currency": "USD",
+			"amount": 50000, "timestamp": "2024-02-02T15:04:05Z"}`, partition, offset))}
+	}
+	invalid := func(partition int, offset int64) kafkago.Message {
+		return kafkago.Message{Topic: "large-transfers", Partition: partition, Offset: offset, Value: []byte(`{"user_id": `)}
+	}
+	// Партиция 0: 3 перевода и 2 нераспознанных сообщения, партиция 2: 2 перевода
+	reader := &fakeReader{messages: []kafkago.Message{
+		transfer(0, 0), invalid(0, 1), transfer(0, 2), transfer(2, 0), invalid(0, 3), transfer(2, 1), transfer(0, 4),
+	}}
+	consumer := kafka.NewConsumerWithReader(&kafka.Config{
+		BatchSize:     2,
+		Workers:       2,
+		FlushInterval: 10 * time.Millisecond,
+		RetryAttempts: 1,
+		RetryDelay:    time.Millisecond,
+	}, reader, NewMockStorage(), logrus.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for stats := consumer.GetStatistics(); stats.MessagesProcessed+stats.MessagesFailed < 7 && time.Now().Before(deadline); stats = consumer.GetStatistics() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	stats := consumer.GetStatistics()
+	// Failed считает сообщения, а не пакеты
+	if stats.MessagesProcessed != 5 || stats.MessagesFailed != 2 {
+		t.Fatalf("Expected 5 processed and 2 failed messages, got %+v", stats)
+	}
+
+	// Сообщения распределяются между воркерами из общей очереди, поэтому
+	// проверяются суммы и согласованность статистики каждого воркера
+	var processed, failed int64
+	for i, w := range stats.Workers {
+		if w.ID < 0 || w.ID > 1 || (i > 0 && stats.Workers[i-1].ID >= w.ID) {
+			t.Errorf("Expected workers 0 and 1 sorted by ID, got %+v", stats.Workers)
+		}
+		if w.Processed > 0 && (w.Flushes == 0 || w.LastFlushAt.IsZero() || w.AvgFlushLatencyMs > w.MaxFlushLatencyMs) {
+			t.Errorf("Inconsistent flush statistics of worker %d: %+v", w.ID, w)
+		}
+		processed += w.Processed
+		failed += w.Failed
+	}
+	if processed != 5 || failed != 2 {
+		t.Errorf("Expected workers to account for 5 processed and 2 failed messages, got %d and %d", processed, failed)
+	}
+
+	if len(stats.Partitions) != 2 || stats.Partitions[0].ID != 0 || stats.Partitions[1].ID != 2 {
+		t.Fatalf("Expected partitions 0 and 2, got %+v", stats.Partitions)
+	}
+	if p := stats.Partitions[0]; p.Processed != 3 || p.Failed != 2 || p.Flushes == 0 {
+		t.Errorf("Expected 3 processed and 2 failed messages in partition 0, got %+v", p)
+	}
+	if p := stats.Partitions[1]; p.Processed != 2 || p.Failed != 0 || p.Flushes == 0 {
+		t.Errorf("Expected 2 processed messages in partition 2, got %+v", p)
+	}
+}
+
+func TestUserLifecycleEvents(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+	_ = storage.SaveTransfer(ctx, &storages.LargeTransfer{UserID: 7, Type: storages.TransferTypeDeposit, Amount: 50000})
+
+	consumer := kafka.NewUserEventsConsumer(&kafka.Config{Brokers: []string{"localhost:9092"}, GroupID: "test"}, "user-lifecycle", storage, logrus.New())
+	defer consumer.Close()
+
+	frozenAt := time.Now()
+	send := func(event storages.UserLifecycleMessage) {
+		value, _ := json.Marshal(event)
+		if err := consumer.HandleMessage(ctx, kafkago.Message{Value: value}); err != nil {
+			t.Fatalf("Failed to handle user event: %v", err)
+		}
+	}
+
+	send(storages.UserLifecycleMessage{EventID: "e1", UserID: 7, Event: storages.UserEventFrozen, Reason: "fraud check", Timestamp: frozenAt})
+
+	prefs, _ := storage.GetUserPreferences(ctx, 7)
+	if prefs.DeliveryEnabled || prefs.Status != storages.UserStatusFrozen {
+		t.Fatalf("Expected delivery disabled for frozen user, got %+v", prefs)
+	}
+	if storage.transfers[0].UserStatus != storages.UserStatusFrozen {
+		t.Errorf("Expected stored transfer annotated as frozen, got %q", storage.transfers[0].UserStatus)
+	}
+
+	// Запоздавшее событие активации не откатывает более новую заморозку
+	send(storages.UserLifecycleMessage{EventID: "e0", UserID: 7, Event: storages.UserEventActivated, Timestamp: frozenAt.Add(-time.Minute)})
+	if prefs, _ := storage.GetUserPreferences(ctx, 7); prefs.DeliveryEnabled {
+		t.Errorf("Expected outdated activation to be skipped, got %+v", prefs)
+	}
+
+	// Некорректное событие пропускается без ошибки, чтобы не блокировать топик
+	if err := consumer.HandleMessage(ctx, kafkago.Message{Value: []byte(`{"user_id":7,"event":"unknown"}`)}); err != nil {
+		t.Errorf("Expected invalid event to be skipped, got %v", err)
+	}
+}
+
+func TestMongoMigrationsPlan(t *testing.T) {
+	latest := mongodb.LatestMigrationVersion()
+	if latest < 2 {
+		t.Fatalf("Expected at least 2 migrations, got %d", latest)
+	}
+
+	// Пустая база: все миграции по порядку, версии идут подряд с 1
+	status := mongodb.PlanMigrations(nil)
+	if status.Version != 0 || status.Latest != latest || len(status.Pending) != latest {
+		t.Fatalf("Unexpected status for empty database: %+v", status)
+	}
+	for i, v := range status.Pending {
+		if v != i+1 {
+			t.Fatalf("Expected sequential migration versions, got %v", status.Pending)
+		}
+	}
+
+	// Частично примененная схема
+	status = mongodb.PlanMigrations([]int{1})
+	if status.Version != 1 || len(status.Pending) != latest-1 || status.Pending[0] != 2 {
+		t.Errorf("Unexpected status after migration 1: %+v", status)
+	}
+
+	// Схема новее сборки: неизвестные версии не мешают, ожидающих нет
+	applied := []int{latest + 1}
+	for v := latest; v >= 1; v-- {
+		applied = append(applied, v)
+	}
+	status = mongodb.PlanMigrations(applied)
+	if len(status.Pending) != 0 || status.Version != latest+1 {
+		t.Errorf("Unexpected status for newer schema: %+v", status)
+	}
+	if len(status.Unknown) != 1 || status.Unknown[0] != latest+1 {
+		t.Errorf("Expected unknown version %d, got %v", latest+1, status.Unknown)
+	}
+}
+
+func TestRetentionPurge(t *testing.T) {
+	storage := NewMockStorage()
+	now := time.Now()
+	for i, age := range []time.Duration{time.Hour, 10 * 24 * time.Hour, 31 * 24 * time.Hour, 90 * 24 * time.Hour} {
+		storage.SaveTransfer(context.Background(), &storages.LargeTransfer{
+			UserID:      int64(i + 1),
+			Amount:      50000,
+			ProcessedAt: now.Add(-age),
+		})
+	}
+
+	purger := retention.NewPurger(storage, 30*24*time.Hour, time.Hour, 100, logrus.New())
+
+	purged, err := purger.PurgeOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Expected 2 purged transfers, got %d", purged)
+	}
+	if len(storage.transfers) != 2 {
+		t.Fatalf("Expected 2 transfers left, got %d", len(storage.transfers))
+	}
+	for _, tr := range storage.transfers {
+		if now.Sub(tr.ProcessedAt) > 30*24*time.Hour {
+			t.Errorf("Transfer of user %d should have been purged", tr.UserID)
+		}
+	}
+
+	// Повторная очистка ничего не удаляет
+	purged, err = purger.PurgeOnce(context.Background())
+	if err != nil || purged != 0 {
+		t.Errorf("Expected nothing to purge, got %d (%v)", purged, err)
+	}
+}
+
+// recordingPublisher запоминает отправленные сводки отчетов
+type recordingPublisher struct {
+	published []string
+}
+
+func (p *recordingPublisher) Name() string {
+	return "recording"
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, report *storages.Report) error {
+	p.published = append(p.published, report.ID)
+	return nil
+}
+
+func TestReportGenerator(t *testing.T) {
+	// Границы периодов в UTC, неделя начинается в понедельник
+	at := time.Date(2024, 2, 1, 15, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)) // четверг
+	start, end := reports.PeriodBounds(storages.ReportPeriodDaily, at)
+	if !start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("Unexpected daily bounds: %v - %v", start, end)
+	}
+	start, end = reports.PeriodBounds(storages.ReportPeriodWeekly, at)
+	if !start.Equal(time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("Unexpected weekly bounds: %v - %v", start, end)
+	}
+
+	storage := NewMockStorage()
+	for _, tr := range []storages.LargeTransfer{
+		{UserID: 1, FromCurrency: "USD", Amount: 40000, Timestamp: time.Date(2024, 2, 4, 10, 0, 0, 0, time.UTC)},
+		{UserID: 1, FromCurrency: "USD", Amount: 60000, Timestamp: time.Date(2024, 2, 4, 12, 0, 0, 0, time.UTC)},
+		{UserID: 2, FromCurrency: "EUR", Amount: 35000, Timestamp: time.Date(2024, 2, 4, 23, 0, 0, 0, time.UTC)},
+		{UserID: 3, FromCurrency: "USD", Amount: 90000, Timestamp: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)},
+		// Следующий день в отчеты не попадает
+		{UserID: 1, FromCurrency: "USD", Amount: 50000, Timestamp: time.Date(2024, 2, 5, 0, 30, 0, 0, time.UTC)},
+	} {
+		storage.SaveTransfer(context.Background(), &tr)
+	}
+
+	publisher := &recordingPublisher{}
+	generator := reports.NewGenerator(storage, reports.Config{
+		Periods:  []string{storages.ReportPeriodDaily, storages.ReportPeriodWeekly},
+		Delay:    time.Hour,
+		Interval: time.Minute,
+		TopUsers: 10,
+	}, []reports.Publisher{publisher}, logrus.New())
+
+	// После задержки в понедельник строятся отчеты за воскресенье и прошлую неделю
+	now := time.Date(2024, 2, 5, 2, 0, 0, 0, time.UTC)
+	created, err := generator.GenerateDue(context.Background(), now)
+	if err != nil || created != 2 {
+		t.Fatalf("Expected 2 reports, got %d (%v)", created, err)
+	}
+	if len(publisher.published) != 2 || publisher.published[0] != "daily-2024-02-04" || publisher.published[1] != "weekly-2024-01-29" {
+		t.Fatalf("Unexpected published reports: %v", publisher.published)
+	}
+
+	daily := storage.reports[0]
+	if daily.Total != 3 || daily.Users != 2 || len(daily.Rows) != 2 {
+		t.Errorf("Unexpected daily report: %+v", daily)
+	}
+	for _, row := range daily.Rows {
+		if row.UserID == 1 && (row.Count != 2 || row.TotalAmount != 100000 || row.Day != "2024-02-04") {
+			t.Errorf("Unexpected row for user 1: %+v", row)
+		}
+	}
+	if weekly := storage.reports[1]; weekly.Total != 4 || weekly.Users != 3 {
+		t.Errorf("Unexpected weekly report: %+v", weekly)
+	}
+
+	// Повторная проверка не создает и не публикует отчеты повторно
+	created, err = generator.GenerateDue(context.Background(), now.Add(10*time.Minute))
+	if err != nil || created != 0 || len(publisher.published) != 2 {
+		t.Errorf("Expected no new reports, got %d (%v), published %v", created, err, publisher.published)
+	}
+
+	summary := reports.FormatSummary(&daily)
+	if !strings.Contains(summary, "Transfers: 3") || !strings.Contains(summary, "daily report for 2024-02-04 - 2024-02-04") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+
+	// GET /admin/reports
+	server := api.NewServer("0", "", api.QueryLimits{MaxLimit: 100, MaxWindow: time.Hour}, time.Minute, nil, storage, logrus.New())
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reports?period=weekly", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response api.ReportsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Reports) != 1 || response.Reports[0].ID != "weekly-2024-01-29" {
+		t.Errorf("Unexpected reports: %+v", response.Reports)
+	}
+
+	for _, query := range []string{"period=monthly", "since=yesterday", "limit=1000"} {
+		rec = httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reports?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
+// recordingAlerter запоминает оповещения о подозрительной активности
+type recordingAlerter struct {
+	alerts []string
+}
+
+func (a *recordingAlerter) Name() string {
+	return "recording"
+}
+
+func (a *recordingAlerter) Alert(ctx context.Context, flag *storages.SuspiciousFlag) error {
+	a.alerts = append(a.alerts, flag.ID)
+	return nil
+}
+
+func TestAnomalyRules(t *testing.T) {
+	storage := NewMockStorage()
+	base := time.Date(2024, 2, 2, 12, 0, 0, 0, time.UTC)
+
+	// Пользователь 1: три перевода за 5 минут. Пользователь 2: обычные суммы
+	// раз в день, затем перевод в 12 раз больше средней
+	history := []storages.LargeTransfer{
+		{EventID: "u1-1", UserID: 1, FromCurrency: "USD", Amount: 40000, Timestamp: base},
+		{EventID: "u1-2", UserID: 1, FromCurrency: "USD", Amount: 45000, Timestamp: base.Add(2 * time.Minute)},
+		{EventID: "u2-1", UserID: 2, FromCurrency: "EUR", Amount: 30000, Timestamp: base.Add(-72 * time.Hour)},
+		{EventID: "u2-2", UserID: 2, FromCurrency: "EUR", Amount: 32000, Timestamp: base.Add(-48 * time.Hour)},
+		{EventID: "u2-3", UserID: 2, FromCurrency: "EUR", Amount: 28000, Timestamp: base.Add(-24 * time.Hour)},
+		// Сумма в другой валюте со средней не сравнивается
+		{EventID: "u2-4", UserID: 2, FromCurrency: "USD", Amount: 900000, Timestamp: base.Add(-time.Hour)},
+	}
+	storage.SaveTransferBatch(context.Background(), history)
+
+	batch := []storages.LargeTransfer{
+		{EventID: "u1-3", UserID: 1, FromCurrency: "USD", Amount: 42000, Timestamp: base.Add(5 * time.Minute)},
+		{EventID: "u2-5", UserID: 2, FromCurrency: "EUR", Amount: 360000, Timestamp: base},
+	}
+	storage.SaveTransferBatch(context.Background(), batch)
+
+	alerter := &recordingAlerter{}
+	engine := rules.NewEngine(storage, []rules.Rule{
+		rules.VelocityRule{Count: 3, Window: 10 * time.Minute},
+		rules.SpikeRule{Factor: 10, MinHistory: 3},
+	}, []rules.Alerter{alerter}, 50, logrus.New())
+
+	engine.EvaluateBatch(context.Background(), batch)
+
+	if len(storage.flags) != 2 {
+		t.Fatalf("Expected 2 flags, got %+v", storage.flags)
+	}
+	for _, f := range storage.flags {
+		switch f.UserID {
+		case 1:
+			if f.Rule != rules.RuleVelocity || f.ID != "velocity:u1-3" {
+				t.Errorf("Unexpected flag for user 1: %+v", f)
+			}
+		case 2:
+			if f.Rule != rules.RuleSpike || f.ID != "amount_spike:u2-5" || f.Currency != "EUR" {
+				t.Errorf("Unexpected flag for user 2: %+v", f)
+			}
+		}
+	}
+	if len(alerter.alerts) != 2 {
+		t.Errorf("Expected 2 alerts, got %v", alerter.alerts)
+	}
+
+	// Повторная доставка пакета не создает новых отметок и оповещений
+	engine.EvaluateBatch(context.Background(), batch)
+	if len(storage.flags) != 2 || len(alerter.alerts) != 2 {
+		t.Errorf("Expected no duplicate flags, got %d flags and %d alerts", len(storage.flags), len(alerter.alerts))
+	}
+
+	// Без достаточной истории и вне окна правила не срабатывают
+	if _, flagged := (rules.SpikeRule{Factor: 10, MinHistory: 3}).Evaluate(batch[0], history[:2]); flagged {
+		t.Error("Spike rule should require minimum history")
+	}
+	if _, flagged := (rules.VelocityRule{Count: 3, Window: time.Minute}).Evaluate(batch[0], append(history[:2:2], batch[0])); flagged {
+		t.Error("Velocity rule should only count transfers within the window")
+	}
+
+	// GET /admin/flags
+	server := api.NewServer("0", "", api.QueryLimits{MaxLimit: 100, MaxWindow: time.Hour}, time.Minute, nil, storage, logrus.New())
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/flags?user_id=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response api.FlagsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Flags) != 1 || response.Flags[0].Rule != rules.RuleSpike {
+		t.Errorf("Unexpected flags: %+v", response.Flags)
+	}
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/flags?user_id=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid user_id, got %d", rec.Code)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	keys := []string{"SERVICE_NAME", "MONGO_URI", "MONGO_MAX_POOL_SIZE", "KAFKA_BROKERS", "REPORTS_PERIODS", "REPORTS_ENABLED"}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	path := filepath.Join(t.TempDir(), "notification.yml")
+	yamlConfig := `
+service:
+  name: notification-yaml
+mongo:
+  uri: mongodb://mongo.internal:27017
+  max_pool_size: 25
+kafka:
+  brokers:
+    - kafka-1:9092
+    - kafka-2:9092
+reports:
+  enabled: true
+  periods: [daily, weekly]
+`
+	if err := os.WriteFile(path, []byte(yamlConfig), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// Переменные окружения важнее файла
+	t.Setenv("SERVICE_NAME", "notification-env")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+	if cfg.Service.Name != "notification-env" {
+		t.Errorf("Expected environment to override the config file, got service name %q", cfg.Service.Name)
+	}
+	if cfg.MongoDB.URI != "mongodb://mongo.internal:27017" || cfg.MongoDB.MaxPoolSize != 25 {
+		t.Errorf("Unexpected MongoDB config: %+v", cfg.MongoDB)
+	}
+	if strings.Join(cfg.Kafka.Brokers, ",") != "kafka-1:9092,kafka-2:9092" {
+		t.Errorf("Unexpected Kafka brokers: %v", cfg.Kafka.Brokers)
+	}
+	if !cfg.Reports.Enabled || strings.Join(cfg.Reports.Periods, ",") != "daily,weekly" {
+		t.Errorf("Unexpected reports config: %+v", cfg.Reports)
+	}
+
+	if err := os.WriteFile(path, []byte("mongo:\n  uri: a\nMONGO_URI: b\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := config.Load(path); err == nil {
+		t.Error("Expected error for duplicate setting")
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	for _, key := range []string{"LOG_LEVEL", "SERVICE_NAME"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	logger := logrus.New()
+	server := api.NewServer("0", "secret", api.QueryLimits{}, time.Minute, nil, NewMockStorage(), logger)
+
+	call := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/config/reload", nil)
+		req.Header.Set("X-Admin-Token", "secret")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		return w
+	}
+	if w := call(http.MethodPost); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without reloader, got %d", w.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), "notification.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("log_level: info\nservice_name: notification\n")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	server.SetConfigReloader(config.NewReloader(path, cfg, func(next *config.Config) error {
+		return notificationlogger.SetLevel(logger, next.Logger.Level)
+	}, logger))
+
+	// Уровень логирования применяется сразу, имя сервиса - после перезапуска
+	write("log_level: debug\nservice_name: notification-2\n")
+	w := call(http.MethodPost)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result config.ReloadResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if strings.Join(result.Applied, ",") != "LOG_LEVEL" || strings.Join(result.RestartRequired, ",") != "Service" {
+		t.Errorf("Unexpected reload result: %+v", result)
+	}
+	if logger.GetLevel() != logrus.DebugLevel {
+		t.Errorf("Expected debug log level, got %v", logger.GetLevel())
+	}
+
+	write("log_level: verbose\n")
+	if w := call(http.MethodPost); w.Code != http.StatusInternalServerError || logger.GetLevel() != logrus.DebugLevel {
+		t.Errorf("Expected 500 and unchanged log level for invalid config, got %d %v", w.Code, logger.GetLevel())
+	}
+	if w := call(http.MethodGet); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/config/reload", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unauthorized request to be rejected, got %d", w.Code)
+	}
+}
+
+func TestEmbeddedApp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.yaml")
+	// Kafka недоступна: consumer повторяет подключение до остановки сервиса
+	yamlConfig := `
+http:
+  port: "0"
+kafka:
+  brokers: [127.0.0.1:1]
+max_processing_time: 2s
+`
+	if err := os.WriteFile(path, []byte(yamlConfig), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	storage := NewMockStorage()
+	application, err := app.New(cfg, app.WithLogger(logger), app.WithStorage(storage))
+	if err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+	application.Handler().ServeHTTP(w, req)
+	var version buildinfo.Info
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &version) != nil || version != buildinfo.Get() {
+		t.Errorf("Expected build info from embedded app, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- application.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("App did not stop after context cancel")
+	}
+}
+
+// TestMemoryStorage проверяет хранилище STORAGE_DRIVER=memory: дедупликацию по
+// event_id, статусы пользователей, агрегаты и очистку так же, как у MongoDB
+func TestMemoryStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.yaml")
+	if err := os.WriteFile(path, []byte("storage:\n  driver: memory\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.MongoDB.URI = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected memory driver to pass validation without MONGO_URI: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	application, err := app.New(cfg, app.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	storage := application.Storage()
+	defer storage.Close(context.Background())
+
+	ctx := context.Background()
+	day := time.Date(2024, 2, 2, 10, 0, 0, 0, time.UTC)
+	batch := []storages.LargeTransfer{
+		{EventID: "e1", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 1000, Timestamp: day},
+		{EventID: "e2", UserID: 2, Type: storages.TransferTypeWithdraw, FromCurrency: "EUR", Amount: 500, Timestamp: day.Add(time.Hour)},
+		{EventID: "e1", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 1000, Timestamp: day},
+	}
+	if err := storage.SaveTransferBatch(ctx, batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+
+	stats, err := storage.GetStatistics(ctx)
+	if err != nil || stats.TotalProcessed != 2 || stats.TotalAmount != 1500 {
+		t.Fatalf("Expected 2 transfers totaling 1500 after dedup, got %+v (err: %v)", stats, err)
+	}
+
+	// Заморозка помечает сохраненные и новые переводы, устаревшее событие пропускается
+	freeze := &storages.UserLifecycleMessage{EventID: "u1", UserID: 1, Event: storages.UserEventFrozen, Timestamp: day.Add(2 * time.Hour)}
+	if marked, err := storage.ApplyUserLifecycle(ctx, freeze); err != nil || marked != 1 {
+		t.Fatalf("Expected 1 marked transfer, got %d (err: %v)", marked, err)
+	}
+	outdated := &storages.UserLifecycleMessage{EventID: "u0", UserID: 1, Event: storages.UserEventActivated, Timestamp: day}
+	if marked, err := storage.ApplyUserLifecycle(ctx, outdated); err != nil || marked != 0 {
+		t.Fatalf("Expected outdated event to be skipped, got %d (err: %v)", marked, err)
+	}
+	transfer := &storages.LargeTransfer{EventID: "e3", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 2000, Timestamp: day.Add(3 * time.Hour)}
+	if err := storage.SaveTransfer(ctx, transfer); err != nil {
+		t.Fatalf("Failed to save transfer: %v", err)
+	}
+	if transfer.UserStatus != storages.UserStatusFrozen {
+		t.Errorf("Expected new transfer of frozen user to be marked, got %q", transfer.UserStatus)
+	}
+	if found, err := storage.GetTransfer(ctx, transfer.ID.Hex()); err != nil || found.EventID != "e3" {
+		t.Errorf("Expected transfer e3 by ID, got %+v (err: %v)", found, err)
+	}
+
+	summary, err := storage.GetSummary(ctx, day, 1)
+	if err != nil || summary.Total != 3 || len(summary.TopUsers) != 1 || summary.TopUsers[0].UserID != 1 {
+		t.Fatalf("Unexpected summary: %+v (err: %v)", summary, err)
+	}
+
+	report, err := storage.BuildReport(ctx, storages.ReportPeriodDaily, day.Truncate(24*time.Hour), day.Truncate(24*time.Hour).Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Failed to build report: %v", err)
+	}
+	if report.Total != 3 || report.Users != 2 || len(report.Rows) != 2 || report.ByCurrency[0].Currency != "USD" || report.ByCurrency[0].TotalAmount != 3000 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	report.ID = "daily-2024-02-02"
+	if saved, err := storage.SaveReport(ctx, report); err != nil || !saved {
+		t.Fatalf("Expected report to be saved: %v", err)
+	}
+	if saved, _ := storage.SaveReport(ctx, report); saved {
+		t.Error("Expected duplicate report to be skipped")
+	}
+
+	purged, err := storage.PurgeTransfers(ctx, time.Now().Add(time.Minute), 1)
+	if err != nil || purged != 3 {
+		t.Fatalf("Expected 3 purged transfers, got %d (err: %v)", purged, err)
+	}
+	health, err := storage.Health(ctx)
+	if err != nil || health.Retention == nil || health.Retention.PurgedTotal != 3 || health.LastWriteAt == nil {
+		t.Errorf("Unexpected health: %+v (err: %v)", health, err)
+	}
+}
+
+// TestPostgresStorageConfig проверяет выбор STORAGE_DRIVER=postgres: параметры DB_*,
+// их проверку и передачу ограничений запросов в хранилище
+func TestPostgresStorageConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.yaml")
+	yaml := "storage:\n  driver: postgres\ndb:\n  host: 127.0.0.1\n  port: 1\n  name: alerts\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("QUERY_MAX_LIMIT", "250")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.MongoDB.URI = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected postgres driver to pass validation without MONGO_URI: %v", err)
+	}
+
+	postgresConfig := app.PostgresConfig(cfg)
+	if postgresConfig.Host != "127.0.0.1" || postgresConfig.Port != 1 || postgresConfig.DBName != "alerts" {
+		t.Errorf("Unexpected PostgreSQL config: %+v", postgresConfig)
+	}
+	if postgresConfig.QueryMaxLimit != 250 || !postgresConfig.MigrateOnStart {
+		t.Errorf("Expected query limit 250 and migrations on start, got %+v", postgresConfig)
+	}
+
+	if latest, err := postgres.LatestMigrationVersion(); err != nil || latest != 1 {
+		t.Errorf("Expected latest migration 1, got %d (err: %v)", latest, err)
+	}
+
+	// Недоступная база: New возвращает ошибку, а не сервис без хранилища
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	if _, err := app.New(cfg, app.WithLogger(logger)); err == nil {
+		t.Error("Expected error for unreachable PostgreSQL")
+	}
+
+	cfg.Postgres.Host = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_HOST") {
+		t.Errorf("Expected DB_HOST error, got %v", err)
+	}
+}
+
+// newTestPostgresStorage подключается к базе из GW_TEST_POSTGRES_* и очищает
+// таблицы сервиса. Без GW_TEST_POSTGRES_HOST тест пропускается. Использовать
+// только отдельную тестовую базу: данные в ней удаляются
+func newTestPostgresStorage(t *testing.T) *postgres.PostgresStorage {
+	t.Helper()
+	host := os.Getenv("GW_TEST_POSTGRES_HOST")
+	if host == "" {
+		t.Skip("GW_TEST_POSTGRES_HOST is not set")
+	}
+
+	cfg := &postgres.Config{
+		Host:           host,
+		Port:           env.Int("GW_TEST_POSTGRES_PORT", 5432),
+		User:           env.String("GW_TEST_POSTGRES_USER", "postgres"),
+		Password:       env.String("GW_TEST_POSTGRES_PASSWORD", ""),
+		DBName:         env.String("GW_TEST_POSTGRES_DB", "notification_test"),
+		SSLMode:        env.String("GW_TEST_POSTGRES_SSLMODE", "disable"),
+		MaxOpenConns:   5,
+		MaxIdleConns:   1,
+		MigrateOnStart: true,
+	}
+
+	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	storage, err := postgres.New(cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { storage.Close(context.Background()) })
+
+	if _, err := db.Exec(`TRUNCATE large_transfers, user_preferences, reports, suspicious_flags`); err != nil {
+		t.Fatalf("Failed to clean tables: %v", err)
+	}
+	return storage
+}
+
+// TestPostgresStorage проверяет поведение хранилища PostgreSQL на реальной базе:
+// дедупликацию, статусы пользователей, выборки, агрегаты, отчеты, отметки и очистку
+func TestPostgresStorage(t *testing.T) {
+	storage := newTestPostgresStorage(t)
+	ctx := context.Background()
+
+	status, err := storage.MigrationStatus(ctx)
+	if err != nil || status.Pending() || status.Version != status.Latest {
+		t.Fatalf("Expected all migrations applied, got %+v (err: %v)", status, err)
+	}
+
+	day := time.Date(2024, 2, 2, 10, 0, 0, 0, time.UTC)
+	batch := []storages.LargeTransfer{
+		{EventID: "e1", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 1000, Timestamp: day},
+		{EventID: "e2", UserID: 2, Type: storages.TransferTypeWithdraw, FromCurrency: "EUR", Amount: 500, Timestamp: day.Add(time.Hour)},
+		{EventID: "e1", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 1000, Timestamp: day},
+	}
+	if err := storage.SaveTransferBatch(ctx, batch); err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	// Повтор пакета после сбоя consumer не создает дублей
+	if err := storage.SaveTransferBatch(ctx, batch[:2]); err != nil {
+		t.Fatalf("Failed to save repeated batch: %v", err)
+	}
+
+	stats, err := storage.GetStatistics(ctx)
+	if err != nil || stats.TotalProcessed != 2 || stats.TotalAmount != 1500 || stats.AverageAmount != 750 {
+		t.Fatalf("Expected 2 transfers totaling 1500 after dedup, got %+v (err: %v)", stats, err)
+	}
+
+	// Заморозка помечает сохраненные и новые переводы, устаревшее событие пропускается
+	freeze := &storages.UserLifecycleMessage{EventID: "u1", UserID: 1, Event: storages.UserEventFrozen, Timestamp: day.Add(2 * time.Hour)}
+	if marked, err := storage.ApplyUserLifecycle(ctx, freeze); err != nil || marked != 1 {
+		t.Fatalf("Expected 1 marked transfer, got %d (err: %v)", marked, err)
+	}
+	outdated := &storages.UserLifecycleMessage{EventID: "u0", UserID: 1, Event: storages.UserEventActivated, Timestamp: day}
+	if marked, err := storage.ApplyUserLifecycle(ctx, outdated); err != nil || marked != 0 {
+		t.Fatalf("Expected outdated event to be skipped, got %d (err: %v)", marked, err)
+	}
+	preferences, err := storage.GetUserPreferences(ctx, 1)
+	if err != nil || preferences.Status != storages.UserStatusFrozen || preferences.DeliveryEnabled {
+		t.Fatalf("Expected frozen user without delivery, got %+v (err: %v)", preferences, err)
+	}
+
+	transfer := &storages.LargeTransfer{EventID: "e3", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 2000, Timestamp: day.Add(3 * time.Hour)}
+	if err := storage.SaveTransfer(ctx, transfer); err != nil {
+		t.Fatalf("Failed to save transfer: %v", err)
+	}
+	if transfer.UserStatus != storages.UserStatusFrozen {
+		t.Errorf("Expected new transfer of frozen user to be marked, got %q", transfer.UserStatus)
+	}
+	found, err := storage.GetTransfer(ctx, transfer.ID.Hex())
+	if err != nil || found.EventID != "e3" || found.UserStatus != storages.UserStatusFrozen || !found.Timestamp.Equal(transfer.Timestamp) {
+		t.Errorf("Expected transfer e3 by ID, got %+v (err: %v)", found, err)
+	}
+	if _, err := storage.GetTransfer(ctx, "not-an-id"); err == nil {
+		t.Error("Expected error for invalid ID")
+	}
+
+	byUser, err := storage.GetTransfersByUser(ctx, 1, 10)
+	if err != nil || len(byUser) != 2 || byUser[0].EventID != "e3" || byUser[1].EventID != "e1" {
+		t.Errorf("Expected transfers e3, e1 of user 1, got %+v (err: %v)", byUser, err)
+	}
+	recent, err := storage.GetRecentTransfers(ctx, 2)
+	if err != nil || len(recent) != 2 {
+		t.Errorf("Expected 2 recent transfers, got %+v (err: %v)", recent, err)
+	}
+
+	summary, err := storage.GetSummary(ctx, day, 1)
+	if err != nil || summary.Total != 3 || len(summary.TopUsers) != 1 || summary.TopUsers[0].UserID != 1 {
+		t.Fatalf("Unexpected summary: %+v (err: %v)", summary, err)
+	}
+	if len(summary.ByTypeCurrency) != 2 {
+		t.Errorf("Expected deposits in USD and withdrawals in EUR, got %+v", summary.ByTypeCurrency)
+	}
+
+	start := day.Truncate(24 * time.Hour)
+	report, err := storage.BuildReport(ctx, storages.ReportPeriodDaily, start, start.Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Failed to build report: %v", err)
+	}
+	if report.Total != 3 || report.Users != 2 || len(report.Rows) != 2 || report.ByCurrency[0].Currency != "USD" || report.ByCurrency[0].TotalAmount != 3000 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	report.ID = "daily-2024-02-02"
+	if saved, err := storage.SaveReport(ctx, report); err != nil || !saved {
+		t.Fatalf("Expected report to be saved: %v", err)
+	}
+	if saved, _ := storage.SaveReport(ctx, report); saved {
+		t.Error("Expected duplicate report to be skipped")
+	}
+	reports, err := storage.GetReports(ctx, storages.ReportFilter{Period: storages.ReportPeriodDaily, Limit: 10})
+	if err != nil || len(reports) != 1 || reports[0].ID != report.ID || reports[0].Total != 3 {
+		t.Errorf("Expected saved report, got %+v (err: %v)", reports, err)
+	}
+
+	flag := &storages.SuspiciousFlag{
+		ID: "velocity:e3", UserID: 1, Rule: "velocity", Reason: "too many transfers", EventID: "e3",
+		Amount: 2000, Currency: "USD", TransferAt: transfer.Timestamp, FlaggedAt: time.Now().UTC(),
+	}
+	if saved, err := storage.SaveFlag(ctx, flag); err != nil || !saved {
+		t.Fatalf("Expected flag to be saved: %v", err)
+	}
+	if saved, _ := storage.SaveFlag(ctx, flag); saved {
+		t.Error("Expected duplicate flag to be skipped")
+	}
+	if flags, err := storage.GetFlags(ctx, storages.FlagFilter{UserID: 1}); err != nil || len(flags) != 1 || flags[0].Rule != "velocity" {
+		t.Errorf("Expected flag of user 1, got %+v (err: %v)", flags, err)
+	}
+	if flags, err := storage.GetFlags(ctx, storages.FlagFilter{UserID: 2}); err != nil || len(flags) != 0 {
+		t.Errorf("Expected no flags of user 2, got %+v (err: %v)", flags, err)
+	}
+
+	purged, err := storage.PurgeTransfers(ctx, time.Now().Add(time.Minute), 1)
+	if err != nil || purged != 3 {
+		t.Fatalf("Expected 3 purged transfers, got %d (err: %v)", purged, err)
+	}
+	health, err := storage.Health(ctx)
+	if err != nil || health.Status != storages.HealthStatusOK || health.Retention == nil || health.Retention.PurgedTotal != 3 || health.LastWriteAt == nil {
+		t.Errorf("Unexpected health: %+v (err: %v)", health, err)
+	}
+}
+
+// blockingWriter пишет пакеты после закрытия release, имитируя недоступный ClickHouse
+type blockingWriter struct {
+	release chan struct{}
+	written chan int
+}
+
+func (w *blockingWriter) Insert(ctx context.Context, transfers []storages.LargeTransfer) error {
+	select {
+	case <-w.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	w.written <- len(transfers)
+	return nil
+}
+
+// TestClickHouseSink проверяет запись переводов в ClickHouse: формат INSERT,
+// ключ дедупликации и поведение очереди async при недоступном ClickHouse
+func TestClickHouseSink(t *testing.T) {
+	var queries, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-ClickHouse-User") != "analyst" {
+			http.Error(w, "Code: 516. Authentication failed", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		queries = append(queries, r.URL.Query().Get("query"))
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	writer := analytics.NewClickHouseWriter(analytics.ClickHouseConfig{
+		URL: server.URL, Database: "analytics", Table: "large_transfers", User: "analyst", Timeout: time.Second,
+	})
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	// Режим sync: пакет пишется в основное хранилище и в ClickHouse
+	primary := NewMockStorage()
+	storage := analytics.NewStorage(primary, analytics.NewSink(writer, analytics.SinkConfig{Mode: analytics.ModeSync}, logger))
+	ts := time.Date(2024, 2, 2, 10, 0, 0, 0, time.UTC)
+	err := storage.SaveTransferBatch(context.Background(), []storages.LargeTransfer{
+		{EventID: "e1", UserID: 1, Type: storages.TransferTypeDeposit, FromCurrency: "USD", Amount: 1000, Timestamp: ts},
+		{UserID: 2, Type: storages.TransferTypeWithdraw, FromCurrency: "EUR", Amount: 500, Timestamp: ts},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save batch: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "INSERT INTO `analytics`.`large_transfers` FORMAT JSONEachRow" {
+		t.Fatalf("Unexpected ClickHouse queries: %v", queries)
+	}
+	rows := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %s", len(rows), bodies[0])
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(rows[0]), &row); err != nil {
+		t.Fatalf("Invalid JSONEachRow row: %v", err)
+	}
+	if row["dedup_key"] != "e1" || row["timestamp"] != "2024-02-02 10:00:00.000" || row["amount"] != 1000.0 {
+		t.Errorf("Unexpected row: %v", row)
+	}
+	if stats := storage.Sink().Stats(); stats.Written != 2 || stats.Failed != 0 {
+		t.Errorf("Unexpected sync stats: %+v", stats)
+	}
+
+	// Ошибка ClickHouse в режиме sync возвращается consumer для повтора пакета
+	failing := analytics.NewClickHouseWriter(analytics.ClickHouseConfig{URL: server.URL, Table: "large_transfers", Timeout: time.Second})
+	storage = analytics.NewStorage(primary, analytics.NewSink(failing, analytics.SinkConfig{Mode: analytics.ModeSync}, logger))
+	err = storage.SaveTransfer(context.Background(), &storages.LargeTransfer{EventID: "e3", UserID: 3, Amount: 1000, Timestamp: ts})
+	if err == nil || !strings.Contains(err.Error(), "516") {
+		t.Errorf("Expected ClickHouse error, got %v", err)
+	}
+
+	// Режим async с drop: переводы сверх очереди отбрасываются, consumer не ждет
+	blocked := &blockingWriter{release: make(chan struct{}), written: make(chan int, 10)}
+	sink := analytics.NewSink(blocked, analytics.SinkConfig{
+		Mode: analytics.ModeAsync, QueueSize: 2, BatchSize: 1, FlushInterval: time.Hour,
+		RetryAttempts: 1, Overflow: analytics.OverflowDrop,
+	}, logger)
+	batch := make([]storages.LargeTransfer, 6)
+	start := time.Now()
+	if err := sink.Write(context.Background(), batch); err != nil {
+		t.Fatalf("Async write returned error: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected drop mode not to block")
+	}
+	// Один перевод ждет в writer, два в очереди
+	if stats := sink.Stats(); stats.Dropped < 3 || stats.Dropped > 4 {
+		t.Errorf("Expected 3-4 dropped transfers, got %+v", stats)
+	}
+
+	// Close дописывает очередь после появления ClickHouse
+	close(blocked.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if stats := sink.Stats(); stats.Written+stats.Dropped != 6 || stats.Queued != 0 {
+		t.Errorf("Expected all transfers written or dropped, got %+v", stats)
+	}
+
+	// Режим async с block: consumer ждет BlockTimeout, затем пакет отбрасывается
+	blocked = &blockingWriter{release: make(chan struct{}), written: make(chan int, 10)}
+	sink = analytics.NewSink(blocked, analytics.SinkConfig{
+		Mode: analytics.ModeAsync, QueueSize: 1, BatchSize: 1, FlushInterval: time.Hour,
+		RetryAttempts: 1, Overflow: analytics.OverflowBlock, BlockTimeout: 100 * time.Millisecond,
+	}, logger)
+	start = time.Now()
+	sink.Write(context.Background(), make([]storages.LargeTransfer, 4))
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected block mode to wait for the queue, waited %v", elapsed)
+	}
+	if stats := sink.Stats(); stats.Dropped == 0 {
+		t.Errorf("Expected dropped transfers after block timeout, got %+v", stats)
+	}
+	close(blocked.release)
+	sink.Close(ctx)
+
+	// Проверка конфигурации
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.ClickHouse.Mode != config.ClickHouseModeOff {
+		t.Errorf("Expected ClickHouse sink to be off by default, got %s", cfg.ClickHouse.Mode)
+	}
+	cfg.ClickHouse.Mode = config.ClickHouseModeAsync
+	cfg.ClickHouse.Overflow = "spill"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CLICKHOUSE_OVERFLOW") {
+		t.Errorf("Expected CLICKHOUSE_OVERFLOW error, got %v", err)
+	}
+}